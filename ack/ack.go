@@ -0,0 +1,375 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ack manages acknowledgements of firing alerts. An acknowledgement
+// states that someone is looking at an alert. It applies until the alert
+// resolves and is shared with all members of the cluster.
+package ack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// ErrNotFound is returned if an acknowledgement does not exist.
+var ErrNotFound = errors.New("acknowledgement not found")
+
+// Ack is the acknowledgement of a firing alert.
+type Ack struct {
+	Fingerprint string `json:"fingerprint"`
+	// StartsAt is the start of the acknowledged firing period of the alert.
+	StartsAt  time.Time `json:"startsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Removed marks a withdrawn acknowledgement. It is kept until it
+	// expires so that it overrides the acknowledgement on other peers.
+	Removed   bool      `json:"removed,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Options configures Acks.
+type Options struct {
+	// SnapshotFile from which the initial state is loaded.
+	SnapshotFile string
+	// Retention is the time after which acknowledgements are garbage
+	// collected.
+	Retention time.Duration
+
+	Logger  log.Logger
+	Metrics prometheus.Registerer
+}
+
+// Acks holds the acknowledgements of alerts.
+type Acks struct {
+	logger    log.Logger
+	retention time.Duration
+	now       func() time.Time
+
+	mtx       sync.RWMutex
+	st        state
+	broadcast func([]byte)
+}
+
+// New returns a new Acks object and loads the snapshot file if it exists.
+func New(o Options) (*Acks, error) {
+	a := &Acks{
+		logger:    log.NewNopLogger(),
+		retention: o.Retention,
+		now:       utcNow,
+		st:        state{},
+		broadcast: func([]byte) {},
+	}
+	if o.Logger != nil {
+		a.logger = o.Logger
+	}
+	if o.Metrics != nil {
+		o.Metrics.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "alertmanager_acknowledgements",
+			Help: "The number of acknowledged alerts.",
+		}, func() float64 {
+			return float64(len(a.List()))
+		}))
+	}
+	if o.SnapshotFile != "" {
+		f, err := os.Open(o.SnapshotFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			defer f.Close()
+			if a.st, err = decodeState(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return a, nil
+}
+
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// Ack acknowledges the firing period of the alert with the given
+// fingerprint that started at startsAt.
+func (a *Acks) Ack(fp model.Fingerprint, startsAt time.Time, createdBy, comment string) error {
+	if createdBy == "" {
+		return fmt.Errorf("creator information missing")
+	}
+	now := a.now()
+	return a.set(&Ack{
+		Fingerprint: fp.String(),
+		StartsAt:    startsAt,
+		CreatedBy:   createdBy,
+		Comment:     comment,
+		UpdatedAt:   now,
+		ExpiresAt:   now.Add(a.retention),
+	})
+}
+
+// Unack withdraws the acknowledgement of the alert with the given
+// fingerprint.
+func (a *Acks) Unack(fp model.Fingerprint) error {
+	prev, ok := a.Get(fp)
+	if !ok {
+		return ErrNotFound
+	}
+	now := a.now()
+	e := *prev
+	e.Removed = true
+	e.UpdatedAt = now
+	e.ExpiresAt = now.Add(a.retention)
+
+	return a.set(&e)
+}
+
+func (a *Acks) set(e *Ack) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	a.mtx.Lock()
+	a.st.merge(e)
+	a.mtx.Unlock()
+
+	a.broadcast(b)
+	return nil
+}
+
+// Get returns the acknowledgement of the alert with the given fingerprint.
+func (a *Acks) Get(fp model.Fingerprint) (*Ack, bool) {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	e, ok := a.st[fp.String()]
+	if !ok || e.Removed {
+		return nil, false
+	}
+	return e, true
+}
+
+// Acknowledged returns the acknowledgement of the alert if its current
+// firing period was acknowledged.
+func (a *Acks) Acknowledged(alert *model.Alert) (*Ack, bool) {
+	e, ok := a.Get(alert.Fingerprint())
+	if !ok || !e.StartsAt.Equal(alert.StartsAt) {
+		return nil, false
+	}
+	return e, true
+}
+
+// List returns all acknowledgements ordered by their update time.
+func (a *Acks) List() []*Ack {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	res := make([]*Ack, 0, len(a.st))
+	for _, e := range a.st {
+		if !e.Removed {
+			res = append(res, e)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].UpdatedAt.Before(res[j].UpdatedAt)
+	})
+	return res
+}
+
+// GC removes expired acknowledgements and returns their number.
+func (a *Acks) GC() (int, error) {
+	now := a.now()
+	var n int
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for k, e := range a.st {
+		if e.ExpiresAt.IsZero() {
+			return n, errors.New("unexpected zero expiration timestamp")
+		}
+		if !e.ExpiresAt.After(now) {
+			delete(a.st, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Maintenance garbage collects the acknowledgements at the given interval.
+// If the snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (a *Acks) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	f := func() error {
+		if _, err := a.GC(); err != nil {
+			return err
+		}
+		if snapf == "" {
+			return nil
+		}
+		f, err := openReplace(snapf)
+		if err != nil {
+			return err
+		}
+		if _, err := a.Snapshot(f); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+Loop:
+	for {
+		select {
+		case <-stopc:
+			break Loop
+		case <-t.C:
+			if err := f(); err != nil {
+				level.Info(a.logger).Log("msg", "Running maintenance failed", "err", err)
+			}
+		}
+	}
+	// No need for final maintenance if we don't want to snapshot.
+	if snapf == "" {
+		return
+	}
+	if err := f(); err != nil {
+		level.Info(a.logger).Log("msg", "Creating shutdown snapshot failed", "err", err)
+	}
+}
+
+// Snapshot writes the current state to w.
+func (a *Acks) Snapshot(w io.Writer) (int64, error) {
+	b, err := a.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, bytes.NewReader(b))
+}
+
+// MarshalBinary serializes all acknowledgements.
+func (a *Acks) MarshalBinary() ([]byte, error) {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	return a.st.MarshalBinary()
+}
+
+// Merge merges acknowledgements received from the cluster with the local
+// state.
+func (a *Acks) Merge(b []byte) error {
+	st, err := decodeState(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for _, e := range st {
+		a.st.merge(e)
+	}
+	return nil
+}
+
+// SetBroadcast sets a broadcast callback that will be invoked with serialized
+// state on updates.
+func (a *Acks) SetBroadcast(f func([]byte)) {
+	a.mtx.Lock()
+	a.broadcast = f
+	a.mtx.Unlock()
+}
+
+type state map[string]*Ack
+
+// merge keeps the most recently updated acknowledgement of an alert.
+func (s state) merge(e *Ack) {
+	prev, ok := s[e.Fingerprint]
+	if !ok || prev.UpdatedAt.Before(e.UpdatedAt) {
+		s[e.Fingerprint] = e
+	}
+}
+
+func (s state) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	for _, e := range s {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeState(r io.Reader) (state, error) {
+	st := state{}
+	dec := json.NewDecoder(r)
+	for {
+		var e Ack
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		st.merge(&e)
+	}
+	return st, nil
+}
+
+// replaceFile wraps a file that is moved to another filename on closing.
+type replaceFile struct {
+	*os.File
+	filename string
+}
+
+func (f *replaceFile) Close() error {
+	if err := f.File.Sync(); err != nil {
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.File.Name(), f.filename)
+}
+
+// openReplace opens a new temporary file that is moved to filename on closing.
+func openReplace(filename string) (*replaceFile, error) {
+	tmpFilename := fmt.Sprintf("%s.%x", filename, uint64(rand.Int63()))
+
+	f, err := os.Create(tmpFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	rf := &replaceFile{
+		File:     f,
+		filename: filename,
+	}
+	return rf, nil
+}
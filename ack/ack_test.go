@@ -0,0 +1,123 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ack
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcknowledged(t *testing.T) {
+	a, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	alert := &model.Alert{
+		Labels:   model.LabelSet{"alertname": "a"},
+		StartsAt: time.Unix(100, 0),
+	}
+	fp := alert.Fingerprint()
+
+	require.Error(t, a.Ack(fp, alert.StartsAt, "", "comment"))
+	require.NoError(t, a.Ack(fp, alert.StartsAt, "alice", "comment"))
+
+	e, ok := a.Acknowledged(alert)
+	require.True(t, ok)
+	require.Equal(t, "alice", e.CreatedBy)
+
+	// The acknowledgement does not apply once the alert fires again.
+	refired := *alert
+	refired.StartsAt = time.Unix(200, 0)
+	_, ok = a.Acknowledged(&refired)
+	require.False(t, ok)
+
+	require.NoError(t, a.Unack(fp))
+	_, ok = a.Acknowledged(alert)
+	require.False(t, ok)
+	require.Len(t, a.List(), 0)
+	require.Equal(t, ErrNotFound, a.Unack(fp))
+}
+
+func TestMerge(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	a1, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+	a1.now = func() time.Time { return now }
+	a2, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+	a2.now = func() time.Time { return now.Add(time.Minute) }
+
+	var bcast [][]byte
+	a1.SetBroadcast(func(b []byte) { bcast = append(bcast, b) })
+
+	fp := model.Fingerprint(1)
+	require.NoError(t, a1.Ack(fp, time.Unix(0, 0), "alice", ""))
+	require.Len(t, bcast, 1)
+
+	require.NoError(t, a2.Merge(bcast[0]))
+	_, ok := a2.Get(fp)
+	require.True(t, ok)
+
+	// The more recent removal wins over the acknowledgement.
+	require.NoError(t, a2.Unack(fp))
+	b, err := a2.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, a1.Merge(b))
+	_, ok = a1.Get(fp)
+	require.False(t, ok)
+
+	// An outdated acknowledgement does not override the removal.
+	require.NoError(t, a2.Merge(bcast[0]))
+	_, ok = a2.Get(fp)
+	require.False(t, ok)
+}
+
+func TestGCAndSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ack")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	snapf := filepath.Join(dir, "acks")
+
+	now := time.Unix(1000, 0)
+	a, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+	a.now = func() time.Time { return now }
+
+	require.NoError(t, a.Ack(model.Fingerprint(1), time.Unix(0, 0), "alice", ""))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, a.Ack(model.Fingerprint(2), time.Unix(0, 0), "bob", ""))
+
+	var buf bytes.Buffer
+	_, err = a.Snapshot(&buf)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(snapf, buf.Bytes(), 0666))
+
+	loaded, err := New(Options{SnapshotFile: snapf, Retention: time.Hour})
+	require.NoError(t, err)
+	require.Len(t, loaded.List(), 2)
+
+	now = now.Add(45 * time.Minute)
+	n, err := a.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	_, ok := a.Get(model.Fingerprint(2))
+	require.True(t, ok)
+}
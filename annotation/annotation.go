@@ -0,0 +1,388 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package annotation manages annotations that responders attach to firing
+// alerts, like notes about a known issue. They override the annotations of
+// the alert with the same name until the alert resolves and are shared with
+// all members of the cluster.
+package annotation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// Annotation is an annotation attached to a firing alert.
+type Annotation struct {
+	Fingerprint string `json:"fingerprint"`
+	// StartsAt is the start of the firing period of the alert the
+	// annotation applies to.
+	StartsAt  time.Time       `json:"startsAt"`
+	Name      model.LabelName `json:"name"`
+	Value     string          `json:"value,omitempty"`
+	UpdatedBy string          `json:"updatedBy"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	// Removed marks a removed annotation. It is kept until it expires so
+	// that it overrides the annotation on other peers.
+	Removed   bool      `json:"removed,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (e *Annotation) key() string {
+	return e.Fingerprint + "/" + string(e.Name)
+}
+
+// Options configures Annotations.
+type Options struct {
+	// SnapshotFile from which the initial state is loaded.
+	SnapshotFile string
+	// Retention is the time after which annotations are garbage collected.
+	Retention time.Duration
+
+	Logger  log.Logger
+	Metrics prometheus.Registerer
+}
+
+// Annotations holds the annotations attached to alerts.
+type Annotations struct {
+	logger    log.Logger
+	retention time.Duration
+	now       func() time.Time
+
+	mtx       sync.RWMutex
+	st        state
+	broadcast func([]byte)
+}
+
+// New returns a new Annotations object and loads the snapshot file if it
+// exists.
+func New(o Options) (*Annotations, error) {
+	a := &Annotations{
+		logger:    log.NewNopLogger(),
+		retention: o.Retention,
+		now:       utcNow,
+		st:        state{},
+		broadcast: func([]byte) {},
+	}
+	if o.Logger != nil {
+		a.logger = o.Logger
+	}
+	if o.Metrics != nil {
+		o.Metrics.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "alertmanager_alert_annotations",
+			Help: "The number of annotations attached to alerts.",
+		}, func() float64 {
+			a.mtx.RLock()
+			defer a.mtx.RUnlock()
+
+			var n int
+			for _, e := range a.st {
+				if !e.Removed {
+					n++
+				}
+			}
+			return float64(n)
+		}))
+	}
+	if o.SnapshotFile != "" {
+		f, err := os.Open(o.SnapshotFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			defer f.Close()
+			if a.st, err = decodeState(f); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return a, nil
+}
+
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// Set attaches the annotations to the firing period of the alert with the
+// given fingerprint that started at startsAt. Annotations with nil values
+// are removed.
+func (a *Annotations) Set(fp model.Fingerprint, startsAt time.Time, updatedBy string, values map[model.LabelName]*string) error {
+	if updatedBy == "" {
+		return fmt.Errorf("updater information missing")
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("no annotations given")
+	}
+	now := a.now()
+	st := state{}
+	for name, v := range values {
+		if !name.IsValid() {
+			return fmt.Errorf("invalid annotation name %q", name)
+		}
+		e := &Annotation{
+			Fingerprint: fp.String(),
+			StartsAt:    startsAt,
+			Name:        name,
+			UpdatedBy:   updatedBy,
+			UpdatedAt:   now,
+			ExpiresAt:   now.Add(a.retention),
+		}
+		if v == nil {
+			e.Removed = true
+		} else {
+			e.Value = *v
+		}
+		st.merge(e)
+	}
+
+	b, err := st.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	a.mtx.Lock()
+	for _, e := range st {
+		a.st.merge(e)
+	}
+	a.mtx.Unlock()
+
+	a.broadcast(b)
+	return nil
+}
+
+// Get returns the annotations attached to the current firing period of the
+// alert ordered by their names.
+func (a *Annotations) Get(alert *model.Alert) []*Annotation {
+	fp := alert.Fingerprint().String()
+
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	var res []*Annotation
+	for _, e := range a.st {
+		if e.Fingerprint == fp && !e.Removed && e.StartsAt.Equal(alert.StartsAt) {
+			res = append(res, e)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Apply returns a copy of the alert whose annotations are overridden by the
+// annotations attached to its current firing period. The alert itself is
+// returned if none are attached.
+func (a *Annotations) Apply(alert *model.Alert) *model.Alert {
+	attached := a.Get(alert)
+	if len(attached) == 0 {
+		return alert
+	}
+	res := *alert
+	res.Annotations = make(model.LabelSet, len(alert.Annotations)+len(attached))
+	for k, v := range alert.Annotations {
+		res.Annotations[k] = v
+	}
+	for _, e := range attached {
+		res.Annotations[e.Name] = model.LabelValue(e.Value)
+	}
+	return &res
+}
+
+// GC removes expired annotations and returns their number.
+func (a *Annotations) GC() (int, error) {
+	now := a.now()
+	var n int
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for k, e := range a.st {
+		if e.ExpiresAt.IsZero() {
+			return n, errors.New("unexpected zero expiration timestamp")
+		}
+		if !e.ExpiresAt.After(now) {
+			delete(a.st, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Maintenance garbage collects the annotations at the given interval. If
+// the snapshot file is set, a snapshot is written to it afterwards.
+// Terminates on receiving from stopc.
+func (a *Annotations) Maintenance(interval time.Duration, snapf string, stopc <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	f := func() error {
+		if _, err := a.GC(); err != nil {
+			return err
+		}
+		if snapf == "" {
+			return nil
+		}
+		f, err := openReplace(snapf)
+		if err != nil {
+			return err
+		}
+		if _, err := a.Snapshot(f); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+Loop:
+	for {
+		select {
+		case <-stopc:
+			break Loop
+		case <-t.C:
+			if err := f(); err != nil {
+				level.Info(a.logger).Log("msg", "Running maintenance failed", "err", err)
+			}
+		}
+	}
+	// No need for final maintenance if we don't want to snapshot.
+	if snapf == "" {
+		return
+	}
+	if err := f(); err != nil {
+		level.Info(a.logger).Log("msg", "Creating shutdown snapshot failed", "err", err)
+	}
+}
+
+// Snapshot writes the current state to w.
+func (a *Annotations) Snapshot(w io.Writer) (int64, error) {
+	b, err := a.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, bytes.NewReader(b))
+}
+
+// MarshalBinary serializes all annotations.
+func (a *Annotations) MarshalBinary() ([]byte, error) {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	return a.st.MarshalBinary()
+}
+
+// Merge merges annotations received from the cluster with the local state.
+func (a *Annotations) Merge(b []byte) error {
+	st, err := decodeState(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for _, e := range st {
+		a.st.merge(e)
+	}
+	return nil
+}
+
+// SetBroadcast sets a broadcast callback that will be invoked with serialized
+// state on updates.
+func (a *Annotations) SetBroadcast(f func([]byte)) {
+	a.mtx.Lock()
+	a.broadcast = f
+	a.mtx.Unlock()
+}
+
+type state map[string]*Annotation
+
+// merge keeps the most recently updated annotation of an alert with the
+// same name.
+func (s state) merge(e *Annotation) {
+	prev, ok := s[e.key()]
+	if !ok || prev.UpdatedAt.Before(e.UpdatedAt) {
+		s[e.key()] = e
+	}
+}
+
+func (s state) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	for _, e := range s {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeState(r io.Reader) (state, error) {
+	st := state{}
+	dec := json.NewDecoder(r)
+	for {
+		var e Annotation
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		st.merge(&e)
+	}
+	return st, nil
+}
+
+// replaceFile wraps a file that is moved to another filename on closing.
+type replaceFile struct {
+	*os.File
+	filename string
+}
+
+func (f *replaceFile) Close() error {
+	if err := f.File.Sync(); err != nil {
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.File.Name(), f.filename)
+}
+
+// openReplace opens a new temporary file that is moved to filename on closing.
+func openReplace(filename string) (*replaceFile, error) {
+	tmpFilename := fmt.Sprintf("%s.%x", filename, uint64(rand.Int63()))
+
+	f, err := os.Create(tmpFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	rf := &replaceFile{
+		File:     f,
+		filename: filename,
+	}
+	return rf, nil
+}
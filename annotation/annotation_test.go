@@ -0,0 +1,143 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotation
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func value(s string) *string {
+	return &s
+}
+
+func TestApply(t *testing.T) {
+	a, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	alert := &model.Alert{
+		Labels:      model.LabelSet{"alertname": "a"},
+		Annotations: model.LabelSet{"summary": "disk full", "note": "none"},
+		StartsAt:    time.Unix(100, 0),
+	}
+	fp := alert.Fingerprint()
+
+	require.Equal(t, alert, a.Apply(alert))
+
+	require.Error(t, a.Set(fp, alert.StartsAt, "", map[model.LabelName]*string{"note": value("x")}))
+	require.Error(t, a.Set(fp, alert.StartsAt, "alice", nil))
+	require.Error(t, a.Set(fp, alert.StartsAt, "alice", map[model.LabelName]*string{"in-valid": value("x")}))
+
+	require.NoError(t, a.Set(fp, alert.StartsAt, "alice", map[model.LabelName]*string{
+		"note":    value("known issue INC-1234"),
+		"runbook": value("https://runbooks/disk"),
+	}))
+	require.Equal(t, model.LabelSet{
+		"summary": "disk full",
+		"note":    "known issue INC-1234",
+		"runbook": "https://runbooks/disk",
+	}, a.Apply(alert).Annotations)
+	// The alert itself is left unchanged.
+	require.Equal(t, model.LabelValue("none"), alert.Annotations["note"])
+
+	require.NoError(t, a.Set(fp, alert.StartsAt, "bob", map[model.LabelName]*string{"runbook": nil}))
+	attached := a.Get(alert)
+	require.Len(t, attached, 1)
+	require.Equal(t, model.LabelName("note"), attached[0].Name)
+	require.Equal(t, "alice", attached[0].UpdatedBy)
+
+	// The annotations do not apply once the alert fires again.
+	refired := *alert
+	refired.StartsAt = time.Unix(200, 0)
+	require.Equal(t, &refired, a.Apply(&refired))
+}
+
+func TestMerge(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	a1, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+	a1.now = func() time.Time { return now }
+	a2, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+	a2.now = func() time.Time { return now.Add(time.Minute) }
+
+	var bcast [][]byte
+	a1.SetBroadcast(func(b []byte) { bcast = append(bcast, b) })
+
+	alert := &model.Alert{Labels: model.LabelSet{"alertname": "a"}, StartsAt: time.Unix(0, 0)}
+	fp := alert.Fingerprint()
+	require.NoError(t, a1.Set(fp, alert.StartsAt, "alice", map[model.LabelName]*string{
+		"note":    value("a"),
+		"runbook": value("b"),
+	}))
+	require.Len(t, bcast, 1)
+
+	require.NoError(t, a2.Merge(bcast[0]))
+	require.Len(t, a2.Get(alert), 2)
+
+	// The more recent removal wins over the annotation, other annotations
+	// of the alert are kept.
+	require.NoError(t, a2.Set(fp, alert.StartsAt, "bob", map[model.LabelName]*string{"note": nil}))
+	b, err := a2.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, a1.Merge(b))
+	require.Equal(t, model.LabelSet{"runbook": "b"}, a1.Apply(alert).Annotations)
+
+	// An outdated annotation does not override the removal.
+	require.NoError(t, a2.Merge(bcast[0]))
+	require.Equal(t, model.LabelSet{"runbook": "b"}, a2.Apply(alert).Annotations)
+}
+
+func TestGCAndSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "annotation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	snapf := filepath.Join(dir, "annotations")
+
+	now := time.Unix(1000, 0)
+	a, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+	a.now = func() time.Time { return now }
+
+	alert1 := &model.Alert{Labels: model.LabelSet{"alertname": "a"}, StartsAt: time.Unix(0, 0)}
+	alert2 := &model.Alert{Labels: model.LabelSet{"alertname": "b"}, StartsAt: time.Unix(0, 0)}
+	require.NoError(t, a.Set(alert1.Fingerprint(), alert1.StartsAt, "alice", map[model.LabelName]*string{"note": value("a")}))
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, a.Set(alert2.Fingerprint(), alert2.StartsAt, "bob", map[model.LabelName]*string{"note": value("b")}))
+
+	var buf bytes.Buffer
+	_, err = a.Snapshot(&buf)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(snapf, buf.Bytes(), 0666))
+
+	loaded, err := New(Options{SnapshotFile: snapf, Retention: time.Hour})
+	require.NoError(t, err)
+	require.Len(t, loaded.Get(alert1), 1)
+	require.Len(t, loaded.Get(alert2), 1)
+
+	now = now.Add(45 * time.Minute)
+	n, err := a.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, a.Get(alert1), 0)
+	require.Len(t, a.Get(alert2), 1)
+}
@@ -14,30 +14,54 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
+	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/common/version"
-	"github.com/prometheus/prometheus/pkg/labels"
 
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/annotation"
+	"github.com/prometheus/alertmanager/audit"
 	"github.com/prometheus/alertmanager/cluster"
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/dlq"
+	"github.com/prometheus/alertmanager/history"
+	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pkg/confighistory"
+	"github.com/prometheus/alertmanager/pkg/labels"
 	"github.com/prometheus/alertmanager/pkg/parse"
+	"github.com/prometheus/alertmanager/pkg/query"
+	"github.com/prometheus/alertmanager/pkg/tracing"
+	"github.com/prometheus/alertmanager/pkg/web"
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/silence"
 	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/tenant"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -81,6 +105,10 @@ func setCORS(w http.ResponseWriter) {
 type API struct {
 	alerts         provider.Alerts
 	silences       *silence.Silences
+	acks           *ack.Acks
+	deadLetters    *dlq.DLQ
+	auditLog       *audit.Log
+	nflog          *nflog.Log
 	config         *config.Config
 	route          *dispatch.Route
 	resolveTimeout time.Duration
@@ -90,19 +118,32 @@ type API struct {
 
 	groups         groupsFn
 	getAlertStatus getAlertStatusFn
+	inhibitors     inhibitorsFn
+	applyConfig    applyConfigFn
+	configHistory  *confighistory.History
+	maintenance    *notify.Maintenance
+	history        *history.History
+	annotations    *annotation.Annotations
 
 	mtx sync.RWMutex
 }
 
 type groupsFn func([]*labels.Matcher) dispatch.AlertOverview
 type getAlertStatusFn func(model.Fingerprint) types.AlertStatus
+type inhibitorsFn func(model.LabelSet) []inhibit.Inhibition
+type applyConfigFn func(*config.Config, []byte, string) error
 
 // New returns a new API.
 func New(
 	alerts provider.Alerts,
 	silences *silence.Silences,
+	acks *ack.Acks,
+	deadLetters *dlq.DLQ,
+	auditLog *audit.Log,
+	notificationLog *nflog.Log,
 	gf groupsFn,
 	sf getAlertStatusFn,
+	inf inhibitorsFn,
 	peer *cluster.Peer,
 	l log.Logger,
 ) *API {
@@ -113,14 +154,66 @@ func New(
 	return &API{
 		alerts:         alerts,
 		silences:       silences,
+		acks:           acks,
+		deadLetters:    deadLetters,
+		auditLog:       auditLog,
+		nflog:          notificationLog,
 		groups:         gf,
 		getAlertStatus: sf,
+		inhibitors:     inf,
 		uptime:         time.Now(),
 		peer:           peer,
 		logger:         l,
 	}
 }
 
+// SetConfigApplier sets the function applying configurations submitted to
+// the API. It is given the parsed and the plain configuration and the
+// source of the configuration for the history, and has to restore the
+// running configuration if applying fails. Without it, submitted
+// configurations are only validated.
+func (api *API) SetConfigApplier(f func(conf *config.Config, plain []byte, source string) error) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.applyConfig = f
+}
+
+// SetConfigHistory sets the history of applied configurations, which is
+// listed and rolled back to by the API.
+func (api *API) SetConfigHistory(h *confighistory.History) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.configHistory = h
+}
+
+// SetMaintenance sets the maintenance mode, which is shown and toggled by
+// the API.
+func (api *API) SetMaintenance(m *notify.Maintenance) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.maintenance = m
+}
+
+// SetHistory sets the history of the alerts, which is served by the API.
+func (api *API) SetHistory(h *history.History) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.history = h
+}
+
+// SetAnnotations sets the annotations attached to alerts, which are applied
+// to the served alerts and edited by the API.
+func (api *API) SetAnnotations(a *annotation.Annotations) {
+	api.mtx.Lock()
+	defer api.mtx.Unlock()
+
+	api.annotations = a
+}
+
 // Register registers the API handlers under their correct routes
 // in the given router.
 func (api *API) Register(r *route.Router) {
@@ -134,16 +227,55 @@ func (api *API) Register(r *route.Router) {
 	r.Options("/*path", wrap(func(w http.ResponseWriter, r *http.Request) {}))
 
 	r.Get("/status", wrap(api.status))
+	r.Get("/maintenance", wrap(api.getMaintenance))
+	r.Post("/maintenance", wrap(api.setMaintenance))
+	r.Post("/config", wrap(api.postConfig))
+	r.Get("/config/history", wrap(api.listConfigVersions))
+	r.Get("/config/diff", wrap(api.diffConfigVersion))
+	r.Post("/config/diff", wrap(api.diffConfig))
+	r.Post("/config/validate", wrap(api.validateConfig))
+	r.Get("/config/schema", wrap(api.configSchema))
+	r.Post("/config/rollback/:version", wrap(api.rollbackConfig))
 	r.Get("/receivers", wrap(api.receivers))
 
 	r.Get("/alerts/groups", wrap(api.alertGroups))
 	r.Get("/alerts", wrap(api.listAlerts))
+	r.Get("/alerts/stream", wrap(api.streamEvents))
 	r.Post("/alerts", wrap(api.addAlerts))
+	r.Get("/alert/:fingerprint/notifications", wrap(api.alertNotifications))
+	r.Get("/alert/:fingerprint/history", wrap(api.alertHistory))
+	r.Post("/alert/:fingerprint/ack", wrap(api.ackAlert))
+	r.Del("/alert/:fingerprint/ack", wrap(api.unackAlert))
+	r.Post("/alert/:fingerprint/silence", wrap(api.silenceAlert))
+	r.Post("/alert/:fingerprint/annotations", wrap(api.setAlertAnnotations))
+	r.Get("/alert/:fingerprint/inhibitors", wrap(api.alertInhibitors))
+	r.Get("/inhibitions", wrap(api.listInhibitions))
+	r.Get("/acks", wrap(api.listAcks))
+	r.Get("/nflog", wrap(api.listNotificationLog))
+	r.Post("/routes/test", wrap(api.testRoutes))
 
 	r.Get("/silences", wrap(api.listSilences))
 	r.Post("/silences", wrap(api.setSilence))
+	r.Post("/silences/preview", wrap(api.previewSilence))
+	r.Post("/silences/bulk", wrap(api.setSilences))
+	r.Post("/silences/expire", wrap(api.expireSilences))
+	r.Get("/silences/export", wrap(api.exportSilences))
+	r.Post("/silences/import", wrap(api.importSilences))
 	r.Get("/silence/:sid", wrap(api.getSilence))
 	r.Del("/silence/:sid", wrap(api.delSilence))
+	r.Get("/silence/:sid/history", wrap(api.getSilenceHistory))
+	r.Post("/silence/:sid/comments", wrap(api.addSilenceComment))
+	r.Get("/silence_templates", wrap(api.listSilenceTemplates))
+	r.Post("/silence_templates/:name", wrap(api.createSilenceFromTemplate))
+
+	r.Get("/dlq", wrap(api.listDeadLetters))
+	r.Get("/dlq/:id", wrap(api.getDeadLetter))
+	r.Del("/dlq/:id", wrap(api.delDeadLetter))
+	r.Post("/dlq/:id/replay", wrap(api.replayDeadLetter))
+
+	r.Get("/audit", wrap(api.queryAuditLog))
+
+	r.Post("/voice/callback", notify.VoiceCallback(api.voiceReceiver))
 }
 
 // Update sets the configuration string to a new value.
@@ -160,9 +292,10 @@ func (api *API) Update(cfg *config.Config, resolveTimeout time.Duration) error {
 type errorType string
 
 const (
-	errorNone     errorType = ""
-	errorInternal errorType = "server_error"
-	errorBadData  errorType = "bad_data"
+	errorNone      errorType = ""
+	errorInternal  errorType = "server_error"
+	errorBadData   errorType = "bad_data"
+	errorForbidden errorType = "forbidden"
 )
 
 type apiError struct {
@@ -186,18 +319,65 @@ func (api *API) receivers(w http.ResponseWriter, req *http.Request) {
 	api.respond(w, receivers)
 }
 
+func (api *API) getMaintenance(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	m := api.maintenance
+	api.mtx.RUnlock()
+
+	if m == nil {
+		http.Error(w, "Maintenance mode not available", http.StatusNotFound)
+		return
+	}
+	api.respond(w, m.Status())
+}
+
+func (api *API) setMaintenance(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	m := api.maintenance
+	api.mtx.RUnlock()
+
+	if m == nil {
+		http.Error(w, "Maintenance mode not available", http.StatusNotFound)
+		return
+	}
+	var req struct {
+		Enabled *bool  `json:"enabled"`
+		Reason  string `json:"reason"`
+	}
+	if err := api.receive(r, &req); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if req.Enabled == nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("enabled must be set"),
+		}, nil)
+		return
+	}
+	m.Set(*req.Enabled, req.Reason)
+	level.Info(api.logger).Log("msg", "Maintenance mode changed", "enabled", *req.Enabled, "reason", req.Reason)
+	api.respond(w, m.Status())
+}
+
 func (api *API) status(w http.ResponseWriter, req *http.Request) {
 	api.mtx.RLock()
 
 	var status = struct {
-		ConfigYAML    string            `json:"configYAML"`
-		ConfigJSON    *config.Config    `json:"configJSON"`
-		VersionInfo   map[string]string `json:"versionInfo"`
-		Uptime        time.Time         `json:"uptime"`
-		ClusterStatus *clusterStatus    `json:"clusterStatus"`
+		ConfigYAML     string                    `json:"configYAML"`
+		ConfigJSON     *config.Config            `json:"configJSON"`
+		ConfigWarnings []string                  `json:"configWarnings,omitempty"`
+		Maintenance    *notify.MaintenanceStatus `json:"maintenance,omitempty"`
+		VersionInfo    map[string]string         `json:"versionInfo"`
+		Uptime         time.Time                 `json:"uptime"`
+		ClusterStatus  *clusterStatus            `json:"clusterStatus"`
 	}{
-		ConfigYAML: api.config.String(),
-		ConfigJSON: api.config,
+		ConfigYAML:     api.config.String(),
+		ConfigJSON:     api.config,
+		ConfigWarnings: api.config.Warnings(),
 		VersionInfo: map[string]string{
 			"version":   version.Version,
 			"revision":  version.Revision,
@@ -209,12 +389,313 @@ func (api *API) status(w http.ResponseWriter, req *http.Request) {
 		Uptime:        api.uptime,
 		ClusterStatus: getClusterStatus(api.peer),
 	}
+	if api.maintenance != nil {
+		ms := api.maintenance.Status()
+		status.Maintenance = &ms
+	}
 
 	api.mtx.RUnlock()
 
 	api.respond(w, status)
 }
 
+type configResult struct {
+	// The unified diff of the running and the submitted configuration.
+	Diff    string `json:"diff"`
+	Applied bool   `json:"applied"`
+}
+
+// postConfig validates the YAML configuration of the request body and
+// applies it unless the dry_run parameter is set.
+func (api *API) postConfig(w http.ResponseWriter, r *http.Request) {
+	if !api.checkConfigContentType(w, r) {
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid dry_run parameter %q", v),
+			}, nil)
+			return
+		}
+	}
+
+	plain, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.mtx.RLock()
+	running := api.config
+	api.mtx.RUnlock()
+	conf, err := config.LoadSubmitted(string(plain), running)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid configuration: %s", err),
+		}, nil)
+		return
+	}
+
+	api.applyConfigWithDiff(w, conf, plain, confighistory.SourceAPI, dryRun)
+}
+
+// checkConfigContentType rejects requests changing the configuration with
+// the content types of HTML forms and text, which browsers send cross-origin
+// without a preflight request.
+func (api *API) checkConfigContentType(w http.ResponseWriter, r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err == nil {
+		switch mt {
+		case "text/plain", "application/x-www-form-urlencoded", "multipart/form-data":
+		default:
+			return true
+		}
+	}
+	api.respondError(w, apiError{
+		typ: errorBadData,
+		err: fmt.Errorf("unsupported content type %q", ct),
+	}, nil)
+	return false
+}
+
+// applyConfigWithDiff responds with the diff of the running and the given
+// configuration and applies the latter unless in a dry run.
+func (api *API) applyConfigWithDiff(w http.ResponseWriter, conf *config.Config, plain []byte, source string, dryRun bool) {
+	api.mtx.RLock()
+	running, apply := api.config, api.applyConfig
+	api.mtx.RUnlock()
+
+	var (
+		res configResult
+		err error
+	)
+	res.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(running.String()),
+		B:        difflib.SplitLines(conf.String()),
+		FromFile: "running",
+		ToFile:   "submitted",
+		Context:  3,
+	})
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if dryRun {
+		api.respond(w, res)
+		return
+	}
+
+	if apply == nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: errors.New("applying configurations is not enabled"),
+		}, nil)
+		return
+	}
+	// Configurations are validated again while applying, e.g. templates
+	// are parsed. The applier restores the running configuration on
+	// failure.
+	if err := apply(conf, plain, source); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("applying configuration failed, the running configuration was restored: %s", err),
+		}, res)
+		return
+	}
+	res.Applied = true
+	api.respond(w, res)
+}
+
+// configSchema returns the JSON Schema of the configuration. The schema is
+// not wrapped in the response envelope so that it can be used by editors
+// and validators directly.
+func (api *API) configSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	if err := json.NewEncoder(w).Encode(config.Schema()); err != nil {
+		level.Error(api.logger).Log("msg", "Error marshalling configuration schema", "err", err)
+	}
+}
+
+type validationResult struct {
+	Valid    bool             `json:"valid"`
+	Problems []config.Problem `json:"problems"`
+}
+
+// validateConfig returns the problems of the YAML configuration of the
+// request body. Invalid configurations are no error of the request.
+func (api *API) validateConfig(w http.ResponseWriter, r *http.Request) {
+	plain, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	problems, err := config.Validate(plain)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if problems == nil {
+		problems = []config.Problem{}
+	}
+	api.respond(w, validationResult{
+		Valid:    len(problems) == 0,
+		Problems: problems,
+	})
+}
+
+// diffConfig returns the semantic diff from the running configuration to the
+// YAML configuration of the request body.
+func (api *API) diffConfig(w http.ResponseWriter, r *http.Request) {
+	plain, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.mtx.RLock()
+	running := api.config
+	api.mtx.RUnlock()
+	conf, err := config.LoadSubmitted(string(plain), running)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid configuration: %s", err),
+		}, nil)
+		return
+	}
+	api.respondConfigDiff(w, conf)
+}
+
+// diffConfigVersion returns the semantic diff from the running configuration
+// to the configuration of the version parameter in the history.
+func (api *API) diffConfigVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := strconv.Atoi(r.FormValue("version"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid version %q", r.FormValue("version")),
+		}, nil)
+		return
+	}
+	conf, _, ok := api.configVersion(w, version)
+	if !ok {
+		return
+	}
+	api.respondConfigDiff(w, conf)
+}
+
+func (api *API) respondConfigDiff(w http.ResponseWriter, conf *config.Config) {
+	api.mtx.RLock()
+	running := api.config
+	api.mtx.RUnlock()
+
+	d, err := config.DiffConfigs(running, conf)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, d)
+}
+
+// configVersion returns the parsed and plain configuration of the version of
+// the history. On failure, the error is responded.
+func (api *API) configVersion(w http.ResponseWriter, version int) (*config.Config, []byte, bool) {
+	api.mtx.RLock()
+	h := api.configHistory
+	api.mtx.RUnlock()
+
+	if h == nil {
+		http.Error(w, "Error getting configuration version: "+confighistory.ErrNotFound.Error(), http.StatusNotFound)
+		return nil, nil, false
+	}
+	plain, err := h.Content(version)
+	if err == confighistory.ErrNotFound {
+		http.Error(w, "Error getting configuration version: "+err.Error(), http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return nil, nil, false
+	}
+	conf, err := config.Load(string(plain))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid configuration of version %d: %s", version, err),
+		}, nil)
+		return nil, nil, false
+	}
+	return conf, plain, true
+}
+
+// listConfigVersions returns the versions of the configuration history, the
+// latest first.
+func (api *API) listConfigVersions(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	h := api.configHistory
+	api.mtx.RUnlock()
+
+	if h == nil {
+		api.respond(w, []confighistory.Version{})
+		return
+	}
+	api.respond(w, h.Versions())
+}
+
+// rollbackConfig applies the configuration of a version of the history.
+// With the dry_run parameter set, only the diff is returned.
+func (api *API) rollbackConfig(w http.ResponseWriter, r *http.Request) {
+	if !api.checkConfigContentType(w, r) {
+		return
+	}
+	version, err := strconv.Atoi(route.Param(r.Context(), "version"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid version %q", route.Param(r.Context(), "version")),
+		}, nil)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	conf, plain, ok := api.configVersion(w, version)
+	if !ok {
+		return
+	}
+	api.applyConfigWithDiff(w, conf, plain, fmt.Sprintf("%s:%d", confighistory.SourceRollback, version), dryRun)
+}
+
 type peerStatus struct {
 	Name    string `json:"name"`
 	Address string `json:"address"`
@@ -256,11 +737,91 @@ func (api *API) alertGroups(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	var receiverFilter *regexp.Regexp
+	if receiverParam := r.FormValue("receiver"); receiverParam != "" {
+		receiverFilter, err = regexp.Compile("^(?:" + receiverParam + ")$")
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("failed to parse receiver param: %s", receiverParam),
+			}, nil)
+			return
+		}
+	}
+	routeKey := r.FormValue("route")
+	matchers = api.tenantMatchers(r, matchers)
+
+	var count bool
+	if countParam := r.FormValue("count"); countParam != "" {
+		if count, err = strconv.ParseBool(countParam); err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("parameter %q can either be 'true' or 'false', not %q", "count", countParam),
+			}, nil)
+			return
+		}
+	}
+
 	groups := api.groups(matchers)
 
+	if receiverFilter != nil || routeKey != "" {
+		filtered := dispatch.AlertOverview{}
+		for _, g := range groups {
+			var blocks []*dispatch.AlertBlock
+			for _, b := range g.Blocks {
+				if receiverFilter != nil && !receiverFilter.MatchString(b.RouteOpts.Receiver) {
+					continue
+				}
+				if routeKey != "" && b.RouteKey != routeKey {
+					continue
+				}
+				blocks = append(blocks, b)
+			}
+			if len(blocks) > 0 {
+				filtered = append(filtered, &dispatch.AlertGroup{
+					Labels:   g.Labels,
+					GroupKey: g.GroupKey,
+					Blocks:   blocks,
+				})
+			}
+		}
+		groups = filtered
+	}
+
+	if count {
+		res := alertGroupsCount{Groups: len(groups)}
+		for _, g := range groups {
+			for _, b := range g.Blocks {
+				res.Alerts += len(b.Alerts)
+			}
+		}
+		api.respond(w, res)
+		return
+	}
+
+	api.mtx.RLock()
+	annotations := api.annotations
+	api.mtx.RUnlock()
+
+	if annotations != nil {
+		for _, g := range groups {
+			for _, b := range g.Blocks {
+				for _, a := range b.Alerts {
+					a.Alert = annotations.Apply(a.Alert)
+				}
+			}
+		}
+	}
 	api.respond(w, groups)
 }
 
+// alertGroupsCount is the response of the alert groups endpoint in count
+// mode. Alerts are counted once per block they are part of.
+type alertGroupsCount struct {
+	Groups int `json:"groups"`
+	Alerts int `json:"alerts"`
+}
+
 func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 	var (
 		err            error
@@ -293,8 +854,10 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 		return true, nil
 	}
 
-	if filter := r.FormValue("filter"); filter != "" {
-		matchers, err = parse.Matchers(filter)
+	// The filter is a query, of which lists of matchers are a subset.
+	var filter query.Expr
+	if q := r.FormValue("filter"); q != "" {
+		filter, err = query.Parse(q)
 		if err != nil {
 			api.respondError(w, apiError{
 				typ: errorBadData,
@@ -304,6 +867,8 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	matchers = api.tenantMatchers(r, matchers)
+
 	showActive, err = getBoolParam("active")
 	if err != nil {
 		return
@@ -338,9 +903,19 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	page, err := parseAlertListPage(r)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
 	alerts := api.alerts.GetPending()
 	defer alerts.Close()
 
+	now := time.Now()
 	api.mtx.RLock()
 	// TODO(fabxc): enforce a sensible timeout.
 	for a := range alerts.Next() {
@@ -369,6 +944,10 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 
 		status := api.getAlertStatus(a.Fingerprint())
 
+		if filter != nil && !filter.Matches(a, status, now) {
+			continue
+		}
+
 		if !showActive && status.State == types.AlertStateActive {
 			continue
 		}
@@ -391,9 +970,16 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 			Receivers:   receivers,
 			Fingerprint: a.Fingerprint().String(),
 		}
+		if api.acks != nil {
+			apiAlert.Acknowledgement, _ = api.acks.Acknowledged(&a.Alert)
+		}
+		if api.annotations != nil {
+			apiAlert.Alert = api.annotations.Apply(apiAlert.Alert)
+		}
 
 		res = append(res, apiAlert)
 	}
+	severityLabel, severities := severityOrder(api.config)
 	api.mtx.RUnlock()
 
 	if err != nil {
@@ -403,33 +989,142 @@ func (api *API) listAlerts(w http.ResponseWriter, r *http.Request) {
 		}, nil)
 		return
 	}
-	sort.Slice(res, func(i, j int) bool {
-		return res[i].Fingerprint < res[j].Fingerprint
-	})
+	res = page.apply(w, res, severityLabel, severities)
 	api.respond(w, res)
 }
 
-func receiversMatchFilter(receivers []string, filter *regexp.Regexp) bool {
-	for _, r := range receivers {
-		if filter.MatchString(r) {
-			return true
+// defaultSeverityOrder is the order of the values of the severity label if
+// the configuration does not order them by a severity inhibition.
+var defaultSeverityOrder = []string{"critical", "error", "warning", "info"}
+
+// severityOrder returns the severity label and its values, the highest
+// first.
+func severityOrder(conf *config.Config) (model.LabelName, []string) {
+	if conf != nil && conf.SeverityInhibition != nil {
+		return conf.SeverityInhibition.Label, conf.SeverityInhibition.Order
+	}
+	return config.DefaultSeverityLabel, defaultSeverityOrder
+}
+
+// alertListPage holds the sorting and pagination parameters of the alerts
+// list. Pages are selected by a cursor rather than an offset, so that
+// alerts added or resolved while paging do not shift the following pages.
+type alertListPage struct {
+	// One of fingerprint, startsAt or severity. Alerts with equal values
+	// are sorted by their fingerprint.
+	sortBy string
+	desc   bool
+
+	// A limit of 0 lists all alerts.
+	limit int
+	// The sort key of the last alert of the previous page.
+	cursor string
+}
+
+func parseAlertListPage(r *http.Request) (*alertListPage, error) {
+	p := &alertListPage{sortBy: "fingerprint"}
+	if sortBy := r.FormValue("sort"); sortBy != "" {
+		if strings.HasPrefix(sortBy, "-") {
+			p.desc = true
+			sortBy = sortBy[1:]
+		}
+		switch sortBy {
+		case "fingerprint", "startsAt", "severity":
+		default:
+			return nil, fmt.Errorf("invalid sort parameter %q", sortBy)
+		}
+		p.sortBy = sortBy
+	}
+	if s := r.FormValue("limit"); s != "" {
+		var err error
+		if p.limit, err = strconv.Atoi(s); err != nil || p.limit < 0 {
+			return nil, fmt.Errorf("invalid limit parameter %q", s)
+		}
+	}
+	if s := r.FormValue("cursor"); s != "" {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || !strings.HasPrefix(string(b), p.sortBy+":") {
+			return nil, fmt.Errorf("invalid cursor parameter %q", s)
 		}
+		p.cursor = string(b)
 	}
+	return p, nil
+}
 
-	return false
+// key returns the sort key of the alert, which orders alerts when compared
+// as strings. Severities sort from the highest to the lowest, unknown
+// severities last.
+func (p *alertListPage) key(a *dispatch.APIAlert, label model.LabelName, severities []string) string {
+	var v string
+	switch p.sortBy {
+	case "startsAt":
+		v = a.StartsAt.UTC().Format("20060102150405.000000000")
+	case "severity":
+		rank := len(severities)
+		for i, sev := range severities {
+			if string(a.Labels[label]) == sev {
+				rank = i
+				break
+			}
+		}
+		v = fmt.Sprintf("%04d", rank)
+	}
+	return p.sortBy + ":" + v + ":" + a.Fingerprint
 }
 
-func alertMatchesFilterLabels(a *model.Alert, matchers []*labels.Matcher) bool {
-	sms := make(map[string]string)
-	for name, value := range a.Labels {
-		sms[string(name)] = string(value)
+// apply sorts the alerts and returns the page selected by the cursor and
+// the limit. If the list is paginated, the total number of alerts is set in
+// the X-Total-Count header and the cursor of the next page, if any, in the
+// X-Next-Cursor header.
+func (p *alertListPage) apply(w http.ResponseWriter, alerts []*dispatch.APIAlert, label model.LabelName, severities []string) []*dispatch.APIAlert {
+	keys := make(map[*dispatch.APIAlert]string, len(alerts))
+	for _, a := range alerts {
+		keys[a] = p.key(a, label, severities)
 	}
-	return matchFilterLabels(matchers, sms)
+	less := func(a, b string) bool {
+		if p.desc {
+			return a > b
+		}
+		return a < b
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return less(keys[alerts[i]], keys[alerts[j]])
+	})
+	if p.limit == 0 && p.cursor == "" {
+		return alerts
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(alerts)))
+
+	if p.cursor != "" {
+		start := sort.Search(len(alerts), func(i int) bool {
+			return less(p.cursor, keys[alerts[i]])
+		})
+		alerts = alerts[start:]
+	}
+	if p.limit > 0 && p.limit < len(alerts) {
+		alerts = alerts[:p.limit]
+		next := keys[alerts[len(alerts)-1]]
+		w.Header().Set("X-Next-Cursor", base64.RawURLEncoding.EncodeToString([]byte(next)))
+	}
+	return alerts
 }
 
-func (api *API) addAlerts(w http.ResponseWriter, r *http.Request) {
-	var alerts []*types.Alert
-	if err := api.receive(r, &alerts); err != nil {
+type alertNotification struct {
+	Receiver    string    `json:"receiver"`
+	Integration string    `json:"integration"`
+	Idx         uint32    `json:"idx"`
+	GroupKey    string    `json:"groupKey"`
+	Timestamp   time.Time `json:"timestamp"`
+	// Status is the status of the alert in the notification.
+	Status model.AlertStatus `json:"status"`
+}
+
+// alertNotifications returns the successful notifications of an alert from
+// the notification log, the most recent first. They are kept after the alert
+// is gone until they expire from the log.
+func (api *API) alertNotifications(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.ParseFingerprint(route.Param(r.Context(), "fingerprint"))
+	if err != nil {
 		api.respondError(w, apiError{
 			typ: errorBadData,
 			err: err,
@@ -437,171 +1132,2248 @@ func (api *API) addAlerts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	api.insertAlerts(w, r, alerts...)
+	entries, err := api.nflog.Query(nflog.QAlert(uint64(fp)))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	groupKeys := api.tenantGroupKeys(r)
+	res := make([]*alertNotification, 0, len(entries))
+	for _, e := range entries {
+		if !groupKeys(string(e.GroupKey)) {
+			continue
+		}
+		n := &alertNotification{
+			Receiver:    e.Receiver.GroupName,
+			Integration: e.Receiver.Integration,
+			Idx:         e.Receiver.Idx,
+			GroupKey:    string(e.GroupKey),
+			Timestamp:   e.Timestamp,
+			Status:      model.AlertFiring,
+		}
+		for _, x := range e.ResolvedFingerprints {
+			if x == uint64(fp) {
+				n.Status = model.AlertResolved
+			}
+		}
+		res = append(res, n)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Timestamp.After(res[j].Timestamp)
+	})
+
+	api.respond(w, res)
 }
 
-func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*types.Alert) {
-	now := time.Now()
+// alertHistory returns the state transitions of an alert and the
+// notifications sent for it, the oldest first.
+func (api *API) alertHistory(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.ParseFingerprint(route.Param(r.Context(), "fingerprint"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
 
 	api.mtx.RLock()
-	resolveTimeout := api.resolveTimeout
+	h := api.history
 	api.mtx.RUnlock()
 
-	for _, alert := range alerts {
-		alert.UpdatedAt = now
+	if h == nil {
+		http.Error(w, "Alert history not available", http.StatusNotFound)
+		return
+	}
+	res, err := h.Get(fp)
+	if label, name := api.requestTenant(r); err == nil && name != "" && res.Labels[label] != model.LabelValue(name) {
+		err = history.ErrNotFound
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprint("Error getting alert history: ", err), http.StatusNotFound)
+		return
+	}
+	api.respond(w, res)
+}
 
-		// Ensure StartsAt is set.
-		if alert.StartsAt.IsZero() {
-			if alert.EndsAt.IsZero() {
-				alert.StartsAt = now
-			} else {
-				alert.StartsAt = alert.EndsAt
+// inhibition is the inhibition of a target alert by a source alert.
+type inhibition struct {
+	// RuleIndex is the position of the rule in the inhibit_rules of the
+	// configuration.
+	RuleIndex int                 `json:"ruleIndex"`
+	Rule      *config.InhibitRule `json:"rule,omitempty"`
+	Source    inhibitionAlert     `json:"source"`
+	Target    inhibitionAlert     `json:"target"`
+}
+
+type inhibitionAlert struct {
+	Fingerprint string         `json:"fingerprint"`
+	Labels      model.LabelSet `json:"labels"`
+}
+
+// inhibitionsOf returns the inhibitions of the alert.
+func (api *API) inhibitionsOf(a *types.Alert) []*inhibition {
+	if api.inhibitors == nil {
+		return nil
+	}
+	api.mtx.RLock()
+	var rules []*config.InhibitRule
+	if api.config != nil {
+		rules = api.config.AllInhibitRules()
+	}
+	api.mtx.RUnlock()
+
+	var res []*inhibition
+	for _, ih := range api.inhibitors(a.Labels) {
+		i := &inhibition{
+			RuleIndex: ih.Rule,
+			Source: inhibitionAlert{
+				Fingerprint: ih.Source.Fingerprint().String(),
+				Labels:      ih.Source.Labels,
+			},
+			Target: inhibitionAlert{
+				Fingerprint: a.Fingerprint().String(),
+				Labels:      a.Labels,
+			},
+		}
+		if ih.Rule < len(rules) {
+			i.Rule = rules[ih.Rule]
+		}
+		res = append(res, i)
+	}
+	return res
+}
+
+// alertInhibitors returns the source alerts and inhibit rules inhibiting an
+// alert.
+func (api *API) alertInhibitors(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.ParseFingerprint(route.Param(r.Context(), "fingerprint"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	alert, err := api.tenantAlerts(r).Get(fp)
+	if err != nil {
+		http.Error(w, fmt.Sprint("Error getting alert: ", err), http.StatusNotFound)
+		return
+	}
+
+	res := []*inhibition{}
+	if !alert.Resolved() {
+		res = append(res, api.inhibitionsOf(alert)...)
+	}
+	api.respond(w, res)
+}
+
+// listInhibitions returns the inhibitions of all active alerts.
+func (api *API) listInhibitions(w http.ResponseWriter, r *http.Request) {
+	alerts := api.tenantAlerts(r).GetPending()
+	defer alerts.Close()
+
+	var (
+		err error
+		res = []*inhibition{}
+	)
+	for a := range alerts.Next() {
+		if err = alerts.Err(); err != nil {
+			break
+		}
+		if a.Resolved() {
+			continue
+		}
+		res = append(res, api.inhibitionsOf(a)...)
+	}
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Target.Fingerprint != res[j].Target.Fingerprint {
+			return res[i].Target.Fingerprint < res[j].Target.Fingerprint
+		}
+		if res[i].RuleIndex != res[j].RuleIndex {
+			return res[i].RuleIndex < res[j].RuleIndex
+		}
+		return res[i].Source.Fingerprint < res[j].Source.Fingerprint
+	})
+
+	api.respond(w, res)
+}
+
+type nflogEntry struct {
+	GroupKey    string    `json:"groupKey"`
+	Receiver    string    `json:"receiver"`
+	Integration string    `json:"integration"`
+	Idx         uint32    `json:"idx"`
+	Timestamp   time.Time `json:"timestamp"`
+	// FiringAlerts and ResolvedAlerts are the alerts of the group at the
+	// time of the notification.
+	FiringAlerts   []nflogAlert `json:"firingAlerts"`
+	ResolvedAlerts []nflogAlert `json:"resolvedAlerts"`
+}
+
+type nflogAlert struct {
+	// Hash is the hash of the alert's labels stored in the log.
+	Hash string `json:"hash"`
+	// Fingerprint is only set for alerts that are still known.
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// listNotificationLog returns the entries of the notification log, optionally
+// filtered by receiver and group key.
+func (api *API) listNotificationLog(w http.ResponseWriter, r *http.Request) {
+	var (
+		receiver  = r.FormValue("receiver")
+		groupKey  = r.FormValue("groupKey")
+		groupKeys = api.tenantGroupKeys(r)
+	)
+
+	// Map the hashes in the log to the fingerprints of the current alerts.
+	fps := map[uint64]model.Fingerprint{}
+	alerts := api.alerts.GetPending()
+	defer alerts.Close()
+
+	var err error
+	for a := range alerts.Next() {
+		if err = alerts.Err(); err != nil {
+			break
+		}
+		fps[notify.HashAlert(a)] = a.Fingerprint()
+	}
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	nflogAlerts := func(hashes []uint64) []nflogAlert {
+		res := make([]nflogAlert, 0, len(hashes))
+		for _, h := range hashes {
+			a := nflogAlert{Hash: fmt.Sprintf("%016x", h)}
+			if fp, ok := fps[h]; ok {
+				a.Fingerprint = fp.String()
 			}
+			res = append(res, a)
 		}
-		// If no end time is defined, set a timeout after which an alert
-		// is marked resolved if it is not updated.
-		if alert.EndsAt.IsZero() {
-			alert.Timeout = true
-			alert.EndsAt = now.Add(resolveTimeout)
+		return res
+	}
+
+	res := []*nflogEntry{}
+	for _, e := range api.nflog.Entries() {
+		if receiver != "" && e.Receiver.GroupName != receiver {
+			continue
 		}
-		if alert.EndsAt.After(time.Now()) {
-			numReceivedAlerts.WithLabelValues("firing").Inc()
-		} else {
-			numReceivedAlerts.WithLabelValues("resolved").Inc()
+		if groupKey != "" && string(e.GroupKey) != groupKey {
+			continue
+		}
+		if !groupKeys(string(e.GroupKey)) {
+			continue
+		}
+		res = append(res, &nflogEntry{
+			GroupKey:       string(e.GroupKey),
+			Receiver:       e.Receiver.GroupName,
+			Integration:    e.Receiver.Integration,
+			Idx:            e.Receiver.Idx,
+			Timestamp:      e.Timestamp,
+			FiringAlerts:   nflogAlerts(e.FiringAlerts),
+			ResolvedAlerts: nflogAlerts(e.ResolvedAlerts),
+		})
+	}
+
+	api.respond(w, res)
+}
+
+type routeTestResult struct {
+	Labels model.LabelSet    `json:"labels"`
+	Routes []*routeTestMatch `json:"routes"`
+}
+
+type routeTestMatch struct {
+	// Path lists the matchers of the routes from the root route to the
+	// matched route. Indices are the positions of the routes among the
+	// child routes of their parent, the root route being omitted.
+	Path        []string            `json:"path"`
+	Indices     []int               `json:"indices"`
+	RouteOpts   *dispatch.RouteOpts `json:"routeOpts"`
+	GroupLabels model.LabelSet      `json:"groupLabels"`
+}
+
+// testRoutes returns the routes of the current configuration that match one
+// or more label sets.
+func (api *API) testRoutes(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if err := api.receive(r, &raw); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	// Accept a single label set as well as a list of label sets.
+	var (
+		lsets []model.LabelSet
+		err   error
+	)
+	if b := bytes.TrimSpace(raw); len(b) > 0 && b[0] == '{' {
+		lsets = make([]model.LabelSet, 1)
+		err = json.Unmarshal(b, &lsets[0])
+	} else {
+		err = json.Unmarshal(raw, &lsets)
+	}
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	for _, lset := range lsets {
+		if err := lset.Validate(); err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+	}
+
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	res := make([]*routeTestResult, 0, len(lsets))
+	for _, lset := range lsets {
+		tr := &routeTestResult{Labels: lset}
+		for _, route := range api.route.Match(lset) {
+			m := &routeTestMatch{
+				Path:        []string{},
+				Indices:     []int{},
+				RouteOpts:   &route.RouteOpts,
+				GroupLabels: route.RouteOpts.GroupLabels(lset),
+			}
+			for _, pr := range route.Path() {
+				m.Path = append(m.Path, pr.Matchers.String())
+				if i := pr.Index(); i >= 0 {
+					m.Indices = append(m.Indices, i)
+				}
+			}
+			tr.Routes = append(tr.Routes, m)
+		}
+		res = append(res, tr)
+	}
+
+	api.respond(w, res)
+}
+
+type ackRequest struct {
+	CreatedBy string `json:"createdBy"`
+	Comment   string `json:"comment"`
+}
+
+// ackAlert acknowledges the current firing period of an alert.
+func (api *API) ackAlert(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.ParseFingerprint(route.Param(r.Context(), "fingerprint"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var req ackRequest
+	if err := api.receive(r, &req); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	alert, err := api.tenantAlerts(r).Get(fp)
+	if err != nil {
+		http.Error(w, fmt.Sprint("Error getting alert: ", err), http.StatusNotFound)
+		return
+	}
+	if alert.Resolved() {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("cannot acknowledge resolved alert"),
+		}, nil)
+		return
+	}
+
+	if err := api.acks.Ack(fp, alert.StartsAt, requestAuthor(r, req.CreatedBy), req.Comment); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, nil)
+}
+
+// unackAlert withdraws the acknowledgement of an alert.
+func (api *API) unackAlert(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.ParseFingerprint(route.Param(r.Context(), "fingerprint"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	if _, name := api.requestTenant(r); name != "" {
+		if _, err := api.tenantAlerts(r).Get(fp); err != nil {
+			http.Error(w, fmt.Sprint("Error getting alert: ", err), http.StatusNotFound)
+			return
+		}
+	}
+	if err := api.acks.Unack(fp); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, nil)
+}
+
+type annotationsUpdate struct {
+	// Annotations maps the names of annotations to their values. Null
+	// values remove annotations attached before.
+	Annotations map[model.LabelName]*string `json:"annotations"`
+	UpdatedBy   string                      `json:"updatedBy"`
+}
+
+// setAlertAnnotations attaches annotations to the current firing period
+// of an alert. It responds with the resulting annotations of the alert.
+func (api *API) setAlertAnnotations(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	annotations := api.annotations
+	api.mtx.RUnlock()
+
+	if annotations == nil {
+		http.Error(w, "Alert annotations not available", http.StatusNotFound)
+		return
+	}
+
+	fp, err := model.ParseFingerprint(route.Param(r.Context(), "fingerprint"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var req annotationsUpdate
+	if err := api.receive(r, &req); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	alert, err := api.tenantAlerts(r).Get(fp)
+	if err != nil {
+		http.Error(w, fmt.Sprint("Error getting alert: ", err), http.StatusNotFound)
+		return
+	}
+	if alert.Resolved() {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("cannot annotate resolved alert"),
+		}, nil)
+		return
+	}
+
+	if err := annotations.Set(fp, alert.StartsAt, requestAuthor(r, req.UpdatedBy), req.Annotations); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, annotations.Apply(&alert.Alert).Annotations)
+}
+
+// silenceAlert creates a silence matching exactly the labels of the alert
+// with the given fingerprint. The silence in the body must not have
+// matchers, its start time defaults to now.
+func (api *API) silenceAlert(w http.ResponseWriter, r *http.Request) {
+	fp, err := model.ParseFingerprint(route.Param(r.Context(), "fingerprint"))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	var sil types.Silence
+	if err := api.receive(r, &sil); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	sil.CreatedBy = requestAuthor(r, sil.CreatedBy)
+	if sil.ID != "" || len(sil.Matchers) > 0 {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: errors.New("silence of an alert must not have an ID or matchers"),
+		}, nil)
+		return
+	}
+
+	alert, err := api.tenantAlerts(r).Get(fp)
+	if err != nil {
+		http.Error(w, fmt.Sprint("Error getting alert: ", err), http.StatusNotFound)
+		return
+	}
+	sil.Matchers = matchersFromLabels(alert.Labels)
+	if sil.StartsAt.IsZero() {
+		sil.StartsAt = time.Now()
+	}
+
+	if err := validateSilenceTimes(&sil); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if !api.checkSilencePolicy(w, &sil) {
+		return
+	}
+	if err := api.silenceOwnershipError(r, "", sil.Owner); err != nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+
+	psil, err := silenceToProto(&sil)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	sid, err := api.silences.SetFrom(psil, silenceOrigin(r, ""))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.respond(w, struct {
+		SilenceID string `json:"silenceId"`
+	}{
+		SilenceID: sid,
+	})
+}
+
+// matchersFromLabels returns equality matchers on all labels of the label
+// set, sorted by label name.
+func matchersFromLabels(lset model.LabelSet) types.Matchers {
+	ms := make(types.Matchers, 0, len(lset))
+	for ln, lv := range lset {
+		ms = append(ms, &types.Matcher{Name: string(ln), Value: string(lv)})
+	}
+	sort.Sort(ms)
+	return ms
+}
+
+func (api *API) listAcks(w http.ResponseWriter, r *http.Request) {
+	acks := api.acks.List()
+	if _, name := api.requestTenant(r); name != "" {
+		alerts := api.tenantAlerts(r)
+		res := acks[:0]
+		for _, a := range acks {
+			fp, err := model.ParseFingerprint(a.Fingerprint)
+			if err != nil {
+				continue
+			}
+			if _, err := alerts.Get(fp); err == nil {
+				res = append(res, a)
+			}
+		}
+		acks = res
+	}
+	api.respond(w, acks)
+}
+
+func receiversMatchFilter(receivers []string, filter *regexp.Regexp) bool {
+	for _, r := range receivers {
+		if filter.MatchString(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Events of the event stream.
+const (
+	eventAlertAdded     = "alert_added"
+	eventAlertUpdated   = "alert_updated"
+	eventAlertResolved  = "alert_resolved"
+	eventSilenceCreated = "silence_created"
+	eventSilenceExpired = "silence_expired"
+)
+
+var (
+	// streamPollInterval is the interval at which streams check for
+	// silence changes and alerts resolved by their end time.
+	streamPollInterval = 5 * time.Second
+	// streamKeepAlive is the interval of the comments keeping idle streams
+	// open.
+	streamKeepAlive = 30 * time.Second
+	// streamQueueSize is the number of alert changes queued for a stream.
+	// Streams whose clients do not keep up are closed once it is full.
+	streamQueueSize = 1024
+	// streamWriteTimeout is the time after which writing an event to a
+	// stream fails.
+	streamWriteTimeout = 10 * time.Second
+)
+
+// queueAlerts forwards the alerts of the iterator to a queue of the given
+// size so that slow streams do not block the provider, which waits for
+// its subscribers while holding its lock. If the queue is full, the
+// returned overflow channel is closed. The iterator is closed on overflow
+// or once stop is closed.
+func queueAlerts(it provider.AlertIterator, size int, stop <-chan struct{}) (<-chan *types.Alert, <-chan struct{}) {
+	var (
+		queue    = make(chan *types.Alert, size)
+		overflow = make(chan struct{})
+	)
+	go func() {
+		defer func() {
+			close(queue)
+			it.Close()
+			// The provider removes the subscription after it was closed,
+			// which may block on pending alerts.
+			for range it.Next() {
+			}
+		}()
+		for {
+			select {
+			case a, ok := <-it.Next():
+				if !ok {
+					return
+				}
+				select {
+				case queue <- a:
+				default:
+					close(overflow)
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return queue, overflow
+}
+
+// streamEvents streams the changes of alerts and silences matching the
+// filter as server-sent events. The stream starts with an alert_added event
+// for each firing alert and a silence_created event for each silence that
+// is not expired.
+func (api *API) streamEvents(w http.ResponseWriter, r *http.Request) {
+	matchers := []*labels.Matcher{}
+	if filter := r.FormValue("filter"); filter != "" {
+		var err error
+		matchers, err = parse.Matchers(filter)
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+	}
+	matchers = api.tenantMatchers(r, matchers)
+	tenantLabel, tenantName := api.requestTenant(r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: errors.New("streaming is not supported"),
+		}, nil)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event string, data interface{}) bool {
+		b, err := json.Marshal(data)
+		if err != nil {
+			level.Error(api.logger).Log("msg", "Error marshalling JSON", "err", err)
+			return true
+		}
+		setWriteDeadline(w, time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	queue, overflow := queueAlerts(api.alerts.Subscribe(), streamQueueSize, stop)
+
+	var (
+		// The firing alerts sent, by their fingerprint.
+		firing = map[model.Fingerprint]*types.Alert{}
+		// The silences sent that are not expired.
+		silences = map[string]struct{}{}
+	)
+	sendAlert := func(event string, a *types.Alert) bool {
+		api.mtx.RLock()
+		routes := api.route.Match(a.Labels)
+		api.mtx.RUnlock()
+		receivers := make([]string, 0, len(routes))
+		for _, r := range routes {
+			receivers = append(receivers, r.RouteOpts.Receiver)
+		}
+		return send(event, &dispatch.APIAlert{
+			Alert:       &a.Alert,
+			Status:      api.getAlertStatus(a.Fingerprint()),
+			Receivers:   receivers,
+			Fingerprint: a.Fingerprint().String(),
+		})
+	}
+	checkSilences := func() bool {
+		psils, err := api.silences.Query()
+		if err != nil {
+			level.Error(api.logger).Log("msg", "Querying silences failed", "err", err)
+			return true
+		}
+		for _, ps := range psils {
+			s, err := silenceFromProto(ps)
+			if err != nil {
+				continue
+			}
+			if !silenceMatchesFilterLabels(s, matchers) {
+				continue
+			}
+			if tenantName != "" && silenceTenant(s, tenantLabel) != tenantName {
+				continue
+			}
+			_, known := silences[s.ID]
+			switch {
+			case !known && s.Status.State != types.SilenceStateExpired:
+				silences[s.ID] = struct{}{}
+				if !send(eventSilenceCreated, s) {
+					return false
+				}
+			case known && s.Status.State == types.SilenceStateExpired:
+				delete(silences, s.ID)
+				if !send(eventSilenceExpired, s) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if !checkSilences() {
+		return
+	}
+
+	poll := time.NewTicker(streamPollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case a, ok := <-queue:
+			if !ok {
+				return
+			}
+			if !alertMatchesFilterLabels(&a.Alert, matchers) {
+				continue
+			}
+			fp := a.Fingerprint()
+			prev, known := firing[fp]
+			event := ""
+			switch {
+			case a.Resolved() && known:
+				delete(firing, fp)
+				event = eventAlertResolved
+			case a.Resolved():
+			case !known:
+				firing[fp] = a
+				event = eventAlertAdded
+			default:
+				firing[fp] = a
+				// Repeated sends of alerts only extend their end.
+				if !a.StartsAt.Equal(prev.StartsAt) || !reflect.DeepEqual(a.Annotations, prev.Annotations) {
+					event = eventAlertUpdated
+				}
+			}
+			if event != "" && !sendAlert(event, a) {
+				return
+			}
+		case <-poll.C:
+			for fp, a := range firing {
+				if a.Resolved() {
+					delete(firing, fp)
+					if !sendAlert(eventAlertResolved, a) {
+						return
+					}
+				}
+			}
+			if !checkSilences() {
+				return
+			}
+		case <-overflow:
+			level.Debug(api.logger).Log("msg", "Closing stream of a client not keeping up")
+			return
+		case <-keepAlive.C:
+			setWriteDeadline(w, time.Now().Add(streamWriteTimeout))
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func alertMatchesFilterLabels(a *model.Alert, matchers []*labels.Matcher) bool {
+	sms := make(map[string]string)
+	for name, value := range a.Labels {
+		sms[string(name)] = string(value)
+	}
+	return matchFilterLabels(matchers, sms)
+}
+
+func (api *API) addAlerts(w http.ResponseWriter, r *http.Request) {
+	var alerts []*types.Alert
+	if err := api.receive(r, &alerts); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.insertAlerts(w, r, alerts...)
+}
+
+func (api *API) insertAlerts(w http.ResponseWriter, r *http.Request, alerts ...*types.Alert) {
+	_, span := tracing.Start(tracing.Extract(r.Context(), r.Header), "api.insert_alerts", tracing.AttrAlerts, strconv.Itoa(len(alerts)))
+	defer span.Finish()
+
+	now := time.Now()
+
+	api.mtx.RLock()
+	resolveTimeout := api.resolveTimeout
+	api.mtx.RUnlock()
+
+	// Alerts sent by tenants are labeled with their name.
+	tenantLabel, tenantName := api.requestTenant(r)
+
+	for _, alert := range alerts {
+		alert.UpdatedAt = now
+		if tenantName != "" {
+			if alert.Labels == nil {
+				alert.Labels = model.LabelSet{}
+			}
+			alert.Labels[tenantLabel] = model.LabelValue(tenantName)
+		}
+
+		// Ensure StartsAt is set.
+		if alert.StartsAt.IsZero() {
+			if alert.EndsAt.IsZero() {
+				alert.StartsAt = now
+			} else {
+				alert.StartsAt = alert.EndsAt
+			}
+		}
+		// If no end time is defined, set a timeout after which an alert
+		// is marked resolved if it is not updated.
+		if alert.EndsAt.IsZero() {
+			alert.Timeout = true
+			alert.EndsAt = now.Add(resolveTimeout)
+		}
+		if alert.EndsAt.After(time.Now()) {
+			numReceivedAlerts.WithLabelValues("firing").Inc()
+		} else {
+			numReceivedAlerts.WithLabelValues("resolved").Inc()
+		}
+	}
+
+	// Make a best effort to insert all alerts that are valid.
+	var (
+		validAlerts    = make([]*types.Alert, 0, len(alerts))
+		validationErrs = &types.MultiError{}
+	)
+	for _, a := range alerts {
+		removeEmptyLabels(a.Labels)
+
+		if err := a.Validate(); err != nil {
+			validationErrs.Add(err)
+			numInvalidAlerts.Inc()
+			continue
+		}
+		validAlerts = append(validAlerts, a)
+	}
+	if err := api.alerts.Put(validAlerts...); err != nil {
+		span.SetError(err)
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	if validationErrs.Len() > 0 {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: validationErrs,
+		}, nil)
+		return
+	}
+
+	api.respond(w, nil)
+}
+
+func removeEmptyLabels(ls model.LabelSet) {
+	for k, v := range ls {
+		if string(v) == "" {
+			delete(ls, k)
+		}
+	}
+}
+
+func (api *API) setSilence(w http.ResponseWriter, r *http.Request) {
+	var sil types.Silence
+	if err := api.receive(r, &sil); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	sil.CreatedBy = requestAuthor(r, sil.CreatedBy)
+
+	if err := validateSilenceTimes(&sil); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	if err := api.setSilenceTenant(r, &sil); err != nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+	if !api.checkSilencePolicy(w, &sil) {
+		return
+	}
+	if err := api.silenceOwnershipError(r, sil.ID, sil.Owner); err != nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+
+	psil, err := silenceToProto(&sil)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	sid, err := api.silences.SetFrom(psil, silenceOrigin(r, ""))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	res := struct {
+		SilenceID string `json:"silenceId"`
+	}{
+		SilenceID: sid,
+	}
+	// With quorum=true success is only reported once a quorum of the
+	// cluster acknowledged the silence. The silence is stored locally
+	// either way and its ID is returned with the error.
+	if r.FormValue("quorum") == "true" {
+		ctx, cancel := context.WithTimeout(r.Context(), silenceQuorumTimeout)
+		defer cancel()
+		if err := api.silences.Replicate(ctx, sid); err != nil {
+			api.respondError(w, apiError{
+				typ: errorInternal,
+				err: fmt.Errorf("silence was not acknowledged by a quorum of peers: %s", err),
+			}, res)
+			return
+		}
+	}
+
+	api.respond(w, res)
+}
+
+// silenceQuorumTimeout is how long silence writes with quorum=true wait for
+// the acknowledgement of the cluster.
+const silenceQuorumTimeout = 10 * time.Second
+
+// validateSilenceTimes returns an error if the time range of a silence set
+// through the API is of no use.
+func validateSilenceTimes(sil *types.Silence) error {
+	// This is an API only validation, it cannot be done internally
+	// because the expired silence is semantically important.
+	// But one should not be able to create expired silences, that
+	// won't have any use.
+	if sil.Expired() {
+		return errors.New("start time must not be equal to end time")
+	}
+	if sil.EndsAt.Before(time.Now()) {
+		return errors.New("end time can't be in the past")
+	}
+	return nil
+}
+
+// bulkSilenceResult is the result of a bulk operation for one silence.
+type bulkSilenceResult struct {
+	SilenceID string `json:"silenceId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// setSilences creates or updates many silences. The results are returned in
+// the order of the silences and failures do not affect other silences.
+func (api *API) setSilences(w http.ResponseWriter, r *http.Request) {
+	var sils []*types.Silence
+	if err := api.receive(r, &sils); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.mtx.RLock()
+	policy := api.config.SilencePolicy
+	api.mtx.RUnlock()
+
+	origin := silenceOrigin(r, "")
+	results := make([]bulkSilenceResult, 0, len(sils))
+	for _, sil := range sils {
+		if sil == nil {
+			results = append(results, bulkSilenceResult{Error: "missing silence"})
+			continue
+		}
+		// Failed updates report the ID of the silence to update.
+		res := bulkSilenceResult{SilenceID: sil.ID}
+		sil.CreatedBy = requestAuthor(r, sil.CreatedBy)
+		sid, err := api.setSilenceWithPolicy(r, sil, policy, origin)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.SilenceID = sid
+		}
+		results = append(results, res)
+	}
+
+	api.respond(w, results)
+}
+
+func (api *API) setSilenceWithPolicy(r *http.Request, sil *types.Silence, policy *config.SilencePolicy, o silence.Origin) (string, error) {
+	if err := validateSilenceTimes(sil); err != nil {
+		return "", err
+	}
+	if err := api.setSilenceTenant(r, sil); err != nil {
+		return "", err
+	}
+	if err := silencePolicyError(policy, sil, time.Now()); err != nil {
+		return "", err
+	}
+	if err := silenceOwnershipError(api.silences, policy, r, sil.ID, sil.Owner); err != nil {
+		return "", err
+	}
+	psil, err := silenceToProto(sil)
+	if err != nil {
+		return "", err
+	}
+	return api.silences.SetFrom(psil, o)
+}
+
+// expireSilencesRequest selects silences by ID, by a matcher filter like
+// the one of listSilences or both.
+type expireSilencesRequest struct {
+	IDs    []string `json:"ids"`
+	Filter string   `json:"filter"`
+}
+
+// expireSilences expires many silences. The results of expiring silences
+// by ID are returned first in the order of the IDs, followed by the results
+// for the active and pending silences matching the filter.
+func (api *API) expireSilences(w http.ResponseWriter, r *http.Request) {
+	var req expireSilencesRequest
+	if err := api.receive(r, &req); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if len(req.IDs) == 0 && req.Filter == "" {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: errors.New("no silence IDs or filter specified"),
+		}, nil)
+		return
+	}
+
+	ids := req.IDs
+	if req.Filter != "" {
+		matchers, err := parse.Matchers(req.Filter)
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+		psils, err := api.silences.Query(silence.QState(types.SilenceStateActive, types.SilenceStatePending))
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		var matching []string
+		for _, ps := range psils {
+			sil, err := silenceFromProto(ps)
+			if err != nil {
+				api.respondError(w, apiError{
+					typ: errorInternal,
+					err: err,
+				}, nil)
+				return
+			}
+			if silenceMatchesFilterLabels(sil, matchers) && api.inRequestTenant(r, sil) {
+				matching = append(matching, sil.ID)
+			}
+		}
+		sort.Strings(matching)
+		ids = append(ids[:len(ids):len(ids)], matching...)
+	}
+
+	api.mtx.RLock()
+	policy := api.config.SilencePolicy
+	api.mtx.RUnlock()
+
+	origin := silenceOrigin(r, r.FormValue("author"))
+	results := make([]bulkSilenceResult, 0, len(ids))
+	for _, id := range ids {
+		res := bulkSilenceResult{SilenceID: id}
+		err := api.silenceTenantError(r, id)
+		if err == nil {
+			err = silenceOwnershipError(api.silences, policy, r, id, "")
+		}
+		if err == nil {
+			err = api.silences.ExpireFrom(id, origin)
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+
+	api.respond(w, results)
+}
+
+func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
+	sid := route.Param(r.Context(), "sid")
+
+	sils, err := api.silences.Query(silence.QIDs(sid))
+	if err != nil || len(sils) == 0 {
+		http.Error(w, fmt.Sprint("Error getting silence: ", err), http.StatusNotFound)
+		return
+	}
+	sil, err := silenceFromProto(sils[0])
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	if !api.inRequestTenant(r, sil) {
+		http.Error(w, fmt.Sprint("Error getting silence: ", silence.ErrNotFound), http.StatusNotFound)
+		return
+	}
+
+	api.respond(w, sil)
+}
+
+// delSilence expires a silence. The optional author parameter is recorded
+// in the history of the silence.
+func (api *API) delSilence(w http.ResponseWriter, r *http.Request) {
+	sid := route.Param(r.Context(), "sid")
+
+	if err := api.silenceTenantError(r, sid); err != nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.silenceOwnershipError(r, sid, ""); err != nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.silences.ExpireFrom(sid, silenceOrigin(r, r.FormValue("author"))); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, nil)
+}
+
+func (api *API) getSilenceHistory(w http.ResponseWriter, r *http.Request) {
+	sid := route.Param(r.Context(), "sid")
+
+	sils, err := api.silences.Query(silence.QIDs(sid))
+	if err != nil || len(sils) == 0 {
+		http.Error(w, fmt.Sprint("Error getting silence: ", err), http.StatusNotFound)
+		return
+	}
+	if err := api.silenceTenantError(r, sid); err != nil {
+		http.Error(w, fmt.Sprint("Error getting silence: ", silence.ErrNotFound), http.StatusNotFound)
+		return
+	}
+	changes := make([]*types.SilenceChange, 0, len(sils[0].History))
+	for _, c := range sils[0].History {
+		change, err := silenceChangeFromProto(c)
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		changes = append(changes, change)
+	}
+
+	api.respond(w, changes)
+}
+
+func (api *API) addSilenceComment(w http.ResponseWriter, r *http.Request) {
+	sid := route.Param(r.Context(), "sid")
+
+	var c types.SilenceComment
+	if err := api.receive(r, &c); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	c.Author = requestAuthor(r, c.Author)
+	if c.Author == "" {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: errors.New("author missing"),
+		}, nil)
+		return
+	}
+
+	if err := api.silenceTenantError(r, sid); err != nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.silences.AddComment(sid, c.Author, c.Comment); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, nil)
+}
+
+// silenceExport is a portable document of silences that can be imported
+// into another Alertmanager.
+type silenceExport struct {
+	ExportedAt time.Time        `json:"exportedAt"`
+	Silences   []*types.Silence `json:"silences"`
+}
+
+// exportSilences returns all active and pending silences.
+func (api *API) exportSilences(w http.ResponseWriter, r *http.Request) {
+	psils, err := api.silences.Query(silence.QState(types.SilenceStateActive, types.SilenceStatePending))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	exp := silenceExport{
+		ExportedAt: time.Now(),
+		Silences:   make([]*types.Silence, 0, len(psils)),
+	}
+	for _, ps := range psils {
+		sil, err := silenceFromProto(ps)
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorInternal,
+				err: err,
+			}, nil)
+			return
+		}
+		if !api.inRequestTenant(r, sil) {
+			continue
+		}
+		exp.Silences = append(exp.Silences, sil)
+	}
+	sort.Slice(exp.Silences, func(i, j int) bool {
+		return exp.Silences[i].ID < exp.Silences[j].ID
+	})
+
+	api.respond(w, exp)
+}
+
+// How to handle imported silences that conflict with an existing silence,
+// which is a silence with the same ID or the same matchers.
+const (
+	// Keep the existing silence.
+	silenceImportSkip = "skip"
+	// Replace the existing silence with the imported one.
+	silenceImportReplace = "replace"
+	// Create the imported silence next to the existing one.
+	silenceImportDuplicate = "duplicate"
+)
+
+type silenceImportResult struct {
+	// The ID of the silence in the imported document.
+	ID string `json:"id"`
+	// The ID of the created, replaced or conflicting silence.
+	SilenceID string `json:"silenceId,omitempty"`
+	// One of created, replaced, skipped or failed.
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// importSilences creates the silences of a document returned by
+// exportSilences. Expired silences are skipped.
+func (api *API) importSilences(w http.ResponseWriter, r *http.Request) {
+	conflict := r.URL.Query().Get("conflict")
+	switch conflict {
+	case "":
+		conflict = silenceImportSkip
+	case silenceImportSkip, silenceImportReplace, silenceImportDuplicate:
+	default:
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: fmt.Errorf("invalid conflict parameter %q", conflict),
+		}, nil)
+		return
+	}
+
+	var exp silenceExport
+	if err := api.receive(r, &exp); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	existing, err := api.silences.Query(silence.QState(types.SilenceStateActive, types.SilenceStatePending))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.mtx.RLock()
+	policy := api.config.SilencePolicy
+	api.mtx.RUnlock()
+
+	origin := silenceOrigin(r, "")
+	results := make([]silenceImportResult, 0, len(exp.Silences))
+	for _, sil := range exp.Silences {
+		res := api.importSilence(r, sil, existing, conflict, policy, origin)
+		results = append(results, res)
+	}
+
+	api.respond(w, results)
+}
+
+func (api *API) importSilence(r *http.Request, sil *types.Silence, existing []*silencepb.Silence, conflict string, policy *config.SilencePolicy, o silence.Origin) silenceImportResult {
+	res := silenceImportResult{ID: sil.ID}
+	fail := func(err error) silenceImportResult {
+		res.Action = "failed"
+		res.Error = err.Error()
+		return res
+	}
+
+	now := time.Now()
+	if !sil.EndsAt.After(now) {
+		res.Action = "skipped"
+		res.Error = "silence has expired"
+		return res
+	}
+	if err := api.addSilenceTenant(r, sil); err != nil {
+		return fail(err)
+	}
+	if err := silencePolicyError(policy, sil, now); err != nil {
+		return fail(err)
+	}
+	psil, err := silenceToProto(sil)
+	if err != nil {
+		return fail(err)
+	}
+	psil.Id = ""
+
+	res.Action = "created"
+	if prev := conflictingSilence(psil, sil.ID, existing); prev != nil {
+		switch conflict {
+		case silenceImportSkip:
+			res.SilenceID = prev.Id
+			res.Action = "skipped"
+			return res
+		case silenceImportReplace:
+			psil.Id = prev.Id
+			res.Action = "replaced"
+		}
+	}
+	if err := api.silenceTenantError(r, psil.Id); err != nil {
+		return fail(err)
+	}
+	if err := silenceOwnershipError(api.silences, policy, r, psil.Id, psil.Owner); err != nil {
+		return fail(err)
+	}
+
+	sid, err := api.silences.SetFrom(psil, o)
+	if err != nil {
+		return fail(err)
+	}
+	res.SilenceID = sid
+	return res
+}
+
+// conflictingSilence returns the first of the existing silences with the
+// given ID or the same matchers as sil.
+func conflictingSilence(sil *silencepb.Silence, id string, existing []*silencepb.Silence) *silencepb.Silence {
+	key := matchersKey(sil.Matchers)
+	for _, e := range existing {
+		if (id != "" && e.Id == id) || matchersKey(e.Matchers) == key {
+			return e
+		}
+	}
+	return nil
+}
+
+// matchersKey returns a string identifying a set of matchers regardless of
+// their order.
+func matchersKey(ms []*silencepb.Matcher) string {
+	keys := make([]string, 0, len(ms))
+	for _, m := range ms {
+		keys = append(keys, fmt.Sprintf("%s/%d/%q", m.Name, m.Type, m.Pattern))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// silenceOrigin returns the origin of a modification of a silence requested
+// by r.
+func silenceOrigin(r *http.Request, author string) silence.Origin {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+	return silence.Origin{Author: requestAuthor(r, author), RemoteAddr: addr}
+}
+
+// requestAuthor returns the author of a change requested by r, which is the
+// identity authenticated by the web handler if any. Otherwise it is the
+// author given by the client.
+func requestAuthor(r *http.Request, author string) string {
+	if id, ok := web.IdentityFromContext(r.Context()); ok {
+		return id.ID
+	}
+	return author
+}
+
+func (api *API) listSilenceTemplates(w http.ResponseWriter, r *http.Request) {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	tmpls := api.config.SilenceTemplates
+	if tmpls == nil {
+		tmpls = []*config.SilenceTemplate{}
+	}
+	api.respond(w, tmpls)
+}
+
+type silenceTemplateRequest struct {
+	Parameters map[string]string `json:"parameters"`
+	CreatedBy  string            `json:"createdBy"`
+	// Comment replaces the comment of the template if set.
+	Comment string `json:"comment,omitempty"`
+	// StartsAt defaults to now and EndsAt to the duration of the template
+	// after StartsAt.
+	StartsAt time.Time `json:"startsAt,omitempty"`
+	EndsAt   time.Time `json:"endsAt,omitempty"`
+}
+
+// createSilenceFromTemplate creates a silence from a silence template of the
+// configuration with the given parameters.
+func (api *API) createSilenceFromTemplate(w http.ResponseWriter, r *http.Request) {
+	name := route.Param(r.Context(), "name")
+
+	var req silenceTemplateRequest
+	if err := api.receive(r, &req); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	req.CreatedBy = requestAuthor(r, req.CreatedBy)
+
+	var st *config.SilenceTemplate
+	api.mtx.RLock()
+	for _, t := range api.config.SilenceTemplates {
+		if t.Name == name {
+			st = t
+			break
+		}
+	}
+	api.mtx.RUnlock()
+
+	if st == nil {
+		http.Error(w, fmt.Sprintf("Silence template %q not found", name), http.StatusNotFound)
+		return
+	}
+
+	sil, err := silenceFromTemplate(st, &req, time.Now())
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.addSilenceTenant(r, sil); err != nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+	if !api.checkSilencePolicy(w, sil) {
+		return
+	}
+	if err := api.silenceOwnershipError(r, "", sil.Owner); err != nil {
+		api.respondError(w, apiError{
+			typ: errorForbidden,
+			err: err,
+		}, nil)
+		return
+	}
+	psil, err := silenceToProto(sil)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	sid, err := api.silences.SetFrom(psil, silenceOrigin(r, ""))
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+
+	api.respond(w, struct {
+		SilenceID string `json:"silenceId"`
+	}{
+		SilenceID: sid,
+	})
+}
+
+// silenceFromTemplate returns the silence of a template with its parameters
+// filled in.
+func silenceFromTemplate(st *config.SilenceTemplate, req *silenceTemplateRequest, now time.Time) (*types.Silence, error) {
+	params := req.Parameters
+	if params == nil {
+		params = map[string]string{}
+	}
+	for _, p := range st.Parameters {
+		if _, ok := params[p]; !ok {
+			return nil, fmt.Errorf("missing parameter %q", p)
+		}
+	}
+	expand := func(text string) (string, error) {
+		t, err := template.New("").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, params); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	sil := &types.Silence{
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		CreatedBy: req.CreatedBy,
+		Comment:   req.Comment,
+	}
+	if sil.StartsAt.IsZero() {
+		sil.StartsAt = now
+	}
+	if sil.EndsAt.IsZero() {
+		sil.EndsAt = sil.StartsAt.Add(time.Duration(st.Duration))
+	}
+	if sil.Comment == "" {
+		c, err := expand(st.Comment)
+		if err != nil {
+			return nil, err
+		}
+		sil.Comment = c
+	}
+
+	for _, m := range []struct {
+		values  map[string]string
+		isRegex bool
+	}{
+		{values: st.Match},
+		{values: st.MatchRE, isRegex: true},
+	} {
+		for k, v := range m.values {
+			value, err := expand(v)
+			if err != nil {
+				return nil, err
+			}
+			matcher := &types.Matcher{Name: k, Value: value, IsRegex: m.isRegex}
+			if err := matcher.Validate(); err != nil {
+				return nil, err
+			}
+			sil.Matchers = append(sil.Matchers, matcher)
+		}
+	}
+	sort.Sort(sil.Matchers)
+
+	return sil, nil
+}
+
+// silencePreview lists the alerts a silence would mute.
+type silencePreview struct {
+	Alerts []*dispatch.APIAlert `json:"alerts"`
+	// Receivers maps the receivers of the alerts to their number of alerts.
+	Receivers map[string]int `json:"receivers"`
+}
+
+// previewSilence returns the firing alerts that the silence in the request
+// would mute without creating it.
+func (api *API) previewSilence(w http.ResponseWriter, r *http.Request) {
+	var sil types.Silence
+	if err := api.receive(r, &sil); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if len(sil.Matchers) == 0 {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: errors.New("at least one matcher required"),
+		}, nil)
+		return
+	}
+	for _, m := range sil.Matchers {
+		err := m.Validate()
+		if err == nil {
+			err = m.Init()
+		}
+		if err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: err,
+			}, nil)
+			return
+		}
+	}
+
+	res := silencePreview{
+		Alerts:    []*dispatch.APIAlert{},
+		Receivers: map[string]int{},
+	}
+	alerts := api.tenantAlerts(r).GetPending()
+	defer alerts.Close()
+
+	api.mtx.RLock()
+	var err error
+	for a := range alerts.Next() {
+		if err = alerts.Err(); err != nil {
+			break
+		}
+		if a.Resolved() || !sil.Matchers.Match(a.Labels) {
+			continue
+		}
+
+		routes := api.route.Match(a.Labels)
+		receivers := make([]string, 0, len(routes))
+		for _, r := range routes {
+			receivers = append(receivers, r.RouteOpts.Receiver)
+			res.Receivers[r.RouteOpts.Receiver]++
+		}
+		res.Alerts = append(res.Alerts, &dispatch.APIAlert{
+			Alert:       &a.Alert,
+			Status:      api.getAlertStatus(a.Fingerprint()),
+			Receivers:   receivers,
+			Fingerprint: a.Fingerprint().String(),
+		})
+	}
+	api.mtx.RUnlock()
+
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	sort.Slice(res.Alerts, func(i, j int) bool {
+		return res.Alerts[i].Fingerprint < res.Alerts[j].Fingerprint
+	})
+	api.respond(w, res)
+}
+
+// silencePolicyViolation describes why a silence violates the silence
+// policy.
+type silencePolicyViolation struct {
+	// The violated rule, named like the option of the policy.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// checkSilencePolicy responds with the violations of the silence policy
+// and returns false if the silence violates it.
+func (api *API) checkSilencePolicy(w http.ResponseWriter, sil *types.Silence) bool {
+	api.mtx.RLock()
+	policy := api.config.SilencePolicy
+	api.mtx.RUnlock()
+
+	if policy == nil {
+		return true
+	}
+	violations := silencePolicyViolations(policy, sil, time.Now())
+	if len(violations) == 0 {
+		return true
+	}
+	api.respondError(w, apiError{
+		typ: errorBadData,
+		err: errors.New("silence violates the silence policy"),
+	}, struct {
+		Violations []silencePolicyViolation `json:"violations"`
+	}{
+		Violations: violations,
+	})
+	return false
+}
+
+// silencePolicyError returns an error listing the violations of the policy
+// by the silence, if any.
+func silencePolicyError(policy *config.SilencePolicy, sil *types.Silence, now time.Time) error {
+	if policy == nil {
+		return nil
+	}
+	violations := silencePolicyViolations(policy, sil, now)
+	if len(violations) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(violations))
+	for _, v := range violations {
+		msgs = append(msgs, v.Message)
+	}
+	return fmt.Errorf("silence violates the silence policy: %s", strings.Join(msgs, "; "))
+}
+
+// requestTenant returns the tenant label and the name of the tenant of the
+// request given by the tenant header. The name is empty without the header.
+func (api *API) requestTenant(r *http.Request) (model.LabelName, string) {
+	label, header := config.DefaultGlobalConfig.TenantLabel, config.DefaultGlobalConfig.TenantHeader
+	api.mtx.RLock()
+	if api.config != nil && api.config.Global != nil {
+		label, header = api.config.Global.TenantLabel, api.config.Global.TenantHeader
+	}
+	api.mtx.RUnlock()
+
+	if header == "" {
+		return label, ""
+	}
+	return label, r.Header.Get(header)
+}
+
+// tenantAlerts returns the alerts of the tenant of the request, or all
+// alerts if the request names no tenant.
+func (api *API) tenantAlerts(r *http.Request) provider.Alerts {
+	label, name := api.requestTenant(r)
+	if name == "" {
+		return api.alerts
+	}
+	return tenant.Only(api.alerts, label, name)
+}
+
+// tenantGroupKeys returns a function that returns true for the group keys of
+// the tenant of the request, or for all group keys if the request names no
+// tenant.
+func (api *API) tenantGroupKeys(r *http.Request) func(string) bool {
+	label, name := api.requestTenant(r)
+	if name == "" {
+		return func(string) bool { return true }
+	}
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	if api.config != nil {
+		for _, t := range api.config.Tenants {
+			if t.Name == name {
+				return tenant.GroupKeys(t, label)
+			}
+		}
+	}
+	return func(string) bool { return false }
+}
+
+// voiceReceiver returns the receiver with the given name of the tenant the
+// group key belongs to, or of the default configuration.
+func (api *API) voiceReceiver(groupKey, name string) *config.Receiver {
+	api.mtx.RLock()
+	defer api.mtx.RUnlock()
+
+	if api.config == nil {
+		return nil
+	}
+	label := config.DefaultGlobalConfig.TenantLabel
+	if api.config.Global != nil {
+		label = api.config.Global.TenantLabel
+	}
+	receivers := api.config.Receivers
+	for _, t := range api.config.Tenants {
+		if tenant.GroupKeys(t, label)(groupKey) {
+			receivers = t.Receivers
+			break
+		}
+	}
+	for _, rc := range receivers {
+		if rc.Name == name {
+			return rc
+		}
+	}
+	return nil
+}
+
+// tenantMatchers returns the matchers restricted to the alerts of the
+// tenant of the request, if any.
+func (api *API) tenantMatchers(r *http.Request, matchers []*labels.Matcher) []*labels.Matcher {
+	label, name := api.requestTenant(r)
+	if name == "" {
+		return matchers
+	}
+	m, _ := labels.NewMatcher(labels.MatchEqual, string(label), name)
+	return append(matchers, m)
+}
+
+// setSilenceTenant adds a matcher of the tenant of the request to the
+// silence, so that it only silences alerts of the tenant. It returns an error
+// if the silence or the silence it replaces belongs to another tenant.
+func (api *API) setSilenceTenant(r *http.Request, sil *types.Silence) error {
+	if err := api.addSilenceTenant(r, sil); err != nil {
+		return err
+	}
+	return api.silenceTenantError(r, sil.ID)
+}
+
+// addSilenceTenant adds a matcher of the tenant of the request to the
+// silence. It returns an error if the silence belongs to another tenant.
+func (api *API) addSilenceTenant(r *http.Request, sil *types.Silence) error {
+	label, name := api.requestTenant(r)
+	if name == "" {
+		return nil
+	}
+	switch t := silenceTenant(sil, label); t {
+	case name:
+	case "":
+		sil.Matchers = append(sil.Matchers, types.NewMatcher(label, name))
+	default:
+		return fmt.Errorf("silence of tenant %q cannot be set by tenant %q", t, name)
+	}
+	return nil
+}
+
+// silenceTenantError returns an error if the existing silence with the
+// given ID does not belong to the tenant of the request.
+func (api *API) silenceTenantError(r *http.Request, id string) error {
+	if id == "" {
+		return nil
+	}
+	_, name := api.requestTenant(r)
+	if name == "" {
+		return nil
+	}
+	psils, err := api.silences.Query(silence.QIDs(id))
+	if err != nil || len(psils) == 0 {
+		// Unknown silences are reported when modifying them.
+		return nil
+	}
+	sil, err := silenceFromProto(psils[0])
+	if err != nil {
+		return err
+	}
+	if !api.inRequestTenant(r, sil) {
+		return fmt.Errorf("silence %s does not belong to tenant %q", id, name)
+	}
+	return nil
+}
+
+// inRequestTenant returns whether the silence belongs to the tenant of the
+// request. Any silence does if the request names no tenant.
+func (api *API) inRequestTenant(r *http.Request, sil *types.Silence) bool {
+	label, name := api.requestTenant(r)
+	return name == "" || silenceTenant(sil, label) == name
+}
+
+// silenceTenant returns the tenant whose alerts the silence is restricted to
+// by an equality matcher of the tenant label or an empty string.
+func silenceTenant(sil *types.Silence, label model.LabelName) string {
+	for _, m := range sil.Matchers {
+		if m.Name == string(label) && !m.IsRegex && m.Operator == "" {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// silenceOwnershipError returns an error if the ownership policy forbids the
+// team of the request to set the silence with the given ID, if any, to the
+// given owner. Expiring a silence is checked with an empty owner.
+func (api *API) silenceOwnershipError(r *http.Request, id, owner string) error {
+	api.mtx.RLock()
+	policy := api.config.SilencePolicy
+	api.mtx.RUnlock()
+
+	return silenceOwnershipError(api.silences, policy, r, id, owner)
+}
+
+func silenceOwnershipError(silences *silence.Silences, policy *config.SilencePolicy, r *http.Request, id, owner string) error {
+	if policy == nil || policy.Ownership == nil {
+		return nil
+	}
+	teams := requestTeams(r, policy.Ownership)
+	for _, team := range teams {
+		if policy.Ownership.IsAdmin(team) {
+			return nil
+		}
+	}
+	if owner != "" && !containsString(teams, owner) {
+		return fmt.Errorf("silence cannot be owned by team %q other than the teams %q of the request", owner, teams)
+	}
+	if id == "" {
+		return nil
+	}
+	sils, err := silences.Query(silence.QIDs(id))
+	if err != nil || len(sils) == 0 {
+		// Unknown silences are reported when modifying them.
+		return nil
+	}
+	if prev := sils[0].Owner; prev != "" && !containsString(teams, prev) {
+		return fmt.Errorf("silence is owned by team %q", prev)
+	}
+	return nil
+}
+
+// requestTeams returns the teams of the request. The teams of requests
+// authenticated by the web handler are looked up by their identity, the
+// team header is only trusted for other requests.
+func requestTeams(r *http.Request, o *config.SilenceOwnership) []string {
+	if id, ok := web.IdentityFromContext(r.Context()); ok {
+		return o.TeamsOf(id.ID)
+	}
+	if o.TeamHeader == "" {
+		return nil
+	}
+	if team := r.Header.Get(o.TeamHeader); team != "" {
+		return []string{team}
+	}
+	return nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
 		}
 	}
+	return false
+}
 
-	// Make a best effort to insert all alerts that are valid.
-	var (
-		validAlerts    = make([]*types.Alert, 0, len(alerts))
-		validationErrs = &types.MultiError{}
-	)
-	for _, a := range alerts {
-		removeEmptyLabels(a.Labels)
+// silencePolicyViolations returns the violations of the policy by the
+// silence.
+func silencePolicyViolations(policy *config.SilencePolicy, sil *types.Silence, now time.Time) []silencePolicyViolation {
+	var violations []silencePolicyViolation
 
-		if err := a.Validate(); err != nil {
-			validationErrs.Add(err)
-			numInvalidAlerts.Inc()
-			continue
+	if policy.MaxDuration > 0 {
+		start := sil.StartsAt
+		if start.Before(now) {
+			start = now
+		}
+		if sil.EndsAt.Sub(start) > time.Duration(policy.MaxDuration) {
+			violations = append(violations, silencePolicyViolation{
+				Rule:    "max_duration",
+				Message: fmt.Sprintf("silence must not last longer than %s", policy.MaxDuration),
+			})
 		}
-		validAlerts = append(validAlerts, a)
 	}
-	if err := api.alerts.Put(validAlerts...); err != nil {
-		api.respondError(w, apiError{
-			typ: errorInternal,
-			err: err,
-		}, nil)
-		return
+
+	if policy.CommentPattern != "" {
+		re, err := regexp.Compile(policy.CommentPattern)
+		if err == nil && !re.MatchString(sil.Comment) {
+			violations = append(violations, silencePolicyViolation{
+				Rule:    "comment_pattern",
+				Message: fmt.Sprintf("comment must match %q", policy.CommentPattern),
+			})
+		}
 	}
 
-	if validationErrs.Len() > 0 {
-		api.respondError(w, apiError{
-			typ: errorBadData,
-			err: validationErrs,
-		}, nil)
-		return
+	if policy.Ownership != nil && policy.Ownership.RequireOwner && sil.Owner == "" {
+		violations = append(violations, silencePolicyViolation{
+			Rule:    "require_owner",
+			Message: "silence must have an owner",
+		})
 	}
 
-	api.respond(w, nil)
+	for _, banned := range policy.BannedMatchers {
+		if silenceWithinLabels(sil, banned) {
+			violations = append(violations, silencePolicyViolation{
+				Rule:    "banned_matchers",
+				Message: fmt.Sprintf("silence only matching %s is too broad", labelSetFromMap(banned)),
+			})
+			break
+		}
+	}
+
+	return violations
 }
 
-func removeEmptyLabels(ls model.LabelSet) {
-	for k, v := range ls {
-		if string(v) == "" {
-			delete(ls, k)
+// silenceWithinLabels returns true if all equality matchers of the silence
+// are among the labels. Regular expressions matching everything are
+// ignored as they do not restrict the silence.
+func silenceWithinLabels(sil *types.Silence, labels map[string]string) bool {
+	for _, m := range sil.Matchers {
+		if m.Operator != "" {
+			return false
+		}
+		if m.IsRegex {
+			if m.Value == ".*" {
+				continue
+			}
+			return false
+		}
+		if v, ok := labels[m.Name]; !ok || v != m.Value {
+			return false
 		}
 	}
+	return true
 }
 
-func (api *API) setSilence(w http.ResponseWriter, r *http.Request) {
-	var sil types.Silence
-	if err := api.receive(r, &sil); err != nil {
-		api.respondError(w, apiError{
-			typ: errorBadData,
-			err: err,
-		}, nil)
-		return
+func labelSetFromMap(m map[string]string) model.LabelSet {
+	ls := make(model.LabelSet, len(m))
+	for k, v := range m {
+		ls[model.LabelName(k)] = model.LabelValue(v)
 	}
+	return ls
+}
 
-	// This is an API only validation, it cannot be done internally
-	// because the expired silence is semantically important.
-	// But one should not be able to create expired silences, that
-	// won't have any use.
-	if sil.Expired() {
-		api.respondError(w, apiError{
-			typ: errorBadData,
-			err: errors.New("start time must not be equal to end time"),
-		}, nil)
-		return
+// silenceListQuery holds the filtering, sorting and pagination parameters
+// of the silences list.
+type silenceListQuery struct {
+	matchers  []*labels.Matcher
+	states    map[types.SilenceState]bool
+	createdBy string
+	// Lower case substring of the comment.
+	comment string
+
+	// One of startsAt, endsAt, updatedAt or createdBy. If empty, active
+	// silences are listed first, followed by pending and expired ones.
+	sortBy string
+	desc   bool
+
+	// A limit of 0 lists all silences.
+	limit, offset int
+}
+
+var silenceSortFields = map[string]func(a, b *types.Silence) bool{
+	"startsAt":  func(a, b *types.Silence) bool { return a.StartsAt.Before(b.StartsAt) },
+	"endsAt":    func(a, b *types.Silence) bool { return a.EndsAt.Before(b.EndsAt) },
+	"updatedAt": func(a, b *types.Silence) bool { return a.UpdatedAt.Before(b.UpdatedAt) },
+	"createdBy": func(a, b *types.Silence) bool { return a.CreatedBy < b.CreatedBy },
+}
+
+func parseSilenceListQuery(r *http.Request) (*silenceListQuery, error) {
+	q := &silenceListQuery{
+		createdBy: r.FormValue("createdBy"),
+		comment:   strings.ToLower(r.FormValue("comment")),
 	}
 
-	if sil.EndsAt.Before(time.Now()) {
-		api.respondError(w, apiError{
-			typ: errorBadData,
-			err: errors.New("end time can't be in the past"),
-		}, nil)
-		return
+	var err error
+	if filter := r.FormValue("filter"); filter != "" {
+		if q.matchers, err = parse.Matchers(filter); err != nil {
+			return nil, err
+		}
+	}
+	if states := r.FormValue("state"); states != "" {
+		q.states = map[types.SilenceState]bool{}
+		for _, st := range strings.Split(states, ",") {
+			switch st := types.SilenceState(st); st {
+			case types.SilenceStateActive, types.SilenceStatePending, types.SilenceStateExpired:
+				q.states[st] = true
+			default:
+				return nil, fmt.Errorf("invalid state parameter %q", st)
+			}
+		}
+	}
+	if sortBy := r.FormValue("sort"); sortBy != "" {
+		if strings.HasPrefix(sortBy, "-") {
+			q.desc = true
+			sortBy = sortBy[1:]
+		}
+		if _, ok := silenceSortFields[sortBy]; !ok {
+			return nil, fmt.Errorf("invalid sort parameter %q", sortBy)
+		}
+		q.sortBy = sortBy
+	}
+	if s := r.FormValue("limit"); s != "" {
+		if q.limit, err = strconv.Atoi(s); err != nil || q.limit < 0 {
+			return nil, fmt.Errorf("invalid limit parameter %q", s)
+		}
+	}
+	if s := r.FormValue("offset"); s != "" {
+		if q.offset, err = strconv.Atoi(s); err != nil || q.offset < 0 {
+			return nil, fmt.Errorf("invalid offset parameter %q", s)
+		}
 	}
+	return q, nil
+}
 
-	psil, err := silenceToProto(&sil)
-	if err != nil {
-		api.respondError(w, apiError{
-			typ: errorBadData,
-			err: err,
-		}, nil)
-		return
+// matches returns true if the silence passes all filters of the query.
+func (q *silenceListQuery) matches(s *types.Silence) bool {
+	if q.states != nil && !q.states[s.Status.State] {
+		return false
+	}
+	if q.createdBy != "" && s.CreatedBy != q.createdBy {
+		return false
 	}
+	if q.comment != "" && !strings.Contains(strings.ToLower(s.Comment), q.comment) {
+		return false
+	}
+	return silenceMatchesFilterLabels(s, q.matchers)
+}
 
-	sid, err := api.silences.Set(psil)
-	if err != nil {
-		api.respondError(w, apiError{
-			typ: errorBadData,
-			err: err,
-		}, nil)
+// sort sorts the silences by the field of the query. Silences with equal
+// values keep their order.
+func (q *silenceListQuery) sort(sils []*types.Silence) {
+	if q.sortBy == "" {
 		return
 	}
-
-	api.respond(w, struct {
-		SilenceID string `json:"silenceId"`
-	}{
-		SilenceID: sid,
+	less := silenceSortFields[q.sortBy]
+	sort.SliceStable(sils, func(i, j int) bool {
+		if q.desc {
+			return less(sils[j], sils[i])
+		}
+		return less(sils[i], sils[j])
 	})
 }
 
-func (api *API) getSilence(w http.ResponseWriter, r *http.Request) {
-	sid := route.Param(r.Context(), "sid")
-
-	sils, err := api.silences.Query(silence.QIDs(sid))
-	if err != nil || len(sils) == 0 {
-		http.Error(w, fmt.Sprint("Error getting silence: ", err), http.StatusNotFound)
-		return
+// page returns the page of the silences selected by the query.
+func (q *silenceListQuery) page(sils []*types.Silence) []*types.Silence {
+	if q.offset >= len(sils) {
+		return []*types.Silence{}
 	}
-	sil, err := silenceFromProto(sils[0])
-	if err != nil {
-		api.respondError(w, apiError{
-			typ: errorInternal,
-			err: err,
-		}, nil)
-		return
+	sils = sils[q.offset:]
+	if q.limit > 0 && q.limit < len(sils) {
+		sils = sils[:q.limit]
 	}
-
-	api.respond(w, sil)
+	return sils
 }
 
-func (api *API) delSilence(w http.ResponseWriter, r *http.Request) {
-	sid := route.Param(r.Context(), "sid")
-
-	if err := api.silences.Expire(sid); err != nil {
+// listSilences lists the silences matching the filters of the request. The
+// total number of matching silences is returned in the X-Total-Count header
+// if the list is paginated.
+func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
+	q, err := parseSilenceListQuery(r)
+	if err != nil {
 		api.respondError(w, apiError{
 			typ: errorBadData,
 			err: err,
 		}, nil)
 		return
 	}
-	api.respond(w, nil)
-}
 
-func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
 	psils, err := api.silences.Query()
 	if err != nil {
 		api.respondError(w, apiError{
@@ -611,18 +3383,6 @@ func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	matchers := []*labels.Matcher{}
-	if filter := r.FormValue("filter"); filter != "" {
-		matchers, err = parse.Matchers(filter)
-		if err != nil {
-			api.respondError(w, apiError{
-				typ: errorBadData,
-				err: err,
-			}, nil)
-			return
-		}
-	}
-
 	sils := []*types.Silence{}
 	for _, ps := range psils {
 		s, err := silenceFromProto(ps)
@@ -634,7 +3394,10 @@ func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if !silenceMatchesFilterLabels(s, matchers) {
+		if !q.matches(s) {
+			continue
+		}
+		if !api.inRequestTenant(r, s) {
 			continue
 		}
 		sils = append(sils, s)
@@ -670,6 +3433,12 @@ func (api *API) listSilences(w http.ResponseWriter, r *http.Request) {
 	silences = append(silences, pending...)
 	silences = append(silences, expired...)
 
+	q.sort(silences)
+	if q.limit > 0 || q.offset > 0 {
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(silences)))
+		silences = q.page(silences)
+	}
+
 	api.respond(w, silences)
 }
 
@@ -686,6 +3455,10 @@ func matchFilterLabels(matchers []*labels.Matcher, sms map[string]string) bool {
 	for _, m := range matchers {
 		v, prs := sms[m.Name]
 		switch m.Type {
+		case labels.MatchAbsent, labels.MatchPresent:
+			if !m.Matches(v) {
+				return false
+			}
 		case labels.MatchNotRegexp, labels.MatchNotEqual:
 			if string(m.Value) == "" && prs {
 				continue
@@ -706,6 +3479,17 @@ func matchFilterLabels(matchers []*labels.Matcher, sms map[string]string) bool {
 	return true
 }
 
+// silenceMatcherTypes maps the operators of matchers to the matcher types of
+// silences.
+var silenceMatcherTypes = map[types.MatchOperator]silencepb.Matcher_Type{
+	types.MatchAbsent:       silencepb.Matcher_ABSENT,
+	types.MatchPresent:      silencepb.Matcher_PRESENT,
+	types.MatchLessThan:     silencepb.Matcher_LESS_THAN,
+	types.MatchLessEqual:    silencepb.Matcher_LESS_EQUAL,
+	types.MatchGreaterThan:  silencepb.Matcher_GREATER_THAN,
+	types.MatchGreaterEqual: silencepb.Matcher_GREATER_EQUAL,
+}
+
 func silenceToProto(s *types.Silence) (*silencepb.Silence, error) {
 	sil := &silencepb.Silence{
 		Id:        s.ID,
@@ -714,6 +3498,17 @@ func silenceToProto(s *types.Silence) (*silencepb.Silence, error) {
 		UpdatedAt: s.UpdatedAt,
 		Comment:   s.Comment,
 		CreatedBy: s.CreatedBy,
+		Owner:     s.Owner,
+	}
+	if s.Schedule != nil {
+		sil.Schedule = &silencepb.Schedule{
+			Cron:     s.Schedule.Cron,
+			Duration: time.Duration(s.Schedule.Duration),
+			TimeZone: s.Schedule.TimeZone,
+		}
+		if _, err := silence.ParseSchedule(sil.Schedule); err != nil {
+			return nil, fmt.Errorf("invalid schedule: %s", err)
+		}
 	}
 	for _, m := range s.Matchers {
 		matcher := &silencepb.Matcher{
@@ -724,8 +3519,22 @@ func silenceToProto(s *types.Silence) (*silencepb.Silence, error) {
 		if m.IsRegex {
 			matcher.Type = silencepb.Matcher_REGEXP
 		}
+		if m.Operator != "" {
+			t, ok := silenceMatcherTypes[m.Operator]
+			if !ok {
+				return nil, fmt.Errorf("unknown matcher operator %q", m.Operator)
+			}
+			matcher.Type = t
+		}
 		sil.Matchers = append(sil.Matchers, matcher)
 	}
+	for _, c := range s.Thread {
+		sil.Thread = append(sil.Thread, &silencepb.Comment{
+			Author:    c.Author,
+			Comment:   c.Comment,
+			Timestamp: c.Timestamp,
+		})
+	}
 	return sil, nil
 }
 
@@ -740,6 +3549,19 @@ func silenceFromProto(s *silencepb.Silence) (*types.Silence, error) {
 		},
 		Comment:   s.Comment,
 		CreatedBy: s.CreatedBy,
+		Owner:     s.Owner,
+	}
+	if s.Schedule != nil {
+		sil.Schedule = &types.SilenceSchedule{
+			Cron:     s.Schedule.Cron,
+			Duration: model.Duration(s.Schedule.Duration),
+			TimeZone: s.Schedule.TimeZone,
+		}
+		now := time.Now()
+		sil.Status.State = silence.State(s, now)
+		if next, ok := silence.NextActivation(s, now); ok {
+			sil.Status.NextActivation = &next
+		}
 	}
 	for _, m := range s.Matchers {
 		matcher := &types.Matcher{
@@ -751,14 +3573,183 @@ func silenceFromProto(s *silencepb.Silence) (*types.Silence, error) {
 		case silencepb.Matcher_REGEXP:
 			matcher.IsRegex = true
 		default:
-			return nil, fmt.Errorf("unknown matcher type")
+			for op, t := range silenceMatcherTypes {
+				if t == m.Type {
+					matcher.Operator = op
+				}
+			}
+			if matcher.Operator == "" {
+				return nil, fmt.Errorf("unknown matcher type")
+			}
 		}
 		sil.Matchers = append(sil.Matchers, matcher)
 	}
+	for _, c := range s.Thread {
+		sil.Thread = append(sil.Thread, &types.SilenceComment{
+			Author:    c.Author,
+			Comment:   c.Comment,
+			Timestamp: c.Timestamp,
+		})
+	}
 
 	return sil, nil
 }
 
+func silenceChangeFromProto(c *silencepb.SilenceChange) (*types.SilenceChange, error) {
+	change := &types.SilenceChange{
+		Timestamp:  c.Timestamp,
+		Author:     c.Author,
+		RemoteAddr: c.RemoteAddr,
+	}
+	switch c.Type {
+	case silencepb.SilenceChange_CREATED:
+		change.Type = types.SilenceChangeCreated
+	case silencepb.SilenceChange_UPDATED:
+		change.Type = types.SilenceChangeUpdated
+	case silencepb.SilenceChange_EXPIRED:
+		change.Type = types.SilenceChangeExpired
+	default:
+		return nil, fmt.Errorf("unknown silence change type")
+	}
+	if c.Previous != nil {
+		prev, err := silenceFromProto(c.Previous)
+		if err != nil {
+			return nil, err
+		}
+		change.Previous = prev
+	}
+	return change, nil
+}
+
+func (api *API) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	groupKeys := api.tenantGroupKeys(r)
+	res := []*dlq.Entry{}
+	for _, e := range api.deadLetters.List() {
+		if groupKeys(e.GroupKey) {
+			res = append(res, e.Redacted())
+		}
+	}
+	api.respond(w, res)
+}
+
+// tenantDeadLetter returns the dead letter with the given ID if it belongs to
+// the tenant of the request.
+func (api *API) tenantDeadLetter(r *http.Request, id string) (*dlq.Entry, error) {
+	e, err := api.deadLetters.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !api.tenantGroupKeys(r)(e.GroupKey) {
+		return nil, dlq.ErrNotFound
+	}
+	return e, nil
+}
+
+func (api *API) getDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(r.Context(), "id")
+
+	e, err := api.tenantDeadLetter(r, id)
+	if err != nil {
+		http.Error(w, fmt.Sprint("Error getting dead letter: ", err), http.StatusNotFound)
+		return
+	}
+	api.respond(w, e.Redacted())
+}
+
+func (api *API) delDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(r.Context(), "id")
+
+	if _, err := api.tenantDeadLetter(r, id); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.deadLetters.Delete(id); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, nil)
+}
+
+func (api *API) replayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := route.Param(r.Context(), "id")
+
+	if _, err := api.tenantDeadLetter(r, id); err != nil {
+		api.respondError(w, apiError{
+			typ: errorBadData,
+			err: err,
+		}, nil)
+		return
+	}
+	if err := api.deadLetters.Replay(r.Context(), id); err != nil {
+		typ := errorInternal
+		if err == dlq.ErrNotFound {
+			typ = errorBadData
+		}
+		api.respondError(w, apiError{
+			typ: typ,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, nil)
+}
+
+func (api *API) queryAuditLog(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	q := audit.Query{
+		Receiver:    params.Get("receiver"),
+		Integration: params.Get("integration"),
+		GroupKey:    params.Get("groupKey"),
+		GroupKeys:   api.tenantGroupKeys(r),
+		Fingerprint: params.Get("fingerprint"),
+	}
+
+	var err error
+	if s := params.Get("since"); s != "" {
+		if q.Since, err = time.Parse(time.RFC3339, s); err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid since parameter: %s", err),
+			}, nil)
+			return
+		}
+	}
+	if s := params.Get("until"); s != "" {
+		if q.Until, err = time.Parse(time.RFC3339, s); err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid until parameter: %s", err),
+			}, nil)
+			return
+		}
+	}
+	if s := params.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil {
+			api.respondError(w, apiError{
+				typ: errorBadData,
+				err: fmt.Errorf("invalid limit parameter: %s", err),
+			}, nil)
+			return
+		}
+	}
+
+	entries, err := api.auditLog.Query(q)
+	if err != nil {
+		api.respondError(w, apiError{
+			typ: errorInternal,
+			err: err,
+		}, nil)
+		return
+	}
+	api.respond(w, entries)
+}
+
 type status string
 
 const (
@@ -796,6 +3787,8 @@ func (api *API) respondError(w http.ResponseWriter, apiErr apiError, data interf
 		w.WriteHeader(http.StatusBadRequest)
 	case errorInternal:
 		w.WriteHeader(http.StatusInternalServerError)
+	case errorForbidden:
+		w.WriteHeader(http.StatusForbidden)
 	default:
 		panic(fmt.Sprintf("unknown error type %q", apiErr.Error()))
 	}
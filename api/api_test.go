@@ -14,23 +14,48 @@
 package api
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/annotation"
+	"github.com/prometheus/alertmanager/audit"
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/dlq"
+	"github.com/prometheus/alertmanager/history"
+	"github.com/prometheus/alertmanager/inhibit"
+	"github.com/prometheus/alertmanager/nflog"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pkg/confighistory"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/pkg/web"
 	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/silence"
+	"github.com/prometheus/alertmanager/silence/silencepb"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/common/route"
 	"github.com/stretchr/testify/require"
 )
 
@@ -56,8 +81,13 @@ func newFakeAlerts(alerts []*types.Alert, withErr bool) *fakeAlerts {
 	return f
 }
 
-func (f *fakeAlerts) Subscribe() provider.AlertIterator           { return nil }
-func (f *fakeAlerts) Get(model.Fingerprint) (*types.Alert, error) { return nil, nil }
+func (f *fakeAlerts) Subscribe() provider.AlertIterator { return nil }
+func (f *fakeAlerts) Get(fp model.Fingerprint) (*types.Alert, error) {
+	if i, ok := f.fps[fp]; ok {
+		return f.alerts[i], nil
+	}
+	return nil, provider.ErrNotFound
+}
 func (f *fakeAlerts) Put(alerts ...*types.Alert) error {
 	return f.err
 }
@@ -133,7 +163,7 @@ func TestAddAlerts(t *testing.T) {
 		}
 
 		alertsProvider := newFakeAlerts([]*types.Alert{}, tc.err)
-		api := New(alertsProvider, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil)
+		api := New(alertsProvider, nil, nil, nil, nil, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
 
 		r, err := http.NewRequest("POST", "/api/v1/alerts", bytes.NewReader(b))
 		w := httptest.NewRecorder()
@@ -240,6 +270,24 @@ func TestListAlerts(t *testing.T) {
 			400,
 			[]string{},
 		},
+		{
+			false,
+			map[string]string{"filter": `alertname=alert1 or alertname="alert3"`},
+			200,
+			[]string{"alert1", "alert3"},
+		},
+		{
+			false,
+			map[string]string{"filter": "not silenced and startsAt > now-2m"},
+			200,
+			[]string{"alert1", "alert2", "alert4"},
+		},
+		{
+			false,
+			map[string]string{"filter": "alertname=alert1 or"},
+			400,
+			[]string{},
+		},
 		{
 			false,
 			map[string]string{"receiver": "other"},
@@ -260,7 +308,7 @@ func TestListAlerts(t *testing.T) {
 		},
 	} {
 		alertsProvider := newFakeAlerts(alerts, tc.err)
-		api := New(alertsProvider, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil)
+		api := New(alertsProvider, nil, nil, nil, nil, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
 		api.route = dispatch.NewRoute(&config.Route{Receiver: "def-receiver"}, nil)
 
 		r, err := http.NewRequest("GET", "/api/v1/alerts", nil)
@@ -310,216 +358,1562 @@ func TestListAlerts(t *testing.T) {
 	}
 }
 
-func TestAlertFiltering(t *testing.T) {
-	type test struct {
-		alert    *model.Alert
-		msg      string
-		expected bool
+func TestListAlertsPagination(t *testing.T) {
+	now := time.Now()
+	var alerts []*types.Alert
+	for i, sev := range []string{"warning", "critical", "", "info", "critical"} {
+		alerts = append(alerts, &types.Alert{Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": model.LabelValue(fmt.Sprintf("alert%d", i)), "severity": model.LabelValue(sev)},
+			StartsAt: now.Add(-time.Duration(i) * time.Minute),
+		}})
 	}
+	alertsProvider := newFakeAlerts(alerts, false)
+	api := New(alertsProvider, nil, nil, nil, nil, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	api.route = dispatch.NewRoute(&config.Route{Receiver: "def-receiver"}, nil)
 
-	// Equal
-	equal, err := labels.NewMatcher(labels.MatchEqual, "label1", "test1")
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
-	}
+	list := func(params url.Values) (*httptest.ResponseRecorder, []string) {
+		r, err := http.NewRequest("GET", "/api/v1/alerts?"+params.Encode(), nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		api.listAlerts(w, r)
 
-	tests := []test{
-		{&model.Alert{Labels: model.LabelSet{"label1": "test1"}}, "label1=test1", true},
-		{&model.Alert{Labels: model.LabelSet{"label1": "test2"}}, "label1=test2", false},
-		{&model.Alert{Labels: model.LabelSet{"label2": "test2"}}, "label2=test2", false},
+		var res struct {
+			Data []*dispatch.APIAlert `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		names := []string{}
+		for _, a := range res.Data {
+			names = append(names, string(a.Labels["alertname"]))
+		}
+		return w, names
 	}
-
-	for _, test := range tests {
-		actual := alertMatchesFilterLabels(test.alert, []*labels.Matcher{equal})
-		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
-		require.Equal(t, test.expected, actual, msg)
+	// pages returns the alert names of all pages.
+	pages := func(sortBy string, limit int) [][]string {
+		var (
+			res    [][]string
+			cursor string
+		)
+		for {
+			params := url.Values{"sort": {sortBy}, "limit": {strconv.Itoa(limit)}}
+			if cursor != "" {
+				params.Set("cursor", cursor)
+			}
+			w, names := list(params)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			require.Equal(t, "5", w.Header().Get("X-Total-Count"))
+			res = append(res, names)
+			if cursor = w.Header().Get("X-Next-Cursor"); cursor == "" {
+				return res
+			}
+		}
 	}
 
-	// Not Equal
-	notEqual, err := labels.NewMatcher(labels.MatchNotEqual, "label1", "test1")
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
-	}
+	require.Equal(t, [][]string{
+		{"alert4", "alert3"},
+		{"alert2", "alert1"},
+		{"alert0"},
+	}, pages("startsAt", 2))
+	require.Equal(t, [][]string{
+		{"alert0", "alert1", "alert2"},
+		{"alert3", "alert4"},
+	}, pages("-startsAt", 3))
 
-	tests = []test{
-		{&model.Alert{Labels: model.LabelSet{"label1": "test1"}}, "label1!=test1", false},
-		{&model.Alert{Labels: model.LabelSet{"label1": "test2"}}, "label1!=test2", true},
-		{&model.Alert{Labels: model.LabelSet{"label2": "test2"}}, "label2!=test2", true},
-	}
+	sev := pages("severity", 2)
+	require.Len(t, sev, 3)
+	sort.Strings(sev[0])
+	require.Equal(t, []string{"alert1", "alert4"}, sev[0])
+	require.Equal(t, []string{"alert0", "alert3"}, sev[1])
+	require.Equal(t, []string{"alert2"}, sev[2])
 
-	for _, test := range tests {
-		actual := alertMatchesFilterLabels(test.alert, []*labels.Matcher{notEqual})
-		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
-		require.Equal(t, test.expected, actual, msg)
-	}
+	// Without pagination, all alerts are returned without the headers.
+	w, names := list(url.Values{"sort": {"-severity"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, names, 5)
+	require.Equal(t, "alert2", names[0])
+	require.Equal(t, "", w.Header().Get("X-Total-Count"))
 
-	// Regexp Equal
-	regexpEqual, err := labels.NewMatcher(labels.MatchRegexp, "label1", "tes.*")
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
+	for _, params := range []url.Values{
+		{"sort": {"labels"}},
+		{"limit": {"-1"}},
+		{"cursor": {"%%"}},
+		// Cursors are only valid for the sort order they were created for.
+		{"sort": {"severity"}, "cursor": {base64.RawURLEncoding.EncodeToString([]byte("startsAt:x"))}},
+	} {
+		w, _ := list(params)
+		require.Equal(t, http.StatusBadRequest, w.Code, params.Encode())
 	}
+}
 
-	tests = []test{
-		{&model.Alert{Labels: model.LabelSet{"label1": "test1"}}, "label1=~test1", true},
-		{&model.Alert{Labels: model.LabelSet{"label1": "test2"}}, "label1=~test2", true},
-		{&model.Alert{Labels: model.LabelSet{"label2": "test2"}}, "label2=~test2", false},
-	}
+func TestAlertNotifications(t *testing.T) {
+	// The alert is not known anymore.
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+	alertsProvider := newFakeAlerts(nil, false)
 
-	for _, test := range tests {
-		actual := alertMatchesFilterLabels(test.alert, []*labels.Matcher{regexpEqual})
-		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
-		require.Equal(t, test.expected, actual, msg)
+	nl, err := nflog.New()
+	require.NoError(t, err)
+	recv := &nflogpb.Receiver{GroupName: "team-X", Integration: "webhook"}
+	require.NoError(t, nl.Log(recv, "{}:{alertname=\"a\"}", []uint64{notify.HashAlert(a)}, nil, []uint64{uint64(a.Fingerprint())}, nil, 0))
+
+	api := New(alertsProvider, nil, nil, nil, nil, nl, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	for _, tc := range []struct {
+		fp   string
+		code int
+		n    int
+	}{
+		{a.Fingerprint().String(), http.StatusOK, 1},
+		{"0000000000000001", http.StatusOK, 0},
+		{"invalid", http.StatusBadRequest, 0},
+	} {
+		r, err := http.NewRequest("GET", "/api/v1/alert/"+tc.fp+"/notifications", nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, tc.code, w.Code)
+		if tc.code != http.StatusOK {
+			continue
+		}
+
+		var res struct {
+			Data []alertNotification `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res.Data, tc.n)
+		if tc.n == 0 {
+			continue
+		}
+		require.Equal(t, "team-X", res.Data[0].Receiver)
+		require.Equal(t, "webhook", res.Data[0].Integration)
+		require.Equal(t, model.AlertFiring, res.Data[0].Status)
 	}
+}
 
-	// Regexp Not Equal
-	regexpNotEqual, err := labels.NewMatcher(labels.MatchNotRegexp, "label1", "tes.*")
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
+func TestInhibitions(t *testing.T) {
+	var (
+		source = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "ClusterDown", "severity": "critical"}}}
+		target = &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "HighLatency", "severity": "warning"}}}
+	)
+	alertsProvider := newFakeAlerts([]*types.Alert{source, target}, false)
+	inhibitors := func(lset model.LabelSet) []inhibit.Inhibition {
+		if lset["severity"] == "warning" {
+			return []inhibit.Inhibition{{Rule: 0, Source: source}}
+		}
+		return nil
 	}
 
-	tests = []test{
-		{&model.Alert{Labels: model.LabelSet{"label1": "test1"}}, "label1!~test1", false},
-		{&model.Alert{Labels: model.LabelSet{"label1": "test2"}}, "label1!~test2", false},
-		{&model.Alert{Labels: model.LabelSet{"label2": "test2"}}, "label2!~test2", true},
+	api := New(alertsProvider, nil, nil, nil, nil, nil, groupAlerts, newGetAlertStatus(alertsProvider), inhibitors, nil, nil)
+	conf, err := config.Load(`
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+inhibit_rules:
+- source_match:
+    severity: critical
+  target_match:
+    severity: warning
+`)
+	require.NoError(t, err)
+	require.NoError(t, api.Update(conf, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	expected := &inhibition{
+		RuleIndex: 0,
+		Rule:      conf.InhibitRules[0],
+		Source:    inhibitionAlert{Fingerprint: source.Fingerprint().String(), Labels: source.Labels},
+		Target:    inhibitionAlert{Fingerprint: target.Fingerprint().String(), Labels: target.Labels},
 	}
+	for _, tc := range []struct {
+		path     string
+		code     int
+		expected []*inhibition
+	}{
+		{"/api/v1/alert/" + target.Fingerprint().String() + "/inhibitors", http.StatusOK, []*inhibition{expected}},
+		{"/api/v1/alert/" + source.Fingerprint().String() + "/inhibitors", http.StatusOK, []*inhibition{}},
+		{"/api/v1/alert/0000000000000001/inhibitors", http.StatusNotFound, nil},
+		{"/api/v1/alert/invalid/inhibitors", http.StatusBadRequest, nil},
+		{"/api/v1/inhibitions", http.StatusOK, []*inhibition{expected}},
+	} {
+		r, err := http.NewRequest("GET", tc.path, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, tc.code, w.Code, tc.path)
+		if tc.code != http.StatusOK {
+			continue
+		}
 
-	for _, test := range tests {
-		actual := alertMatchesFilterLabels(test.alert, []*labels.Matcher{regexpNotEqual})
-		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
-		require.Equal(t, test.expected, actual, msg)
+		b, err := json.Marshal(tc.expected)
+		require.NoError(t, err)
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.JSONEq(t, string(b), string(res.Data), tc.path)
 	}
 }
 
-func TestSilenceFiltering(t *testing.T) {
-	type test struct {
-		silence  *types.Silence
-		msg      string
-		expected bool
-	}
+func TestListNotificationLog(t *testing.T) {
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+	alertsProvider := newFakeAlerts([]*types.Alert{a}, false)
 
-	// Equal
-	equal, err := labels.NewMatcher(labels.MatchEqual, "label1", "test1")
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
+	nl, err := nflog.New()
+	require.NoError(t, err)
+	require.NoError(t, nl.Log(
+		&nflogpb.Receiver{GroupName: "team-X", Integration: "webhook"},
+		"{}:{alertname=\"a\"}",
+		[]uint64{notify.HashAlert(a)},
+		[]uint64{42},
+		nil,
+		nil,
+		0,
+	))
+	require.NoError(t, nl.Log(
+		&nflogpb.Receiver{GroupName: "team-Y", Integration: "email"},
+		"{}:{alertname=\"b\"}",
+		nil,
+		nil,
+		nil,
+		nil,
+		0,
+	))
+
+	api := New(alertsProvider, nil, nil, nil, nil, nl, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	list := func(query string) []nflogEntry {
+		r, err := http.NewRequest("GET", "/api/v1/nflog"+query, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Data []nflogEntry `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res.Data
 	}
 
-	tests := []test{
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test1"})},
-			"label1=test1",
-			true,
-		},
+	require.Len(t, list(""), 2)
+	require.Len(t, list("?groupKey="+url.QueryEscape("{}:{alertname=\"b\"}")), 1)
+	require.Len(t, list("?receiver=team-Z"), 0)
+
+	res := list("?receiver=team-X")
+	require.Len(t, res, 1)
+	require.Equal(t, "{}:{alertname=\"a\"}", res[0].GroupKey)
+	require.Equal(t, "webhook", res[0].Integration)
+	require.Equal(t, []nflogAlert{{
+		Hash:        fmt.Sprintf("%016x", notify.HashAlert(a)),
+		Fingerprint: a.Fingerprint().String(),
+	}}, res[0].FiringAlerts)
+	// Alerts that are no longer known have no fingerprint.
+	require.Equal(t, []nflogAlert{{Hash: "000000000000002a"}}, res[0].ResolvedAlerts)
+}
+
+func TestAlertGroups(t *testing.T) {
+	overview := dispatch.AlertOverview{
 		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test2"})},
-			"label1=test2",
-			false,
+			Labels:   model.LabelSet{"alertname": "a"},
+			GroupKey: "a",
+			Blocks: []*dispatch.AlertBlock{
+				{
+					RouteOpts: &dispatch.RouteOpts{Receiver: "team-X"},
+					RouteKey:  "{}/{team=\"x\"}",
+					Alerts:    []*dispatch.APIAlert{{}, {}},
+				},
+				{
+					RouteOpts: &dispatch.RouteOpts{Receiver: "team-Y"},
+					RouteKey:  "{}/{team=\"y\"}",
+					Alerts:    []*dispatch.APIAlert{{}},
+				},
+			},
 		},
 		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label2": "test2"})},
-			"label2=test2",
-			false,
+			Labels:   model.LabelSet{"alertname": "b"},
+			GroupKey: "b",
+			Blocks: []*dispatch.AlertBlock{
+				{
+					RouteOpts: &dispatch.RouteOpts{Receiver: "team-Y"},
+					RouteKey:  "{}/{team=\"y\"}",
+					Alerts:    []*dispatch.APIAlert{{}},
+				},
+			},
 		},
 	}
+	api := New(nil, nil, nil, nil, nil, nil, func([]*labels.Matcher) dispatch.AlertOverview { return overview }, nil, nil, nil, nil)
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
 
-	for _, test := range tests {
-		actual := silenceMatchesFilterLabels(test.silence, []*labels.Matcher{equal})
-		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
-		require.Equal(t, test.expected, actual, msg)
-	}
+	get := func(query string, v interface{}) int {
+		r, err := http.NewRequest("GET", "/api/v1/alerts/groups"+query, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
 
-	// Not Equal
-	notEqual, err := labels.NewMatcher(labels.MatchNotEqual, "label1", "test1")
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
+		res := struct {
+			Data interface{} `json:"data"`
+		}{Data: v}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return w.Code
+	}
+	type group struct {
+		GroupKey string `json:"groupKey"`
+		Blocks   []struct {
+			RouteKey string `json:"routeKey"`
+		} `json:"blocks"`
 	}
 
-	tests = []test{
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test1"})},
-			"label1!=test1",
-			false,
-		},
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test2"})},
-			"label1!=test2",
-			true,
-		},
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label2": "test2"})},
-			"label2!=test2",
-			true,
-		},
+	var groups []group
+	require.Equal(t, http.StatusOK, get("", &groups))
+	require.Len(t, groups, 2)
+
+	groups = nil
+	require.Equal(t, http.StatusOK, get("?receiver=team-X", &groups))
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Blocks, 1)
+
+	groups = nil
+	require.Equal(t, http.StatusOK, get("?route="+url.QueryEscape("{}/{team=\"y\"}"), &groups))
+	require.Len(t, groups, 2)
+	require.Equal(t, "{}/{team=\"y\"}", groups[0].Blocks[0].RouteKey)
+
+	var count alertGroupsCount
+	require.Equal(t, http.StatusOK, get("?count=true", &count))
+	require.Equal(t, alertGroupsCount{Groups: 2, Alerts: 4}, count)
+	require.Equal(t, http.StatusOK, get("?count=true&receiver=team-Y", &count))
+	require.Equal(t, alertGroupsCount{Groups: 2, Alerts: 2}, count)
+
+	require.Equal(t, http.StatusBadRequest, get("?count=maybe", nil))
+	require.Equal(t, http.StatusBadRequest, get("?receiver=(", nil))
+}
+
+func TestTestRoutes(t *testing.T) {
+	conf, err := config.Load(`
+route:
+  receiver: default
+  group_by: [alertname]
+  routes:
+  - match:
+      team: db
+    receiver: db
+  - match:
+      team: frontend
+    receiver: frontend
+    group_by: ['...']
+    group_wait: 1m
+    continue: true
+    mute_time_intervals: [offhours]
+  - match_re:
+      team: front.*
+    receiver: frontend-oncall
+receivers:
+- name: default
+- name: db
+- name: frontend
+- name: frontend-oncall
+mute_time_intervals:
+- name: offhours
+  time_intervals: [{weekdays: [sunday]}]
+`)
+	require.NoError(t, err)
+
+	api := New(nil, nil, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(conf, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	// RouteOpts are only serialized, so they are decoded generically.
+	type testResult struct {
+		Routes []struct {
+			Path        []string               `json:"path"`
+			Indices     []int                  `json:"indices"`
+			RouteOpts   map[string]interface{} `json:"routeOpts"`
+			GroupLabels model.LabelSet         `json:"groupLabels"`
+		} `json:"routes"`
 	}
+	test := func(body string) (int, []testResult) {
+		r, err := http.NewRequest("POST", "/api/v1/routes/test", strings.NewReader(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
 
-	for _, test := range tests {
-		actual := silenceMatchesFilterLabels(test.silence, []*labels.Matcher{notEqual})
-		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
-		require.Equal(t, test.expected, actual, msg)
+		var res struct {
+			Data []testResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return w.Code, res.Data
 	}
 
-	// Regexp Equal
-	regexpEqual, err := labels.NewMatcher(labels.MatchRegexp, "label1", "tes.*")
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
+	code, res := test(`{"alertname": "a", "team": "frontend", "instance": "i"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, res, 1)
+	require.Len(t, res[0].Routes, 2)
+	require.Equal(t, []int{1}, res[0].Routes[0].Indices)
+	require.Equal(t, []string{"{}", `{team="frontend"}`}, res[0].Routes[0].Path)
+	require.Equal(t, model.LabelSet{"alertname": "a", "team": "frontend", "instance": "i"}, res[0].Routes[0].GroupLabels)
+	require.Equal(t, []int{2}, res[0].Routes[1].Indices)
+	require.Equal(t, model.LabelSet{"alertname": "a"}, res[0].Routes[1].GroupLabels)
+
+	opts := res[0].Routes[0].RouteOpts
+	require.Equal(t, "frontend", opts["receiver"])
+	require.Equal(t, true, opts["groupByAll"])
+	require.Equal(t, float64(time.Minute), opts["groupWait"])
+	require.Equal(t, []interface{}{"offhours"}, opts["muteTimeIntervals"])
+	require.Equal(t, "frontend-oncall", res[0].Routes[1].RouteOpts["receiver"])
+
+	code, res = test(`[{"team": "db"}, {"alertname": "b"}]`)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, res, 2)
+	require.Equal(t, []int{0}, res[0].Routes[0].Indices)
+	require.Equal(t, []string{"{}"}, res[1].Routes[0].Path)
+	require.Equal(t, []int{}, res[1].Routes[0].Indices)
+
+	code, _ = test(`{"not-a-label": "a"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = test(`"team"`)
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestAckAlert(t *testing.T) {
+	a := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "a"},
+		StartsAt: time.Now().Add(-time.Hour),
+	}}
+	alertsProvider := newFakeAlerts([]*types.Alert{a}, false)
+
+	acks, err := ack.New(ack.Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	api := New(alertsProvider, nil, acks, nil, nil, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, "/api/v1"+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
 	}
+	path := "/alert/" + a.Fingerprint().String() + "/ack"
 
-	tests = []test{
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test1"})},
-			"label1=~test1",
-			true,
-		},
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test2"})},
-			"label1=~test2",
-			true,
-		},
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label2": "test2"})},
-			"label2=~test2",
-			false,
-		},
+	w := do("POST", path, `{"createdBy": "alice", "comment": "looking"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = do("POST", path, `{"comment": "looking"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	w = do("POST", "/alert/0000000000000001/ack", `{"createdBy": "alice"}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	e, ok := acks.Acknowledged(&a.Alert)
+	require.True(t, ok)
+	require.Equal(t, "alice", e.CreatedBy)
+	require.Equal(t, "looking", e.Comment)
+
+	w = do("GET", "/acks", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data []*ack.Ack `json:"data"`
 	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.Data, 1)
+	require.Equal(t, a.Fingerprint().String(), res.Data[0].Fingerprint)
 
-	for _, test := range tests {
-		actual := silenceMatchesFilterLabels(test.silence, []*labels.Matcher{regexpEqual})
-		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
-		require.Equal(t, test.expected, actual, msg)
+	w = do("DELETE", path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	_, ok = acks.Acknowledged(&a.Alert)
+	require.False(t, ok)
+	w = do("DELETE", path, "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSetAlertAnnotations(t *testing.T) {
+	a := &types.Alert{Alert: model.Alert{
+		Labels:      model.LabelSet{"alertname": "a"},
+		Annotations: model.LabelSet{"summary": "disk full"},
+		StartsAt:    time.Now().Add(-time.Hour),
+	}}
+	alertsProvider := newFakeAlerts([]*types.Alert{a}, false)
+	groups := func([]*labels.Matcher) dispatch.AlertOverview {
+		return dispatch.AlertOverview{{
+			Labels: model.LabelSet{"alertname": "a"},
+			Blocks: []*dispatch.AlertBlock{{
+				RouteOpts: &dispatch.RouteOpts{Receiver: "team-X"},
+				Alerts:    []*dispatch.APIAlert{{Alert: &a.Alert, Fingerprint: a.Fingerprint().String()}},
+			}},
+		}}
 	}
 
-	// Regexp Not Equal
-	regexpNotEqual, err := labels.NewMatcher(labels.MatchNotRegexp, "label1", "tes.*")
-	if err != nil {
-		t.Errorf("Unexpected error %v", err)
+	annotations, err := annotation.New(annotation.Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	api := New(alertsProvider, nil, nil, nil, nil, nil, groups, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	api.SetAnnotations(annotations)
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
 	}
+	path := "/api/v1/alert/" + a.Fingerprint().String() + "/annotations"
 
-	tests = []test{
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test1"})},
-			"label1!~test1",
-			false,
-		},
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test2"})},
-			"label1!~test2",
-			false,
-		},
-		{
-			&types.Silence{Matchers: newMatcher(model.LabelSet{"label2": "test2"})},
-			"label2!~test2",
-			true,
-		},
+	w := do("POST", path, `{"updatedBy": "alice", "annotations": {"note": "known issue INC-1234", "runbook": "https://runbooks/disk"}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data model.LabelSet `json:"data"`
 	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, model.LabelSet{
+		"summary": "disk full",
+		"note":    "known issue INC-1234",
+		"runbook": "https://runbooks/disk",
+	}, res.Data)
 
-	for _, test := range tests {
-		actual := silenceMatchesFilterLabels(test.silence, []*labels.Matcher{regexpNotEqual})
-		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
-		require.Equal(t, test.expected, actual, msg)
+	w = do("POST", path, `{"updatedBy": "bob", "annotations": {"runbook": null}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, model.LabelSet{"summary": "disk full", "note": "known issue INC-1234"}, res.Data)
+
+	w = do("POST", path, `{"annotations": {"note": "x"}}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	w = do("POST", "/api/v1/alert/0000000000000001/annotations", `{"updatedBy": "alice", "annotations": {"note": "x"}}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	// The annotations are served with the alert.
+	w = do("GET", "/api/v1/alerts/groups", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var groupsRes struct {
+		Data dispatch.AlertOverview `json:"data"`
 	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &groupsRes))
+	require.Equal(t, model.LabelValue("known issue INC-1234"), groupsRes.Data[0].Blocks[0].Alerts[0].Annotations["note"])
+	require.Equal(t, model.LabelSet{"summary": "disk full"}, a.Annotations)
 }
 
-func TestReceiversMatchFilter(t *testing.T) {
-	receivers := []string{"pagerduty", "slack", "hipchat"}
+func TestSilenceAlert(t *testing.T) {
+	a := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "a", "instance": "host-1"},
+		StartsAt: time.Now().Add(-time.Hour),
+	}}
+	alertsProvider := newFakeAlerts([]*types.Alert{a}, false)
+
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(alertsProvider, silences, nil, nil, nil, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{Route: &config.Route{}}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, "/api/v1"+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	path := "/alert/" + a.Fingerprint().String() + "/silence"
+	endsAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	w := do("POST", path, `{"endsAt": "`+endsAt+`", "createdBy": "alice", "comment": "flapping"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data struct {
+			SilenceID string `json:"silenceId"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	sils, err := silences.Query(silence.QIDs(res.Data.SilenceID))
+	require.NoError(t, err)
+	require.Len(t, sils, 1)
+	require.Equal(t, []*silencepb.Matcher{
+		{Type: silencepb.Matcher_EQUAL, Name: "alertname", Pattern: "a"},
+		{Type: silencepb.Matcher_EQUAL, Name: "instance", Pattern: "host-1"},
+	}, sils[0].Matchers)
+	require.Equal(t, "alice", sils[0].CreatedBy)
+
+	for _, body := range []string{
+		`{"createdBy": "alice", "comment": "no end"}`,
+		`{"endsAt": "` + endsAt + `", "matchers": [{"name": "job", "value": "x"}]}`,
+	} {
+		w = do("POST", path, body)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	w = do("POST", "/alert/0000000000000001/silence", `{"endsAt": "`+endsAt+`"}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSilenceTemplates(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{
+		Route: &config.Route{},
+		SilenceTemplates: []*config.SilenceTemplate{
+			{
+				Name:       "node-maintenance",
+				Parameters: []string{"HOST"},
+				Match:      map[string]string{"job": "node"},
+				MatchRE:    map[string]string{"instance": "{{ .HOST }}(:.*)?"},
+				Duration:   model.Duration(2 * time.Hour),
+				Comment:    "Maintenance of {{ .HOST }}",
+			},
+		},
+	}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, "/api/v1"+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("GET", "/silence_templates", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = do("POST", "/silence_templates/node-maintenance", `{"parameters": {"HOST": "abc"}, "createdBy": "alice"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data struct {
+			SilenceID string `json:"silenceId"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+	sil, err := silences.QueryOne(silence.QIDs(res.Data.SilenceID))
+	require.NoError(t, err)
+	require.Equal(t, "alice", sil.CreatedBy)
+	require.Equal(t, "Maintenance of abc", sil.Comment)
+	require.WithinDuration(t, sil.StartsAt.Add(2*time.Hour), sil.EndsAt, time.Second)
+	require.Len(t, sil.Matchers, 2)
+	require.Equal(t, "instance", sil.Matchers[0].Name)
+	require.Equal(t, "abc(:.*)?", sil.Matchers[0].Pattern)
+
+	w = do("POST", "/silence_templates/node-maintenance", `{"createdBy": "alice"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	w = do("POST", "/silence_templates/unknown", `{"createdBy": "alice"}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSilenceFromTemplate(t *testing.T) {
+	now := time.Now()
+	st := &config.SilenceTemplate{
+		Name:     "deploy",
+		Match:    map[string]string{"service": "{{ .SERVICE }}"},
+		Duration: model.Duration(time.Hour),
+		Comment:  "Deploying {{ .SERVICE }}",
+	}
+
+	sil, err := silenceFromTemplate(st, &silenceTemplateRequest{
+		Parameters: map[string]string{"SERVICE": "api"},
+		Comment:    "Release 1.2",
+	}, now)
+	require.NoError(t, err)
+	require.Equal(t, "Release 1.2", sil.Comment)
+	require.Equal(t, now, sil.StartsAt)
+	require.Equal(t, now.Add(time.Hour), sil.EndsAt)
+	require.Equal(t, types.Matchers{{Name: "service", Value: "api"}}, sil.Matchers)
+
+	// Parameters referenced by the template must be given.
+	_, err = silenceFromTemplate(st, &silenceTemplateRequest{}, now)
+	require.Error(t, err)
+
+	// Matchers must still be valid after rendering.
+	_, err = silenceFromTemplate(st, &silenceTemplateRequest{
+		Parameters: map[string]string{"SERVICE": ""},
+	}, now)
+	require.Error(t, err)
+}
+
+func TestSilencePolicyViolations(t *testing.T) {
+	now := time.Now()
+	policy := &config.SilencePolicy{
+		MaxDuration:    model.Duration(24 * time.Hour),
+		CommentPattern: `[A-Z]+-[0-9]+`,
+		BannedMatchers: []map[string]string{
+			{"env": "prod", "region": "eu"},
+		},
+	}
+
+	cases := []struct {
+		sil   *types.Silence
+		rules []string
+	}{
+		{
+			sil: &types.Silence{
+				Matchers: types.Matchers{{Name: "job", Value: "node"}},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+				Comment:  "Upgrade, see OPS-123",
+			},
+		},
+		{
+			sil: &types.Silence{
+				Matchers: types.Matchers{{Name: "job", Value: "node"}},
+				StartsAt: now.Add(time.Hour),
+				EndsAt:   now.Add(26 * time.Hour),
+				Comment:  "upgrade",
+			},
+			rules: []string{"max_duration", "comment_pattern"},
+		},
+		{
+			sil: &types.Silence{
+				Matchers: types.Matchers{
+					{Name: "env", Value: "prod"},
+					{Name: "instance", Value: ".*", IsRegex: true},
+				},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+				Comment:  "OPS-1",
+			},
+			rules: []string{"banned_matchers"},
+		},
+		{
+			sil: &types.Silence{
+				Matchers: types.Matchers{
+					{Name: "env", Value: "prod"},
+					{Name: "instance", Value: "db.*", IsRegex: true},
+				},
+				StartsAt: now,
+				EndsAt:   now.Add(time.Hour),
+				Comment:  "OPS-1",
+			},
+		},
+	}
+	for i, c := range cases {
+		var rules []string
+		for _, v := range silencePolicyViolations(policy, c.sil, now) {
+			rules = append(rules, v.Rule)
+		}
+		require.Equal(t, c.rules, rules, "case %d", i)
+	}
+}
+
+func TestSetSilencePolicy(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{
+		Route: &config.Route{},
+		SilencePolicy: &config.SilencePolicy{
+			CommentPattern: `[A-Z]+-[0-9]+`,
+		},
+	}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	sil := fmt.Sprintf(`{"matchers": [{"name": "job", "value": "node"}], "startsAt": %q, "endsAt": %q, "comment": "no ticket"}`,
+		time.Now().Format(time.RFC3339), time.Now().Add(time.Hour).Format(time.RFC3339))
+	r, err := http.NewRequest("POST", "/api/v1/silences", bytes.NewBufferString(sil))
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var res struct {
+		Error string `json:"error"`
+		Data  struct {
+			Violations []silencePolicyViolation `json:"violations"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, "silence violates the silence policy", res.Error)
+	require.Equal(t, []silencePolicyViolation{
+		{Rule: "comment_pattern", Message: `comment must match "[A-Z]+-[0-9]+"`},
+	}, res.Data.Violations)
+}
+
+func TestSilenceHistory(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{Route: &config.Route{}}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		r.RemoteAddr = "10.0.0.1:4321"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	start, end := time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)
+	sil := `{"id": %q, "matchers": [{"name": "job", "value": "node"}], "startsAt": %q, "endsAt": %q, "createdBy": %q}`
+	w := do("POST", "/api/v1/silences", fmt.Sprintf(sil, "", start.Format(time.RFC3339), end.Format(time.RFC3339), "alice"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var created struct {
+		Data struct {
+			SilenceID string `json:"silenceId"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	id := created.Data.SilenceID
+
+	end = end.Add(time.Hour)
+	w = do("POST", "/api/v1/silences", fmt.Sprintf(sil, id, start.Format(time.RFC3339), end.Format(time.RFC3339), "bob"))
+	require.Equal(t, http.StatusOK, w.Code)
+	w = do("DELETE", "/api/v1/silence/"+id+"?author=carol", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = do("GET", "/api/v1/silence/"+id+"/history", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data []*types.SilenceChange `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.Data, 3)
+
+	for i, exp := range []struct {
+		typ    types.SilenceChangeType
+		author string
+	}{
+		{types.SilenceChangeCreated, "alice"},
+		{types.SilenceChangeUpdated, "bob"},
+		{types.SilenceChangeExpired, "carol"},
+	} {
+		require.Equal(t, exp.typ, res.Data[i].Type)
+		require.Equal(t, exp.author, res.Data[i].Author)
+		require.Equal(t, "10.0.0.1", res.Data[i].RemoteAddr)
+	}
+	require.Nil(t, res.Data[0].Previous)
+	require.Equal(t, "alice", res.Data[1].Previous.CreatedBy)
+	require.True(t, end.Add(-time.Hour).Truncate(time.Second).Equal(res.Data[1].Previous.EndsAt))
+	require.True(t, end.Truncate(time.Second).Equal(res.Data[2].Previous.EndsAt))
+
+	w = do("GET", "/api/v1/silence/unknown/history", "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAddSilenceComment(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{Route: &config.Route{}}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	now := time.Now()
+	id, err := silences.Set(&silencepb.Silence{
+		Matchers:  []*silencepb.Matcher{{Name: "job", Pattern: "node"}},
+		StartsAt:  now,
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "alice",
+		Comment:   "maintenance",
+	})
+	require.NoError(t, err)
+
+	w := do("POST", "/api/v1/silence/"+id+"/comments", `{"author": "bob", "comment": "extended until the fix is deployed"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	for _, body := range []string{
+		`{"comment": "no author"}`,
+		`{"author": "bob"}`,
+	} {
+		w = do("POST", "/api/v1/silence/"+id+"/comments", body)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	w = do("POST", "/api/v1/silence/unknown/comments", `{"author": "bob", "comment": "hello"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do("GET", "/api/v1/silence/"+id, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data *types.Silence `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, "maintenance", res.Data.Comment)
+	require.Len(t, res.Data.Thread, 1)
+	require.Equal(t, "bob", res.Data.Thread[0].Author)
+	require.Equal(t, "extended until the fix is deployed", res.Data.Thread[0].Comment)
+}
+
+func TestSilenceOwnership(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{
+		Route: &config.Route{},
+		SilencePolicy: &config.SilencePolicy{
+			Ownership: &config.SilenceOwnership{
+				TeamHeader:   "X-Team",
+				AdminTeams:   []string{"sre"},
+				RequireOwner: true,
+			},
+		},
+	}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, url, team, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		r.Header.Set("X-Team", team)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	silenceBody := func(id, owner string) string {
+		now := time.Now()
+		b, err := json.Marshal(&types.Silence{
+			ID:        id,
+			Matchers:  types.Matchers{{Name: "job", Value: "db"}},
+			StartsAt:  now,
+			EndsAt:    now.Add(time.Hour),
+			CreatedBy: "alice",
+			Comment:   "maintenance",
+			Owner:     owner,
+		})
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	w := do("POST", "/api/v1/silences", "db", silenceBody("", ""))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "require_owner")
+
+	w = do("POST", "/api/v1/silences", "web", silenceBody("", "db"))
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	w = do("POST", "/api/v1/silences", "db", silenceBody("", "db"))
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data struct {
+			SilenceID string `json:"silenceId"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	id := res.Data.SilenceID
+
+	// Other teams can neither take over nor expire the silence.
+	w = do("POST", "/api/v1/silences", "web", silenceBody(id, "web"))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	w = do("DELETE", "/api/v1/silence/"+id, "web", "")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	w = do("POST", "/api/v1/silences/expire", "web", `{"ids": ["`+id+`"]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `owned by team \"db\"`)
+
+	sils, err := silences.Query(silence.QIDs(id))
+	require.NoError(t, err)
+	require.Equal(t, "db", sils[0].Owner)
+	require.Equal(t, types.SilenceStateActive, silence.State(sils[0], time.Now()))
+
+	// Admin teams can expire the silences of all teams.
+	w = do("DELETE", "/api/v1/silence/"+id, "sre", "")
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestSilenceOwnershipIdentity(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{
+		Route: &config.Route{},
+		SilencePolicy: &config.SilencePolicy{
+			Ownership: &config.SilenceOwnership{
+				TeamHeader: "X-Team",
+				Teams: map[string][]string{
+					"db":  {"user:alice"},
+					"web": {"user:bob"},
+				},
+				RequireOwner: true,
+			},
+		},
+	}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	// The team header of authenticated requests is ignored.
+	do := func(method, url, user, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		r = r.WithContext(web.WithIdentity(r.Context(), web.Identity{ID: "user:" + user, Role: web.RoleSilencer}))
+		r.Header.Set("X-Team", "db")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	now := time.Now()
+	b, err := json.Marshal(&types.Silence{
+		Matchers:  types.Matchers{{Name: "job", Value: "db"}},
+		StartsAt:  now,
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "mallory",
+		Comment:   "maintenance",
+		Owner:     "db",
+	})
+	require.NoError(t, err)
+
+	w := do("POST", "/api/v1/silences", "bob", string(b))
+	require.Equal(t, http.StatusForbidden, w.Code)
+
+	w = do("POST", "/api/v1/silences", "alice", string(b))
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data struct {
+			SilenceID string `json:"silenceId"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	id := res.Data.SilenceID
+
+	// Authors are taken from the identity instead of the request body.
+	w = do("POST", "/api/v1/silence/"+id+"/comments", "alice", `{"author": "mallory", "comment": "extended"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	sils, err := silences.Query(silence.QIDs(id))
+	require.NoError(t, err)
+	require.Equal(t, "user:alice", sils[0].CreatedBy)
+	require.Len(t, sils[0].Thread, 1)
+	require.Equal(t, "user:alice", sils[0].Thread[0].Author)
+
+	w = do("DELETE", "/api/v1/silence/"+id, "bob", "")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	w = do("DELETE", "/api/v1/silence/"+id, "alice", "")
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestExportImportSilences(t *testing.T) {
+	newAPI := func() (*silence.Silences, *route.Router) {
+		silences, err := silence.New(silence.Options{})
+		require.NoError(t, err)
+		api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+		require.NoError(t, api.Update(&config.Config{Route: &config.Route{}}, time.Minute))
+		router := route.New()
+		api.Register(router.WithPrefix("/api/v1"))
+		return silences, router
+	}
+	do := func(router *route.Router, method, url, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	now := time.Now()
+	src, srcRouter := newAPI()
+	for _, sil := range []*silencepb.Silence{
+		{
+			Matchers: []*silencepb.Matcher{{Name: "job", Pattern: "node"}},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		},
+		{
+			Matchers: []*silencepb.Matcher{{Name: "job", Pattern: "db"}},
+			StartsAt: now.Add(time.Hour),
+			EndsAt:   now.Add(2 * time.Hour),
+		},
+		{
+			Matchers: []*silencepb.Matcher{{Name: "job", Pattern: "old"}},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		},
+	} {
+		id, err := src.Set(sil)
+		require.NoError(t, err)
+		if sil.Matchers[0].Pattern == "old" {
+			require.NoError(t, src.Expire(id))
+		}
+	}
+
+	w := do(srcRouter, "GET", "/api/v1/silences/export", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var exp struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &exp))
+	var doc silenceExport
+	require.NoError(t, json.Unmarshal(exp.Data, &doc))
+	require.Len(t, doc.Silences, 2)
+
+	dst, dstRouter := newAPI()
+	_, err := dst.Set(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Name: "job", Pattern: "node"}},
+		StartsAt: now,
+		EndsAt:   now.Add(30 * time.Minute),
+	})
+	require.NoError(t, err)
+
+	importDoc := func(conflict string) []silenceImportResult {
+		w := do(dstRouter, "POST", "/api/v1/silences/import?conflict="+conflict, string(exp.Data))
+		require.Equal(t, http.StatusOK, w.Code)
+		var res struct {
+			Data []silenceImportResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Len(t, res.Data, 2)
+		return res.Data
+	}
+	actions := func(results []silenceImportResult) map[string]string {
+		m := map[string]string{}
+		for _, r := range results {
+			m[r.ID] = r.Action
+		}
+		return m
+	}
+	var nodeID, dbID string
+	for _, sil := range doc.Silences {
+		if sil.Matchers[0].Value == "node" {
+			nodeID = sil.ID
+		} else {
+			dbID = sil.ID
+		}
+	}
+
+	require.Equal(t, map[string]string{nodeID: "skipped", dbID: "created"}, actions(importDoc("")))
+	count, err := dst.CountState(types.SilenceStateActive, types.SilenceStatePending)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	require.Equal(t, map[string]string{nodeID: "replaced", dbID: "replaced"}, actions(importDoc("replace")))
+	count, err = dst.CountState(types.SilenceStateActive, types.SilenceStatePending)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	require.Equal(t, map[string]string{nodeID: "created", dbID: "created"}, actions(importDoc("duplicate")))
+	count, err = dst.CountState(types.SilenceStateActive, types.SilenceStatePending)
+	require.NoError(t, err)
+	require.Equal(t, 4, count)
+
+	w = do(dstRouter, "POST", "/api/v1/silences/import?conflict=merge", string(exp.Data))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListSilencesQuery(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	now := time.Now()
+	ids := map[string]string{}
+	for _, sil := range []*silencepb.Silence{
+		{Comment: "a", CreatedBy: "alice", StartsAt: now, EndsAt: now.Add(3 * time.Hour)},
+		{Comment: "b DB maintenance", CreatedBy: "bob", StartsAt: now, EndsAt: now.Add(time.Hour)},
+		{Comment: "c", CreatedBy: "carol", StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)},
+		{Comment: "d db upgrade", CreatedBy: "alice", StartsAt: now, EndsAt: now.Add(time.Hour)},
+	} {
+		sil.Matchers = []*silencepb.Matcher{{Name: "job", Pattern: sil.Comment[:1]}}
+		id, err := silences.Set(sil)
+		require.NoError(t, err)
+		ids[id] = sil.Comment[:1]
+		if sil.Comment[:1] == "d" {
+			require.NoError(t, silences.Expire(id))
+		}
+	}
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{Route: &config.Route{}}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	list := func(query string) (*httptest.ResponseRecorder, string) {
+		r, err := http.NewRequest("GET", "/api/v1/silences?"+query, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var res struct {
+			Data []*types.Silence `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		var names string
+		for _, s := range res.Data {
+			names += ids[s.ID]
+		}
+		return w, names
+	}
+
+	for _, tc := range []struct {
+		query string
+		names string
+		total string
+	}{
+		{query: "", names: "bacd"},
+		{query: "state=active,expired", names: "bad"},
+		{query: "createdBy=alice", names: "ad"},
+		{query: "comment=DB", names: "bd"},
+		{query: "filter=" + url.QueryEscape("{job=~\"[ab]\"}"), names: "ba"},
+		{query: "sort=createdBy", names: "adbc"},
+		{query: "sort=-endsAt", names: "acbd"},
+		{query: "limit=2", names: "ba", total: "4"},
+		{query: "limit=2&offset=3", names: "d", total: "4"},
+		{query: "offset=5", names: "", total: "4"},
+		{query: "state=active&sort=startsAt&limit=1&offset=1", names: "b", total: "2"},
+	} {
+		w, names := list(tc.query)
+		require.Equal(t, http.StatusOK, w.Code, tc.query)
+		require.Equal(t, tc.names, names, tc.query)
+		require.Equal(t, tc.total, w.Header().Get("X-Total-Count"), tc.query)
+	}
+
+	for _, query := range []string{"state=gone", "sort=comment", "limit=-1", "offset=x"} {
+		w, _ := list(query)
+		require.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestBulkSilences(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{
+		Route:         &config.Route{},
+		SilencePolicy: &config.SilencePolicy{MaxDuration: model.Duration(24 * time.Hour)},
+	}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(url, body string) []bulkSilenceResult {
+		r, err := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var res struct {
+			Data []bulkSilenceResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return res.Data
+	}
+
+	now := time.Now()
+	sil := `{"matchers": [{"name": "team", "value": %q}], "startsAt": %q, "endsAt": %q}`
+	ts := func(d time.Duration) string { return now.Add(d).Format(time.RFC3339) }
+	res := do("/api/v1/silences/bulk", "["+strings.Join([]string{
+		fmt.Sprintf(sil, "db", ts(0), ts(time.Hour)),
+		fmt.Sprintf(sil, "db", ts(0), ts(48*time.Hour)),
+		fmt.Sprintf(sil, "web", ts(0), ts(time.Hour)),
+		fmt.Sprintf(sil, "db", ts(-time.Hour), ts(-time.Minute)),
+		"null",
+	}, ",")+"]")
+	require.Len(t, res, 5)
+	require.NotEmpty(t, res[0].SilenceID)
+	require.Empty(t, res[0].Error)
+	require.Contains(t, res[1].Error, "silence violates the silence policy")
+	require.NotEmpty(t, res[2].SilenceID)
+	require.Equal(t, "end time can't be in the past", res[3].Error)
+	require.Equal(t, "missing silence", res[4].Error)
+	dbID := res[0].SilenceID
+
+	count, err := silences.CountState(types.SilenceStateActive)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	res = do("/api/v1/silences/expire", `{"ids": ["unknown"], "filter": "{team=\"db\"}"}`)
+	require.Equal(t, []bulkSilenceResult{
+		{SilenceID: "unknown", Error: silence.ErrNotFound.Error()},
+		{SilenceID: dbID},
+	}, res)
+
+	count, err = silences.CountState(types.SilenceStateActive)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	r, err := http.NewRequest("POST", "/api/v1/silences/expire", bytes.NewBufferString(`{}`))
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPreviewSilence(t *testing.T) {
+	now := time.Now()
+	alerts := []*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a", "service": "api", "env": "prod"}, StartsAt: now}},
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "b", "service": "db", "env": "prod"}, StartsAt: now}},
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "c", "service": "db", "env": "dev"}, StartsAt: now}},
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "d", "service": "db", "env": "prod"}, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(-time.Minute)}},
+	}
+	alertsProvider := newFakeAlerts(alerts, false)
+
+	conf, err := config.Load(`
+route:
+  receiver: default
+  routes:
+  - match:
+      service: db
+    receiver: dba
+receivers:
+- name: default
+- name: dba
+`)
+	require.NoError(t, err)
+
+	api := New(alertsProvider, nil, nil, nil, nil, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	require.NoError(t, api.Update(conf, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("POST", "/api/v1/silences/preview", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(`{"matchers": [{"name": "env", "value": "prod"}]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var res struct {
+		Data struct {
+			Alerts []struct {
+				Labels    model.LabelSet `json:"labels"`
+				Receivers []string       `json:"receivers"`
+			} `json:"alerts"`
+			Receivers map[string]int `json:"receivers"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Len(t, res.Data.Alerts, 2)
+	require.Equal(t, map[string]int{"default": 1, "dba": 1}, res.Data.Receivers)
+
+	w = do(`{"matchers": [{"name": "service", "value": "(", "isRegex": true}]}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(`{"matchers": []}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAlertFiltering(t *testing.T) {
+	type test struct {
+		alert    *model.Alert
+		msg      string
+		expected bool
+	}
+
+	// Equal
+	equal, err := labels.NewMatcher(labels.MatchEqual, "label1", "test1")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	tests := []test{
+		{&model.Alert{Labels: model.LabelSet{"label1": "test1"}}, "label1=test1", true},
+		{&model.Alert{Labels: model.LabelSet{"label1": "test2"}}, "label1=test2", false},
+		{&model.Alert{Labels: model.LabelSet{"label2": "test2"}}, "label2=test2", false},
+	}
+
+	for _, test := range tests {
+		actual := alertMatchesFilterLabels(test.alert, []*labels.Matcher{equal})
+		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
+		require.Equal(t, test.expected, actual, msg)
+	}
+
+	// Not Equal
+	notEqual, err := labels.NewMatcher(labels.MatchNotEqual, "label1", "test1")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	tests = []test{
+		{&model.Alert{Labels: model.LabelSet{"label1": "test1"}}, "label1!=test1", false},
+		{&model.Alert{Labels: model.LabelSet{"label1": "test2"}}, "label1!=test2", true},
+		{&model.Alert{Labels: model.LabelSet{"label2": "test2"}}, "label2!=test2", true},
+	}
+
+	for _, test := range tests {
+		actual := alertMatchesFilterLabels(test.alert, []*labels.Matcher{notEqual})
+		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
+		require.Equal(t, test.expected, actual, msg)
+	}
+
+	// Regexp Equal
+	regexpEqual, err := labels.NewMatcher(labels.MatchRegexp, "label1", "tes.*")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	tests = []test{
+		{&model.Alert{Labels: model.LabelSet{"label1": "test1"}}, "label1=~test1", true},
+		{&model.Alert{Labels: model.LabelSet{"label1": "test2"}}, "label1=~test2", true},
+		{&model.Alert{Labels: model.LabelSet{"label2": "test2"}}, "label2=~test2", false},
+	}
+
+	for _, test := range tests {
+		actual := alertMatchesFilterLabels(test.alert, []*labels.Matcher{regexpEqual})
+		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
+		require.Equal(t, test.expected, actual, msg)
+	}
+
+	// Regexp Not Equal
+	regexpNotEqual, err := labels.NewMatcher(labels.MatchNotRegexp, "label1", "tes.*")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	tests = []test{
+		{&model.Alert{Labels: model.LabelSet{"label1": "test1"}}, "label1!~test1", false},
+		{&model.Alert{Labels: model.LabelSet{"label1": "test2"}}, "label1!~test2", false},
+		{&model.Alert{Labels: model.LabelSet{"label2": "test2"}}, "label2!~test2", true},
+	}
+
+	for _, test := range tests {
+		actual := alertMatchesFilterLabels(test.alert, []*labels.Matcher{regexpNotEqual})
+		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
+		require.Equal(t, test.expected, actual, msg)
+	}
+}
+
+func TestSilenceFiltering(t *testing.T) {
+	type test struct {
+		silence  *types.Silence
+		msg      string
+		expected bool
+	}
+
+	// Equal
+	equal, err := labels.NewMatcher(labels.MatchEqual, "label1", "test1")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	tests := []test{
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test1"})},
+			"label1=test1",
+			true,
+		},
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test2"})},
+			"label1=test2",
+			false,
+		},
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label2": "test2"})},
+			"label2=test2",
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		actual := silenceMatchesFilterLabels(test.silence, []*labels.Matcher{equal})
+		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
+		require.Equal(t, test.expected, actual, msg)
+	}
+
+	// Not Equal
+	notEqual, err := labels.NewMatcher(labels.MatchNotEqual, "label1", "test1")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	tests = []test{
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test1"})},
+			"label1!=test1",
+			false,
+		},
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test2"})},
+			"label1!=test2",
+			true,
+		},
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label2": "test2"})},
+			"label2!=test2",
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		actual := silenceMatchesFilterLabels(test.silence, []*labels.Matcher{notEqual})
+		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
+		require.Equal(t, test.expected, actual, msg)
+	}
+
+	// Regexp Equal
+	regexpEqual, err := labels.NewMatcher(labels.MatchRegexp, "label1", "tes.*")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	tests = []test{
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test1"})},
+			"label1=~test1",
+			true,
+		},
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test2"})},
+			"label1=~test2",
+			true,
+		},
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label2": "test2"})},
+			"label2=~test2",
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		actual := silenceMatchesFilterLabels(test.silence, []*labels.Matcher{regexpEqual})
+		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
+		require.Equal(t, test.expected, actual, msg)
+	}
+
+	// Regexp Not Equal
+	regexpNotEqual, err := labels.NewMatcher(labels.MatchNotRegexp, "label1", "tes.*")
+	if err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	tests = []test{
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test1"})},
+			"label1!~test1",
+			false,
+		},
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label1": "test2"})},
+			"label1!~test2",
+			false,
+		},
+		{
+			&types.Silence{Matchers: newMatcher(model.LabelSet{"label2": "test2"})},
+			"label2!~test2",
+			true,
+		},
+	}
+
+	for _, test := range tests {
+		actual := silenceMatchesFilterLabels(test.silence, []*labels.Matcher{regexpNotEqual})
+		msg := fmt.Sprintf("Expected %t for %s", test.expected, test.msg)
+		require.Equal(t, test.expected, actual, msg)
+	}
+}
+
+func TestReceiversMatchFilter(t *testing.T) {
+	receivers := []string{"pagerduty", "slack", "hipchat"}
 
 	filter, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", "hip.*"))
 	if err != nil {
@@ -543,6 +1937,8 @@ func TestMatchFilterLabels(t *testing.T) {
 		{labels.MatchRegexp, true},
 		{labels.MatchNotEqual, false},
 		{labels.MatchNotRegexp, false},
+		{labels.MatchAbsent, true},
+		{labels.MatchPresent, false},
 	}
 
 	for _, tc := range testCases {
@@ -566,6 +1962,32 @@ func TestMatchFilterLabels(t *testing.T) {
 	}
 }
 
+func TestSilenceProtoMatcherOperators(t *testing.T) {
+	now := time.Now()
+	sil := &types.Silence{
+		Matchers: types.Matchers{
+			{Name: "owner", Operator: types.MatchAbsent},
+			{Name: "priority", Value: "3", Operator: types.MatchGreaterEqual},
+			{Name: "job", Value: "api.*", IsRegex: true},
+		},
+		StartsAt:  now,
+		EndsAt:    now.Add(time.Hour),
+		UpdatedAt: now,
+	}
+
+	pb, err := silenceToProto(sil)
+	require.NoError(t, err)
+	require.Equal(t, []*silencepb.Matcher{
+		{Name: "owner", Type: silencepb.Matcher_ABSENT},
+		{Name: "priority", Pattern: "3", Type: silencepb.Matcher_GREATER_EQUAL},
+		{Name: "job", Pattern: "api.*", Type: silencepb.Matcher_REGEXP},
+	}, pb.Matchers)
+
+	res, err := silenceFromProto(pb)
+	require.NoError(t, err)
+	require.Equal(t, sil.Matchers, res.Matchers)
+}
+
 func newMatcher(labelSet model.LabelSet) types.Matchers {
 	matchers := make([]*types.Matcher, 0, len(labelSet))
 	for key, val := range labelSet {
@@ -573,3 +1995,806 @@ func newMatcher(labelSet model.LabelSet) types.Matchers {
 	}
 	return matchers
 }
+
+func TestSetSilenceQuorum(t *testing.T) {
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(nil, silences, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{Route: &config.Route{}}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	now := time.Now()
+	body, err := json.Marshal(&types.Silence{
+		Matchers:  types.Matchers{{Name: "job", Value: "db"}},
+		StartsAt:  now,
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "alice",
+		Comment:   "maintenance",
+	})
+	require.NoError(t, err)
+
+	do := func(url string) (int, map[string]interface{}) {
+		r, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var res struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return w.Code, res.Data
+	}
+
+	acked := false
+	silences.SetQuorumBroadcast(func(context.Context, []byte) error {
+		acked = true
+		return nil
+	})
+	code, _ := do("/api/v1/silences")
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, acked)
+
+	code, _ = do("/api/v1/silences?quorum=true")
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, acked)
+
+	silences.SetQuorumBroadcast(func(context.Context, []byte) error {
+		return errors.New("acknowledged by 0 of 1 required peers")
+	})
+	code, data := do("/api/v1/silences?quorum=true")
+	require.Equal(t, http.StatusInternalServerError, code)
+	require.NotEmpty(t, data["silenceId"])
+}
+
+func TestPostConfig(t *testing.T) {
+	const running = `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+`
+	conf, err := config.Load(running)
+	require.NoError(t, err)
+
+	api := New(nil, nil, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(conf, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	post := func(url, body string) (int, configResult) {
+		r, err := http.NewRequest("POST", url, strings.NewReader(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var res struct {
+			Data configResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return w.Code, res.Data
+	}
+	submitted := strings.Replace(running, "team-X", "team-Y", -1)
+
+	code, res := post("/api/v1/config?dry_run=true", submitted)
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, res.Applied)
+	require.Contains(t, res.Diff, "--- running\n+++ submitted\n")
+	require.Contains(t, res.Diff, "-  receiver: team-X\n")
+	require.Contains(t, res.Diff, "+  receiver: team-Y\n")
+
+	code, _ = post("/api/v1/config?dry_run=true", "route: {}")
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = post("/api/v1/config?dry_run=maybe", submitted)
+	require.Equal(t, http.StatusBadRequest, code)
+	// Submitted configurations cannot reference secrets of the server.
+	code, _ = post("/api/v1/config?dry_run=true", submitted+`  webhook_configs:
+  - url: http://example.com/hook
+    http_config:
+      bearer_token: env://VAULT_TOKEN
+`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// Without an applier, configurations can only be validated.
+	code, _ = post("/api/v1/config", submitted)
+	require.Equal(t, http.StatusForbidden, code)
+
+	var applied *config.Config
+	api.SetConfigApplier(func(c *config.Config, plain []byte, source string) error {
+		require.Equal(t, "api", source)
+		if c.Route.Receiver == "team-Z" {
+			return errors.New("template not found")
+		}
+		applied = c
+		return nil
+	})
+	code, res = post("/api/v1/config", strings.Replace(running, "team-X", "team-Z", -1))
+	require.Equal(t, http.StatusBadRequest, code)
+	require.False(t, res.Applied)
+	require.NotEmpty(t, res.Diff)
+	require.Nil(t, applied)
+
+	code, res = post("/api/v1/config", submitted)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, res.Applied)
+	require.Equal(t, "team-Y", applied.Route.Receiver)
+
+	// Content types that browsers send cross-origin without a preflight
+	// request are rejected.
+	applied = nil
+	for _, ct := range []string{"text/plain;charset=UTF-8", "application/x-www-form-urlencoded", "multipart/form-data; boundary=x"} {
+		r, err := http.NewRequest("POST", "/api/v1/config", strings.NewReader(submitted))
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", ct)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code, ct)
+
+		r, err = http.NewRequest("POST", "/api/v1/config/rollback/1", nil)
+		require.NoError(t, err)
+		r.Header.Set("Content-Type", ct)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusBadRequest, w.Code, ct)
+	}
+	require.Nil(t, applied)
+}
+
+func TestTenantHeader(t *testing.T) {
+	alertsProvider := newFakeAlerts([]*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a", "tenant": "team-a"}, EndsAt: time.Now().Add(time.Hour)}},
+		{Alert: model.Alert{Labels: model.LabelSet{"alertname": "b", "tenant": "team-b"}, EndsAt: time.Now().Add(time.Hour)}},
+	}, false)
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(alertsProvider, silences, nil, nil, nil, nil, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	global := config.DefaultGlobalConfig
+	require.NoError(t, api.Update(&config.Config{Global: &global, Route: &config.Route{}}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	header := "X-Alertmanager-Tenant"
+	do := func(method, url, tenant string, body interface{}) (int, json.RawMessage) {
+		b, err := json.Marshal(body)
+		require.NoError(t, err)
+		r, err := http.NewRequest(method, url, bytes.NewReader(b))
+		require.NoError(t, err)
+		if tenant != "" {
+			r.Header.Set(header, tenant)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if w.Code != http.StatusNotFound {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		}
+		return w.Code, res.Data
+	}
+
+	code, data := do("GET", "/api/v1/alerts", "team-a", nil)
+	require.Equal(t, http.StatusOK, code)
+	var alerts []*dispatch.APIAlert
+	require.NoError(t, json.Unmarshal(data, &alerts))
+	require.Len(t, alerts, 1)
+	require.Equal(t, model.LabelValue("a"), alerts[0].Labels["alertname"])
+
+	now := time.Now()
+	sil := &types.Silence{
+		Matchers:  types.Matchers{{Name: "alertname", Value: "a"}},
+		StartsAt:  now,
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "alice",
+		Comment:   "maintenance",
+	}
+	code, data = do("POST", "/api/v1/silences", "team-a", sil)
+	require.Equal(t, http.StatusOK, code)
+	var res struct {
+		SilenceID string `json:"silenceId"`
+	}
+	require.NoError(t, json.Unmarshal(data, &res))
+
+	listed := func(tenant string) []*types.Silence {
+		code, data := do("GET", "/api/v1/silences", tenant, nil)
+		require.Equal(t, http.StatusOK, code)
+		var sils []*types.Silence
+		require.NoError(t, json.Unmarshal(data, &sils))
+		return sils
+	}
+	sils := listed("team-a")
+	require.Len(t, sils, 1)
+	require.Equal(t, `{alertname="a",tenant="team-a"}`, types.NewMatchers(sils[0].Matchers...).String())
+	require.Len(t, listed("team-b"), 0)
+	require.Len(t, listed(""), 1)
+
+	// Tenants cannot replace silences of other tenants.
+	sil.ID = res.SilenceID
+	code, _ = do("POST", "/api/v1/silences", "team-b", sil)
+	require.Equal(t, http.StatusForbidden, code)
+	sil.ID = ""
+	sil.Matchers = append(sil.Matchers, types.NewMatcher("tenant", "team-a"))
+	code, _ = do("POST", "/api/v1/silences", "team-b", sil)
+	require.Equal(t, http.StatusForbidden, code)
+
+	// The tenant header can be set to the header of multi-tenant proxies.
+	global.TenantHeader = "X-Scope-OrgID"
+	require.NoError(t, api.Update(&config.Config{Global: &global, Route: &config.Route{}}, time.Minute))
+	header = "X-Scope-OrgID"
+
+	code, _ = do("GET", "/api/v1/silence/"+res.SilenceID, "team-b", nil)
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = do("GET", "/api/v1/silence/"+res.SilenceID, "team-a", nil)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = do("DELETE", "/api/v1/silence/"+res.SilenceID, "team-b", nil)
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, data = do("GET", "/api/v1/silences/export", "team-b", nil)
+	require.Equal(t, http.StatusOK, code)
+	var exp silenceExport
+	require.NoError(t, json.Unmarshal(data, &exp))
+	require.Len(t, exp.Silences, 0)
+
+	code, data = do("POST", "/api/v1/silences/expire", "team-b", expireSilencesRequest{IDs: []string{res.SilenceID}})
+	require.Equal(t, http.StatusOK, code)
+	var results []bulkSilenceResult
+	require.NoError(t, json.Unmarshal(data, &results))
+	require.Contains(t, results[0].Error, "does not belong to tenant")
+	require.Len(t, listed("team-a"), 1)
+
+	fp := alertsProvider.alerts[1].Fingerprint().String()
+	code, _ = do("GET", "/api/v1/alert/"+fp+"/inhibitors", "team-a", nil)
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = do("GET", "/api/v1/alert/"+fp+"/inhibitors", "team-b", nil)
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestConfigRollback(t *testing.T) {
+	const v1 = `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+`
+	v2 := strings.Replace(v1, "team-X", "team-Y", -1)
+
+	dir, err := ioutil.TempDir("", "config-history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	h, err := confighistory.New(dir, 10)
+	require.NoError(t, err)
+	_, err = h.Add([]byte(v1), confighistory.SourceFile)
+	require.NoError(t, err)
+	_, err = h.Add([]byte(v2), confighistory.SourceAPI)
+	require.NoError(t, err)
+
+	conf, err := config.Load(v2)
+	require.NoError(t, err)
+	api := New(nil, nil, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(conf, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, url string) (int, json.RawMessage) {
+		r, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res.Data
+	}
+
+	// Without a history, no versions are listed.
+	code, data := do("GET", "/api/v1/config/history")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "[]", string(data))
+
+	api.SetConfigHistory(h)
+	code, data = do("GET", "/api/v1/config/history")
+	require.Equal(t, http.StatusOK, code)
+	var versions []confighistory.Version
+	require.NoError(t, json.Unmarshal(data, &versions))
+	require.Len(t, versions, 2)
+	require.Equal(t, 2, versions[0].Version)
+	require.Equal(t, "api", versions[0].Source)
+
+	var applied, source string
+	api.SetConfigApplier(func(c *config.Config, plain []byte, s string) error {
+		applied, source = c.Route.Receiver, s
+		return nil
+	})
+
+	code, data = do("POST", "/api/v1/config/rollback/1?dry_run=true")
+	require.Equal(t, http.StatusOK, code)
+	var res configResult
+	require.NoError(t, json.Unmarshal(data, &res))
+	require.False(t, res.Applied)
+	require.Contains(t, res.Diff, "+  receiver: team-X\n")
+	require.Equal(t, "", applied)
+
+	code, data = do("POST", "/api/v1/config/rollback/1")
+	require.Equal(t, http.StatusOK, code)
+	require.NoError(t, json.Unmarshal(data, &res))
+	require.True(t, res.Applied)
+	require.Equal(t, "team-X", applied)
+	require.Equal(t, "rollback:1", source)
+
+	code, _ = do("POST", "/api/v1/config/rollback/3")
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = do("POST", "/api/v1/config/rollback/latest")
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestConfigDiff(t *testing.T) {
+	const running = `
+route:
+  receiver: team-X
+  group_wait: 30s
+receivers:
+- name: team-X
+`
+	conf, err := config.Load(running)
+	require.NoError(t, err)
+	api := New(nil, nil, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(conf, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, url, body string) (int, json.RawMessage) {
+		r, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res.Data
+	}
+
+	candidate := `
+route:
+  receiver: team-Y
+  group_wait: 1m
+receivers:
+- name: team-Y
+`
+	code, data := do("POST", "/api/v1/config/diff", candidate)
+	require.Equal(t, http.StatusOK, code)
+	var d config.Diff
+	require.NoError(t, json.Unmarshal(data, &d))
+	require.Equal(t, []string{"team-Y"}, d.Receivers.Added)
+	require.Equal(t, []string{"team-X"}, d.Receivers.Removed)
+	require.Len(t, d.Routes.Changed, 1)
+	require.Equal(t, "group_wait", d.Routes.Changed[0].Fields[0].Field)
+
+	code, _ = do("POST", "/api/v1/config/diff", "route: [")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	// Without a history, versions are not found.
+	code, _ = do("GET", "/api/v1/config/diff?version=1", "")
+	require.Equal(t, http.StatusNotFound, code)
+
+	dir, err := ioutil.TempDir("", "config-history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	h, err := confighistory.New(dir, 10)
+	require.NoError(t, err)
+	_, err = h.Add([]byte(running), confighistory.SourceFile)
+	require.NoError(t, err)
+	api.SetConfigHistory(h)
+
+	code, data = do("GET", "/api/v1/config/diff?version=1", "")
+	require.Equal(t, http.StatusOK, code)
+	d = config.Diff{}
+	require.NoError(t, json.Unmarshal(data, &d))
+	require.True(t, d.Empty())
+
+	code, _ = do("GET", "/api/v1/config/diff?version=2", "")
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = do("GET", "/api/v1/config/diff?version=latest", "")
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestValidateConfig(t *testing.T) {
+	api := New(nil, nil, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	for _, tc := range []struct {
+		body     string
+		valid    bool
+		problems []config.Problem
+	}{
+		{
+			body:     "route:\n  receiver: team-X\nreceivers:\n- name: team-X\n",
+			valid:    true,
+			problems: []config.Problem{},
+		},
+		{
+			body:  "route:\n  receiver: team-Y\nreceivers:\n- name: team-X\n",
+			valid: false,
+			problems: []config.Problem{{
+				Line:    2,
+				Column:  13,
+				Code:    config.ProblemUndefinedReference,
+				Message: `undefined receiver "team-Y" used in route`,
+			}},
+		},
+	} {
+		r, err := http.NewRequest("POST", "/api/v1/config/validate", strings.NewReader(tc.body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Data validationResult `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		require.Equal(t, tc.valid, res.Data.Valid)
+		require.Equal(t, tc.problems, res.Data.Problems)
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	api := New(nil, nil, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	r, err := http.NewRequest("GET", "/api/v1/config/schema", nil)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/schema+json", w.Header().Get("Content-Type"))
+
+	var schema struct {
+		Schema      string                 `json:"$schema"`
+		Properties  map[string]interface{} `json:"properties"`
+		Definitions map[string]interface{} `json:"definitions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &schema))
+	require.Equal(t, "http://json-schema.org/draft-07/schema#", schema.Schema)
+	require.Contains(t, schema.Properties, "receivers")
+	require.Contains(t, schema.Definitions, "SlackConfig")
+}
+
+func TestMaintenance(t *testing.T) {
+	conf, err := config.Load("route:\n  receiver: team-X\nreceivers:\n- name: team-X\n")
+	require.NoError(t, err)
+	api := New(nil, nil, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	require.NoError(t, api.Update(conf, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, url, body string) (int, json.RawMessage) {
+		r, err := http.NewRequest(method, url, strings.NewReader(body))
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res.Data
+	}
+
+	// Without a maintenance mode, it cannot be toggled.
+	code, _ := do("GET", "/api/v1/maintenance", "")
+	require.Equal(t, http.StatusNotFound, code)
+
+	m := notify.NewMaintenance(false, "")
+	api.SetMaintenance(m)
+
+	code, data := do("POST", "/api/v1/maintenance", `{"enabled": true, "reason": "migration"}`)
+	require.Equal(t, http.StatusOK, code)
+	var st notify.MaintenanceStatus
+	require.NoError(t, json.Unmarshal(data, &st))
+	require.True(t, st.Enabled)
+	require.Equal(t, "migration", st.Reason)
+	require.True(t, m.Status().Enabled)
+
+	code, data = do("GET", "/api/v1/status", "")
+	require.Equal(t, http.StatusOK, code)
+	var status struct {
+		Maintenance notify.MaintenanceStatus `json:"maintenance"`
+	}
+	require.NoError(t, json.Unmarshal(data, &status))
+	require.True(t, status.Maintenance.Enabled)
+
+	code, _ = do("POST", "/api/v1/maintenance", `{"reason": "missing"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = do("POST", "/api/v1/maintenance", `{"enabled": false}`)
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, m.Status().Enabled)
+}
+
+func TestStreamEvents(t *testing.T) {
+	defer func(d time.Duration) { streamPollInterval = d }(streamPollInterval)
+	streamPollInterval = 10 * time.Millisecond
+
+	marker := types.NewMarker()
+	alerts, err := mem.NewAlerts(marker, time.Hour)
+	require.NoError(t, err)
+	defer alerts.Close()
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(alerts, silences, nil, nil, nil, nil, groupAlerts, marker.Status, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{Route: &config.Route{Receiver: "team-X"}}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/alerts/stream?filter=" + url.QueryEscape(`{job="a"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan [2]string, 10)
+	go func() {
+		var event string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			l := scanner.Text()
+			switch {
+			case strings.HasPrefix(l, "event: "):
+				event = strings.TrimPrefix(l, "event: ")
+			case strings.HasPrefix(l, "data: "):
+				events <- [2]string{event, strings.TrimPrefix(l, "data: ")}
+			}
+		}
+		close(events)
+	}()
+	next := func() (string, string) {
+		select {
+		case e := <-events:
+			return e[0], e[1]
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+		}
+		return "", ""
+	}
+
+	now := time.Now()
+	newAlert := func(job string, endsAt time.Time, summary string) *types.Alert {
+		return &types.Alert{Alert: model.Alert{
+			Labels:      model.LabelSet{"alertname": "x", "job": model.LabelValue(job)},
+			Annotations: model.LabelSet{"summary": model.LabelValue(summary)},
+			StartsAt:    now,
+			EndsAt:      endsAt,
+		}}
+	}
+	require.NoError(t, alerts.Put(newAlert("b", now.Add(time.Hour), "")))
+	require.NoError(t, alerts.Put(newAlert("a", now.Add(time.Hour), "one")))
+	event, data := next()
+	require.Equal(t, eventAlertAdded, event)
+	require.Contains(t, data, `"job":"a"`)
+	require.Contains(t, data, `"receivers":["team-X"]`)
+
+	// Refreshing the alert is no update.
+	require.NoError(t, alerts.Put(newAlert("a", now.Add(2*time.Hour), "one")))
+	require.NoError(t, alerts.Put(newAlert("a", now.Add(2*time.Hour), "two")))
+	event, data = next()
+	require.Equal(t, eventAlertUpdated, event)
+	require.Contains(t, data, `"summary":"two"`)
+
+	require.NoError(t, alerts.Put(newAlert("a", now.Add(-time.Second), "two")))
+	event, _ = next()
+	require.Equal(t, eventAlertResolved, event)
+
+	// Silences not matching the filter are not streamed.
+	_, err = silences.Set(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Type: silencepb.Matcher_EQUAL, Name: "job", Pattern: "b"}},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	id, err := silences.Set(&silencepb.Silence{
+		Matchers: []*silencepb.Matcher{{Type: silencepb.Matcher_EQUAL, Name: "job", Pattern: "a"}},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	event, data = next()
+	require.Equal(t, eventSilenceCreated, event)
+	require.Contains(t, data, id)
+
+	require.NoError(t, silences.Expire(id))
+	event, data = next()
+	require.Equal(t, eventSilenceExpired, event)
+	require.Contains(t, data, id)
+}
+
+func TestStreamEventsSlowClient(t *testing.T) {
+	defer func(n int, d time.Duration) {
+		streamQueueSize, streamWriteTimeout = n, d
+	}(streamQueueSize, streamWriteTimeout)
+	streamQueueSize, streamWriteTimeout = 10, 100*time.Millisecond
+
+	marker := types.NewMarker()
+	alerts, err := mem.NewAlerts(marker, time.Hour)
+	require.NoError(t, err)
+	defer alerts.Close()
+	silences, err := silence.New(silence.Options{})
+	require.NoError(t, err)
+
+	api := New(alerts, silences, nil, nil, nil, nil, groupAlerts, marker.Status, nil, nil, nil)
+	require.NoError(t, api.Update(&config.Config{Route: &config.Route{Receiver: "team-X"}}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	// The client opens a stream and never reads from it.
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = fmt.Fprint(conn, "GET /api/v1/alerts/stream HTTP/1.1\r\nHost: alertmanager\r\n\r\n")
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	// Inserting alerts does not block on the stream, even once the
+	// connection buffers are full.
+	summary := model.LabelValue(strings.Repeat("x", 64<<10))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			require.NoError(t, alerts.Put(&types.Alert{Alert: model.Alert{
+				Labels:      model.LabelSet{"alertname": model.LabelValue(fmt.Sprintf("a%d", i))},
+				Annotations: model.LabelSet{"summary": summary},
+				StartsAt:    time.Now(),
+				EndsAt:      time.Now().Add(time.Hour),
+			}}))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("inserting alerts blocked on a stream that is not read")
+	}
+}
+
+func TestAlertHistory(t *testing.T) {
+	api := New(nil, nil, nil, nil, nil, nil, groupAlerts, nil, nil, nil, nil)
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+	path := "/api/v1/alert/" + a.Fingerprint().String() + "/history"
+	get := func(path string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest("GET", path, nil)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// Without a history, no history is served.
+	require.Equal(t, http.StatusNotFound, get(path).Code)
+
+	h := history.New(history.Options{})
+	api.SetHistory(h)
+	require.Equal(t, http.StatusNotFound, get(path).Code)
+	require.Equal(t, http.StatusBadRequest, get("/api/v1/alert/xyz/history").Code)
+
+	h.Marker(types.NewMarker()).SetSilenced(a.Fingerprint(), "s1")
+	w := get(path)
+	require.Equal(t, http.StatusOK, w.Code)
+	var res struct {
+		Data history.Alert `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	require.Equal(t, a.Fingerprint().String(), res.Data.Fingerprint)
+	require.Len(t, res.Data.Events, 1)
+	require.Equal(t, history.EventSilenced, res.Data.Events[0].Type)
+	require.Equal(t, []string{"s1"}, res.Data.Events[0].SilencedBy)
+}
+
+func TestTenantNotificationRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	gkeys := map[string]string{
+		"team-a": `{tenant="team-a"}:{alertname="a"}`,
+		"team-b": `{tenant="team-b"}/{service="db"}:{alertname="b"}`,
+		"":       `{}:{alertname="c"}`,
+	}
+	nl, err := nflog.New()
+	require.NoError(t, err)
+	deadLetters, err := dlq.New(dlq.Options{})
+	require.NoError(t, err)
+	auditLog, err := audit.New(audit.Options{Path: filepath.Join(dir, "audit.log")})
+	require.NoError(t, err)
+	defer auditLog.Close()
+
+	ids := map[string]string{}
+	for name, gkey := range gkeys {
+		recv := &nflogpb.Receiver{GroupName: "team-X", Integration: "webhook"}
+		require.NoError(t, nl.Log(recv, gkey, []uint64{1}, nil, []uint64{7}, nil, 0))
+		ids[name], err = deadLetters.Add(&dlq.Entry{Receiver: "team-X", Integration: "webhook", GroupKey: gkey})
+		require.NoError(t, err)
+		require.NoError(t, auditLog.Log(&audit.Entry{Timestamp: time.Now(), Receiver: "team-X", Integration: "webhook", GroupKey: gkey}))
+	}
+
+	alertsProvider := newFakeAlerts(nil, false)
+	api := New(alertsProvider, nil, nil, deadLetters, auditLog, nl, groupAlerts, newGetAlertStatus(alertsProvider), nil, nil, nil)
+	global := config.DefaultGlobalConfig
+	require.NoError(t, api.Update(&config.Config{
+		Global: &global,
+		Route:  &config.Route{},
+		Tenants: []*config.Tenant{
+			{Name: "team-a", Route: &config.Route{Receiver: "team-X"}},
+			{Name: "team-b", Route: &config.Route{Receiver: "team-X"}},
+		},
+	}, time.Minute))
+	router := route.New()
+	api.Register(router.WithPrefix("/api/v1"))
+
+	do := func(method, url, tenant string) (int, json.RawMessage) {
+		r, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		if tenant != "" {
+			r.Header.Set("X-Alertmanager-Tenant", tenant)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if w.Code != http.StatusNotFound {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		}
+		return w.Code, res.Data
+	}
+	groupKeys := func(url, tenant string) []string {
+		code, data := do("GET", url, tenant)
+		require.Equal(t, http.StatusOK, code)
+		var entries []struct {
+			GroupKey string `json:"groupKey"`
+		}
+		require.NoError(t, json.Unmarshal(data, &entries))
+		res := make([]string, 0, len(entries))
+		for _, e := range entries {
+			res = append(res, e.GroupKey)
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	for _, url := range []string{"/api/v1/nflog", "/api/v1/alert/0000000000000007/notifications", "/api/v1/dlq", "/api/v1/audit"} {
+		require.Equal(t, []string{gkeys["team-a"]}, groupKeys(url, "team-a"), url)
+		require.Equal(t, []string{gkeys["team-b"]}, groupKeys(url, "team-b"), url)
+		require.Equal(t, []string{}, groupKeys(url, "team-c"), url)
+		require.Len(t, groupKeys(url, ""), 3, url)
+	}
+
+	// Dead letters of other tenants are not found.
+	code, _ := do("GET", "/api/v1/dlq/"+ids["team-b"], "team-a")
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = do("POST", "/api/v1/dlq/"+ids["team-b"]+"/replay", "team-a")
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = do("DELETE", "/api/v1/dlq/"+ids["team-b"], "team-a")
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = do("GET", "/api/v1/dlq/"+ids["team-b"], "team-b")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = do("DELETE", "/api/v1/dlq/"+ids["team-b"], "team-b")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, groupKeys("/api/v1/dlq", ""), 2)
+}
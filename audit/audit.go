@@ -0,0 +1,267 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit implements an on-disk log of all notification attempts.
+// Entries are written as JSON lines to a file that is rotated once it
+// exceeds a maximum size.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is a single notification attempt.
+type Entry struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Receiver    string    `json:"receiver"`
+	Integration string    `json:"integration"`
+	GroupKey    string    `json:"groupKey"`
+	// Alerts holds the fingerprints of the notified alerts.
+	Alerts         []string `json:"alerts"`
+	Success        bool     `json:"success"`
+	Error          string   `json:"error,omitempty"`
+	LatencySeconds float64  `json:"latencySeconds"`
+	// StatusCode is the HTTP status code of the last response received
+	// during the attempt, if any.
+	StatusCode int `json:"statusCode,omitempty"`
+}
+
+// Options configures a Log.
+type Options struct {
+	// Path of the current log file. Rotated files get a numeric suffix.
+	Path string
+	// MaxSize is the size in bytes after which the log file is rotated.
+	MaxSize int64
+	// MaxFiles is the number of rotated files that are kept.
+	MaxFiles int
+}
+
+// Log is a rotating notification audit log.
+type Log struct {
+	opts Options
+
+	mtx  sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// New opens the audit log at the configured path.
+func New(o Options) (*Log, error) {
+	l := &Log{opts: o}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	f, err := os.OpenFile(l.opts.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f = f
+	l.size = fi.Size()
+	return nil
+}
+
+func (l *Log) rotatedPath(i int) string {
+	return fmt.Sprintf("%s.%d", l.opts.Path, i)
+}
+
+// rotate moves the current file to the first rotated file and opens a new
+// one. The caller must hold the lock.
+func (l *Log) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	os.Remove(l.rotatedPath(l.opts.MaxFiles))
+	for i := l.opts.MaxFiles - 1; i > 0; i-- {
+		if err := os.Rename(l.rotatedPath(i), l.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if l.opts.MaxFiles > 0 {
+		if err := os.Rename(l.opts.Path, l.rotatedPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.opts.Path); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Log appends an entry to the audit log.
+func (l *Log) Log(e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return l.Append(b)
+}
+
+// Append appends a line to the audit log, rotating the file first if it
+// would exceed the maximum size.
+func (l *Log) Append(line []byte) error {
+	b := append(line, '\n')
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.opts.MaxSize > 0 && l.size > 0 && l.size+int64(len(b)) > l.opts.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.f.Write(b)
+	l.size += int64(n)
+	return err
+}
+
+// Query filters audit log entries. Empty fields match all entries.
+type Query struct {
+	Receiver    string
+	Integration string
+	GroupKey    string
+	// GroupKeys, if set, must return true for the group keys of matching
+	// entries.
+	GroupKeys   func(string) bool
+	Fingerprint string
+	Since       time.Time
+	Until       time.Time
+	// Limit is the maximum number of returned entries. The most recent
+	// entries are returned.
+	Limit int
+}
+
+func (q *Query) matches(e *Entry) bool {
+	if q.Receiver != "" && e.Receiver != q.Receiver {
+		return false
+	}
+	if q.Integration != "" && e.Integration != q.Integration {
+		return false
+	}
+	if q.GroupKey != "" && e.GroupKey != q.GroupKey {
+		return false
+	}
+	if q.GroupKeys != nil && !q.GroupKeys(e.GroupKey) {
+		return false
+	}
+	if !q.Since.IsZero() && e.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && e.Timestamp.After(q.Until) {
+		return false
+	}
+	if q.Fingerprint == "" {
+		return true
+	}
+	for _, fp := range e.Alerts {
+		if fp == q.Fingerprint {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the entries matching the query, oldest first.
+func (l *Log) Query(q Query) ([]*Entry, error) {
+	files, err := l.snapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	var res []*Entry
+	for _, f := range files {
+		sc := bufio.NewScanner(f.r)
+		for sc.Scan() {
+			var e Entry
+			// Skip partially written lines.
+			if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+				continue
+			}
+			if q.matches(&e) {
+				res = append(res, &e)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if q.Limit > 0 && len(res) > q.Limit {
+		res = res[len(res)-q.Limit:]
+	}
+	return res, nil
+}
+
+// snapshotFile is a file of the audit log opened for reading.
+type snapshotFile struct {
+	*os.File
+	r io.Reader
+}
+
+// snapshot opens the files of the audit log, oldest first, so that they can
+// be read without holding the lock. Open files remain readable when they are
+// rotated and the current file is only read up to its size at the time of
+// the snapshot.
+func (l *Log) snapshot() ([]snapshotFile, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var files []snapshotFile
+	for i := l.opts.MaxFiles; i >= 0; i-- {
+		p := l.opts.Path
+		if i > 0 {
+			p = l.rotatedPath(i)
+		}
+		f, err := os.Open(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, err
+		}
+		var r io.Reader = f
+		if i == 0 {
+			r = io.LimitReader(f, l.size)
+		}
+		files = append(files, snapshotFile{File: f, r: r})
+	}
+	return files, nil
+}
+
+// Close closes the audit log.
+func (l *Log) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.f.Close()
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	l, err := New(Options{Path: path, MaxSize: 200, MaxFiles: 2})
+	require.NoError(t, err)
+	defer l.Close()
+
+	now := time.Unix(0, 0).UTC()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, l.Log(&Entry{
+			Timestamp:   now.Add(time.Duration(i) * time.Minute),
+			Receiver:    "team-X",
+			Integration: "webhook",
+			GroupKey:    fmt.Sprint(i),
+		}))
+	}
+
+	_, err = os.Stat(path + ".2")
+	require.NoError(t, err)
+	_, err = os.Stat(path + ".3")
+	require.True(t, os.IsNotExist(err))
+
+	// Only the entries of the kept files are returned, oldest first.
+	entries, err := l.Query(Query{})
+	require.NoError(t, err)
+	require.True(t, len(entries) > 0 && len(entries) < 20)
+	require.Equal(t, "19", entries[len(entries)-1].GroupKey)
+	for i := 1; i < len(entries); i++ {
+		require.True(t, entries[i-1].Timestamp.Before(entries[i].Timestamp))
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := New(Options{Path: filepath.Join(dir, "audit.log")})
+	require.NoError(t, err)
+	defer l.Close()
+
+	now := time.Unix(0, 0).UTC()
+	entries := []*Entry{
+		{Timestamp: now, Receiver: "team-X", Integration: "webhook", Alerts: []string{"a"}, Success: true, StatusCode: 200},
+		{Timestamp: now.Add(time.Hour), Receiver: "team-X", Integration: "email", Alerts: []string{"a", "b"}},
+		{Timestamp: now.Add(2 * time.Hour), Receiver: "team-Y", Integration: "webhook", Alerts: []string{"b"}},
+	}
+	for _, e := range entries {
+		require.NoError(t, l.Log(e))
+	}
+
+	for _, tc := range []struct {
+		q        Query
+		expected []*Entry
+	}{
+		{q: Query{}, expected: entries},
+		{q: Query{Receiver: "team-X"}, expected: entries[:2]},
+		{q: Query{Integration: "webhook"}, expected: []*Entry{entries[0], entries[2]}},
+		{q: Query{Fingerprint: "b"}, expected: entries[1:]},
+		{q: Query{Since: now.Add(time.Hour)}, expected: entries[1:]},
+		{q: Query{Until: now.Add(time.Hour)}, expected: entries[:2]},
+		{q: Query{Limit: 1}, expected: entries[2:]},
+	} {
+		res, err := l.Query(tc.q)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, res)
+	}
+}
+
+func TestLogQueryConcurrentAppend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := New(Options{Path: filepath.Join(dir, "audit.log"), MaxSize: 200, MaxFiles: 2})
+	require.NoError(t, err)
+	defer l.Close()
+
+	now := time.Unix(0, 0).UTC()
+	log := func(i int) error {
+		return l.Log(&Entry{Timestamp: now.Add(time.Duration(i) * time.Minute), Receiver: "team-X", GroupKey: fmt.Sprint(i)})
+	}
+	require.NoError(t, log(0))
+
+	// Entries are appended and the files rotated while a query is scanning
+	// the files.
+	scanning := make(chan struct{})
+	appended := make(chan error)
+	res := make(chan []*Entry)
+	go func() {
+		entries, err := l.Query(Query{GroupKeys: func(string) bool {
+			close(scanning)
+			<-appended
+			return true
+		}})
+		require.NoError(t, err)
+		res <- entries
+	}()
+	<-scanning
+	go func() {
+		for i := 1; i < 10; i++ {
+			if err := log(i); err != nil {
+				appended <- err
+				return
+			}
+		}
+		close(appended)
+	}()
+	select {
+	case err := <-appended:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("appending blocked by query")
+	}
+
+	// The query returns the entries at the time it started.
+	entries := <-res
+	require.Len(t, entries, 1)
+	require.Equal(t, "0", entries[0].GroupKey)
+}
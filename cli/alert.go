@@ -25,11 +25,19 @@ import (
 	"github.com/prometheus/alertmanager/cli/format"
 	"github.com/prometheus/alertmanager/client"
 	"github.com/prometheus/alertmanager/pkg/parse"
+	"github.com/prometheus/alertmanager/pkg/query"
 )
 
+type alertAckCmd struct {
+	author       string
+	comment      string
+	fingerprints []string
+}
+
 type alertQueryCmd struct {
 	inhibited, silenced, active, unprocessed bool
 	receiver                                 string
+	query                                    string
 	matcherGroups                            []string
 }
 
@@ -56,6 +64,13 @@ amtool alert query 'alertname=~foo.*'
 	(similar to prometheus) is used to represent a regex match. Regex matching
 	can be used in combination with a direct match.
 
+amtool alert query --query='severity=~"critical|error" and startsAt > now-2h'
+
+	The --query flag filters alerts by a query, which combines label
+	matchers, the startsAt, endsAt and updatedAt times of alerts and their
+	states (active, suppressed, unprocessed, silenced, inhibited) with and, or,
+	not and parentheses. The query is combined with the matcher groups by and.
+
 Amtool supports several flags for filtering the returned alerts by state
 (inhibited, silenced, active, unprocessed). If none of these flags is given,
 only active alerts are returned.
@@ -72,8 +87,54 @@ func configureAlertCmd(app *kingpin.Application) {
 	queryCmd.Flag("active", "Show active alerts").Short('a').BoolVar(&a.active)
 	queryCmd.Flag("unprocessed", "Show unprocessed alerts").Short('u').BoolVar(&a.unprocessed)
 	queryCmd.Flag("receiver", "Show alerts matching receiver (Supports regex syntax)").Short('r').StringVar(&a.receiver)
+	queryCmd.Flag("query", "Show alerts matching the query").Short('q').StringVar(&a.query)
 	queryCmd.Arg("matcher-groups", "Query filter").StringsVar(&a.matcherGroups)
 	queryCmd.Action(a.queryAlerts)
+
+	ack := &alertAckCmd{}
+	ackCmd := alertCmd.Command("ack", "Acknowledge alerts to stop repeated notifications while they keep firing")
+	ackCmd.Flag("author", "Username for the acknowledgement").Short('a').Default(username()).StringVar(&ack.author)
+	ackCmd.Flag("comment", "A comment to help describe the acknowledgement").Short('c').StringVar(&ack.comment)
+	ackCmd.Arg("fingerprints", "Fingerprints of the alerts to acknowledge").StringsVar(&ack.fingerprints)
+	ackCmd.Action(ack.ack)
+
+	unackCmd := alertCmd.Command("unack", "Withdraw the acknowledgement of alerts")
+	unackCmd.Arg("fingerprints", "Fingerprints of the alerts").StringsVar(&ack.fingerprints)
+	unackCmd.Action(ack.unack)
+}
+
+func (a *alertAckCmd) ack(ctx *kingpin.ParseContext) error {
+	if len(a.fingerprints) < 1 {
+		return errors.New("no alert fingerprints specified")
+	}
+	c, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
+	if err != nil {
+		return err
+	}
+	alertAPI := client.NewAlertAPI(c)
+	for _, fp := range a.fingerprints {
+		if err := alertAPI.Ack(context.Background(), fp, a.author, a.comment); err != nil {
+			return fmt.Errorf("acknowledging %s: %s", fp, err)
+		}
+	}
+	return nil
+}
+
+func (a *alertAckCmd) unack(ctx *kingpin.ParseContext) error {
+	if len(a.fingerprints) < 1 {
+		return errors.New("no alert fingerprints specified")
+	}
+	c, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
+	if err != nil {
+		return err
+	}
+	alertAPI := client.NewAlertAPI(c)
+	for _, fp := range a.fingerprints {
+		if err := alertAPI.Unack(context.Background(), fp); err != nil {
+			return fmt.Errorf("withdrawing acknowledgement of %s: %s", fp, err)
+		}
+	}
+	return nil
 }
 
 func (a *alertQueryCmd) queryAlerts(ctx *kingpin.ParseContext) error {
@@ -91,6 +152,17 @@ func (a *alertQueryCmd) queryAlerts(ctx *kingpin.ParseContext) error {
 	} else if len(a.matcherGroups) > 1 {
 		filterString = fmt.Sprintf("{%s}", strings.Join(a.matcherGroups, ","))
 	}
+	if a.query != "" {
+		// Invalid queries are reported before contacting the Alertmanager.
+		if _, err := query.Parse(a.query); err != nil {
+			return fmt.Errorf("invalid query: %s", err)
+		}
+		if filterString == "" {
+			filterString = a.query
+		} else {
+			filterString = fmt.Sprintf("%s and (%s)", filterString, a.query)
+		}
+	}
 
 	c, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
 	if err != nil {
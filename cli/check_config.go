@@ -29,7 +29,8 @@ type checkConfigCmd struct {
 const checkConfigHelp = `Validate alertmanager config files
 
 Will validate the syntax and schema for alertmanager config file
-and associated templates. Non existing templates will not trigger
+and associated templates. Config files ending in .jsonnet are evaluated
+with the jsonnet command first. Non existing templates will not trigger
 errors.
 `
 
@@ -39,6 +40,9 @@ func configureCheckConfigCmd(app *kingpin.Application) {
 		checkCmd = app.Command("check-config", checkConfigHelp)
 	)
 	checkCmd.Arg("check-files", "Files to be validated").ExistingFilesVar(&c.files)
+	checkCmd.Flag("jsonnet-path", "Library path searched by imports of Jsonnet config files").StringsVar(&config.Jsonnet.LibPaths)
+	checkCmd.Flag("jsonnet-command", "Command evaluating Jsonnet config files").Default("jsonnet").StringVar(&config.Jsonnet.Command)
+	checkCmd.Flag("unknown-fields", "Handling of unknown fields of sections the config sets no handling for").Default(config.UnknownFieldsStrict).EnumVar(&config.DefaultUnknownFields, config.UnknownFieldsStrict, config.UnknownFieldsWarn, config.UnknownFieldsIgnore)
 	checkCmd.Action(c.checkConfig)
 }
 
@@ -54,9 +58,17 @@ func CheckConfig(args []string) error {
 		cfg, _, err := config.LoadFile(arg)
 		if err != nil {
 			fmt.Printf("  FAILED: %s\n", err)
+			if e, ok := err.(*config.Error); ok {
+				for _, p := range e.Problems {
+					fmt.Printf("    %s [%s]\n", p, p.Code)
+				}
+			}
 			failed++
 		} else {
 			fmt.Printf("  SUCCESS\n")
+			for _, w := range cfg.Warnings() {
+				fmt.Printf("    WARNING: %s\n", w)
+			}
 		}
 
 		if cfg != nil {
@@ -68,6 +80,9 @@ func CheckConfig(args []string) error {
 				fmt.Println(" - route")
 			}
 			fmt.Printf(" - %d inhibit rules\n", len(cfg.InhibitRules))
+			if cfg.SeverityInhibition != nil {
+				fmt.Printf(" - severity inhibition of %d severities\n", len(cfg.SeverityInhibition.Order))
+			}
 			fmt.Printf(" - %d receivers\n", len(cfg.Receivers))
 			fmt.Printf(" - %d templates\n", len(cfg.Templates))
 			if len(cfg.Templates) > 0 {
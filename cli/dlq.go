@@ -0,0 +1,130 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/prometheus/alertmanager/cli/format"
+	"github.com/prometheus/alertmanager/client"
+)
+
+type dlqCmd struct {
+	quiet bool
+	ids   []string
+}
+
+const dlqHelp = `View, replay or delete notifications in the dead-letter queue.
+
+Notifications that could not be delivered are kept in the dead-letter queue of
+the Alertmanager. Replaying a notification sends its alerts through the
+notification pipeline of its receiver again and removes it from the queue if
+the notification succeeds.
+
+amtool dlq query
+
+	Lists all notifications in the dead-letter queue.
+
+amtool dlq replay $(amtool dlq query -q)
+
+	Replays all notifications in the dead-letter queue.
+`
+
+// configureDLQCmd represents the dlq command.
+func configureDLQCmd(app *kingpin.Application) {
+	var (
+		c      = &dlqCmd{}
+		dlqCmd = app.Command("dlq", dlqHelp).PreAction(requireAlertManagerURL)
+	)
+
+	queryCmd := dlqCmd.Command("query", "View notifications in the dead-letter queue").Default()
+	queryCmd.Flag("quiet", "Only show notification ids").Short('q').BoolVar(&c.quiet)
+	queryCmd.Action(c.query)
+
+	replayCmd := dlqCmd.Command("replay", "Replay notifications from the dead-letter queue")
+	replayCmd.Arg("ids", "Ids of notifications to replay").StringsVar(&c.ids)
+	replayCmd.Action(c.replay)
+
+	deleteCmd := dlqCmd.Command("delete", "Delete notifications from the dead-letter queue")
+	deleteCmd.Arg("ids", "Ids of notifications to delete").StringsVar(&c.ids)
+	deleteCmd.Action(c.delete)
+}
+
+func (c *dlqCmd) newAPI() (client.DeadLetterAPI, error) {
+	apiClient, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
+	if err != nil {
+		return nil, err
+	}
+	return client.NewDeadLetterAPI(apiClient), nil
+}
+
+func (c *dlqCmd) query(ctx *kingpin.ParseContext) error {
+	dlqAPI, err := c.newAPI()
+	if err != nil {
+		return err
+	}
+	entries, err := dlqAPI.List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if c.quiet {
+		for _, e := range entries {
+			fmt.Println(e.ID)
+		}
+		return nil
+	}
+	formatter, found := format.Formatters[output]
+	if !found {
+		return errors.New("unknown output formatter")
+	}
+	return formatter.FormatDeadLetters(entries)
+}
+
+func (c *dlqCmd) replay(ctx *kingpin.ParseContext) error {
+	if len(c.ids) < 1 {
+		return errors.New("no notification IDs specified")
+	}
+	dlqAPI, err := c.newAPI()
+	if err != nil {
+		return err
+	}
+	for _, id := range c.ids {
+		if err := dlqAPI.Replay(context.Background(), id); err != nil {
+			return fmt.Errorf("replaying %s: %s", id, err)
+		}
+	}
+	return nil
+}
+
+func (c *dlqCmd) delete(ctx *kingpin.ParseContext) error {
+	if len(c.ids) < 1 {
+		return errors.New("no notification IDs specified")
+	}
+	dlqAPI, err := c.newAPI()
+	if err != nil {
+		return err
+	}
+	for _, id := range c.ids {
+		if err := dlqAPI.Delete(context.Background(), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"gopkg.in/alecthomas/kingpin.v2"
 
 	"github.com/prometheus/alertmanager/client"
+	"github.com/prometheus/alertmanager/dlq"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -39,6 +40,8 @@ type Formatter interface {
 	FormatSilences([]types.Silence) error
 	FormatAlerts([]*client.ExtendedAlert) error
 	FormatConfig(*client.ServerStatus) error
+	FormatDeadLetters([]*dlq.Entry) error
+	FormatNotificationLog([]*client.NotificationLogEntry) error
 }
 
 // Formatters is a map of cli argument names to formatter interface object.
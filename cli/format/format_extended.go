@@ -22,6 +22,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/prometheus/alertmanager/client"
+	"github.com/prometheus/alertmanager/dlq"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -89,6 +90,60 @@ func (formatter *ExtendedFormatter) FormatConfig(status *client.ServerStatus) er
 	return nil
 }
 
+func (formatter *ExtendedFormatter) FormatDeadLetters(entries []*dlq.Entry) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTimestamp\tReceiver\tIntegration\tGroup Labels\tAlerts\tError\t")
+	for _, e := range entries {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%d\t%s\t\n",
+			e.ID,
+			FormatDate(e.Timestamp),
+			e.Receiver,
+			e.Integration,
+			e.GroupLabels,
+			len(e.Alerts),
+			e.Error,
+		)
+	}
+	w.Flush()
+	return nil
+}
+
+func (formatter *ExtendedFormatter) FormatNotificationLog(entries []*client.NotificationLogEntry) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Timestamp\tReceiver\tIntegration\tIdx\tGroup Key\tFiring\tResolved\t")
+	for _, e := range entries {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%d\t%s\t%s\t%s\t\n",
+			FormatDate(e.Timestamp),
+			e.Receiver,
+			e.Integration,
+			e.Idx,
+			e.GroupKey,
+			extendedFormatNotificationLogAlerts(e.FiringAlerts),
+			extendedFormatNotificationLogAlerts(e.ResolvedAlerts),
+		)
+	}
+	w.Flush()
+	return nil
+}
+
+// extendedFormatNotificationLogAlerts shows the fingerprints of alerts that
+// are still known and the hashes of all others.
+func extendedFormatNotificationLogAlerts(alerts []client.NotificationLogAlert) string {
+	output := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		if a.Fingerprint != "" {
+			output = append(output, a.Fingerprint)
+			continue
+		}
+		output = append(output, "hash:"+a.Hash)
+	}
+	return strings.Join(output, " ")
+}
+
 func extendedFormatLabels(labels client.LabelSet) string {
 	output := []string{}
 	for name, value := range labels {
@@ -116,6 +171,9 @@ func extendedFormatMatchers(matchers types.Matchers) string {
 }
 
 func extendedFormatMatcher(matcher types.Matcher) string {
+	if matcher.Operator != "" {
+		return matcher.String()
+	}
 	if matcher.IsRegex {
 		return fmt.Sprintf("%s~=%s", matcher.Name, matcher.Value)
 	}
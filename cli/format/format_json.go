@@ -19,6 +19,7 @@ import (
 	"os"
 
 	"github.com/prometheus/alertmanager/client"
+	"github.com/prometheus/alertmanager/dlq"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -48,3 +49,13 @@ func (formatter *JSONFormatter) FormatConfig(status *client.ServerStatus) error
 	enc := json.NewEncoder(formatter.writer)
 	return enc.Encode(status)
 }
+
+func (formatter *JSONFormatter) FormatDeadLetters(entries []*dlq.Entry) error {
+	enc := json.NewEncoder(formatter.writer)
+	return enc.Encode(entries)
+}
+
+func (formatter *JSONFormatter) FormatNotificationLog(entries []*client.NotificationLogEntry) error {
+	enc := json.NewEncoder(formatter.writer)
+	return enc.Encode(entries)
+}
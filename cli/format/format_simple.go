@@ -22,6 +22,7 @@ import (
 	"text/tabwriter"
 
 	"github.com/prometheus/alertmanager/client"
+	"github.com/prometheus/alertmanager/dlq"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -78,6 +79,43 @@ func (formatter *SimpleFormatter) FormatConfig(status *client.ServerStatus) erro
 	return nil
 }
 
+func (formatter *SimpleFormatter) FormatDeadLetters(entries []*dlq.Entry) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTimestamp\tReceiver\tIntegration\tAlerts\t")
+	for _, e := range entries {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%d\t\n",
+			e.ID,
+			FormatDate(e.Timestamp),
+			e.Receiver,
+			e.Integration,
+			len(e.Alerts),
+		)
+	}
+	w.Flush()
+	return nil
+}
+
+func (formatter *SimpleFormatter) FormatNotificationLog(entries []*client.NotificationLogEntry) error {
+	w := tabwriter.NewWriter(formatter.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Timestamp\tReceiver\tIntegration\tFiring\tResolved\tGroup Key\t")
+	for _, e := range entries {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%d\t%d\t%s\t\n",
+			FormatDate(e.Timestamp),
+			e.Receiver,
+			e.Integration,
+			len(e.FiringAlerts),
+			len(e.ResolvedAlerts),
+			e.GroupKey,
+		)
+	}
+	w.Flush()
+	return nil
+}
+
 func simpleFormatMatchers(matchers types.Matchers) string {
 	output := []string{}
 	for _, matcher := range matchers {
@@ -87,6 +125,9 @@ func simpleFormatMatchers(matchers types.Matchers) string {
 }
 
 func simpleFormatMatcher(matcher types.Matcher) string {
+	if matcher.Operator != "" {
+		return matcher.String()
+	}
 	if matcher.IsRegex {
 		return fmt.Sprintf("%s=~%s", matcher.Name, matcher.Value)
 	}
@@ -0,0 +1,79 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+
+	"github.com/prometheus/client_golang/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/prometheus/alertmanager/cli/format"
+	"github.com/prometheus/alertmanager/client"
+)
+
+type nflogCmd struct {
+	receiver string
+	groupKey string
+}
+
+const nflogHelp = `View the notification log.
+
+The notification log holds the last successful notification of every
+aggregation group to every receiver integration. It is used to decide whether
+a group has to be notified again, so it helps to understand why a notification
+was or was not sent.
+
+amtool nflog query --receiver=team-X
+
+	Shows the last notifications of all groups to the receiver team-X.
+
+amtool -o extended nflog query --group-key='{}:{alertname="InstanceDown"}'
+
+	Shows the alerts of the last notifications of a single group. Alerts that
+	are still known to the Alertmanager are shown by their fingerprint.
+`
+
+// configureNotificationLogCmd represents the nflog command.
+func configureNotificationLogCmd(app *kingpin.Application) {
+	var (
+		c        = &nflogCmd{}
+		nflogCmd = app.Command("nflog", nflogHelp).PreAction(requireAlertManagerURL)
+	)
+
+	queryCmd := nflogCmd.Command("query", "View entries of the notification log").Default()
+	queryCmd.Flag("receiver", "Only show notifications to this receiver").StringVar(&c.receiver)
+	queryCmd.Flag("group-key", "Only show notifications of the group with this key").StringVar(&c.groupKey)
+	queryCmd.Action(c.query)
+}
+
+func (c *nflogCmd) query(ctx *kingpin.ParseContext) error {
+	apiClient, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
+	if err != nil {
+		return err
+	}
+	nflogAPI := client.NewNotificationLogAPI(apiClient)
+
+	entries, err := nflogAPI.List(context.Background(), c.receiver, c.groupKey)
+	if err != nil {
+		return err
+	}
+
+	formatter, found := format.Formatters[output]
+	if !found {
+		return errors.New("unknown output formatter")
+	}
+	return formatter.FormatNotificationLog(entries)
+}
@@ -72,6 +72,8 @@ func Execute() {
 
 	configureAlertCmd(app)
 	configureSilenceCmd(app)
+	configureDLQCmd(app)
+	configureNotificationLogCmd(app)
 	configureCheckConfigCmd(app)
 	configureConfigCmd(app)
 
@@ -19,8 +19,11 @@ import "gopkg.in/alecthomas/kingpin.v2"
 func configureSilenceCmd(app *kingpin.Application) {
 	silenceCmd := app.Command("silence", "Add, expire or view silences. For more information and additional flags see query help").PreAction(requireAlertManagerURL)
 	configureSilenceAddCmd(silenceCmd)
+	configureSilenceCommentCmd(silenceCmd)
 	configureSilenceExpireCmd(silenceCmd)
+	configureSilenceExportCmd(silenceCmd)
 	configureSilenceImportCmd(silenceCmd)
 	configureSilenceQueryCmd(silenceCmd)
+	configureSilenceTemplateCmd(silenceCmd)
 	configureSilenceUpdateCmd(silenceCmd)
 }
@@ -24,6 +24,7 @@ import (
 	"github.com/prometheus/common/model"
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/prometheus/alertmanager/cli/format"
 	"github.com/prometheus/alertmanager/client"
 	"github.com/prometheus/alertmanager/types"
 )
@@ -43,7 +44,14 @@ type silenceAddCmd struct {
 	start          string
 	end            string
 	comment        string
+	owner          string
 	matchers       []string
+	fingerprint    string
+	preview        bool
+
+	schedule         string
+	scheduleDuration string
+	scheduleTimeZone string
 }
 
 const silenceAddHelp = `Add a new alertmanager silence
@@ -68,6 +76,17 @@ const silenceAddHelp = `Add a new alertmanager silence
 	As well as direct equality, regex matching is also supported. The '=~' syntax
 	(similar to Prometheus) is used to represent a regex match. Regex matching
 	can be used in combination with a direct match.
+
+  amtool silence add foo 'absent(owner)' 'priority>=3'
+
+	The absent() and present() matchers match alerts without or with the
+	given label. The '<', '<=', '>' and '>=' operators compare label values
+	as numbers, label values that are not numbers never match.
+
+  amtool silence add --fingerprint=1c93eec3511dc156
+
+	Silences exactly the alert with the given fingerprint, as returned by
+	the alerts API, by matching all of its labels.
 `
 
 func configureSilenceAddCmd(cc *kingpin.CmdClause) {
@@ -81,6 +100,12 @@ func configureSilenceAddCmd(cc *kingpin.CmdClause) {
 	addCmd.Flag("start", "Set when the silence should start. RFC3339 format 2006-01-02T15:04:05Z07:00").StringVar(&c.start)
 	addCmd.Flag("end", "Set when the silence should end (overwrites duration). RFC3339 format 2006-01-02T15:04:05Z07:00").StringVar(&c.end)
 	addCmd.Flag("comment", "A comment to help describe the silence").Short('c').StringVar(&c.comment)
+	addCmd.Flag("owner", "Team owning the silence").StringVar(&c.owner)
+	addCmd.Flag("schedule", "Cron expression of the times the silence becomes active within its time range, e.g. '0 22 * * *'").StringVar(&c.schedule)
+	addCmd.Flag("schedule-duration", "How long the silence stays active each time the schedule matches").Default("1h").StringVar(&c.scheduleDuration)
+	addCmd.Flag("schedule-timezone", "IANA time zone the schedule is evaluated in").Default("UTC").StringVar(&c.scheduleTimeZone)
+	addCmd.Flag("preview", "Show the alerts the silence would mute instead of adding it").BoolVar(&c.preview)
+	addCmd.Flag("fingerprint", "Silence exactly the alert with the given fingerprint instead of using matchers").StringVar(&c.fingerprint)
 	addCmd.Arg("matcher-groups", "Query filter").StringsVar(&c.matchers)
 	addCmd.Action(c.add)
 
@@ -94,7 +119,14 @@ func (c *silenceAddCmd) add(ctx *kingpin.ParseContext) error {
 		return err
 	}
 
-	if len(matchers) < 1 {
+	if c.fingerprint != "" {
+		if len(matchers) > 0 {
+			return fmt.Errorf("matchers cannot be specified with a fingerprint")
+		}
+		if c.preview {
+			return fmt.Errorf("preview is not supported with a fingerprint")
+		}
+	} else if len(matchers) < 1 {
 		return fmt.Errorf("no matchers specified")
 	}
 
@@ -145,13 +177,50 @@ func (c *silenceAddCmd) add(ctx *kingpin.ParseContext) error {
 		EndsAt:    endsAt,
 		CreatedBy: c.author,
 		Comment:   c.comment,
+		Owner:     c.owner,
+	}
+	if c.schedule != "" {
+		d, err := model.ParseDuration(c.scheduleDuration)
+		if err != nil {
+			return err
+		}
+		silence.Schedule = &types.SilenceSchedule{
+			Cron:     c.schedule,
+			Duration: d,
+			TimeZone: c.scheduleTimeZone,
+		}
 	}
 
 	apiClient, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
 	if err != nil {
 		return err
 	}
+
+	if c.fingerprint != "" {
+		silenceID, err := client.NewAlertAPI(apiClient).Silence(context.Background(), c.fingerprint, silence)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(silenceID)
+		return err
+	}
+
 	silenceAPI := client.NewSilenceAPI(apiClient)
+
+	if c.preview {
+		preview, err := silenceAPI.Preview(context.Background(), silence)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("This silence would mute %d alerts across %d receivers.\n\n", len(preview.Alerts), len(preview.Receivers))
+
+		formatter, found := format.Formatters[output]
+		if !found {
+			return errors.New("unknown output formatter")
+		}
+		return formatter.FormatAlerts(preview.Alerts)
+	}
+
 	silenceID, err := silenceAPI.Set(context.Background(), silence)
 	if err != nil {
 		return err
@@ -0,0 +1,57 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/prometheus/alertmanager/client"
+)
+
+type silenceCommentCmd struct {
+	author  string
+	id      string
+	comment string
+}
+
+const silenceCommentHelp = `Append a comment to a silence
+
+The comment is added to the thread of the silence and does not change the
+original comment. For example:
+
+amtool silence comment 8b8dc3c4 'Extended until the fix is deployed'
+`
+
+func configureSilenceCommentCmd(cc *kingpin.CmdClause) {
+	var (
+		c          = &silenceCommentCmd{}
+		commentCmd = cc.Command("comment", silenceCommentHelp)
+	)
+	commentCmd.Flag("author", "Username of the comment author").Short('a').Default(username()).StringVar(&c.author)
+	commentCmd.Arg("silence-id", "Id of the silence to comment on").Required().StringVar(&c.id)
+	commentCmd.Arg("comment", "The comment").Required().StringVar(&c.comment)
+	commentCmd.Action(c.addComment)
+}
+
+func (c *silenceCommentCmd) addComment(ctx *kingpin.ParseContext) error {
+	apiClient, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
+	if err != nil {
+		return err
+	}
+	silenceAPI := client.NewSilenceAPI(apiClient)
+	return silenceAPI.AddComment(context.Background(), c.id, c.author, c.comment)
+}
@@ -16,6 +16,8 @@ package cli
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 
 	"github.com/prometheus/client_golang/api"
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -24,7 +26,8 @@ import (
 )
 
 type silenceExpireCmd struct {
-	ids []string
+	ids    []string
+	filter string
 }
 
 func configureSilenceExpireCmd(cc *kingpin.CmdClause) {
@@ -32,13 +35,14 @@ func configureSilenceExpireCmd(cc *kingpin.CmdClause) {
 		c         = &silenceExpireCmd{}
 		expireCmd = cc.Command("expire", "expire an alertmanager silence")
 	)
+	expireCmd.Flag("filter", `Also expire all active and pending silences matching the filter, e.g. {team="db"}`).StringVar(&c.filter)
 	expireCmd.Arg("silence-ids", "Ids of silences to expire").StringsVar(&c.ids)
 	expireCmd.Action(c.expire)
 }
 
 func (c *silenceExpireCmd) expire(ctx *kingpin.ParseContext) error {
-	if len(c.ids) < 1 {
-		return errors.New("no silence IDs specified")
+	if len(c.ids) < 1 && c.filter == "" {
+		return errors.New("no silence IDs or filter specified")
 	}
 
 	apiClient, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
@@ -47,12 +51,20 @@ func (c *silenceExpireCmd) expire(ctx *kingpin.ParseContext) error {
 	}
 	silenceAPI := client.NewSilenceAPI(apiClient)
 
-	for _, id := range c.ids {
-		err := silenceAPI.Expire(context.Background(), id)
-		if err != nil {
-			return err
+	results, err := silenceAPI.ExpireMany(context.Background(), c.ids, c.filter)
+	if err != nil {
+		return err
+	}
+	errCount := 0
+	for _, res := range results {
+		if res.Error != "" {
+			errCount++
+			fmt.Fprintf(os.Stderr, "Error expiring silence id='%v': %v\n", res.SilenceID, res.Error)
 		}
 	}
+	if errCount > 0 {
+		return fmt.Errorf("couldn't expire %v out of %v silences", errCount, len(results))
+	}
 
 	return nil
 }
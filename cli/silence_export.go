@@ -0,0 +1,130 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/prometheus/client_golang/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/client"
+)
+
+type silenceExportCmd struct {
+	format string
+	file   string
+}
+
+const silenceExportHelp = `Export the active and pending silences to a JSON or YAML document
+
+The document can be imported into another Alertmanager with the import
+command. For example:
+
+amtool silence export --format=yaml silences.yml
+
+amtool --alertmanager.url=http://other:9093 silence import silences.yml
+
+The document is written to stdout if no file is specified.
+`
+
+func configureSilenceExportCmd(cc *kingpin.CmdClause) {
+	var (
+		c         = &silenceExportCmd{}
+		exportCmd = cc.Command("export", silenceExportHelp)
+	)
+
+	exportCmd.Flag("format", "Format of the document (json, yaml)").Default("json").EnumVar(&c.format, "json", "yaml")
+	exportCmd.Arg("output-file", "File to write the document to").StringVar(&c.file)
+	exportCmd.Action(c.export)
+}
+
+func (c *silenceExportCmd) export(ctx *kingpin.ParseContext) error {
+	apiClient, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
+	if err != nil {
+		return err
+	}
+	silenceAPI := client.NewSilenceAPI(apiClient)
+	exp, err := silenceAPI.Export(context.Background())
+	if err != nil {
+		return err
+	}
+
+	b, err := encodeSilenceExport(exp, c.format)
+	if err != nil {
+		return err
+	}
+	if c.file == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return ioutil.WriteFile(c.file, b, 0644)
+}
+
+// encodeSilenceExport encodes an exported document in the given format.
+// YAML documents use the field names of the JSON encoding.
+func encodeSilenceExport(exp *client.SilenceExport, format string) ([]byte, error) {
+	b, err := json.MarshalIndent(exp, "", "  ")
+	if err != nil || format == "json" {
+		return b, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// decodeSilenceExport decodes an exported document in JSON or YAML format.
+func decodeSilenceExport(b []byte) (*client.SilenceExport, error) {
+	var exp client.SilenceExport
+	if err := json.Unmarshal(b, &exp); err == nil {
+		return &exp, nil
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(jsonCompatible(v))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &exp); err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
+
+// jsonCompatible converts the maps of a decoded YAML value to maps with
+// string keys, which can be encoded as JSON.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonCompatible(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/client"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSilenceExportRoundTrip(t *testing.T) {
+	now := time.Date(2018, 3, 4, 5, 6, 7, 8, time.UTC)
+	exp := &client.SilenceExport{
+		ExportedAt: now,
+		Silences: []*types.Silence{
+			{
+				ID:        "abc",
+				Matchers:  types.Matchers{{Name: "job", Value: "node.*", IsRegex: true}},
+				StartsAt:  now,
+				EndsAt:    now.Add(time.Hour),
+				UpdatedAt: now,
+				CreatedBy: "alice",
+				Comment:   "maintenance: 1/2",
+				Schedule: &types.SilenceSchedule{
+					Cron:     "0 2 * * *",
+					Duration: model.Duration(time.Hour),
+				},
+				Status: types.SilenceStatus{State: types.SilenceStateActive},
+			},
+		},
+	}
+
+	for _, format := range []string{"json", "yaml"} {
+		b, err := encodeSilenceExport(exp, format)
+		if err != nil {
+			t.Fatalf("encoding %s: %s", format, err)
+		}
+		got, err := decodeSilenceExport(b)
+		if err != nil {
+			t.Fatalf("decoding %s: %s\n%s", format, err, b)
+		}
+		if !got.ExportedAt.Equal(exp.ExportedAt) {
+			t.Errorf("%s: unexpected export time %s", format, got.ExportedAt)
+		}
+		if len(got.Silences) != 1 {
+			t.Fatalf("%s: expected 1 silence, got %d", format, len(got.Silences))
+		}
+		sil, want := got.Silences[0], exp.Silences[0]
+		if sil.ID != want.ID || sil.Comment != want.Comment || !sil.EndsAt.Equal(want.EndsAt) {
+			t.Errorf("%s: unexpected silence %+v", format, sil)
+		}
+		if *sil.Matchers[0] != *want.Matchers[0] {
+			t.Errorf("%s: unexpected matcher %+v", format, sil.Matchers[0])
+		}
+		if *sil.Schedule != *want.Schedule {
+			t.Errorf("%s: unexpected schedule %+v", format, sil.Schedule)
+		}
+	}
+}
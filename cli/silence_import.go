@@ -14,12 +14,16 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/api"
@@ -30,9 +34,10 @@ import (
 )
 
 type silenceImportCmd struct {
-	force   bool
-	workers int
-	file    string
+	force    bool
+	workers  int
+	file     string
+	conflict string
 }
 
 const silenceImportHelp = `Import alertmanager silences from JSON file or stdin
@@ -44,6 +49,13 @@ amtool silence query -o json foo > foo.json
 
 amtool silence import foo.json
 
+It also imports JSON and YAML documents created by the export command.
+Exported silences conflicting with an existing silence with the same ID or
+matchers are skipped, replace the existing silence or are created next to
+it depending on the "--conflict" parameter.
+
+amtool silence import --conflict=replace silences.yml
+
 JSON data can also come from stdin if no param is specified.
 `
 
@@ -55,6 +67,7 @@ func configureSilenceImportCmd(cc *kingpin.CmdClause) {
 
 	importCmd.Flag("force", "Force adding new silences even if it already exists").Short('f').BoolVar(&c.force)
 	importCmd.Flag("worker", "Number of concurrent workers to use for import").Short('w').Default("8").IntVar(&c.workers)
+	importCmd.Flag("conflict", "How to handle exported silences conflicting with existing ones (skip, replace, duplicate)").Default("skip").EnumVar(&c.conflict, "skip", "replace", "duplicate")
 	importCmd.Arg("input-file", "JSON file with silences").ExistingFileVar(&c.file)
 	importCmd.Action(c.bulkImport)
 }
@@ -79,21 +92,14 @@ func addSilenceWorker(sclient client.SilenceAPI, silencec <-chan *types.Silence,
 }
 
 func (c *silenceImportCmd) bulkImport(ctx *kingpin.ParseContext) error {
-	input := os.Stdin
-	var err error
+	var input io.Reader = os.Stdin
 	if c.file != "" {
-		input, err = os.Open(c.file)
+		f, err := os.Open(c.file)
 		if err != nil {
 			return err
 		}
-		defer input.Close()
-	}
-
-	dec := json.NewDecoder(input)
-	// read open square bracket
-	_, err = dec.Token()
-	if err != nil {
-		return errors.Wrap(err, "couldn't unmarshal input data, is it JSON?")
+		defer f.Close()
+		input = f
 	}
 
 	apiClient, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
@@ -101,6 +107,31 @@ func (c *silenceImportCmd) bulkImport(ctx *kingpin.ParseContext) error {
 		return err
 	}
 	silenceAPI := client.NewSilenceAPI(apiClient)
+
+	// Lists of silences are imported one by one, everything else is
+	// expected to be an exported document.
+	br := bufio.NewReader(input)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return errors.Wrap(err, "couldn't read input data")
+		}
+		if unicode.IsSpace(rune(b[0])) {
+			br.ReadByte()
+			continue
+		}
+		if b[0] != '[' {
+			return c.importDocument(silenceAPI, br)
+		}
+		break
+	}
+
+	dec := json.NewDecoder(br)
+	// read open square bracket
+	_, err = dec.Token()
+	if err != nil {
+		return errors.Wrap(err, "couldn't unmarshal input data, is it JSON?")
+	}
 	silencec := make(chan *types.Silence, 100)
 	errc := make(chan error, 100)
 	var wg sync.WaitGroup
@@ -147,3 +178,36 @@ func (c *silenceImportCmd) bulkImport(ctx *kingpin.ParseContext) error {
 	}
 	return nil
 }
+
+// importDocument imports a document created by the export command.
+func (c *silenceImportCmd) importDocument(silenceAPI client.SilenceAPI, r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	exp, err := decodeSilenceExport(b)
+	if err != nil {
+		return errors.Wrap(err, "couldn't unmarshal input data, is it JSON or YAML?")
+	}
+
+	results, err := silenceAPI.Import(context.Background(), exp, c.conflict)
+	if err != nil {
+		return err
+	}
+	errCount := 0
+	for _, res := range results {
+		switch res.Action {
+		case "failed":
+			errCount++
+			fmt.Fprintf(os.Stderr, "Error importing silence id='%v': %v\n", res.ID, res.Error)
+		case "skipped":
+			fmt.Fprintf(os.Stderr, "Skipped silence id='%v'\n", res.ID)
+		default:
+			fmt.Println(res.SilenceID)
+		}
+	}
+	if errCount > 0 {
+		return fmt.Errorf("couldn't import %v out of %v silences", errCount, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/prometheus/client_golang/api"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/prometheus/alertmanager/client"
+)
+
+type silenceTemplateCmd struct {
+	author  string
+	comment string
+	name    string
+	params  []string
+}
+
+const silenceTemplateHelp = `Add a silence from a silence template of the configuration
+
+  amtool silence template
+
+	Without arguments the available templates and their parameters are listed.
+
+  amtool silence template node-maintenance HOST=abc
+
+	This statement will add a silence from the node-maintenance template with
+	the HOST parameter set to abc.
+`
+
+func configureSilenceTemplateCmd(cc *kingpin.CmdClause) {
+	var (
+		c       = &silenceTemplateCmd{}
+		tmplCmd = cc.Command("template", silenceTemplateHelp)
+	)
+	tmplCmd.Flag("author", "Username for CreatedBy field").Short('a').Default(username()).StringVar(&c.author)
+	tmplCmd.Flag("comment", "A comment replacing the comment of the template").Short('c').StringVar(&c.comment)
+	tmplCmd.Arg("name", "Name of the silence template").StringVar(&c.name)
+	tmplCmd.Arg("parameters", "Parameters of the template as name=value pairs").StringsVar(&c.params)
+	tmplCmd.Action(c.run)
+}
+
+func (c *silenceTemplateCmd) run(ctx *kingpin.ParseContext) error {
+	apiClient, err := api.NewClient(api.Config{Address: alertmanagerURL.String()})
+	if err != nil {
+		return err
+	}
+	silenceAPI := client.NewSilenceAPI(apiClient)
+
+	if c.name == "" {
+		tmpls, err := silenceAPI.Templates(context.Background())
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Name\tParameters\tDuration\tComment\t")
+		for _, t := range tmpls {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", t.Name, strings.Join(t.Parameters, ","), t.Duration, t.Comment)
+		}
+		return w.Flush()
+	}
+
+	params := make(map[string]string, len(c.params))
+	for _, p := range c.params {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid parameter %q, expected name=value", p)
+		}
+		params[kv[0]] = kv[1]
+	}
+
+	silenceID, err := silenceAPI.SetFromTemplate(context.Background(), c.name, params, c.author, c.comment)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Println(silenceID)
+	return err
+}
@@ -34,6 +34,7 @@ type silenceUpdateCmd struct {
 	start    string
 	end      string
 	comment  string
+	owner    string
 	ids      []string
 }
 
@@ -47,6 +48,7 @@ func configureSilenceUpdateCmd(cc *kingpin.CmdClause) {
 	updateCmd.Flag("start", "Set when the silence should start. RFC3339 format 2006-01-02T15:04:05Z07:00").StringVar(&c.start)
 	updateCmd.Flag("end", "Set when the silence should end (overwrites duration). RFC3339 format 2006-01-02T15:04:05Z07:00").StringVar(&c.end)
 	updateCmd.Flag("comment", "A comment to help describe the silence").Short('c').StringVar(&c.comment)
+	updateCmd.Flag("owner", "Team owning the silence").StringVar(&c.owner)
 	updateCmd.Arg("update-ids", "Silence IDs to update").StringsVar(&c.ids)
 
 	updateCmd.Action(c.update)
@@ -99,6 +101,9 @@ func (c *silenceUpdateCmd) update(ctx *kingpin.ParseContext) error {
 		if c.comment != "" {
 			silence.Comment = c.comment
 		}
+		if c.owner != "" {
+			silence.Owner = c.owner
+		}
 
 		newID, err := silenceAPI.Set(context.Background(), *silence)
 		if err != nil {
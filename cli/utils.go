@@ -18,10 +18,9 @@ import (
 	"net/url"
 	"path"
 
+	"github.com/prometheus/alertmanager/pkg/labels"
 	"github.com/prometheus/alertmanager/pkg/parse"
 	"github.com/prometheus/alertmanager/types"
-	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/pkg/labels"
 )
 
 type ByAlphabetical []labels.Matcher
@@ -81,14 +80,12 @@ func TypeMatchers(matchers []labels.Matcher) (types.Matchers, error) {
 // Only valid for when you are going to add a silence
 // Doesn't allow negative operators
 func TypeMatcher(matcher labels.Matcher) (types.Matcher, error) {
-	typeMatcher := types.NewMatcher(model.LabelName(matcher.Name), matcher.Value)
-
-	switch matcher.Type {
-	case labels.MatchEqual:
-		typeMatcher.IsRegex = false
-	case labels.MatchRegexp:
-		typeMatcher.IsRegex = true
-	default:
+	lm, err := labels.NewMatcher(matcher.Type, matcher.Name, matcher.Value)
+	if err != nil {
+		return types.Matcher{}, err
+	}
+	typeMatcher, err := types.NewMatcherFromLabels(lm)
+	if err != nil {
 		return types.Matcher{}, fmt.Errorf("invalid match type for creation operation: %s", matcher.Type)
 	}
 	return *typeMatcher, nil
@@ -20,22 +20,48 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/api"
+	"github.com/prometheus/common/model"
 
+	"github.com/prometheus/alertmanager/ack"
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dlq"
 	"github.com/prometheus/alertmanager/types"
 )
 
 const (
 	apiPrefix = "/api/v1"
 
-	epStatus      = apiPrefix + "/status"
-	epSilence     = apiPrefix + "/silence/:id"
-	epSilences    = apiPrefix + "/silences"
-	epAlerts      = apiPrefix + "/alerts"
-	epAlertGroups = apiPrefix + "/alerts/groups"
+	epStatus       = apiPrefix + "/status"
+	epSilence      = apiPrefix + "/silence/:id"
+	epSilenceHist  = apiPrefix + "/silence/:id/history"
+	epSilenceComm  = apiPrefix + "/silence/:id/comments"
+	epSilences     = apiPrefix + "/silences"
+	epSilencesPrev = apiPrefix + "/silences/preview"
+	epSilencesExp  = apiPrefix + "/silences/export"
+	epSilencesBulk = apiPrefix + "/silences/bulk"
+	epSilencesExpr = apiPrefix + "/silences/expire"
+	epSilencesImp  = apiPrefix + "/silences/import"
+	epSilenceTmpl  = apiPrefix + "/silence_templates/:name"
+	epSilenceTmpls = apiPrefix + "/silence_templates"
+	epAlerts       = apiPrefix + "/alerts"
+	epAlertGroups  = apiPrefix + "/alerts/groups"
+	epAlertAck     = apiPrefix + "/alert/:fingerprint/ack"
+	epAlertSilence = apiPrefix + "/alert/:fingerprint/silence"
+	epAlertInhibs  = apiPrefix + "/alert/:fingerprint/inhibitors"
+	epInhibitions  = apiPrefix + "/inhibitions"
+
+	epDeadLetters      = apiPrefix + "/dlq"
+	epDeadLetter       = apiPrefix + "/dlq/:id"
+	epDeadLetterReplay = apiPrefix + "/dlq/:id/replay"
+
+	epNotificationLog = apiPrefix + "/nflog"
+
+	epRoutesTest = apiPrefix + "/routes/test"
 
 	statusSuccess = "success"
 	statusError   = "error"
@@ -157,6 +183,20 @@ type AlertAPI interface {
 	List(ctx context.Context, filter, receiver string, silenced, inhibited, active, unprocessed bool) ([]*ExtendedAlert, error)
 	// Push sends a list of alerts to the Alertmanager.
 	Push(ctx context.Context, alerts ...Alert) error
+	// Ack acknowledges the alert with the given fingerprint.
+	Ack(ctx context.Context, fingerprint, createdBy, comment string) error
+	// Unack withdraws the acknowledgement of the alert with the given
+	// fingerprint.
+	Unack(ctx context.Context, fingerprint string) error
+	// Silence creates a silence matching exactly the labels of the alert
+	// with the given fingerprint and returns its ID. The given silence
+	// must not have matchers.
+	Silence(ctx context.Context, fingerprint string, sil types.Silence) (string, error)
+	// Inhibitors returns the inhibitions of the alert with the given
+	// fingerprint.
+	Inhibitors(ctx context.Context, fingerprint string) ([]*Inhibition, error)
+	// Inhibitions returns the inhibitions of all active alerts.
+	Inhibitions(ctx context.Context) ([]*Inhibition, error)
 }
 
 // Alert represents an alert as expected by the AlertManager's push alert API.
@@ -174,6 +214,23 @@ type ExtendedAlert struct {
 	Status      types.AlertStatus `json:"status"`
 	Receivers   []string          `json:"receivers"`
 	Fingerprint string            `json:"fingerprint"`
+
+	Acknowledgement *ack.Ack `json:"acknowledgement,omitempty"`
+}
+
+// Inhibition represents the inhibition of a target alert by a source alert
+// according to an inhibition rule.
+type Inhibition struct {
+	RuleIndex int                 `json:"ruleIndex"`
+	Rule      *config.InhibitRule `json:"rule,omitempty"`
+	Source    InhibitionAlert     `json:"source"`
+	Target    InhibitionAlert     `json:"target"`
+}
+
+// InhibitionAlert identifies an alert of an inhibition.
+type InhibitionAlert struct {
+	Fingerprint string   `json:"fingerprint"`
+	Labels      LabelSet `json:"labels"`
 }
 
 // LabelSet represents a collection of label names and values as a map.
@@ -234,6 +291,87 @@ func (h *httpAlertAPI) Push(ctx context.Context, alerts ...Alert) error {
 	return err
 }
 
+func (h *httpAlertAPI) Ack(ctx context.Context, fingerprint, createdBy, comment string) error {
+	u := h.client.URL(epAlertAck, map[string]string{
+		"fingerprint": fingerprint,
+	})
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]string{
+		"createdBy": createdBy,
+		"comment":   comment,
+	}); err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, _, err := h.client.Do(ctx, req)
+	return err
+}
+
+func (h *httpAlertAPI) Unack(ctx context.Context, fingerprint string) error {
+	u := h.client.URL(epAlertAck, map[string]string{
+		"fingerprint": fingerprint,
+	})
+
+	req, _ := http.NewRequest(http.MethodDelete, u.String(), nil)
+
+	_, _, err := h.client.Do(ctx, req)
+	return err
+}
+
+func (h *httpAlertAPI) Silence(ctx context.Context, fingerprint string, sil types.Silence) (string, error) {
+	u := h.client.URL(epAlertSilence, map[string]string{
+		"fingerprint": fingerprint,
+	})
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&sil); err != nil {
+		return "", err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		SilenceID string `json:"silenceId"`
+	}
+	err = json.Unmarshal(body, &res)
+
+	return res.SilenceID, err
+}
+
+func (h *httpAlertAPI) Inhibitors(ctx context.Context, fingerprint string) ([]*Inhibition, error) {
+	u := h.client.URL(epAlertInhibs, map[string]string{
+		"fingerprint": fingerprint,
+	})
+	return h.inhibitions(ctx, u.String())
+}
+
+func (h *httpAlertAPI) Inhibitions(ctx context.Context) ([]*Inhibition, error) {
+	u := h.client.URL(epInhibitions, nil)
+	return h.inhibitions(ctx, u.String())
+}
+
+func (h *httpAlertAPI) inhibitions(ctx context.Context, u string) ([]*Inhibition, error) {
+	req, _ := http.NewRequest(http.MethodGet, u, nil)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*Inhibition
+	err = json.Unmarshal(body, &res)
+
+	return res, err
+}
+
 // SilenceAPI provides bindings for the Alertmanager's silence API.
 type SilenceAPI interface {
 	// Get returns the silence associated with the given ID.
@@ -242,8 +380,83 @@ type SilenceAPI interface {
 	Set(ctx context.Context, sil types.Silence) (string, error)
 	// Expire expires the silence with the given ID.
 	Expire(ctx context.Context, id string) error
+	// SetMany updates or creates the given silences. The results are in
+	// the order of the silences.
+	SetMany(ctx context.Context, sils []types.Silence) ([]*BulkSilenceResult, error)
+	// ExpireMany expires the silences with the given IDs and the active
+	// and pending silences matching the filter.
+	ExpireMany(ctx context.Context, ids []string, filter string) ([]*BulkSilenceResult, error)
+	// History returns the changes made to the silence with the given ID.
+	History(ctx context.Context, id string) ([]*types.SilenceChange, error)
+	// AddComment appends a comment to the silence with the given ID.
+	AddComment(ctx context.Context, id, author, comment string) error
 	// List returns silences matching the given filter.
 	List(ctx context.Context, filter string) ([]*types.Silence, error)
+	// Query returns a page of the silences selected by the options and
+	// the total number of selected silences.
+	Query(ctx context.Context, opts SilenceQueryOptions) ([]*types.Silence, int, error)
+	// Preview returns the alerts the given silence would mute without
+	// creating it.
+	Preview(ctx context.Context, sil types.Silence) (*SilencePreview, error)
+	// Export returns all active and pending silences.
+	Export(ctx context.Context) (*SilenceExport, error)
+	// Import creates the silences of an exported document. Conflicts with
+	// existing silences are resolved according to conflict, which is one
+	// of skip, replace or duplicate.
+	Import(ctx context.Context, exp *SilenceExport, conflict string) ([]*SilenceImportResult, error)
+	// Templates returns the silence templates of the configuration.
+	Templates(ctx context.Context) ([]*config.SilenceTemplate, error)
+	// SetFromTemplate creates a silence from the silence template with the
+	// given name and parameters and returns its ID.
+	SetFromTemplate(ctx context.Context, name string, params map[string]string, createdBy, comment string) (string, error)
+}
+
+// BulkSilenceResult is the result of a bulk operation for one silence.
+type BulkSilenceResult struct {
+	SilenceID string `json:"silenceId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SilenceQueryOptions select, sort and paginate the silences returned by
+// SilenceAPI.Query.
+type SilenceQueryOptions struct {
+	// Filter is a matcher expression like {job="node"}.
+	Filter string
+	States []types.SilenceState
+	// CreatedBy matches the creator exactly, Comment matches a substring
+	// of the comment regardless of case.
+	CreatedBy string
+	Comment   string
+	// Sort is one of startsAt, endsAt, updatedAt or createdBy, prefixed
+	// with "-" for descending order.
+	Sort string
+	// A limit of 0 returns all silences.
+	Limit  int
+	Offset int
+}
+
+// SilenceExport is a portable document of silences.
+type SilenceExport struct {
+	ExportedAt time.Time        `json:"exportedAt"`
+	Silences   []*types.Silence `json:"silences"`
+}
+
+// SilenceImportResult is the outcome of importing a silence.
+type SilenceImportResult struct {
+	// The ID of the silence in the imported document.
+	ID string `json:"id"`
+	// The ID of the created, replaced or conflicting silence.
+	SilenceID string `json:"silenceId,omitempty"`
+	// One of created, replaced, skipped or failed.
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SilencePreview lists the alerts a silence would mute.
+type SilencePreview struct {
+	Alerts []*ExtendedAlert `json:"alerts"`
+	// Receivers maps the receivers of the alerts to their number of alerts.
+	Receivers map[string]int `json:"receivers"`
 }
 
 // NewSilenceAPI returns a new SilenceAPI for the client.
@@ -284,6 +497,91 @@ func (h *httpSilenceAPI) Expire(ctx context.Context, id string) error {
 	return err
 }
 
+func (h *httpSilenceAPI) SetMany(ctx context.Context, sils []types.Silence) ([]*BulkSilenceResult, error) {
+	u := h.client.URL(epSilencesBulk, nil)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(sils); err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*BulkSilenceResult
+	err = json.Unmarshal(body, &res)
+
+	return res, err
+}
+
+func (h *httpSilenceAPI) ExpireMany(ctx context.Context, ids []string, filter string) ([]*BulkSilenceResult, error) {
+	u := h.client.URL(epSilencesExpr, nil)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(struct {
+		IDs    []string `json:"ids,omitempty"`
+		Filter string   `json:"filter,omitempty"`
+	}{
+		IDs:    ids,
+		Filter: filter,
+	}); err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*BulkSilenceResult
+	err = json.Unmarshal(body, &res)
+
+	return res, err
+}
+
+func (h *httpSilenceAPI) History(ctx context.Context, id string) ([]*types.SilenceChange, error) {
+	u := h.client.URL(epSilenceHist, map[string]string{
+		"id": id,
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*types.SilenceChange
+	err = json.Unmarshal(body, &changes)
+
+	return changes, err
+}
+
+func (h *httpSilenceAPI) AddComment(ctx context.Context, id, author, comment string) error {
+	u := h.client.URL(epSilenceComm, map[string]string{
+		"id": id,
+	})
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&types.SilenceComment{
+		Author:  author,
+		Comment: comment,
+	}); err != nil {
+		return err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, _, err := h.client.Do(ctx, req)
+	return err
+}
+
 func (h *httpSilenceAPI) Set(ctx context.Context, sil types.Silence) (string, error) {
 	u := h.client.URL(epSilences, nil)
 
@@ -327,3 +625,362 @@ func (h *httpSilenceAPI) List(ctx context.Context, filter string) ([]*types.Sile
 
 	return sils, err
 }
+
+func (h *httpSilenceAPI) Query(ctx context.Context, opts SilenceQueryOptions) ([]*types.Silence, int, error) {
+	u := h.client.URL(epSilences, nil)
+	params := url.Values{}
+	if opts.Filter != "" {
+		params.Add("filter", opts.Filter)
+	}
+	if len(opts.States) > 0 {
+		states := make([]string, 0, len(opts.States))
+		for _, st := range opts.States {
+			states = append(states, string(st))
+		}
+		params.Add("state", strings.Join(states, ","))
+	}
+	if opts.CreatedBy != "" {
+		params.Add("createdBy", opts.CreatedBy)
+	}
+	if opts.Comment != "" {
+		params.Add("comment", opts.Comment)
+	}
+	if opts.Sort != "" {
+		params.Add("sort", opts.Sort)
+	}
+	if opts.Limit > 0 {
+		params.Add("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		params.Add("offset", strconv.Itoa(opts.Offset))
+	}
+	u.RawQuery = params.Encode()
+
+	req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+
+	resp, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var sils []*types.Silence
+	if err = json.Unmarshal(body, &sils); err != nil {
+		return nil, 0, err
+	}
+	total := len(sils)
+	if s := resp.Header.Get("X-Total-Count"); s != "" {
+		if total, err = strconv.Atoi(s); err != nil {
+			return nil, 0, fmt.Errorf("invalid total count %q", s)
+		}
+	}
+
+	return sils, total, nil
+}
+
+func (h *httpSilenceAPI) Preview(ctx context.Context, sil types.Silence) (*SilencePreview, error) {
+	u := h.client.URL(epSilencesPrev, nil)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(&sil); err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res SilencePreview
+	err = json.Unmarshal(body, &res)
+
+	return &res, err
+}
+
+func (h *httpSilenceAPI) Export(ctx context.Context) (*SilenceExport, error) {
+	u := h.client.URL(epSilencesExp, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var exp SilenceExport
+	err = json.Unmarshal(body, &exp)
+
+	return &exp, err
+}
+
+func (h *httpSilenceAPI) Import(ctx context.Context, exp *SilenceExport, conflict string) ([]*SilenceImportResult, error) {
+	u := h.client.URL(epSilencesImp, nil)
+	params := url.Values{}
+	if conflict != "" {
+		params.Add("conflict", conflict)
+	}
+	u.RawQuery = params.Encode()
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(exp); err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*SilenceImportResult
+	err = json.Unmarshal(body, &res)
+
+	return res, err
+}
+
+func (h *httpSilenceAPI) Templates(ctx context.Context) ([]*config.SilenceTemplate, error) {
+	u := h.client.URL(epSilenceTmpls, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var tmpls []*config.SilenceTemplate
+	err = json.Unmarshal(body, &tmpls)
+
+	return tmpls, err
+}
+
+func (h *httpSilenceAPI) SetFromTemplate(ctx context.Context, name string, params map[string]string, createdBy, comment string) (string, error) {
+	u := h.client.URL(epSilenceTmpl, map[string]string{
+		"name": name,
+	})
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"parameters": params,
+		"createdBy":  createdBy,
+		"comment":    comment,
+	}); err != nil {
+		return "", err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	var res struct {
+		SilenceID string `json:"silenceId"`
+	}
+	err = json.Unmarshal(body, &res)
+
+	return res.SilenceID, err
+}
+
+// DeadLetterAPI provides bindings for the Alertmanager's dead-letter queue API.
+type DeadLetterAPI interface {
+	// List returns all notifications in the dead-letter queue.
+	List(ctx context.Context) ([]*dlq.Entry, error)
+	// Delete removes the notification with the given ID.
+	Delete(ctx context.Context, id string) error
+	// Replay sends the notification with the given ID again.
+	Replay(ctx context.Context, id string) error
+}
+
+// NewDeadLetterAPI returns a new DeadLetterAPI for the client.
+func NewDeadLetterAPI(c api.Client) DeadLetterAPI {
+	return &httpDeadLetterAPI{client: apiClient{c}}
+}
+
+type httpDeadLetterAPI struct {
+	client api.Client
+}
+
+func (h *httpDeadLetterAPI) List(ctx context.Context) ([]*dlq.Entry, error) {
+	u := h.client.URL(epDeadLetters, nil)
+
+	req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*dlq.Entry
+	err = json.Unmarshal(body, &entries)
+
+	return entries, err
+}
+
+func (h *httpDeadLetterAPI) Delete(ctx context.Context, id string) error {
+	u := h.client.URL(epDeadLetter, map[string]string{
+		"id": id,
+	})
+
+	req, _ := http.NewRequest(http.MethodDelete, u.String(), nil)
+
+	_, _, err := h.client.Do(ctx, req)
+	return err
+}
+
+func (h *httpDeadLetterAPI) Replay(ctx context.Context, id string) error {
+	u := h.client.URL(epDeadLetterReplay, map[string]string{
+		"id": id,
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), nil)
+
+	_, _, err := h.client.Do(ctx, req)
+	return err
+}
+
+// NotificationLogEntry is the last successful notification of an aggregation
+// group to a receiver integration.
+type NotificationLogEntry struct {
+	GroupKey       string                 `json:"groupKey"`
+	Receiver       string                 `json:"receiver"`
+	Integration    string                 `json:"integration"`
+	Idx            uint32                 `json:"idx"`
+	Timestamp      time.Time              `json:"timestamp"`
+	FiringAlerts   []NotificationLogAlert `json:"firingAlerts"`
+	ResolvedAlerts []NotificationLogAlert `json:"resolvedAlerts"`
+}
+
+// NotificationLogAlert is an alert of a notification log entry. The
+// fingerprint is empty if the alert is no longer known to the Alertmanager.
+type NotificationLogAlert struct {
+	Hash        string `json:"hash"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// NotificationLogAPI provides bindings for the Alertmanager's notification
+// log API.
+type NotificationLogAPI interface {
+	// List returns the entries of the notification log. Empty receiver and
+	// group key arguments match all entries.
+	List(ctx context.Context, receiver, groupKey string) ([]*NotificationLogEntry, error)
+}
+
+// NewNotificationLogAPI returns a new NotificationLogAPI for the client.
+func NewNotificationLogAPI(c api.Client) NotificationLogAPI {
+	return &httpNotificationLogAPI{client: apiClient{c}}
+}
+
+type httpNotificationLogAPI struct {
+	client api.Client
+}
+
+func (h *httpNotificationLogAPI) List(ctx context.Context, receiver, groupKey string) ([]*NotificationLogEntry, error) {
+	u := h.client.URL(epNotificationLog, nil)
+	params := url.Values{}
+	if receiver != "" {
+		params.Add("receiver", receiver)
+	}
+	if groupKey != "" {
+		params.Add("groupKey", groupKey)
+	}
+	u.RawQuery = params.Encode()
+
+	req, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*NotificationLogEntry
+	err = json.Unmarshal(body, &entries)
+
+	return entries, err
+}
+
+// RouteOpts are the options of a route, including those inherited from its
+// parent routes.
+type RouteOpts struct {
+	Receiver            string           `json:"receiver"`
+	FallbackReceiver    string           `json:"fallbackReceiver,omitempty"`
+	Split               *ReceiverSplit   `json:"split,omitempty"`
+	GroupBy             model.LabelNames `json:"groupBy"`
+	GroupByAll          bool             `json:"groupByAll,omitempty"`
+	GroupByRegex        string           `json:"groupByRegex,omitempty"`
+	GroupWait           time.Duration    `json:"groupWait"`
+	GroupInterval       time.Duration    `json:"groupInterval"`
+	RepeatInterval      time.Duration    `json:"repeatInterval"`
+	MuteTimeIntervals   []string         `json:"muteTimeIntervals,omitempty"`
+	ActiveTimeIntervals []string         `json:"activeTimeIntervals,omitempty"`
+}
+
+// ReceiverSplit holds the distribution of the notifications of a route
+// among receivers.
+type ReceiverSplit struct {
+	By        string             `json:"by"`
+	Receivers []WeightedReceiver `json:"receivers"`
+}
+
+// WeightedReceiver is a receiver getting a percentage of the notifications
+// of a route.
+type WeightedReceiver struct {
+	Receiver string `json:"receiver"`
+	Percent  int    `json:"percent"`
+}
+
+// RouteTestResult holds the routes matching a label set.
+type RouteTestResult struct {
+	Labels model.LabelSet    `json:"labels"`
+	Routes []*RouteTestMatch `json:"routes"`
+}
+
+// RouteTestMatch is a route matching a label set. Path holds the matchers
+// of the routes from the root route to the matched route and Indices their
+// positions among the child routes of their parent.
+type RouteTestMatch struct {
+	Path        []string       `json:"path"`
+	Indices     []int          `json:"indices"`
+	RouteOpts   RouteOpts      `json:"routeOpts"`
+	GroupLabels model.LabelSet `json:"groupLabels"`
+}
+
+// RouteAPI provides bindings for the Alertmanager's route API.
+type RouteAPI interface {
+	// Test returns the routes of the running configuration matching each
+	// of the label sets.
+	Test(ctx context.Context, lsets ...model.LabelSet) ([]*RouteTestResult, error)
+}
+
+// NewRouteAPI returns a new RouteAPI for the client.
+func NewRouteAPI(c api.Client) RouteAPI {
+	return &httpRouteAPI{client: apiClient{c}}
+}
+
+type httpRouteAPI struct {
+	client api.Client
+}
+
+func (h *httpRouteAPI) Test(ctx context.Context, lsets ...model.LabelSet) ([]*RouteTestResult, error) {
+	u := h.client.URL(epRoutesTest, nil)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(lsets); err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, u.String(), &buf)
+
+	_, body, err := h.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*RouteTestResult
+	err = json.Unmarshal(body, &res)
+
+	return res, err
+}
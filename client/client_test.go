@@ -24,6 +24,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/common/model"
+
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/types"
 )
@@ -124,6 +126,73 @@ func TestAPI(t *testing.T) {
 		api := httpAlertAPI{client: client}
 		return nil, api.Push(context.Background(), []Alert{alertOne}...)
 	}
+	doAlertAck := func() (interface{}, error) {
+		api := httpAlertAPI{client: client}
+		return nil, api.Ack(context.Background(), "1c93eec3511dc156", "alice", "some comment")
+	}
+	doAlertUnack := func() (interface{}, error) {
+		api := httpAlertAPI{client: client}
+		return nil, api.Unack(context.Background(), "1c93eec3511dc156")
+	}
+	doAlertSilence := func() (interface{}, error) {
+		api := httpAlertAPI{client: client}
+		return api.Silence(context.Background(), "1c93eec3511dc156", types.Silence{
+			EndsAt:    time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+			CreatedBy: "alice",
+			Comment:   "some comment",
+		})
+	}
+
+	inhibitions := []*Inhibition{{
+		RuleIndex: 0,
+		Source:    InhibitionAlert{Fingerprint: "5f1f8bd2a7e1e3a4", Labels: LabelSet{"severity": "critical"}},
+		Target:    InhibitionAlert{Fingerprint: "1c93eec3511dc156", Labels: LabelSet{"severity": "warning"}},
+	}}
+	doAlertInhibitors := func() (interface{}, error) {
+		api := httpAlertAPI{client: client}
+		return api.Inhibitors(context.Background(), "1c93eec3511dc156")
+	}
+	doInhibitions := func() (interface{}, error) {
+		api := httpAlertAPI{client: client}
+		return api.Inhibitions(context.Background())
+	}
+
+	nflogEntries := []*NotificationLogEntry{{
+		GroupKey:    "{}:{alertname=\"a\"}",
+		Receiver:    "team-X",
+		Integration: "webhook",
+		Timestamp:   time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+		FiringAlerts: []NotificationLogAlert{{
+			Hash:        "5f1f8bd2a7e1e3a4",
+			Fingerprint: "1c93eec3511dc156",
+		}},
+		ResolvedAlerts: []NotificationLogAlert{},
+	}}
+	doNotificationLogList := func() (interface{}, error) {
+		api := httpNotificationLogAPI{client: client}
+		return api.List(context.Background(), "team-X", "")
+	}
+
+	routeTestResults := []*RouteTestResult{{
+		Labels: model.LabelSet{"team": "db"},
+		Routes: []*RouteTestMatch{{
+			Path:    []string{"{}", `{team="db"}`},
+			Indices: []int{0},
+			RouteOpts: RouteOpts{
+				Receiver:          "db",
+				GroupBy:           model.LabelNames{"alertname"},
+				GroupWait:         30 * time.Second,
+				GroupInterval:     5 * time.Minute,
+				RepeatInterval:    4 * time.Hour,
+				MuteTimeIntervals: []string{"offhours"},
+			},
+			GroupLabels: model.LabelSet{},
+		}},
+	}}
+	doRouteTest := func() (interface{}, error) {
+		api := httpRouteAPI{client: client}
+		return api.Test(context.Background(), model.LabelSet{"team": "db"})
+	}
 
 	silOne := &types.Silence{
 		ID: "abc",
@@ -161,11 +230,89 @@ func TestAPI(t *testing.T) {
 			return nil, api.Expire(context.Background(), id)
 		}
 	}
+	doSilenceAddComment := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return nil, api.AddComment(context.Background(), "abc", "bob", "extended")
+	}
+	silenceHistory := []*types.SilenceChange{
+		{
+			Type:       types.SilenceChangeCreated,
+			Timestamp:  now,
+			Author:     "alice",
+			RemoteAddr: "10.0.0.1",
+		},
+	}
+	doSilenceHistory := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return api.History(context.Background(), "abc")
+	}
+	silenceExport := &SilenceExport{
+		ExportedAt: now,
+		Silences:   []*types.Silence{silOne},
+	}
+	doSilenceExport := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return api.Export(context.Background())
+	}
+	silenceImportResults := []*SilenceImportResult{
+		{ID: "abc", SilenceID: "def", Action: "created"},
+	}
+	doSilenceImport := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return api.Import(context.Background(), silenceExport, "skip")
+	}
+	doSilenceQuery := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		sils, _, err := api.Query(context.Background(), SilenceQueryOptions{
+			States: []types.SilenceState{types.SilenceStateActive},
+			Sort:   "-endsAt",
+			Limit:  10,
+		})
+		return sils, err
+	}
+	bulkResults := []*BulkSilenceResult{
+		{SilenceID: "abc"},
+		{Error: "end time can't be in the past"},
+	}
+	doSilenceSetMany := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return api.SetMany(context.Background(), []types.Silence{*silOne, *silOne})
+	}
+	doSilenceExpireMany := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return api.ExpireMany(context.Background(), []string{"abc"}, `{team="db"}`)
+	}
 	doSilenceList := func() (interface{}, error) {
 		api := httpSilenceAPI{client: client}
 		return api.List(context.Background(), "")
 	}
 
+	silencePreview := &SilencePreview{
+		Alerts:    alerts,
+		Receivers: map[string]int{"team-X": 1},
+	}
+	doSilencePreview := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return api.Preview(context.Background(), *silOne)
+	}
+
+	silenceTmpls := []*config.SilenceTemplate{
+		{
+			Name:       "node-maintenance",
+			Parameters: []string{"HOST"},
+			Match:      map[string]string{"instance": "{{ .HOST }}"},
+			Duration:   model.Duration(2 * time.Hour),
+		},
+	}
+	doSilenceTemplates := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return api.Templates(context.Background())
+	}
+	doSilenceSetFromTemplate := func() (interface{}, error) {
+		api := httpSilenceAPI{client: client}
+		return api.SetFromTemplate(context.Background(), "node-maintenance", map[string]string{"HOST": "abc"}, "alice", "")
+	}
+
 	tests := []apiTest{
 		{
 			do: doStatus,
@@ -221,6 +368,69 @@ func TestAPI(t *testing.T) {
 			},
 			err: fmt.Errorf("some error"),
 		},
+		{
+			do: doAlertAck,
+			apiRes: fakeAPIResponse{
+				res:    nil,
+				path:   "/api/v1/alert/1c93eec3511dc156/ack",
+				method: http.MethodPost,
+			},
+			res: nil,
+		},
+		{
+			do: doAlertUnack,
+			apiRes: fakeAPIResponse{
+				res:    nil,
+				path:   "/api/v1/alert/1c93eec3511dc156/ack",
+				method: http.MethodDelete,
+			},
+			res: nil,
+		},
+		{
+			do: doAlertSilence,
+			apiRes: fakeAPIResponse{
+				res:    map[string]string{"SilenceId": "abc"},
+				path:   "/api/v1/alert/1c93eec3511dc156/silence",
+				method: http.MethodPost,
+			},
+			res: "abc",
+		},
+		{
+			do: doAlertInhibitors,
+			apiRes: fakeAPIResponse{
+				res:    inhibitions,
+				path:   "/api/v1/alert/1c93eec3511dc156/inhibitors",
+				method: http.MethodGet,
+			},
+			res: inhibitions,
+		},
+		{
+			do: doInhibitions,
+			apiRes: fakeAPIResponse{
+				res:    inhibitions,
+				path:   "/api/v1/inhibitions",
+				method: http.MethodGet,
+			},
+			res: inhibitions,
+		},
+		{
+			do: doNotificationLogList,
+			apiRes: fakeAPIResponse{
+				res:    nflogEntries,
+				path:   "/api/v1/nflog",
+				method: http.MethodGet,
+			},
+			res: nflogEntries,
+		},
+		{
+			do: doRouteTest,
+			apiRes: fakeAPIResponse{
+				res:    routeTestResults,
+				path:   "/api/v1/routes/test",
+				method: http.MethodPost,
+			},
+			res: routeTestResults,
+		},
 		{
 			do: doSilenceGet("abc"),
 			apiRes: fakeAPIResponse{
@@ -273,6 +483,67 @@ func TestAPI(t *testing.T) {
 			},
 			err: fmt.Errorf("some error"),
 		},
+		{
+			do: doSilenceQuery,
+			apiRes: fakeAPIResponse{
+				res:    []*types.Silence{silOne},
+				path:   "/api/v1/silences",
+				method: http.MethodGet,
+			},
+			res: []*types.Silence{silOne},
+		},
+		{
+			do: doSilenceSetMany,
+			apiRes: fakeAPIResponse{
+				res:    bulkResults,
+				path:   "/api/v1/silences/bulk",
+				method: http.MethodPost,
+			},
+			res: bulkResults,
+		},
+		{
+			do: doSilenceExpireMany,
+			apiRes: fakeAPIResponse{
+				res:    bulkResults,
+				path:   "/api/v1/silences/expire",
+				method: http.MethodPost,
+			},
+			res: bulkResults,
+		},
+		{
+			do: doSilenceExport,
+			apiRes: fakeAPIResponse{
+				res:    silenceExport,
+				path:   "/api/v1/silences/export",
+				method: http.MethodGet,
+			},
+			res: silenceExport,
+		},
+		{
+			do: doSilenceImport,
+			apiRes: fakeAPIResponse{
+				res:    silenceImportResults,
+				path:   "/api/v1/silences/import",
+				method: http.MethodPost,
+			},
+			res: silenceImportResults,
+		},
+		{
+			do: doSilenceAddComment,
+			apiRes: fakeAPIResponse{
+				path:   "/api/v1/silence/abc/comments",
+				method: http.MethodPost,
+			},
+		},
+		{
+			do: doSilenceHistory,
+			apiRes: fakeAPIResponse{
+				res:    silenceHistory,
+				path:   "/api/v1/silence/abc/history",
+				method: http.MethodGet,
+			},
+			res: silenceHistory,
+		},
 		{
 			do: doSilenceList,
 			apiRes: fakeAPIResponse{
@@ -291,6 +562,42 @@ func TestAPI(t *testing.T) {
 			},
 			err: fmt.Errorf("some error"),
 		},
+		{
+			do: doSilencePreview,
+			apiRes: fakeAPIResponse{
+				res:    silencePreview,
+				path:   "/api/v1/silences/preview",
+				method: http.MethodPost,
+			},
+			res: silencePreview,
+		},
+		{
+			do: doSilenceTemplates,
+			apiRes: fakeAPIResponse{
+				res:    silenceTmpls,
+				path:   "/api/v1/silence_templates",
+				method: http.MethodGet,
+			},
+			res: silenceTmpls,
+		},
+		{
+			do: doSilenceSetFromTemplate,
+			apiRes: fakeAPIResponse{
+				res:    map[string]string{"SilenceId": "abc"},
+				path:   "/api/v1/silence_templates/node-maintenance",
+				method: http.MethodPost,
+			},
+			res: "abc",
+		},
+		{
+			do: doSilenceSetFromTemplate,
+			apiRes: fakeAPIResponse{
+				err:    fmt.Errorf("some error"),
+				path:   "/api/v1/silence_templates/node-maintenance",
+				method: http.MethodPost,
+			},
+			err: fmt.Errorf("some error"),
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -16,6 +16,7 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"math/rand"
 	"net"
 	"sort"
@@ -35,6 +36,18 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+const (
+	// ProtocolVersionMatcherOperators added the absent, present and numeric
+	// silence matchers, which older peers treat as equality matchers.
+	ProtocolVersionMatcherOperators = 1
+
+	// ProtocolVersion is announced to the other peers in the metadata of
+	// the node. It is increased when peers of an older version would
+	// misinterpret the state shared by newer peers. Peers that don't
+	// announce a version have version 0.
+	ProtocolVersion = ProtocolVersionMatcherOperators
+)
+
 // Peer is a single peer in a gossip cluster.
 type Peer struct {
 	mlist    *memberlist.Memberlist
@@ -49,6 +62,12 @@ type Peer struct {
 	peers       map[string]peer
 	failedPeers []peer
 
+	// pendingAcks holds the broadcasts waiting for acknowledgements by
+	// their ID.
+	ackMtx      sync.Mutex
+	ackSeq      uint64
+	pendingAcks map[string]*pendingAck
+
 	failedReconnectionsCounter prometheus.Counter
 	reconnectionsCounter       prometheus.Counter
 	peerLeaveCounter           prometheus.Counter
@@ -165,11 +184,12 @@ func Join(
 	}
 
 	p := &Peer{
-		states: map[string]State{},
-		stopc:  make(chan struct{}),
-		readyc: make(chan struct{}),
-		logger: l,
-		peers:  map[string]peer{},
+		states:      map[string]State{},
+		stopc:       make(chan struct{}),
+		readyc:      make(chan struct{}),
+		logger:      l,
+		peers:       map[string]peer{},
+		pendingAcks: map[string]*pendingAck{},
 	}
 
 	p.register(reg)
@@ -419,7 +439,7 @@ func (p *Peer) peerUpdate(n *memberlist.Node) {
 // broadcast messages for the state can be sent.
 func (p *Peer) AddState(key string, s State) *Channel {
 	p.states[key] = s
-	return &Channel{key: key, bcast: p.delegate.bcast}
+	return &Channel{key: key, bcast: p.delegate.bcast, peer: p}
 }
 
 // Leave the cluster, waiting up to timeout.
@@ -439,6 +459,19 @@ func (p *Peer) ClusterSize() int {
 	return p.mlist.NumMembers()
 }
 
+// knownClusterSize returns the number of members in the cluster including
+// the initial peers and the failed members that did not time out yet.
+func (p *Peer) knownClusterSize() int {
+	p.peerLock.RLock()
+	n := len(p.peers)
+	p.peerLock.RUnlock()
+
+	if m := p.ClusterSize(); m > n {
+		return m
+	}
+	return n
+}
+
 // Return true when router has settled.
 func (p *Peer) Ready() bool {
 	select {
@@ -485,6 +518,26 @@ func (p *Peer) Peers() []*memberlist.Node {
 	return p.mlist.Members()
 }
 
+// MinProtocolVersion returns the lowest protocol version of the peers in
+// the cluster.
+func (p *Peer) MinProtocolVersion() int {
+	return minProtocolVersion(p.Peers())
+}
+
+func minProtocolVersion(nodes []*memberlist.Node) int {
+	min := ProtocolVersion
+	for _, n := range nodes {
+		v, err := strconv.Atoi(string(n.Meta))
+		if err != nil {
+			v = 0
+		}
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
 // Position returns the position of the peer in the cluster.
 func (p *Peer) Position() int {
 	all := p.Peers()
@@ -502,6 +555,41 @@ func (p *Peer) Position() int {
 	return k
 }
 
+// Owner returns the name of the peer responsible for the given key, such as
+// the key of an aggregation group. Keys are assigned to the alive peers by
+// rendezvous hashing, so only the keys of peers joining or leaving the
+// cluster are reassigned.
+func (p *Peer) Owner(key string) string {
+	var names []string
+	for _, n := range p.Peers() {
+		names = append(names, n.Name)
+	}
+	return owner(names, key)
+}
+
+// IsOwner returns true if the peer is responsible for the given key.
+func (p *Peer) IsOwner(key string) bool {
+	return p.Owner(key) == p.Self().Name
+}
+
+// owner returns the name with the highest hash combined with the key.
+func owner(names []string, key string) string {
+	var (
+		res string
+		max uint64
+	)
+	for _, name := range names {
+		h := fnv.New64a()
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if sum := h.Sum64(); res == "" || sum > max || (sum == max && name < res) {
+			res, max = name, sum
+		}
+	}
+	return res
+}
+
 // Settle waits until the mesh is ready (and sets the appropriate internal state when it is).
 // The idea is that we don't want to start "working" before we get a chance to know most of the alerts and/or silences.
 // Inspired from https://github.com/apache/cassandra/blob/7a40abb6a5108688fb1b10c375bb751cbb782ea4/src/java/org/apache/cassandra/gms/Gossiper.java
@@ -557,6 +645,7 @@ type State interface {
 type Channel struct {
 	key   string
 	bcast *memberlist.TransmitLimitedQueue
+	peer  *Peer
 }
 
 // We use a simple broadcast implementation in which items are never invalidated by others.
@@ -575,6 +664,136 @@ func (c *Channel) Broadcast(b []byte) {
 	c.bcast.QueueBroadcast(simpleBroadcast(b))
 }
 
+// BroadcastQuorum broadcasts a message like Broadcast and additionally sends
+// it directly to all peers. It returns once a majority of the cluster,
+// including this peer, merged the message or with an error once the context
+// is done. The majority is taken of the known cluster size, which includes
+// failed peers, and an error is returned right away if not enough peers are
+// alive to reach it.
+func (c *Channel) BroadcastQuorum(ctx context.Context, b []byte) error {
+	c.Broadcast(b)
+
+	others := make([]*memberlist.Node, 0)
+	for _, n := range c.peer.Peers() {
+		if n.Name != c.peer.Name() {
+			others = append(others, n)
+		}
+	}
+	// A majority of the cluster including this peer is needed.
+	size := c.peer.knownClusterSize()
+	needed := size / 2
+	if needed == 0 {
+		return nil
+	}
+	if len(others) < needed {
+		return fmt.Errorf("quorum not reachable: %d of %d required peers alive in a cluster of %d", len(others), needed, size)
+	}
+
+	id, pa := c.peer.addPendingAck(needed)
+	defer c.peer.removePendingAck(id)
+
+	msg, err := proto.Marshal(&clusterpb.Part{Key: c.key, Data: b, Id: id, Origin: c.peer.Name()})
+	if err != nil {
+		return err
+	}
+	for _, n := range others {
+		go func(n *memberlist.Node) {
+			if err := c.peer.mlist.SendReliable(n, msg); err != nil {
+				level.Debug(c.peer.logger).Log("msg", "sending message for quorum failed", "peer", n.Name, "err", err)
+			}
+		}(n)
+	}
+
+	select {
+	case <-pa.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("acknowledged by %d of %d required peers: %s", pa.count(), needed, ctx.Err())
+	}
+}
+
+// pendingAck tracks the peers that acknowledged a broadcast.
+type pendingAck struct {
+	mtx    sync.Mutex
+	needed int
+	from   map[string]struct{}
+	done   chan struct{}
+}
+
+func (pa *pendingAck) add(peer string) {
+	pa.mtx.Lock()
+	defer pa.mtx.Unlock()
+
+	if len(pa.from) >= pa.needed {
+		return
+	}
+	pa.from[peer] = struct{}{}
+	if len(pa.from) == pa.needed {
+		close(pa.done)
+	}
+}
+
+func (pa *pendingAck) count() int {
+	pa.mtx.Lock()
+	defer pa.mtx.Unlock()
+	return len(pa.from)
+}
+
+func (p *Peer) addPendingAck(needed int) (string, *pendingAck) {
+	p.ackMtx.Lock()
+	defer p.ackMtx.Unlock()
+
+	p.ackSeq++
+	id := fmt.Sprintf("%s/%d", p.Name(), p.ackSeq)
+	pa := &pendingAck{
+		needed: needed,
+		from:   map[string]struct{}{},
+		done:   make(chan struct{}),
+	}
+	p.pendingAcks[id] = pa
+	return id, pa
+}
+
+func (p *Peer) removePendingAck(id string) {
+	p.ackMtx.Lock()
+	delete(p.pendingAcks, id)
+	p.ackMtx.Unlock()
+}
+
+// ackReceived records the acknowledgement of the broadcast with the given ID
+// by a peer.
+func (p *Peer) ackReceived(id, peer string) {
+	p.ackMtx.Lock()
+	pa, ok := p.pendingAcks[id]
+	p.ackMtx.Unlock()
+
+	if ok {
+		pa.add(peer)
+	}
+}
+
+// sendAck acknowledges the broadcast with the given ID to its origin.
+func (p *Peer) sendAck(id, origin string) {
+	var to *memberlist.Node
+	for _, n := range p.Peers() {
+		if n.Name == origin {
+			to = n
+			break
+		}
+	}
+	if to == nil {
+		level.Debug(p.logger).Log("msg", "unknown origin of acknowledged message", "peer", origin)
+		return
+	}
+	msg, err := proto.Marshal(&clusterpb.Part{Id: id, Origin: p.Name(), Ack: true})
+	if err != nil {
+		return
+	}
+	if err := p.mlist.SendReliable(to, msg); err != nil {
+		level.Debug(p.logger).Log("msg", "sending acknowledgement failed", "peer", origin, "err", err)
+	}
+}
+
 // delegate implements memberlist.Delegate and memberlist.EventDelegate
 // and broadcasts its peer's state in the cluster.
 func resolvePeers(ctx context.Context, peers []string, myAddress string, res net.Resolver, waitIfEmpty bool) ([]string, error) {
@@ -15,10 +15,13 @@ package cluster
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/hashicorp/memberlist"
 	"github.com/stretchr/testify/require"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -206,3 +209,118 @@ func TestInitiallyFailingPeers(t *testing.T) {
 		require.Equal(t, StatusNone, pr.status)
 	}
 }
+
+type fakeState struct {
+	mtx    sync.Mutex
+	merged [][]byte
+}
+
+func (s *fakeState) MarshalBinary() ([]byte, error) { return nil, nil }
+
+func (s *fakeState) Merge(b []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.merged = append(s.merged, b)
+	return nil
+}
+
+func TestBroadcastQuorum(t *testing.T) {
+	logger := log.NewNopLogger()
+	join := func(peers []string) *Peer {
+		p, err := Join(
+			logger,
+			prometheus.NewRegistry(),
+			"127.0.0.1:0",
+			"",
+			peers,
+			true,
+			DefaultPushPullInterval,
+			DefaultGossipInterval,
+			DefaultTcpTimeout,
+			DefaultProbeTimeout,
+			DefaultProbeInterval,
+			DefaultReconnectInterval,
+			DefaultReconnectTimeout,
+		)
+		require.NoError(t, err)
+		go p.Settle(context.Background(), 0*time.Second)
+		return p
+	}
+
+	p := join([]string{})
+	defer p.Leave(0 * time.Second)
+	c := p.AddState("test", &fakeState{})
+
+	// A single peer is its own quorum.
+	require.NoError(t, c.BroadcastQuorum(context.Background(), []byte("alone")))
+
+	p2 := join([]string{p.Self().Address()})
+	s2 := &fakeState{}
+	p2.AddState("test", s2)
+	require.Equal(t, 2, p.ClusterSize())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, c.BroadcastQuorum(ctx, []byte("replicated")))
+
+	s2.mtx.Lock()
+	require.Contains(t, s2.merged, []byte("replicated"))
+	s2.mtx.Unlock()
+
+	// Messages for states unknown to the other peer are not acknowledged.
+	other := p.AddState("other", &fakeState{})
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err := other.BroadcastQuorum(ctx, []byte("lost"))
+	require.EqualError(t, err, "acknowledged by 0 of 1 required peers: context deadline exceeded")
+
+	// The failed peer still counts towards the cluster size.
+	require.NoError(t, p2.Leave(time.Second))
+	for i := 0; p.ClusterSize() > 1; i++ {
+		require.True(t, i < 100, "peer did not leave")
+		time.Sleep(50 * time.Millisecond)
+	}
+	require.Equal(t, 2, p.knownClusterSize())
+	err = c.BroadcastQuorum(context.Background(), []byte("failed"))
+	require.EqualError(t, err, "quorum not reachable: 0 of 1 required peers alive in a cluster of 2")
+}
+
+func TestOwner(t *testing.T) {
+	peers := []string{"peer-a", "peer-b", "peer-c"}
+
+	owners := map[string]string{}
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("{}:{alertname=\"%d\"}", i)
+		o := owner(peers, key)
+		owners[key] = o
+		counts[o]++
+
+		// The owner does not depend on the order of the peers.
+		require.Equal(t, o, owner([]string{"peer-c", "peer-a", "peer-b"}, key))
+	}
+	// Keys are spread across all peers.
+	for _, p := range peers {
+		require.True(t, counts[p] > 800, "peer %s owns %d keys", p, counts[p])
+	}
+
+	// Only the keys of a leaving peer are reassigned.
+	for key, o := range owners {
+		res := owner([]string{"peer-a", "peer-b"}, key)
+		if o != "peer-c" {
+			require.Equal(t, o, res)
+		}
+	}
+
+	require.Equal(t, "", owner(nil, "key"))
+}
+
+func TestMinProtocolVersion(t *testing.T) {
+	d := &delegate{}
+	upgraded := &memberlist.Node{Name: "peer-a", Meta: d.NodeMeta(512)}
+	old := &memberlist.Node{Name: "peer-b", Meta: []byte{}}
+
+	require.Equal(t, ProtocolVersion, minProtocolVersion([]*memberlist.Node{upgraded}))
+	// Peers that don't announce a version have version 0.
+	require.Equal(t, 0, minProtocolVersion([]*memberlist.Node{upgraded, old}))
+}
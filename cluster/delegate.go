@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"strconv"
+
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/gogo/protobuf/proto"
@@ -91,8 +93,9 @@ func newDelegate(l log.Logger, reg prometheus.Registerer, p *Peer) *delegate {
 }
 
 // NodeMeta retrieves meta-data about the current node when broadcasting an alive message.
+// It holds the protocol version of the peer.
 func (d *delegate) NodeMeta(limit int) []byte {
-	return []byte{}
+	return []byte(strconv.Itoa(ProtocolVersion))
 }
 
 // NotifyMsg is the callback invoked when a user-level gossip message is received.
@@ -107,6 +110,10 @@ func (d *delegate) NotifyMsg(b []byte) {
 		level.Warn(d.logger).Log("msg", "decode broadcast", "err", err)
 		return
 	}
+	if p.Ack {
+		d.Peer.ackReceived(p.Id, p.Origin)
+		return
+	}
 	s, ok := d.states[p.Key]
 	if !ok {
 		return
@@ -115,6 +122,10 @@ func (d *delegate) NotifyMsg(b []byte) {
 		level.Warn(d.logger).Log("msg", "merge broadcast", "err", err, "key", p.Key)
 		return
 	}
+	if p.Id != "" {
+		// Do not block the handling of messages while sending.
+		go d.Peer.sendAck(p.Id, p.Origin)
+	}
 }
 
 // GetBroadcasts is called when user data messages can be broadcasted.
@@ -18,6 +18,7 @@ import (
 	"crypto/md5"
 	"encoding/binary"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
@@ -25,6 +26,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -32,24 +34,39 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/annotation"
 	"github.com/prometheus/alertmanager/api"
+	"github.com/prometheus/alertmanager/audit"
 	"github.com/prometheus/alertmanager/cluster"
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/dlq"
+	"github.com/prometheus/alertmanager/history"
 	"github.com/prometheus/alertmanager/inhibit"
 	"github.com/prometheus/alertmanager/nflog"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pkg/confighistory"
+	"github.com/prometheus/alertmanager/pkg/filewatch"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/pkg/objstore"
+	"github.com/prometheus/alertmanager/pkg/remoteconfig"
+	"github.com/prometheus/alertmanager/pkg/snapcrypt"
+	"github.com/prometheus/alertmanager/pkg/tracing"
+	"github.com/prometheus/alertmanager/pkg/web"
 	"github.com/prometheus/alertmanager/provider/mem"
 	"github.com/prometheus/alertmanager/silence"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/tenant"
+	"github.com/prometheus/alertmanager/timeinterval"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/alertmanager/ui"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/route"
 	"github.com/prometheus/common/version"
-	"github.com/prometheus/prometheus/pkg/labels"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -139,21 +156,48 @@ func main() {
 		panic(err)
 	}
 	var (
-		configFile      = kingpin.Flag("config.file", "Alertmanager configuration file name.").Default("alertmanager.yml").String()
-		dataDir         = kingpin.Flag("storage.path", "Base path for data storage.").Default("data/").String()
-		retention       = kingpin.Flag("data.retention", "How long to keep data for.").Default("120h").Duration()
-		alertGCInterval = kingpin.Flag("alerts.gc-interval", "Interval between alert GC.").Default("30m").Duration()
-		logLevelString  = kingpin.Flag("log.level", "Only log messages with the given severity or above.").Default("info").Enum("debug", "info", "warn", "error")
+		configFile        = kingpin.Flag("config.file", "Alertmanager configuration file name. May be an HTTP(S), S3 (s3://bucket/key) or GCS (gs://bucket/key) URL, which is polled for changes. YAML and JSON files are read as is, files ending in .jsonnet are evaluated with the jsonnet command.").Default("alertmanager.yml").String()
+		jsonnetPaths      = kingpin.Flag("config.jsonnet-path", "Library path searched by imports of Jsonnet configuration files. May be repeated.").Strings()
+		jsonnetCommand    = kingpin.Flag("config.jsonnet-command", "Command evaluating Jsonnet configuration files.").Default("jsonnet").String()
+		configDir         = kingpin.Flag("config.dir", "Directory of configuration fragments merged into the configuration file. Fragments may add routes, receivers, inhibition rules, templates, time intervals and silence templates.").String()
+		configHistorySize = kingpin.Flag("config.history-size", "Number of applied configurations kept in the history under the storage path, which can be rolled back to. 0 disables the history.").Default("10").Int()
+		configWatch       = kingpin.Flag("config.watch-interval", "Interval between checks of the configuration file and directory for changes, which are reloaded. Symlinked files, such as Kubernetes ConfigMap volumes, are followed. 0 disables watching.").Default("0s").Duration()
+		configDebounce    = kingpin.Flag("config.watch-debounce", "How long a changed configuration must not change again before it is reloaded.").Default("2s").Duration()
+		unknownFields     = kingpin.Flag("config.unknown-fields", "Handling of unknown configuration fields, such as fields of other versions. strict rejects the configuration, warn ignores the fields and reports them in the status API, ignore ignores them. The unknown_fields section of the configuration overrides it per top-level section.").Default(config.UnknownFieldsStrict).Enum(config.UnknownFieldsStrict, config.UnknownFieldsWarn, config.UnknownFieldsIgnore)
+		configPoll        = kingpin.Flag("config.poll-interval", "Interval between checks of a remote configuration for changes. 0 disables polling.").Default("1m").Duration()
+		secretRefresh     = kingpin.Flag("config.secret-refresh-interval", "Interval between resolutions of the secret references of the configuration to detect rotated secrets. 0 disables refreshing.").Default("1m").Duration()
+		dataDir           = kingpin.Flag("storage.path", "Base path for data storage.").Default("data/").String()
+		remoteStorage     = kingpin.Flag("storage.remote-config", "Object storage configuration file. If set, the silence, notification log, acknowledgement and annotation snapshots are uploaded to the object storage and restored from it on startup if they are missing locally.").String()
+		encryptionConfig  = kingpin.Flag("storage.encryption-config", "Snapshot encryption configuration file. If set, the silence and notification log snapshots are encrypted with AES-GCM. Existing unencrypted snapshots are still read.").String()
+		retention         = kingpin.Flag("data.retention", "How long to keep data for.").Default("120h").Duration()
+		maintenanceMode   = kingpin.Flag("notify.maintenance", "Start in maintenance mode, which pauses all notifications until it is disabled through the API.").Bool()
+		maintenanceReason = kingpin.Flag("notify.maintenance-reason", "Reason of the maintenance mode set by --notify.maintenance.").String()
+		alertGCInterval   = kingpin.Flag("alerts.gc-interval", "Interval between alert GC.").Default("30m").Duration()
+		historyRetention  = kingpin.Flag("alerts.history-retention", "How long to keep the state transitions of alerts and the notifications sent for them, which are served by the alert history API. 0 disables the history.").Default("24h").Duration()
+		historyMaxEvents  = kingpin.Flag("alerts.history-max-events", "Maximum number of events kept per alert in the alert history, the oldest are removed first. 0 means no limit.").Default("1000").Int()
+		silenceGCInterval = kingpin.Flag("silences.gc-interval", "Interval between silence GC and snapshots.").Default("15m").Duration()
+		silenceRetention  = kingpin.Flag("silences.retention", "How long to keep silences after they expired. Defaults to --data.retention.").Duration()
+		nflogMaxEntries   = kingpin.Flag("nflog.max-entries", "Maximum number of notification log entries. Entries of resolved groups are evicted first, the oldest first. 0 means no limit.").Default("0").Int()
+		nflogMaxSize      = kingpin.Flag("nflog.max-size", "Maximum encoded size of the notification log entries, which roughly is the size of its snapshot. 0 means no limit.").Default("0").Bytes()
+		auditMaxSize      = kingpin.Flag("audit.max-size", "Size after which the notification audit log is rotated.").Default("10MB").Bytes()
+		auditMaxFiles     = kingpin.Flag("audit.max-files", "Number of rotated notification audit log files to keep.").Default("5").Int()
+		tracingExporter   = kingpin.Flag("tracing.exporter", "Exporter of the tracing spans of the notification pipeline. The otlp exporter sends spans to an OpenTelemetry collector with OTLP over HTTP, the log exporter logs them.").Default("none").Enum("none", "otlp", "log")
+		tracingEndpoint   = kingpin.Flag("tracing.endpoint", "URL of the OTLP HTTP endpoint of the OpenTelemetry collector. Spans are sent to /v1/traces if the URL has no path.").Default("http://localhost:4318").String()
+		tracingSample     = kingpin.Flag("tracing.sample-ratio", "Fraction of notifications and alert insertions that are traced. Requests with a sampled traceparent header are always traced.").Default("1").Float64()
+		logLevelString    = kingpin.Flag("log.level", "Only log messages with the given severity or above.").Default("info").Enum("debug", "info", "warn", "error")
 
-		externalURL   = kingpin.Flag("web.external-url", "The URL under which Alertmanager is externally reachable (for example, if Alertmanager is served via a reverse proxy). Used for generating relative and absolute links back to Alertmanager itself. If the URL has a path portion, it will be used to prefix all HTTP endpoints served by Alertmanager. If omitted, relevant URL components will be derived automatically.").String()
-		routePrefix   = kingpin.Flag("web.route-prefix", "Prefix for the internal routes of web endpoints. Defaults to path of --web.external-url.").String()
-		listenAddress = kingpin.Flag("web.listen-address", "Address to listen on for the web interface and API.").Default(":9093").String()
+		externalURL     = kingpin.Flag("web.external-url", "The URL under which Alertmanager is externally reachable (for example, if Alertmanager is served via a reverse proxy). Used for generating relative and absolute links back to Alertmanager itself. If the URL has a path portion, it will be used to prefix all HTTP endpoints served by Alertmanager. If omitted, relevant URL components will be derived automatically.").String()
+		routePrefix     = kingpin.Flag("web.route-prefix", "Prefix for the internal routes of web endpoints. Defaults to path of --web.external-url.").String()
+		listenAddress   = kingpin.Flag("web.listen-address", "Address to listen on for the web interface and API.").Default(":9093").String()
+		webConfigFile   = kingpin.Flag("web.config.file", "Path to the configuration file enabling TLS and authentication of the web interface and API, read on startup.").String()
+		enableConfigAPI = kingpin.Flag("web.enable-config-api", "Enable applying and rolling back configurations through the API. Requires authentication to be configured in --web.config.file.").Default("false").Bool()
 
 		clusterBindAddr = kingpin.Flag("cluster.listen-address", "Listen address for cluster.").
 				Default(defaultClusterAddr).String()
 		clusterAdvertiseAddr = kingpin.Flag("cluster.advertise-address", "Explicit address to advertise in cluster.").String()
 		peers                = kingpin.Flag("cluster.peer", "Initial peers (may be repeated).").Strings()
 		peerTimeout          = kingpin.Flag("cluster.peer-timeout", "Time to wait between peers to send notifications.").Default("15s").Duration()
+		notificationMode     = kingpin.Flag("cluster.notification-mode", "How peers share sending notifications. In staggered mode peers wait for the peers before them according to their position in the cluster. In active-active mode all peers send immediately and rely on the notification log for deduplication, which may cause occasional duplicates. In sharded mode each aggregation group is notified by a single peer, chosen by hashing the group across the alive peers.").Default("staggered").Enum("staggered", "active-active", "sharded")
 		gossipInterval       = kingpin.Flag("cluster.gossip-interval", "Interval between sending gossip messages. By lowering this value (more frequent) gossip messages are propagated across the cluster more quickly at the expense of increased bandwidth.").Default(cluster.DefaultGossipInterval.String()).Duration()
 		pushPullInterval     = kingpin.Flag("cluster.pushpull-interval", "Interval for gossip state syncs. Setting this interval lower (more frequent) will increase convergence speeds across larger clusters at the expense of increased bandwidth usage.").Default(cluster.DefaultPushPullInterval.String()).Duration()
 		tcpTimeout           = kingpin.Flag("cluster.tcp-timeout", "Timeout for establishing a stream connection with a remote node for a full state sync, and for stream read and write operations.").Default(cluster.DefaultTcpTimeout.String()).Duration()
@@ -174,6 +218,30 @@ func main() {
 	level.Info(logger).Log("msg", "Starting Alertmanager", "version", version.Info())
 	level.Info(logger).Log("build_context", version.BuildContext())
 
+	if *tracingExporter != "none" {
+		var exporter tracing.Exporter
+		switch *tracingExporter {
+		case "otlp":
+			e, err := tracing.NewOTLPExporter(*tracingEndpoint, "alertmanager", nil)
+			if err != nil {
+				level.Error(logger).Log("msg", "Creating tracing exporter failed", "err", err)
+				os.Exit(1)
+			}
+			exporter = e
+		case "log":
+			exporter = tracing.NewLogExporter(log.With(logger, "component", "tracing"))
+		}
+		tracer := tracing.NewTracer(exporter, tracing.Options{SampleRatio: *tracingSample}, log.With(logger, "component", "tracing"))
+		tracing.SetTracer(tracer)
+		go tracer.Run()
+		defer tracer.Stop()
+	}
+
+	if *silenceGCInterval <= 0 {
+		level.Error(logger).Log("msg", "Invalid silence GC interval, it must be greater than zero", "interval", *silenceGCInterval)
+		os.Exit(1)
+	}
+
 	err := os.MkdirAll(*dataDir, 0777)
 	if err != nil {
 		level.Error(logger).Log("msg", "Unable to create data directory", "err", err)
@@ -209,6 +277,51 @@ func main() {
 		go peer.Settle(ctx, *gossipInterval*10)
 	}
 
+	snapshots := []string{
+		filepath.Join(*dataDir, "nflog"),
+		filepath.Join(*dataDir, "silences"),
+		filepath.Join(*dataDir, "acks"),
+		filepath.Join(*dataDir, "annotations"),
+	}
+	var shipper *objstore.Shipper
+	if *remoteStorage != "" {
+		conf, err := objstore.LoadFile(*remoteStorage)
+		if err != nil {
+			level.Error(logger).Log("msg", "Loading object storage configuration failed", "err", err)
+			os.Exit(1)
+		}
+		bucket, err := objstore.NewBucket(conf)
+		if err != nil {
+			level.Error(logger).Log("msg", "Creating object storage bucket failed", "err", err)
+			os.Exit(1)
+		}
+		for _, f := range snapshots {
+			restored, err := objstore.Restore(context.Background(), bucket, f)
+			if err != nil {
+				level.Error(logger).Log("msg", "Restoring snapshot from object storage failed", "file", f, "err", err)
+				os.Exit(1)
+			}
+			if restored {
+				level.Info(logger).Log("msg", "Restored snapshot from object storage", "file", f)
+			}
+		}
+		shipper = objstore.NewShipper(bucket, log.With(logger, "component", "objstore"), prometheus.DefaultRegisterer, snapshots...)
+	}
+
+	var snapshotCipher *snapcrypt.Cipher
+	if *encryptionConfig != "" {
+		conf, err := snapcrypt.LoadFile(*encryptionConfig)
+		if err != nil {
+			level.Error(logger).Log("msg", "Loading snapshot encryption configuration failed", "err", err)
+			os.Exit(1)
+		}
+		snapshotCipher, err = snapcrypt.NewFromConfig(context.Background(), conf)
+		if err != nil {
+			level.Error(logger).Log("msg", "Creating snapshot encryption key failed", "err", err)
+			os.Exit(1)
+		}
+	}
+
 	stopc := make(chan struct{})
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -216,6 +329,9 @@ func main() {
 	notificationLogOpts := []nflog.Option{
 		nflog.WithRetention(*retention),
 		nflog.WithSnapshot(filepath.Join(*dataDir, "nflog")),
+		nflog.WithCipher(snapshotCipher),
+		nflog.WithMaxEntries(*nflogMaxEntries),
+		nflog.WithMaxBytes(int64(*nflogMaxSize)),
 		nflog.WithMaintenance(15*time.Minute, stopc, wg.Done),
 		nflog.WithMetrics(prometheus.DefaultRegisterer),
 		nflog.WithLogger(log.With(logger, "component", "nflog")),
@@ -234,9 +350,24 @@ func main() {
 	marker := types.NewMarker()
 	newMarkerMetrics(marker)
 
+	var alertHistory *history.History
+	if *historyRetention > 0 {
+		alertHistory = history.New(history.Options{
+			Retention: *historyRetention,
+			MaxEvents: *historyMaxEvents,
+		})
+		// Suppressions are recorded by the marker all inhibitors and
+		// silencers share.
+		marker = alertHistory.Marker(marker)
+	}
+
+	if *silenceRetention == 0 {
+		*silenceRetention = *retention
+	}
 	silenceOpts := silence.Options{
 		SnapshotFile: filepath.Join(*dataDir, "silences"),
-		Retention:    *retention,
+		Retention:    *silenceRetention,
+		Cipher:       snapshotCipher,
 		Logger:       log.With(logger, "component", "silences"),
 		Metrics:      prometheus.DefaultRegisterer,
 	}
@@ -249,15 +380,85 @@ func main() {
 	if peer != nil {
 		c := peer.AddState("sil", silences)
 		silences.SetBroadcast(c.Broadcast)
+		silences.SetQuorumBroadcast(c.BroadcastQuorum)
+		silences.SetMatcherOperatorsSupported(func() bool {
+			return peer.MinProtocolVersion() >= cluster.ProtocolVersionMatcherOperators
+		})
+	}
+
+	acks, err := ack.New(ack.Options{
+		SnapshotFile: filepath.Join(*dataDir, "acks"),
+		Retention:    *retention,
+		Logger:       log.With(logger, "component", "acks"),
+		Metrics:      prometheus.DefaultRegisterer,
+	})
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		os.Exit(1)
+	}
+	if peer != nil {
+		c := peer.AddState("ack", acks)
+		acks.SetBroadcast(c.Broadcast)
+	}
+
+	annotations, err := annotation.New(annotation.Options{
+		SnapshotFile: filepath.Join(*dataDir, "annotations"),
+		Retention:    *retention,
+		Logger:       log.With(logger, "component", "annotations"),
+		Metrics:      prometheus.DefaultRegisterer,
+	})
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		os.Exit(1)
+	}
+	if peer != nil {
+		c := peer.AddState("ann", annotations)
+		annotations.SetBroadcast(c.Broadcast)
 	}
 
+	// The tokens consumed by the flood protection are shared with the
+	// other peers, whichever configuration is loaded.
+	var floodState *notify.FloodState
+	if peer != nil {
+		floodState = notify.NewFloodState(peer.Name())
+		c := peer.AddState("flood", floodState)
+		floodState.SetBroadcast(c.Broadcast)
+	}
+
+	// Voice calls are shared so that any peer can receive the callback of a
+	// call placed by another peer.
+	if peer != nil {
+		c := peer.AddState("voice", notify.VoiceCallState())
+		notify.VoiceCallState().SetBroadcast(c.Broadcast)
+	}
+
+	silenceExpiry := silence.NewExpiryScheduler(silences)
+
 	// Start providers before router potentially sends updates.
-	wg.Add(1)
+	wg.Add(4)
+	go func() {
+		silences.Maintenance(*silenceGCInterval, filepath.Join(*dataDir, "silences"), stopc)
+		wg.Done()
+	}()
 	go func() {
-		silences.Maintenance(15*time.Minute, filepath.Join(*dataDir, "silences"), stopc)
+		silenceExpiry.Run(time.Minute, stopc)
+		wg.Done()
+	}()
+	go func() {
+		acks.Maintenance(15*time.Minute, filepath.Join(*dataDir, "acks"), stopc)
+		wg.Done()
+	}()
+	go func() {
+		annotations.Maintenance(15*time.Minute, filepath.Join(*dataDir, "annotations"), stopc)
 		wg.Done()
 	}()
 
+	if shipper != nil {
+		// Deferred before the maintenance is stopped below to upload the
+		// final snapshots.
+		defer shipper.Ship()
+		go shipper.Run(15*time.Minute, stopc)
+	}
 	defer func() {
 		close(stopc)
 		wg.Wait()
@@ -270,21 +471,99 @@ func main() {
 	}
 	defer alerts.Close()
 
+	if alertHistory != nil {
+		wg.Add(1)
+		go func() {
+			alertHistory.Run(alerts, time.Minute, stopc)
+			wg.Done()
+		}()
+	}
+
 	var (
 		inhibitor *inhibit.Inhibitor
 		tmpl      *template.Template
 		pipeline  notify.Stage
 		disp      *dispatch.Dispatcher
+		tenants   []*tenantRuntime
+
+		// reloadMtx guards the state that is replaced by reloads and used
+		// outside of the reloading goroutine.
+		reloadMtx sync.RWMutex
+		// deadLetterReceiver returns the receiver configuration and the
+		// template of the receiver of a dead letter.
+		deadLetterReceiver = func(*dlq.Entry) (*config.Receiver, *template.Template) { return nil, nil }
 	)
+	// Pending digests are sent once the dispatchers stopped.
+	defer notify.FlushDigests()
 	defer disp.Stop()
+	defer func() {
+		for _, t := range tenants {
+			t.stop()
+		}
+	}()
+
+	prometheus.MustRegister(dispatch.NewGroupsCollector(func() *dispatch.Dispatcher { return disp }))
+
+	deadLetters, err := dlq.New(dlq.Options{
+		Path:      filepath.Join(*dataDir, "dlq"),
+		Retention: *retention,
+		Replay: func(ctx context.Context, e *dlq.Entry) error {
+			reloadMtx.RLock()
+			rc, t := deadLetterReceiver(e)
+			reloadMtx.RUnlock()
+			if rc == nil {
+				return fmt.Errorf("receiver %q not found", e.Receiver)
+			}
+			return notify.ReplayDeadLetter(ctx, rc, t, e, log.With(logger, "component", "dlq"))
+		},
+		Logger:  log.With(logger, "component", "dlq"),
+		Metrics: prometheus.DefaultRegisterer,
+	})
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		os.Exit(1)
+	}
+	wg.Add(1)
+	go func() {
+		deadLetters.Maintenance(15*time.Minute, stopc)
+		wg.Done()
+	}()
+
+	auditLog, err := audit.New(audit.Options{
+		Path:     filepath.Join(*dataDir, "audit.log"),
+		MaxSize:  int64(*auditMaxSize),
+		MaxFiles: *auditMaxFiles,
+	})
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		os.Exit(1)
+	}
+	defer auditLog.Close()
 
 	apiv := api.New(
 		alerts,
 		silences,
+		acks,
+		deadLetters,
+		auditLog,
+		notificationLog,
 		func(matchers []*labels.Matcher) dispatch.AlertOverview {
-			return disp.Groups(matchers)
+			groups := disp.Groups(matchers)
+			for _, t := range tenants {
+				groups = append(groups, t.disp.Groups(matchers)...)
+			}
+			sort.Sort(groups)
+			return groups
 		},
 		marker.Status,
+		func(lset model.LabelSet) []inhibit.Inhibition {
+			for _, t := range tenants {
+				if lset[t.label] == model.LabelValue(t.name) {
+					return t.inhibitor.Inhibitors(lset)
+				}
+			}
+			return inhibitor.Inhibitors(lset)
+		},
 		peer,
 		logger,
 	)
@@ -296,7 +575,7 @@ func main() {
 	}
 
 	waitFunc := func() time.Duration { return 0 }
-	if peer != nil {
+	if peer != nil && *notificationMode == "staggered" {
 		waitFunc = clusterWait(peer, *peerTimeout)
 	}
 	timeoutFunc := func(d time.Duration) time.Duration {
@@ -306,9 +585,76 @@ func main() {
 		return d + waitFunc()
 	}
 
-	var hash float64
-	reload := func() (err error) {
-		level.Info(logger).Log("msg", "Loading configuration file", "file", *configFile)
+	var (
+		hash            float64
+		calendarFeeds   []*timeinterval.Feed
+		floodProtection *notify.FloodProtection
+	)
+	// The rate limiters of the receivers by tenant are kept across reloads.
+	rateLimiters := map[string]*notify.RateLimiters{}
+	rateLimitersOf := func(tenant string) *notify.RateLimiters {
+		if _, ok := rateLimiters[tenant]; !ok {
+			rateLimiters[tenant] = notify.NewRateLimiters()
+		}
+		return rateLimiters[tenant]
+	}
+	config.Jsonnet.Command, config.Jsonnet.LibPaths = *jsonnetCommand, *jsonnetPaths
+	config.DefaultUnknownFields = *unknownFields
+	loadConfig := func() (*config.Config, []byte, error) {
+		return config.LoadFiles(*configFile, *configDir)
+	}
+	var remoteConfig *remoteconfig.Source
+	if remoteconfig.IsRemote(*configFile) {
+		if *configDir != "" {
+			level.Error(logger).Log("msg", "Configuration fragments are not supported for remote configurations")
+			os.Exit(1)
+		}
+		remoteConfig, err = remoteconfig.NewSource(*configFile, nil)
+		if err != nil {
+			level.Error(logger).Log("msg", "Invalid remote configuration URL", "err", err)
+			os.Exit(1)
+		}
+		loadConfig = func() (*config.Config, []byte, error) {
+			return remoteConfig.Load(context.Background())
+		}
+	}
+
+	// The running configuration, which is restored if applying a
+	// configuration submitted to the API fails.
+	var (
+		runningConf  *config.Config
+		runningPlain []byte
+	)
+	var configHistory *confighistory.History
+	if *configHistorySize > 0 {
+		configHistory, err = confighistory.New(filepath.Join(*dataDir, "config-history"), *configHistorySize)
+		if err != nil {
+			level.Error(logger).Log("msg", "Opening configuration history failed", "err", err)
+			os.Exit(1)
+		}
+		apiv.SetConfigHistory(configHistory)
+	}
+
+	// The maintenance stage is part of all pipelines built by apply.
+	maintenance := notify.NewMaintenance(*maintenanceMode, *maintenanceReason)
+	if err := notify.RegisterStage(notify.BeforeDedup, "maintenance", 0, maintenance.StageFactory()); err != nil {
+		level.Error(logger).Log("msg", "Registering maintenance stage failed", "err", err)
+		os.Exit(1)
+	}
+	apiv.SetMaintenance(maintenance)
+	apiv.SetAnnotations(annotations)
+
+	if alertHistory != nil {
+		if err := notify.RegisterStage(notify.AfterSend, "history", 0, alertHistory.StageFactory()); err != nil {
+			level.Error(logger).Log("msg", "Registering alert history stage failed", "err", err)
+			os.Exit(1)
+		}
+		apiv.SetHistory(alertHistory)
+	}
+
+	// apply applies the configuration returned by the load function and
+	// records it in the history with the given source.
+	apply := func(source string, load func() (*config.Config, []byte, error)) (err error) {
 		defer func() {
 			if err != nil {
 				level.Error(logger).Log("msg", "Loading configuration file failed", "file", *configFile, "err", err)
@@ -320,10 +666,13 @@ func main() {
 			}
 		}()
 
-		conf, plainCfg, err := config.LoadFile(*configFile)
+		conf, plainCfg, err := load()
 		if err != nil {
 			return err
 		}
+		for _, w := range conf.Warnings() {
+			level.Warn(logger).Log("msg", "Ignoring configuration problem", "file", *configFile, "problem", w)
+		}
 
 		hash = md5HashAsMetricValue(plainCfg)
 
@@ -338,26 +687,174 @@ func main() {
 		}
 		tmpl.ExternalURL = amURL
 
+		tenantTmpls := make([]*template.Template, len(conf.Tenants))
+		for i, t := range conf.Tenants {
+			tenantTmpls[i], err = template.FromGlobs(append(conf.Templates, t.Templates...)...)
+			if err != nil {
+				return fmt.Errorf("tenant %q: %s", t.Name, err)
+			}
+			tenantTmpls[i].ExternalURL = amURL
+		}
+
 		inhibitor.Stop()
 		disp.Stop()
+		for _, t := range tenants {
+			t.stop()
+		}
+		notify.FlushDigests()
+
+		// Alerts of tenants are only seen by the tenants' dispatchers and
+		// inhibitors.
+		tenantLabel := conf.Global.TenantLabel
+		defaultAlerts := tenant.Exclude(alerts, tenantLabel, conf.Tenants)
+
+		inhibitor = inhibit.NewInhibitor(defaultAlerts, conf.AllInhibitRules(), marker, logger)
+
+		for _, f := range calendarFeeds {
+			f.Stop()
+		}
+		calendarFeeds = nil
+
+		floodProtection.Stop()
+		floodProtection = nil
+		if conf.FloodProtection != nil {
+			floodProtection = notify.NewFloodProtection(conf.FloodProtection, floodState, log.With(logger, "component", "flood-protection"))
+		}
 
-		inhibitor = inhibit.NewInhibitor(alerts, conf.InhibitRules, marker, logger)
-		pipeline = notify.BuildPipeline(
+		timeIntervals := make(map[string][]timeinterval.Matcher, len(conf.TimeIntervals)+len(conf.MuteTimeIntervals))
+		for _, tis := range [][]*config.TimeInterval{conf.TimeIntervals, conf.MuteTimeIntervals} {
+			for _, ti := range tis {
+				for _, i := range ti.TimeIntervals {
+					timeIntervals[ti.Name] = append(timeIntervals[ti.Name], i)
+				}
+				for _, c := range ti.Calendars {
+					f := timeinterval.NewFeed(c, log.With(logger, "component", "calendar", "time_interval", ti.Name))
+					calendarFeeds = append(calendarFeeds, f)
+					timeIntervals[ti.Name] = append(timeIntervals[ti.Name], f)
+					go f.Run()
+				}
+			}
+		}
+
+		p := notify.BuildPipeline(
 			conf.Receivers,
 			tmpl,
 			waitFunc,
 			inhibitor,
 			silences,
+			acks,
+			annotations,
+			timeIntervals,
 			notificationLog,
+			deadLetters,
+			auditLog,
 			marker,
 			peer,
+			*notificationMode == "sharded",
+			floodProtection,
+			rateLimitersOf(""),
 			logger,
 		)
-		disp = dispatch.NewDispatcher(alerts, dispatch.NewRoute(conf.Route, nil), pipeline, marker, timeoutFunc, logger)
+		disp = dispatch.NewDispatcher(defaultAlerts, dispatch.NewRoute(conf.Route, nil), p, marker, acks, timeoutFunc, logger)
+
+		tenants = nil
+		for i, t := range conf.Tenants {
+			tl := log.With(logger, "tenant", t.Name)
+			ta := tenant.Only(alerts, tenantLabel, t.Name)
+			ti := inhibit.NewInhibitor(ta, t.InhibitRules, marker, tl)
+			tp := notify.BuildPipeline(
+				t.Receivers,
+				tenantTmpls[i],
+				waitFunc,
+				ti,
+				silences,
+				acks,
+				annotations,
+				timeIntervals,
+				notificationLog,
+				deadLetters,
+				auditLog,
+				marker,
+				peer,
+				*notificationMode == "sharded",
+				nil,
+				rateLimitersOf(t.Name),
+				tl,
+			)
+			tenants = append(tenants, &tenantRuntime{
+				name:      t.Name,
+				label:     tenantLabel,
+				inhibitor: ti,
+				disp:      dispatch.NewDispatcher(ta, tenant.Route(t, tenantLabel), tp, marker, acks, timeoutFunc, tl),
+			})
+		}
+
+		defaultTmpl := tmpl
+		reloadMtx.Lock()
+		pipeline = p
+		deadLetterReceiver = func(e *dlq.Entry) (*config.Receiver, *template.Template) {
+			for i, t := range conf.Tenants {
+				if tenant.GroupKeys(t, tenantLabel)(e.GroupKey) {
+					return findReceiver(t.Receivers, e.Receiver), tenantTmpls[i]
+				}
+			}
+			return findReceiver(conf.Receivers, e.Receiver), defaultTmpl
+		}
+		reloadMtx.Unlock()
+
+		if conf.SilenceExpiry != nil {
+			n := notify.NewSilenceExpiryNotifier(conf.SilenceExpiry, conf.Receivers, pipeline, amURL.String(), log.With(logger, "component", "silence-expiry"))
+			silenceExpiry.Update(time.Duration(conf.SilenceExpiry.Before), n.Notify)
+		} else {
+			silenceExpiry.Update(0, nil)
+		}
+
+		if floodProtection != nil {
+			go floodProtection.Run(pipeline, amURL.String())
+		}
 
 		go disp.Run()
 		go inhibitor.Run()
+		for _, t := range tenants {
+			go t.disp.Run()
+			go t.inhibitor.Run()
+		}
 
+		runningConf, runningPlain = conf, plainCfg
+		if configHistory != nil {
+			if _, err := configHistory.Add(plainCfg, source); err != nil {
+				level.Error(logger).Log("msg", "Recording configuration history failed", "err", err)
+			}
+		}
+		return nil
+	}
+	reloadSource := confighistory.SourceFile
+	if remoteConfig != nil {
+		reloadSource = confighistory.SourceRemote
+	}
+	reload := func() error {
+		level.Info(logger).Log("msg", "Loading configuration file", "file", *configFile)
+		return apply(reloadSource, loadConfig)
+	}
+	// applySubmitted applies a configuration submitted to the API and
+	// writes it to the configuration file. On failure, the running
+	// configuration is restored.
+	applySubmitted := func(conf *config.Config, plain []byte, source string) error {
+		level.Info(logger).Log("msg", "Applying configuration submitted to the API", "source", source)
+		prevConf, prevPlain := runningConf, runningPlain
+		restore := func() {
+			if err := apply(confighistory.SourceRestore, func() (*config.Config, []byte, error) { return prevConf, prevPlain, nil }); err != nil {
+				level.Error(logger).Log("msg", "Restoring the running configuration failed", "err", err)
+			}
+		}
+		if err := apply(source, func() (*config.Config, []byte, error) { return conf, plain, nil }); err != nil {
+			restore()
+			return err
+		}
+		if err := writeFileAtomic(*configFile, plain); err != nil {
+			restore()
+			return fmt.Errorf("writing configuration file: %s", err)
+		}
 		return nil
 	}
 
@@ -384,14 +881,43 @@ func main() {
 
 	apiv.Register(router.WithPrefix("/api/v1"))
 
+	webConfig := &web.Config{}
+	if *webConfigFile != "" {
+		webConfig, err = web.LoadConfigFile(*webConfigFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "Loading web configuration file failed", "file", *webConfigFile, "err", err)
+			os.Exit(1)
+		}
+	}
+
+	webHandler, err := web.NewHandler(webConfig, *routePrefix, router)
+	if err != nil {
+		level.Error(logger).Log("msg", "Creating web handler failed", "err", err)
+		os.Exit(1)
+	}
+	defer webHandler.Close()
+	if *enableConfigAPI && !webHandler.AuthenticationRequired() {
+		level.Error(logger).Log("msg", "Enabling the configuration API requires authentication to be configured in the web configuration file")
+		os.Exit(1)
+	}
+
 	level.Info(logger).Log("msg", "Listening", "address", *listenAddress)
-	go listen(*listenAddress, router, logger)
+	go listen(*listenAddress, webConfig, webHandler, logger)
 
 	var (
-		hup      = make(chan os.Signal)
-		hupReady = make(chan bool)
-		term     = make(chan os.Signal, 1)
+		hup          = make(chan os.Signal)
+		hupReady     = make(chan bool)
+		term         = make(chan os.Signal, 1)
+		remoteReload = make(chan struct{})
+		fileReload   = make(chan struct{})
+		configApply  = make(chan func())
+		secretTick   <-chan time.Time
 	)
+	if *secretRefresh > 0 {
+		t := time.NewTicker(*secretRefresh)
+		defer t.Stop()
+		secretTick = t.C
+	}
 	signal.Notify(hup, syscall.SIGHUP)
 	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
 
@@ -401,12 +927,63 @@ func main() {
 			select {
 			case <-hup:
 				reload()
+			case <-remoteReload:
+				reload()
+			case <-fileReload:
+				reload()
+			case f := <-configApply:
+				f()
+			case <-secretTick:
+				changed, err := runningConf.SecretsChanged()
+				if err != nil {
+					level.Error(logger).Log("msg", "Resolving configuration secrets failed", "err", err)
+					continue
+				}
+				if changed {
+					level.Info(logger).Log("msg", "Configuration secrets changed")
+					reload()
+				}
 			case errc := <-webReload:
 				errc <- reload()
 			}
 		}
 	}()
 
+	// Configurations submitted to the API are applied by the same goroutine
+	// as reloads if enabled. Remote, merged and generated configurations
+	// cannot be replaced.
+	if *enableConfigAPI && remoteConfig == nil && *configDir == "" && !config.IsJsonnet(*configFile) {
+		apiv.SetConfigApplier(func(conf *config.Config, plain []byte, source string) error {
+			errc := make(chan error, 1)
+			configApply <- func() { errc <- applySubmitted(conf, plain, source) }
+			return <-errc
+		})
+	}
+
+	if remoteConfig != nil && *configPoll > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go remoteConfig.Poll(ctx, *configPoll, func() {
+			level.Info(logger).Log("msg", "Remote configuration changed", "file", *configFile)
+			remoteReload <- struct{}{}
+		}, func(err error) {
+			level.Error(logger).Log("msg", "Checking remote configuration for changes failed", "file", *configFile, "err", err)
+		})
+	}
+
+	if remoteConfig == nil && *configWatch > 0 {
+		paths := []string{*configFile}
+		if *configDir != "" {
+			paths = append(paths, *configDir)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go filewatch.New(paths, *configWatch, *configDebounce).Run(ctx, func() {
+			level.Info(logger).Log("msg", "Configuration file changed", "file", *configFile)
+			fileReload <- struct{}{}
+		})
+	}
+
 	// Wait for reload or termination signals.
 	close(hupReady) // Unblock SIGHUP handler.
 
@@ -451,8 +1028,8 @@ func extURL(listen, external string) (*url.URL, error) {
 	return u, nil
 }
 
-func listen(listen string, router *route.Router, logger log.Logger) {
-	if err := http.ListenAndServe(listen, router); err != nil {
+func listen(listen string, webConfig *web.Config, h http.Handler, logger log.Logger) {
+	if err := web.ListenAndServe(listen, webConfig, h); err != nil {
 		level.Error(logger).Log("msg", "Listen error", "err", err)
 		os.Exit(1)
 	}
@@ -466,3 +1043,52 @@ func md5HashAsMetricValue(data []byte) float64 {
 	copy(bytes, smallSum)
 	return float64(binary.LittleEndian.Uint64(bytes))
 }
+
+// tenantRuntime is the dispatcher and inhibitor of a tenant.
+type tenantRuntime struct {
+	name      string
+	label     model.LabelName
+	disp      *dispatch.Dispatcher
+	inhibitor *inhibit.Inhibitor
+}
+
+func (t *tenantRuntime) stop() {
+	t.inhibitor.Stop()
+	t.disp.Stop()
+}
+
+// writeFileAtomic replaces the content of the file by writing a temporary
+// file in the same directory and renaming it.
+func writeFileAtomic(filename string, content []byte) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(filename); err == nil {
+		mode = fi.Mode()
+	}
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// findReceiver returns the receiver with the given name or nil.
+func findReceiver(receivers []*config.Receiver, name string) *config.Receiver {
+	for _, rc := range receivers {
+		if rc.Name == name {
+			return rc
+		}
+	}
+	return nil
+}
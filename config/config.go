@@ -17,18 +17,26 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
-	commoncfg "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/pkg/parse"
+	amtemplate "github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/alertmanager/types"
 )
 
-// Secret is a string that must not be revealed on marshaling.
+// Secret is a string that must not be revealed on marshaling. Its value may
+// be read from the environment, a file or Vault when the configuration is
+// loaded, see resolveSecret.
 type Secret string
 
 // MarshalYAML implements the yaml.Marshaler interface.
@@ -50,13 +58,70 @@ func (s Secret) MarshalJSON() ([]byte, error) {
 	return json.Marshal("<secret>")
 }
 
-// Load parses the YAML input s into a Config.
+// Load parses the YAML input s into a Config. Errors of invalid
+// configurations are of type *Error.
 func Load(s string) (*Config, error) {
-	cfg := &Config{}
-	err := yaml.UnmarshalStrict([]byte(s), cfg)
+	cfg, err := unmarshal(s)
+	if err != nil {
+		return nil, newError(s, err)
+	}
+	if err := resolveSecrets(cfg); err != nil {
+		return nil, newError(s, err)
+	}
+
+	cfg.original = s
+	return cfg, nil
+}
+
+// LoadSubmitted parses the YAML input s submitted by a client, e.g. through
+// the API, into a Config. Its secrets may only reference what the secrets
+// of the running configuration reference, which keeps clients from reading
+// the environment, files and Vault secrets of the server through the
+// configuration.
+func LoadSubmitted(s string, running *Config) (*Config, error) {
+	cfg, err := unmarshal(s)
+	if err != nil {
+		return nil, newError(s, err)
+	}
+	var known map[string]string
+	if running != nil {
+		known = running.secretRefs
+	}
+	if err := resolveKnownSecrets(cfg, known); err != nil {
+		return nil, newError(s, err)
+	}
+
+	cfg.original = s
+	return cfg, nil
+}
+
+// unmarshal parses the YAML input s into a Config without resolving its
+// secrets.
+func unmarshal(s string) (*Config, error) {
+	s, err := expandReceivers(s)
 	if err != nil {
 		return nil, err
 	}
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict([]byte(s), cfg); err != nil {
+		te, ok := err.(*yaml.TypeError)
+		if !ok {
+			return nil, err
+		}
+		errs, warnings := filterUnknownFields(s, te)
+		if len(errs) == len(te.Errors) {
+			return nil, err
+		}
+		if len(errs) > 0 {
+			return nil, &yaml.TypeError{Errors: errs}
+		}
+		// Parse again as the strict parsing stopped at the unknown fields.
+		cfg = &Config{}
+		if err := yaml.Unmarshal([]byte(s), cfg); err != nil {
+			return nil, err
+		}
+		cfg.warnings = warnings
+	}
 	// Check if we have a root route. We cannot check for it in the
 	// UnmarshalYAML method because it won't be called if the input is empty
 	// (e.g. the config file is empty or only contains whitespace).
@@ -68,20 +133,20 @@ func Load(s string) (*Config, error) {
 	if cfg.Route.Continue {
 		return nil, errors.New("cannot have continue in root route")
 	}
-
-	cfg.original = s
 	return cfg, nil
 }
 
-// LoadFile parses the given YAML file into a Config.
+// LoadFile parses the given YAML or JSON file into a Config. Files ending
+// in .jsonnet are evaluated first, see Jsonnet. The returned content is the
+// parsed configuration.
 func LoadFile(filename string) (*Config, []byte, error) {
-	content, err := ioutil.ReadFile(filename)
+	content, err := readFile(filename)
 	if err != nil {
 		return nil, nil, err
 	}
 	cfg, err := Load(string(content))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, withFile(err, filename)
 	}
 
 	resolveFilepaths(filepath.Dir(filename), cfg)
@@ -101,6 +166,11 @@ func resolveFilepaths(baseDir string, cfg *Config) {
 	for i, tf := range cfg.Templates {
 		cfg.Templates[i] = join(tf)
 	}
+	for _, t := range cfg.Tenants {
+		for i, tf := range t.Templates {
+			t.Templates[i] = join(tf)
+		}
+	}
 }
 
 // Config is the top-level configuration for Alertmanager's config files.
@@ -111,8 +181,52 @@ type Config struct {
 	Receivers    []*Receiver    `yaml:"receivers,omitempty" json:"receivers,omitempty"`
 	Templates    []string       `yaml:"templates" json:"templates"`
 
+	// SeverityInhibition makes alerts inhibit alerts of lower severity.
+	SeverityInhibition *SeverityInhibition `yaml:"severity_inhibition,omitempty" json:"severity_inhibition,omitempty"`
+
+	SilenceTemplates []*SilenceTemplate   `yaml:"silence_templates,omitempty" json:"silence_templates,omitempty"`
+	SilenceExpiry    *SilenceExpiryConfig `yaml:"silence_expiry_notification,omitempty" json:"silence_expiry_notification,omitempty"`
+	SilencePolicy    *SilencePolicy       `yaml:"silence_policy,omitempty" json:"silence_policy,omitempty"`
+
+	FloodProtection *FloodProtectionConfig `yaml:"flood_protection,omitempty" json:"flood_protection,omitempty"`
+
+	// TimeIntervals and MuteTimeIntervals are named time intervals referenced
+	// by the mute and active time intervals of routes. MuteTimeIntervals is
+	// kept for compatibility, both lists are equivalent.
+	TimeIntervals     []*TimeInterval `yaml:"time_intervals,omitempty" json:"time_intervals,omitempty"`
+	MuteTimeIntervals []*TimeInterval `yaml:"mute_time_intervals,omitempty" json:"mute_time_intervals,omitempty"`
+
+	// Tenants have their own routing trees, receivers, inhibition rules and
+	// templates, which apply to the alerts labeled with their name.
+	Tenants []*Tenant `yaml:"tenants,omitempty" json:"tenants,omitempty"`
+
+	// UnknownFields sets how unknown fields of the sections are handled.
+	UnknownFields UnknownFieldsConfig `yaml:"unknown_fields,omitempty" json:"unknown_fields,omitempty"`
+
 	// original is the input from which the config was parsed.
 	original string
+	// warnings are the problems of the config that were ignored.
+	warnings []string
+	// secretRefs maps the secret references of the config to their
+	// resolved values.
+	secretRefs map[string]string
+}
+
+// Warnings returns the ignored problems of the configuration, such as
+// unknown fields.
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
+// AllInhibitRules returns the inhibition rules of the configuration followed
+// by the rules generated for the severity inhibition.
+func (c *Config) AllInhibitRules() []*InhibitRule {
+	if c.SeverityInhibition == nil {
+		return c.InhibitRules
+	}
+	rules := make([]*InhibitRule, 0, len(c.InhibitRules)+len(c.SeverityInhibition.Order)-1)
+	rules = append(rules, c.InhibitRules...)
+	return append(rules, c.SeverityInhibition.InhibitRules()...)
 }
 
 func (c Config) String() string {
@@ -140,193 +254,448 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		*c.Global = DefaultGlobalConfig
 	}
 
+	tmplNames := map[string]struct{}{}
+	for _, st := range c.SilenceTemplates {
+		if _, ok := tmplNames[st.Name]; ok {
+			return fmt.Errorf("silence template name %q is not unique", st.Name)
+		}
+		tmplNames[st.Name] = struct{}{}
+	}
+
 	names := map[string]struct{}{}
 
 	for _, rcv := range c.Receivers {
 		if _, ok := names[rcv.Name]; ok {
 			return fmt.Errorf("notification config name %q is not unique", rcv.Name)
 		}
-		for _, wh := range rcv.WebhookConfigs {
-			if wh.HTTPConfig == nil {
-				wh.HTTPConfig = c.Global.HTTPConfig
-			}
+		if err := c.Global.setReceiverDefaults(rcv); err != nil {
+			return err
 		}
-		for _, ec := range rcv.EmailConfigs {
-			if ec.Smarthost == "" {
-				if c.Global.SMTPSmarthost == "" {
-					return fmt.Errorf("no global SMTP smarthost set")
-				}
-				ec.Smarthost = c.Global.SMTPSmarthost
-			}
-			if ec.From == "" {
-				if c.Global.SMTPFrom == "" {
-					return fmt.Errorf("no global SMTP from set")
-				}
-				ec.From = c.Global.SMTPFrom
-			}
-			if ec.Hello == "" {
-				ec.Hello = c.Global.SMTPHello
-			}
-			if ec.AuthUsername == "" {
-				ec.AuthUsername = c.Global.SMTPAuthUsername
-			}
-			if ec.AuthPassword == "" {
-				ec.AuthPassword = c.Global.SMTPAuthPassword
-			}
-			if ec.AuthSecret == "" {
-				ec.AuthSecret = c.Global.SMTPAuthSecret
-			}
-			if ec.AuthIdentity == "" {
-				ec.AuthIdentity = c.Global.SMTPAuthIdentity
-			}
-			if ec.RequireTLS == nil {
-				ec.RequireTLS = new(bool)
-				*ec.RequireTLS = c.Global.SMTPRequireTLS
-			}
+		names[rcv.Name] = struct{}{}
+	}
+
+	if c.SilenceExpiry != nil {
+		if _, ok := names[c.SilenceExpiry.Receiver]; !ok {
+			return fmt.Errorf("undefined receiver %q used in silence expiry notification", c.SilenceExpiry.Receiver)
 		}
-		for _, sc := range rcv.SlackConfigs {
-			if sc.HTTPConfig == nil {
-				sc.HTTPConfig = c.Global.HTTPConfig
-			}
-			if sc.APIURL == "" {
-				if c.Global.SlackAPIURL == "" {
-					return fmt.Errorf("no global Slack API URL set")
-				}
-				sc.APIURL = c.Global.SlackAPIURL
-			}
+	}
+
+	if c.FloodProtection != nil && c.FloodProtection.Receiver != "" {
+		if _, ok := names[c.FloodProtection.Receiver]; !ok {
+			return fmt.Errorf("undefined receiver %q used in flood protection", c.FloodProtection.Receiver)
 		}
-		for _, hc := range rcv.HipchatConfigs {
-			if hc.HTTPConfig == nil {
-				hc.HTTPConfig = c.Global.HTTPConfig
-			}
-			if hc.APIURL == "" {
-				if c.Global.HipchatAPIURL == "" {
-					return fmt.Errorf("no global Hipchat API URL set")
-				}
-				hc.APIURL = c.Global.HipchatAPIURL
-			}
-			if !strings.HasSuffix(hc.APIURL, "/") {
-				hc.APIURL += "/"
-			}
-			if hc.AuthToken == "" {
-				if c.Global.HipchatAuthToken == "" {
-					return fmt.Errorf("no global Hipchat Auth Token set")
-				}
-				hc.AuthToken = c.Global.HipchatAuthToken
+	}
+
+	// The root route must not have any matchers as it is the fallback node
+	// for all alerts.
+	if c.Route == nil {
+		return fmt.Errorf("no routes provided")
+	}
+	if len(c.Route.Receiver) == 0 {
+		return fmt.Errorf("root route must specify a default receiver")
+	}
+	if len(c.Route.Match) > 0 || len(c.Route.MatchRE) > 0 || len(c.Route.Matchers) > 0 {
+		return fmt.Errorf("root route must not have any matchers")
+	}
+	if len(c.Route.MuteTimeIntervals) > 0 {
+		return fmt.Errorf("root route must not have any mute time intervals")
+	}
+	if len(c.Route.ActiveTimeIntervals) > 0 {
+		return fmt.Errorf("root route must not have any active time intervals")
+	}
+
+	tiNames := map[string]struct{}{}
+	for _, tis := range [][]*TimeInterval{c.TimeIntervals, c.MuteTimeIntervals} {
+		for _, ti := range tis {
+			if _, ok := tiNames[ti.Name]; ok {
+				return fmt.Errorf("time interval %q is not unique", ti.Name)
 			}
+			tiNames[ti.Name] = struct{}{}
+		}
+	}
+	if err := checkTimeInterval(c.Route, tiNames); err != nil {
+		return err
+	}
+
+	for _, rcv := range c.Receivers {
+		if rcv.CircuitBreaker == nil || rcv.CircuitBreaker.FallbackReceiver == "" {
+			continue
+		}
+		if rcv.CircuitBreaker.FallbackReceiver == rcv.Name {
+			return fmt.Errorf("receiver %q cannot be its own fallback receiver", rcv.Name)
+		}
+		if _, ok := names[rcv.CircuitBreaker.FallbackReceiver]; !ok {
+			return fmt.Errorf("undefined fallback receiver %q used in receiver %q", rcv.CircuitBreaker.FallbackReceiver, rcv.Name)
+		}
+	}
+
+	// Validate that all receivers used in the routing tree are defined.
+	if err := checkReceiver(c.Route, names); err != nil {
+		return err
+	}
+
+	if len(c.Tenants) > 0 && !c.Global.TenantLabel.IsValid() {
+		return fmt.Errorf("invalid tenant label %q", c.Global.TenantLabel)
+	}
+	tenants := map[string]struct{}{}
+	for _, t := range c.Tenants {
+		if _, ok := tenants[t.Name]; ok {
+			return fmt.Errorf("tenant %q is not unique", t.Name)
+		}
+		tenants[t.Name] = struct{}{}
+		if err := t.check(c.Global, tiNames); err != nil {
+			return fmt.Errorf("tenant %q: %s", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// Tenant is the configuration of a tenant sharing the Alertmanager. Its
+// alerts are routed by the tenant's routing tree only and inhibited by the
+// tenant's inhibition rules only. Time intervals are shared by all tenants.
+type Tenant struct {
+	Name         string         `yaml:"name" json:"name"`
+	Route        *Route         `yaml:"route,omitempty" json:"route,omitempty"`
+	Receivers    []*Receiver    `yaml:"receivers,omitempty" json:"receivers,omitempty"`
+	InhibitRules []*InhibitRule `yaml:"inhibit_rules,omitempty" json:"inhibit_rules,omitempty"`
+	Templates    []string       `yaml:"templates" json:"templates"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (t *Tenant) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Tenant
+	if err := unmarshal((*plain)(t)); err != nil {
+		return err
+	}
+	if t.Name == "" {
+		return fmt.Errorf("missing name in tenant")
+	}
+	if !model.LabelValue(t.Name).IsValid() {
+		return fmt.Errorf("invalid tenant name %q", t.Name)
+	}
+	if t.Route == nil {
+		return fmt.Errorf("missing route in tenant %q", t.Name)
+	}
+	return nil
+}
+
+// check sets the global defaults of the tenant's receivers and validates
+// its routing tree like the one of the configuration.
+func (t *Tenant) check(g *GlobalConfig, timeIntervals map[string]struct{}) error {
+	names := map[string]struct{}{}
+	for _, rcv := range t.Receivers {
+		if _, ok := names[rcv.Name]; ok {
+			return fmt.Errorf("notification config name %q is not unique", rcv.Name)
+		}
+		if err := g.setReceiverDefaults(rcv); err != nil {
+			return err
+		}
+		names[rcv.Name] = struct{}{}
+	}
+
+	if len(t.Route.Receiver) == 0 {
+		return fmt.Errorf("root route must specify a default receiver")
+	}
+	if len(t.Route.Match) > 0 || len(t.Route.MatchRE) > 0 || len(t.Route.Matchers) > 0 {
+		return fmt.Errorf("root route must not have any matchers")
+	}
+	if t.Route.Continue {
+		return fmt.Errorf("cannot have continue in root route")
+	}
+	if err := checkTimeInterval(t.Route, timeIntervals); err != nil {
+		return err
+	}
+	return checkReceiver(t.Route, names)
+}
+
+// setReceiverDefaults sets the unset fields of the receiver's integrations
+// that have global defaults.
+func (g *GlobalConfig) setReceiverDefaults(rcv *Receiver) error {
+	for _, wh := range rcv.WebhookConfigs {
+		if wh.HTTPConfig == nil {
+			wh.HTTPConfig = g.HTTPConfig
 		}
-		for _, poc := range rcv.PushoverConfigs {
-			if poc.HTTPConfig == nil {
-				poc.HTTPConfig = c.Global.HTTPConfig
+	}
+	for _, ec := range rcv.EmailConfigs {
+		if ec.Smarthost == "" {
+			if g.SMTPSmarthost == "" {
+				return fmt.Errorf("no global SMTP smarthost set")
 			}
+			ec.Smarthost = g.SMTPSmarthost
 		}
-		for _, pdc := range rcv.PagerdutyConfigs {
-			if pdc.HTTPConfig == nil {
-				pdc.HTTPConfig = c.Global.HTTPConfig
+		if ec.From == "" {
+			if g.SMTPFrom == "" {
+				return fmt.Errorf("no global SMTP from set")
 			}
-			if pdc.URL == "" {
-				if c.Global.PagerdutyURL == "" {
-					return fmt.Errorf("no global PagerDuty URL set")
-				}
-				pdc.URL = c.Global.PagerdutyURL
+			ec.From = g.SMTPFrom
+		}
+		if ec.Hello == "" {
+			ec.Hello = g.SMTPHello
+		}
+		if ec.AuthUsername == "" {
+			ec.AuthUsername = g.SMTPAuthUsername
+		}
+		if ec.AuthPassword == "" {
+			ec.AuthPassword = g.SMTPAuthPassword
+		}
+		if ec.AuthSecret == "" {
+			ec.AuthSecret = g.SMTPAuthSecret
+		}
+		if ec.AuthIdentity == "" {
+			ec.AuthIdentity = g.SMTPAuthIdentity
+		}
+		if ec.RequireTLS == nil {
+			ec.RequireTLS = new(bool)
+			*ec.RequireTLS = g.SMTPRequireTLS
+		}
+	}
+	for _, sc := range rcv.SlackConfigs {
+		if sc.HTTPConfig == nil {
+			sc.HTTPConfig = g.HTTPConfig
+		}
+		if sc.APIURL == "" {
+			if g.SlackAPIURL == "" {
+				return fmt.Errorf("no global Slack API URL set")
 			}
+			sc.APIURL = g.SlackAPIURL
 		}
-		for _, ogc := range rcv.OpsGenieConfigs {
-			if ogc.HTTPConfig == nil {
-				ogc.HTTPConfig = c.Global.HTTPConfig
+	}
+	for _, hc := range rcv.HipchatConfigs {
+		if hc.HTTPConfig == nil {
+			hc.HTTPConfig = g.HTTPConfig
+		}
+		if hc.APIURL == "" {
+			if g.HipchatAPIURL == "" {
+				return fmt.Errorf("no global Hipchat API URL set")
 			}
-			if ogc.APIURL == "" {
-				if c.Global.OpsGenieAPIURL == "" {
-					return fmt.Errorf("no global OpsGenie URL set")
-				}
-				ogc.APIURL = c.Global.OpsGenieAPIURL
+			hc.APIURL = g.HipchatAPIURL
+		}
+		if !strings.HasSuffix(hc.APIURL, "/") {
+			hc.APIURL += "/"
+		}
+		if hc.AuthToken == "" {
+			if g.HipchatAuthToken == "" {
+				return fmt.Errorf("no global Hipchat Auth Token set")
 			}
-			if !strings.HasSuffix(ogc.APIURL, "/") {
-				ogc.APIURL += "/"
+			hc.AuthToken = g.HipchatAuthToken
+		}
+	}
+	for _, poc := range rcv.PushoverConfigs {
+		if poc.HTTPConfig == nil {
+			poc.HTTPConfig = g.HTTPConfig
+		}
+	}
+	for _, pdc := range rcv.PagerdutyConfigs {
+		if pdc.HTTPConfig == nil {
+			pdc.HTTPConfig = g.HTTPConfig
+		}
+		if pdc.URL == "" {
+			if g.PagerdutyURL == "" {
+				return fmt.Errorf("no global PagerDuty URL set")
 			}
-			if ogc.APIKey == "" {
-				if c.Global.OpsGenieAPIKey == "" {
-					return fmt.Errorf("no global OpsGenie API Key set")
-				}
-				ogc.APIKey = c.Global.OpsGenieAPIKey
+			pdc.URL = g.PagerdutyURL
+		}
+	}
+	for _, ogc := range rcv.OpsGenieConfigs {
+		if ogc.HTTPConfig == nil {
+			ogc.HTTPConfig = g.HTTPConfig
+		}
+		if ogc.APIURL == "" {
+			if g.OpsGenieAPIURL == "" {
+				return fmt.Errorf("no global OpsGenie URL set")
 			}
+			ogc.APIURL = g.OpsGenieAPIURL
 		}
-		for _, wcc := range rcv.WechatConfigs {
-			if wcc.HTTPConfig == nil {
-				wcc.HTTPConfig = c.Global.HTTPConfig
+		if !strings.HasSuffix(ogc.APIURL, "/") {
+			ogc.APIURL += "/"
+		}
+		if ogc.APIKey == "" {
+			if g.OpsGenieAPIKey == "" {
+				return fmt.Errorf("no global OpsGenie API Key set")
 			}
+			ogc.APIKey = g.OpsGenieAPIKey
+		}
+	}
+	for _, wcc := range rcv.WechatConfigs {
+		if wcc.HTTPConfig == nil {
+			wcc.HTTPConfig = g.HTTPConfig
+		}
 
-			if wcc.APIURL == "" {
-				if c.Global.WeChatAPIURL == "" {
-					return fmt.Errorf("no global Wechat URL set")
-				}
-				wcc.APIURL = c.Global.WeChatAPIURL
+		if wcc.APIURL == "" {
+			if g.WeChatAPIURL == "" {
+				return fmt.Errorf("no global Wechat URL set")
 			}
+			wcc.APIURL = g.WeChatAPIURL
+		}
 
-			if wcc.APISecret == "" {
-				if c.Global.WeChatAPISecret == "" {
-					return fmt.Errorf("no global Wechat ApiSecret set")
-				}
-				wcc.APISecret = c.Global.WeChatAPISecret
+		if wcc.APISecret == "" {
+			if g.WeChatAPISecret == "" {
+				return fmt.Errorf("no global Wechat ApiSecret set")
 			}
+			wcc.APISecret = g.WeChatAPISecret
+		}
 
-			if wcc.CorpID == "" {
-				if c.Global.WeChatAPICorpID == "" {
-					return fmt.Errorf("no global Wechat CorpID set")
-				}
-				wcc.CorpID = c.Global.WeChatAPICorpID
+		if wcc.CorpID == "" {
+			if g.WeChatAPICorpID == "" {
+				return fmt.Errorf("no global Wechat CorpID set")
 			}
+			wcc.CorpID = g.WeChatAPICorpID
+		}
 
-			if !strings.HasSuffix(wcc.APIURL, "/") {
-				wcc.APIURL += "/"
+		if !strings.HasSuffix(wcc.APIURL, "/") {
+			wcc.APIURL += "/"
+		}
+	}
+	for _, voc := range rcv.VictorOpsConfigs {
+		if voc.HTTPConfig == nil {
+			voc.HTTPConfig = g.HTTPConfig
+		}
+		if voc.APIURL == "" {
+			if g.VictorOpsAPIURL == "" {
+				return fmt.Errorf("no global VictorOps URL set")
 			}
+			voc.APIURL = g.VictorOpsAPIURL
 		}
-		for _, voc := range rcv.VictorOpsConfigs {
-			if voc.HTTPConfig == nil {
-				voc.HTTPConfig = c.Global.HTTPConfig
+		if !strings.HasSuffix(voc.APIURL, "/") {
+			voc.APIURL += "/"
+		}
+		if voc.APIKey == "" {
+			if g.VictorOpsAPIKey == "" {
+				return fmt.Errorf("no global VictorOps API Key set")
 			}
-			if voc.APIURL == "" {
-				if c.Global.VictorOpsAPIURL == "" {
-					return fmt.Errorf("no global VictorOps URL set")
-				}
-				voc.APIURL = c.Global.VictorOpsAPIURL
+			voc.APIKey = g.VictorOpsAPIKey
+		}
+	}
+	for _, tc := range rcv.TwilioConfigs {
+		if tc.HTTPConfig == nil {
+			tc.HTTPConfig = g.HTTPConfig
+		}
+		if tc.APIURL == "" {
+			if g.TwilioAPIURL == "" {
+				return fmt.Errorf("no global Twilio API URL set")
 			}
-			if !strings.HasSuffix(voc.APIURL, "/") {
-				voc.APIURL += "/"
+			tc.APIURL = g.TwilioAPIURL
+		}
+		if !strings.HasSuffix(tc.APIURL, "/") {
+			tc.APIURL += "/"
+		}
+		if tc.AccountSID == "" {
+			if g.TwilioAccountSID == "" {
+				return fmt.Errorf("no global Twilio account SID set")
 			}
-			if voc.APIKey == "" {
-				if c.Global.VictorOpsAPIKey == "" {
-					return fmt.Errorf("no global VictorOps API Key set")
-				}
-				voc.APIKey = c.Global.VictorOpsAPIKey
+			tc.AccountSID = g.TwilioAccountSID
+		}
+		if tc.AuthToken == "" && tc.AuthTokenFile == "" {
+			if g.TwilioAuthToken == "" {
+				return fmt.Errorf("no global Twilio auth token set")
 			}
+			tc.AuthToken = g.TwilioAuthToken
 		}
-		names[rcv.Name] = struct{}{}
 	}
-
-	// The root route must not have any matchers as it is the fallback node
-	// for all alerts.
-	if c.Route == nil {
-		return fmt.Errorf("no routes provided")
+	for _, vc := range rcv.VoiceConfigs {
+		if vc.HTTPConfig == nil {
+			vc.HTTPConfig = g.HTTPConfig
+		}
+		if vc.Provider != "twilio" {
+			continue
+		}
+		if vc.APIURL == "" {
+			if g.TwilioAPIURL == "" {
+				return fmt.Errorf("no global Twilio API URL set")
+			}
+			vc.APIURL = g.TwilioAPIURL
+		}
+		if !strings.HasSuffix(vc.APIURL, "/") {
+			vc.APIURL += "/"
+		}
+		if vc.AccountSID == "" {
+			if g.TwilioAccountSID == "" {
+				return fmt.Errorf("no global Twilio account SID set")
+			}
+			vc.AccountSID = g.TwilioAccountSID
+		}
+		if vc.AuthToken == "" && vc.AuthTokenFile == "" {
+			if g.TwilioAuthToken == "" {
+				return fmt.Errorf("no global Twilio auth token set")
+			}
+			vc.AuthToken = g.TwilioAuthToken
+		}
 	}
-	if len(c.Route.Receiver) == 0 {
-		return fmt.Errorf("root route must specify a default receiver")
+	for _, jc := range rcv.JiraConfigs {
+		if jc.HTTPConfig == nil {
+			jc.HTTPConfig = g.HTTPConfig
+		}
 	}
-	if len(c.Route.Match) > 0 || len(c.Route.MatchRE) > 0 {
-		return fmt.Errorf("root route must not have any matchers")
+	for _, snc := range rcv.ServiceNowConfigs {
+		if snc.HTTPConfig == nil {
+			snc.HTTPConfig = g.HTTPConfig
+		}
+	}
+	for _, spc := range rcv.SplunkConfigs {
+		if spc.HTTPConfig == nil {
+			spc.HTTPConfig = g.HTTPConfig
+		}
+	}
+	for _, psc := range rcv.PubSubConfigs {
+		if psc.HTTPConfig == nil {
+			psc.HTTPConfig = g.HTTPConfig
+		}
+	}
+	if rcv.Enrichment != nil && rcv.Enrichment.HTTPConfig == nil {
+		rcv.Enrichment.HTTPConfig = g.HTTPConfig
 	}
+	return nil
+}
 
-	// Validate that all receivers used in the routing tree are defined.
-	return checkReceiver(c.Route, names)
+// checkTimeInterval returns an error if a node in the routing tree
+// references a time interval not in the given map.
+func checkTimeInterval(r *Route, timeIntervals map[string]struct{}) error {
+	for _, name := range r.MuteTimeIntervals {
+		if _, ok := timeIntervals[name]; !ok {
+			return fmt.Errorf("undefined mute time interval %q used in route", name)
+		}
+	}
+	for _, name := range r.ActiveTimeIntervals {
+		if _, ok := timeIntervals[name]; !ok {
+			return fmt.Errorf("undefined active time interval %q used in route", name)
+		}
+	}
+	for _, sr := range r.Routes {
+		if err := checkTimeInterval(sr, timeIntervals); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // checkReceiver returns an error if a node in the routing tree
 // references a receiver not in the given map.
 func checkReceiver(r *Route, receivers map[string]struct{}) error {
+	for _, e := range r.Escalation {
+		if _, ok := receivers[e.Receiver]; !ok {
+			return fmt.Errorf("undefined escalation receiver %q used in route", e.Receiver)
+		}
+	}
+	if r.FallbackReceiver != "" {
+		if _, ok := receivers[r.FallbackReceiver]; !ok {
+			return fmt.Errorf("undefined fallback receiver %q used in route", r.FallbackReceiver)
+		}
+	}
+	if r.Split != nil {
+		for _, sr := range r.Split.Receivers {
+			if _, ok := receivers[sr.Receiver]; !ok {
+				return fmt.Errorf("undefined receiver %q used in route split", sr.Receiver)
+			}
+		}
+	}
 	if r.Receiver == "" {
 		return nil
 	}
-	if _, ok := receivers[r.Receiver]; !ok {
+	if r.ReceiverTemplate != nil {
+		if _, ok := receivers[r.DefaultReceiver]; !ok {
+			return fmt.Errorf("undefined default receiver %q used in route", r.DefaultReceiver)
+		}
+	} else if _, ok := receivers[r.Receiver]; !ok {
 		return fmt.Errorf("undefined receiver %q used in route", r.Receiver)
 	}
 	for _, sr := range r.Routes {
@@ -337,10 +706,15 @@ func checkReceiver(r *Route, receivers map[string]struct{}) error {
 	return nil
 }
 
+// DefaultCircuitBreakerConfig provides default values for circuit breakers.
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	OpenDuration: model.Duration(5 * time.Minute),
+}
+
 // DefaultGlobalConfig provides global default values.
 var DefaultGlobalConfig = GlobalConfig{
 	ResolveTimeout: model.Duration(5 * time.Minute),
-	HTTPConfig:     &commoncfg.HTTPClientConfig{},
+	HTTPConfig:     &HTTPClientConfig{},
 
 	SMTPHello:       "localhost",
 	SMTPRequireTLS:  true,
@@ -349,6 +723,9 @@ var DefaultGlobalConfig = GlobalConfig{
 	OpsGenieAPIURL:  "https://api.opsgenie.com/",
 	WeChatAPIURL:    "https://qyapi.weixin.qq.com/cgi-bin/",
 	VictorOpsAPIURL: "https://alert.victorops.com/integrations/generic/20131114/alert/",
+	TwilioAPIURL:    "https://api.twilio.com/2010-04-01/",
+	TenantLabel:     "tenant",
+	TenantHeader:    "X-Alertmanager-Tenant",
 }
 
 // GlobalConfig defines configuration parameters that are valid globally
@@ -358,7 +735,7 @@ type GlobalConfig struct {
 	// if it has not been updated.
 	ResolveTimeout model.Duration `yaml:"resolve_timeout" json:"resolve_timeout"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	SMTPFrom         string `yaml:"smtp_from,omitempty" json:"smtp_from,omitempty"`
 	SMTPHello        string `yaml:"smtp_hello,omitempty" json:"smtp_hello,omitempty"`
@@ -379,6 +756,15 @@ type GlobalConfig struct {
 	WeChatAPICorpID  string `yaml:"wechat_api_corp_id,omitempty" json:"wechat_api_corp_id,omitempty"`
 	VictorOpsAPIURL  string `yaml:"victorops_api_url,omitempty" json:"victorops_api_url,omitempty"`
 	VictorOpsAPIKey  Secret `yaml:"victorops_api_key,omitempty" json:"victorops_api_key,omitempty"`
+	TwilioAPIURL     string `yaml:"twilio_api_url,omitempty" json:"twilio_api_url,omitempty"`
+	TwilioAccountSID string `yaml:"twilio_account_sid,omitempty" json:"twilio_account_sid,omitempty"`
+	TwilioAuthToken  Secret `yaml:"twilio_auth_token,omitempty" json:"twilio_auth_token,omitempty"`
+
+	// TenantLabel is the label naming the tenant of an alert.
+	TenantLabel model.LabelName `yaml:"tenant_label,omitempty" json:"tenant_label,omitempty"`
+	// TenantHeader is the HTTP header naming the tenant of API requests,
+	// such as the X-Scope-OrgID header set by multi-tenant proxies.
+	TenantHeader string `yaml:"tenant_header,omitempty" json:"tenant_header,omitempty"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -390,17 +776,93 @@ func (c *GlobalConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // A Route is a node that contains definitions of how to handle alerts.
 type Route struct {
-	Receiver string            `yaml:"receiver,omitempty" json:"receiver,omitempty"`
-	GroupBy  []model.LabelName `yaml:"group_by,omitempty" json:"group_by,omitempty"`
+	// Receiver is the name of the receiver or a template of the name
+	// executed with the group and common labels of the notified alerts.
+	// ReceiverTemplate holds the parsed template and DefaultReceiver is
+	// notified if the executed template is not the name of a receiver.
+	Receiver         string             `yaml:"receiver,omitempty" json:"receiver,omitempty"`
+	ReceiverTemplate *template.Template `yaml:"-" json:"-"`
+	DefaultReceiver  string             `yaml:"default_receiver,omitempty" json:"default_receiver,omitempty"`
+	// FallbackReceiver is notified instead of the receiver if its
+	// notifications fail after all retries. The failover is recorded in the
+	// notification log with the "failover" integration of the fallback
+	// receiver.
+	FallbackReceiver string `yaml:"fallback_receiver,omitempty" json:"fallback_receiver,omitempty"`
+
+	// GroupByStr holds the configured group_by labels. The special value
+	// '...' groups by all labels and sets GroupByAll, otherwise the labels
+	// are parsed into GroupBy. Alerts are additionally grouped by all
+	// labels whose name matches GroupByRegex.
+	GroupByStr   []string          `yaml:"group_by,omitempty" json:"group_by,omitempty"`
+	GroupBy      []model.LabelName `yaml:"-" json:"-"`
+	GroupByAll   bool              `yaml:"-" json:"-"`
+	GroupByRegex *Regexp           `yaml:"group_by_regex,omitempty" json:"group_by_regex,omitempty"`
 
-	Match    map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
-	MatchRE  map[string]Regexp `yaml:"match_re,omitempty" json:"match_re,omitempty"`
-	Continue bool              `yaml:"continue,omitempty" json:"continue,omitempty"`
-	Routes   []*Route          `yaml:"routes,omitempty" json:"routes,omitempty"`
+	Match   map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
+	MatchRE map[string]Regexp `yaml:"match_re,omitempty" json:"match_re,omitempty"`
+	// Matchers are matchers in the syntax of amtool, which also supports
+	// matching on the absence of labels and numeric comparisons.
+	Matchers Matchers `yaml:"matchers,omitempty" json:"matchers,omitempty"`
+	Continue bool     `yaml:"continue,omitempty" json:"continue,omitempty"`
+	Routes   []*Route `yaml:"routes,omitempty" json:"routes,omitempty"`
+	// MaxMatches stops the evaluation of the child routes after as many of
+	// them matched. Zero means no limit.
+	MaxMatches int `yaml:"max_matches,omitempty" json:"max_matches,omitempty"`
+	// Stop ends the evaluation of the routing tree if the route matches,
+	// regardless of the continue setting of the route and its parents.
+	Stop bool `yaml:"stop,omitempty" json:"stop,omitempty"`
 
 	GroupWait      *model.Duration `yaml:"group_wait,omitempty" json:"group_wait,omitempty"`
 	GroupInterval  *model.Duration `yaml:"group_interval,omitempty" json:"group_interval,omitempty"`
 	RepeatInterval *model.Duration `yaml:"repeat_interval,omitempty" json:"repeat_interval,omitempty"`
+
+	// TimerOverrides replace the timers of aggregation groups whose labels
+	// match. The first matching override applies. They are not inherited
+	// by child routes.
+	TimerOverrides []*TimerOverride `yaml:"timer_overrides,omitempty" json:"timer_overrides,omitempty"`
+
+	// Escalation lists receivers that are notified additionally about
+	// alerts that remain firing.
+	Escalation []*EscalationStep `yaml:"escalation,omitempty" json:"escalation,omitempty"`
+
+	// GroupLimits bound the number of aggregation groups of the route and
+	// the number of alerts per group.
+	GroupLimits *GroupLimits `yaml:"group_limits,omitempty" json:"group_limits,omitempty"`
+
+	// Split sends a share of the notifications of the route to other
+	// receivers than Receiver.
+	Split *ReceiverSplit `yaml:"split,omitempty" json:"split,omitempty"`
+
+	// MuteTimeIntervals are the names of the time intervals during which
+	// no notifications are sent for the route. ActiveTimeIntervals are the
+	// names of the time intervals outside of which no notifications are
+	// sent. Neither is inherited by child routes.
+	MuteTimeIntervals   []string `yaml:"mute_time_intervals,omitempty" json:"mute_time_intervals,omitempty"`
+	ActiveTimeIntervals []string `yaml:"active_time_intervals,omitempty" json:"active_time_intervals,omitempty"`
+}
+
+// TimeInterval is a named set of time intervals that routes can reference.
+// A time is contained if it is within one of the time intervals or within an
+// event of one of the calendars.
+type TimeInterval struct {
+	Name          string                         `yaml:"name" json:"name"`
+	TimeIntervals []timeinterval.TimeInterval    `yaml:"time_intervals,omitempty" json:"time_intervals,omitempty"`
+	Calendars     []*timeinterval.CalendarConfig `yaml:"calendars,omitempty" json:"calendars,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (ti *TimeInterval) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TimeInterval
+	if err := unmarshal((*plain)(ti)); err != nil {
+		return err
+	}
+	if ti.Name == "" {
+		return fmt.Errorf("missing name in time interval")
+	}
+	if len(ti.TimeIntervals) == 0 && len(ti.Calendars) == 0 {
+		return fmt.Errorf("missing time_intervals or calendars in time interval %q", ti.Name)
+	}
+	return nil
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -422,6 +884,24 @@ func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		}
 	}
 
+	for _, l := range r.GroupByStr {
+		if l == "..." {
+			r.GroupByAll = true
+			continue
+		}
+		ln := model.LabelName(l)
+		if !ln.IsValid() {
+			return fmt.Errorf("invalid label name %q in group_by list", l)
+		}
+		r.GroupBy = append(r.GroupBy, ln)
+	}
+	if r.GroupByAll && len(r.GroupByStr) > 1 {
+		return fmt.Errorf("cannot have wildcard group_by ('...') and other labels at the same time")
+	}
+	if r.GroupByAll && r.GroupByRegex != nil {
+		return fmt.Errorf("cannot have wildcard group_by ('...') and group_by_regex at the same time")
+	}
+
 	groupBy := map[model.LabelName]struct{}{}
 
 	for _, ln := range r.GroupBy {
@@ -431,6 +911,26 @@ func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		groupBy[ln] = struct{}{}
 	}
 
+	if r.MaxMatches < 0 {
+		return fmt.Errorf("max_matches must not be negative")
+	}
+
+	if strings.Contains(r.Receiver, "{{") {
+		tmpl, err := template.New("").
+			Option("missingkey=zero").
+			Funcs(template.FuncMap(amtemplate.DefaultFuncs)).
+			Parse(r.Receiver)
+		if err != nil {
+			return fmt.Errorf("invalid receiver template %q: %s", r.Receiver, err)
+		}
+		if r.DefaultReceiver == "" {
+			return fmt.Errorf("missing default_receiver for receiver template %q", r.Receiver)
+		}
+		r.ReceiverTemplate = tmpl
+	} else if r.DefaultReceiver != "" {
+		return fmt.Errorf("default_receiver requires a receiver template")
+	}
+
 	if r.GroupInterval != nil && time.Duration(*r.GroupInterval) == time.Duration(0) {
 		return fmt.Errorf("group_interval cannot be zero")
 	}
@@ -438,13 +938,171 @@ func (r *Route) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return fmt.Errorf("repeat_interval cannot be zero")
 	}
 
+	for i, e := range r.Escalation {
+		if i > 0 && e.After <= r.Escalation[i-1].After {
+			return fmt.Errorf("escalation steps must be ordered by increasing duration")
+		}
+	}
+
 	return nil
 }
 
-// InhibitRule defines an inhibition rule that mutes alerts that match the
-// target labels if an alert matching the source labels exists.
-// Both alerts have to have a set of labels being equal.
-type InhibitRule struct {
+// EscalationStep notifies a receiver about alerts of a group that have been
+// firing for longer than a duration.
+type EscalationStep struct {
+	After    model.Duration `yaml:"after" json:"after"`
+	Receiver string         `yaml:"receiver" json:"receiver"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (e *EscalationStep) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EscalationStep
+	if err := unmarshal((*plain)(e)); err != nil {
+		return err
+	}
+	if e.After <= 0 {
+		return fmt.Errorf("after in escalation step must be positive")
+	}
+	if e.Receiver == "" {
+		return fmt.Errorf("missing receiver in escalation step")
+	}
+	return nil
+}
+
+// TimerOverride replaces the timers of a route for aggregation groups whose
+// labels match all matchers.
+type TimerOverride struct {
+	Matchers       Matchers        `yaml:"matchers" json:"matchers"`
+	GroupWait      *model.Duration `yaml:"group_wait,omitempty" json:"group_wait,omitempty"`
+	GroupInterval  *model.Duration `yaml:"group_interval,omitempty" json:"group_interval,omitempty"`
+	RepeatInterval *model.Duration `yaml:"repeat_interval,omitempty" json:"repeat_interval,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (o *TimerOverride) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TimerOverride
+	if err := unmarshal((*plain)(o)); err != nil {
+		return err
+	}
+	if len(o.Matchers) == 0 {
+		return fmt.Errorf("missing matchers in timer override")
+	}
+	if o.GroupWait == nil && o.GroupInterval == nil && o.RepeatInterval == nil {
+		return fmt.Errorf("no timers set in timer override")
+	}
+	if o.GroupInterval != nil && *o.GroupInterval == 0 {
+		return fmt.Errorf("group_interval cannot be zero")
+	}
+	if o.RepeatInterval != nil && *o.RepeatInterval == 0 {
+		return fmt.Errorf("repeat_interval cannot be zero")
+	}
+	return nil
+}
+
+// Overflow policies of group limits.
+const (
+	// OverflowReject drops alerts exceeding the limits.
+	OverflowReject = "reject"
+	// OverflowMerge adds alerts exceeding the limits to a single catch-all
+	// group of the route.
+	OverflowMerge = "merge"
+)
+
+// GroupLimits limit the number of aggregation groups of a route and the
+// number of alerts per group. Zero values mean no limit.
+type GroupLimits struct {
+	MaxGroups         int    `yaml:"max_groups,omitempty" json:"max_groups,omitempty"`
+	MaxAlertsPerGroup int    `yaml:"max_alerts_per_group,omitempty" json:"max_alerts_per_group,omitempty"`
+	Overflow          string `yaml:"overflow,omitempty" json:"overflow,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (l *GroupLimits) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain GroupLimits
+	if err := unmarshal((*plain)(l)); err != nil {
+		return err
+	}
+	if l.MaxGroups < 0 || l.MaxAlertsPerGroup < 0 {
+		return fmt.Errorf("group limits must not be negative")
+	}
+	switch l.Overflow {
+	case "":
+		l.Overflow = OverflowReject
+	case OverflowReject, OverflowMerge:
+	default:
+		return fmt.Errorf("unknown overflow policy %q in group limits", l.Overflow)
+	}
+	return nil
+}
+
+// Methods of choosing the receiver of a split notification.
+const (
+	// SplitByGroupKey chooses the receiver by the hash of the group key,
+	// so that all notifications of a group go to the same receiver.
+	SplitByGroupKey = "group_key"
+	// SplitByRandom chooses the receiver of every notification at random.
+	SplitByRandom = "random"
+)
+
+// ReceiverSplit distributes the notifications of a route among receivers.
+// The receiver of the route gets the share not assigned to other receivers.
+type ReceiverSplit struct {
+	By        string              `yaml:"by,omitempty" json:"by,omitempty"`
+	Receivers []*WeightedReceiver `yaml:"receivers" json:"receivers"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (s *ReceiverSplit) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ReceiverSplit
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	switch s.By {
+	case "":
+		s.By = SplitByGroupKey
+	case SplitByGroupKey, SplitByRandom:
+	default:
+		return fmt.Errorf("unknown split method %q", s.By)
+	}
+	if len(s.Receivers) == 0 {
+		return fmt.Errorf("missing receivers in split")
+	}
+	var total int
+	for _, r := range s.Receivers {
+		total += r.Percent
+	}
+	if total > 100 {
+		return fmt.Errorf("percentages in split must not add up to more than 100")
+	}
+	return nil
+}
+
+// WeightedReceiver is a receiver getting a percentage of the notifications
+// of a route.
+type WeightedReceiver struct {
+	Receiver string `yaml:"receiver" json:"receiver"`
+	Percent  int    `yaml:"percent" json:"percent"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *WeightedReceiver) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain WeightedReceiver
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	if r.Receiver == "" {
+		return fmt.Errorf("missing receiver in split")
+	}
+	if r.Percent <= 0 || r.Percent > 100 {
+		return fmt.Errorf("percent of receiver %q in split must be between 1 and 100", r.Receiver)
+	}
+	return nil
+}
+
+// InhibitRule defines an inhibition rule that mutes alerts that match the
+// target labels if an alert matching the source labels exists.
+// Both alerts have to have a set of labels being equal.
+type InhibitRule struct {
 	// SourceMatch defines a set of labels that have to equal the given
 	// value for source alerts.
 	SourceMatch map[string]string `yaml:"source_match,omitempty" json:"source_match,omitempty"`
@@ -457,9 +1115,84 @@ type InhibitRule struct {
 	// TargetMatchRE defines pairs like TargetMatch but does regular expression
 	// matching.
 	TargetMatchRE map[string]Regexp `yaml:"target_match_re,omitempty" json:"target_match_re,omitempty"`
+	// SourceMatchers and TargetMatchers define matchers for source and
+	// target alerts in the syntax of amtool.
+	SourceMatchers Matchers `yaml:"source_matchers,omitempty" json:"source_matchers,omitempty"`
+	TargetMatchers Matchers `yaml:"target_matchers,omitempty" json:"target_matchers,omitempty"`
 	// A set of labels that must be equal between the source and target alert
 	// for them to be a match.
 	Equal model.LabelNames `yaml:"equal,omitempty" json:"equal,omitempty"`
+	// EqualMappings are pairs of labels with different names whose values
+	// must be equal between the source and target alert.
+	EqualMappings []*LabelMapping `yaml:"equal_mappings,omitempty" json:"equal_mappings,omitempty"`
+	// Delay is how long a source alert must have been firing before it
+	// inhibits target alerts.
+	Delay model.Duration `yaml:"delay,omitempty" json:"delay,omitempty"`
+}
+
+// DefaultSeverityLabel is the default label holding the severity of alerts.
+const DefaultSeverityLabel = "severity"
+
+// SeverityInhibition makes alerts inhibit alerts with the same identity
+// labels and a lower severity.
+type SeverityInhibition struct {
+	// Label is the label holding the severity of alerts.
+	Label model.LabelName `yaml:"label,omitempty" json:"label,omitempty"`
+	// Order lists the severities, the highest first.
+	Order []string `yaml:"order" json:"order"`
+	// Equal lists the labels identifying alerts of the same problem.
+	Equal model.LabelNames `yaml:"equal,omitempty" json:"equal,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (s *SeverityInhibition) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SeverityInhibition
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	if s.Label == "" {
+		s.Label = DefaultSeverityLabel
+	}
+	if len(s.Order) < 2 {
+		return fmt.Errorf("at least two severities must be ordered in severity_inhibition")
+	}
+	seen := map[string]struct{}{}
+	for _, sev := range s.Order {
+		if sev == "" {
+			return fmt.Errorf("empty severity in severity_inhibition")
+		}
+		if _, ok := seen[sev]; ok {
+			return fmt.Errorf("duplicate severity %q in severity_inhibition", sev)
+		}
+		seen[sev] = struct{}{}
+	}
+	return nil
+}
+
+// InhibitRules returns inhibition rules making alerts of every severity
+// inhibit the alerts of all lower severities.
+func (s *SeverityInhibition) InhibitRules() []*InhibitRule {
+	var rules []*InhibitRule
+	for i := 1; i < len(s.Order); i++ {
+		higher := make([]string, 0, i)
+		for _, sev := range s.Order[:i] {
+			higher = append(higher, regexp.QuoteMeta(sev))
+		}
+		rules = append(rules, &InhibitRule{
+			SourceMatchRE: map[string]Regexp{
+				string(s.Label): {regexp.MustCompile("^(?:" + strings.Join(higher, "|") + ")$")},
+			},
+			TargetMatch: map[string]string{string(s.Label): s.Order[i]},
+			Equal:       s.Equal,
+		})
+	}
+	return rules
+}
+
+// LabelMapping maps a label of source alerts to a label of target alerts.
+type LabelMapping struct {
+	Source model.LabelName `yaml:"source" json:"source"`
+	Target model.LabelName `yaml:"target" json:"target"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -493,6 +1226,236 @@ func (r *InhibitRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		}
 	}
 
+	for _, m := range r.EqualMappings {
+		if m == nil || m.Source == "" || m.Target == "" {
+			return fmt.Errorf("source and target must be set in equal mappings")
+		}
+	}
+
+	return nil
+}
+
+// DefaultSilenceExpiryConfig provides default values for silence expiry
+// notifications.
+var DefaultSilenceExpiryConfig = SilenceExpiryConfig{
+	Before: model.Duration(15 * time.Minute),
+}
+
+// SilenceExpiryConfig configures notifications about silences that are
+// about to expire.
+type SilenceExpiryConfig struct {
+	// Receiver is notified about expiring silences.
+	Receiver string `yaml:"receiver" json:"receiver"`
+	// Before is how long before their end silences are reported.
+	Before model.Duration `yaml:"before,omitempty" json:"before,omitempty"`
+	// NotifyCreator notifies the receiver named like the creator of a
+	// silence instead if it exists.
+	NotifyCreator bool `yaml:"notify_creator,omitempty" json:"notify_creator,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SilenceExpiryConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultSilenceExpiryConfig
+	type plain SilenceExpiryConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Receiver == "" {
+		return fmt.Errorf("missing receiver in silence expiry notification")
+	}
+	if c.Before <= 0 {
+		return fmt.Errorf("before must be positive in silence expiry notification")
+	}
+	return nil
+}
+
+// FloodProtectionConfig limits the number of notifications sent by all
+// receivers together. In a cluster, the limit applies to all peers together.
+type FloodProtectionConfig struct {
+	// Notifications is the number of notifications allowed per interval.
+	Notifications int            `yaml:"notifications" json:"notifications"`
+	Interval      model.Duration `yaml:"interval" json:"interval"`
+	// Burst is the number of notifications that may be sent at once.
+	// Defaults to Notifications.
+	Burst int `yaml:"burst,omitempty" json:"burst,omitempty"`
+	// Receiver is notified about the number of suppressed notifications
+	// once per interval. Its notifications are not limited.
+	Receiver string `yaml:"receiver,omitempty" json:"receiver,omitempty"`
+	// DashboardURL is linked in the notifications about suppressed
+	// notifications.
+	DashboardURL string `yaml:"dashboard_url,omitempty" json:"dashboard_url,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *FloodProtectionConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain FloodProtectionConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Notifications <= 0 {
+		return fmt.Errorf("notifications in flood_protection must be positive")
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval in flood_protection must be positive")
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("burst in flood_protection must not be negative")
+	}
+	if c.Burst == 0 {
+		c.Burst = c.Notifications
+	}
+	return nil
+}
+
+// SilencePolicy restricts the silences that can be created or updated
+// through the API.
+type SilencePolicy struct {
+	// MaxDuration is the maximum time from now or the start of a silence
+	// until its end.
+	MaxDuration model.Duration `yaml:"max_duration,omitempty" json:"max_duration,omitempty"`
+	// CommentPattern is a regular expression that must match a part of the
+	// comment, e.g. a ticket ID.
+	CommentPattern string `yaml:"comment_pattern,omitempty" json:"comment_pattern,omitempty"`
+	// BannedMatchers are sets of labels that are too broad to be silenced
+	// on their own. Silences whose equality matchers are all part of one
+	// of the sets are rejected.
+	BannedMatchers []map[string]string `yaml:"banned_matchers,omitempty" json:"banned_matchers,omitempty"`
+	// Ownership restricts the modification of silences with an owner to
+	// the owning team.
+	Ownership *SilenceOwnership `yaml:"ownership,omitempty" json:"ownership,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (p *SilencePolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SilencePolicy
+	if err := unmarshal((*plain)(p)); err != nil {
+		return err
+	}
+	if p.MaxDuration < 0 {
+		return fmt.Errorf("max_duration must not be negative in silence policy")
+	}
+	if _, err := regexp.Compile(p.CommentPattern); err != nil {
+		return fmt.Errorf("invalid comment_pattern in silence policy: %s", err)
+	}
+	for _, m := range p.BannedMatchers {
+		if len(m) == 0 {
+			return fmt.Errorf("empty banned_matchers entry in silence policy")
+		}
+		for k := range m {
+			if !model.LabelNameRE.MatchString(k) {
+				return fmt.Errorf("invalid label name %q", k)
+			}
+		}
+	}
+	return nil
+}
+
+// SilenceOwnership restricts modifying and expiring a silence with an owner
+// to the owning team and admin teams. The teams of requests authenticated
+// by Alertmanager are looked up by their identity. The team of other
+// requests is read from a header which must be set by an authenticating
+// proxy in front of Alertmanager.
+type SilenceOwnership struct {
+	// TeamHeader is the name of the request header holding the team of
+	// requests not authenticated by Alertmanager.
+	TeamHeader string `yaml:"team_header,omitempty" json:"team_header,omitempty"`
+	// Teams maps teams to the identities authenticated by Alertmanager
+	// that are members, like user:alice or oidc:alice@example.com.
+	Teams map[string][]string `yaml:"teams,omitempty" json:"teams,omitempty"`
+	// AdminTeams may modify and expire the silences of all teams.
+	AdminTeams []string `yaml:"admin_teams,omitempty" json:"admin_teams,omitempty"`
+	// RequireOwner rejects silences without an owner.
+	RequireOwner bool `yaml:"require_owner,omitempty" json:"require_owner,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (o *SilenceOwnership) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SilenceOwnership
+	if err := unmarshal((*plain)(o)); err != nil {
+		return err
+	}
+	if o.TeamHeader == "" && len(o.Teams) == 0 {
+		return fmt.Errorf("missing team_header or teams in silence ownership")
+	}
+	return nil
+}
+
+// TeamsOf returns the teams the identity authenticated by Alertmanager is a
+// member of.
+func (o *SilenceOwnership) TeamsOf(id string) []string {
+	var teams []string
+	for team, ids := range o.Teams {
+		for _, i := range ids {
+			if i == id {
+				teams = append(teams, team)
+				break
+			}
+		}
+	}
+	sort.Strings(teams)
+	return teams
+}
+
+// IsAdmin returns true if the team is one of the admin teams.
+func (o *SilenceOwnership) IsAdmin(team string) bool {
+	for _, t := range o.AdminTeams {
+		if t != "" && t == team {
+			return true
+		}
+	}
+	return false
+}
+
+// SilenceTemplate is a named silence which is created by filling in its
+// parameters. The values of its matchers and its comment are Go templates
+// executed with the parameters, e.g. {{ .HOST }}.
+type SilenceTemplate struct {
+	Name string `yaml:"name" json:"name"`
+	// Parameters are the names of the parameters that must be given.
+	Parameters []string `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	// Match and MatchRE define the matchers of the silence.
+	Match   map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
+	MatchRE map[string]string `yaml:"match_re,omitempty" json:"match_re,omitempty"`
+	// Duration is the default duration of the silence.
+	Duration model.Duration `yaml:"duration" json:"duration"`
+	Comment  string         `yaml:"comment,omitempty" json:"comment,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (t *SilenceTemplate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SilenceTemplate
+	if err := unmarshal((*plain)(t)); err != nil {
+		return err
+	}
+	if t.Name == "" {
+		return fmt.Errorf("missing name in silence template")
+	}
+	if len(t.Match) == 0 && len(t.MatchRE) == 0 {
+		return fmt.Errorf("silence template %q must have at least one matcher", t.Name)
+	}
+	if t.Duration <= 0 {
+		return fmt.Errorf("silence template %q must have a positive duration", t.Name)
+	}
+	for _, p := range t.Parameters {
+		if !model.LabelNameRE.MatchString(p) {
+			return fmt.Errorf("invalid parameter name %q in silence template %q", p, t.Name)
+		}
+	}
+
+	values := []string{t.Comment}
+	for _, m := range []map[string]string{t.Match, t.MatchRE} {
+		for k, v := range m {
+			if !model.LabelNameRE.MatchString(k) {
+				return fmt.Errorf("invalid label name %q", k)
+			}
+			values = append(values, v)
+		}
+	}
+	for _, v := range values {
+		if _, err := template.New("").Parse(v); err != nil {
+			return fmt.Errorf("invalid template in silence template %q: %s", t.Name, err)
+		}
+	}
 	return nil
 }
 
@@ -500,16 +1463,43 @@ func (r *InhibitRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type Receiver struct {
 	// A unique identifier for this receiver.
 	Name string `yaml:"name" json:"name"`
+	// Extends is the name of the receiver this receiver was expanded from.
+	Extends string `yaml:"extends,omitempty" json:"extends,omitempty"`
+
+	EmailConfigs      []*EmailConfig      `yaml:"email_configs,omitempty" json:"email_configs,omitempty"`
+	PagerdutyConfigs  []*PagerdutyConfig  `yaml:"pagerduty_configs,omitempty" json:"pagerduty_configs,omitempty"`
+	HipchatConfigs    []*HipchatConfig    `yaml:"hipchat_configs,omitempty" json:"hipchat_configs,omitempty"`
+	SlackConfigs      []*SlackConfig      `yaml:"slack_configs,omitempty" json:"slack_configs,omitempty"`
+	WebhookConfigs    []*WebhookConfig    `yaml:"webhook_configs,omitempty" json:"webhook_configs,omitempty"`
+	OpsGenieConfigs   []*OpsGenieConfig   `yaml:"opsgenie_configs,omitempty" json:"opsgenie_configs,omitempty"`
+	WechatConfigs     []*WechatConfig     `yaml:"wechat_configs,omitempty" json:"wechat_configs,omitempty"`
+	PushoverConfigs   []*PushoverConfig   `yaml:"pushover_configs,omitempty" json:"pushover_configs,omitempty"`
+	VictorOpsConfigs  []*VictorOpsConfig  `yaml:"victorops_configs,omitempty" json:"victorops_configs,omitempty"`
+	NATSConfigs       []*NATSConfig       `yaml:"nats_configs,omitempty" json:"nats_configs,omitempty"`
+	MQTTConfigs       []*MQTTConfig       `yaml:"mqtt_configs,omitempty" json:"mqtt_configs,omitempty"`
+	TwilioConfigs     []*TwilioConfig     `yaml:"twilio_configs,omitempty" json:"twilio_configs,omitempty"`
+	VoiceConfigs      []*VoiceConfig      `yaml:"voice_configs,omitempty" json:"voice_configs,omitempty"`
+	JiraConfigs       []*JiraConfig       `yaml:"jira_configs,omitempty" json:"jira_configs,omitempty"`
+	ServiceNowConfigs []*ServiceNowConfig `yaml:"servicenow_configs,omitempty" json:"servicenow_configs,omitempty"`
+	SplunkConfigs     []*SplunkConfig     `yaml:"splunk_configs,omitempty" json:"splunk_configs,omitempty"`
+	PubSubConfigs     []*PubSubConfig     `yaml:"pubsub_configs,omitempty" json:"pubsub_configs,omitempty"`
+	PluginConfigs     []*PluginConfig     `yaml:"plugin_configs,omitempty" json:"plugin_configs,omitempty"`
+	ExecConfigs       []*ExecConfig       `yaml:"exec_configs,omitempty" json:"exec_configs,omitempty"`
+	SyslogConfigs     []*SyslogConfig     `yaml:"syslog_configs,omitempty" json:"syslog_configs,omitempty"`
+	SNMPConfigs       []*SNMPConfig       `yaml:"snmp_configs,omitempty" json:"snmp_configs,omitempty"`
+
+	RateLimit *RateLimitConfig `yaml:"rate_limit,omitempty" json:"rate_limit,omitempty"`
+	Retry     *RetryConfig     `yaml:"retry,omitempty" json:"retry,omitempty"`
 
-	EmailConfigs     []*EmailConfig     `yaml:"email_configs,omitempty" json:"email_configs,omitempty"`
-	PagerdutyConfigs []*PagerdutyConfig `yaml:"pagerduty_configs,omitempty" json:"pagerduty_configs,omitempty"`
-	HipchatConfigs   []*HipchatConfig   `yaml:"hipchat_configs,omitempty" json:"hipchat_configs,omitempty"`
-	SlackConfigs     []*SlackConfig     `yaml:"slack_configs,omitempty" json:"slack_configs,omitempty"`
-	WebhookConfigs   []*WebhookConfig   `yaml:"webhook_configs,omitempty" json:"webhook_configs,omitempty"`
-	OpsGenieConfigs  []*OpsGenieConfig  `yaml:"opsgenie_configs,omitempty" json:"opsgenie_configs,omitempty"`
-	WechatConfigs    []*WechatConfig    `yaml:"wechat_configs,omitempty" json:"wechat_configs,omitempty"`
-	PushoverConfigs  []*PushoverConfig  `yaml:"pushover_configs,omitempty" json:"pushover_configs,omitempty"`
-	VictorOpsConfigs []*VictorOpsConfig `yaml:"victorops_configs,omitempty" json:"victorops_configs,omitempty"`
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker,omitempty" json:"circuit_breaker,omitempty"`
+	Digest         *DigestConfig         `yaml:"digest,omitempty" json:"digest,omitempty"`
+	Enrichment     *EnrichmentConfig     `yaml:"enrichment,omitempty" json:"enrichment,omitempty"`
+
+	// NotificationLogRetention overrides how long the notifications of the
+	// receiver are kept in the notification log. Entries expiring before
+	// the repeat interval of a group cause notifications to be repeated
+	// early.
+	NotificationLogRetention model.Duration `yaml:"notification_log_retention,omitempty" json:"notification_log_retention,omitempty"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -524,6 +1514,236 @@ func (c *Receiver) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// RateLimitConfig limits the number of notifications sent by a receiver.
+type RateLimitConfig struct {
+	// Notifications is the number of notifications allowed per interval.
+	// Every integration of the receiver counts separately.
+	Notifications int            `yaml:"notifications" json:"notifications"`
+	Interval      model.Duration `yaml:"interval" json:"interval"`
+	// Burst is the number of notifications that may be sent at once.
+	// Defaults to Notifications.
+	Burst int `yaml:"burst,omitempty" json:"burst,omitempty"`
+	// Overflow defines what happens to notifications exceeding the limit:
+	// "drop" discards them, "queue" delays them until the limit allows or
+	// the notification times out, "summarize" discards them but attaches
+	// their alerts to the next notification of the same group that is sent.
+	Overflow string `yaml:"overflow,omitempty" json:"overflow,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *RateLimitConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RateLimitConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Notifications <= 0 {
+		return fmt.Errorf("notifications in rate_limit must be positive")
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval in rate_limit must be positive")
+	}
+	if c.Burst < 0 {
+		return fmt.Errorf("burst in rate_limit must not be negative")
+	}
+	if c.Burst == 0 {
+		c.Burst = c.Notifications
+	}
+	switch c.Overflow {
+	case "":
+		c.Overflow = "drop"
+	case "drop", "queue", "summarize":
+	default:
+		return fmt.Errorf("unknown overflow policy %q in rate_limit", c.Overflow)
+	}
+	return nil
+}
+
+// RetryConfig configures how failed notifications of a receiver are retried.
+// Unset fields keep the default exponential backoff.
+type RetryConfig struct {
+	// MaxAttempts limits the number of attempts per notification. Zero
+	// means no limit.
+	MaxAttempts    int            `yaml:"max_attempts,omitempty" json:"max_attempts,omitempty"`
+	InitialBackoff model.Duration `yaml:"initial_backoff,omitempty" json:"initial_backoff,omitempty"`
+	MaxBackoff     model.Duration `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`
+	// Budget is the total time spent retrying a notification.
+	Budget model.Duration `yaml:"budget,omitempty" json:"budget,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *RetryConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RetryConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts in retry must not be negative")
+	}
+	if c.InitialBackoff < 0 || c.MaxBackoff < 0 || c.Budget < 0 {
+		return fmt.Errorf("durations in retry must not be negative")
+	}
+	if c.MaxBackoff != 0 && c.InitialBackoff > c.MaxBackoff {
+		return fmt.Errorf("initial_backoff in retry must not be greater than max_backoff")
+	}
+	return nil
+}
+
+// CircuitBreakerConfig configures the circuit breaker of a receiver. The
+// breaker opens after a number of consecutive failed notifications and stops
+// notifying the receiver until the open duration has passed.
+type CircuitBreakerConfig struct {
+	FailureThreshold int            `yaml:"failure_threshold" json:"failure_threshold"`
+	OpenDuration     model.Duration `yaml:"open_duration,omitempty" json:"open_duration,omitempty"`
+	// FallbackReceiver is notified instead while the breaker is open.
+	FallbackReceiver string `yaml:"fallback_receiver,omitempty" json:"fallback_receiver,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *CircuitBreakerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultCircuitBreakerConfig
+	type plain CircuitBreakerConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.FailureThreshold <= 0 {
+		return fmt.Errorf("failure_threshold in circuit_breaker must be positive")
+	}
+	if c.OpenDuration <= 0 {
+		return fmt.Errorf("open_duration in circuit_breaker must be positive")
+	}
+	return nil
+}
+
+// DigestConfig configures a receiver to accumulate the notifications of all
+// its groups and send them as a single notification. In a cluster the
+// digests are sent by the first peer, or by the owners of the groups in
+// sharded mode.
+type DigestConfig struct {
+	// Interval is the time notifications are accumulated for.
+	Interval model.Duration `yaml:"interval" json:"interval"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *DigestConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain DigestConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval in digest must be positive")
+	}
+	return nil
+}
+
+// DefaultEnrichmentConfig defines default values for enrichment configurations.
+var DefaultEnrichmentConfig = EnrichmentConfig{
+	Timeout:  model.Duration(5 * time.Second),
+	CacheTTL: model.Duration(5 * time.Minute),
+}
+
+// EnrichmentConfig configures an HTTP endpoint that is queried for
+// additional annotations of the alerts before notifications are rendered.
+type EnrichmentConfig struct {
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+
+	URL     string         `yaml:"url" json:"url"`
+	Timeout model.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// CacheTTL is the time the annotations of an alert are cached for.
+	CacheTTL model.Duration `yaml:"cache_ttl,omitempty" json:"cache_ttl,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *EnrichmentConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultEnrichmentConfig
+	type plain EnrichmentConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing URL in enrichment")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("scheme required for enrichment url")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout in enrichment must be positive")
+	}
+	if c.CacheTTL < 0 {
+		return fmt.Errorf("cache_ttl in enrichment must not be negative")
+	}
+	return nil
+}
+
+// Matchers is a list of matchers like severity>=3 or absent(team) that is
+// marshaled as a list of strings. Negative matchers are not supported.
+type Matchers types.Matchers
+
+func parseMatchers(ss []string) (Matchers, error) {
+	ms := make(Matchers, 0, len(ss))
+	for _, s := range ss {
+		lm, err := parse.Matcher(s)
+		if err != nil {
+			return nil, err
+		}
+		m, err := types.NewMatcherFromLabels(lm)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matcher %q: %s", s, err)
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+func (ms Matchers) strings() []string {
+	ss := make([]string, 0, len(ms))
+	for _, m := range ms {
+		ss = append(ss, m.String())
+	}
+	return ss
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (ms *Matchers) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var ss []string
+	if err := unmarshal(&ss); err != nil {
+		return err
+	}
+	res, err := parseMatchers(ss)
+	if err != nil {
+		return err
+	}
+	*ms = res
+	return nil
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (ms Matchers) MarshalYAML() (interface{}, error) {
+	return ms.strings(), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (ms *Matchers) UnmarshalJSON(data []byte) error {
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return err
+	}
+	res, err := parseMatchers(ss)
+	if err != nil {
+		return err
+	}
+	*ms = res
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (ms Matchers) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ms.strings())
+}
+
 // Regexp encapsulates a regexp.Regexp and makes it YAML marshalable.
 type Regexp struct {
 	*regexp.Regexp
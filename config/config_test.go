@@ -21,10 +21,12 @@ import (
 	"testing"
 	"time"
 
-	commoncfg "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/timeinterval"
+	"github.com/prometheus/alertmanager/types"
 )
 
 func TestLoadEmptyString(t *testing.T) {
@@ -122,6 +124,169 @@ receivers:
 
 }
 
+func TestTimeIntervals(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  routes:
+  - match:
+      severity: warning
+    mute_time_intervals: [offhours]
+  - match:
+      severity: critical
+    active_time_intervals: [oncall]
+receivers:
+- name: team-X
+time_intervals:
+- name: oncall
+  time_intervals:
+  - weekdays: ['monday:friday']
+mute_time_intervals:
+- name: offhours
+  calendars:
+  - url: https://example.com/holidays.ics
+    location: Europe/Berlin
+  time_intervals:
+  - weekdays: ['saturday:sunday']
+  - times:
+    - start_time: "00:00"
+      end_time: "09:00"
+    location: Europe/Berlin
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("\nerror returned when none expected, error:\n%v", err)
+	}
+	if len(conf.MuteTimeIntervals) != 1 || len(conf.MuteTimeIntervals[0].TimeIntervals) != 2 {
+		t.Fatalf("\nunexpected mute time intervals:\n%v", conf.MuteTimeIntervals)
+	}
+	if len(conf.TimeIntervals) != 1 || conf.TimeIntervals[0].Name != "oncall" {
+		t.Fatalf("\nunexpected time intervals:\n%v", conf.TimeIntervals)
+	}
+	if cals := conf.MuteTimeIntervals[0].Calendars; len(cals) != 1 || cals[0].RefreshInterval != timeinterval.DefaultCalendarRefreshInterval {
+		t.Fatalf("\nunexpected calendars:\n%v", cals)
+	}
+
+	for _, tc := range []struct {
+		in       string
+		expected string
+	}{
+		{
+			in: `
+route:
+  receiver: team-X
+  routes:
+  - mute_time_intervals: [offhours]
+receivers:
+- name: team-X
+`,
+			expected: `undefined mute time interval "offhours" used in route`,
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  routes:
+  - active_time_intervals: [oncall]
+receivers:
+- name: team-X
+mute_time_intervals:
+- name: offhours
+  time_intervals: [{weekdays: [sunday]}]
+`,
+			expected: `undefined active time interval "oncall" used in route`,
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  active_time_intervals: [oncall]
+receivers:
+- name: team-X
+time_intervals:
+- name: oncall
+  time_intervals: [{weekdays: [sunday]}]
+`,
+			expected: "root route must not have any active time intervals",
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+time_intervals:
+- name: offhours
+  time_intervals: [{weekdays: [sunday]}]
+mute_time_intervals:
+- name: offhours
+  time_intervals: [{weekdays: [saturday]}]
+`,
+			expected: `time interval "offhours" is not unique`,
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  mute_time_intervals: [offhours]
+receivers:
+- name: team-X
+mute_time_intervals:
+- name: offhours
+  time_intervals: [{weekdays: [sunday]}]
+`,
+			expected: "root route must not have any mute time intervals",
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+mute_time_intervals:
+- name: offhours
+  time_intervals: [{weekdays: [sunday]}]
+- name: offhours
+  time_intervals: [{weekdays: [saturday]}]
+`,
+			expected: `time interval "offhours" is not unique`,
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+mute_time_intervals:
+- name: offhours
+`,
+			expected: `missing time_intervals or calendars in time interval "offhours"`,
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+time_intervals:
+- name: holidays
+  calendars:
+  - url: https://example.com/holidays.ics
+    file: /etc/alertmanager/holidays.ics
+`,
+			expected: "exactly one of url and file must be set in a calendar",
+		},
+	} {
+		_, err := Load(tc.in)
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%q", tc.expected)
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("\nexpected:\n%q\ngot:\n%q", tc.expected, err.Error())
+		}
+	}
+}
+
 func TestGroupByHasNoDuplicatedLabels(t *testing.T) {
 	in := `
 route:
@@ -143,6 +308,135 @@ receivers:
 
 }
 
+func TestGroupByAll(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  group_by: ['...']
+  routes:
+  - group_by: [cluster]
+    group_by_regex: 'team_.+'
+receivers:
+- name: team-X
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("\nerror returned when none expected, error:\n%v", err)
+	}
+	if !conf.Route.GroupByAll || len(conf.Route.GroupBy) != 0 {
+		t.Fatalf("\nexpected root route to group by all labels, got:\n%v", conf.Route.GroupBy)
+	}
+	r := conf.Route.Routes[0]
+	if r.GroupByAll || !reflect.DeepEqual(r.GroupBy, []model.LabelName{"cluster"}) {
+		t.Fatalf("\nunexpected group_by:\n%v", r.GroupBy)
+	}
+	if !r.GroupByRegex.MatchString("team_owner") || r.GroupByRegex.MatchString("owner_team_x") {
+		t.Fatalf("\nunexpected group_by_regex:\n%v", r.GroupByRegex)
+	}
+
+	for _, tc := range []struct {
+		in       string
+		expected string
+	}{
+		{
+			in: `
+route:
+  receiver: team-X
+  group_by: ['...', cluster]
+receivers:
+- name: team-X
+`,
+			expected: "cannot have wildcard group_by ('...') and other labels at the same time",
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  group_by: ['...']
+  group_by_regex: 'team_.+'
+receivers:
+- name: team-X
+`,
+			expected: "cannot have wildcard group_by ('...') and group_by_regex at the same time",
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  group_by: ['not-a-label']
+receivers:
+- name: team-X
+`,
+			expected: `invalid label name "not-a-label" in group_by list`,
+		},
+	} {
+		_, err := Load(tc.in)
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%q", tc.expected)
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("\nexpected:\n%q\ngot:\n%q", tc.expected, err.Error())
+		}
+	}
+}
+
+func TestGroupLimits(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  group_limits:
+    max_groups: 100
+    max_alerts_per_group: 1000
+receivers:
+- name: team-X
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("\nerror returned when none expected, error:\n%v", err)
+	}
+	expected := &GroupLimits{MaxGroups: 100, MaxAlertsPerGroup: 1000, Overflow: OverflowReject}
+	if !reflect.DeepEqual(conf.Route.GroupLimits, expected) {
+		t.Fatalf("\nexpected:\n%v\ngot:\n%v", expected, conf.Route.GroupLimits)
+	}
+
+	for _, tc := range []struct {
+		in       string
+		expected string
+	}{
+		{
+			in: `
+route:
+  receiver: team-X
+  group_limits:
+    max_groups: -1
+receivers:
+- name: team-X
+`,
+			expected: "group limits must not be negative",
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  group_limits:
+    max_groups: 10
+    overflow: drop
+receivers:
+- name: team-X
+`,
+			expected: `unknown overflow policy "drop" in group limits`,
+		},
+	} {
+		_, err := Load(tc.in)
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%q", tc.expected)
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("\nexpected:\n%q\ngot:\n%q", tc.expected, err.Error())
+		}
+	}
+}
+
 func TestRootRouteExists(t *testing.T) {
 	in := `
 receivers:
@@ -318,7 +612,7 @@ func TestEmptyFieldsAndRegex(t *testing.T) {
 	var expectedConf = Config{
 
 		Global: &GlobalConfig{
-			HTTPConfig:       &commoncfg.HTTPClientConfig{},
+			HTTPConfig:       &HTTPClientConfig{},
 			ResolveTimeout:   model.Duration(5 * time.Minute),
 			SMTPSmarthost:    "localhost:25",
 			SMTPFrom:         "alertmanager@example.org",
@@ -330,6 +624,9 @@ func TestEmptyFieldsAndRegex(t *testing.T) {
 			OpsGenieAPIURL:   "https://api.opsgenie.com/",
 			WeChatAPIURL:     "https://qyapi.weixin.qq.com/cgi-bin/",
 			VictorOpsAPIURL:  "https://alert.victorops.com/integrations/generic/20131114/alert/",
+			TwilioAPIURL:     "https://api.twilio.com/2010-04-01/",
+			TenantLabel:      "tenant",
+			TenantHeader:     "X-Alertmanager-Tenant",
 		},
 
 		Templates: []string{
@@ -337,6 +634,11 @@ func TestEmptyFieldsAndRegex(t *testing.T) {
 		},
 		Route: &Route{
 			Receiver: "team-X-mails",
+			GroupByStr: []string{
+				"alertname",
+				"cluster",
+				"service",
+			},
 			GroupBy: []model.LabelName{
 				"alertname",
 				"cluster",
@@ -449,3 +751,782 @@ func TestOpsGenieNoAPIKey(t *testing.T) {
 		t.Errorf("Expected: %s\nGot: %s", "no global OpsGenie API Key set", err.Error())
 	}
 }
+
+func TestReceiverNotificationLogRetention(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+  notification_log_retention: 6h
+- name: team-Y
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("\nerror returned when none expected, error:\n%v", err)
+	}
+
+	if r := conf.Receivers[0].NotificationLogRetention; time.Duration(r) != 6*time.Hour {
+		t.Errorf("\nexpected retention:\n%v\ngot:\n%v", 6*time.Hour, r)
+	}
+	if r := conf.Receivers[1].NotificationLogRetention; r != 0 {
+		t.Errorf("\nexpected no retention override, got:\n%v", r)
+	}
+}
+
+func TestRateLimitDefaults(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+  rate_limit:
+    notifications: 10
+    interval: 1h
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("\nerror returned when none expected, error:\n%v", err)
+	}
+
+	rl := conf.Receivers[0].RateLimit
+	if rl.Burst != 10 {
+		t.Errorf("\nexpected burst:\n%v\ngot:\n%v", 10, rl.Burst)
+	}
+	if rl.Overflow != "drop" {
+		t.Errorf("\nexpected overflow:\n%v\ngot:\n%v", "drop", rl.Overflow)
+	}
+}
+
+func TestRateLimitUnknownOverflow(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+  rate_limit:
+    notifications: 10
+    interval: 1h
+    overflow: page
+`
+	_, err := Load(in)
+
+	expected := `unknown overflow policy "page" in rate_limit`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestCircuitBreakerUndefinedFallback(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+  circuit_breaker:
+    failure_threshold: 3
+    fallback_receiver: team-Y
+`
+	_, err := Load(in)
+
+	expected := `undefined fallback receiver "team-Y" used in receiver "team-X"`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestRouteUndefinedFallbackReceiver(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  routes:
+  - match:
+      team: Y
+    receiver: team-X
+    fallback_receiver: team-Y
+receivers:
+- name: team-X
+`
+	_, err := Load(in)
+
+	expected := `undefined fallback receiver "team-Y" used in route`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestRouteSplit(t *testing.T) {
+	for in, expected := range map[string]string{
+		`
+route:
+  receiver: team-X
+  split:
+    receivers:
+    - receiver: team-Y
+      percent: 5
+receivers:
+- name: team-X
+`: `undefined receiver "team-Y" used in route split`,
+		`
+route:
+  receiver: team-X
+  split:
+    by: alertname
+    receivers:
+    - receiver: team-X
+      percent: 5
+receivers:
+- name: team-X
+`: `unknown split method "alertname"`,
+		`
+route:
+  receiver: team-X
+  split:
+    receivers: []
+receivers:
+- name: team-X
+`: "missing receivers in split",
+		`
+route:
+  receiver: team-X
+  split:
+    receivers:
+    - receiver: team-X
+      percent: 0
+receivers:
+- name: team-X
+`: `percent of receiver "team-X" in split must be between 1 and 100`,
+		`
+route:
+  receiver: team-X
+  split:
+    receivers:
+    - receiver: team-X
+      percent: 60
+    - receiver: team-X
+      percent: 60
+receivers:
+- name: team-X
+`: "percentages in split must not add up to more than 100",
+	} {
+		_, err := Load(in)
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%v", expected)
+		}
+		if err.Error() != expected {
+			t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+		}
+	}
+}
+
+func TestInhibitRuleEqualMappings(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+inhibit_rules:
+- source_match:
+    alertname: ClusterDown
+  target_match:
+    severity: warning
+  equal_mappings:
+  - source: cluster
+`
+	_, err := Load(in)
+
+	expected := "source and target must be set in equal mappings"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSeverityInhibition(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+inhibit_rules:
+- source_match:
+    alertname: ClusterDown
+  target_match:
+    severity: warning
+severity_inhibition:
+  order: [critical, warning, info]
+  equal: [alertname, cluster]
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", in, err)
+	}
+	if conf.SeverityInhibition.Label != DefaultSeverityLabel {
+		t.Errorf("expected default label %q, got %q", DefaultSeverityLabel, conf.SeverityInhibition.Label)
+	}
+
+	rules := conf.AllInhibitRules()
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 inhibit rules, got %d", len(rules))
+	}
+	if rules[0] != conf.InhibitRules[0] {
+		t.Errorf("expected the configured rule first")
+	}
+	for i, tc := range []struct {
+		target  string
+		sources []string
+	}{
+		{"warning", []string{"critical"}},
+		{"info", []string{"critical", "warning"}},
+	} {
+		r := rules[i+1]
+		if !reflect.DeepEqual(r.TargetMatch, map[string]string{"severity": tc.target}) {
+			t.Errorf("unexpected target match %v", r.TargetMatch)
+		}
+		if !reflect.DeepEqual(r.Equal, model.LabelNames{"alertname", "cluster"}) {
+			t.Errorf("unexpected equal labels %v", r.Equal)
+		}
+		re := r.SourceMatchRE["severity"]
+		for _, sev := range []string{"critical", "warning", "info"} {
+			expected := false
+			for _, src := range tc.sources {
+				expected = expected || src == sev
+			}
+			if re.MatchString(sev) != expected {
+				t.Errorf("expected severity %q matching %t for target %q", sev, expected, tc.target)
+			}
+		}
+	}
+
+	for in, expected := range map[string]string{
+		`
+severity_inhibition:
+  order: [critical]
+`: "at least two severities must be ordered in severity_inhibition",
+		`
+severity_inhibition:
+  order: [critical, warning, critical]
+`: `duplicate severity "critical" in severity_inhibition`,
+	} {
+		_, err := Load(in)
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%v", expected)
+		}
+		if err.Error() != expected {
+			t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+		}
+	}
+}
+
+func TestRouteMaxMatchesIsNotNegative(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  max_matches: -1
+receivers:
+- name: team-X
+`
+	_, err := Load(in)
+
+	expected := "max_matches must not be negative"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestTimerOverrides(t *testing.T) {
+	for in, expected := range map[string]string{
+		`
+route:
+  receiver: team-X
+  timer_overrides:
+  - repeat_interval: 1h
+receivers:
+- name: team-X
+`: "missing matchers in timer override",
+		`
+route:
+  receiver: team-X
+  timer_overrides:
+  - matchers: ['severity="critical"']
+receivers:
+- name: team-X
+`: "no timers set in timer override",
+		`
+route:
+  receiver: team-X
+  timer_overrides:
+  - matchers: ['severity="critical"']
+    repeat_interval: 0s
+receivers:
+- name: team-X
+`: "repeat_interval cannot be zero",
+	} {
+		_, err := Load(in)
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%v", expected)
+		}
+		if err.Error() != expected {
+			t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+		}
+	}
+}
+
+func TestReceiverTemplate(t *testing.T) {
+	for in, expected := range map[string]string{
+		`
+route:
+  receiver: 'team-{{ .CommonLabels.team }}'
+receivers:
+- name: team-X
+`: `missing default_receiver for receiver template "team-{{ .CommonLabels.team }}"`,
+		`
+route:
+  receiver: 'team-{{ .CommonLabels.team }'
+  default_receiver: team-X
+receivers:
+- name: team-X
+`: `invalid receiver template "team-{{ .CommonLabels.team }": template: :1: unexpected "}" in operand`,
+		`
+route:
+  receiver: 'team-{{ .CommonLabels.team }}'
+  default_receiver: team-Y
+receivers:
+- name: team-X
+`: `undefined default receiver "team-Y" used in route`,
+		`
+route:
+  receiver: team-X
+  default_receiver: team-X
+receivers:
+- name: team-X
+`: "default_receiver requires a receiver template",
+	} {
+		_, err := Load(in)
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%v", expected)
+		}
+		if err.Error() != expected {
+			t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+		}
+	}
+
+	c, err := Load(`
+route:
+  receiver: 'team-{{ .CommonLabels.team }}'
+  default_receiver: team-X
+receivers:
+- name: team-X
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Route.ReceiverTemplate == nil {
+		t.Fatal("expected receiver template to be parsed")
+	}
+}
+
+func TestDigestIntervalIsPositive(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+  digest:
+    interval: 0s
+`
+	_, err := Load(in)
+
+	expected := "interval in digest must be positive"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestEscalationUndefinedReceiver(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  escalation:
+  - after: 30m
+    receiver: team-Y
+receivers:
+- name: team-X
+`
+	_, err := Load(in)
+
+	expected := `undefined escalation receiver "team-Y" used in route`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestEscalationStepsOrdered(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  escalation:
+  - after: 1h
+    receiver: team-Y
+  - after: 30m
+    receiver: team-Z
+receivers:
+- name: team-X
+- name: team-Y
+- name: team-Z
+`
+	_, err := Load(in)
+
+	expected := "escalation steps must be ordered by increasing duration"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestEnrichmentTimeoutIsPositive(t *testing.T) {
+	in := `
+url: http://enricher:8080/lookup
+timeout: 0s
+`
+	var cfg EnrichmentConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "timeout in enrichment must be positive"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSilenceTemplateNameIsUnique(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+silence_templates:
+- name: node-maintenance
+  match:
+    instance: '{{ .HOST }}'
+  duration: 2h
+- name: node-maintenance
+  match:
+    job: node
+  duration: 1h
+`
+	_, err := Load(in)
+
+	expected := `silence template name "node-maintenance" is not unique`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSilenceTemplateHasMatchers(t *testing.T) {
+	in := `
+name: node-maintenance
+duration: 2h
+`
+	var st SilenceTemplate
+	err := yaml.UnmarshalStrict([]byte(in), &st)
+
+	expected := `silence template "node-maintenance" must have at least one matcher`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSilenceExpiryUndefinedReceiver(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+silence_expiry_notification:
+  receiver: team-Y
+`
+	_, err := Load(in)
+
+	expected := `undefined receiver "team-Y" used in silence expiry notification`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSilenceExpiryDefaults(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+silence_expiry_notification:
+  receiver: team-X
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", in, err)
+	}
+	if conf.SilenceExpiry.Before != DefaultSilenceExpiryConfig.Before {
+		t.Errorf("expected default before %v, got %v", DefaultSilenceExpiryConfig.Before, conf.SilenceExpiry.Before)
+	}
+}
+
+func TestFloodProtection(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+flood_protection:
+  notifications: 100
+  interval: 1h
+  receiver: team-X
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", in, err)
+	}
+	if conf.FloodProtection.Burst != 100 {
+		t.Errorf("expected default burst 100, got %d", conf.FloodProtection.Burst)
+	}
+
+	for in, expected := range map[string]string{
+		`
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+flood_protection:
+  notifications: 100
+  interval: 1h
+  receiver: team-Y
+`: `undefined receiver "team-Y" used in flood protection`,
+		`
+flood_protection:
+  interval: 1h
+`: "notifications in flood_protection must be positive",
+		`
+flood_protection:
+  notifications: 100
+`: "interval in flood_protection must be positive",
+		`
+flood_protection:
+  notifications: 100
+  interval: 1h
+  burst: -1
+`: "burst in flood_protection must not be negative",
+	} {
+		_, err := Load(in)
+		if err == nil {
+			t.Fatalf("no error returned, expected:\n%v", expected)
+		}
+		if err.Error() != expected {
+			t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+		}
+	}
+}
+
+func TestSilencePolicyCommentPatternIsValid(t *testing.T) {
+	in := `
+comment_pattern: '[A-Z+'
+`
+	var p SilencePolicy
+	err := yaml.UnmarshalStrict([]byte(in), &p)
+
+	expected := "invalid comment_pattern in silence policy: error parsing regexp: missing closing ]: `[A-Z+`"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSilenceOwnershipTeamHeaderIsRequired(t *testing.T) {
+	in := `
+ownership:
+  admin_teams: [sre]
+`
+	var p SilencePolicy
+	err := yaml.UnmarshalStrict([]byte(in), &p)
+
+	expected := "missing team_header or teams in silence ownership"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  routes:
+  - matchers: ['absent(owner)', 'priority<=2', 'job=~"api.*"']
+    receiver: team-X
+inhibit_rules:
+- source_matchers: ['alertname="Maintenance"']
+  target_matchers: ['present(service)']
+receivers:
+- name: team-X
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing %s: %s", in, err)
+	}
+
+	expected := `{absent(owner),priority<="2",job=~"api.*"}`
+	if s := types.Matchers(conf.Route.Routes[0].Matchers).String(); s != expected {
+		t.Errorf("expected route matchers %s, got %s", expected, s)
+	}
+	expected = `{present(service)}`
+	if s := types.Matchers(conf.InhibitRules[0].TargetMatchers).String(); s != expected {
+		t.Errorf("expected target matchers %s, got %s", expected, s)
+	}
+
+	// The configuration is marshaled with the original matchers.
+	reloaded, err := Load(conf.String())
+	if err != nil {
+		t.Fatalf("Error parsing marshaled configuration: %s", err)
+	}
+	expected = `{absent(owner),priority<="2",job=~"api.*"}`
+	if s := types.Matchers(reloaded.Route.Routes[0].Matchers).String(); s != expected {
+		t.Errorf("expected reloaded route matchers %s, got %s", expected, s)
+	}
+}
+
+func TestMatchersNotNegative(t *testing.T) {
+	in := `
+route:
+  receiver: team-X
+  routes:
+  - matchers: ['owner!="team-Y"']
+    receiver: team-X
+receivers:
+- name: team-X
+`
+	_, err := Load(in)
+
+	expected := `invalid matcher "owner!=\"team-Y\"": unsupported match type !=`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%q", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, err.Error())
+	}
+}
+
+func TestTenants(t *testing.T) {
+	in := `
+global:
+  slack_api_url: http://slack.example.com/hook
+route:
+  receiver: default
+receivers:
+- name: default
+tenants:
+- name: team-a
+  route:
+    receiver: default
+    routes:
+    - match:
+        severity: critical
+      receiver: pager
+  receivers:
+  - name: default
+    slack_configs:
+    - channel: '#team-a'
+  - name: pager
+`
+	conf, err := Load(in)
+	if err != nil {
+		t.Fatalf("Error parsing configuration: %s", err)
+	}
+	if len(conf.Tenants) != 1 || conf.Tenants[0].Name != "team-a" {
+		t.Fatalf("unexpected tenants %v", conf.Tenants)
+	}
+	if conf.Global.TenantLabel != "tenant" {
+		t.Errorf("expected default tenant label, got %q", conf.Global.TenantLabel)
+	}
+	// Global defaults apply to the receivers of tenants.
+	if u := conf.Tenants[0].Receivers[0].SlackConfigs[0].APIURL; u != "http://slack.example.com/hook" {
+		t.Errorf("expected global Slack API URL, got %q", u)
+	}
+
+	for _, tc := range []struct {
+		in, err string
+	}{
+		{
+			in:  strings.Replace(in, "  - name: pager\n", "", 1),
+			err: `tenant "team-a": undefined receiver "pager" used in route`,
+		},
+		{
+			in:  strings.Replace(in, "    receiver: default\n    routes:", "    receiver: default\n    match: {a: b}\n    routes:", 1),
+			err: `tenant "team-a": root route must not have any matchers`,
+		},
+		{
+			in:  in + "- name: team-a\n  route:\n    receiver: x\n  receivers:\n  - name: x\n",
+			err: `tenant "team-a" is not unique`,
+		},
+		{
+			in:  in + "- name: team-b\n",
+			err: `missing route in tenant "team-b"`,
+		},
+	} {
+		_, err := Load(tc.in)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("expected error %q, got %v", tc.err, err)
+		}
+	}
+}
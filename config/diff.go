@@ -0,0 +1,298 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Diff is the semantic difference between two configurations. Items are
+// identified by their names, routes by the matchers of their path and
+// inhibition rules by their content, so reordering items is no change.
+// Secrets are compared in their redacted form.
+type Diff struct {
+	Global        []FieldChange `json:"global,omitempty"`
+	Routes        ItemsDiff     `json:"routes"`
+	Receivers     ItemsDiff     `json:"receivers"`
+	InhibitRules  ItemsDiff     `json:"inhibitRules"`
+	TimeIntervals ItemsDiff     `json:"timeIntervals"`
+	Templates     ItemsDiff     `json:"templates"`
+}
+
+// Empty returns true if the configurations are semantically equal.
+func (d *Diff) Empty() bool {
+	return len(d.Global) == 0 && d.Routes.empty() && d.Receivers.empty() &&
+		d.InhibitRules.empty() && d.TimeIntervals.empty() && d.Templates.empty()
+}
+
+// ItemsDiff lists the added, removed and changed items of a kind.
+type ItemsDiff struct {
+	Added   []string     `json:"added,omitempty"`
+	Removed []string     `json:"removed,omitempty"`
+	Changed []ItemChange `json:"changed,omitempty"`
+}
+
+func (d ItemsDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// ItemChange lists the changed fields of an item.
+type ItemChange struct {
+	Name   string        `json:"name"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is the old and new value of a field. Values of unset fields
+// are null.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// DiffConfigs returns the semantic difference from the old to the new
+// configuration.
+func DiffConfigs(from, to *Config) (*Diff, error) {
+	var (
+		d   = &Diff{}
+		err error
+	)
+	if d.Global, err = diffFields(from.Global, to.Global); err != nil {
+		return nil, err
+	}
+
+	oldRoutes, newRoutes := map[string]interface{}{}, map[string]interface{}{}
+	flattenRoutes(from.Route, "", oldRoutes)
+	flattenRoutes(to.Route, "", newRoutes)
+	if d.Routes, err = diffItems(oldRoutes, newRoutes); err != nil {
+		return nil, err
+	}
+
+	oldRcvs, newRcvs := map[string]interface{}{}, map[string]interface{}{}
+	for _, r := range from.Receivers {
+		oldRcvs[r.Name] = r
+	}
+	for _, r := range to.Receivers {
+		newRcvs[r.Name] = r
+	}
+	if d.Receivers, err = diffItems(oldRcvs, newRcvs); err != nil {
+		return nil, err
+	}
+
+	oldRules, err := inhibitRuleSet(from.AllInhibitRules())
+	if err != nil {
+		return nil, err
+	}
+	newRules, err := inhibitRuleSet(to.AllInhibitRules())
+	if err != nil {
+		return nil, err
+	}
+	d.InhibitRules = diffSets(oldRules, newRules)
+
+	oldTIs, newTIs := map[string]interface{}{}, map[string]interface{}{}
+	for _, tis := range [][]*TimeInterval{from.TimeIntervals, from.MuteTimeIntervals} {
+		for _, ti := range tis {
+			oldTIs[ti.Name] = ti
+		}
+	}
+	for _, tis := range [][]*TimeInterval{to.TimeIntervals, to.MuteTimeIntervals} {
+		for _, ti := range tis {
+			newTIs[ti.Name] = ti
+		}
+	}
+	if d.TimeIntervals, err = diffItems(oldTIs, newTIs); err != nil {
+		return nil, err
+	}
+
+	oldTmpls, newTmpls := map[string]struct{}{}, map[string]struct{}{}
+	for _, t := range from.Templates {
+		oldTmpls[t] = struct{}{}
+	}
+	for _, t := range to.Templates {
+		newTmpls[t] = struct{}{}
+	}
+	d.Templates = diffSets(oldTmpls, newTmpls)
+
+	return d, nil
+}
+
+// flattenRoutes adds the routes of the tree by their path to the map. Child
+// routes are not part of their parent's fields.
+func flattenRoutes(r *Route, parent string, routes map[string]interface{}) {
+	if r == nil {
+		return
+	}
+	path := routeMatchers(r)
+	if parent != "" {
+		path = parent + "/" + path
+	}
+	// Siblings with equal matchers are told apart by their position.
+	name := path
+	for i := 2; routes[name] != nil; i++ {
+		name = fmt.Sprintf("%s#%d", path, i)
+	}
+	flat := *r
+	flat.Routes = nil
+	routes[name] = &flat
+
+	for _, cr := range r.Routes {
+		flattenRoutes(cr, name, routes)
+	}
+}
+
+// routeMatchers returns the sorted matchers of the route.
+func routeMatchers(r *Route) string {
+	var ms []string
+	for k, v := range r.Match {
+		ms = append(ms, fmt.Sprintf("%s=%q", k, v))
+	}
+	for k, v := range r.MatchRE {
+		ms = append(ms, fmt.Sprintf("%s=~%q", k, v.String()))
+	}
+	for _, m := range r.Matchers {
+		ms = append(ms, m.String())
+	}
+	sort.Strings(ms)
+	return "{" + strings.Join(ms, ",") + "}"
+}
+
+func inhibitRuleSet(rules []*InhibitRule) (map[string]struct{}, error) {
+	set := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		f, err := genericFields(r)
+		if err != nil {
+			return nil, err
+		}
+		// JSON sorts the keys of maps, which makes the content canonical.
+		b, err := json.Marshal(f)
+		if err != nil {
+			return nil, err
+		}
+		set[string(b)] = struct{}{}
+	}
+	return set, nil
+}
+
+func diffSets(from, to map[string]struct{}) ItemsDiff {
+	var d ItemsDiff
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+	for k := range from {
+		if _, ok := to[k]; !ok {
+			d.Removed = append(d.Removed, k)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	return d
+}
+
+func diffItems(from, to map[string]interface{}) (ItemsDiff, error) {
+	oldSet, newSet := map[string]struct{}{}, map[string]struct{}{}
+	for k := range from {
+		oldSet[k] = struct{}{}
+	}
+	for k := range to {
+		newSet[k] = struct{}{}
+	}
+	d := diffSets(oldSet, newSet)
+
+	for k, o := range from {
+		n, ok := to[k]
+		if !ok {
+			continue
+		}
+		fields, err := diffFields(o, n)
+		if err != nil {
+			return d, err
+		}
+		if len(fields) > 0 {
+			d.Changed = append(d.Changed, ItemChange{Name: k, Fields: fields})
+		}
+	}
+	sort.Slice(d.Changed, func(i, j int) bool { return d.Changed[i].Name < d.Changed[j].Name })
+	return d, nil
+}
+
+// diffFields returns the changed top-level fields of the YAML representation
+// of the values.
+func diffFields(from, to interface{}) ([]FieldChange, error) {
+	of, err := genericFields(from)
+	if err != nil {
+		return nil, err
+	}
+	nf, err := genericFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FieldChange
+	for k, ov := range of {
+		if nv := nf[k]; !reflect.DeepEqual(ov, nv) {
+			changes = append(changes, FieldChange{Field: k, Old: ov, New: nv})
+		}
+	}
+	for k, nv := range nf {
+		if _, ok := of[k]; !ok {
+			changes = append(changes, FieldChange{Field: k, New: nv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// genericFields returns the fields of the YAML representation of the value
+// as values that can be encoded as JSON.
+func genericFields(v interface{}) (map[string]interface{}, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for k, v := range m {
+		m[k] = jsonValue(v)
+	}
+	return m, nil
+}
+
+// jsonValue converts the maps of a generic YAML value to maps with string
+// keys.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, mv := range v {
+			m[fmt.Sprint(k)] = jsonValue(mv)
+		}
+		return m
+	case []interface{}:
+		for i, lv := range v {
+			v[i] = jsonValue(lv)
+		}
+		return v
+	}
+	return v
+}
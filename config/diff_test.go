@@ -0,0 +1,119 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const diffBase = `
+route:
+  receiver: default
+  group_wait: 30s
+  routes:
+  - receiver: db
+    match:
+      team: db
+  - receiver: web
+    match:
+      team: web
+receivers:
+- name: default
+- name: db
+- name: web
+inhibit_rules:
+- source_match:
+    severity: critical
+  target_match:
+    severity: warning
+  equal: [alertname]
+`
+
+func TestDiffConfigsReordered(t *testing.T) {
+	from, err := Load(diffBase)
+	require.NoError(t, err)
+	to, err := Load(`
+receivers:
+- name: web
+- name: db
+- name: default
+route:
+  group_wait: 30s
+  receiver: default
+  routes:
+  - receiver: web
+    match:
+      team: web
+  - receiver: db
+    match:
+      team: db
+inhibit_rules:
+- equal: [alertname]
+  target_match:
+    severity: warning
+  source_match:
+    severity: critical
+`)
+	require.NoError(t, err)
+
+	d, err := DiffConfigs(from, to)
+	require.NoError(t, err)
+	require.True(t, d.Empty(), "unexpected diff %+v", d)
+}
+
+func TestDiffConfigs(t *testing.T) {
+	from, err := Load(diffBase)
+	require.NoError(t, err)
+	to, err := Load(`
+global:
+  resolve_timeout: 10m
+route:
+  receiver: default
+  group_wait: 1m
+  routes:
+  - receiver: db
+    match:
+      team: db
+receivers:
+- name: default
+- name: db
+- name: ops
+`)
+	require.NoError(t, err)
+
+	d, err := DiffConfigs(from, to)
+	require.NoError(t, err)
+	require.False(t, d.Empty())
+
+	require.Len(t, d.Global, 1)
+	require.Equal(t, "resolve_timeout", d.Global[0].Field)
+	require.Equal(t, "5m", d.Global[0].Old)
+	require.Equal(t, "10m", d.Global[0].New)
+
+	require.Equal(t, []string{"ops"}, d.Receivers.Added)
+	require.Equal(t, []string{"web"}, d.Receivers.Removed)
+	require.Empty(t, d.Receivers.Changed)
+
+	require.Empty(t, d.Routes.Added)
+	require.Equal(t, []string{`{}/{team="web"}`}, d.Routes.Removed)
+	require.Equal(t, []ItemChange{{
+		Name:   "{}",
+		Fields: []FieldChange{{Field: "group_wait", Old: "30s", New: "1m"}},
+	}}, d.Routes.Changed)
+
+	require.Len(t, d.InhibitRules.Removed, 1)
+	require.Empty(t, d.InhibitRules.Added)
+}
@@ -0,0 +1,210 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// fragment is a configuration file of a configuration directory. Fragments
+// may only add to the lists of the main configuration file, routes are
+// appended to the child routes of the root route.
+type fragment struct {
+	Routes            []*Route           `yaml:"routes,omitempty"`
+	Receivers         []*Receiver        `yaml:"receivers,omitempty"`
+	InhibitRules      []*InhibitRule     `yaml:"inhibit_rules,omitempty"`
+	Templates         []string           `yaml:"templates,omitempty"`
+	TimeIntervals     []*TimeInterval    `yaml:"time_intervals,omitempty"`
+	MuteTimeIntervals []*TimeInterval    `yaml:"mute_time_intervals,omitempty"`
+	SilenceTemplates  []*SilenceTemplate `yaml:"silence_templates,omitempty"`
+}
+
+// fragmentLists maps the keys of fragments to the keys of the configuration
+// their items are appended to, in the order they are merged.
+var fragmentLists = []struct{ from, to string }{
+	{"receivers", "receivers"},
+	{"inhibit_rules", "inhibit_rules"},
+	{"templates", "templates"},
+	{"time_intervals", "time_intervals"},
+	{"mute_time_intervals", "mute_time_intervals"},
+	{"silence_templates", "silence_templates"},
+}
+
+// nameKind maps the keys of named items to the prefix of their names in
+// the definedBy map of mergeFragment.
+var nameKind = map[string]string{
+	"receivers":           "receiver/",
+	"time_intervals":      "time_interval/",
+	"mute_time_intervals": "time_interval/",
+}
+
+// LoadFiles parses the given YAML file merged with the fragments of the
+// directory into a Config. Fragments are all files of the directory ending
+// in .yml, .yaml, .json or .jsonnet, merged in lexical order. The returned content is the
+// merged YAML configuration. If dir is empty, LoadFiles is equivalent to
+// LoadFile.
+func LoadFiles(filename, dir string) (*Config, []byte, error) {
+	if dir == "" {
+		return LoadFile(filename)
+	}
+	content, err := readFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	var main yaml.MapSlice
+	if err := yaml.Unmarshal(content, &main); err != nil {
+		return nil, nil, err
+	}
+
+	files, err := fragmentFiles(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	definedBy := map[string]string{}
+	for _, key := range []string{"receivers", "time_intervals", "mute_time_intervals"} {
+		list, _ := mapValue(main, key).([]interface{})
+		for _, item := range list {
+			if m, ok := item.(yaml.MapSlice); ok {
+				name, _ := mapValue(m, "name").(string)
+				definedBy[nameKind[key]+name] = filename
+			}
+		}
+	}
+	for _, f := range files {
+		if err := mergeFragment(&main, f, definedBy); err != nil {
+			e := newError("", err)
+			for i := range e.Problems {
+				e.Problems[i].File = f
+			}
+			e.err = fmt.Errorf("fragment %s: %s", f, err)
+			return nil, nil, e
+		}
+	}
+
+	merged, err := yaml.Marshal(main)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := Load(string(merged))
+	if err != nil {
+		// Positions in the merged configuration are meaningless.
+		if e, ok := err.(*Error); ok {
+			for i := range e.Problems {
+				e.Problems[i].Line, e.Problems[i].Column = 0, 0
+			}
+		}
+		return nil, nil, err
+	}
+	resolveFilepaths(filepath.Dir(filename), cfg)
+	return cfg, merged, nil
+}
+
+func fragmentFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml", "*.json", "*.jsonnet"} {
+		m, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, m...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// mergeFragment validates the fragment file and appends its items to the
+// configuration. definedBy maps the names of receivers and time intervals
+// to the fragments defining them.
+func mergeFragment(main *yaml.MapSlice, filename string, definedBy map[string]string) error {
+	content, err := readFile(filename)
+	if err != nil {
+		return err
+	}
+	var frag fragment
+	if err := yaml.UnmarshalStrict(content, &frag); err != nil {
+		return newError(string(content), err)
+	}
+	for _, r := range frag.Receivers {
+		if f, ok := definedBy["receiver/"+r.Name]; ok {
+			return fmt.Errorf("receiver %q is already defined in %s", r.Name, f)
+		}
+		definedBy["receiver/"+r.Name] = filename
+	}
+	for _, ti := range append(frag.TimeIntervals, frag.MuteTimeIntervals...) {
+		if f, ok := definedBy["time_interval/"+ti.Name]; ok {
+			return fmt.Errorf("time interval %q is already defined in %s", ti.Name, f)
+		}
+		definedBy["time_interval/"+ti.Name] = filename
+	}
+
+	// The fragment is merged in its generic form as secrets are not
+	// marshaled.
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return err
+	}
+	if tmpls, ok := raw["templates"].([]interface{}); ok {
+		for i, t := range tmpls {
+			if s, ok := t.(string); ok && !filepath.IsAbs(s) {
+				tmpls[i] = filepath.Join(filepath.Dir(filename), s)
+			}
+		}
+	}
+	for _, l := range fragmentLists {
+		if items, ok := raw[l.from].([]interface{}); ok {
+			appendItems(main, l.to, items)
+		}
+	}
+	if routes, ok := raw["routes"].([]interface{}); ok {
+		item := mapItem(main, "route")
+		root, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			return fmt.Errorf("routes require a root route in the main configuration file")
+		}
+		appendItems(&root, "routes", routes)
+		item.Value = root
+	}
+	return nil
+}
+
+// mapValue returns the value of the key or nil.
+func mapValue(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// mapItem returns the item of the key, which is added if missing.
+func mapItem(m *yaml.MapSlice, key string) *yaml.MapItem {
+	for i := range *m {
+		if (*m)[i].Key == key {
+			return &(*m)[i]
+		}
+	}
+	*m = append(*m, yaml.MapItem{Key: key})
+	return &(*m)[len(*m)-1]
+}
+
+func appendItems(m *yaml.MapSlice, key string, items []interface{}) {
+	item := mapItem(m, key)
+	list, _ := item.Value.([]interface{})
+	item.Value = append(list, items...)
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fragments")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) string {
+		fn := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(fn, []byte(content), 0644))
+		return fn
+	}
+	main := write("alertmanager.yml", `
+global:
+  smtp_smarthost: localhost:25
+  smtp_from: alertmanager@example.com
+route:
+  receiver: default
+  routes:
+  - match:
+      team: ops
+    receiver: default
+receivers:
+- name: default
+templates:
+- default.tmpl
+`)
+	fragDir := filepath.Join(dir, "conf.d")
+	require.NoError(t, os.Mkdir(fragDir, 0755))
+	writeFrag := func(name, content string) {
+		write(filepath.Join("conf.d", name), content)
+	}
+	writeFrag("team-b.yaml", `
+routes:
+- match:
+    team: b
+  receiver: team-b
+receivers:
+- name: team-b
+  email_configs:
+  - to: team-b@example.com
+`)
+	writeFrag("team-a.yml", `
+routes:
+- match:
+    team: a
+  receiver: team-a
+receivers:
+- name: team-a
+templates:
+- team-a.tmpl
+time_intervals:
+- name: weekends
+  time_intervals: [{weekdays: [saturday, sunday]}]
+`)
+	writeFrag("README.md", "ignored")
+
+	cfg, content, err := LoadFiles(main, fragDir)
+	require.NoError(t, err)
+	require.Len(t, cfg.Route.Routes, 3)
+	require.Equal(t, "default", cfg.Route.Routes[0].Receiver)
+	require.Equal(t, "team-a", cfg.Route.Routes[1].Receiver)
+	require.Equal(t, "team-b", cfg.Route.Routes[2].Receiver)
+	require.Len(t, cfg.Receivers, 3)
+	require.Equal(t, "team-b@example.com", cfg.Receivers[2].EmailConfigs[0].To)
+	// Global defaults apply to the receivers of fragments.
+	require.Equal(t, "alertmanager@example.com", cfg.Receivers[2].EmailConfigs[0].From)
+	require.Equal(t, []string{filepath.Join(dir, "default.tmpl"), filepath.Join(fragDir, "team-a.tmpl")}, cfg.Templates)
+	require.Len(t, cfg.TimeIntervals, 1)
+
+	// The merged content is a valid configuration itself.
+	_, err = Load(string(content))
+	require.NoError(t, err)
+
+	// Without a directory, only the main file is loaded.
+	cfg, _, err = LoadFiles(main, "")
+	require.NoError(t, err)
+	require.Len(t, cfg.Receivers, 1)
+
+	writeFrag("team-c.yml", `
+receivers:
+- name: team-a
+`)
+	_, _, err = LoadFiles(main, fragDir)
+	require.EqualError(t, err, "fragment "+filepath.Join(fragDir, "team-c.yml")+`: receiver "team-a" is already defined in `+filepath.Join(fragDir, "team-a.yml"))
+
+	writeFrag("team-c.yml", `
+receivers:
+- name: default
+`)
+	_, _, err = LoadFiles(main, fragDir)
+	require.EqualError(t, err, "fragment "+filepath.Join(fragDir, "team-c.yml")+`: receiver "default" is already defined in `+main)
+
+	writeFrag("team-c.yml", `
+global:
+  resolve_timeout: 1m
+`)
+	_, _, err = LoadFiles(main, fragDir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "fragment "+filepath.Join(fragDir, "team-c.yml")+": ")
+
+	writeFrag("team-c.yml", `
+routes:
+- receiver: team-c
+`)
+	_, _, err = LoadFiles(main, fragDir)
+	require.EqualError(t, err, `undefined receiver "team-c" used in route`)
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	commoncfg "github.com/prometheus/common/config"
+)
+
+// HTTPClientConfig configures the HTTP client used by notifiers. It is a
+// superset of the common HTTP client configuration.
+type HTTPClientConfig struct {
+	// The HTTP basic authentication credentials for the targets.
+	BasicAuth *commoncfg.BasicAuth `yaml:"basic_auth,omitempty" json:"basic_auth,omitempty"`
+	// The bearer token for the targets.
+	BearerToken Secret `yaml:"bearer_token,omitempty" json:"bearer_token,omitempty"`
+	// The bearer token file for the targets.
+	BearerTokenFile string `yaml:"bearer_token_file,omitempty" json:"bearer_token_file,omitempty"`
+	// OAuth2 client credentials used to fetch bearer tokens.
+	OAuth2 *OAuth2 `yaml:"oauth2,omitempty" json:"oauth2,omitempty"`
+	// HTTP proxy server to use to connect to the targets.
+	ProxyURL commoncfg.URL `yaml:"proxy_url,omitempty" json:"proxy_url,omitempty"`
+	// TLSConfig to use to connect to the targets.
+	TLSConfig TLSConfig `yaml:"tls_config,omitempty" json:"tls_config,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *HTTPClientConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain HTTPClientConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate checks that at most one authentication method is configured.
+func (c *HTTPClientConfig) Validate() error {
+	if len(c.BearerToken) > 0 && len(c.BearerTokenFile) > 0 {
+		return fmt.Errorf("at most one of bearer_token & bearer_token_file must be configured")
+	}
+	n := 0
+	if c.BasicAuth != nil {
+		n++
+	}
+	if len(c.BearerToken) > 0 || len(c.BearerTokenFile) > 0 {
+		n++
+	}
+	if c.OAuth2 != nil {
+		n++
+	}
+	if n > 1 {
+		return fmt.Errorf("at most one of basic_auth, oauth2, bearer_token & bearer_token_file must be configured")
+	}
+	return nil
+}
+
+// OAuth2 configures fetching access tokens using the OAuth2 client
+// credentials grant.
+type OAuth2 struct {
+	ClientID         string            `yaml:"client_id" json:"client_id"`
+	ClientSecret     Secret            `yaml:"client_secret,omitempty" json:"client_secret,omitempty"`
+	ClientSecretFile string            `yaml:"client_secret_file,omitempty" json:"client_secret_file,omitempty"`
+	TokenURL         string            `yaml:"token_url" json:"token_url"`
+	Scopes           []string          `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	EndpointParams   map[string]string `yaml:"endpoint_params,omitempty" json:"endpoint_params,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *OAuth2) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain OAuth2
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.ClientID == "" {
+		return fmt.Errorf("missing client_id in oauth2 config")
+	}
+	if c.TokenURL == "" {
+		return fmt.Errorf("missing token_url in oauth2 config")
+	}
+	if _, err := url.Parse(c.TokenURL); err != nil {
+		return err
+	}
+	if len(c.ClientSecret) > 0 && len(c.ClientSecretFile) > 0 {
+		return fmt.Errorf("at most one of client_secret & client_secret_file must be configured")
+	}
+	return nil
+}
+
+// TLSConfig configures the TLS connections to the targets.
+type TLSConfig struct {
+	// The CA cert to use for the targets.
+	CAFile string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`
+	// The client cert file for the targets.
+	CertFile string `yaml:"cert_file,omitempty" json:"cert_file,omitempty"`
+	// The client key file for the targets.
+	KeyFile string `yaml:"key_file,omitempty" json:"key_file,omitempty"`
+	// Used to verify the hostname for the targets.
+	ServerName string `yaml:"server_name,omitempty" json:"server_name,omitempty"`
+	// Disable target certificate validation.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify" json:"insecure_skip_verify"`
+	// Minimum acceptable TLS version.
+	MinVersion TLSVersion `yaml:"min_version,omitempty" json:"min_version,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TLSConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TLSConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if len(c.CertFile) > 0 && len(c.KeyFile) == 0 {
+		return fmt.Errorf("client cert file %q specified without client key file", c.CertFile)
+	}
+	if len(c.KeyFile) > 0 && len(c.CertFile) == 0 {
+		return fmt.Errorf("client key file %q specified without client cert file", c.KeyFile)
+	}
+	return nil
+}
+
+// TLSVersion is a TLS protocol version.
+type TLSVersion uint16
+
+// TLSVersions maps the configuration names of TLS versions to their values.
+// TLS 1.3 is only available when built with Go 1.12 or later.
+var TLSVersions = map[string]TLSVersion{
+	"TLS12": (TLSVersion)(tls.VersionTLS12),
+	"TLS11": (TLSVersion)(tls.VersionTLS11),
+	"TLS10": (TLSVersion)(tls.VersionTLS10),
+}
+
+func (v TLSVersion) String() string {
+	for name, version := range TLSVersions {
+		if version == v {
+			return name
+		}
+	}
+	return fmt.Sprintf("%d", v)
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (v *TLSVersion) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	if version, ok := TLSVersions[s]; ok {
+		*v = version
+		return nil
+	}
+	return fmt.Errorf("unknown TLS version: %s", s)
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (v TLSVersion) MarshalYAML() (interface{}, error) {
+	if v == 0 {
+		return nil, nil
+	}
+	return v.String(), nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (v TLSVersion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestHTTPClientConfigAuthIsExclusive(t *testing.T) {
+	in := `
+bearer_token: 'token'
+oauth2:
+  client_id: 'am'
+  client_secret: 's3cr3t'
+  token_url: 'https://login.example.com/token'
+`
+	var cfg HTTPClientConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "at most one of basic_auth, oauth2, bearer_token & bearer_token_file must be configured"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestOAuth2TokenURLIsPresent(t *testing.T) {
+	in := `
+oauth2:
+  client_id: 'am'
+  client_secret: 's3cr3t'
+`
+	var cfg HTTPClientConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing token_url in oauth2 config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestHTTPClientConfigUnknownField(t *testing.T) {
+	in := `
+proxy_url: 'http://proxy:3128'
+unknown: true
+`
+	var cfg HTTPClientConfig
+	if err := yaml.UnmarshalStrict([]byte(in), &cfg); err == nil {
+		t.Fatalf("no error returned for unknown field")
+	}
+}
+
+func TestTLSConfigMinVersion(t *testing.T) {
+	in := `
+tls_config:
+  min_version: TLS12
+`
+	var cfg HTTPClientConfig
+	if err := yaml.UnmarshalStrict([]byte(in), &cfg); err != nil {
+		t.Fatalf("\nerror returned when none expected, error:\n%v", err)
+	}
+	if cfg.TLSConfig.MinVersion != TLSVersions["TLS12"] {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", TLSVersions["TLS12"], cfg.TLSConfig.MinVersion)
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "min_version: TLS12") {
+		t.Errorf("min_version not marshaled:\n%s", out)
+	}
+}
+
+func TestTLSConfigUnknownMinVersion(t *testing.T) {
+	in := `
+tls_config:
+  min_version: SSL3
+`
+	var cfg HTTPClientConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "unknown TLS version: SSL3"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
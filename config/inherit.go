@@ -0,0 +1,254 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Receivers may extend another receiver and integrations may have defaults:
+//
+//	receiver_defaults:
+//	  slack_configs:
+//	    api_url: https://hooks.slack.com/...
+//	    send_resolved: true
+//	receivers:
+//	- name: team-base
+//	  slack_configs:
+//	  - title: '{{ .CommonLabels.alertname }}'
+//	- name: team-a
+//	  extends: team-base
+//	  slack_configs:
+//	  - channel: '#team-a'
+//
+// A receiver extending another one inherits all its fields. Its own fields
+// override the inherited ones, maps are merged and the integrations of the
+// same type are merged by their position. The defaults of an integration
+// type are merged into all integrations of the type. Receivers of tenants
+// may extend the receivers of their tenant and the top-level receivers.
+// Both are resolved before the configuration is parsed, so the parsed
+// configuration holds the expanded receivers.
+
+// integrationKeys are the keys of the integration lists of receivers.
+var integrationKeys = func() map[string]bool {
+	keys := map[string]bool{}
+	t := reflect.TypeOf(Receiver{})
+	for i := 0; i < t.NumField(); i++ {
+		if key := yamlName(t.Field(i)); strings.HasSuffix(key, "_configs") {
+			keys[key] = true
+		}
+	}
+	return keys
+}()
+
+// expandReceivers returns the YAML configuration with the receiver defaults
+// and extended receivers resolved. It returns the input if it uses
+// neither.
+func expandReceivers(s string) (string, error) {
+	if !strings.Contains(s, "receiver_defaults") && !strings.Contains(s, "extends") {
+		return s, nil
+	}
+	var cfg yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(s), &cfg); err != nil {
+		// The error is reported when parsing the configuration.
+		return s, nil
+	}
+
+	var defaults yaml.MapSlice
+	if v := mapValue(cfg, "receiver_defaults"); v != nil {
+		var ok bool
+		if defaults, ok = v.(yaml.MapSlice); !ok {
+			return "", fmt.Errorf("receiver_defaults must be a map of integration types")
+		}
+		for _, d := range defaults {
+			key, _ := d.Key.(string)
+			if !integrationKeys[key] {
+				return "", fmt.Errorf("unknown integration type %q in receiver_defaults", key)
+			}
+			if _, ok := d.Value.(yaml.MapSlice); !ok {
+				return "", fmt.Errorf("receiver_defaults of %s must be a map", key)
+			}
+		}
+	}
+	cfg = removeKey(cfg, "receiver_defaults")
+
+	global, err := expandReceiverList(cfg, nil, defaults)
+	if err != nil {
+		return "", err
+	}
+	tenants, _ := mapValue(cfg, "tenants").([]interface{})
+	for _, t := range tenants {
+		tenant, ok := t.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		if _, err := expandReceiverList(tenant, global, defaults); err != nil {
+			name, _ := mapValue(tenant, "name").(string)
+			return "", fmt.Errorf("tenant %q: %s", name, err)
+		}
+	}
+
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// expandReceiverList expands the receivers of the map in place. Receivers
+// may extend the receivers of the list and the given outer receivers. The
+// unexpanded receivers of the list are returned by their name.
+func expandReceiverList(m yaml.MapSlice, outer map[string]yaml.MapSlice, defaults yaml.MapSlice) (map[string]yaml.MapSlice, error) {
+	list, _ := mapValue(m, "receivers").([]interface{})
+	byName := map[string]yaml.MapSlice{}
+	for name, r := range outer {
+		byName[name] = r
+	}
+	own := map[string]yaml.MapSlice{}
+	for _, item := range list {
+		if r, ok := item.(yaml.MapSlice); ok {
+			name, _ := mapValue(r, "name").(string)
+			byName[name] = r
+			own[name] = r
+		}
+	}
+
+	expanded := make(map[string]yaml.MapSlice, len(byName))
+	var expand func(name string, seen []string) (yaml.MapSlice, error)
+	expand = func(name string, seen []string) (yaml.MapSlice, error) {
+		if r, ok := expanded[name]; ok {
+			return r, nil
+		}
+		for _, s := range seen {
+			if s == name {
+				return nil, fmt.Errorf("receiver %q extends itself via %s", name, strings.Join(seen, " -> "))
+			}
+		}
+		r := byName[name]
+		base, ok := mapValue(r, "extends").(string)
+		if !ok || base == "" {
+			expanded[name] = r
+			return r, nil
+		}
+		if _, ok := byName[base]; !ok {
+			return nil, fmt.Errorf("undefined receiver %q extended by receiver %q", base, name)
+		}
+		b, err := expand(base, append(seen, name))
+		if err != nil {
+			return nil, err
+		}
+		b = removeKey(removeKey(b, "name"), "extends")
+		res := mergeReceiver(b, r)
+		expanded[name] = res
+		return res, nil
+	}
+
+	for i, item := range list {
+		r, ok := item.(yaml.MapSlice)
+		if !ok {
+			continue
+		}
+		name, _ := mapValue(r, "name").(string)
+		res, err := expand(name, nil)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = applyDefaults(res, defaults)
+	}
+	return own, nil
+}
+
+// mergeReceiver returns the base receiver overridden by the receiver. The
+// integrations of the same type are merged by their position.
+func mergeReceiver(base, r yaml.MapSlice) yaml.MapSlice {
+	res := append(yaml.MapSlice{}, base...)
+	for _, item := range r {
+		key, _ := item.Key.(string)
+		bv := mapValue(base, key)
+		if integrationKeys[key] {
+			bl, _ := bv.([]interface{})
+			l, _ := item.Value.([]interface{})
+			merged := make([]interface{}, 0, len(l))
+			for i, v := range l {
+				if i < len(bl) {
+					v = mergeValues(bl[i], v)
+				}
+				merged = append(merged, v)
+			}
+			if len(bl) > len(l) {
+				merged = append(merged, bl[len(l):]...)
+			}
+			mapItem(&res, key).Value = merged
+			continue
+		}
+		mapItem(&res, key).Value = mergeValues(bv, item.Value)
+	}
+	return res
+}
+
+// applyDefaults returns the receiver with the defaults merged into its
+// integrations.
+func applyDefaults(r, defaults yaml.MapSlice) yaml.MapSlice {
+	if len(defaults) == 0 {
+		return r
+	}
+	res := append(yaml.MapSlice{}, r...)
+	for _, d := range defaults {
+		key := d.Key.(string)
+		l, ok := mapValue(res, key).([]interface{})
+		if !ok {
+			continue
+		}
+		merged := make([]interface{}, 0, len(l))
+		for _, v := range l {
+			merged = append(merged, mergeValues(d.Value, v))
+		}
+		mapItem(&res, key).Value = merged
+	}
+	return res
+}
+
+// mergeValues returns the base value overridden by the value. Maps are
+// merged recursively, other values are replaced.
+func mergeValues(base, v interface{}) interface{} {
+	bm, ok := base.(yaml.MapSlice)
+	if !ok {
+		return v
+	}
+	m, ok := v.(yaml.MapSlice)
+	if !ok {
+		return v
+	}
+	res := append(yaml.MapSlice{}, bm...)
+	for _, item := range m {
+		key, _ := item.Key.(string)
+		mapItem(&res, key).Value = mergeValues(mapValue(bm, key), item.Value)
+	}
+	return res
+}
+
+// removeKey returns a copy of the map without the key.
+func removeKey(m yaml.MapSlice, key string) yaml.MapSlice {
+	res := make(yaml.MapSlice, 0, len(m))
+	for _, item := range m {
+		if item.Key != key {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiverInheritance(t *testing.T) {
+	cfg, err := Load(`
+receiver_defaults:
+  slack_configs:
+    api_url: http://slack.example.com/hook
+    send_resolved: true
+    http_config:
+      bearer_token: token
+route:
+  receiver: team-a
+receivers:
+- name: team-base
+  slack_configs:
+  - title: base title
+    channel: '#base'
+- name: team-a
+  extends: team-base
+  slack_configs:
+  - channel: '#team-a'
+- name: team-b
+  extends: team-a
+  slack_configs:
+  - channel: '#team-b'
+    send_resolved: false
+  - channel: '#team-b-extra'
+tenants:
+- name: tenant-x
+  route:
+    receiver: x
+  receivers:
+  - name: x
+    extends: team-base
+    slack_configs:
+    - channel: '#x'
+`)
+	require.NoError(t, err)
+
+	byName := map[string]*Receiver{}
+	for _, r := range cfg.Receivers {
+		byName[r.Name] = r
+	}
+	a := byName["team-a"]
+	require.Equal(t, "team-base", a.Extends)
+	require.Len(t, a.SlackConfigs, 1)
+	require.Equal(t, "#team-a", a.SlackConfigs[0].Channel)
+	require.Equal(t, "base title", a.SlackConfigs[0].Title)
+	require.Equal(t, Secret("http://slack.example.com/hook"), a.SlackConfigs[0].APIURL)
+	require.True(t, a.SlackConfigs[0].SendResolved())
+	require.Equal(t, Secret("token"), a.SlackConfigs[0].HTTPConfig.BearerToken)
+
+	b := byName["team-b"]
+	require.Len(t, b.SlackConfigs, 2)
+	require.Equal(t, "#team-b", b.SlackConfigs[0].Channel)
+	require.Equal(t, "base title", b.SlackConfigs[0].Title)
+	require.False(t, b.SlackConfigs[0].SendResolved())
+	require.Equal(t, "#team-b-extra", b.SlackConfigs[1].Channel)
+	require.Equal(t, Secret("http://slack.example.com/hook"), b.SlackConfigs[1].APIURL)
+
+	x := cfg.Tenants[0].Receivers[0]
+	require.Equal(t, "#x", x.SlackConfigs[0].Channel)
+	require.Equal(t, "base title", x.SlackConfigs[0].Title)
+	require.Equal(t, Secret("http://slack.example.com/hook"), x.SlackConfigs[0].APIURL)
+}
+
+func TestReceiverInheritanceErrors(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{
+			in: `
+route:
+  receiver: a
+receivers:
+- name: a
+  extends: b
+`,
+			err: `undefined receiver "b" extended by receiver "a"`,
+		},
+		{
+			in: `
+route:
+  receiver: a
+receivers:
+- name: a
+  extends: b
+- name: b
+  extends: a
+`,
+			err: `receiver "a" extends itself via a -> b`,
+		},
+		{
+			in: `
+receiver_defaults:
+  slack_config:
+    send_resolved: true
+route:
+  receiver: a
+receivers:
+- name: a
+`,
+			err: `unknown integration type "slack_config" in receiver_defaults`,
+		},
+	} {
+		_, err := Load(tc.in)
+		require.EqualError(t, err, tc.err)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Jsonnet configures the evaluation of configuration files ending in
+// .jsonnet. They are evaluated by Command, which must print the resulting
+// JSON configuration. LibPaths are the library paths imports are searched
+// in besides the directory of the file.
+var Jsonnet = struct {
+	Command  string
+	LibPaths []string
+}{
+	Command: "jsonnet",
+}
+
+// IsJsonnet returns true if the configuration file is evaluated as Jsonnet.
+func IsJsonnet(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), ".jsonnet")
+}
+
+// readFile returns the content of the configuration file. Jsonnet files are
+// evaluated, JSON files need no conversion as JSON is valid YAML.
+func readFile(filename string) ([]byte, error) {
+	if !IsJsonnet(filename) {
+		return ioutil.ReadFile(filename)
+	}
+	var args []string
+	for _, p := range Jsonnet.LibPaths {
+		args = append(args, "--jpath", p)
+	}
+	args = append(args, filename)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(Jsonnet.Command, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("evaluating jsonnet file %s: %s", filename, msg)
+		}
+		return nil, fmt.Errorf("evaluating jsonnet file %s: %s", filename, err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadJSONFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-json")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "alertmanager.json")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{
+	"route": {"receiver": "team-X", "group_wait": "1m"},
+	"receivers": [{"name": "team-X"}]
+}`), 0644))
+
+	cfg, _, err := LoadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, "team-X", cfg.Route.Receiver)
+	require.Equal(t, "1m", cfg.Route.GroupWait.String())
+}
+
+func TestLoadJsonnetFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-jsonnet")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The command stands in for jsonnet and prints its arguments as the
+	// receiver name.
+	cmd := filepath.Join(dir, "jsonnet")
+	require.NoError(t, ioutil.WriteFile(cmd, []byte(`#!/bin/sh
+if [ "$2" = "fail" ]; then echo "syntax error" >&2; exit 1; fi
+echo '{"route": {"receiver": "'"$*"'"}, "receivers": [{"name": "'"$*"'"}]}'
+`), 0755))
+
+	defer func(cmd string, paths []string) {
+		Jsonnet.Command, Jsonnet.LibPaths = cmd, paths
+	}(Jsonnet.Command, Jsonnet.LibPaths)
+	Jsonnet.Command, Jsonnet.LibPaths = cmd, []string{"lib", "vendor"}
+
+	filename := filepath.Join(dir, "alertmanager.jsonnet")
+	cfg, content, err := LoadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, "--jpath lib --jpath vendor "+filename, cfg.Route.Receiver)
+	require.Contains(t, string(content), `"receivers"`)
+
+	Jsonnet.LibPaths = []string{"fail"}
+	_, _, err = LoadFile(filename)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "syntax error")
+}
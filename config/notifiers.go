@@ -14,12 +14,14 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
-	commoncfg "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
 )
 
 var (
@@ -30,6 +32,13 @@ var (
 		},
 	}
 
+	// DefaultCloudEventsConfig defines default values for the CloudEvents
+	// encoding of webhook notifications.
+	DefaultCloudEventsConfig = CloudEventsConfig{
+		Mode: "structured",
+		Type: "io.prometheus.alertmanager.notification",
+	}
+
 	// DefaultEmailConfig defines default values for Email configurations.
 	DefaultEmailConfig = EmailConfig{
 		NotifierConfig: NotifierConfig{
@@ -134,6 +143,124 @@ var (
 		Retry:    duration(1 * time.Minute),
 		Expire:   duration(1 * time.Hour),
 	}
+
+	// DefaultNATSConfig defines default values for NATS configurations.
+	DefaultNATSConfig = NATSConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+	}
+
+	// DefaultMQTTConfig defines default values for MQTT configurations.
+	DefaultMQTTConfig = MQTTConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		QoS: 1,
+	}
+
+	// DefaultTwilioConfig defines default values for Twilio configurations.
+	DefaultTwilioConfig = TwilioConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: false,
+		},
+		Message: `{{ template "twilio.default.message" . }}`,
+	}
+
+	// DefaultVoiceConfig defines default values for voice call configurations.
+	DefaultVoiceConfig = VoiceConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: false,
+		},
+		Provider:   "twilio",
+		Message:    `{{ template "voice.default.message" . }}`,
+		AckTimeout: duration(2 * time.Minute),
+	}
+
+	// DefaultJiraConfig defines default values for Jira configurations.
+	DefaultJiraConfig = JiraConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		IssueType:        "Bug",
+		Summary:          `{{ template "jira.default.summary" . }}`,
+		Description:      `{{ template "jira.default.description" . }}`,
+		ResolvedComment:  `{{ template "jira.default.resolved_comment" . }}`,
+		ReopenTransition: "Reopen",
+	}
+
+	// DefaultServiceNowConfig defines default values for ServiceNow configurations.
+	DefaultServiceNowConfig = ServiceNowConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Table:            "incident",
+		ShortDescription: `{{ template "servicenow.default.short_description" . }}`,
+		Description:      `{{ template "servicenow.default.description" . }}`,
+		Impact:           `{{ template "servicenow.default.impact" . }}`,
+		Urgency:          `{{ template "servicenow.default.urgency" . }}`,
+		ResolveState:     "6",
+		CloseCode:        "Resolved by caller",
+		CloseNotes:       `{{ template "servicenow.default.close_notes" . }}`,
+	}
+
+	// DefaultSplunkConfig defines default values for Splunk configurations.
+	DefaultSplunkConfig = SplunkConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Source:     `{{ template "splunk.default.source" . }}`,
+		SourceType: "alertmanager",
+	}
+
+	// DefaultPubSubConfig defines default values for Google Cloud Pub/Sub configurations.
+	DefaultPubSubConfig = PubSubConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		APIURL: "https://pubsub.googleapis.com/",
+	}
+
+	// DefaultPluginConfig defines default values for plugin configurations.
+	DefaultPluginConfig = PluginConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Timeout: duration(30 * time.Second),
+	}
+
+	// DefaultExecConfig defines default values for exec configurations.
+	DefaultExecConfig = ExecConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Message:        `{{ template "exec.default.message" . }}`,
+		Timeout:        duration(30 * time.Second),
+		MaxConcurrency: 1,
+	}
+
+	// DefaultSyslogConfig defines default values for syslog configurations.
+	DefaultSyslogConfig = SyslogConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Network:  "udp",
+		Facility: "daemon",
+		Severity: `{{ template "syslog.default.severity" . }}`,
+		AppName:  "alertmanager",
+		MsgID:    `{{ template "syslog.default.msgid" . }}`,
+		Message:  `{{ template "syslog.default.message" . }}`,
+		SDID:     "alertmanager@32473",
+	}
+
+	// DefaultSNMPConfig defines default values for SNMP configurations.
+	DefaultSNMPConfig = SNMPConfig{
+		NotifierConfig: NotifierConfig{
+			VSendResolved: true,
+		},
+		Version:   "2c",
+		Community: "public",
+	}
 )
 
 // NotifierConfig contains base options common across all notifier configurations.
@@ -162,6 +289,28 @@ type EmailConfig struct {
 	HTML         string            `yaml:"html,omitempty" json:"html,omitempty"`
 	Text         string            `yaml:"text,omitempty" json:"text,omitempty"`
 	RequireTLS   *bool             `yaml:"require_tls,omitempty" json:"require_tls,omitempty"`
+	// AuthOAuth2 configures XOAUTH2 authentication with the SMTP server.
+	AuthOAuth2 *OAuth2Config `yaml:"auth_oauth2,omitempty" json:"auth_oauth2,omitempty"`
+	// SMIME configures S/MIME signing of the emails.
+	SMIME *SMIMEConfig `yaml:"smime,omitempty" json:"smime,omitempty"`
+}
+
+// SMIMEConfig configures the certificate and key emails are signed with.
+type SMIMEConfig struct {
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SMIMEConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SMIMEConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("cert_file and key_file are required in smime config")
+	}
+	return nil
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -185,6 +334,46 @@ func (c *EmailConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 	c.Headers = normalizedHeaders
 
+	if c.AuthOAuth2 != nil && c.AuthUsername == "" {
+		return fmt.Errorf("auth_username is required for auth_oauth2 in email config")
+	}
+	return nil
+}
+
+// OAuth2Config configures how OAuth2 access tokens are obtained. Tokens are
+// either requested with the client credentials grant or read from a file
+// that is kept up to date by an external process.
+type OAuth2Config struct {
+	ClientID         string            `yaml:"client_id,omitempty" json:"client_id,omitempty"`
+	ClientSecret     Secret            `yaml:"client_secret,omitempty" json:"client_secret,omitempty"`
+	ClientSecretFile string            `yaml:"client_secret_file,omitempty" json:"client_secret_file,omitempty"`
+	TokenURL         string            `yaml:"token_url,omitempty" json:"token_url,omitempty"`
+	Scopes           []string          `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+	EndpointParams   map[string]string `yaml:"endpoint_params,omitempty" json:"endpoint_params,omitempty"`
+	TokenFile        string            `yaml:"token_file,omitempty" json:"token_file,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *OAuth2Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain OAuth2Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.TokenFile != "" {
+		if c.ClientID != "" || c.ClientSecret != "" || c.ClientSecretFile != "" || c.TokenURL != "" {
+			return fmt.Errorf("at most one of token_file and client credentials must be configured in oauth2 config")
+		}
+		return nil
+	}
+	if c.ClientSecret != "" && c.ClientSecretFile != "" {
+		return fmt.Errorf("at most one of client_secret & client_secret_file must be configured in oauth2 config")
+	}
+	if c.ClientID == "" || (c.ClientSecret == "" && c.ClientSecretFile == "") || c.TokenURL == "" {
+		return fmt.Errorf("client_id, client_secret and token_url are required in oauth2 config")
+	}
+	if _, err := url.Parse(c.TokenURL); err != nil {
+		return fmt.Errorf("invalid token_url in oauth2 config: %s", err)
+	}
 	return nil
 }
 
@@ -192,7 +381,7 @@ func (c *EmailConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type PagerdutyConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	ServiceKey  Secret            `yaml:"service_key,omitempty" json"service_key,omitempty"`
 	RoutingKey  Secret            `yaml:"routing_key,omitempty" json:"routing_key,omitempty"`
@@ -205,6 +394,18 @@ type PagerdutyConfig struct {
 	Class       string            `yaml:"class,omitempty" json:"class,omitempty"`
 	Component   string            `yaml:"component,omitempty" json:"component,omitempty"`
 	Group       string            `yaml:"group,omitempty" json:"group,omitempty"`
+	// SeverityMap maps rendered severities to PagerDuty severities.
+	SeverityMap map[string]string `yaml:"severity_map,omitempty" json:"severity_map,omitempty"`
+	Links       []*PagerdutyLink  `yaml:"links,omitempty" json:"links,omitempty"`
+	Images      []*PagerdutyImage `yaml:"images,omitempty" json:"images,omitempty"`
+	// ChangeEvents sends firing notifications as change events instead of
+	// alert events. Resolved notifications are not sent.
+	ChangeEvents bool `yaml:"change_events,omitempty" json:"change_events,omitempty"`
+}
+
+// pagerdutySeverities are the severities accepted by PagerDuty.
+var pagerdutySeverities = map[string]struct{}{
+	"critical": {}, "error": {}, "warning": {}, "info": {},
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -217,9 +418,32 @@ func (c *PagerdutyConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	if c.RoutingKey == "" && c.ServiceKey == "" {
 		return fmt.Errorf("missing service or routing key in PagerDuty config")
 	}
+	for k, v := range c.SeverityMap {
+		if _, ok := pagerdutySeverities[v]; !ok {
+			return fmt.Errorf("invalid PagerDuty severity %q for %q in severity_map", v, k)
+		}
+	}
+	if c.ChangeEvents && c.RoutingKey == "" {
+		return fmt.Errorf("change_events require a routing key in PagerDuty config")
+	}
 	return nil
 }
 
+// PagerdutyLink is a link attached to PagerDuty events. Its fields are
+// templated, links with an empty href are not sent.
+type PagerdutyLink struct {
+	Href string `yaml:"href" json:"href"`
+	Text string `yaml:"text,omitempty" json:"text,omitempty"`
+}
+
+// PagerdutyImage is an image attached to PagerDuty events. Its fields are
+// templated, images with an empty source are not sent.
+type PagerdutyImage struct {
+	Src  string `yaml:"src" json:"src"`
+	Alt  string `yaml:"alt,omitempty" json:"alt,omitempty"`
+	Href string `yaml:"href,omitempty" json:"href,omitempty"`
+}
+
 // SlackAction configures a single Slack action that is sent with each notification.
 // Each action must contain a type, text, and url.
 // See https://api.slack.com/docs/message-attachments#action_fields for more information.
@@ -277,7 +501,7 @@ func (c *SlackField) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type SlackConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	APIURL Secret `yaml:"api_url,omitempty" json:"api_url,omitempty"`
 
@@ -311,7 +535,7 @@ func (c *SlackConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type HipchatConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	APIURL        string `yaml:"api_url,omitempty" json:"api_url,omitempty"`
 	AuthToken     Secret `yaml:"auth_token,omitempty" json:"auth_token,omitempty"`
@@ -340,10 +564,50 @@ func (c *HipchatConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type WebhookConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	// URL to send POST request to.
 	URL string `yaml:"url" json:"url"`
+
+	// SigningSecret enables HMAC-SHA256 signing of the request body. The
+	// signature is sent in the X-Alertmanager-Signature header.
+	SigningSecret Secret `yaml:"signing_secret,omitempty" json:"signing_secret,omitempty"`
+
+	// Body is a template for the request body. If empty, the default JSON
+	// message is sent.
+	Body string `yaml:"body,omitempty" json:"body,omitempty"`
+	// ContentType overrides the Content-Type header of the request.
+	ContentType string `yaml:"content_type,omitempty" json:"content_type,omitempty"`
+	// Method is the HTTP method of the request. Defaults to POST.
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+
+	// CloudEvents wraps the notification in a CloudEvents 1.0 event.
+	CloudEvents *CloudEventsConfig `yaml:"cloudevents,omitempty" json:"cloudevents,omitempty"`
+}
+
+// CloudEventsConfig configures the CloudEvents encoding of webhook notifications.
+type CloudEventsConfig struct {
+	// Mode is either "structured" or "binary".
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// Source of the event. Defaults to the external URL of the Alertmanager.
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+	Type   string `yaml:"type,omitempty" json:"type,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *CloudEventsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultCloudEventsConfig
+	type plain CloudEventsConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Mode != "structured" && c.Mode != "binary" {
+		return fmt.Errorf("unknown CloudEvents mode %q, must be structured or binary", c.Mode)
+	}
+	if c.Type == "" {
+		return fmt.Errorf("missing type in CloudEvents config")
+	}
+	return nil
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -364,6 +628,11 @@ func (c *WebhookConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return fmt.Errorf("scheme required for webhook url")
 	}
 	c.URL = url.String()
+	switch c.Method {
+	case "", "POST", "PUT", "PATCH":
+	default:
+		return fmt.Errorf("unsupported method %q in webhook config", c.Method)
+	}
 	return nil
 }
 
@@ -371,7 +640,7 @@ func (c *WebhookConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type WechatConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	APISecret Secret `yaml:"api_secret,omitempty" json:"api_secret,omitempty"`
 	CorpID    string `yaml:"corp_id,omitempty" json:"corp_id,omitempty"`
@@ -403,7 +672,7 @@ func (c *WechatConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 type OpsGenieConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	APIKey      Secret            `yaml:"api_key,omitempty" json:"api_key,omitempty"`
 	APIURL      string            `yaml:"api_url,omitempty" json:"api_url,omitempty"`
@@ -415,6 +684,17 @@ type OpsGenieConfig struct {
 	Tags        string            `yaml:"tags,omitempty" json:"tags,omitempty"`
 	Note        string            `yaml:"note,omitempty" json:"note,omitempty"`
 	Priority    string            `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Entity      string            `yaml:"entity,omitempty" json:"entity,omitempty"`
+	// Actions is a comma separated list of custom actions.
+	Actions string `yaml:"actions,omitempty" json:"actions,omitempty"`
+	// LabelDetails adds the common labels of the alerts to the details.
+	// Explicitly configured details take precedence.
+	LabelDetails bool `yaml:"label_details,omitempty" json:"label_details,omitempty"`
+
+	Responders []*OpsGenieResponder `yaml:"responders,omitempty" json:"responders,omitempty"`
+	// PriorityRules set the priority of the first rule matching the common
+	// labels of the alerts. Priority is used if no rule matches.
+	PriorityRules []*OpsGeniePriorityRule `yaml:"priority_rules,omitempty" json:"priority_rules,omitempty"`
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -424,11 +704,83 @@ func (c *OpsGenieConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	return unmarshal((*plain)(c))
 }
 
+// OpsGenieResponder is a team, user, escalation or schedule notified about
+// the alert. Exactly one of ID, name or username identifies the responder,
+// all of them are templated.
+type OpsGenieResponder struct {
+	ID       string `yaml:"id,omitempty" json:"id,omitempty"`
+	Name     string `yaml:"name,omitempty" json:"name,omitempty"`
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Type     string `yaml:"type" json:"type"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *OpsGenieResponder) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain OpsGenieResponder
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	var n int
+	for _, v := range []string{r.ID, r.Name, r.Username} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("exactly one of id, name and username must be configured in OpsGenie responder")
+	}
+	switch r.Type {
+	case "team", "escalation", "schedule":
+		if r.Username != "" {
+			return fmt.Errorf("username can only be used for OpsGenie responders of type user")
+		}
+	case "user":
+		if r.Name != "" {
+			return fmt.Errorf("name cannot be used for OpsGenie responders of type user")
+		}
+	default:
+		return fmt.Errorf("responder type must be one of team, user, escalation or schedule in OpsGenie config")
+	}
+	return nil
+}
+
+// opsGeniePriorityRegexp matches OpsGenie priorities.
+var opsGeniePriorityRegexp = regexp.MustCompile(`^P[1-5]$`)
+
+// OpsGeniePriorityRule maps alerts with matching labels to a priority.
+type OpsGeniePriorityRule struct {
+	Match    map[string]string `yaml:"match,omitempty" json:"match,omitempty"`
+	MatchRE  map[string]Regexp `yaml:"match_re,omitempty" json:"match_re,omitempty"`
+	Priority string            `yaml:"priority" json:"priority"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *OpsGeniePriorityRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain OpsGeniePriorityRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	for k := range r.Match {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+	for k := range r.MatchRE {
+		if !model.LabelNameRE.MatchString(k) {
+			return fmt.Errorf("invalid label name %q", k)
+		}
+	}
+	if !opsGeniePriorityRegexp.MatchString(r.Priority) {
+		return fmt.Errorf("priority must be one of P1 to P5 in OpsGenie priority rule")
+	}
+	return nil
+}
+
 // VictorOpsConfig configures notifications via VictorOps.
 type VictorOpsConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	APIKey            Secret `yaml:"api_key" json:"api_key"`
 	APIURL            string `yaml:"api_url" json:"api_url"`
@@ -469,7 +821,7 @@ func (d duration) MarshalText() ([]byte, error) {
 type PushoverConfig struct {
 	NotifierConfig `yaml:",inline" json:",inline"`
 
-	HTTPConfig *commoncfg.HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
 
 	UserKey  Secret   `yaml:"user_key,omitempty" json:"user_key,omitempty"`
 	Token    Secret   `yaml:"token,omitempty" json:"token,omitempty"`
@@ -496,3 +848,638 @@ func (c *PushoverConfig) UnmarshalYAML(unmarshal func(interface{}) error) error
 	}
 	return nil
 }
+
+// NATSConfig configures notifications published to a NATS subject.
+type NATSConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	// URL of the NATS server, e.g. nats://localhost:4222 or tls://nats:4222.
+	URL      string     `yaml:"url" json:"url"`
+	Subject  string     `yaml:"subject" json:"subject"`
+	Username string     `yaml:"username,omitempty" json:"username,omitempty"`
+	Password Secret     `yaml:"password,omitempty" json:"password,omitempty"`
+	Token    Secret     `yaml:"token,omitempty" json:"token,omitempty"`
+	TLS      *TLSConfig `yaml:"tls_config,omitempty" json:"tls_config,omitempty"`
+	// If set, messages are published to a JetStream stream bound to the
+	// subject and the notification only succeeds once the stream acknowledged it.
+	JetStream bool `yaml:"jetstream,omitempty" json:"jetstream,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *NATSConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultNATSConfig
+	type plain NATSConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing URL in NATS config")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return fmt.Errorf("scheme must be nats or tls for NATS url")
+	}
+	if c.Subject == "" {
+		return fmt.Errorf("missing subject in NATS config")
+	}
+	if c.Token != "" && (c.Username != "" || c.Password != "") {
+		return fmt.Errorf("at most one of token & username/password must be configured in NATS config")
+	}
+	return nil
+}
+
+// MQTTConfig configures notifications published to an MQTT broker.
+type MQTTConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	// URL of the broker, e.g. tcp://broker:1883 or ssl://broker:8883.
+	URL      string     `yaml:"url" json:"url"`
+	Topic    string     `yaml:"topic" json:"topic"`
+	QoS      int        `yaml:"qos" json:"qos"`
+	Retain   bool       `yaml:"retain,omitempty" json:"retain,omitempty"`
+	ClientID string     `yaml:"client_id,omitempty" json:"client_id,omitempty"`
+	Username string     `yaml:"username,omitempty" json:"username,omitempty"`
+	Password Secret     `yaml:"password,omitempty" json:"password,omitempty"`
+	TLS      *TLSConfig `yaml:"tls_config,omitempty" json:"tls_config,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *MQTTConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultMQTTConfig
+	type plain MQTTConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing URL in MQTT config")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts":
+	default:
+		return fmt.Errorf("scheme must be one of tcp, mqtt, ssl, tls or mqtts for MQTT url")
+	}
+	if c.Topic == "" {
+		return fmt.Errorf("missing topic in MQTT config")
+	}
+	if c.QoS < 0 || c.QoS > 2 {
+		return fmt.Errorf("qos must be 0, 1 or 2 in MQTT config")
+	}
+	if c.Password != "" && c.Username == "" {
+		return fmt.Errorf("password requires a username in MQTT config")
+	}
+	return nil
+}
+
+// e164Regexp matches phone numbers in E.164 format.
+var e164Regexp = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// TwilioConfig configures notifications via Twilio SMS.
+type TwilioConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+
+	APIURL              string   `yaml:"api_url,omitempty" json:"api_url,omitempty"`
+	AccountSID          string   `yaml:"account_sid,omitempty" json:"account_sid,omitempty"`
+	AuthToken           Secret   `yaml:"auth_token,omitempty" json:"auth_token,omitempty"`
+	AuthTokenFile       string   `yaml:"auth_token_file,omitempty" json:"auth_token_file,omitempty"`
+	From                string   `yaml:"from,omitempty" json:"from,omitempty"`
+	MessagingServiceSID string   `yaml:"messaging_service_sid,omitempty" json:"messaging_service_sid,omitempty"`
+	To                  []string `yaml:"to" json:"to"`
+	Message             string   `yaml:"message,omitempty" json:"message,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TwilioConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultTwilioConfig
+	type plain TwilioConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("missing to numbers in Twilio config")
+	}
+	for _, to := range c.To {
+		if !e164Regexp.MatchString(to) {
+			return fmt.Errorf("invalid to number %q in Twilio config, must be in E.164 format", to)
+		}
+	}
+	if (c.From == "") == (c.MessagingServiceSID == "") {
+		return fmt.Errorf("exactly one of from & messaging_service_sid must be configured in Twilio config")
+	}
+	if c.AuthToken != "" && c.AuthTokenFile != "" {
+		return fmt.Errorf("at most one of auth_token & auth_token_file must be configured in Twilio config")
+	}
+	return nil
+}
+
+// VoiceConfig configures phone call notifications. Calls are placed to the
+// configured numbers in order until one of the callees acknowledges the call
+// by pressing 1.
+type VoiceConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+
+	// Provider is either twilio or webhook. The webhook provider posts the
+	// call request to a generic SIP gateway.
+	Provider string `yaml:"provider" json:"provider"`
+
+	APIURL        string `yaml:"api_url,omitempty" json:"api_url,omitempty"`
+	AccountSID    string `yaml:"account_sid,omitempty" json:"account_sid,omitempty"`
+	AuthToken     Secret `yaml:"auth_token,omitempty" json:"auth_token,omitempty"`
+	AuthTokenFile string `yaml:"auth_token_file,omitempty" json:"auth_token_file,omitempty"`
+	From          string `yaml:"from,omitempty" json:"from,omitempty"`
+
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+
+	To      []string `yaml:"to" json:"to"`
+	Message string   `yaml:"message,omitempty" json:"message,omitempty"`
+	// CallbackURL is where the provider reports the keypress of the callee.
+	// Defaults to the voice callback endpoint of the external URL.
+	CallbackURL string   `yaml:"callback_url,omitempty" json:"callback_url,omitempty"`
+	AckTimeout  duration `yaml:"ack_timeout,omitempty" json:"ack_timeout,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *VoiceConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultVoiceConfig
+	type plain VoiceConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("missing to numbers in voice config")
+	}
+	switch c.Provider {
+	case "twilio":
+		for _, to := range c.To {
+			if !e164Regexp.MatchString(to) {
+				return fmt.Errorf("invalid to number %q in voice config, must be in E.164 format", to)
+			}
+		}
+		if c.From == "" {
+			return fmt.Errorf("missing from number in voice config")
+		}
+		if c.AuthToken != "" && c.AuthTokenFile != "" {
+			return fmt.Errorf("at most one of auth_token & auth_token_file must be configured in voice config")
+		}
+	case "webhook":
+		if c.URL == "" {
+			return fmt.Errorf("missing URL in voice config")
+		}
+		if _, err := url.Parse(c.URL); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown provider %q in voice config", c.Provider)
+	}
+	if c.AckTimeout <= 0 {
+		return fmt.Errorf("ack_timeout must be positive in voice config")
+	}
+	return nil
+}
+
+// JiraConfig configures notifications via Jira issues. One issue is kept per
+// alert group, identified by a label derived from the group key.
+type JiraConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+
+	APIURL              string `yaml:"api_url" json:"api_url"`
+	Username            string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password            Secret `yaml:"password,omitempty" json:"password,omitempty"`
+	PersonalAccessToken Secret `yaml:"personal_access_token,omitempty" json:"personal_access_token,omitempty"`
+
+	Project     string   `yaml:"project" json:"project"`
+	IssueType   string   `yaml:"issue_type,omitempty" json:"issue_type,omitempty"`
+	Summary     string   `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Priority    string   `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Labels      []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// ResolvedComment is added to the issue once all alerts of the group
+	// are resolved.
+	ResolvedComment string `yaml:"resolved_comment,omitempty" json:"resolved_comment,omitempty"`
+	// ResolveTransition is applied to the issue once all alerts of the
+	// group are resolved. Issues are only commented on if empty.
+	ResolveTransition string `yaml:"resolve_transition,omitempty" json:"resolve_transition,omitempty"`
+	// ReopenTransition is applied to a resolved issue when the alert group
+	// fires again.
+	ReopenTransition string `yaml:"reopen_transition,omitempty" json:"reopen_transition,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *JiraConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultJiraConfig
+	type plain JiraConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.APIURL == "" {
+		return fmt.Errorf("missing API URL in Jira config")
+	}
+	if _, err := url.Parse(c.APIURL); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(c.APIURL, "/") {
+		c.APIURL += "/"
+	}
+	if c.Project == "" {
+		return fmt.Errorf("missing project in Jira config")
+	}
+	if c.PersonalAccessToken != "" && (c.Username != "" || c.Password != "") {
+		return fmt.Errorf("at most one of personal_access_token & username/password must be configured in Jira config")
+	}
+	return nil
+}
+
+// ServiceNowConfig configures notifications via ServiceNow incidents. One
+// incident is kept per alert group, correlated by the group key.
+type ServiceNowConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+
+	InstanceURL string `yaml:"instance_url" json:"instance_url"`
+	Username    string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password    Secret `yaml:"password,omitempty" json:"password,omitempty"`
+	// If a client ID is set an OAuth token is requested using the password
+	// grant, or the client credentials grant if no username is configured.
+	ClientID     string `yaml:"client_id,omitempty" json:"client_id,omitempty"`
+	ClientSecret Secret `yaml:"client_secret,omitempty" json:"client_secret,omitempty"`
+
+	Table            string            `yaml:"table,omitempty" json:"table,omitempty"`
+	ShortDescription string            `yaml:"short_description,omitempty" json:"short_description,omitempty"`
+	Description      string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Impact           string            `yaml:"impact,omitempty" json:"impact,omitempty"`
+	Urgency          string            `yaml:"urgency,omitempty" json:"urgency,omitempty"`
+	AssignmentGroup  string            `yaml:"assignment_group,omitempty" json:"assignment_group,omitempty"`
+	Category         string            `yaml:"category,omitempty" json:"category,omitempty"`
+	CallerID         string            `yaml:"caller_id,omitempty" json:"caller_id,omitempty"`
+	Fields           map[string]string `yaml:"fields,omitempty" json:"fields,omitempty"`
+	ResolveState     string            `yaml:"resolve_state,omitempty" json:"resolve_state,omitempty"`
+	CloseCode        string            `yaml:"close_code,omitempty" json:"close_code,omitempty"`
+	CloseNotes       string            `yaml:"close_notes,omitempty" json:"close_notes,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *ServiceNowConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultServiceNowConfig
+	type plain ServiceNowConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.InstanceURL == "" {
+		return fmt.Errorf("missing instance URL in ServiceNow config")
+	}
+	if _, err := url.Parse(c.InstanceURL); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(c.InstanceURL, "/") {
+		c.InstanceURL += "/"
+	}
+	if c.ClientID == "" && c.Username == "" {
+		return fmt.Errorf("missing username or client ID in ServiceNow config")
+	}
+	if c.ClientID != "" && c.ClientSecret == "" {
+		return fmt.Errorf("missing client secret in ServiceNow config")
+	}
+	return nil
+}
+
+// SplunkConfig configures notifications sent to a Splunk HTTP Event Collector.
+type SplunkConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+
+	// URL of the event endpoint, e.g. https://splunk:8088/services/collector/event.
+	URL        string            `yaml:"url" json:"url"`
+	Token      Secret            `yaml:"token" json:"token"`
+	Index      string            `yaml:"index,omitempty" json:"index,omitempty"`
+	Source     string            `yaml:"source,omitempty" json:"source,omitempty"`
+	SourceType string            `yaml:"sourcetype,omitempty" json:"sourcetype,omitempty"`
+	Host       string            `yaml:"host,omitempty" json:"host,omitempty"`
+	Fields     map[string]string `yaml:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SplunkConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultSplunkConfig
+	type plain SplunkConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.URL == "" {
+		return fmt.Errorf("missing URL in Splunk config")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("scheme required for Splunk url")
+	}
+	if c.Token == "" {
+		return fmt.Errorf("missing token in Splunk config")
+	}
+	return nil
+}
+
+// PubSubConfig configures notifications published to a Google Cloud Pub/Sub
+// topic. Group labels are attached to the messages as attributes.
+type PubSubConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	HTTPConfig *HTTPClientConfig `yaml:"http_config,omitempty" json:"http_config,omitempty"`
+
+	APIURL  string `yaml:"api_url,omitempty" json:"api_url,omitempty"`
+	Project string `yaml:"project" json:"project"`
+	Topic   string `yaml:"topic" json:"topic"`
+	// CredentialsFile is the path to a service account key file. If empty,
+	// application default credentials are used.
+	CredentialsFile string            `yaml:"credentials_file,omitempty" json:"credentials_file,omitempty"`
+	Attributes      map[string]string `yaml:"attributes,omitempty" json:"attributes,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *PubSubConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultPubSubConfig
+	type plain PubSubConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Project == "" {
+		return fmt.Errorf("missing project in Pub/Sub config")
+	}
+	if c.Topic == "" {
+		return fmt.Errorf("missing topic in Pub/Sub config")
+	}
+	if _, err := url.Parse(c.APIURL); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(c.APIURL, "/") {
+		c.APIURL += "/"
+	}
+	return nil
+}
+
+// PluginConfig configures notifications sent through an external plugin.
+// The plugin is an executable that receives the notification as JSON on
+// stdin, see the notify package for the protocol.
+type PluginConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	// Name identifies the plugin in logs and is passed to it.
+	Name    string   `yaml:"name" json:"name"`
+	Command string   `yaml:"command" json:"command"`
+	Args    []string `yaml:"args,omitempty" json:"args,omitempty"`
+	// Payload is a template rendered and passed to the plugin. The plugin
+	// receives the template data in any case.
+	Payload string `yaml:"payload,omitempty" json:"payload,omitempty"`
+	// Settings are plugin specific options. Their values are templated.
+	Settings map[string]string `yaml:"settings,omitempty" json:"settings,omitempty"`
+	Timeout  duration          `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *PluginConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultPluginConfig
+	type plain PluginConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Name == "" {
+		return fmt.Errorf("missing name in plugin config")
+	}
+	if c.Command == "" {
+		return fmt.Errorf("missing command in plugin config")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout in plugin config must be positive")
+	}
+	return nil
+}
+
+// ExecConfig configures notifications sent by running a local command. The
+// rendered message is written to the standard input of the command, key
+// fields of the notification are passed as environment variables.
+type ExecConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	Command string   `yaml:"command" json:"command"`
+	Args    []string `yaml:"args,omitempty" json:"args,omitempty"`
+	Message string   `yaml:"message,omitempty" json:"message,omitempty"`
+	// Env sets additional environment variables. Their values are templated.
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// InheritEnv passes the environment of the Alertmanager to the command.
+	// By default the command only sees PATH and the notification variables.
+	InheritEnv bool   `yaml:"inherit_env,omitempty" json:"inherit_env,omitempty"`
+	WorkingDir string `yaml:"working_dir,omitempty" json:"working_dir,omitempty"`
+	// MaxOutputBytes limits the output of the command kept for error
+	// messages.
+	MaxOutputBytes int      `yaml:"max_output_bytes,omitempty" json:"max_output_bytes,omitempty"`
+	Timeout        duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// MaxConcurrency is the number of commands run at the same time.
+	MaxConcurrency int `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *ExecConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultExecConfig
+	type plain ExecConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Command == "" {
+		return fmt.Errorf("missing command in exec config")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout in exec config must be positive")
+	}
+	if c.MaxConcurrency <= 0 {
+		return fmt.Errorf("max_concurrency in exec config must be positive")
+	}
+	if c.MaxOutputBytes < 0 {
+		return fmt.Errorf("max_output_bytes in exec config must not be negative")
+	}
+	return nil
+}
+
+// syslogFacilities are the facility names accepted in syslog configurations.
+var syslogFacilities = map[string]struct{}{
+	"kern": {}, "user": {}, "mail": {}, "daemon": {}, "auth": {}, "syslog": {},
+	"lpr": {}, "news": {}, "uucp": {}, "cron": {}, "authpriv": {}, "ftp": {},
+	"local0": {}, "local1": {}, "local2": {}, "local3": {}, "local4": {},
+	"local5": {}, "local6": {}, "local7": {},
+}
+
+// sdNameRegexp matches valid RFC 5424 structured data IDs.
+var sdNameRegexp = regexp.MustCompile(`^[!#-<>-\\^-~]{1,32}$`)
+
+// SyslogConfig configures notifications sent as RFC 5424 syslog messages.
+// The common labels of the alerts are attached as structured data.
+type SyslogConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	// Network is one of udp, tcp or tls.
+	Network string     `yaml:"network,omitempty" json:"network,omitempty"`
+	Address string     `yaml:"address" json:"address"`
+	TLS     *TLSConfig `yaml:"tls_config,omitempty" json:"tls_config,omitempty"`
+
+	Facility string `yaml:"facility,omitempty" json:"facility,omitempty"`
+	// Severity is a template rendering a syslog severity name, e.g. err.
+	Severity string `yaml:"severity,omitempty" json:"severity,omitempty"`
+	// Hostname defaults to the hostname of the machine.
+	Hostname string `yaml:"hostname,omitempty" json:"hostname,omitempty"`
+	AppName  string `yaml:"app_name,omitempty" json:"app_name,omitempty"`
+	MsgID    string `yaml:"msg_id,omitempty" json:"msg_id,omitempty"`
+	Message  string `yaml:"message,omitempty" json:"message,omitempty"`
+	// SDID is the ID of the structured data element holding the labels.
+	SDID string `yaml:"sd_id,omitempty" json:"sd_id,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SyslogConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultSyslogConfig
+	type plain SyslogConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	switch c.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return fmt.Errorf("network must be one of udp, tcp or tls in syslog config")
+	}
+	if c.Address == "" {
+		return fmt.Errorf("missing address in syslog config")
+	}
+	if _, ok := syslogFacilities[c.Facility]; !ok {
+		return fmt.Errorf("unknown facility %q in syslog config", c.Facility)
+	}
+	if !sdNameRegexp.MatchString(c.SDID) {
+		return fmt.Errorf("invalid sd_id %q in syslog config", c.SDID)
+	}
+	return nil
+}
+
+// oidRegexp matches numeric object identifiers.
+var oidRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)+$`)
+
+// SNMPConfig configures notifications sent as SNMP traps.
+type SNMPConfig struct {
+	NotifierConfig `yaml:",inline" json:",inline"`
+
+	// Address of the trap receiver, the port defaults to 162.
+	Address string `yaml:"address" json:"address"`
+	// Version is either 2c or 3.
+	Version   string `yaml:"version,omitempty" json:"version,omitempty"`
+	Community Secret `yaml:"community,omitempty" json:"community,omitempty"`
+
+	// EnterpriseOID identifies the traps. Firing notifications are sent with
+	// the trap OID <enterprise_oid>.0.1, resolved ones with <enterprise_oid>.0.2.
+	EnterpriseOID string `yaml:"enterprise_oid" json:"enterprise_oid"`
+	// Varbinds are added to the trap. If none are configured the status, group
+	// key, summary and Alertmanager URL are sent below <enterprise_oid>.1.
+	Varbinds []*SNMPVarbind `yaml:"varbinds,omitempty" json:"varbinds,omitempty"`
+
+	// Security settings for SNMPv3.
+	User         string `yaml:"user,omitempty" json:"user,omitempty"`
+	AuthProtocol string `yaml:"auth_protocol,omitempty" json:"auth_protocol,omitempty"`
+	AuthPassword Secret `yaml:"auth_password,omitempty" json:"auth_password,omitempty"`
+	PrivProtocol string `yaml:"priv_protocol,omitempty" json:"priv_protocol,omitempty"`
+	PrivPassword Secret `yaml:"priv_password,omitempty" json:"priv_password,omitempty"`
+	// EngineID is the hex encoded engine ID of the Alertmanager.
+	EngineID string `yaml:"engine_id,omitempty" json:"engine_id,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SNMPConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultSNMPConfig
+	type plain SNMPConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Address == "" {
+		return fmt.Errorf("missing address in SNMP config")
+	}
+	if !oidRegexp.MatchString(c.EnterpriseOID) {
+		return fmt.Errorf("invalid enterprise_oid %q in SNMP config", c.EnterpriseOID)
+	}
+	switch c.Version {
+	case "2c":
+		if c.Community == "" {
+			return fmt.Errorf("missing community in SNMP config")
+		}
+	case "3":
+		if c.User == "" {
+			return fmt.Errorf("missing user in SNMPv3 config")
+		}
+		switch c.AuthProtocol {
+		case "":
+			if c.PrivProtocol != "" {
+				return fmt.Errorf("priv_protocol requires auth_protocol in SNMPv3 config")
+			}
+		case "MD5", "SHA":
+			if len(c.AuthPassword) < 8 {
+				return fmt.Errorf("auth_password must be at least 8 characters in SNMPv3 config")
+			}
+		default:
+			return fmt.Errorf("auth_protocol must be MD5 or SHA in SNMPv3 config")
+		}
+		switch c.PrivProtocol {
+		case "":
+		case "AES":
+			if len(c.PrivPassword) < 8 {
+				return fmt.Errorf("priv_password must be at least 8 characters in SNMPv3 config")
+			}
+		default:
+			return fmt.Errorf("priv_protocol must be AES in SNMPv3 config")
+		}
+		if c.EngineID != "" {
+			b, err := hex.DecodeString(c.EngineID)
+			if err != nil || len(b) < 5 || len(b) > 32 {
+				return fmt.Errorf("engine_id must be 5 to 32 hex encoded bytes in SNMPv3 config")
+			}
+		}
+	default:
+		return fmt.Errorf("version must be 2c or 3 in SNMP config")
+	}
+	return nil
+}
+
+// SNMPVarbind maps a templated value to an object identifier.
+type SNMPVarbind struct {
+	OID string `yaml:"oid" json:"oid"`
+	// Type is either string or integer.
+	Type  string `yaml:"type,omitempty" json:"type,omitempty"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *SNMPVarbind) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain SNMPVarbind
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if !oidRegexp.MatchString(c.OID) {
+		return fmt.Errorf("invalid oid %q in SNMP varbind", c.OID)
+	}
+	switch c.Type {
+	case "":
+		c.Type = "string"
+	case "string", "integer":
+	default:
+		return fmt.Errorf("type must be string or integer in SNMP varbind")
+	}
+	return nil
+}
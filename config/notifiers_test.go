@@ -157,6 +157,43 @@ url: 'http://example.com'
 	}
 }
 
+func TestWebhookMethodIsValid(t *testing.T) {
+	in := `
+url: 'http://example.com'
+method: DELETE
+`
+	var cfg WebhookConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := `unsupported method "DELETE" in webhook config`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestWebhookCloudEventsModeIsValid(t *testing.T) {
+	in := `
+url: 'http://example.com'
+cloudevents:
+  mode: batched
+`
+	var cfg WebhookConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := `unknown CloudEvents mode "batched", must be structured or binary`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
 func TestWebhookPasswordIsObsfucated(t *testing.T) {
 	in := `
 url: 'http://example.com'
@@ -383,3 +420,442 @@ fields:
 func newBoolPointer(b bool) *bool {
 	return &b
 }
+
+func TestNATSSubjectIsPresent(t *testing.T) {
+	in := `
+url: 'nats://localhost:4222'
+`
+	var cfg NATSConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing subject in NATS config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestNATSURLScheme(t *testing.T) {
+	in := `
+url: 'http://localhost:4222'
+subject: 'alerts'
+`
+	var cfg NATSConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "scheme must be nats or tls for NATS url"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestNATSAuthIsExclusive(t *testing.T) {
+	in := `
+url: 'nats://localhost:4222'
+subject: 'alerts'
+username: 'am'
+token: 's3cr3t'
+`
+	var cfg NATSConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "at most one of token & username/password must be configured in NATS config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestMQTTTopicIsPresent(t *testing.T) {
+	in := `
+url: 'tcp://localhost:1883'
+`
+	var cfg MQTTConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing topic in MQTT config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestMQTTQoS(t *testing.T) {
+	in := `
+url: 'tcp://localhost:1883'
+topic: 'alerts'
+qos: 3
+`
+	var cfg MQTTConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "qos must be 0, 1 or 2 in MQTT config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestTwilioToIsE164(t *testing.T) {
+	in := `
+from: '+15005550006'
+to: ['+14155552671', '0155552671']
+`
+	var cfg TwilioConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "invalid to number \"0155552671\" in Twilio config, must be in E.164 format"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestTwilioSenderIsExclusive(t *testing.T) {
+	in := `
+from: '+15005550006'
+messaging_service_sid: 'MG0123'
+to: ['+14155552671']
+`
+	var cfg TwilioConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "exactly one of from & messaging_service_sid must be configured in Twilio config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestVoiceProvider(t *testing.T) {
+	in := `
+provider: 'sip'
+to: ['+14155552671']
+`
+	var cfg VoiceConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "unknown provider \"sip\" in voice config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestVoiceWebhookURLIsPresent(t *testing.T) {
+	in := `
+provider: 'webhook'
+to: ['sip:oncall@example.com']
+`
+	var cfg VoiceConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing URL in voice config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestJiraProjectIsPresent(t *testing.T) {
+	in := `
+api_url: 'https://jira.example.com'
+`
+	var cfg JiraConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing project in Jira config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestServiceNowAuthIsPresent(t *testing.T) {
+	in := `
+instance_url: 'https://example.service-now.com'
+`
+	var cfg ServiceNowConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing username or client ID in ServiceNow config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSplunkTokenIsPresent(t *testing.T) {
+	in := `
+url: 'https://splunk:8088/services/collector/event'
+`
+	var cfg SplunkConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing token in Splunk config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestPubSubTopicIsPresent(t *testing.T) {
+	in := `
+project: 'my-project'
+`
+	var cfg PubSubConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing topic in Pub/Sub config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestEmailOAuth2RequiresUsername(t *testing.T) {
+	in := `
+to: 'to@email.com'
+auth_oauth2:
+  token_file: /etc/token
+`
+	var cfg EmailConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "auth_username is required for auth_oauth2 in email config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestOAuth2ClientCredentialsRequired(t *testing.T) {
+	in := `
+client_id: id
+token_url: https://login.example.com/token
+`
+	var cfg OAuth2Config
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "client_id, client_secret and token_url are required in oauth2 config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestOAuth2ClientSecretAndFile(t *testing.T) {
+	in := `
+client_id: id
+client_secret: secret
+client_secret_file: /etc/secret
+token_url: https://login.example.com/token
+`
+	var cfg OAuth2Config
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "at most one of client_secret & client_secret_file must be configured in oauth2 config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSMIMEKeyFileRequired(t *testing.T) {
+	in := `
+cert_file: /etc/alertmanager/smime.crt
+`
+	var cfg SMIMEConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "cert_file and key_file are required in smime config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestPluginCommandIsPresent(t *testing.T) {
+	in := `
+name: chat
+`
+	var cfg PluginConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "missing command in plugin config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestExecMaxConcurrencyIsPositive(t *testing.T) {
+	in := `
+command: /usr/local/bin/page
+max_concurrency: 0
+`
+	var cfg ExecConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "max_concurrency in exec config must be positive"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSyslogFacilityIsKnown(t *testing.T) {
+	in := `
+address: localhost:514
+facility: local9
+`
+	var cfg SyslogConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := `unknown facility "local9" in syslog config`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestSNMPv3PrivRequiresAuth(t *testing.T) {
+	in := `
+address: nms.example.com
+enterprise_oid: 1.3.6.1.4.1.32473
+version: 3
+user: alertmanager
+priv_protocol: AES
+priv_password: privpassword
+`
+	var cfg SNMPConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "priv_protocol requires auth_protocol in SNMPv3 config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestOpsGenieResponderType(t *testing.T) {
+	in := `
+responders:
+- name: dba
+  type: group
+`
+	var cfg OpsGenieConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "responder type must be one of team, user, escalation or schedule in OpsGenie config"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestOpsGeniePriorityRulePriority(t *testing.T) {
+	in := `
+priority_rules:
+- match:
+    severity: critical
+  priority: P0
+`
+	var cfg OpsGenieConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := "priority must be one of P1 to P5 in OpsGenie priority rule"
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
+
+func TestPagerdutySeverityMapValues(t *testing.T) {
+	in := `
+routing_key: 'xyz'
+severity_map:
+  page: urgent
+`
+	var cfg PagerdutyConfig
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+
+	expected := `invalid PagerDuty severity "urgent" for "page" in severity_map`
+
+	if err == nil {
+		t.Fatalf("no error returned, expected:\n%v", expected)
+	}
+	if err.Error() != expected {
+		t.Errorf("\nexpected:\n%v\ngot:\n%v", expected, err.Error())
+	}
+}
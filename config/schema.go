@@ -0,0 +1,195 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	commoncfg "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/timeinterval"
+)
+
+// schemaTypes are the schemas of types whose YAML form differs from their
+// structure.
+var schemaTypes = map[reflect.Type]func() map[string]interface{}{
+	reflect.TypeOf(Secret("")): func() map[string]interface{} {
+		return map[string]interface{}{
+			"type":        "string",
+			"description": "A secret or a reference to it of the form env://NAME, file:///path or vault://path#key.",
+		}
+	},
+	reflect.TypeOf(model.Duration(0)): func() map[string]interface{} {
+		return map[string]interface{}{
+			"type":    "string",
+			"pattern": `^((\d+)y)?((\d+)w)?((\d+)d)?((\d+)h)?((\d+)m)?((\d+)s)?((\d+)ms)?$`,
+		}
+	},
+	reflect.TypeOf(commoncfg.URL{}): func() map[string]interface{} {
+		return map[string]interface{}{"type": "string", "format": "uri"}
+	},
+	reflect.TypeOf(Regexp{}): func() map[string]interface{} {
+		return map[string]interface{}{"type": "string", "format": "regex"}
+	},
+	reflect.TypeOf(TLSVersion(0)): func() map[string]interface{} {
+		var names []string
+		for n := range TLSVersions {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return map[string]interface{}{"type": "string", "enum": names}
+	},
+	reflect.TypeOf(Matchers{}): func() map[string]interface{} {
+		return map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		}
+	},
+	reflect.TypeOf(timeinterval.TimeRange{}): func() map[string]interface{} {
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"start_time": map[string]interface{}{"type": "string", "pattern": `^\d\d:\d\d$`},
+				"end_time":   map[string]interface{}{"type": "string", "pattern": `^\d\d:\d\d$`},
+			},
+			"required":             []string{"start_time", "end_time"},
+			"additionalProperties": false,
+		}
+	},
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	configPkg           = reflect.TypeOf(Config{}).PkgPath()
+)
+
+// Schema returns the JSON Schema of the YAML configuration. Structures are
+// described in the definitions of the schema, named by their type.
+func Schema() map[string]interface{} {
+	g := &schemaGenerator{definitions: map[string]interface{}{}}
+	root := g.structSchema(reflect.TypeOf(Config{}))
+
+	// Receiver defaults are resolved before parsing, see expandReceivers.
+	defaults := map[string]interface{}{}
+	rt := reflect.TypeOf(Receiver{})
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if key := yamlName(f); integrationKeys[key] {
+			defaults[key] = g.schema(f.Type.Elem())
+		}
+	}
+	root["properties"].(map[string]interface{})["receiver_defaults"] = map[string]interface{}{
+		"type":                 "object",
+		"properties":           defaults,
+		"additionalProperties": false,
+	}
+
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "Alertmanager configuration"
+	root["definitions"] = g.definitions
+	return root
+}
+
+type schemaGenerator struct {
+	definitions map[string]interface{}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if f, ok := schemaTypes[t]; ok {
+		return f()
+	}
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		// Types of other packages are named by their package path.
+		name := t.Name()
+		if p := t.PkgPath(); p != configPkg {
+			p = strings.TrimPrefix(p, path.Dir(configPkg)+"/")
+			if i := strings.LastIndex(p, "/vendor/"); i >= 0 {
+				p = p[i+len("/vendor/"):]
+			}
+			name = strings.Replace(p, "/", ".", -1) + "." + name
+		}
+		if _, ok := g.definitions[name]; !ok {
+			// Reserve the name for recursive structures.
+			g.definitions[name] = nil
+			g.definitions[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	g.addProperties(t, props)
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+}
+
+func (g *schemaGenerator) addProperties(t reflect.Type, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		if strings.Contains(tag, ",inline") {
+			// Inlined maps collect unknown fields to reject them.
+			if f.Type.Kind() == reflect.Struct {
+				g.addProperties(f.Type, props)
+			}
+			continue
+		}
+		props[yamlName(f)] = g.schema(f.Type)
+	}
+}
+
+// yamlName returns the YAML key of the field.
+func yamlName(f reflect.StructField) string {
+	if name := strings.Split(f.Tag.Get("yaml"), ",")[0]; name != "" {
+		return name
+	}
+	return strings.ToLower(f.Name)
+}
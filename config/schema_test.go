@@ -0,0 +1,86 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// checkSchema returns the keys of the generic YAML value the schema does not
+// describe.
+func checkSchema(defs map[string]interface{}, s map[string]interface{}, v interface{}, path string) []string {
+	if ref, ok := s["$ref"].(string); ok {
+		s = defs[strings.TrimPrefix(ref, "#/definitions/")].(map[string]interface{})
+	}
+	var unknown []string
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		props, _ := s["properties"].(map[string]interface{})
+		for k, mv := range v {
+			key := fmt.Sprint(k)
+			ps, ok := props[key].(map[string]interface{})
+			if !ok {
+				ps, ok = s["additionalProperties"].(map[string]interface{})
+			}
+			if !ok {
+				unknown = append(unknown, path+"."+key)
+				continue
+			}
+			unknown = append(unknown, checkSchema(defs, ps, mv, path+"."+key)...)
+		}
+	case []interface{}:
+		items, _ := s["items"].(map[string]interface{})
+		for _, lv := range v {
+			unknown = append(unknown, checkSchema(defs, items, lv, path+"[]")...)
+		}
+	}
+	return unknown
+}
+
+func TestSchema(t *testing.T) {
+	// The schema must be valid JSON.
+	b, err := json.Marshal(Schema())
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &schema))
+	defs := schema["definitions"].(map[string]interface{})
+
+	route := defs["Route"].(map[string]interface{})["properties"].(map[string]interface{})
+	require.Equal(t, "#/definitions/Route", route["routes"].(map[string]interface{})["items"].(map[string]interface{})["$ref"])
+	require.Equal(t, "string", route["group_wait"].(map[string]interface{})["type"])
+
+	slack := defs["SlackConfig"].(map[string]interface{})["properties"].(map[string]interface{})
+	require.Contains(t, slack, "channel")
+	require.Contains(t, slack, "send_resolved")
+	require.Contains(t, slack, "http_config")
+
+	defaults := schema["properties"].(map[string]interface{})["receiver_defaults"].(map[string]interface{})
+	require.Contains(t, defaults["properties"], "slack_configs")
+
+	// All keys of the test configurations are described.
+	for _, f := range []string{"testdata/conf.good.yml", "testdata/conf.empty-fields.yml"} {
+		content, err := ioutil.ReadFile(f)
+		require.NoError(t, err)
+		var v interface{}
+		require.NoError(t, yaml.Unmarshal(content, &v))
+		require.Empty(t, checkSchema(defs, schema, v, ""), f)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Secrets may reference their value instead of containing it:
+//
+//	env://NAME           the value of the environment variable NAME
+//	file:///path         the content of the file, without surrounding whitespace
+//	vault://path#key     the key of the Vault secret at path
+//
+// Vault is addressed by the VAULT_ADDR and VAULT_TOKEN environment variables
+// and may be namespaced with VAULT_NAMESPACE. Both version 1 and 2 of the
+// key/value secrets engine are supported.
+const (
+	secretEnvPrefix   = "env://"
+	secretFilePrefix  = "file://"
+	secretVaultPrefix = "vault://"
+)
+
+var vaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// isSecretRef returns whether the secret references its value.
+func isSecretRef(s string) bool {
+	return strings.HasPrefix(s, secretEnvPrefix) ||
+		strings.HasPrefix(s, secretFilePrefix) ||
+		strings.HasPrefix(s, secretVaultPrefix)
+}
+
+// resolveSecret returns the value a secret references. The second return
+// value is false if the secret is not a reference.
+func resolveSecret(ref string) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(ref, secretEnvPrefix):
+		name := strings.TrimPrefix(ref, secretEnvPrefix)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", true, fmt.Errorf("environment variable %q of secret is not set", name)
+		}
+		return v, true, nil
+	case strings.HasPrefix(ref, secretFilePrefix):
+		b, err := ioutil.ReadFile(strings.TrimPrefix(ref, secretFilePrefix))
+		if err != nil {
+			return "", true, fmt.Errorf("reading secret: %s", err)
+		}
+		return strings.TrimSpace(string(b)), true, nil
+	case strings.HasPrefix(ref, secretVaultPrefix):
+		v, err := readVaultSecret(strings.TrimPrefix(ref, secretVaultPrefix))
+		return v, true, err
+	}
+	return ref, false, nil
+}
+
+func readVaultSecret(ref string) (string, error) {
+	i := strings.LastIndex(ref, "#")
+	if i <= 0 || i == len(ref)-1 {
+		return "", fmt.Errorf("vault secret %q must have the form vault://path#key", ref)
+	}
+	path, key := strings.Trim(ref[:i], "/"), ref[i+1:]
+
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return "", fmt.Errorf("reading vault secret %q: VAULT_ADDR is not set", path)
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("reading vault secret %q: %s", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("reading vault secret %q: unexpected status code %v", path, resp.StatusCode)
+	}
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("reading vault secret %q: %s", path, err)
+	}
+	data := secret.Data
+	// Version 2 of the key/value engine nests the data with its metadata.
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+	v, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %q has no key %q", path, key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// resolveSecrets replaces all secret references of the configuration with
+// their values and remembers the references to detect rotations.
+func resolveSecrets(cfg *Config) error {
+	return resolveSecretsWith(cfg, resolveSecret)
+}
+
+// resolveKnownSecrets replaces the secret references of the configuration
+// with the values they were resolved to before. Other references are
+// rejected without resolving them.
+func resolveKnownSecrets(cfg *Config, known map[string]string) error {
+	return resolveSecretsWith(cfg, func(ref string) (string, bool, error) {
+		if !isSecretRef(ref) {
+			return ref, false, nil
+		}
+		v, ok := known[ref]
+		if !ok {
+			return "", true, fmt.Errorf("secret reference %q is not used by the running configuration", ref)
+		}
+		return v, true, nil
+	})
+}
+
+func resolveSecretsWith(cfg *Config, resolveSecret func(string) (string, bool, error)) error {
+	resolved := map[string]string{}
+	err := walkSecrets(reflect.ValueOf(cfg), func(s *Secret) error {
+		v, ok := resolved[string(*s)]
+		if !ok {
+			var (
+				isRef bool
+				err   error
+			)
+			v, isRef, err = resolveSecret(string(*s))
+			if err != nil {
+				return err
+			}
+			if !isRef {
+				return nil
+			}
+			resolved[string(*s)] = v
+		}
+		*s = Secret(v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cfg.secretRefs = resolved
+	return nil
+}
+
+// walkSecrets calls f for all settable secrets reachable from v.
+func walkSecrets(v reflect.Value, f func(*Secret) error) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return walkSecrets(v.Elem(), f)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if fv := v.Field(i); fv.CanSet() {
+				if err := walkSecrets(fv, f); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkSecrets(v.Index(i), f); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem() == secretType {
+			for _, k := range v.MapKeys() {
+				s := v.MapIndex(k).Interface().(Secret)
+				if err := f(&s); err != nil {
+					return err
+				}
+				v.SetMapIndex(k, reflect.ValueOf(s))
+			}
+			return nil
+		}
+		for _, k := range v.MapKeys() {
+			if err := walkSecrets(v.MapIndex(k), f); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		if v.Type() == secretType && v.CanSet() {
+			return f(v.Addr().Interface().(*Secret))
+		}
+	}
+	return nil
+}
+
+// SecretsChanged resolves the secret references of the configuration again
+// and returns true if any of their values changed, e.g. because a secret
+// was rotated.
+func (c *Config) SecretsChanged() (bool, error) {
+	for ref, old := range c.secretRefs {
+		v, _, err := resolveSecret(ref)
+		if err != nil {
+			return false, err
+		}
+		if v != old {
+			return true, nil
+		}
+	}
+	return false, nil
+}
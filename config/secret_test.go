@@ -0,0 +1,161 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("file-token\n"), 0600))
+
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/alertmanager":
+			fmt.Fprint(w, `{"data": {"data": {"routing_key": "vault-key"}, "metadata": {"version": 1}}}`)
+		case "/v1/kv/slack":
+			fmt.Fprint(w, `{"data": {"url": "http://slack.example.com/hook"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	os.Setenv("VAULT_ADDR", vault.URL)
+	os.Setenv("VAULT_TOKEN", "root")
+	os.Setenv("TEST_SMTP_PASSWORD", "env-password")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+	defer os.Unsetenv("TEST_SMTP_PASSWORD")
+
+	in := `
+global:
+  smtp_auth_password: env://TEST_SMTP_PASSWORD
+  slack_api_url: vault://kv/slack#url
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+  pagerduty_configs:
+  - routing_key: vault://secret/data/alertmanager#routing_key
+  slack_configs:
+  - channel: '#alerts'
+  webhook_configs:
+  - url: http://example.com/hook
+    http_config:
+      bearer_token: file://` + tokenFile + `
+`
+	cfg, err := Load(in)
+	require.NoError(t, err)
+	require.Equal(t, Secret("env-password"), cfg.Global.SMTPAuthPassword)
+	require.Equal(t, Secret("vault-key"), cfg.Receivers[0].PagerdutyConfigs[0].RoutingKey)
+	require.Equal(t, Secret("http://slack.example.com/hook"), cfg.Receivers[0].SlackConfigs[0].APIURL)
+	require.Equal(t, Secret("file-token"), cfg.Receivers[0].WebhookConfigs[0].HTTPConfig.BearerToken)
+	require.NotContains(t, cfg.String(), "env-password")
+
+	changed, err := cfg.SecretsChanged()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("rotated"), 0600))
+	changed, err = cfg.SecretsChanged()
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	for ref, msg := range map[string]string{
+		"env://TEST_MISSING":         `environment variable "TEST_MISSING" of secret is not set`,
+		"vault://kv/slack":           `vault secret "kv/slack" must have the form vault://path#key`,
+		"vault://kv/slack#token":     `vault secret "kv/slack" has no key "token"`,
+		"vault://kv/missing#token":   `reading vault secret "kv/missing": unexpected status code 404`,
+		"file://" + dir + "/missing": "reading secret: open " + dir + "/missing: no such file or directory",
+	} {
+		_, err := Load(strings.Replace(in, "env://TEST_SMTP_PASSWORD", ref, 1))
+		require.EqualError(t, err, msg, ref)
+	}
+}
+
+func TestLoadSubmittedSecretReferences(t *testing.T) {
+	os.Setenv("TEST_SMTP_PASSWORD", "env-password")
+	os.Setenv("TEST_OTHER_SECRET", "other")
+	defer os.Unsetenv("TEST_SMTP_PASSWORD")
+	defer os.Unsetenv("TEST_OTHER_SECRET")
+
+	running, err := Load(`
+global:
+  smtp_auth_password: env://TEST_SMTP_PASSWORD
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+`)
+	require.NoError(t, err)
+
+	// References of the running configuration are resolved to their
+	// values, even if they changed since.
+	os.Setenv("TEST_SMTP_PASSWORD", "changed")
+	cfg, err := LoadSubmitted(`
+global:
+  smtp_auth_password: env://TEST_SMTP_PASSWORD
+route:
+  receiver: team-Y
+receivers:
+- name: team-Y
+  webhook_configs:
+  - url: http://example.com/hook
+`, running)
+	require.NoError(t, err)
+	require.Equal(t, Secret("env-password"), cfg.Global.SMTPAuthPassword)
+
+	// New references are rejected without being resolved.
+	for _, ref := range []string{"env://TEST_OTHER_SECRET", "file:///etc/hostname", "vault://kv/slack#url"} {
+		_, err := LoadSubmitted(`
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+  webhook_configs:
+  - url: http://example.com/hook
+    http_config:
+      bearer_token: `+ref+`
+`, running)
+		require.Error(t, err, ref)
+		require.Contains(t, err.Error(), "is not used by the running configuration", ref)
+	}
+	_, err = LoadSubmitted(`
+global:
+  smtp_auth_password: env://TEST_SMTP_PASSWORD
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+`, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Handling of unknown fields, which are fields of newer or older versions
+// when up- or downgrading.
+const (
+	// UnknownFieldsStrict rejects configurations with unknown fields.
+	UnknownFieldsStrict = "strict"
+	// UnknownFieldsWarn ignores unknown fields and reports them as warnings.
+	UnknownFieldsWarn = "warn"
+	// UnknownFieldsIgnore ignores unknown fields.
+	UnknownFieldsIgnore = "ignore"
+)
+
+// DefaultUnknownFields is the handling of unknown fields of sections the
+// configuration sets no handling for.
+var DefaultUnknownFields = UnknownFieldsStrict
+
+// unknownFieldsDefault is the key of the unknown_fields section setting the
+// handling of all sections.
+const unknownFieldsDefault = "default"
+
+// UnknownFieldsConfig maps top-level sections of the configuration, or
+// "default" for all sections, to the handling of their unknown fields.
+type UnknownFieldsConfig map[string]string
+
+// sections are the top-level keys of the configuration.
+var sections = func() map[string]bool {
+	keys := map[string]bool{"receiver_defaults": true}
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" {
+			keys[yamlName(f)] = true
+		}
+	}
+	return keys
+}()
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *UnknownFieldsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain UnknownFieldsConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	for s, mode := range *c {
+		if s != unknownFieldsDefault && !sections[s] {
+			return fmt.Errorf("unknown section %q in unknown_fields", s)
+		}
+		if err := checkUnknownFieldsMode(mode); err != nil {
+			return fmt.Errorf("%s in unknown_fields", err)
+		}
+	}
+	return nil
+}
+
+func checkUnknownFieldsMode(mode string) error {
+	switch mode {
+	case UnknownFieldsStrict, UnknownFieldsWarn, UnknownFieldsIgnore:
+		return nil
+	}
+	return fmt.Errorf("unknown handling %q of unknown fields, must be strict, warn or ignore", mode)
+}
+
+// mode returns the handling of the unknown fields of the section.
+func (c UnknownFieldsConfig) mode(section string) string {
+	if m, ok := c[section]; ok {
+		return m
+	}
+	if m, ok := c[unknownFieldsDefault]; ok {
+		return m
+	}
+	return DefaultUnknownFields
+}
+
+var (
+	topLevelKeyRe = regexp.MustCompile(`^["']?([A-Za-z_][\w-]*)["']?\s*:`)
+	errorLineRe   = regexp.MustCompile(`^line (\d+): `)
+)
+
+// filterUnknownFields applies the handling of unknown fields to the errors
+// of parsing the configuration strictly. It returns the remaining errors
+// and the warnings of unknown fields. Unknown fields are assigned to the
+// section by their line, fields of configurations on a single line are
+// handled by the default.
+func filterUnknownFields(s string, te *yaml.TypeError) ([]string, []string) {
+	var uf struct {
+		UnknownFields UnknownFieldsConfig `yaml:"unknown_fields"`
+	}
+	// Errors are reported by the strict parsing.
+	yaml.Unmarshal([]byte(s), &uf)
+
+	// The sections of the lines.
+	var (
+		lines   = strings.Split(s, "\n")
+		section = make([]string, len(lines)+1)
+		current string
+	)
+	for i, l := range lines {
+		if m := topLevelKeyRe.FindStringSubmatch(l); m != nil {
+			current = m[1]
+		}
+		section[i+1] = current
+	}
+
+	var errs, warnings []string
+	for _, e := range te.Errors {
+		if !strings.Contains(e, " not found in type ") {
+			errs = append(errs, e)
+			continue
+		}
+		var sec string
+		if m := errorLineRe.FindStringSubmatch(e); m != nil && len(lines) > 1 {
+			if n, _ := strconv.Atoi(m[1]); n < len(section) {
+				sec = section[n]
+			}
+		}
+		switch uf.UnknownFields.mode(sec) {
+		case UnknownFieldsWarn:
+			warnings = append(warnings, e)
+		case UnknownFieldsIgnore:
+		default:
+			errs = append(errs, e)
+		}
+	}
+	return errs, warnings
+}
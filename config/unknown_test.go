@@ -0,0 +1,71 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnknownFields(t *testing.T) {
+	const in = `
+route:
+  receiver: team-X
+  future_route_field: true
+receivers:
+- name: team-X
+  slack_configs:
+  - channel: '#team-x'
+    api_url: http://slack.example.com
+    future_slack_field: true
+`
+	// Unknown fields are rejected by default.
+	_, err := Load(in)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "field future_route_field not found")
+	require.Contains(t, err.Error(), "field future_slack_field not found")
+
+	// Unknown fields of receivers are ignored, those of the route are still
+	// rejected.
+	_, err = Load(in + `
+unknown_fields:
+  receivers: ignore
+`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "field future_route_field not found")
+	require.NotContains(t, err.Error(), "future_slack_field")
+
+	cfg, err := Load(in + `
+unknown_fields:
+  default: warn
+  receivers: ignore
+`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"line 4: field future_route_field not found in type config.plain"}, cfg.Warnings())
+	require.Equal(t, "#team-x", cfg.Receivers[0].SlackConfigs[0].Channel)
+
+	defer func(d string) { DefaultUnknownFields = d }(DefaultUnknownFields)
+	DefaultUnknownFields = UnknownFieldsIgnore
+	cfg, err = Load(in)
+	require.NoError(t, err)
+	require.Empty(t, cfg.Warnings())
+
+	// Other errors are still reported.
+	_, err = Load(in + `
+unknown_fields:
+  routes: warn
+`)
+	require.EqualError(t, err, `unknown section "routes" in unknown_fields`)
+}
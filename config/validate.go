@@ -0,0 +1,219 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Codes of configuration problems.
+const (
+	// ProblemSyntax is invalid YAML.
+	ProblemSyntax = "syntax"
+	// ProblemUnknownField is a field that does not exist.
+	ProblemUnknownField = "unknown_field"
+	// ProblemInvalidType is a value of the wrong type.
+	ProblemInvalidType = "invalid_type"
+	// ProblemMissingField is a required field that is not set.
+	ProblemMissingField = "missing_field"
+	// ProblemUndefinedReference is a reference to an undefined receiver or
+	// time interval.
+	ProblemUndefinedReference = "undefined_reference"
+	// ProblemDuplicate is a name that is not unique.
+	ProblemDuplicate = "duplicate"
+	// ProblemInvalidValue is any other invalid value.
+	ProblemInvalidValue = "invalid_value"
+	// ProblemSecret is a secret reference that cannot be resolved.
+	ProblemSecret = "secret"
+)
+
+// Problem is a problem of a configuration. Line and column start at 1 and
+// are 0 if the position is unknown. The positions of problems found after
+// parsing are located by the names and fields they mention on a best-effort
+// basis.
+type Problem struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	var pos []string
+	if p.File != "" {
+		pos = append(pos, p.File)
+	}
+	if p.Line > 0 {
+		pos = append(pos, strconv.Itoa(p.Line))
+		if p.Column > 0 {
+			pos = append(pos, strconv.Itoa(p.Column))
+		}
+	}
+	if len(pos) == 0 {
+		return p.Message
+	}
+	return strings.Join(pos, ":") + ": " + p.Message
+}
+
+// Error is the error of loading an invalid configuration.
+type Error struct {
+	Problems []Problem
+	err      error
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+// newError returns the error of the configuration content with the
+// problems of err.
+func newError(content string, err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Problems: problems(content, err), err: err}
+}
+
+// withFile sets the file of the problems of err.
+func withFile(err error, filename string) error {
+	if e, ok := err.(*Error); ok {
+		for i := range e.Problems {
+			e.Problems[i].File = filename
+		}
+	}
+	return err
+}
+
+// Validate returns the problems of the YAML configuration. Secret
+// references are not resolved. The error is only set if the configuration
+// cannot be validated.
+func Validate(content []byte) ([]Problem, error) {
+	if _, err := unmarshal(string(content)); err != nil {
+		return newError(string(content), err).Problems, nil
+	}
+	return nil, nil
+}
+
+var (
+	yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	quotedRe   = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	fieldRe    = regexp.MustCompile(`^[a-z]+(?:_[a-z]+)+\b`)
+)
+
+func problems(content string, err error) []Problem {
+	if te, ok := err.(*yaml.TypeError); ok {
+		ps := make([]Problem, 0, len(te.Errors))
+		for _, msg := range te.Errors {
+			ps = append(ps, yamlProblem(content, msg))
+		}
+		return ps
+	}
+	msg := err.Error()
+	if strings.HasPrefix(msg, "yaml: ") {
+		p := yamlProblem(content, msg)
+		p.Code = ProblemSyntax
+		return []Problem{p}
+	}
+
+	p := Problem{Code: problemCode(msg), Message: msg}
+	p.Line, p.Column = locate(content, msg, p.Code == ProblemDuplicate)
+	return []Problem{p}
+}
+
+// yamlProblem returns the problem of a message of the YAML parser, which
+// starts with the line of the problem.
+func yamlProblem(content, msg string) Problem {
+	p := Problem{Code: ProblemInvalidType, Message: strings.TrimPrefix(msg, "yaml: ")}
+	if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+		p.Line, _ = strconv.Atoi(m[1])
+		p.Message = m[2]
+		p.Column = firstColumn(content, p.Line)
+	}
+	if strings.Contains(p.Message, " not found in type ") || strings.Contains(p.Message, " already set in type ") {
+		p.Code = ProblemUnknownField
+	}
+	return p
+}
+
+func problemCode(msg string) string {
+	switch {
+	case strings.HasPrefix(msg, "undefined "):
+		return ProblemUndefinedReference
+	case strings.Contains(msg, " is not unique"), strings.Contains(msg, " is already defined"):
+		return ProblemDuplicate
+	case strings.HasPrefix(msg, "no "), strings.HasPrefix(msg, "missing "),
+		strings.Contains(msg, " required"), strings.Contains(msg, " must specify "),
+		strings.Contains(msg, " must be set"):
+		return ProblemMissingField
+	case strings.Contains(msg, "secret"), strings.Contains(msg, "environment variable"):
+		return ProblemSecret
+	}
+	return ProblemInvalidValue
+}
+
+// locate returns the position of the first quoted name of the message in
+// the content, or of its second definition for duplicates. Without quoted
+// names, the position of the field the message starts with is returned.
+func locate(content, msg string, duplicate bool) (int, int) {
+	lines := strings.Split(content, "\n")
+	for _, q := range quotedRe.FindAllString(msg, -1) {
+		name, err := strconv.Unquote(q)
+		if err != nil || name == "" {
+			continue
+		}
+		prefix := `(?:^|[\s:\[,{'"])`
+		if duplicate {
+			// Duplicates are names of items.
+			prefix = `name:\s*['"]?`
+		}
+		re := regexp.MustCompile(prefix + `(` + regexp.QuoteMeta(name) + `)(?:$|[\s,\]}'"#])`)
+		found := 0
+		for i, l := range lines {
+			if m := re.FindStringSubmatchIndex(l); m != nil {
+				found++
+				if !duplicate || found == 2 {
+					return i + 1, m[2] + 1
+				}
+			}
+		}
+	}
+	if f := fieldRe.FindString(msg); f != "" {
+		re := regexp.MustCompile(`(?:^|[\s-])(` + f + `)\s*:`)
+		for i, l := range lines {
+			if m := re.FindStringSubmatchIndex(l); m != nil {
+				return i + 1, m[2] + 1
+			}
+		}
+	}
+	return 0, 0
+}
+
+// firstColumn returns the column of the first non-blank character of the
+// line.
+func firstColumn(content string, line int) int {
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return 0
+	}
+	l := lines[line-1]
+	if t := strings.TrimLeft(l, " \t-"); t != "" {
+		return len(l) - len(t) + 1
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		problems []Problem
+	}{
+		{
+			in: `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+`,
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  group_wait: [
+`,
+			problems: []Problem{{Line: 4, Column: 3, Code: ProblemSyntax, Message: "did not find expected node content"}},
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  unknown: true
+receivers:
+- name: team-X
+`,
+			problems: []Problem{{Line: 4, Column: 3, Code: ProblemUnknownField, Message: "field unknown not found in type config.plain"}},
+		},
+		{
+			in: `
+route:
+  receiver: team-Y
+receivers:
+- name: team-X
+`,
+			problems: []Problem{{Line: 3, Column: 13, Code: ProblemUndefinedReference, Message: `undefined receiver "team-Y" used in route`}},
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+- name: team-X
+`,
+			problems: []Problem{{Line: 6, Column: 9, Code: ProblemDuplicate, Message: `notification config name "team-X" is not unique`}},
+		},
+		{
+			in: `
+route:
+  receiver: team-X
+  group_interval: 0s
+receivers:
+- name: team-X
+`,
+			problems: []Problem{{Line: 4, Column: 3, Code: ProblemInvalidValue, Message: "group_interval cannot be zero"}},
+		},
+		{
+			in: `
+receivers:
+- name: team-X
+`,
+			problems: []Problem{{Code: ProblemMissingField, Message: "no routes provided"}},
+		},
+	} {
+		problems, err := Validate([]byte(tc.in))
+		require.NoError(t, err)
+		require.Equal(t, tc.problems, problems, tc.in)
+	}
+}
+
+func TestValidateSkipsSecrets(t *testing.T) {
+	const in = `
+global:
+  slack_api_url: env://VALIDATE_TEST_UNSET
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+`
+	problems, err := Validate([]byte(in))
+	require.NoError(t, err)
+	require.Empty(t, problems)
+
+	_, err = Load(in)
+	require.Error(t, err)
+	e, ok := err.(*Error)
+	require.True(t, ok)
+	require.Equal(t, ProblemSecret, e.Problems[0].Code)
+}
+
+func TestLoadFileError(t *testing.T) {
+	f, err := ioutil.TempFile("", "alertmanager.yml")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("route:\n  receiver: team-Y\nreceivers:\n- name: team-X\n")
+	require.NoError(t, err)
+	f.Close()
+
+	_, _, err = LoadFile(f.Name())
+	require.EqualError(t, err, `undefined receiver "team-Y" used in route`)
+	e, ok := err.(*Error)
+	require.True(t, ok)
+	require.Equal(t, []Problem{{
+		File:    f.Name(),
+		Line:    2,
+		Column:  13,
+		Code:    ProblemUndefinedReference,
+		Message: `undefined receiver "team-Y" used in route`,
+	}}, e.Problems)
+	require.Equal(t, f.Name()+`:2:13: undefined receiver "team-Y" used in route`, e.Problems[0].String())
+}
@@ -16,20 +16,65 @@ package dispatch
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/pkg/labels"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/pkg/tracing"
 	"github.com/prometheus/alertmanager/provider"
 	"github.com/prometheus/alertmanager/types"
 )
 
+var (
+	// overflowLabels are the group labels of the aggregation group that
+	// alerts exceeding the group limits of a route are merged into.
+	overflowLabels      = model.LabelSet{"__overflow__": "true"}
+	overflowFingerprint = overflowLabels.Fingerprint()
+
+	limitedAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "dispatcher_limited_alerts_total",
+		Help:      "The total number of alert updates exceeding the aggregation group limits of a route.",
+	}, []string{"receiver", "limit", "action"})
+)
+
+func init() {
+	prometheus.MustRegister(limitedAlerts)
+}
+
+const (
+	// numShards is the number of shards the aggregation groups of the
+	// dispatcher are distributed over.
+	numShards = 64
+	// mailboxSize is the number of alerts buffered per shard.
+	mailboxSize = 1024
+)
+
+// groupShard holds the aggregation groups whose labels hash to the shard.
+// Alerts for the groups of a shard are processed in order by a goroutine
+// receiving them from the mailbox.
+type groupShard struct {
+	mtx     sync.RWMutex
+	groups  map[*Route]map[model.Fingerprint]*aggrGroup
+	mailbox chan routedAlert
+}
+
+// routedAlert is an alert and a route it matched.
+type routedAlert struct {
+	alert *types.Alert
+	route *Route
+}
+
 // Dispatcher sorts incoming alerts into aggregation groups and
 // assigns the correct notifiers to each.
 type Dispatcher struct {
@@ -38,14 +83,21 @@ type Dispatcher struct {
 	stage  notify.Stage
 
 	marker  types.Marker
+	acks    *ack.Acks
 	timeout func(time.Duration) time.Duration
 
-	aggrGroups map[*Route]map[model.Fingerprint]*aggrGroup
-	mtx        sync.RWMutex
+	shards [numShards]*groupShard
+	// The number of aggregation groups per route, not counting the
+	// overflow groups.
+	numGroups map[*Route]int
+	mtx       sync.Mutex
+	// The interval at which empty aggregation groups are removed.
+	cleanupInterval time.Duration
 
-	done   chan struct{}
-	ctx    context.Context
-	cancel func()
+	workers sync.WaitGroup
+	done    chan struct{}
+	ctx     context.Context
+	cancel  func()
 
 	logger log.Logger
 }
@@ -56,6 +108,7 @@ func NewDispatcher(
 	r *Route,
 	s notify.Stage,
 	mk types.Marker,
+	acks *ack.Acks,
 	to func(time.Duration) time.Duration,
 	l log.Logger,
 ) *Dispatcher {
@@ -64,8 +117,18 @@ func NewDispatcher(
 		stage:   s,
 		route:   r,
 		marker:  mk,
+		acks:    acks,
 		timeout: to,
 		logger:  log.With(l, "component", "dispatcher"),
+
+		numGroups:       map[*Route]int{},
+		cleanupInterval: 30 * time.Second,
+	}
+	for i := range disp.shards {
+		disp.shards[i] = &groupShard{
+			groups:  map[*Route]map[model.Fingerprint]*aggrGroup{},
+			mailbox: make(chan routedAlert, mailboxSize),
+		}
 	}
 	return disp
 }
@@ -74,21 +137,47 @@ func NewDispatcher(
 func (d *Dispatcher) Run() {
 	d.done = make(chan struct{})
 
-	d.mtx.Lock()
-	d.aggrGroups = map[*Route]map[model.Fingerprint]*aggrGroup{}
-	d.mtx.Unlock()
-
 	d.ctx, d.cancel = context.WithCancel(context.Background())
 
+	for _, s := range d.shards {
+		d.workers.Add(1)
+		go d.processMailbox(s)
+	}
+
 	d.run(d.alerts.Subscribe())
+	d.workers.Wait()
 	close(d.done)
 }
 
+// processMailbox inserts the alerts sent to the mailbox of the shard into
+// their aggregation groups.
+func (d *Dispatcher) processMailbox(s *groupShard) {
+	defer d.workers.Done()
+
+	for {
+		select {
+		case ra := <-s.mailbox:
+			d.processAlert(ra.alert, ra.route)
+		case <-d.ctx.Done():
+			return
+		}
+	}
+}
+
+// shard returns the shard holding the aggregation groups with the given
+// labels fingerprint.
+func (d *Dispatcher) shard(fp model.Fingerprint) *groupShard {
+	return d.shards[uint64(fp)%numShards]
+}
+
 // AlertBlock contains a list of alerts associated with a set of
 // routing options.
 type AlertBlock struct {
 	RouteOpts *RouteOpts  `json:"routeOpts"`
 	Alerts    []*APIAlert `json:"alerts"`
+	// RouteKey identifies the route like the route label of the
+	// aggregation group metrics.
+	RouteKey string `json:"routeKey"`
 }
 
 // APIAlert is the API representation of an alert, which is a regular alert
@@ -98,6 +187,8 @@ type APIAlert struct {
 	Status      types.AlertStatus `json:"status"`
 	Receivers   []string          `json:"receivers"`
 	Fingerprint string            `json:"fingerprint"`
+
+	Acknowledgement *ack.Ack `json:"acknowledgement,omitempty"`
 }
 
 // AlertGroup is a list of alert blocks grouped by the same label set.
@@ -116,7 +207,8 @@ func (ao AlertOverview) Len() int           { return len(ao) }
 
 func matchesFilterLabels(a *APIAlert, matchers []*labels.Matcher) bool {
 	for _, m := range matchers {
-		if v, prs := a.Labels[model.LabelName(m.Name)]; !prs || !m.Matches(string(v)) {
+		v, prs := a.Labels[model.LabelName(m.Name)]
+		if !prs && m.Type != labels.MatchAbsent || !m.Matches(string(v)) {
 			return false
 		}
 	}
@@ -128,53 +220,55 @@ func matchesFilterLabels(a *APIAlert, matchers []*labels.Matcher) bool {
 func (d *Dispatcher) Groups(matchers []*labels.Matcher) AlertOverview {
 	overview := AlertOverview{}
 
-	d.mtx.RLock()
-	defer d.mtx.RUnlock()
-
 	seen := map[model.Fingerprint]*AlertGroup{}
 
-	for route, ags := range d.aggrGroups {
-		for _, ag := range ags {
-			alertGroup, ok := seen[ag.fingerprint()]
-			if !ok {
-				alertGroup = &AlertGroup{Labels: ag.labels}
-				alertGroup.GroupKey = ag.GroupKey()
-
-				seen[ag.fingerprint()] = alertGroup
-			}
-
-			now := time.Now()
+	d.forEachGroup(func(route *Route, ag *aggrGroup) {
+		alertGroup, ok := seen[ag.fingerprint()]
+		if !ok {
+			alertGroup = &AlertGroup{Labels: ag.labels}
+			alertGroup.GroupKey = ag.GroupKey()
 
-			var apiAlerts []*APIAlert
-			for _, a := range types.Alerts(ag.alertSlice()...) {
-				if !a.EndsAt.IsZero() && a.EndsAt.Before(now) {
-					continue
-				}
-				status := d.marker.Status(a.Fingerprint())
-				aa := &APIAlert{
-					Alert:       a,
-					Status:      status,
-					Fingerprint: a.Fingerprint().String(),
-				}
+			seen[ag.fingerprint()] = alertGroup
+		}
 
-				if !matchesFilterLabels(aa, matchers) {
-					continue
-				}
+		now := time.Now()
 
-				apiAlerts = append(apiAlerts, aa)
+		var apiAlerts []*APIAlert
+		for _, a := range types.Alerts(ag.alertSlice()...) {
+			if !a.EndsAt.IsZero() && a.EndsAt.Before(now) {
+				continue
+			}
+			status := d.marker.Status(a.Fingerprint())
+			aa := &APIAlert{
+				Alert:       a,
+				Status:      status,
+				Fingerprint: a.Fingerprint().String(),
 			}
-			if len(apiAlerts) == 0 {
+			if d.acks != nil {
+				aa.Acknowledgement, _ = d.acks.Acknowledged(a)
+			}
+
+			if !matchesFilterLabels(aa, matchers) {
 				continue
 			}
 
-			alertGroup.Blocks = append(alertGroup.Blocks, &AlertBlock{
-				RouteOpts: &route.RouteOpts,
-				Alerts:    apiAlerts,
-			})
+			apiAlerts = append(apiAlerts, aa)
+		}
+		if len(apiAlerts) == 0 {
+			return
+		}
 
+		// Groups of different routes with the same labels are listed
+		// once.
+		if len(alertGroup.Blocks) == 0 {
 			overview = append(overview, alertGroup)
 		}
-	}
+		alertGroup.Blocks = append(alertGroup.Blocks, &AlertBlock{
+			RouteOpts: &route.RouteOpts,
+			RouteKey:  route.Key(),
+			Alerts:    apiAlerts,
+		})
+	})
 
 	sort.Sort(overview)
 
@@ -182,7 +276,7 @@ func (d *Dispatcher) Groups(matchers []*labels.Matcher) AlertOverview {
 }
 
 func (d *Dispatcher) run(it provider.AlertIterator) {
-	cleanup := time.NewTicker(30 * time.Second)
+	cleanup := time.NewTicker(d.cleanupInterval)
 	defer cleanup.Stop()
 
 	defer it.Close()
@@ -207,23 +301,19 @@ func (d *Dispatcher) run(it provider.AlertIterator) {
 			}
 
 			for _, r := range d.route.Match(alert.Labels) {
-				d.processAlert(alert, r)
+				s := d.shard(r.RouteOpts.GroupLabels(alert.Labels).Fingerprint())
+				select {
+				case s.mailbox <- routedAlert{alert: alert, route: r}:
+				case <-d.ctx.Done():
+					return
+				}
 			}
 
 		case <-cleanup.C:
-			d.mtx.Lock()
-
-			for _, groups := range d.aggrGroups {
-				for _, ag := range groups {
-					if ag.empty() {
-						ag.stop()
-						delete(groups, ag.fingerprint())
-					}
-				}
+			for _, s := range d.shards {
+				d.cleanup(s)
 			}
 
-			d.mtx.Unlock()
-
 		case <-d.ctx.Done():
 			return
 		}
@@ -241,6 +331,44 @@ func (d *Dispatcher) Stop() {
 	<-d.done
 }
 
+// forEachGroup calls f for every aggregation group. Only the shard of the
+// group is locked while f is called.
+func (d *Dispatcher) forEachGroup(f func(*Route, *aggrGroup)) {
+	for _, s := range d.shards {
+		s.mtx.RLock()
+		for route, groups := range s.groups {
+			for _, ag := range groups {
+				f(route, ag)
+			}
+		}
+		s.mtx.RUnlock()
+	}
+}
+
+// cleanup stops and removes the empty aggregation groups of the shard.
+func (d *Dispatcher) cleanup(s *groupShard) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for route, groups := range s.groups {
+		for fp, ag := range groups {
+			if !ag.empty() {
+				continue
+			}
+			ag.stop()
+			delete(groups, fp)
+			if fp != overflowFingerprint {
+				d.mtx.Lock()
+				d.numGroups[route]--
+				d.mtx.Unlock()
+			}
+		}
+		if len(groups) == 0 {
+			delete(s.groups, route)
+		}
+	}
+}
+
 // notifyFunc is a function that performs notifcation for the alert
 // with the given fingerprint. It aborts on context cancelation.
 // Returns false iff notifying failed.
@@ -249,40 +377,103 @@ type notifyFunc func(context.Context, ...*types.Alert) bool
 // processAlert determines in which aggregation group the alert falls
 // and inserts it.
 func (d *Dispatcher) processAlert(alert *types.Alert, route *Route) {
-	groupLabels := model.LabelSet{}
+	var (
+		groupLabels = route.RouteOpts.GroupLabels(alert.Labels)
+		fp          = groupLabels.Fingerprint()
+		limits      = route.RouteOpts.GroupLimits
+	)
+	limit := d.insert(alert, route, groupLabels, fp, limits.MaxGroups, limits.MaxAlertsPerGroup)
+	if limit == "" {
+		return
+	}
 
-	for ln, lv := range alert.Labels {
-		if _, ok := route.RouteOpts.GroupBy[ln]; ok {
-			groupLabels[ln] = lv
-		}
+	if limits.Overflow != config.OverflowMerge || fp == overflowFingerprint {
+		limitedAlerts.WithLabelValues(route.RouteOpts.Receiver, limit, config.OverflowReject).Inc()
+		level.Debug(d.logger).Log("msg", "Alert exceeds group limits", "limit", limit, "alert", alert, "route", route.Key())
+		return
 	}
+	if d.insert(alert, route, overflowLabels, overflowFingerprint, 0, limits.MaxAlertsPerGroup) != "" {
+		limitedAlerts.WithLabelValues(route.RouteOpts.Receiver, "max_alerts_per_group", config.OverflowReject).Inc()
+		level.Debug(d.logger).Log("msg", "Alert exceeds group limits", "limit", "max_alerts_per_group", "alert", alert, "route", route.Key())
+		return
+	}
+	limitedAlerts.WithLabelValues(route.RouteOpts.Receiver, limit, config.OverflowMerge).Inc()
+}
+
+// insert inserts the alert into the aggregation group of the route with
+// the given labels, which holds at most maxAlerts alerts. The group is
+// created unless the route already has maxGroups groups. It returns the
+// exceeded limit, or an empty string if the alert was inserted.
+//
+// The shard of the group stays locked until the alert is inserted, so that
+// the group cannot be removed as empty in between.
+func (d *Dispatcher) insert(alert *types.Alert, route *Route, labels model.LabelSet, fp model.Fingerprint, maxGroups, maxAlerts int) string {
+	s := d.shard(fp)
+
+	s.mtx.RLock()
+	ag, ok := s.groups[route][fp]
+	if ok {
+		defer s.mtx.RUnlock()
+		return insertLimit(ag, alert, maxAlerts)
+	}
+	s.mtx.RUnlock()
 
-	fp := groupLabels.Fingerprint()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
 
-	d.mtx.Lock()
-	group, ok := d.aggrGroups[route]
+	// Overflow groups are shared by the workers of all shards, so the
+	// group may have been created since the lookup.
+	ag, ok = d.aggrGroup(s, route, labels, fp, maxGroups)
 	if !ok {
-		group = map[model.Fingerprint]*aggrGroup{}
-		d.aggrGroups[route] = group
+		return "max_groups"
 	}
-	d.mtx.Unlock()
+	return insertLimit(ag, alert, maxAlerts)
+}
 
-	// If the group does not exist, create it.
-	ag, ok := group[fp]
-	if !ok {
-		ag = newAggrGroup(d.ctx, groupLabels, route, d.timeout, d.logger)
-		group[fp] = ag
+func insertLimit(ag *aggrGroup, alert *types.Alert, max int) string {
+	if !ag.insertLimited(alert, max) {
+		return "max_alerts_per_group"
+	}
+	return ""
+}
 
-		go ag.run(func(ctx context.Context, alerts ...*types.Alert) bool {
-			_, _, err := d.stage.Exec(ctx, d.logger, alerts...)
-			if err != nil {
-				level.Error(d.logger).Log("msg", "Notify for alerts failed", "num_alerts", len(alerts), "err", err)
-			}
-			return err == nil
-		})
+// aggrGroup returns the aggregation group of the route with the given
+// labels. If the group does not exist, it is created unless the route
+// already has max groups. A max of zero means no limit. The overflow group
+// does not count towards the limit. The shard must be locked.
+func (d *Dispatcher) aggrGroup(s *groupShard, route *Route, labels model.LabelSet, fp model.Fingerprint, max int) (*aggrGroup, bool) {
+	if ag, ok := s.groups[route][fp]; ok {
+		return ag, true
+	}
+	if fp != overflowFingerprint {
+		d.mtx.Lock()
+		if max > 0 && d.numGroups[route] >= max {
+			d.mtx.Unlock()
+			return nil, false
+		}
+		d.numGroups[route]++
+		d.mtx.Unlock()
 	}
 
-	ag.insert(alert)
+	ag := newAggrGroup(d.ctx, labels, route, d.timeout, d.logger)
+	ag.acks = d.acks
+
+	groups, ok := s.groups[route]
+	if !ok {
+		groups = map[model.Fingerprint]*aggrGroup{}
+		s.groups[route] = groups
+	}
+	groups[fp] = ag
+
+	go ag.run(func(ctx context.Context, alerts ...*types.Alert) bool {
+		_, _, err := d.stage.Exec(ctx, d.logger, alerts...)
+		if err != nil {
+			level.Error(d.logger).Log("msg", "Notify for alerts failed", "num_alerts", len(alerts), "err", err)
+		}
+		return err == nil
+	})
+
+	return ag, true
 }
 
 // aggrGroup aggregates alert fingerprints into groups to which a
@@ -293,6 +484,7 @@ type aggrGroup struct {
 	opts     *RouteOpts
 	logger   log.Logger
 	routeKey string
+	acks     *ack.Acks
 
 	ctx     context.Context
 	cancel  func()
@@ -313,7 +505,7 @@ func newAggrGroup(ctx context.Context, labels model.LabelSet, r *Route, to func(
 	ag := &aggrGroup{
 		labels:   labels,
 		routeKey: r.Key(),
-		opts:     &r.RouteOpts,
+		opts:     r.RouteOpts.WithTimerOverride(labels),
 		timeout:  to,
 		alerts:   map[model.Fingerprint]*types.Alert{},
 	}
@@ -373,8 +565,13 @@ func (ag *aggrGroup) run(nf notifyFunc) {
 			// Populate context with information needed along the pipeline.
 			ctx = notify.WithGroupKey(ctx, ag.GroupKey())
 			ctx = notify.WithGroupLabels(ctx, ag.labels)
-			ctx = notify.WithReceiverName(ctx, ag.opts.Receiver)
+			ctx = notify.WithReceiverName(ctx, ag.opts.SplitReceiver(ag.GroupKey()))
+			if ag.opts.FallbackReceiver != "" {
+				ctx = notify.WithFallbackReceiver(ctx, ag.opts.FallbackReceiver)
+			}
 			ctx = notify.WithRepeatInterval(ctx, ag.opts.RepeatInterval)
+			ctx = notify.WithMuteTimeIntervals(ctx, ag.opts.MuteTimeIntervals)
+			ctx = notify.WithActiveTimeIntervals(ctx, ag.opts.ActiveTimeIntervals)
 
 			// Wait the configured interval before calling flush again.
 			ag.mtx.Lock()
@@ -382,10 +579,31 @@ func (ag *aggrGroup) run(nf notifyFunc) {
 			ag.hasFlushed = true
 			ag.mtx.Unlock()
 
+			// Determine the escalated alerts before flushing, which removes
+			// resolved alerts from the group.
+			escalations := ag.escalations(now)
+
 			ag.flush(func(alerts ...*types.Alert) bool {
-				return nf(ctx, alerts...)
+				fctx, span := tracing.Start(ctx, "dispatch.flush",
+					tracing.AttrGroupKey, ag.GroupKey(),
+					tracing.AttrAlerts, strconv.Itoa(len(alerts)),
+				)
+				defer span.Finish()
+
+				if ag.opts.ReceiverTemplate != nil {
+					fctx = notify.WithReceiverName(fctx, ag.opts.TemplateReceiver(ag.labels, alerts...))
+					fctx = notify.WithDefaultReceiver(fctx, ag.opts.DefaultReceiver)
+				}
+				if recv, ok := notify.ReceiverName(fctx); ok {
+					span.SetAttribute(tracing.AttrReceiver, recv)
+				}
+				return nf(fctx, alerts...)
 			})
 
+			for i, alerts := range escalations {
+				nf(notify.WithReceiverName(ctx, ag.opts.Escalation[i].Receiver), alerts...)
+			}
+
 			cancel()
 
 		case <-ag.ctx.Done():
@@ -403,16 +621,29 @@ func (ag *aggrGroup) stop() {
 
 // insert inserts the alert into the aggregation group.
 func (ag *aggrGroup) insert(alert *types.Alert) {
+	ag.insertLimited(alert, 0)
+}
+
+// insertLimited inserts the alert into the aggregation group unless the
+// alert is not part of the group yet and the group already holds max alerts.
+// A max of zero means no limit. It returns false iff the alert was not
+// inserted.
+func (ag *aggrGroup) insertLimited(alert *types.Alert, max int) bool {
 	ag.mtx.Lock()
 	defer ag.mtx.Unlock()
 
-	ag.alerts[alert.Fingerprint()] = alert
+	fp := alert.Fingerprint()
+	if _, ok := ag.alerts[fp]; !ok && max > 0 && len(ag.alerts) >= max {
+		return false
+	}
+	ag.alerts[fp] = alert
 
 	// Immediately trigger a flush if the wait duration for this
 	// alert is already over.
 	if !ag.hasFlushed && alert.StartsAt.Add(ag.opts.GroupWait).Before(time.Now()) {
 		ag.next.Reset(0)
 	}
+	return true
 }
 
 func (ag *aggrGroup) empty() bool {
@@ -422,6 +653,40 @@ func (ag *aggrGroup) empty() bool {
 	return len(ag.alerts) == 0
 }
 
+// escalations returns the alerts to notify each escalation receiver about.
+// An alert is escalated once it has been firing unacknowledged for longer
+// than the duration of the escalation step. Resolved alerts are included if
+// they had been escalated before they resolved.
+func (ag *aggrGroup) escalations(now time.Time) [][]*types.Alert {
+	if len(ag.opts.Escalation) == 0 {
+		return nil
+	}
+	ag.mtx.RLock()
+	defer ag.mtx.RUnlock()
+
+	res := make([][]*types.Alert, len(ag.opts.Escalation))
+	for i, e := range ag.opts.Escalation {
+		for _, a := range ag.alerts {
+			if ag.acks != nil {
+				if _, ok := ag.acks.Acknowledged(&a.Alert); ok {
+					continue
+				}
+			}
+			end := now
+			if a.ResolvedAt(now) {
+				end = a.EndsAt
+			}
+			if end.Sub(a.StartsAt) >= e.After {
+				res[i] = append(res[i], a)
+			}
+		}
+		sort.Slice(res[i], func(j, k int) bool {
+			return res[i][j].Labels.Before(res[i][k].Labels)
+		})
+	}
+	return res
+}
+
 // flush sends notifications for all new alerts.
 func (ag *aggrGroup) flush(notify func(...*types.Alert) bool) {
 	if ag.empty() {
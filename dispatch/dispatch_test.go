@@ -14,6 +14,7 @@
 package dispatch
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
 	"sync"
@@ -21,11 +22,16 @@ import (
 	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/model"
-	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/provider/mem"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -315,3 +321,355 @@ func TestAggrGroup(t *testing.T) {
 
 	ag.stop()
 }
+
+func TestAggrGroupEscalations(t *testing.T) {
+	now := time.Now()
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:  "n1",
+			GroupWait: time.Second,
+			Escalation: []Escalation{
+				{After: 10 * time.Minute, Receiver: "n2"},
+				{After: time.Hour, Receiver: "n3"},
+			},
+		},
+	}
+	ag := newAggrGroup(context.Background(), model.LabelSet{}, route, nil, log.NewNopLogger())
+
+	// Firing for 5 minutes.
+	a1 := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"a": "v1"},
+		StartsAt: now.Add(-5 * time.Minute),
+		EndsAt:   now.Add(time.Hour),
+	}}
+	// Firing for 30 minutes.
+	a2 := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"a": "v2"},
+		StartsAt: now.Add(-30 * time.Minute),
+		EndsAt:   now.Add(time.Hour),
+	}}
+	// Resolved after 20 minutes.
+	a3 := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"a": "v3"},
+		StartsAt: now.Add(-2 * time.Hour),
+		EndsAt:   now.Add(-100 * time.Minute),
+	}}
+	for _, a := range []*types.Alert{a1, a2, a3} {
+		ag.insert(a)
+	}
+
+	expected := [][]*types.Alert{{a2, a3}, nil}
+	if res := ag.escalations(now); !reflect.DeepEqual(res, expected) {
+		t.Fatalf("expected escalations %v, got %v", expected, res)
+	}
+}
+
+func TestGroupLimits(t *testing.T) {
+	newAlert := func(group, instance string) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"group": model.LabelValue(group), "instance": model.LabelValue(instance)},
+				StartsAt: time.Now().Add(time.Minute),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+	}
+	alertCount := func(ag *aggrGroup) int {
+		if ag == nil {
+			return 0
+		}
+		ag.mtx.RLock()
+		defer ag.mtx.RUnlock()
+		return len(ag.alerts)
+	}
+
+	for _, overflow := range []string{config.OverflowReject, config.OverflowMerge} {
+		route := &Route{
+			RouteOpts: RouteOpts{
+				Receiver:       "n1",
+				GroupBy:        map[model.LabelName]struct{}{"group": {}},
+				GroupWait:      time.Hour,
+				GroupInterval:  time.Hour,
+				RepeatInterval: time.Hour,
+				GroupLimits: GroupLimits{
+					MaxGroups:         2,
+					MaxAlertsPerGroup: 2,
+					Overflow:          overflow,
+				},
+			},
+		}
+		stage := notify.StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+			return ctx, alerts, nil
+		})
+		d := NewDispatcher(nil, route, stage, nil, nil, nil, log.NewNopLogger())
+		d.ctx, d.cancel = context.WithCancel(context.Background())
+
+		for _, a := range []*types.Alert{
+			newAlert("a", "1"),
+			newAlert("a", "2"),
+			// Exceeds the alerts per group.
+			newAlert("a", "3"),
+			// Updates of existing alerts are not limited.
+			newAlert("a", "1"),
+			newAlert("b", "1"),
+			// Exceed the number of groups. Only the first one fits into
+			// the overflow group.
+			newAlert("c", "1"),
+			newAlert("d", "1"),
+			newAlert("e", "1"),
+		} {
+			d.processAlert(a, route)
+		}
+
+		groups := routeGroups(d, route)
+		fp := func(group string) model.Fingerprint {
+			return model.LabelSet{"group": model.LabelValue(group)}.Fingerprint()
+		}
+		require.Equal(t, 2, alertCount(groups[fp("a")]))
+		require.Equal(t, 1, alertCount(groups[fp("b")]))
+		require.Nil(t, groups[fp("c")])
+
+		if overflow == config.OverflowMerge {
+			require.Len(t, groups, 3)
+			require.Equal(t, overflowLabels, groups[overflowFingerprint].labels)
+			require.Equal(t, 2, alertCount(groups[overflowFingerprint]))
+		} else {
+			require.Len(t, groups, 2)
+		}
+
+		// Stops the aggregation groups.
+		d.cancel()
+	}
+
+	var m dto.Metric
+	require.NoError(t, limitedAlerts.WithLabelValues("n1", "max_groups", config.OverflowReject).Write(&m))
+	require.Equal(t, 3.0, m.GetCounter().GetValue())
+	require.NoError(t, limitedAlerts.WithLabelValues("n1", "max_groups", config.OverflowMerge).Write(&m))
+	require.Equal(t, 1.0, m.GetCounter().GetValue())
+	// Alerts not fitting into the overflow group are rejected.
+	require.NoError(t, limitedAlerts.WithLabelValues("n1", "max_alerts_per_group", config.OverflowReject).Write(&m))
+	require.Equal(t, 3.0, m.GetCounter().GetValue())
+}
+
+// routeGroups returns the aggregation groups of the route in all shards.
+func routeGroups(d *Dispatcher, route *Route) map[model.Fingerprint]*aggrGroup {
+	res := map[model.Fingerprint]*aggrGroup{}
+	d.forEachGroup(func(r *Route, ag *aggrGroup) {
+		if r == route {
+			res[ag.fingerprint()] = ag
+		}
+	})
+	return res
+}
+
+func TestGroupsCollector(t *testing.T) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{"group": {}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	stage := notify.StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, alerts, nil
+	})
+
+	var d *Dispatcher
+	c := NewGroupsCollector(func() *Dispatcher { return d })
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(c))
+
+	// Nothing is exported without a dispatcher.
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	require.Len(t, mfs, 0)
+
+	d = NewDispatcher(nil, route, stage, nil, nil, nil, log.NewNopLogger())
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+
+	for _, lset := range []model.LabelSet{
+		{"group": "a", "instance": "1"},
+		{"group": "a", "instance": "2"},
+		{"group": "a", "instance": "3"},
+		{"group": "b", "instance": "1"},
+	} {
+		d.processAlert(&types.Alert{Alert: model.Alert{Labels: lset, StartsAt: time.Now().Add(time.Minute)}}, route)
+	}
+
+	mfs, err = reg.Gather()
+	require.NoError(t, err)
+	values := map[string]float64{}
+	for _, mf := range mfs {
+		require.Len(t, mf.GetMetric(), 1)
+		m := mf.GetMetric()[0]
+		for _, lp := range m.GetLabel() {
+			if lp.GetName() == "receiver" {
+				require.Equal(t, "n1", lp.GetValue())
+			}
+		}
+		values[mf.GetName()] = m.GetGauge().GetValue()
+	}
+	require.Equal(t, map[string]float64{
+		"alertmanager_dispatcher_aggregation_groups":           2,
+		"alertmanager_dispatcher_aggregation_group_alerts":     4,
+		"alertmanager_dispatcher_aggregation_group_max_alerts": 3,
+	}, values)
+}
+
+func TestDispatcherShards(t *testing.T) {
+	marker := types.NewMarker()
+	alerts, err := mem.NewAlerts(marker, time.Hour)
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{"group": {}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+			GroupLimits:    GroupLimits{MaxGroups: 50},
+		},
+	}
+	stage := notify.StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, alerts, nil
+	})
+	d := NewDispatcher(alerts, route, stage, marker, nil, nil, log.NewNopLogger())
+	go d.Run()
+	defer d.Stop()
+
+	for i := 0; i < 100; i++ {
+		for j := 0; j < 3; j++ {
+			require.NoError(t, alerts.Put(&types.Alert{Alert: model.Alert{
+				Labels:   model.LabelSet{"group": model.LabelValue(fmt.Sprint(i)), "instance": model.LabelValue(fmt.Sprint(j))},
+				StartsAt: time.Now(),
+				EndsAt:   time.Now().Add(time.Hour),
+			}}))
+		}
+	}
+
+	// The groups are distributed over the shards and the limit of groups
+	// applies across all of them.
+	numAlerts := func() int {
+		n := 0
+		for _, ag := range routeGroups(d, route) {
+			ag.mtx.RLock()
+			n += len(ag.alerts)
+			ag.mtx.RUnlock()
+		}
+		return n
+	}
+	for i := 0; i < 500 && numAlerts() < 150; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, 150, numAlerts())
+	require.Len(t, routeGroups(d, route), 50)
+
+	var used int
+	for _, s := range d.shards {
+		s.mtx.RLock()
+		if len(s.groups) > 0 {
+			used++
+		}
+		s.mtx.RUnlock()
+	}
+	require.True(t, used > 1, "groups are not sharded")
+}
+
+func TestDispatcherCleanupRace(t *testing.T) {
+	marker := types.NewMarker()
+	alerts, err := mem.NewAlerts(marker, time.Hour)
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{"group": {}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	stage := notify.StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, alerts, nil
+	})
+	d := NewDispatcher(alerts, route, stage, marker, nil, nil, log.NewNopLogger())
+	d.cleanupInterval = time.Millisecond
+	go d.Run()
+	defer d.Stop()
+
+	const n = 1000
+	for i := 0; i < n; i++ {
+		require.NoError(t, alerts.Put(&types.Alert{Alert: model.Alert{
+			Labels:   model.LabelSet{"group": model.LabelValue(fmt.Sprint(i))},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		}}))
+	}
+
+	// Groups created for new alerts are never removed as empty before the
+	// alerts are inserted.
+	numAlerts := func() int {
+		n := 0
+		for _, ag := range routeGroups(d, route) {
+			ag.mtx.RLock()
+			n += len(ag.alerts)
+			ag.mtx.RUnlock()
+		}
+		return n
+	}
+	for i := 0; i < 500 && numAlerts() < n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, n, numAlerts())
+}
+
+// BenchmarkProcessAlert measures inserting alerts concurrently from
+// multiple goroutines into a dispatcher holding 1M active alerts.
+func BenchmarkProcessAlert(b *testing.B) {
+	route := &Route{
+		RouteOpts: RouteOpts{
+			Receiver:       "n1",
+			GroupBy:        map[model.LabelName]struct{}{"group": {}},
+			GroupWait:      time.Hour,
+			GroupInterval:  time.Hour,
+			RepeatInterval: time.Hour,
+		},
+	}
+	stage := notify.StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, alerts, nil
+	})
+	d := NewDispatcher(nil, route, stage, types.NewMarker(), nil, nil, log.NewNopLogger())
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+
+	const numGroups, numAlerts = 10000, 1000000
+	newAlert := func(i int) *types.Alert {
+		return &types.Alert{Alert: model.Alert{
+			Labels: model.LabelSet{
+				"group":    model.LabelValue(fmt.Sprint(i % numGroups)),
+				"instance": model.LabelValue(fmt.Sprint(i)),
+			},
+			StartsAt: time.Now(),
+		}}
+	}
+	alerts := make([]*types.Alert, numAlerts)
+	for i := range alerts {
+		alerts[i] = newAlert(i)
+		d.processAlert(alerts[i], route)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			d.processAlert(alerts[i%numAlerts], route)
+			i += 7919
+		}
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatch
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	aggrGroupsDesc = prometheus.NewDesc(
+		"alertmanager_dispatcher_aggregation_groups",
+		"Number of active aggregation groups per route.",
+		[]string{"route", "receiver"}, nil,
+	)
+	aggrGroupAlertsDesc = prometheus.NewDesc(
+		"alertmanager_dispatcher_aggregation_group_alerts",
+		"Number of alerts in the aggregation groups per route.",
+		[]string{"route", "receiver"}, nil,
+	)
+	aggrGroupMaxAlertsDesc = prometheus.NewDesc(
+		"alertmanager_dispatcher_aggregation_group_max_alerts",
+		"Number of alerts in the largest aggregation group per route.",
+		[]string{"route", "receiver"}, nil,
+	)
+)
+
+type groupsCollector struct {
+	dispatcher func() *Dispatcher
+}
+
+// NewGroupsCollector returns a collector exporting the aggregation groups
+// of the dispatcher returned by the given function, which may change when
+// the configuration is reloaded.
+func NewGroupsCollector(dispatcher func() *Dispatcher) prometheus.Collector {
+	return &groupsCollector{dispatcher: dispatcher}
+}
+
+// Describe implements prometheus.Collector.
+func (c *groupsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- aggrGroupsDesc
+	ch <- aggrGroupAlertsDesc
+	ch <- aggrGroupMaxAlertsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *groupsCollector) Collect(ch chan<- prometheus.Metric) {
+	d := c.dispatcher()
+	if d == nil {
+		return
+	}
+
+	// Route keys are not unique, so the metrics of routes with the same
+	// key and receiver are combined.
+	type routeID struct {
+		key, receiver string
+	}
+	type routeStats struct {
+		groups, alerts, maxAlerts int
+	}
+	stats := map[routeID]*routeStats{}
+
+	d.forEachGroup(func(route *Route, ag *aggrGroup) {
+		id := routeID{key: route.Key(), receiver: route.RouteOpts.Receiver}
+		s, ok := stats[id]
+		if !ok {
+			s = &routeStats{}
+			stats[id] = s
+		}
+		ag.mtx.RLock()
+		n := len(ag.alerts)
+		ag.mtx.RUnlock()
+
+		s.groups++
+		s.alerts += n
+		if n > s.maxAlerts {
+			s.maxAlerts = n
+		}
+	})
+
+	for id, s := range stats {
+		ch <- prometheus.MustNewConstMetric(aggrGroupsDesc, prometheus.GaugeValue, float64(s.groups), id.key, id.receiver)
+		ch <- prometheus.MustNewConstMetric(aggrGroupAlertsDesc, prometheus.GaugeValue, float64(s.alerts), id.key, id.receiver)
+		ch <- prometheus.MustNewConstMetric(aggrGroupMaxAlertsDesc, prometheus.GaugeValue, float64(s.maxAlerts), id.key, id.receiver)
+	}
+}
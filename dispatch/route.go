@@ -16,7 +16,12 @@ package dispatch
 import (
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"regexp"
 	"sort"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/prometheus/common/model"
@@ -48,6 +53,14 @@ type Route struct {
 	// If true, an alert matches further routes on the same level.
 	Continue bool
 
+	// If positive, no further child routes are matched once as many of
+	// them matched.
+	MaxMatches int
+
+	// If true, no further routes are matched at all once the route
+	// matched.
+	Stop bool
+
 	// Children routes of this route.
 	Routes []*Route
 }
@@ -62,12 +75,32 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 
 	if cr.Receiver != "" {
 		opts.Receiver = cr.Receiver
+		opts.ReceiverTemplate = cr.ReceiverTemplate
+		opts.DefaultReceiver = cr.DefaultReceiver
+		opts.Split = nil
+	}
+	if cr.Split != nil {
+		opts.Split = &ReceiverSplit{By: cr.Split.By}
+		for _, r := range cr.Split.Receivers {
+			opts.Split.Receivers = append(opts.Split.Receivers, WeightedReceiver{
+				Receiver: r.Receiver,
+				Percent:  r.Percent,
+			})
+		}
+	}
+	if cr.FallbackReceiver != "" {
+		opts.FallbackReceiver = cr.FallbackReceiver
 	}
-	if cr.GroupBy != nil {
+	if cr.GroupByStr != nil || cr.GroupByRegex != nil {
 		opts.GroupBy = map[model.LabelName]struct{}{}
 		for _, ln := range cr.GroupBy {
 			opts.GroupBy[ln] = struct{}{}
 		}
+		opts.GroupByAll = cr.GroupByAll
+		opts.GroupByRegex = nil
+		if cr.GroupByRegex != nil {
+			opts.GroupByRegex = cr.GroupByRegex.Regexp
+		}
 	}
 	if cr.GroupWait != nil {
 		opts.GroupWait = time.Duration(*cr.GroupWait)
@@ -78,6 +111,40 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 	if cr.RepeatInterval != nil {
 		opts.RepeatInterval = time.Duration(*cr.RepeatInterval)
 	}
+	if cr.Escalation != nil {
+		opts.Escalation = nil
+		for _, e := range cr.Escalation {
+			opts.Escalation = append(opts.Escalation, Escalation{
+				After:    time.Duration(e.After),
+				Receiver: e.Receiver,
+			})
+		}
+	}
+	if cr.GroupLimits != nil {
+		opts.GroupLimits = GroupLimits{
+			MaxGroups:         cr.GroupLimits.MaxGroups,
+			MaxAlertsPerGroup: cr.GroupLimits.MaxAlertsPerGroup,
+			Overflow:          cr.GroupLimits.Overflow,
+		}
+	}
+
+	// Time intervals and timer overrides are not inherited.
+	opts.MuteTimeIntervals = cr.MuteTimeIntervals
+	opts.ActiveTimeIntervals = cr.ActiveTimeIntervals
+	opts.TimerOverrides = nil
+	for _, o := range cr.TimerOverrides {
+		to := TimerOverride{Matchers: types.Matchers(o.Matchers)}
+		if o.GroupWait != nil {
+			to.GroupWait = time.Duration(*o.GroupWait)
+		}
+		if o.GroupInterval != nil {
+			to.GroupInterval = time.Duration(*o.GroupInterval)
+		}
+		if o.RepeatInterval != nil {
+			to.RepeatInterval = time.Duration(*o.RepeatInterval)
+		}
+		opts.TimerOverrides = append(opts.TimerOverrides, to)
+	}
 
 	// Build matchers.
 	var matchers types.Matchers
@@ -88,13 +155,16 @@ func NewRoute(cr *config.Route, parent *Route) *Route {
 	for ln, lv := range cr.MatchRE {
 		matchers = append(matchers, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
 	}
+	matchers = append(matchers, cr.Matchers...)
 	sort.Sort(matchers)
 
 	route := &Route{
-		parent:    parent,
-		RouteOpts: opts,
-		Matchers:  matchers,
-		Continue:  cr.Continue,
+		parent:     parent,
+		RouteOpts:  opts,
+		Matchers:   matchers,
+		Continue:   cr.Continue,
+		MaxMatches: cr.MaxMatches,
+		Stop:       cr.Stop,
 	}
 
 	route.Routes = NewRoutes(cr.Routes, route)
@@ -114,18 +184,35 @@ func NewRoutes(croutes []*config.Route, parent *Route) []*Route {
 // Match does a depth-first left-to-right search through the route tree
 // and returns the matching routing nodes.
 func (r *Route) Match(lset model.LabelSet) []*Route {
+	all, _ := r.match(lset)
+	return all
+}
+
+// match returns the matching routing nodes and whether a matching route
+// stopped the evaluation of the routing tree.
+func (r *Route) match(lset model.LabelSet) ([]*Route, bool) {
 	if !r.Matchers.Match(lset) {
-		return nil
+		return nil, false
 	}
 
-	var all []*Route
+	var (
+		all     []*Route
+		matched int
+	)
 
 	for _, cr := range r.Routes {
-		matches := cr.Match(lset)
+		matches, stop := cr.match(lset)
 
 		all = append(all, matches...)
 
-		if matches != nil && !cr.Continue {
+		if stop {
+			return all, true
+		}
+		if matches == nil {
+			continue
+		}
+		matched++
+		if !cr.Continue || (r.MaxMatches > 0 && matched >= r.MaxMatches) {
 			break
 		}
 	}
@@ -135,7 +222,28 @@ func (r *Route) Match(lset model.LabelSet) []*Route {
 		all = append(all, r)
 	}
 
-	return all
+	return all, r.Stop
+}
+
+// Path returns the routes from the root of the routing tree to the route.
+func (r *Route) Path() []*Route {
+	if r.parent == nil {
+		return []*Route{r}
+	}
+	return append(r.parent.Path(), r)
+}
+
+// Index returns the position of the route among the child routes of its
+// parent, or -1 for the root route.
+func (r *Route) Index() int {
+	if r.parent != nil {
+		for i, cr := range r.parent.Routes {
+			if cr == r {
+				return i
+			}
+		}
+	}
+	return -1
 }
 
 // Key returns a key for the route. It does not uniquely identify a the route in general.
@@ -155,14 +263,86 @@ type RouteOpts struct {
 	// The identifier of the associated notification configuration.
 	Receiver string
 
-	// What labels to group alerts by for notifications.
-	GroupBy map[model.LabelName]struct{}
+	// If set, the receiver is computed from the template and the labels
+	// of the notified alerts. The default receiver is notified if no
+	// receiver of the computed name exists.
+	ReceiverTemplate *template.Template
+	DefaultReceiver  string
+
+	// The receiver notified instead if notifying Receiver fails.
+	FallbackReceiver string
+
+	// Distribution of notifications among Receiver and other receivers.
+	// Split is reset by routes setting a different receiver.
+	Split *ReceiverSplit
+
+	// What labels to group alerts by for notifications. If GroupByAll is
+	// set, alerts are grouped by all of their labels. Labels whose name
+	// matches GroupByRegex are grouped by in addition to GroupBy.
+	GroupBy      map[model.LabelName]struct{}
+	GroupByAll   bool
+	GroupByRegex *regexp.Regexp
 
 	// How long to wait to group matching alerts before sending
 	// a notification.
 	GroupWait      time.Duration
 	GroupInterval  time.Duration
 	RepeatInterval time.Duration
+
+	// Timers replacing the above for aggregation groups with matching
+	// labels.
+	TimerOverrides []TimerOverride
+
+	// Receivers to notify about alerts that remain firing.
+	Escalation []Escalation
+
+	// Limits of the number of aggregation groups and alerts per group.
+	GroupLimits GroupLimits
+
+	// The names of the time intervals during which notifications are muted
+	// and outside of which notifications are muted.
+	MuteTimeIntervals   []string
+	ActiveTimeIntervals []string
+}
+
+// Escalation notifies a receiver about alerts that have been firing for
+// longer than a duration.
+type Escalation struct {
+	After    time.Duration `json:"after"`
+	Receiver string        `json:"receiver"`
+}
+
+// GroupLimits limit the number of aggregation groups of a route and the
+// number of alerts per group. Zero values mean no limit. Alerts exceeding
+// the limits are dropped or, if the overflow policy is merge, added to a
+// catch-all group of the route.
+type GroupLimits struct {
+	MaxGroups         int    `json:"maxGroups,omitempty"`
+	MaxAlertsPerGroup int    `json:"maxAlertsPerGroup,omitempty"`
+	Overflow          string `json:"overflow,omitempty"`
+}
+
+// TimerOverride replaces the timers of aggregation groups whose labels match
+// all matchers. Zero durations are not replaced.
+type TimerOverride struct {
+	Matchers       types.Matchers `json:"matchers"`
+	GroupWait      time.Duration  `json:"groupWait,omitempty"`
+	GroupInterval  time.Duration  `json:"groupInterval,omitempty"`
+	RepeatInterval time.Duration  `json:"repeatInterval,omitempty"`
+}
+
+// ReceiverSplit sends a percentage of the notifications of a route to
+// other receivers than the receiver of the route.
+type ReceiverSplit struct {
+	By        string             `json:"by"`
+	Receivers []WeightedReceiver `json:"receivers"`
+}
+
+// WeightedReceiver is a receiver getting a percentage of the notifications
+// of a route.
+type WeightedReceiver struct {
+	Receiver string `json:"receiver"`
+	Percent  int    `json:"percent"`
 }
 
 func (ro *RouteOpts) String() string {
@@ -170,26 +350,147 @@ func (ro *RouteOpts) String() string {
 	for ln := range ro.GroupBy {
 		labels = append(labels, ln)
 	}
-	return fmt.Sprintf("<RouteOpts send_to:%q group_by:%q timers:%q|%q>", ro.Receiver, labels, ro.GroupWait, ro.GroupInterval)
+	return fmt.Sprintf("<RouteOpts send_to:%q group_by:%q group_by_all:%t timers:%q|%q>", ro.Receiver, labels, ro.GroupByAll, ro.GroupWait, ro.GroupInterval)
+}
+
+// GroupLabels returns the labels of an alert that its aggregation group is
+// identified by.
+func (ro *RouteOpts) GroupLabels(lset model.LabelSet) model.LabelSet {
+	groupLabels := model.LabelSet{}
+
+	for ln, lv := range lset {
+		_, ok := ro.GroupBy[ln]
+		if ok || ro.GroupByAll || (ro.GroupByRegex != nil && ro.GroupByRegex.MatchString(string(ln))) {
+			groupLabels[ln] = lv
+		}
+	}
+	return groupLabels
+}
+
+// WithTimerOverride returns the routing options for an aggregation group
+// with the given labels. If a timer override matches, a copy with the timers
+// replaced is returned.
+func (ro *RouteOpts) WithTimerOverride(groupLabels model.LabelSet) *RouteOpts {
+	for _, o := range ro.TimerOverrides {
+		if !o.Matchers.Match(groupLabels) {
+			continue
+		}
+		opts := *ro
+		if o.GroupWait != 0 {
+			opts.GroupWait = o.GroupWait
+		}
+		if o.GroupInterval != 0 {
+			opts.GroupInterval = o.GroupInterval
+		}
+		if o.RepeatInterval != 0 {
+			opts.RepeatInterval = o.RepeatInterval
+		}
+		return &opts
+	}
+	return ro
+}
+
+// SplitReceiver returns the receiver to notify about the aggregation group
+// with the given key.
+func (ro *RouteOpts) SplitReceiver(groupKey string) string {
+	if ro.Split == nil {
+		return ro.Receiver
+	}
+	var n int
+	if ro.Split.By == config.SplitByRandom {
+		n = rand.Intn(100)
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(groupKey))
+		n = int(h.Sum64() % 100)
+	}
+	for _, r := range ro.Split.Receivers {
+		if n < r.Percent {
+			return r.Receiver
+		}
+		n -= r.Percent
+	}
+	return ro.Receiver
+}
+
+// TemplateReceiver returns the receiver to notify about the given alerts of
+// the aggregation group with the given labels. Without a receiver template,
+// the receiver of the routing options is returned.
+func (ro *RouteOpts) TemplateReceiver(groupLabels model.LabelSet, alerts ...*types.Alert) string {
+	if ro.ReceiverTemplate == nil {
+		return ro.Receiver
+	}
+	data := struct {
+		GroupLabels  map[string]string
+		CommonLabels map[string]string
+	}{
+		GroupLabels:  map[string]string{},
+		CommonLabels: map[string]string{},
+	}
+	for ln, lv := range groupLabels {
+		data.GroupLabels[string(ln)] = string(lv)
+	}
+	if len(alerts) > 0 {
+		for ln, lv := range alerts[0].Labels {
+			data.CommonLabels[string(ln)] = string(lv)
+		}
+		for _, a := range alerts[1:] {
+			for ln, lv := range data.CommonLabels {
+				if string(a.Labels[model.LabelName(ln)]) != lv {
+					delete(data.CommonLabels, ln)
+				}
+			}
+		}
+	}
+
+	var buf strings.Builder
+	if err := ro.ReceiverTemplate.Execute(&buf, data); err != nil {
+		return ro.DefaultReceiver
+	}
+	return buf.String()
 }
 
 // MarshalJSON returns a JSON representation of the routing options.
 func (ro *RouteOpts) MarshalJSON() ([]byte, error) {
 	v := struct {
-		Receiver       string           `json:"receiver"`
-		GroupBy        model.LabelNames `json:"groupBy"`
-		GroupWait      time.Duration    `json:"groupWait"`
-		GroupInterval  time.Duration    `json:"groupInterval"`
-		RepeatInterval time.Duration    `json:"repeatInterval"`
+		Receiver            string           `json:"receiver"`
+		DefaultReceiver     string           `json:"defaultReceiver,omitempty"`
+		FallbackReceiver    string           `json:"fallbackReceiver,omitempty"`
+		Split               *ReceiverSplit   `json:"split,omitempty"`
+		GroupBy             model.LabelNames `json:"groupBy"`
+		GroupByAll          bool             `json:"groupByAll,omitempty"`
+		GroupByRegex        string           `json:"groupByRegex,omitempty"`
+		GroupWait           time.Duration    `json:"groupWait"`
+		GroupInterval       time.Duration    `json:"groupInterval"`
+		RepeatInterval      time.Duration    `json:"repeatInterval"`
+		TimerOverrides      []TimerOverride  `json:"timerOverrides,omitempty"`
+		Escalation          []Escalation     `json:"escalation,omitempty"`
+		GroupLimits         *GroupLimits     `json:"groupLimits,omitempty"`
+		MuteTimeIntervals   []string         `json:"muteTimeIntervals,omitempty"`
+		ActiveTimeIntervals []string         `json:"activeTimeIntervals,omitempty"`
 	}{
-		Receiver:       ro.Receiver,
-		GroupWait:      ro.GroupWait,
-		GroupInterval:  ro.GroupInterval,
-		RepeatInterval: ro.RepeatInterval,
+		Receiver:            ro.Receiver,
+		DefaultReceiver:     ro.DefaultReceiver,
+		FallbackReceiver:    ro.FallbackReceiver,
+		Split:               ro.Split,
+		GroupByAll:          ro.GroupByAll,
+		GroupWait:           ro.GroupWait,
+		GroupInterval:       ro.GroupInterval,
+		RepeatInterval:      ro.RepeatInterval,
+		TimerOverrides:      ro.TimerOverrides,
+		Escalation:          ro.Escalation,
+		MuteTimeIntervals:   ro.MuteTimeIntervals,
+		ActiveTimeIntervals: ro.ActiveTimeIntervals,
 	}
 	for ln := range ro.GroupBy {
 		v.GroupBy = append(v.GroupBy, ln)
 	}
+	if ro.GroupByRegex != nil {
+		v.GroupByRegex = ro.GroupByRegex.String()
+	}
+	if ro.GroupLimits != (GroupLimits{}) {
+		v.GroupLimits = &ro.GroupLimits
+	}
 
 	return json.Marshal(&v)
 }
@@ -14,14 +14,20 @@
 package dispatch
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-kit/kit/log"
 	"github.com/prometheus/common/model"
 	"gopkg.in/yaml.v2"
 
 	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
 )
 
 func TestRouteMatch(t *testing.T) {
@@ -258,3 +264,426 @@ routes:
 		}
 	}
 }
+
+func TestRouteMatchers(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+
+routes:
+- matchers: ['absent(owner)', 'severity>=3']
+  receiver: 'notify-unowned'
+
+- matchers: ['present(owner)']
+  receiver: 'notify-owner'
+  match:
+    env: 'production'
+`
+
+	var ctree config.Route
+	if err := yaml.UnmarshalStrict([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	tests := []struct {
+		input    model.LabelSet
+		receiver string
+		key      string
+	}{
+		{
+			input:    model.LabelSet{"severity": "4"},
+			receiver: "notify-unowned",
+			key:      `{}/{absent(owner),severity>="3"}`,
+		},
+		{
+			input:    model.LabelSet{"severity": "2"},
+			receiver: "notify-def",
+			key:      `{}`,
+		},
+		{
+			input:    model.LabelSet{"severity": "critical"},
+			receiver: "notify-def",
+			key:      `{}`,
+		},
+		{
+			input:    model.LabelSet{"owner": "team-A", "env": "production"},
+			receiver: "notify-owner",
+			key:      `{}/{env="production",present(owner)}`,
+		},
+	}
+
+	for _, test := range tests {
+		matches := tree.Match(test.input)
+		if len(matches) != 1 {
+			t.Fatalf("expected one route for %v, got %d", test.input, len(matches))
+		}
+		if matches[0].RouteOpts.Receiver != test.receiver {
+			t.Errorf("expected receiver %q for %v, got %q", test.receiver, test.input, matches[0].RouteOpts.Receiver)
+		}
+		if matches[0].Key() != test.key {
+			t.Errorf("expected key %q for %v, got %q", test.key, test.input, matches[0].Key())
+		}
+	}
+}
+
+func TestRouteTimeIntervals(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+
+routes:
+- match:
+    severity: 'warning'
+  mute_time_intervals: ['offhours']
+
+  routes:
+  - match:
+      team: 'db'
+
+- match:
+    severity: 'critical'
+  active_time_intervals: ['oncall']
+`
+
+	var ctree config.Route
+	if err := yaml.UnmarshalStrict([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	tests := []struct {
+		input     model.LabelSet
+		intervals []string
+		active    []string
+	}{
+		{
+			input:     model.LabelSet{"severity": "warning"},
+			intervals: []string{"offhours"},
+		},
+		{
+			input:  model.LabelSet{"severity": "critical"},
+			active: []string{"oncall"},
+		},
+		{
+			// Mute time intervals are not inherited.
+			input:     model.LabelSet{"severity": "warning", "team": "db"},
+			intervals: nil,
+		},
+		{
+			input:     model.LabelSet{"severity": "info"},
+			intervals: nil,
+		},
+	}
+
+	for _, test := range tests {
+		matches := tree.Match(test.input)
+		if len(matches) != 1 {
+			t.Fatalf("expected one route for %v, got %d", test.input, len(matches))
+		}
+		if !reflect.DeepEqual(matches[0].RouteOpts.MuteTimeIntervals, test.intervals) {
+			t.Errorf("expected mute time intervals %v for %v, got %v", test.intervals, test.input, matches[0].RouteOpts.MuteTimeIntervals)
+		}
+		if !reflect.DeepEqual(matches[0].RouteOpts.ActiveTimeIntervals, test.active) {
+			t.Errorf("expected active time intervals %v for %v, got %v", test.active, test.input, matches[0].RouteOpts.ActiveTimeIntervals)
+		}
+	}
+}
+
+func TestRouteGroupBy(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+group_by: ['...']
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+  group_by: ['alertname']
+  group_by_regex: 'team_.+'
+
+- match:
+    owner: 'team-B'
+  receiver: 'notify-B'
+`
+
+	var ctree config.Route
+	if err := yaml.UnmarshalStrict([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	for _, tc := range []struct {
+		input  model.LabelSet
+		result model.LabelSet
+	}{
+		{
+			input:  model.LabelSet{"owner": "team-C", "alertname": "a", "instance": "i"},
+			result: model.LabelSet{"owner": "team-C", "alertname": "a", "instance": "i"},
+		},
+		{
+			input:  model.LabelSet{"owner": "team-A", "alertname": "a", "instance": "i", "team_region": "eu", "team_service": "db"},
+			result: model.LabelSet{"alertname": "a", "team_region": "eu", "team_service": "db"},
+		},
+		{
+			// Grouping by all labels is inherited.
+			input:  model.LabelSet{"owner": "team-B", "instance": "i"},
+			result: model.LabelSet{"owner": "team-B", "instance": "i"},
+		},
+	} {
+		routes := tree.Match(tc.input)
+		if len(routes) != 1 {
+			t.Fatalf("expected one matching route for %v, got %d", tc.input, len(routes))
+		}
+		if res := routes[0].RouteOpts.GroupLabels(tc.input); !reflect.DeepEqual(res, tc.result) {
+			t.Errorf("\nexpected:\n%v\ngot:\n%v", tc.result, res)
+		}
+	}
+
+	b, err := json.Marshal(&tree.Routes[0].RouteOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"groupByRegex":"^(?:team_.+)$"`) {
+		t.Errorf("unexpected JSON representation: %s", b)
+	}
+}
+
+func TestRouteSplit(t *testing.T) {
+	in := `
+receiver: 'pagerduty'
+split:
+  receivers:
+  - receiver: 'opsgenie'
+    percent: 5
+
+routes:
+- match:
+    owner: 'team-A'
+  split:
+    by: random
+    receivers:
+    - receiver: 'opsgenie'
+      percent: 50
+
+- match:
+    owner: 'team-B'
+  receiver: 'notify-B'
+`
+
+	var ctree config.Route
+	if err := yaml.UnmarshalStrict([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	// Groups are split by the hash of their key.
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("{}:{alertname=\"%d\"}", i)
+		r := tree.RouteOpts.SplitReceiver(key)
+		if r != tree.RouteOpts.SplitReceiver(key) {
+			t.Fatalf("receiver of group %s changed", key)
+		}
+		counts[r]++
+	}
+	if counts["opsgenie"] < 20 || counts["opsgenie"] > 80 || counts["pagerduty"] != 1000-counts["opsgenie"] {
+		t.Errorf("unexpected distribution of groups: %v", counts)
+	}
+
+	counts = map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[tree.Routes[0].RouteOpts.SplitReceiver("{}:{}")]++
+	}
+	if counts["opsgenie"] < 400 || counts["opsgenie"] > 600 || counts["pagerduty"] != 1000-counts["opsgenie"] {
+		t.Errorf("unexpected distribution of notifications: %v", counts)
+	}
+
+	// Setting a receiver resets the split.
+	if tree.Routes[1].RouteOpts.Split != nil {
+		t.Errorf("unexpected split %v", tree.Routes[1].RouteOpts.Split)
+	}
+	if r := tree.Routes[1].RouteOpts.SplitReceiver("{}:{}"); r != "notify-B" {
+		t.Errorf("expected receiver notify-B, got %s", r)
+	}
+
+	b, err := json.Marshal(&tree.RouteOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"split":{"by":"group_key","receivers":[{"receiver":"opsgenie","percent":5}]}`) {
+		t.Errorf("unexpected JSON representation: %s", b)
+	}
+}
+
+func TestRouteMaxMatchesAndStop(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+  max_matches: 2
+  routes:
+  - match:
+      env: 'prod'
+    receiver: 'notify-A1'
+    continue: true
+  - match:
+      env: 'prod'
+    receiver: 'notify-A2'
+    continue: true
+  - match:
+      env: 'prod'
+    receiver: 'notify-A3'
+    continue: true
+  continue: true
+
+- match:
+    owner: 'team-A'
+  receiver: 'notify-B'
+  continue: true
+  routes:
+  - match:
+      severity: 'page'
+    receiver: 'notify-B1'
+    continue: true
+    stop: true
+  - match:
+      severity: 'page'
+    receiver: 'notify-B2'
+
+- match:
+    owner: 'team-A'
+  receiver: 'notify-C'
+`
+
+	var ctree config.Route
+	if err := yaml.UnmarshalStrict([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	for _, tc := range []struct {
+		input  model.LabelSet
+		result []string
+	}{
+		{
+			// The first route stops after two matching children.
+			input:  model.LabelSet{"owner": "team-A", "env": "prod"},
+			result: []string{"notify-A1", "notify-A2", "notify-B", "notify-C"},
+		},
+		{
+			// The stopping route ends the evaluation of its siblings and
+			// of the siblings of its parent.
+			input:  model.LabelSet{"owner": "team-A", "env": "prod", "severity": "page"},
+			result: []string{"notify-A1", "notify-A2", "notify-B1"},
+		},
+		{
+			input:  model.LabelSet{"owner": "team-A"},
+			result: []string{"notify-A", "notify-B", "notify-C"},
+		},
+	} {
+		var res []string
+		for _, r := range tree.Match(tc.input) {
+			res = append(res, r.RouteOpts.Receiver)
+		}
+		if !reflect.DeepEqual(res, tc.result) {
+			t.Errorf("\nexpected:\n%v\ngot:\n%v", tc.result, res)
+		}
+	}
+}
+
+func TestRouteTimerOverrides(t *testing.T) {
+	in := `
+receiver: 'notify-def'
+group_wait: 30s
+group_interval: 5m
+repeat_interval: 4h
+timer_overrides:
+- matchers: ['severity="critical"']
+  repeat_interval: 1h
+- matchers: ['severity=~"critical|warning"']
+  group_wait: 1m
+  repeat_interval: 24h
+
+routes:
+- match:
+    owner: 'team-A'
+  receiver: 'notify-A'
+`
+
+	var ctree config.Route
+	if err := yaml.UnmarshalStrict([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	for _, tc := range []struct {
+		labels                                   model.LabelSet
+		groupWait, groupInterval, repeatInterval time.Duration
+	}{
+		// The first matching override applies.
+		{model.LabelSet{"severity": "critical"}, 30 * time.Second, 5 * time.Minute, time.Hour},
+		{model.LabelSet{"severity": "warning"}, time.Minute, 5 * time.Minute, 24 * time.Hour},
+		{model.LabelSet{"severity": "info"}, 30 * time.Second, 5 * time.Minute, 4 * time.Hour},
+	} {
+		opts := tree.RouteOpts.WithTimerOverride(tc.labels)
+		if opts.GroupWait != tc.groupWait || opts.GroupInterval != tc.groupInterval || opts.RepeatInterval != tc.repeatInterval {
+			t.Errorf("unexpected timers for %v: %s", tc.labels, opts)
+		}
+	}
+	if tree.RouteOpts.RepeatInterval != 4*time.Hour {
+		t.Errorf("route options were modified")
+	}
+
+	// Timer overrides are not inherited.
+	opts := tree.Routes[0].RouteOpts.WithTimerOverride(model.LabelSet{"severity": "critical"})
+	if opts.RepeatInterval != 4*time.Hour {
+		t.Errorf("unexpected repeat interval %s", opts.RepeatInterval)
+	}
+
+	ag := newAggrGroup(context.Background(), model.LabelSet{"severity": "critical"}, tree, nil, log.NewNopLogger())
+	if ag.opts.RepeatInterval != time.Hour {
+		t.Errorf("unexpected repeat interval %s of aggregation group", ag.opts.RepeatInterval)
+	}
+}
+
+func TestRouteReceiverTemplate(t *testing.T) {
+	in := `
+receiver: 'team-{{ .CommonLabels.team }}'
+default_receiver: 'default'
+
+routes:
+- match:
+    owner: 'ops'
+  receiver: 'ops'
+`
+
+	var ctree config.Route
+	if err := yaml.UnmarshalStrict([]byte(in), &ctree); err != nil {
+		t.Fatal(err)
+	}
+	tree := NewRoute(&ctree, nil)
+
+	alerts := []*types.Alert{
+		{Alert: model.Alert{Labels: model.LabelSet{"team": "a", "instance": "1"}}},
+		{Alert: model.Alert{Labels: model.LabelSet{"team": "a", "instance": "2"}}},
+	}
+	if r := tree.RouteOpts.TemplateReceiver(model.LabelSet{}, alerts...); r != "team-a" {
+		t.Errorf("expected receiver team-a, got %s", r)
+	}
+	if tree.RouteOpts.DefaultReceiver != "default" {
+		t.Errorf("expected default receiver default, got %s", tree.RouteOpts.DefaultReceiver)
+	}
+
+	// Labels not common to all alerts are empty.
+	alerts[1].Labels["team"] = "b"
+	if r := tree.RouteOpts.TemplateReceiver(model.LabelSet{}, alerts...); r != "team-" {
+		t.Errorf("expected receiver team-, got %s", r)
+	}
+
+	// Setting a receiver resets the template.
+	if r := tree.Routes[0].RouteOpts.TemplateReceiver(model.LabelSet{}, alerts...); r != "ops" {
+		t.Errorf("expected receiver ops, got %s", r)
+	}
+}
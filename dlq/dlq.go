@@ -0,0 +1,344 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dlq implements a dead-letter queue for notifications that could
+// not be delivered. Entries are persisted to disk so that they survive
+// restarts and can be inspected and replayed later on.
+package dlq
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/satori/go.uuid"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+// ErrNotFound is returned if an entry does not exist.
+var ErrNotFound = errors.New("dead letter not found")
+
+// Entry is a notification that failed permanently.
+type Entry struct {
+	ID          string         `json:"id"`
+	Timestamp   time.Time      `json:"timestamp"`
+	Receiver    string         `json:"receiver"`
+	Integration string         `json:"integration"`
+	Idx         uint32         `json:"idx"`
+	GroupKey    string         `json:"groupKey"`
+	GroupLabels model.LabelSet `json:"groupLabels"`
+	Alerts      []*types.Alert `json:"alerts"`
+	Error       string         `json:"error"`
+	// Requests are the rendered requests of the integration that failed,
+	// if it notifies over HTTP.
+	Requests []*Request `json:"requests,omitempty"`
+	// Replays is the number of failed replays.
+	Replays int `json:"replays,omitempty"`
+}
+
+// Redacted returns a copy of the entry without its requests, which may
+// contain credentials of the integration.
+func (e *Entry) Redacted() *Entry {
+	res := *e
+	res.Requests = nil
+	return &res
+}
+
+// Request is a rendered HTTP request of an integration.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// ReplayFunc sends an entry to the integration of its receiver again. It
+// removes the requests that succeeded from the entry.
+type ReplayFunc func(ctx context.Context, e *Entry) error
+
+// Options configures a DLQ.
+type Options struct {
+	// Path of the file the entries are persisted to. If empty, entries are
+	// only kept in memory.
+	Path string
+	// Retention is the time after which entries are removed.
+	Retention time.Duration
+	// Replay is used to replay entries.
+	Replay ReplayFunc
+
+	Logger  log.Logger
+	Metrics prometheus.Registerer
+}
+
+// DLQ is a persistent dead-letter queue.
+type DLQ struct {
+	opts    Options
+	logger  log.Logger
+	now     func() time.Time
+	entries prometheus.Gauge
+
+	mtx sync.Mutex
+	st  map[string]*Entry
+}
+
+// New returns a new DLQ and loads existing entries from its file.
+func New(o Options) (*DLQ, error) {
+	q := &DLQ{
+		opts:   o,
+		logger: log.NewNopLogger(),
+		now:    utcNow,
+		entries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "alertmanager_dead_letters",
+			Help: "The number of notifications in the dead-letter queue.",
+		}),
+		st: map[string]*Entry{},
+	}
+	if o.Logger != nil {
+		q.logger = o.Logger
+	}
+	if o.Metrics != nil {
+		o.Metrics.MustRegister(q.entries)
+	}
+	if o.Path == "" {
+		return q, nil
+	}
+
+	f, err := os.Open(o.Path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64*1024*1024)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			// A partially written line is expected after a crash.
+			level.Warn(q.logger).Log("msg", "Skipping corrupt dead letter", "err", err)
+			continue
+		}
+		q.st[e.ID] = &e
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	q.entries.Set(float64(len(q.st)))
+
+	return q, nil
+}
+
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// Add stores a new entry and returns its ID.
+func (q *DLQ) Add(e *Entry) (string, error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	e.ID = uuid.NewV4().String()
+	if e.Timestamp.IsZero() {
+		e.Timestamp = q.now()
+	}
+
+	if q.opts.Path != "" {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return "", err
+		}
+		// The requests of entries may contain credentials.
+		f, err := os.OpenFile(q.opts.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(append(b, '\n')); err != nil {
+			f.Close()
+			return "", err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return "", err
+		}
+		if err := f.Close(); err != nil {
+			return "", err
+		}
+	}
+	q.st[e.ID] = e
+	q.entries.Set(float64(len(q.st)))
+
+	return e.ID, nil
+}
+
+// List returns all entries ordered by their timestamp.
+func (q *DLQ) List() []*Entry {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	res := make([]*Entry, 0, len(q.st))
+	for _, e := range q.st {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Timestamp.Before(res[j].Timestamp)
+	})
+	return res
+}
+
+// Get returns the entry with the given ID.
+func (q *DLQ) Get(id string) (*Entry, error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	e, ok := q.st[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return e, nil
+}
+
+// Delete removes the entry with the given ID.
+func (q *DLQ) Delete(id string) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if _, ok := q.st[id]; !ok {
+		return ErrNotFound
+	}
+	delete(q.st, id)
+	q.entries.Set(float64(len(q.st)))
+
+	return q.persist()
+}
+
+// Replay sends the entry with the given ID again. The entry is removed if
+// the notification succeeds, otherwise it is updated with the error and the
+// requests that remain to be sent.
+func (q *DLQ) Replay(ctx context.Context, id string) error {
+	if q.opts.Replay == nil {
+		return fmt.Errorf("replay not supported")
+	}
+	e, err := q.Get(id)
+	if err != nil {
+		return err
+	}
+	replayed := *e
+	replayed.Requests = append([]*Request(nil), e.Requests...)
+	if err := q.opts.Replay(ctx, &replayed); err != nil {
+		if uerr := q.update(id, &replayed, err); uerr != nil {
+			level.Error(q.logger).Log("msg", "Updating dead letter failed", "id", id, "err", uerr)
+		}
+		return err
+	}
+	return q.Delete(id)
+}
+
+// update replaces the entry with the given ID after a failed replay, unless
+// it was removed in the meantime.
+func (q *DLQ) update(id string, e *Entry, err error) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if _, ok := q.st[id]; !ok {
+		return nil
+	}
+	e.Error = err.Error()
+	e.Replays++
+	q.st[id] = e
+
+	return q.persist()
+}
+
+// GC removes entries older than the retention time and returns the number
+// of removed entries.
+func (q *DLQ) GC() (int, error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	var n int
+	for id, e := range q.st {
+		if q.opts.Retention > 0 && q.now().Sub(e.Timestamp) > q.opts.Retention {
+			delete(q.st, id)
+			n++
+		}
+	}
+	q.entries.Set(float64(len(q.st)))
+
+	if n == 0 {
+		return 0, nil
+	}
+	return n, q.persist()
+}
+
+// Maintenance garbage collects the queue in the given interval until stopc
+// is closed.
+func (q *DLQ) Maintenance(interval time.Duration, stopc <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+			if n, err := q.GC(); err != nil {
+				level.Error(q.logger).Log("msg", "Dead-letter queue GC failed", "err", err)
+			} else if n > 0 {
+				level.Debug(q.logger).Log("msg", "Dead-letter queue GC done", "removed", n)
+			}
+		}
+	}
+}
+
+// persist rewrites the queue file with the current entries. The caller must
+// hold the lock.
+func (q *DLQ) persist() error {
+	if q.opts.Path == "" {
+		return nil
+	}
+	tmp := q.opts.Path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range q.st {
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.opts.Path)
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dlq
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDLQPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dlq")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	o := Options{Path: filepath.Join(dir, "dlq")}
+	q, err := New(o)
+	require.NoError(t, err)
+
+	alerts := []*types.Alert{{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}}
+	id1, err := q.Add(&Entry{Receiver: "team-X", Integration: "webhook", GroupKey: "1", Alerts: alerts, Error: "fail"})
+	require.NoError(t, err)
+	id2, err := q.Add(&Entry{Receiver: "team-Y", Integration: "email", GroupKey: "2", Error: "fail"})
+	require.NoError(t, err)
+	require.NoError(t, q.Delete(id2))
+
+	q, err = New(o)
+	require.NoError(t, err)
+	entries := q.List()
+	require.Len(t, entries, 1)
+	require.Equal(t, id1, entries[0].ID)
+	require.Equal(t, "team-X", entries[0].Receiver)
+	require.Equal(t, model.LabelValue("a"), entries[0].Alerts[0].Labels["alertname"])
+
+	_, err = q.Get(id2)
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestDLQGC(t *testing.T) {
+	q, err := New(Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	_, err = q.Add(&Entry{Timestamp: now.Add(-2 * time.Hour)})
+	require.NoError(t, err)
+	id, err := q.Add(&Entry{})
+	require.NoError(t, err)
+
+	n, err := q.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	entries := q.List()
+	require.Len(t, entries, 1)
+	require.Equal(t, id, entries[0].ID)
+}
+
+func TestDLQReplay(t *testing.T) {
+	var fail bool
+	q, err := New(Options{
+		Replay: func(ctx context.Context, e *Entry) error {
+			if fail {
+				// The first request succeeded.
+				e.Requests = e.Requests[1:]
+				return fmt.Errorf("fail")
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	id, err := q.Add(&Entry{
+		Error:    "initial",
+		Requests: []*Request{{Method: "POST", URL: "http://a"}, {Method: "POST", URL: "http://b"}},
+	})
+	require.NoError(t, err)
+
+	// Failed replays update the entry in place.
+	fail = true
+	require.EqualError(t, q.Replay(context.Background(), id), "fail")
+	entries := q.List()
+	require.Len(t, entries, 1)
+	require.Equal(t, id, entries[0].ID)
+	require.Equal(t, "fail", entries[0].Error)
+	require.Equal(t, 1, entries[0].Replays)
+	require.Equal(t, []*Request{{Method: "POST", URL: "http://b"}}, entries[0].Requests)
+	require.Nil(t, entries[0].Redacted().Requests)
+
+	fail = false
+	require.NoError(t, q.Replay(context.Background(), id))
+	require.Len(t, q.List(), 0)
+}
@@ -0,0 +1,351 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package history records the state transitions of alerts and the
+// notifications sent for them, so that the timeline of an incident can be
+// reconstructed.
+package history
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// ErrNotFound is returned if the history of an alert does not exist.
+var ErrNotFound = errors.New("alert history not found")
+
+// EventType is the type of an event in the history of an alert.
+type EventType string
+
+// Types of events.
+const (
+	EventFiring      EventType = "firing"
+	EventResolved    EventType = "resolved"
+	EventSilenced    EventType = "silenced"
+	EventUnsilenced  EventType = "unsilenced"
+	EventInhibited   EventType = "inhibited"
+	EventUninhibited EventType = "uninhibited"
+	EventNotified    EventType = "notified"
+)
+
+// Event is a state transition of an alert or a notification sent for it.
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	Type      EventType `json:"type"`
+	// SilencedBy and InhibitedBy hold the IDs of the silences and the
+	// fingerprints of the alerts suppressing the alert from a silenced or
+	// inhibited event on. A changed set of suppressing silences or alerts
+	// is recorded as another silenced or inhibited event.
+	SilencedBy  []string `json:"silencedBy,omitempty"`
+	InhibitedBy []string `json:"inhibitedBy,omitempty"`
+	// Receiver, Integration and Status describe a notification.
+	Receiver    string            `json:"receiver,omitempty"`
+	Integration string            `json:"integration,omitempty"`
+	Status      model.AlertStatus `json:"status,omitempty"`
+}
+
+// Alert is the history of an alert.
+type Alert struct {
+	Fingerprint string         `json:"fingerprint"`
+	Labels      model.LabelSet `json:"labels"`
+	// Events holds the events of the alert, the oldest first.
+	Events []Event `json:"events"`
+}
+
+// Options configures a History.
+type Options struct {
+	// Retention is the time after which events are removed. The histories
+	// of resolved alerts are removed once all their events are.
+	Retention time.Duration
+	// MaxEvents is the maximum number of events kept per alert, the oldest
+	// are removed first. 0 means no limit.
+	MaxEvents int
+}
+
+// History records the events of alerts in memory.
+type History struct {
+	opts Options
+	now  func() time.Time
+
+	mtx    sync.RWMutex
+	alerts map[model.Fingerprint]*alertHistory
+}
+
+type alertHistory struct {
+	labels model.LabelSet
+	firing bool
+	// The end of the firing alert, after which it is resolved.
+	endsAt time.Time
+	events []Event
+}
+
+// New returns a new history.
+func New(o Options) *History {
+	return &History{
+		opts:   o,
+		now:    utcNow,
+		alerts: map[model.Fingerprint]*alertHistory{},
+	}
+}
+
+func utcNow() time.Time {
+	return time.Now().UTC()
+}
+
+// Get returns the history of the alert with the given fingerprint.
+func (h *History) Get(fp model.Fingerprint) (*Alert, error) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	ah, ok := h.alerts[fp]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &Alert{
+		Fingerprint: fp.String(),
+		Labels:      ah.labels,
+		Events:      append([]Event{}, ah.events...),
+	}, nil
+}
+
+// alert returns the history of the alert, which is created if it does not
+// exist. The caller must hold the lock.
+func (h *History) alert(fp model.Fingerprint) *alertHistory {
+	ah, ok := h.alerts[fp]
+	if !ok {
+		ah = &alertHistory{}
+		h.alerts[fp] = ah
+	}
+	return ah
+}
+
+// add adds the event to the history of the alert. The caller must hold the
+// lock.
+func (h *History) add(ah *alertHistory, e Event) {
+	ah.events = append(ah.events, e)
+	if h.opts.MaxEvents > 0 && len(ah.events) > h.opts.MaxEvents {
+		ah.events = append(ah.events[:0], ah.events[len(ah.events)-h.opts.MaxEvents:]...)
+	}
+}
+
+// observe records the transition of the alert between firing and resolved.
+func (h *History) observe(a *types.Alert) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	now := h.now()
+	ah := h.alert(a.Fingerprint())
+	ah.labels = a.Labels
+
+	if !a.ResolvedAt(now) {
+		ah.endsAt = a.EndsAt
+		if !ah.firing {
+			ah.firing = true
+			h.add(ah, Event{Timestamp: now, Type: EventFiring})
+		}
+		return
+	}
+	if ah.firing {
+		ah.firing = false
+		h.add(ah, Event{Timestamp: a.EndsAt.UTC(), Type: EventResolved})
+	}
+}
+
+// markerChanged records the changes of the suppression of the alert.
+func (h *History) markerChanged(fp model.Fingerprint, before, after types.AlertStatus) {
+	silenced := !equalSets(before.SilencedBy, after.SilencedBy)
+	inhibited := !equalSets(before.InhibitedBy, after.InhibitedBy)
+	if !silenced && !inhibited {
+		return
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	now := h.now()
+	ah := h.alert(fp)
+	if silenced {
+		e := Event{Timestamp: now, Type: EventUnsilenced}
+		if len(after.SilencedBy) > 0 {
+			e = Event{Timestamp: now, Type: EventSilenced, SilencedBy: after.SilencedBy}
+		}
+		h.add(ah, e)
+	}
+	if inhibited {
+		e := Event{Timestamp: now, Type: EventUninhibited}
+		if len(after.InhibitedBy) > 0 {
+			e = Event{Timestamp: now, Type: EventInhibited, InhibitedBy: after.InhibitedBy}
+		}
+		h.add(ah, e)
+	}
+}
+
+func equalSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string{}, a...), append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GC records the resolution of alerts that resolved by reaching their end
+// and removes the events older than the retention time. It returns the
+// number of removed alert histories.
+func (h *History) GC() int {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	now := h.now()
+	var n int
+	for fp, ah := range h.alerts {
+		if ah.firing && !ah.endsAt.IsZero() && !ah.endsAt.After(now) {
+			ah.firing = false
+			h.add(ah, Event{Timestamp: ah.endsAt.UTC(), Type: EventResolved})
+		}
+		if h.opts.Retention <= 0 {
+			continue
+		}
+		i := 0
+		for i < len(ah.events) && now.Sub(ah.events[i].Timestamp) > h.opts.Retention {
+			i++
+		}
+		ah.events = append(ah.events[:0], ah.events[i:]...)
+		if len(ah.events) == 0 && !ah.firing {
+			delete(h.alerts, fp)
+			n++
+		}
+	}
+	return n
+}
+
+// Run records the transitions of the alerts between firing and resolved
+// and garbage collects the history in the given interval until stopc is
+// closed.
+func (h *History) Run(alerts provider.Alerts, interval time.Duration, stopc <-chan struct{}) {
+	it := alerts.Subscribe()
+	defer it.Close()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case a, ok := <-it.Next():
+			if !ok {
+				return
+			}
+			h.observe(a)
+		case <-t.C:
+			h.GC()
+		}
+	}
+}
+
+// Marker returns a marker recording the changes of the suppression of
+// alerts in the history. The changes are applied to the given marker.
+func (h *History) Marker(m types.Marker) types.Marker {
+	return &marker{Marker: m, h: h}
+}
+
+type marker struct {
+	types.Marker
+	h *History
+
+	// Serializes changes so that they are compared with the status they
+	// were applied to.
+	mtx sync.Mutex
+}
+
+func (m *marker) set(fp model.Fingerprint, f func()) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	before := m.Marker.Status(fp)
+	f()
+	m.h.markerChanged(fp, before, m.Marker.Status(fp))
+}
+
+// SetActive implements the types.Marker interface.
+func (m *marker) SetActive(fp model.Fingerprint) {
+	m.set(fp, func() { m.Marker.SetActive(fp) })
+}
+
+// SetSilenced implements the types.Marker interface.
+func (m *marker) SetSilenced(fp model.Fingerprint, ids ...string) {
+	m.set(fp, func() { m.Marker.SetSilenced(fp, ids...) })
+}
+
+// SetInhibited implements the types.Marker interface.
+func (m *marker) SetInhibited(fp model.Fingerprint, ids ...string) {
+	m.set(fp, func() { m.Marker.SetInhibited(fp, ids...) })
+}
+
+// StageFactory returns the factory of the stage recording sent
+// notifications, which is registered after the notifications are sent.
+func (h *History) StageFactory() notify.StageFactory {
+	return func(rc *config.Receiver, integration string) notify.Stage {
+		return &notifyStage{h: h, receiver: rc.Name, integration: integration}
+	}
+}
+
+type notifyStage struct {
+	h           *History
+	receiver    string
+	integration string
+}
+
+// Exec implements the notify.Stage interface.
+func (s *notifyStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	s.h.mtx.Lock()
+	defer s.h.mtx.Unlock()
+
+	now := s.h.now()
+	for _, a := range alerts {
+		ah := s.h.alert(a.Fingerprint())
+		if ah.labels == nil {
+			ah.labels = a.Labels
+		}
+		status := model.AlertFiring
+		if a.ResolvedAt(now) {
+			status = model.AlertResolved
+		}
+		s.h.add(ah, Event{
+			Timestamp:   now,
+			Type:        EventNotified,
+			Receiver:    s.receiver,
+			Integration: s.integration,
+			Status:      status,
+		})
+	}
+	return ctx, alerts, nil
+}
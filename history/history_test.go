@@ -0,0 +1,143 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func eventTypes(t *testing.T, h *History, fp model.Fingerprint) []EventType {
+	a, err := h.Get(fp)
+	require.NoError(t, err)
+	var res []EventType
+	for _, e := range a.Events {
+		res = append(res, e.Type)
+	}
+	return res
+}
+
+func TestHistoryTransitions(t *testing.T) {
+	h := New(Options{Retention: time.Hour})
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	a := &types.Alert{Alert: model.Alert{
+		Labels:   model.LabelSet{"alertname": "a"},
+		StartsAt: now,
+		EndsAt:   now.Add(5 * time.Minute),
+	}}
+	fp := a.Fingerprint()
+	_, err := h.Get(fp)
+	require.Equal(t, ErrNotFound, err)
+
+	h.observe(a)
+	// Updates of firing alerts are no transitions.
+	h.observe(a)
+
+	m := h.Marker(types.NewMarker())
+	m.SetSilenced(fp, "s1")
+	m.SetSilenced(fp, "s1")
+	m.SetSilenced(fp, "s1", "s2")
+	m.SetInhibited(fp, "i1")
+	m.SetActive(fp)
+
+	resolved := *a
+	resolved.EndsAt = now.Add(-time.Second)
+	h.observe(&resolved)
+	h.observe(a)
+
+	// Alerts reaching their end are resolved by the GC.
+	now = now.Add(10 * time.Minute)
+	require.Equal(t, 0, h.GC())
+
+	require.Equal(t, []EventType{
+		EventFiring,
+		EventSilenced,
+		EventSilenced,
+		EventInhibited,
+		EventUnsilenced,
+		EventUninhibited,
+		EventResolved,
+		EventFiring,
+		EventResolved,
+	}, eventTypes(t, h, fp))
+
+	res, err := h.Get(fp)
+	require.NoError(t, err)
+	require.Equal(t, model.LabelSet{"alertname": "a"}, res.Labels)
+	require.Equal(t, []string{"s1", "s2"}, res.Events[2].SilencedBy)
+	require.Equal(t, []string{"i1"}, res.Events[3].InhibitedBy)
+	require.Equal(t, a.EndsAt.UTC(), res.Events[8].Timestamp)
+
+	// Resolved alerts are removed once all their events expired.
+	now = now.Add(2 * time.Hour)
+	require.Equal(t, 1, h.GC())
+	_, err = h.Get(fp)
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestHistoryMaxEvents(t *testing.T) {
+	h := New(Options{MaxEvents: 2})
+	m := h.Marker(types.NewMarker())
+	fp := model.Fingerprint(1)
+
+	m.SetSilenced(fp, "s1")
+	m.SetSilenced(fp, "s2")
+	m.SetActive(fp)
+
+	require.Equal(t, []EventType{EventSilenced, EventUnsilenced}, eventTypes(t, h, fp))
+}
+
+func TestHistoryNotifyStage(t *testing.T) {
+	h := New(Options{})
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	firing := &types.Alert{Alert: model.Alert{
+		Labels: model.LabelSet{"alertname": "a"},
+		EndsAt: now.Add(time.Minute),
+	}}
+	resolved := &types.Alert{Alert: model.Alert{
+		Labels: model.LabelSet{"alertname": "b"},
+		EndsAt: now.Add(-time.Minute),
+	}}
+
+	s := h.StageFactory()(&config.Receiver{Name: "team-X"}, "webhook")
+	_, alerts, err := s.Exec(context.Background(), log.NewNopLogger(), firing, resolved)
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+
+	res, err := h.Get(firing.Fingerprint())
+	require.NoError(t, err)
+	require.Equal(t, []Event{{
+		Timestamp:   now,
+		Type:        EventNotified,
+		Receiver:    "team-X",
+		Integration: "webhook",
+		Status:      model.AlertFiring,
+	}}, res.Events)
+
+	res, err = h.Get(resolved.Fingerprint())
+	require.NoError(t, err)
+	require.Equal(t, model.AlertResolved, res.Events[0].Status)
+}
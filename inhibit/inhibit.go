@@ -15,6 +15,7 @@ package inhibit
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 
@@ -135,10 +136,11 @@ func (ih *Inhibitor) Stop() {
 // Mutes returns true iff the given label set is muted.
 func (ih *Inhibitor) Mutes(lset model.LabelSet) bool {
 	fp := lset.Fingerprint()
+	now := time.Now()
 
 	for _, r := range ih.rules {
 		// Only inhibit if target matchers match but source matchers don't.
-		if inhibitedByFP, eq := r.hasEqual(lset); !r.SourceMatchers.Match(lset) && r.TargetMatchers.Match(lset) && eq {
+		if inhibitedByFP, eq := r.hasEqual(lset, now); !r.SourceMatchers.Match(lset) && r.TargetMatchers.Match(lset) && eq {
 			ih.marker.SetInhibited(fp, inhibitedByFP.String())
 			return true
 		}
@@ -148,6 +150,32 @@ func (ih *Inhibitor) Mutes(lset model.LabelSet) bool {
 	return false
 }
 
+// Inhibition is the inhibition of a label set by a source alert.
+type Inhibition struct {
+	// Rule is the index of the inhibition rule in the configuration.
+	Rule   int
+	Source *types.Alert
+}
+
+// Inhibitors returns the source alerts inhibiting the given label set along
+// with the rules they inhibit it by. Unlike Mutes, it does not update the
+// marker.
+func (ih *Inhibitor) Inhibitors(lset model.LabelSet) []Inhibition {
+	var (
+		now = time.Now()
+		res []Inhibition
+	)
+	for i, r := range ih.rules {
+		if r.SourceMatchers.Match(lset) || !r.TargetMatchers.Match(lset) {
+			continue
+		}
+		for _, a := range r.sources(lset, now) {
+			res = append(res, Inhibition{Rule: i, Source: a})
+		}
+	}
+	return res
+}
+
 // An InhibitRule specifies that a class of (source) alerts should inhibit
 // notifications for another class of (target) alerts if all specified matching
 // labels are equal between the two alerts. This may be used to inhibit alerts
@@ -163,12 +191,24 @@ type InhibitRule struct {
 	// A set of label names whose label values need to be identical in source and
 	// target alerts in order for the inhibition to take effect.
 	Equal map[model.LabelName]struct{}
+	// Pairs of a source and a target label name whose values need to be
+	// identical in source and target alerts.
+	EqualMappings []LabelMapping
+	// The duration a source alert must have been firing for before it
+	// inhibits target alerts.
+	Delay time.Duration
 
 	mtx sync.RWMutex
 	// Cache of alerts matching source labels.
 	scache map[model.Fingerprint]*types.Alert
 }
 
+// LabelMapping maps a label of source alerts to a label of target alerts.
+type LabelMapping struct {
+	Source model.LabelName
+	Target model.LabelName
+}
+
 // NewInhibitRule returns a new InihibtRule based on a configuration definition.
 func NewInhibitRule(cr *config.InhibitRule) *InhibitRule {
 	var (
@@ -182,6 +222,7 @@ func NewInhibitRule(cr *config.InhibitRule) *InhibitRule {
 	for ln, lv := range cr.SourceMatchRE {
 		sourcem = append(sourcem, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
 	}
+	sourcem = append(sourcem, cr.SourceMatchers...)
 
 	for ln, lv := range cr.TargetMatch {
 		targetm = append(targetm, types.NewMatcher(model.LabelName(ln), lv))
@@ -189,16 +230,24 @@ func NewInhibitRule(cr *config.InhibitRule) *InhibitRule {
 	for ln, lv := range cr.TargetMatchRE {
 		targetm = append(targetm, types.NewRegexMatcher(model.LabelName(ln), lv.Regexp))
 	}
+	targetm = append(targetm, cr.TargetMatchers...)
 
 	equal := map[model.LabelName]struct{}{}
 	for _, ln := range cr.Equal {
 		equal[ln] = struct{}{}
 	}
 
+	var mappings []LabelMapping
+	for _, m := range cr.EqualMappings {
+		mappings = append(mappings, LabelMapping{Source: m.Source, Target: m.Target})
+	}
+
 	return &InhibitRule{
 		SourceMatchers: sourcem,
 		TargetMatchers: targetm,
 		Equal:          equal,
+		EqualMappings:  mappings,
+		Delay:          time.Duration(cr.Delay),
 		scache:         map[model.Fingerprint]*types.Alert{},
 	}
 }
@@ -211,28 +260,64 @@ func (r *InhibitRule) set(a *types.Alert) {
 	r.scache[a.Fingerprint()] = a
 }
 
-// hasEqual checks whether the source cache contains alerts matching
-// the equal labels for the given label set.
-func (r *InhibitRule) hasEqual(lset model.LabelSet) (model.Fingerprint, bool) {
+// hasEqual checks whether the source cache contains alerts active at the
+// given time matching the equal labels for the given label set.
+func (r *InhibitRule) hasEqual(lset model.LabelSet, now time.Time) (model.Fingerprint, bool) {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
 
-Outer:
 	for fp, a := range r.scache {
 		// The cache might be stale and contain resolved alerts.
-		if a.Resolved() {
-			continue
-		}
-		for n := range r.Equal {
-			if a.Labels[n] != lset[n] {
-				continue Outer
-			}
+		if r.active(a, now) && r.equal(a, lset) {
+			return fp, true
 		}
-		return fp, true
 	}
 	return model.Fingerprint(0), false
 }
 
+// sources returns the alerts in the source cache active at the given time
+// matching the equal labels for the given label set, ordered by fingerprint.
+func (r *InhibitRule) sources(lset model.LabelSet, now time.Time) []*types.Alert {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	var res []*types.Alert
+	for _, a := range r.scache {
+		if r.active(a, now) && r.equal(a, lset) {
+			res = append(res, a)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Fingerprint() < res[j].Fingerprint()
+	})
+	return res
+}
+
+// active returns whether the source alert is firing and has been firing
+// for longer than the delay of the rule.
+func (r *InhibitRule) active(a *types.Alert, now time.Time) bool {
+	if a.ResolvedAt(now) {
+		return false
+	}
+	return r.Delay == 0 || now.Sub(a.StartsAt) >= r.Delay
+}
+
+// equal returns whether the alert and the label set have the same values of
+// the equal labels and of the mapped labels.
+func (r *InhibitRule) equal(a *types.Alert, lset model.LabelSet) bool {
+	for n := range r.Equal {
+		if a.Labels[n] != lset[n] {
+			return false
+		}
+	}
+	for _, m := range r.EqualMappings {
+		if a.Labels[m.Source] != lset[m.Target] {
+			return false
+		}
+	}
+	return true
+}
+
 // gc clears out resolved alerts from the source cache.
 func (r *InhibitRule) gc() {
 	r.mtx.Lock()
@@ -131,7 +131,7 @@ func TestInhibitRuleHasEqual(t *testing.T) {
 			r.scache[k] = v
 		}
 
-		if _, have := r.hasEqual(c.input); have != c.result {
+		if _, have := r.hasEqual(c.input, now); have != c.result {
 			t.Errorf("Unexpected result %t, expected %t", have, c.result)
 		}
 		if !reflect.DeepEqual(r.scache, c.initial) {
@@ -383,3 +383,121 @@ func TestInhibit(t *testing.T) {
 		}
 	}
 }
+
+func TestInhibitors(t *testing.T) {
+	now := time.Now()
+	newAlert := func(lset model.LabelSet, end time.Time) *types.Alert {
+		return &types.Alert{
+			Alert: model.Alert{
+				Labels:   lset,
+				StartsAt: now.Add(-time.Minute),
+				EndsAt:   end,
+			},
+		}
+	}
+	ih := NewInhibitor(nil, []*config.InhibitRule{
+		{
+			SourceMatch: map[string]string{"severity": "critical"},
+			TargetMatch: map[string]string{"severity": "warning"},
+			Equal:       model.LabelNames{"cluster"},
+		},
+		{
+			SourceMatch: map[string]string{"alertname": "ClusterDown"},
+			TargetMatch: map[string]string{"severity": "warning"},
+			Equal:       model.LabelNames{"cluster"},
+		},
+	}, types.NewMarker(), nopLogger)
+
+	critical := newAlert(model.LabelSet{"alertname": "ClusterDown", "severity": "critical", "cluster": "a"}, now.Add(time.Hour))
+	other := newAlert(model.LabelSet{"alertname": "DiskFull", "severity": "critical", "cluster": "a"}, now.Add(time.Hour))
+	resolved := newAlert(model.LabelSet{"alertname": "NodeDown", "severity": "critical", "cluster": "a"}, now.Add(-time.Second))
+	elsewhere := newAlert(model.LabelSet{"alertname": "DiskFull", "severity": "critical", "cluster": "b"}, now.Add(time.Hour))
+	for _, a := range []*types.Alert{critical, other, resolved, elsewhere} {
+		for _, r := range ih.rules {
+			if r.SourceMatchers.Match(a.Labels) {
+				r.set(a)
+			}
+		}
+	}
+
+	res := ih.Inhibitors(model.LabelSet{"alertname": "HighLatency", "severity": "warning", "cluster": "a"})
+	expected := []Inhibition{{Rule: 0}, {Rule: 0}, {Rule: 1, Source: critical}}
+	if critical.Fingerprint() < other.Fingerprint() {
+		expected[0].Source, expected[1].Source = critical, other
+	} else {
+		expected[0].Source, expected[1].Source = other, critical
+	}
+	if !reflect.DeepEqual(expected, res) {
+		t.Errorf("Unexpected inhibitors:\n%s", pretty.Compare(expected, res))
+	}
+
+	// Source alerts are not inhibited by their own rules.
+	if res := ih.Inhibitors(critical.Labels); len(res) != 0 {
+		t.Errorf("Unexpected inhibitors %v", res)
+	}
+}
+
+func TestInhibitRuleDelay(t *testing.T) {
+	now := time.Now()
+	r := NewInhibitRule(&config.InhibitRule{
+		SourceMatch: map[string]string{"alertname": "DatacenterDown"},
+		TargetMatch: map[string]string{"severity": "warning"},
+		Equal:       model.LabelNames{"dc"},
+		Delay:       model.Duration(5 * time.Minute),
+	})
+	source := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "DatacenterDown", "dc": "eu"},
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Hour),
+		},
+	}
+	r.set(source)
+	target := model.LabelSet{"alertname": "HighLatency", "severity": "warning", "dc": "eu"}
+
+	// The source alert does not inhibit before it has been firing for
+	// the delay.
+	if _, ok := r.hasEqual(target, now); ok {
+		t.Errorf("Expected no inhibition before the delay")
+	}
+	if res := r.sources(target, now); len(res) != 0 {
+		t.Errorf("Unexpected sources %v", res)
+	}
+	if _, ok := r.hasEqual(target, now.Add(4*time.Minute)); !ok {
+		t.Errorf("Expected inhibition after the delay")
+	}
+	if res := r.sources(target, now.Add(4*time.Minute)); !reflect.DeepEqual(res, []*types.Alert{source}) {
+		t.Errorf("Unexpected sources %v", res)
+	}
+}
+
+func TestInhibitRuleEqualMappings(t *testing.T) {
+	now := time.Now()
+	r := NewInhibitRule(&config.InhibitRule{
+		SourceMatch:   map[string]string{"alertname": "ClusterDown"},
+		TargetMatch:   map[string]string{"severity": "warning"},
+		Equal:         model.LabelNames{"env"},
+		EqualMappings: []*config.LabelMapping{{Source: "cluster", Target: "kubernetes_cluster"}},
+	})
+	r.set(&types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "ClusterDown", "cluster": "eu-1", "env": "prod"},
+			StartsAt: now.Add(-time.Minute),
+			EndsAt:   now.Add(time.Hour),
+		},
+	})
+
+	for _, tc := range []struct {
+		lset  model.LabelSet
+		equal bool
+	}{
+		{model.LabelSet{"kubernetes_cluster": "eu-1", "env": "prod"}, true},
+		{model.LabelSet{"kubernetes_cluster": "eu-2", "env": "prod"}, false},
+		{model.LabelSet{"cluster": "eu-1", "env": "prod"}, false},
+		{model.LabelSet{"kubernetes_cluster": "eu-1", "env": "dev"}, false},
+	} {
+		if _, ok := r.hasEqual(tc.lset, now); ok != tc.equal {
+			t.Errorf("Unexpected result %t for %v, expected %t", ok, tc.lset, tc.equal)
+		}
+	}
+}
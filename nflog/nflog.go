@@ -24,6 +24,7 @@ import (
 	"io"
 	"math/rand"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/matttproud/golang_protobuf_extensions/pbutil"
 	pb "github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pkg/snapcrypt"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -46,8 +48,9 @@ var ErrInvalidState = fmt.Errorf("invalid state")
 // TODO(fabxc): Future versions could allow querying a certain receiver
 // group or a given time interval.
 type query struct {
-	recv     *pb.Receiver
-	groupKey string
+	recv      *pb.Receiver
+	groupKey  string
+	alertFP   *uint64
 }
 
 // QueryParam is a function that modifies a query to incorporate
@@ -71,6 +74,15 @@ func QGroupKey(gk string) QueryParam {
 	}
 }
 
+// QAlert queries all entries of notifications that included the alert with
+// the given fingerprint. It cannot be combined with other parameters.
+func QAlert(fp uint64) QueryParam {
+	return func(q *query) error {
+		q.alertFP = &fp
+		return nil
+	}
+}
+
 type Log struct {
 	logger    log.Logger
 	metrics   *metrics
@@ -78,7 +90,10 @@ type Log struct {
 	retention time.Duration
 
 	runInterval time.Duration
+	maxEntries  int
+	maxBytes    int64
 	snapf       string
+	cipher      *snapcrypt.Cipher
 	stopc       chan struct{}
 	done        func()
 
@@ -91,6 +106,7 @@ type Log struct {
 
 type metrics struct {
 	gcDuration       prometheus.Summary
+	evictionsTotal   *prometheus.CounterVec
 	snapshotDuration prometheus.Summary
 	snapshotSize     prometheus.Gauge
 	queriesTotal     prometheus.Counter
@@ -105,6 +121,10 @@ func newMetrics(r prometheus.Registerer) *metrics {
 		Name: "alertmanager_nflog_gc_duration_seconds",
 		Help: "Duration of the last notification log garbage collection cycle.",
 	})
+	m.evictionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "alertmanager_nflog_evictions_total",
+		Help: "Number of notification log entries evicted by compaction because the log exceeded its size limits.",
+	}, []string{"reason"})
 	m.snapshotDuration = prometheus.NewSummary(prometheus.SummaryOpts{
 		Name: "alertmanager_nflog_snapshot_duration_seconds",
 		Help: "Duration of the last notification log snapshot.",
@@ -129,6 +149,7 @@ func newMetrics(r prometheus.Registerer) *metrics {
 	if r != nil {
 		r.MustRegister(
 			m.gcDuration,
+			m.evictionsTotal,
 			m.snapshotDuration,
 			m.queriesTotal,
 			m.queryErrorsTotal,
@@ -192,6 +213,31 @@ func WithMaintenance(d time.Duration, stopc chan struct{}, done func()) Option {
 	}
 }
 
+// WithMaxEntries limits the number of entries in the log. Compaction during
+// maintenance evicts entries exceeding the limit. Zero means no limit.
+func WithMaxEntries(n int) Option {
+	return func(l *Log) error {
+		if n < 0 {
+			return fmt.Errorf("maximum number of entries must not be negative")
+		}
+		l.maxEntries = n
+		return nil
+	}
+}
+
+// WithMaxBytes limits the encoded size of the log's entries, which roughly
+// is the size of its snapshots. Compaction during maintenance evicts entries
+// exceeding the limit. Zero means no limit.
+func WithMaxBytes(n int64) Option {
+	return func(l *Log) error {
+		if n < 0 {
+			return fmt.Errorf("maximum size must not be negative")
+		}
+		l.maxBytes = n
+		return nil
+	}
+}
+
 // WithSnapshot configures the log to be initialized from a given snapshot file.
 // If maintenance is configured, a snapshot will be saved periodically and on
 // shutdown as well.
@@ -202,6 +248,15 @@ func WithSnapshot(sf string) Option {
 	}
 }
 
+// WithCipher configures the log to encrypt its snapshots with the given
+// cipher.
+func WithCipher(c *snapcrypt.Cipher) Option {
+	return func(l *Log) error {
+		l.cipher = c
+		return nil
+	}
+}
+
 func utcNow() time.Time {
 	return time.Now().UTC()
 }
@@ -289,7 +344,11 @@ func New(opts ...Option) (*Log, error) {
 			}
 			defer f.Close()
 
-			if err := l.loadSnapshot(f); err != nil {
+			r, err := l.cipher.Reader(f)
+			if err != nil {
+				return l, err
+			}
+			if err := l.loadSnapshot(r); err != nil {
 				return l, err
 			}
 		}
@@ -325,6 +384,9 @@ func (l *Log) run() {
 		if _, err := l.GC(); err != nil {
 			return err
 		}
+		if n := l.Compact(); n > 0 {
+			level.Warn(l.logger).Log("msg", "Notification log exceeded its size limits, evicted entries", "evicted", n)
+		}
 		if l.snapf == "" {
 			return nil
 		}
@@ -332,7 +394,11 @@ func (l *Log) run() {
 		if err != nil {
 			return err
 		}
-		if size, err = l.Snapshot(f); err != nil {
+		w := l.cipher.Writer(f)
+		if size, err = l.Snapshot(w); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
 			return err
 		}
 		return f.Close()
@@ -368,7 +434,11 @@ func stateKey(k string, r *pb.Receiver) string {
 	return fmt.Sprintf("%s:%s", k, receiverKey(r))
 }
 
-func (l *Log) Log(r *pb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64) error {
+// Log records a successful notification of the group with the given key to
+// the receiver with the hashes and fingerprints of the notified alerts. The
+// entry expires after the given duration or, if it is zero, after the
+// retention of the log.
+func (l *Log) Log(r *pb.Receiver, gkey string, firingAlerts, resolvedAlerts, firingFingerprints, resolvedFingerprints []uint64, expiry time.Duration) error {
 	// Write all st with the same timestamp.
 	now := l.now()
 	key := stateKey(gkey, r)
@@ -376,6 +446,10 @@ func (l *Log) Log(r *pb.Receiver, gkey string, firingAlerts, resolvedAlerts []ui
 	l.mtx.Lock()
 	defer l.mtx.Unlock()
 
+	if expiry == 0 {
+		expiry = l.retention
+	}
+
 	if prevle, ok := l.st[key]; ok {
 		// Entry already exists, only overwrite if timestamp is newer.
 		// This may happen with raciness or clock-drift across AM nodes.
@@ -389,10 +463,12 @@ func (l *Log) Log(r *pb.Receiver, gkey string, firingAlerts, resolvedAlerts []ui
 			Receiver:       r,
 			GroupKey:       []byte(gkey),
 			Timestamp:      now,
-			FiringAlerts:   firingAlerts,
-			ResolvedAlerts: resolvedAlerts,
+			FiringAlerts:         firingAlerts,
+			ResolvedAlerts:       resolvedAlerts,
+			FiringFingerprints:   firingFingerprints,
+			ResolvedFingerprints: resolvedFingerprints,
 		},
-		ExpiresAt: now.Add(l.retention),
+		ExpiresAt: now.Add(expiry),
 	}
 
 	b, err := marshalMeshEntry(e)
@@ -429,6 +505,57 @@ func (l *Log) GC() (int, error) {
 	return n, nil
 }
 
+// Compact evicts entries until the log is within its size limits and returns
+// the number of evicted entries. Entries of groups whose alerts all resolved
+// are evicted first as they are least likely to be needed again, the oldest
+// first. Evicting the entry of a firing group causes it to be notified again.
+func (l *Log) Compact() int {
+	if l.maxEntries == 0 && l.maxBytes == 0 {
+		return 0
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var size int64
+	for _, le := range l.st {
+		size += int64(le.Size())
+	}
+	if (l.maxEntries == 0 || len(l.st) <= l.maxEntries) && (l.maxBytes == 0 || size <= l.maxBytes) {
+		return 0
+	}
+
+	keys := make([]string, 0, len(l.st))
+	for k := range l.st {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ei, ej := l.st[keys[i]].Entry, l.st[keys[j]].Entry
+		if ri, rj := len(ei.FiringAlerts) == 0, len(ej.FiringAlerts) == 0; ri != rj {
+			return ri
+		}
+		return ei.Timestamp.Before(ej.Timestamp)
+	})
+
+	var n int
+	for _, k := range keys {
+		var reason string
+		switch {
+		case l.maxEntries > 0 && len(l.st) > l.maxEntries:
+			reason = "max_entries"
+		case l.maxBytes > 0 && size > l.maxBytes:
+			reason = "max_bytes"
+		default:
+			return n
+		}
+		size -= int64(l.st[k].Size())
+		delete(l.st, k)
+		l.metrics.evictionsTotal.WithLabelValues(reason).Inc()
+		n++
+	}
+	return n
+}
+
 // Query implements the Log interface.
 func (l *Log) Query(params ...QueryParam) ([]*pb.Entry, error) {
 	start := time.Now()
@@ -441,6 +568,12 @@ func (l *Log) Query(params ...QueryParam) ([]*pb.Entry, error) {
 				return nil, err
 			}
 		}
+		if q.alertFP != nil {
+			if q.recv != nil || q.groupKey != "" {
+				return nil, errors.New("alert queries cannot be combined with other parameters")
+			}
+			return l.queryAlert(*q.alertFP), nil
+		}
 		// TODO(fabxc): For now our only query mode is the most recent entry for a
 		// receiver/group_key combination.
 		if q.recv == nil || q.groupKey == "" {
@@ -464,6 +597,47 @@ func (l *Log) Query(params ...QueryParam) ([]*pb.Entry, error) {
 	return entries, err
 }
 
+// Entries returns all entries in the log sorted by group key and receiver.
+func (l *Log) Entries() []*pb.Entry {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	res := make([]*pb.Entry, 0, len(l.st))
+	for _, le := range l.st {
+		res = append(res, le.Entry)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if gi, gj := string(res[i].GroupKey), string(res[j].GroupKey); gi != gj {
+			return gi < gj
+		}
+		return receiverKey(res[i].Receiver) < receiverKey(res[j].Receiver)
+	})
+	return res
+}
+
+// queryAlert returns all entries containing the given alert fingerprint.
+func (l *Log) queryAlert(fp uint64) []*pb.Entry {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	res := []*pb.Entry{}
+	for _, le := range l.st {
+		if contains(le.Entry.FiringFingerprints, fp) || contains(le.Entry.ResolvedFingerprints, fp) {
+			res = append(res, le.Entry)
+		}
+	}
+	return res
+}
+
+func contains(values []uint64, v uint64) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
 // loadSnapshot loads a snapshot generated by Snapshot() into the state.
 func (l *Log) loadSnapshot(r io.Reader) error {
 	st, err := decodeState(r)
@@ -22,6 +22,8 @@ import (
 	"time"
 
 	pb "github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pkg/snapcrypt"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -53,6 +55,79 @@ func TestLogGC(t *testing.T) {
 	require.Equal(t, l.st, expected, "unepexcted state after garbage collection")
 }
 
+func TestLogExpiry(t *testing.T) {
+	now := utcNow()
+	nl, err := New(WithRetention(time.Hour), WithNow(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	recv1 := &pb.Receiver{GroupName: "pager", Integration: "pagerduty"}
+	recv2 := &pb.Receiver{GroupName: "chatty", Integration: "webhook"}
+	require.NoError(t, nl.Log(recv1, "key", []uint64{1}, nil, nil, nil, 0))
+	require.NoError(t, nl.Log(recv2, "key", []uint64{1}, nil, nil, nil, time.Minute))
+
+	require.Equal(t, now.Add(time.Hour), nl.st[stateKey("key", recv1)].ExpiresAt)
+	require.Equal(t, now.Add(time.Minute), nl.st[stateKey("key", recv2)].ExpiresAt)
+
+	// Only the entry with the shorter retention is collected.
+	now = now.Add(2 * time.Minute)
+	n, err := nl.GC()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	_, err = nl.Query(QGroupKey("key"), QReceiver(recv1))
+	require.NoError(t, err)
+	_, err = nl.Query(QGroupKey("key"), QReceiver(recv2))
+	require.Equal(t, ErrNotFound, err)
+}
+
+func TestLogCompact(t *testing.T) {
+	now := utcNow()
+	l, err := New(WithMaxEntries(3), WithNow(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	recv := &pb.Receiver{GroupName: "team-X", Integration: "webhook"}
+	for i, e := range []struct {
+		key      string
+		firing   []uint64
+		resolved []uint64
+	}{
+		{"firing-old", []uint64{1}, nil},
+		{"resolved-old", nil, []uint64{2}},
+		{"firing-new", []uint64{3}, nil},
+		{"resolved-new", nil, []uint64{4}},
+		{"firing-newest", []uint64{5}, []uint64{6}},
+	} {
+		now = now.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, l.Log(recv, e.key, e.firing, e.resolved, nil, nil, 0))
+	}
+	require.Equal(t, 0, (&Log{st: l.st}).Compact(), "log without limits is not compacted")
+
+	// Resolved groups are evicted first.
+	require.Equal(t, 2, l.Compact())
+	keys := func() []string {
+		var res []string
+		for _, e := range l.Entries() {
+			res = append(res, string(e.GroupKey))
+		}
+		return res
+	}
+	require.Equal(t, []string{"firing-new", "firing-newest", "firing-old"}, keys())
+	require.Equal(t, 0, l.Compact())
+
+	// Firing groups are evicted, the oldest first, if there are not
+	// enough resolved ones.
+	l.maxEntries = 0
+	l.maxBytes = int64(l.st[stateKey("firing-newest", recv)].Size())
+	require.Equal(t, 2, l.Compact())
+	require.Equal(t, []string{"firing-newest"}, keys())
+
+	var m dto.Metric
+	require.NoError(t, l.metrics.evictionsTotal.WithLabelValues("max_entries").Write(&m))
+	require.Equal(t, 2.0, m.GetCounter().GetValue())
+	require.NoError(t, l.metrics.evictionsTotal.WithLabelValues("max_bytes").Write(&m))
+	require.Equal(t, 2.0, m.GetCounter().GetValue())
+}
+
 func TestLogSnapshot(t *testing.T) {
 	// Check whether storing and loading the snapshot is symmetric.
 	now := utcNow()
@@ -89,6 +164,17 @@ func TestLogSnapshot(t *testing.T) {
 						Timestamp: now,
 					},
 					ExpiresAt: now,
+				}, {
+					Entry: &pb.Entry{
+						GroupKey:             []byte("bbbbbca2dc0f896fd7cb4cb0031ba249"),
+						Receiver:             &pb.Receiver{GroupName: "jkl", Integration: "test4", Idx: 0},
+						Timestamp:            now,
+						FiringAlerts:         []uint64{1, 1 << 40},
+						ResolvedAlerts:       []uint64{2},
+						FiringFingerprints:   []uint64{3, 1 << 63},
+						ResolvedFingerprints: []uint64{4},
+					},
+					ExpiresAt: now,
 				},
 			},
 		},
@@ -123,6 +209,41 @@ func TestLogSnapshot(t *testing.T) {
 	}
 }
 
+func TestLogEncryptedSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nflog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	snapf := filepath.Join(dir, "nflog")
+
+	c, err := snapcrypt.New(bytes.Repeat([]byte{0x42}, 32))
+	require.NoError(t, err)
+
+	stopc := make(chan struct{})
+	done := make(chan struct{})
+	l1, err := New(
+		WithSnapshot(snapf),
+		WithCipher(c),
+		WithRetention(time.Hour),
+		WithMaintenance(time.Hour, stopc, func() { close(done) }),
+	)
+	require.NoError(t, err)
+	recv := &pb.Receiver{GroupName: "customer-42", Integration: "email", Idx: 0}
+	require.NoError(t, l1.Log(recv, "{}:{alertname=\"test\"}", []uint64{1}, nil, nil, nil, 0))
+	close(stopc)
+	<-done
+
+	b, err := ioutil.ReadFile(snapf)
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(b, []byte("customer-42")), "snapshot is not encrypted")
+
+	l2, err := New(WithSnapshot(snapf), WithCipher(c))
+	require.NoError(t, err)
+	require.Equal(t, l1.st, l2.st)
+
+	_, err = New(WithSnapshot(snapf))
+	require.Error(t, err)
+}
+
 func TestReplaceFile(t *testing.T) {
 	dir, err := ioutil.TempDir("", "replace_file")
 	require.NoError(t, err, "creating temp dir failed")
@@ -287,7 +408,7 @@ func TestQuery(t *testing.T) {
 	firingAlerts := []uint64{1, 2, 3}
 	resolvedAlerts := []uint64{4, 5}
 
-	err = nl.Log(recv, "key", firingAlerts, resolvedAlerts)
+	err = nl.Log(recv, "key", firingAlerts, resolvedAlerts, nil, nil, 0)
 	require.NoError(t, err, "logging notification failed")
 
 	entries, err := nl.Query(QGroupKey("key"), QReceiver(recv))
@@ -297,6 +418,58 @@ func TestQuery(t *testing.T) {
 	require.EqualValues(t, resolvedAlerts, entry.ResolvedAlerts)
 }
 
+func TestQueryAlert(t *testing.T) {
+	nl, err := New()
+	require.NoError(t, err, "constructing nflog failed")
+
+	recv1 := &pb.Receiver{GroupName: "team-X", Integration: "webhook"}
+	recv2 := &pb.Receiver{GroupName: "team-Y", Integration: "email"}
+	require.NoError(t, nl.Log(recv1, "key1", []uint64{11, 12}, nil, []uint64{1, 2}, nil, 0))
+	require.NoError(t, nl.Log(recv2, "key2", []uint64{13}, []uint64{12}, []uint64{3}, []uint64{2}, 0))
+
+	entries, err := nl.Query(QAlert(2))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	entries, err = nl.Query(QAlert(3))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, recv2, entries[0].Receiver)
+
+	entries, err = nl.Query(QAlert(4))
+	require.NoError(t, err)
+	require.Len(t, entries, 0)
+
+	_, err = nl.Query(QAlert(1), QGroupKey("key1"))
+	require.EqualError(t, err, "alert queries cannot be combined with other parameters")
+}
+
+func TestEntries(t *testing.T) {
+	nl, err := New()
+	require.NoError(t, err, "constructing nflog failed")
+	require.Len(t, nl.Entries(), 0)
+
+	recv1 := &pb.Receiver{GroupName: "team-X", Integration: "webhook"}
+	recv2 := &pb.Receiver{GroupName: "team-Y", Integration: "email"}
+	require.NoError(t, nl.Log(recv2, "key2", []uint64{3}, nil, nil, nil, 0))
+	require.NoError(t, nl.Log(recv2, "key1", []uint64{2}, nil, nil, nil, 0))
+	require.NoError(t, nl.Log(recv1, "key1", []uint64{1}, []uint64{2}, nil, nil, 0))
+
+	entries := nl.Entries()
+	require.Len(t, entries, 3)
+	for i, exp := range []struct {
+		groupKey string
+		recv     *pb.Receiver
+	}{
+		{"key1", recv1},
+		{"key1", recv2},
+		{"key2", recv2},
+	} {
+		require.Equal(t, exp.groupKey, string(entries[i].GroupKey))
+		require.Equal(t, exp.recv, entries[i].Receiver)
+	}
+}
+
 func TestStateDecodingError(t *testing.T) {
 	// Check whether decoding copes with erroneous data.
 	s := state{"": &pb.MeshEntry{}}
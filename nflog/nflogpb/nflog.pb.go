@@ -72,6 +72,10 @@ type Entry struct {
 	FiringAlerts []uint64 `protobuf:"varint,6,rep,packed,name=firing_alerts,json=firingAlerts" json:"firing_alerts,omitempty"`
 	// ResolvedAlerts list of hashes of resolved alerts at the last notification time.
 	ResolvedAlerts []uint64 `protobuf:"varint,7,rep,packed,name=resolved_alerts,json=resolvedAlerts" json:"resolved_alerts,omitempty"`
+	// FiringFingerprints list of fingerprints of firing alerts at the last notification time.
+	FiringFingerprints []uint64 `protobuf:"varint,8,rep,packed,name=firing_fingerprints,json=firingFingerprints" json:"firing_fingerprints,omitempty"`
+	// ResolvedFingerprints list of fingerprints of resolved alerts at the last notification time.
+	ResolvedFingerprints []uint64 `protobuf:"varint,9,rep,packed,name=resolved_fingerprints,json=resolvedFingerprints" json:"resolved_fingerprints,omitempty"`
 }
 
 func (m *Entry) Reset()                    { *m = Entry{} }
@@ -223,6 +227,40 @@ func (m *Entry) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintNflog(dAtA, i, uint64(j5))
 		i += copy(dAtA[i:], dAtA6[:j5])
 	}
+	if len(m.FiringFingerprints) > 0 {
+		dAtA8 := make([]byte, len(m.FiringFingerprints)*10)
+		var j7 int
+		for _, num := range m.FiringFingerprints {
+			for num >= 1<<7 {
+				dAtA8[j7] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j7++
+			}
+			dAtA8[j7] = uint8(num)
+			j7++
+		}
+		dAtA[i] = 0x42
+		i++
+		i = encodeVarintNflog(dAtA, i, uint64(j7))
+		i += copy(dAtA[i:], dAtA8[:j7])
+	}
+	if len(m.ResolvedFingerprints) > 0 {
+		dAtA10 := make([]byte, len(m.ResolvedFingerprints)*10)
+		var j9 int
+		for _, num := range m.ResolvedFingerprints {
+			for num >= 1<<7 {
+				dAtA10[j9] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j9++
+			}
+			dAtA10[j9] = uint8(num)
+			j9++
+		}
+		dAtA[i] = 0x4a
+		i++
+		i = encodeVarintNflog(dAtA, i, uint64(j9))
+		i += copy(dAtA[i:], dAtA10[:j9])
+	}
 	return i, nil
 }
 
@@ -245,20 +283,20 @@ func (m *MeshEntry) MarshalTo(dAtA []byte) (int, error) {
 		dAtA[i] = 0xa
 		i++
 		i = encodeVarintNflog(dAtA, i, uint64(m.Entry.Size()))
-		n7, err := m.Entry.MarshalTo(dAtA[i:])
+		n11, err := m.Entry.MarshalTo(dAtA[i:])
 		if err != nil {
 			return 0, err
 		}
-		i += n7
+		i += n11
 	}
 	dAtA[i] = 0x12
 	i++
 	i = encodeVarintNflog(dAtA, i, uint64(types.SizeOfStdTime(m.ExpiresAt)))
-	n8, err := types.StdTimeMarshalTo(m.ExpiresAt, dAtA[i:])
+	n12, err := types.StdTimeMarshalTo(m.ExpiresAt, dAtA[i:])
 	if err != nil {
 		return 0, err
 	}
-	i += n8
+	i += n12
 	return i, nil
 }
 
@@ -322,6 +360,20 @@ func (m *Entry) Size() (n int) {
 		}
 		n += 1 + sovNflog(uint64(l)) + l
 	}
+	if len(m.FiringFingerprints) > 0 {
+		l = 0
+		for _, e := range m.FiringFingerprints {
+			l += sovNflog(uint64(e))
+		}
+		n += 1 + sovNflog(uint64(l)) + l
+	}
+	if len(m.ResolvedFingerprints) > 0 {
+		l = 0
+		for _, e := range m.ResolvedFingerprints {
+			l += sovNflog(uint64(e))
+		}
+		n += 1 + sovNflog(uint64(l)) + l
+	}
 	return n
 }
 
@@ -775,6 +827,130 @@ func (m *Entry) Unmarshal(dAtA []byte) error {
 			} else {
 				return fmt.Errorf("proto: wrong wireType = %d for field ResolvedAlerts", wireType)
 			}
+		case 8:
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowNflog
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.FiringFingerprints = append(m.FiringFingerprints, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowNflog
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthNflog
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowNflog
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.FiringFingerprints = append(m.FiringFingerprints, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field FiringFingerprints", wireType)
+			}
+		case 9:
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowNflog
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= (uint64(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.ResolvedFingerprints = append(m.ResolvedFingerprints, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowNflog
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= (int(b) & 0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthNflog
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowNflog
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= (uint64(b) & 0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.ResolvedFingerprints = append(m.ResolvedFingerprints, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResolvedFingerprints", wireType)
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipNflog(dAtA[iNdEx:])
@@ -1017,30 +1193,31 @@ var (
 func init() { proto.RegisterFile("nflog.proto", fileDescriptorNflog) }
 
 var fileDescriptorNflog = []byte{
-	// 385 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x90, 0xcf, 0x6e, 0xd3, 0x40,
-	0x10, 0xc6, 0xbb, 0x4d, 0xd3, 0xda, 0xe3, 0xb4, 0x94, 0x15, 0x07, 0xcb, 0x08, 0xc7, 0x0a, 0x48,
-	0xf8, 0x82, 0x23, 0x95, 0x27, 0x68, 0x10, 0x12, 0x12, 0x82, 0xc3, 0x8a, 0x2b, 0xb2, 0x36, 0x74,
-	0xb2, 0x5e, 0x61, 0x7b, 0xad, 0xf5, 0x36, 0x6a, 0xde, 0x82, 0x47, 0xe0, 0x71, 0x72, 0xe4, 0x09,
-	0xf8, 0x93, 0x27, 0x41, 0xde, 0xb5, 0x1d, 0x8e, 0xdc, 0x66, 0x7f, 0xf3, 0xcd, 0xcc, 0xb7, 0x1f,
-	0x04, 0xf5, 0xa6, 0x54, 0x22, 0x6b, 0xb4, 0x32, 0x8a, 0x5e, 0xd8, 0x47, 0xb3, 0x8e, 0xe6, 0x42,
-	0x29, 0x51, 0xe2, 0xd2, 0xe2, 0xf5, 0xfd, 0x66, 0x69, 0x64, 0x85, 0xad, 0xe1, 0x55, 0xe3, 0x94,
-	0xd1, 0x13, 0xa1, 0x84, 0xb2, 0xe5, 0xb2, 0xab, 0x1c, 0x5d, 0x7c, 0x06, 0x8f, 0xe1, 0x17, 0x94,
-	0x5b, 0xd4, 0xf4, 0x19, 0x80, 0xd0, 0xea, 0xbe, 0xc9, 0x6b, 0x5e, 0x61, 0x48, 0x12, 0x92, 0xfa,
-	0xcc, 0xb7, 0xe4, 0x23, 0xaf, 0x90, 0x26, 0x10, 0xc8, 0xda, 0xa0, 0xd0, 0xdc, 0x48, 0x55, 0x87,
-	0xa7, 0xb6, 0xff, 0x2f, 0xa2, 0xd7, 0x30, 0x91, 0x77, 0x0f, 0xe1, 0x24, 0x21, 0xe9, 0x25, 0xeb,
-	0xca, 0xc5, 0xf7, 0x53, 0x98, 0xbe, 0xad, 0x8d, 0xde, 0xd1, 0xa7, 0xe0, 0x56, 0xe5, 0x5f, 0x71,
-	0x67, 0x77, 0xcf, 0x98, 0x67, 0xc1, 0x7b, 0xdc, 0xd1, 0x57, 0xe0, 0xe9, 0xde, 0x85, 0xdd, 0x1b,
-	0xdc, 0x3c, 0xce, 0xfa, 0x8f, 0x65, 0x83, 0x3d, 0x36, 0x4a, 0x8e, 0x46, 0x0b, 0xde, 0x16, 0xf6,
-	0xdc, 0xac, 0x37, 0xfa, 0x8e, 0xb7, 0x05, 0x8d, 0xba, 0x6d, 0xad, 0x2a, 0xb7, 0x78, 0x17, 0x9e,
-	0x25, 0x24, 0xf5, 0xd8, 0xf8, 0xa6, 0x2b, 0xf0, 0xc7, 0x60, 0xc2, 0xa9, 0x3d, 0x15, 0x65, 0x2e,
-	0xba, 0x6c, 0x88, 0x2e, 0xfb, 0x34, 0x28, 0x56, 0xde, 0xfe, 0xe7, 0xfc, 0xe4, 0xdb, 0xaf, 0x39,
-	0x61, 0xc7, 0x31, 0xfa, 0x1c, 0x2e, 0x37, 0x52, 0xcb, 0x5a, 0xe4, 0xbc, 0x44, 0x6d, 0xda, 0xf0,
-	0x3c, 0x99, 0xa4, 0x67, 0x6c, 0xe6, 0xe0, 0xad, 0x65, 0xf4, 0x25, 0x3c, 0x1a, 0x8e, 0x0e, 0xb2,
-	0x0b, 0x2b, 0xbb, 0x1a, 0xb0, 0x13, 0x2e, 0xb6, 0xe0, 0x7f, 0xc0, 0xb6, 0x70, 0x29, 0xbd, 0x80,
-	0x29, 0x76, 0x85, 0x4d, 0x28, 0xb8, 0xb9, 0x1a, 0x53, 0xb0, 0x6d, 0xe6, 0x9a, 0xf4, 0x0d, 0x00,
-	0x3e, 0x34, 0x52, 0x63, 0x9b, 0x73, 0xd3, 0x07, 0xf6, 0x9f, 0xbf, 0xe8, 0xe7, 0x6e, 0xcd, 0xea,
-	0x7a, 0xff, 0x27, 0x3e, 0xd9, 0x1f, 0x62, 0xf2, 0xe3, 0x10, 0x93, 0xdf, 0x87, 0x98, 0xac, 0xcf,
-	0xed, 0xe8, 0xeb, 0xbf, 0x01, 0x00, 0x00, 0xff, 0xff, 0x49, 0xcd, 0xa7, 0x1e, 0x61, 0x02, 0x00,
-	0x00,
+	// 415 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x51, 0xcd, 0x6e, 0x13, 0x31,
+	0x10, 0xee, 0x36, 0x4d, 0xbb, 0x3b, 0x49, 0x4b, 0x31, 0x45, 0xb2, 0x82, 0x48, 0x56, 0x01, 0x89,
+	0x5c, 0xd8, 0x48, 0xed, 0x13, 0x34, 0x08, 0x84, 0x84, 0xe0, 0x60, 0x71, 0x45, 0x91, 0x43, 0x27,
+	0x8e, 0x45, 0x62, 0xaf, 0x6c, 0x37, 0x6a, 0x5e, 0x80, 0x33, 0x8f, 0x95, 0x23, 0x4f, 0xc0, 0x4f,
+	0x9e, 0x04, 0x65, 0xbc, 0x1b, 0x82, 0x38, 0xf5, 0x36, 0xfb, 0xfd, 0xcd, 0xec, 0x67, 0x68, 0x99,
+	0xe9, 0xdc, 0xaa, 0xa2, 0x74, 0x36, 0x58, 0x76, 0x42, 0x1f, 0xe5, 0xa4, 0xd3, 0x53, 0xd6, 0xaa,
+	0x39, 0x0e, 0x09, 0x9e, 0xdc, 0x4e, 0x87, 0x41, 0x2f, 0xd0, 0x07, 0xb9, 0x28, 0xa3, 0xb2, 0x73,
+	0xa1, 0xac, 0xb2, 0x34, 0x0e, 0xb7, 0x53, 0x44, 0xfb, 0x9f, 0x20, 0x15, 0xf8, 0x19, 0xf5, 0x12,
+	0x1d, 0x7b, 0x0a, 0xa0, 0x9c, 0xbd, 0x2d, 0xc7, 0x46, 0x2e, 0x90, 0x27, 0x79, 0x32, 0xc8, 0x44,
+	0x46, 0xc8, 0x07, 0xb9, 0x40, 0x96, 0x43, 0x4b, 0x9b, 0x80, 0xca, 0xc9, 0xa0, 0xad, 0xe1, 0x87,
+	0xc4, 0xef, 0x43, 0xec, 0x1c, 0x1a, 0xfa, 0xe6, 0x8e, 0x37, 0xf2, 0x64, 0x70, 0x2a, 0xb6, 0x63,
+	0xff, 0x6b, 0x03, 0x9a, 0xaf, 0x4d, 0x70, 0x2b, 0xf6, 0x04, 0x62, 0xd4, 0xf8, 0x0b, 0xae, 0x28,
+	0xbb, 0x2d, 0x52, 0x02, 0xde, 0xe1, 0x8a, 0xbd, 0x84, 0xd4, 0x55, 0x57, 0x50, 0x6e, 0xeb, 0xf2,
+	0x61, 0x51, 0xfd, 0x58, 0x51, 0x9f, 0x27, 0x52, 0xf7, 0xdf, 0xa1, 0x33, 0xe9, 0x67, 0xb4, 0xae,
+	0x5d, 0x1d, 0xfa, 0x56, 0xfa, 0x19, 0xeb, 0x6c, 0xd3, 0xbc, 0x9d, 0x2f, 0xf1, 0x86, 0x1f, 0xe5,
+	0xc9, 0x20, 0x15, 0xbb, 0x6f, 0x36, 0x82, 0x6c, 0x57, 0x0c, 0x6f, 0xd2, 0xaa, 0x4e, 0x11, 0xab,
+	0x2b, 0xea, 0xea, 0x8a, 0x8f, 0xb5, 0x62, 0x94, 0xae, 0x7f, 0xf4, 0x0e, 0xbe, 0xfd, 0xec, 0x25,
+	0xe2, 0xaf, 0x8d, 0x3d, 0x83, 0xd3, 0xa9, 0x76, 0xda, 0xa8, 0xb1, 0x9c, 0xa3, 0x0b, 0x9e, 0x1f,
+	0xe7, 0x8d, 0xc1, 0x91, 0x68, 0x47, 0xf0, 0x9a, 0x30, 0xf6, 0x02, 0x1e, 0xd4, 0x4b, 0x6b, 0xd9,
+	0x09, 0xc9, 0xce, 0x6a, 0xb8, 0x12, 0x0e, 0xe1, 0x51, 0x95, 0x36, 0xd5, 0x46, 0xa1, 0x2b, 0x9d,
+	0x36, 0xc1, 0xf3, 0x94, 0xc4, 0x2c, 0x52, 0x6f, 0xf6, 0x18, 0x76, 0x05, 0x8f, 0x77, 0xc9, 0xff,
+	0x58, 0x32, 0xb2, 0x5c, 0xd4, 0xe4, 0xbe, 0xa9, 0xbf, 0x84, 0xec, 0x3d, 0xfa, 0x59, 0x7c, 0x8b,
+	0xe7, 0xd0, 0xc4, 0xed, 0x40, 0xef, 0xd0, 0xba, 0x3c, 0xdb, 0x75, 0x4d, 0xb4, 0x88, 0x24, 0x7b,
+	0x05, 0x80, 0x77, 0xa5, 0x76, 0xe8, 0xc7, 0x32, 0xf0, 0xc3, 0xfb, 0x74, 0x55, 0xf9, 0xae, 0xc3,
+	0xe8, 0x7c, 0xfd, 0xbb, 0x7b, 0xb0, 0xde, 0x74, 0x93, 0xef, 0x9b, 0x6e, 0xf2, 0x6b, 0xd3, 0x4d,
+	0x26, 0xc7, 0x64, 0xbd, 0xfa, 0x33, 0x00, 0x3e, 0x62, 0x6d, 0xea, 0xc7, 0x02, 0x00, 0x00,
 }
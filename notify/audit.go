@@ -0,0 +1,105 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/audit"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// AuditLog records notification attempts.
+type AuditLog interface {
+	Log(e *audit.Entry) error
+}
+
+// auditNotifier records every notification attempt of the wrapped notifier
+// in the audit log.
+type auditNotifier struct {
+	notifier Notifier
+	log      AuditLog
+	recv     *nflogpb.Receiver
+	logger   log.Logger
+}
+
+// Notify implements the Notifier interface.
+func (n *auditNotifier) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+	rec := &statusRecorder{}
+	ctx = context.WithValue(ctx, keyStatusRecorder, rec)
+
+	start := time.Now()
+	retry, err := n.notifier.Notify(ctx, alerts...)
+
+	gkey, _ := GroupKey(ctx)
+	e := &audit.Entry{
+		Timestamp:      start.UTC(),
+		Receiver:       n.recv.GroupName,
+		Integration:    n.recv.Integration,
+		GroupKey:       gkey,
+		Alerts:         make([]string, 0, len(alerts)),
+		Success:        err == nil,
+		LatencySeconds: time.Since(start).Seconds(),
+		StatusCode:     rec.get(),
+	}
+	for _, a := range alerts {
+		e.Alerts = append(e.Alerts, a.Fingerprint().String())
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if lerr := n.log.Log(e); lerr != nil {
+		level.Error(n.logger).Log("msg", "Failed to write audit log", "err", lerr)
+	}
+	return retry, err
+}
+
+// statusRecorder holds the status code of the last HTTP response received
+// during a notification.
+type statusRecorder struct {
+	mtx  sync.Mutex
+	code int
+}
+
+func (r *statusRecorder) set(code int) {
+	r.mtx.Lock()
+	r.code = code
+	r.mtx.Unlock()
+}
+
+func (r *statusRecorder) get() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.code
+}
+
+type statusRecordingRoundTripper struct {
+	rec  *statusRecorder
+	next http.RoundTripper
+}
+
+func (rt *statusRecordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := rt.next.RoundTrip(req)
+	if err == nil {
+		rt.rec.set(resp.StatusCode)
+	}
+	return resp, err
+}
@@ -0,0 +1,159 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// breakerState is the state of a circuit breaker.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+var circuitBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "alertmanager",
+	Name:      "receiver_circuit_breaker_state",
+	Help:      "The state of the circuit breaker of a receiver (0 = closed, 1 = open, 2 = half-open).",
+}, []string{"receiver"})
+
+func init() {
+	prometheus.Register(circuitBreakerState)
+}
+
+// CircuitBreakerStage stops notifying a receiver after a number of
+// consecutive failures. While the breaker is open, notifications go to the
+// fallback stage if there is one. After the open duration a single
+// notification is let through to probe whether the receiver recovered.
+type CircuitBreakerStage struct {
+	receiver string
+	conf     *config.CircuitBreakerConfig
+	stage    Stage
+	fallback Stage
+	now      func() time.Time
+
+	mtx      sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreakerStage returns a new instance of a CircuitBreakerStage
+// guarding the given stage. The fallback stage may be nil.
+func NewCircuitBreakerStage(receiver string, conf *config.CircuitBreakerConfig, s, fallback Stage) *CircuitBreakerStage {
+	circuitBreakerState.WithLabelValues(receiver).Set(float64(breakerClosed))
+	return &CircuitBreakerStage{
+		receiver: receiver,
+		conf:     conf,
+		stage:    s,
+		fallback: fallback,
+		now:      time.Now,
+	}
+}
+
+// allow reports whether a notification may be sent to the receiver.
+func (c *CircuitBreakerStage) allow() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	switch c.state {
+	case breakerOpen:
+		if c.now().Sub(c.openedAt) < time.Duration(c.conf.OpenDuration) {
+			return false
+		}
+		c.setState(breakerHalfOpen)
+		fallthrough
+	case breakerHalfOpen:
+		if c.probing {
+			return false
+		}
+		c.probing = true
+	}
+	return true
+}
+
+// record updates the breaker with the result of a notification. Results
+// without any notification attempt, like of deduplicated notifications,
+// only release the probe.
+func (c *CircuitBreakerStage) record(l log.Logger, attempted bool, err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.probing = false
+	if !attempted {
+		return
+	}
+	if err == nil {
+		c.failures = 0
+		if c.state != breakerClosed {
+			level.Info(l).Log("msg", "Circuit breaker closed", "receiver", c.receiver)
+			c.setState(breakerClosed)
+		}
+		return
+	}
+
+	c.failures++
+	if c.state == breakerHalfOpen || c.failures >= c.conf.FailureThreshold {
+		if c.state != breakerOpen {
+			level.Warn(l).Log("msg", "Circuit breaker opened", "receiver", c.receiver, "failures", c.failures)
+		}
+		c.openedAt = c.now()
+		c.setState(breakerOpen)
+	}
+}
+
+func (c *CircuitBreakerStage) setState(s breakerState) {
+	c.state = s
+	circuitBreakerState.WithLabelValues(c.receiver).Set(float64(s))
+}
+
+// Exec implements the Stage interface.
+func (c *CircuitBreakerStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	if !c.allow() {
+		if c.fallback == nil {
+			return ctx, nil, fmt.Errorf("circuit breaker for receiver %q is open", c.receiver)
+		}
+		level.Debug(l).Log("msg", "Circuit breaker open, notifying fallback receiver", "receiver", c.receiver, "fallback", c.conf.FallbackReceiver)
+		return c.fallback.Exec(ctx, l, alerts...)
+	}
+
+	attempts := new(int32)
+	ctx, alerts, err := c.stage.Exec(context.WithValue(ctx, keyNotifyAttempts, attempts), l, alerts...)
+	c.record(l, atomic.LoadInt32(attempts) > 0, err)
+	return ctx, alerts, err
+}
+
+// countNotifyAttempt counts an attempt to notify an integration for the
+// circuit breaker of the context, if any.
+func countNotifyAttempt(ctx context.Context) {
+	if attempts, ok := ctx.Value(keyNotifyAttempts).(*int32); ok {
+		atomic.AddInt32(attempts, 1)
+	}
+}
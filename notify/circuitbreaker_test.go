@@ -0,0 +1,101 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestCircuitBreakerStage(t *testing.T) {
+	var (
+		fail          = true
+		deduped       bool
+		calls         int
+		fallbackCalls int
+	)
+	s := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		if deduped {
+			return ctx, nil, nil
+		}
+		calls++
+		countNotifyAttempt(ctx)
+		if fail {
+			return ctx, nil, fmt.Errorf("fail")
+		}
+		return ctx, alerts, nil
+	})
+	fallback := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		fallbackCalls++
+		return ctx, alerts, nil
+	})
+
+	now := time.Unix(0, 0)
+	cb := NewCircuitBreakerStage("test", &config.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenDuration:     model.Duration(time.Minute),
+		FallbackReceiver: "fallback",
+	}, s, fallback)
+	cb.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	alerts := []*types.Alert{{}}
+
+	// The breaker opens after two consecutive failures.
+	for i := 0; i < 2; i++ {
+		_, _, err := cb.Exec(ctx, log.NewNopLogger(), alerts...)
+		require.Error(t, err)
+	}
+	require.Equal(t, breakerOpen, cb.state)
+
+	// While open, the fallback is notified.
+	_, res, err := cb.Exec(ctx, log.NewNopLogger(), alerts...)
+	require.NoError(t, err)
+	require.Equal(t, alerts, res)
+	require.Equal(t, 2, calls)
+	require.Equal(t, 1, fallbackCalls)
+
+	// A failed probe after the open duration opens the breaker again.
+	now = now.Add(time.Minute)
+	_, _, err = cb.Exec(ctx, log.NewNopLogger(), alerts...)
+	require.Error(t, err)
+	require.Equal(t, 3, calls)
+	require.Equal(t, breakerOpen, cb.state)
+
+	// A probe without any notification attempt leaves it half-open.
+	now = now.Add(time.Minute)
+	deduped = true
+	_, _, err = cb.Exec(ctx, log.NewNopLogger(), alerts...)
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+	require.Equal(t, breakerHalfOpen, cb.state)
+
+	// A successful probe closes it.
+	deduped = false
+	fail = false
+	_, res, err = cb.Exec(ctx, log.NewNopLogger(), alerts...)
+	require.NoError(t, err)
+	require.Equal(t, alerts, res)
+	require.Equal(t, 4, calls)
+	require.Equal(t, breakerClosed, cb.state)
+}
@@ -0,0 +1,221 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dlq"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// DeadLetterQueue stores notifications that failed permanently.
+type DeadLetterQueue interface {
+	Add(e *dlq.Entry) (string, error)
+}
+
+// DeadLetterStage adds notifications that the inner stage failed to deliver
+// to a dead-letter queue. The failed HTTP requests of the last attempt of
+// the integration are added with the rendered payload.
+type DeadLetterStage struct {
+	queue DeadLetterQueue
+	recv  *nflogpb.Receiver
+	stage Stage
+}
+
+// NewDeadLetterStage returns a new instance of a DeadLetterStage.
+func NewDeadLetterStage(q DeadLetterQueue, recv *nflogpb.Receiver, s Stage) *DeadLetterStage {
+	return &DeadLetterStage{
+		queue: q,
+		recv:  recv,
+		stage: s,
+	}
+}
+
+// Exec implements the Stage interface.
+func (s *DeadLetterStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	rec := &requestRecorder{}
+	rctx, res, err := s.stage.Exec(context.WithValue(ctx, keyRequestRecorder, rec), l, alerts...)
+	if err == nil {
+		return rctx, res, nil
+	}
+
+	gkey, _ := GroupKey(ctx)
+	glabels, _ := GroupLabels(ctx)
+	id, qerr := s.queue.Add(&dlq.Entry{
+		Receiver:    s.recv.GroupName,
+		Integration: s.recv.Integration,
+		Idx:         s.recv.Idx,
+		GroupKey:    gkey,
+		GroupLabels: glabels,
+		Alerts:      alerts,
+		Error:       err.Error(),
+		Requests:    rec.failed(),
+	})
+	if qerr != nil {
+		level.Error(l).Log("msg", "Failed to add notification to the dead-letter queue", "receiver", s.recv.GroupName, "integration", s.recv.Integration, "err", qerr)
+	} else {
+		level.Debug(l).Log("msg", "Added notification to the dead-letter queue", "receiver", s.recv.GroupName, "integration", s.recv.Integration, "id", id)
+	}
+	return rctx, res, err
+}
+
+// requestRecorder holds the HTTP requests sent during the last attempt of a
+// notification.
+type requestRecorder struct {
+	mtx      sync.Mutex
+	requests []*recordedRequest
+}
+
+type recordedRequest struct {
+	req *dlq.Request
+	ok  bool
+}
+
+// reset drops the requests of the previous attempt.
+func (r *requestRecorder) reset() {
+	r.mtx.Lock()
+	r.requests = nil
+	r.mtx.Unlock()
+}
+
+func (r *requestRecorder) add(req *dlq.Request, ok bool) {
+	r.mtx.Lock()
+	r.requests = append(r.requests, &recordedRequest{req: req, ok: ok})
+	r.mtx.Unlock()
+}
+
+// failed returns the requests that did not succeed.
+func (r *requestRecorder) failed() []*dlq.Request {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var res []*dlq.Request
+	for _, rr := range r.requests {
+		if !rr.ok {
+			res = append(res, rr.req)
+		}
+	}
+	return res
+}
+
+type requestRecordingRoundTripper struct {
+	rec  *requestRecorder
+	next http.RoundTripper
+}
+
+func (rt *requestRecordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := &dlq.Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: make(http.Header, len(req.Header)),
+	}
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = b
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+	resp, err := rt.next.RoundTrip(req)
+	rt.rec.add(r, err == nil && resp.StatusCode/100 == 2)
+	return resp, err
+}
+
+// ReplayDeadLetter sends the dead letter again to the integration of the
+// given receiver configuration it failed for. Its recorded requests are
+// sent as they are and removed from the entry once they succeeded.
+// Integrations not notifying over HTTP notify about the alerts again.
+func ReplayDeadLetter(ctx context.Context, rc *config.Receiver, tmpl *template.Template, e *dlq.Entry, l log.Logger) error {
+	var integration *Integration
+	integrations := BuildReceiverIntegrations(rc, tmpl, l)
+	for i := range integrations {
+		if integrations[i].name == e.Integration && uint32(integrations[i].idx) == e.Idx {
+			integration = &integrations[i]
+			break
+		}
+	}
+	if integration == nil {
+		return fmt.Errorf("integration %s[%d] of receiver %q not found", e.Integration, e.Idx, e.Receiver)
+	}
+
+	ctx = WithReceiverName(ctx, e.Receiver)
+	ctx = WithGroupKey(ctx, e.GroupKey)
+	ctx = WithGroupLabels(ctx, e.GroupLabels)
+	ctx = WithNow(ctx, time.Now())
+
+	if len(e.Requests) == 0 {
+		_, err := integration.Notify(ctx, e.Alerts...)
+		return err
+	}
+
+	c, err := newHTTPClient(ctx, integrationHTTPConfig(integration.conf))
+	if err != nil {
+		return err
+	}
+	for len(e.Requests) > 0 {
+		r := e.Requests[0]
+		req, err := http.NewRequest(r.Method, r.URL, bytes.NewReader(r.Body))
+		if err != nil {
+			return err
+		}
+		for k, v := range r.Header {
+			req.Header[k] = v
+		}
+		resp, err := ctxhttp.Do(ctx, c, req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("unexpected status code %v from %s", resp.StatusCode, req.URL.Host)
+		}
+		e.Requests = e.Requests[1:]
+	}
+	return nil
+}
+
+// integrationHTTPConfig returns the HTTP client configuration of an
+// integration. All configurations of integrations notifying over HTTP have
+// an HTTPConfig field.
+func integrationHTTPConfig(nc notifierConfig) *config.HTTPClientConfig {
+	v := reflect.Indirect(reflect.ValueOf(nc))
+	if v.Kind() == reflect.Struct {
+		if f := v.FieldByName("HTTPConfig"); f.IsValid() {
+			if c, ok := f.Interface().(*config.HTTPClientConfig); ok && c != nil {
+				return c
+			}
+		}
+	}
+	return &config.HTTPClientConfig{}
+}
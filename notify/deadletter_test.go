@@ -0,0 +1,129 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dlq"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+type testDeadLetters []*dlq.Entry
+
+func (q *testDeadLetters) Add(e *dlq.Entry) (string, error) {
+	*q = append(*q, e)
+	return "id", nil
+}
+
+func TestDeadLetterStage(t *testing.T) {
+	var (
+		q    testDeadLetters
+		fail bool
+	)
+	inner := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		if fail {
+			return ctx, nil, fmt.Errorf("fail")
+		}
+		return ctx, alerts, nil
+	})
+	s := NewDeadLetterStage(&q, &nflogpb.Receiver{GroupName: "team-X", Integration: "webhook"}, inner)
+
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"alertname": "a"})
+	alerts := []*types.Alert{{}}
+
+	_, res, err := s.Exec(ctx, log.NewNopLogger(), alerts...)
+	require.NoError(t, err)
+	require.Equal(t, alerts, res)
+	require.Len(t, q, 0)
+
+	fail = true
+	_, _, err = s.Exec(ctx, log.NewNopLogger(), alerts...)
+	require.EqualError(t, err, "fail")
+	require.Equal(t, testDeadLetters{{
+		Receiver:    "team-X",
+		Integration: "webhook",
+		GroupKey:    "1",
+		GroupLabels: model.LabelSet{"alertname": "a"},
+		Alerts:      alerts,
+		Error:       "fail",
+	}}, q)
+}
+
+func TestDeadLetterReplay(t *testing.T) {
+	var (
+		status = http.StatusInternalServerError
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	rc := &config.Receiver{
+		Name: "team-X",
+		WebhookConfigs: []*config.WebhookConfig{
+			{HTTPConfig: &config.HTTPClientConfig{}, URL: srv.URL},
+		},
+	}
+	tmpl := createTmpl(t)
+	integration := BuildReceiverIntegrations(rc, tmpl, log.NewNopLogger())[0]
+	inner := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		_, err := integration.Notify(ctx, alerts...)
+		return ctx, nil, err
+	})
+
+	var q testDeadLetters
+	s := NewDeadLetterStage(&q, &nflogpb.Receiver{GroupName: "team-X", Integration: "webhook"}, inner)
+
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithReceiverName(ctx, "team-X")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"alertname": "a"})
+	alerts := []*types.Alert{{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}}
+
+	_, _, err := s.Exec(ctx, log.NewNopLogger(), alerts...)
+	require.Error(t, err)
+	require.Len(t, q, 1)
+	require.Len(t, q[0].Requests, 1)
+	require.Equal(t, "POST", q[0].Requests[0].Method)
+	require.Equal(t, srv.URL, q[0].Requests[0].URL)
+	require.Equal(t, bodies[0], string(q[0].Requests[0].Body))
+
+	// The recorded request is sent as it is.
+	require.Error(t, ReplayDeadLetter(context.Background(), rc, tmpl, q[0], log.NewNopLogger()))
+	require.Len(t, q[0].Requests, 1)
+
+	status = http.StatusOK
+	require.NoError(t, ReplayDeadLetter(context.Background(), rc, tmpl, q[0], log.NewNopLogger()))
+	require.Len(t, q[0].Requests, 0)
+	require.Len(t, bodies, 3)
+	require.Equal(t, bodies[0], bodies[2])
+
+	q[0].Integration = "email"
+	require.EqualError(t, ReplayDeadLetter(context.Background(), rc, tmpl, q[0], log.NewNopLogger()), `integration email[0] of receiver "team-X" not found`)
+}
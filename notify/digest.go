@@ -0,0 +1,231 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// digestGroup is an alert group accumulated by a DigestStage.
+type digestGroup struct {
+	key      string
+	labels   model.LabelSet
+	alerts   []*types.Alert
+	firing   []uint64
+	resolved []uint64
+	acks     map[model.Fingerprint]*ack.Ack
+}
+
+var (
+	digestStagesMtx sync.Mutex
+	digestStages    = map[*DigestStage]struct{}{}
+)
+
+// FlushDigests sends the pending digests of all digest stages right away.
+// It is called before the pipelines are replaced or Alertmanager shuts
+// down, as the pending digests are lost otherwise.
+func FlushDigests() {
+	digestStagesMtx.Lock()
+	stages := digestStages
+	digestStages = map[*DigestStage]struct{}{}
+	digestStagesMtx.Unlock()
+
+	for d := range stages {
+		d.mtx.Lock()
+		if d.timer != nil {
+			d.timer.Stop()
+		}
+		d.stopped = true
+		d.mtx.Unlock()
+
+		d.flush()
+	}
+}
+
+// DigestStage accumulates the notifications of all groups of a receiver and
+// sends them as a single notification once the digest interval has passed.
+// The groups are available to templates as .Groups. The notifications of
+// the groups are recorded once the digest was sent.
+//
+// As the notifications are only recorded after the digest interval, the
+// notification log cannot deduplicate digests between peers. Only the peer
+// returning true from the sender function accumulates groups, other peers
+// drop them. If the sender fails, the groups it did not send yet are
+// notified by the next sender at their next group interval.
+type DigestStage struct {
+	interval time.Duration
+	stage    Stage
+	notifies *SetNotifiesStage
+	sender   func() bool
+	recv     *nflogpb.Receiver
+	logger   log.Logger
+	now      func() time.Time
+
+	mtx     sync.Mutex
+	pending map[string]*digestGroup
+	timer   *time.Timer
+	stopped bool
+}
+
+// NewDigestStage returns a new instance of a DigestStage sending digests
+// through the given stage. Notifications are recorded with the notifies stage
+// when the digest was sent successfully. The sender function returns
+// whether this peer sends the digests of the cluster.
+func NewDigestStage(conf *config.DigestConfig, s Stage, notifies *SetNotifiesStage, sender func() bool, l log.Logger) *DigestStage {
+	d := &DigestStage{
+		interval: time.Duration(conf.Interval),
+		stage:    s,
+		notifies: notifies,
+		sender:   sender,
+		recv:     notifies.recv,
+		logger:   l,
+		now:      time.Now,
+		pending:  map[string]*digestGroup{},
+	}
+	digestStagesMtx.Lock()
+	digestStages[d] = struct{}{}
+	digestStagesMtx.Unlock()
+
+	return d
+}
+
+// Exec implements the Stage interface.
+func (d *DigestStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	gkey, ok := GroupKey(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("group key missing")
+	}
+	if !d.sender() {
+		level.Debug(l).Log("msg", "Dropping group, digests are sent by another peer", "group", gkey)
+		return ctx, nil, nil
+	}
+	lset, _ := GroupLabels(ctx)
+	firing, _ := FiringAlerts(ctx)
+	resolved, _ := ResolvedAlerts(ctx)
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	// A group notified again before the digest was sent replaces its
+	// earlier notification.
+	d.pending[gkey] = &digestGroup{
+		key:      gkey,
+		labels:   lset,
+		alerts:   alerts,
+		firing:   firing,
+		resolved: resolved,
+		acks:     acknowledgements(ctx),
+	}
+	d.schedule()
+	return ctx, nil, nil
+}
+
+// schedule starts the timer sending the digest unless it is running. The
+// mutex must be held.
+func (d *DigestStage) schedule() {
+	if d.timer == nil && !d.stopped {
+		d.timer = time.AfterFunc(d.interval, d.flush)
+	}
+}
+
+// flush sends all accumulated groups as a single notification. The groups
+// are kept for the next digest if sending failed.
+func (d *DigestStage) flush() {
+	d.mtx.Lock()
+	pending := d.pending
+	d.pending = map[string]*digestGroup{}
+	d.timer = nil
+	d.mtx.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	groups := make([]*digestGroup, 0, len(pending))
+	for _, g := range pending {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].key < groups[j].key
+	})
+
+	var (
+		alerts           []*types.Alert
+		firing, resolved []uint64
+		seen             = map[model.Fingerprint]struct{}{}
+		acks             = map[model.Fingerprint]*ack.Ack{}
+	)
+	for _, g := range groups {
+		for fp, e := range g.acks {
+			acks[fp] = e
+		}
+		for _, a := range g.alerts {
+			if _, ok := seen[a.Fingerprint()]; ok {
+				continue
+			}
+			seen[a.Fingerprint()] = struct{}{}
+			alerts = append(alerts, a)
+		}
+		firing = append(firing, g.firing...)
+		resolved = append(resolved, g.resolved...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), d.interval)
+	defer cancel()
+
+	ctx = WithReceiverName(ctx, d.recv.GroupName)
+	ctx = WithGroupKey(ctx, "digest/"+d.recv.GroupName)
+	ctx = WithGroupLabels(ctx, model.LabelSet{})
+	ctx = WithNow(ctx, d.now())
+	ctx = WithFiringAlerts(ctx, firing)
+	ctx = WithResolvedAlerts(ctx, resolved)
+	ctx = context.WithValue(ctx, keyDigestGroups, groups)
+	ctx = context.WithValue(ctx, keyAcknowledgements, acks)
+
+	l := log.With(d.logger, "receiver", d.recv.GroupName, "integration", d.recv.Integration)
+	if _, _, err := d.stage.Exec(ctx, l, alerts...); err != nil {
+		level.Error(l).Log("msg", "Sending digest failed", "groups", len(groups), "err", err)
+
+		d.mtx.Lock()
+		for _, g := range groups {
+			if _, ok := d.pending[g.key]; !ok {
+				d.pending[g.key] = g
+			}
+		}
+		d.schedule()
+		d.mtx.Unlock()
+		return
+	}
+
+	// Record the notifications of the groups only now so that they are
+	// notified again if the digest is never sent.
+	for _, g := range groups {
+		if err := d.notifies.log(g.key, g.alerts, g.firing, g.resolved); err != nil {
+			level.Error(l).Log("msg", "Recording digest notification failed", "group", g.key, "err", err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestDigestStage(t *testing.T) {
+	logged := make(chan string, 10)
+	tnflog := &testNflog{
+		logFunc: func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error {
+			logged <- gkey
+			return nil
+		},
+	}
+
+	type digest struct {
+		ctx    context.Context
+		alerts []*types.Alert
+	}
+	sent := make(chan digest, 1)
+	fail := make(chan bool, 1)
+	fail <- true
+	s := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		select {
+		case <-fail:
+			return ctx, nil, fmt.Errorf("failed")
+		default:
+		}
+		sent <- digest{ctx, alerts}
+		return ctx, alerts, nil
+	})
+
+	d := NewDigestStage(
+		&config.DigestConfig{Interval: model.Duration(10 * time.Millisecond)},
+		s,
+		NewSetNotifiesStage(tnflog, &nflogpb.Receiver{GroupName: "test", Integration: "webhook"}, 0),
+		func() bool { return true },
+		log.NewNopLogger(),
+	)
+
+	a1 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a1"}}}
+	a2 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a2"}}}
+
+	for i, a := range []*types.Alert{a1, a2} {
+		ctx := WithGroupKey(context.Background(), fmt.Sprint(i+1))
+		ctx = WithGroupLabels(ctx, model.LabelSet{"alertname": a.Labels["alertname"]})
+		ctx = WithFiringAlerts(ctx, []uint64{uint64(i)})
+		ctx = WithResolvedAlerts(ctx, []uint64{})
+		ctx = context.WithValue(ctx, keyAcknowledgements, map[model.Fingerprint]*ack.Ack{
+			a.Fingerprint(): {CreatedBy: "alice"},
+		})
+
+		_, res, err := d.Exec(ctx, log.NewNopLogger(), a)
+		require.NoError(t, err)
+		require.Nil(t, res)
+	}
+
+	// The first digest fails and is sent again, the notifications are only
+	// recorded afterwards.
+	var res digest
+	select {
+	case res = <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("digest not sent")
+	}
+	require.Equal(t, []*types.Alert{a1, a2}, res.alerts)
+	require.Equal(t, "1", <-logged)
+	require.Equal(t, "2", <-logged)
+
+	gkey, _ := GroupKey(res.ctx)
+	require.Equal(t, "digest/test", gkey)
+	firing, _ := FiringAlerts(res.ctx)
+	require.Equal(t, []uint64{0, 1}, firing)
+	require.Len(t, acknowledgements(res.ctx), 2)
+
+	tmpl := createTmpl(t)
+	data := tmplData(res.ctx, tmpl, log.NewNopLogger(), res.alerts...)
+	require.Len(t, data.Groups, 2)
+	require.Equal(t, "a1", data.Groups[0].GroupLabels["alertname"])
+	require.Equal(t, "a2", data.Groups[1].GroupLabels["alertname"])
+	require.Len(t, data.Alerts, 2)
+	require.NotNil(t, data.Alerts[0].Acknowledgement)
+}
+
+func TestFlushDigests(t *testing.T) {
+	tnflog := &testNflog{
+		logFunc: func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error {
+			return nil
+		},
+	}
+	sent := make(chan []*types.Alert, 1)
+	s := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		sent <- alerts
+		return ctx, alerts, nil
+	})
+	d := NewDigestStage(
+		&config.DigestConfig{Interval: model.Duration(time.Hour)},
+		s,
+		NewSetNotifiesStage(tnflog, &nflogpb.Receiver{GroupName: "test", Integration: "webhook"}, 0),
+		func() bool { return true },
+		log.NewNopLogger(),
+	)
+
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithFiringAlerts(ctx, []uint64{1})
+	ctx = WithResolvedAlerts(ctx, []uint64{})
+	_, _, err := d.Exec(ctx, log.NewNopLogger(), a)
+	require.NoError(t, err)
+
+	// Pending digests are sent without waiting for the interval.
+	FlushDigests()
+	select {
+	case res := <-sent:
+		require.Equal(t, []*types.Alert{a}, res)
+	default:
+		t.Fatal("digest not sent")
+	}
+}
+
+func TestDigestStageCluster(t *testing.T) {
+	logged := make(chan string, 10)
+	tnflog := &testNflog{
+		logFunc: func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error {
+			logged <- gkey
+			return nil
+		},
+	}
+	sent := make(chan string, 10)
+
+	// Both peers pass the group to their digest stage as the notification
+	// is only recorded once the digest was sent.
+	var peers []*DigestStage
+	for i := 0; i < 2; i++ {
+		name := fmt.Sprintf("peer%d", i)
+		s := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+			sent <- name
+			return ctx, alerts, nil
+		})
+		position := i
+		peers = append(peers, NewDigestStage(
+			&config.DigestConfig{Interval: model.Duration(10 * time.Millisecond)},
+			s,
+			NewSetNotifiesStage(tnflog, &nflogpb.Receiver{GroupName: "test", Integration: "webhook"}, 0),
+			func() bool { return position == 0 },
+			log.NewNopLogger(),
+		))
+	}
+
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+	for _, d := range peers {
+		ctx := WithGroupKey(context.Background(), "1")
+		ctx = WithFiringAlerts(ctx, []uint64{1})
+		ctx = WithResolvedAlerts(ctx, []uint64{})
+		_, _, err := d.Exec(ctx, log.NewNopLogger(), a)
+		require.NoError(t, err)
+	}
+
+	select {
+	case name := <-sent:
+		require.Equal(t, "peer0", name)
+	case <-time.After(time.Second):
+		t.Fatal("digest not sent")
+	}
+	require.Equal(t, "1", <-logged)
+
+	time.Sleep(50 * time.Millisecond)
+	require.Len(t, sent, 0)
+	require.Len(t, logged, 0)
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// EnrichmentRequest is sent to the enrichment endpoint with the alerts whose
+// annotations are not cached.
+type EnrichmentRequest struct {
+	// The protocol version.
+	Version  string             `json:"version"`
+	Receiver string             `json:"receiver"`
+	Alerts   []*EnrichmentAlert `json:"alerts"`
+}
+
+// EnrichmentResponse is returned by the enrichment endpoint. Alerts that are
+// not part of the response receive no additional annotations.
+type EnrichmentResponse struct {
+	Alerts []*EnrichmentAlert `json:"alerts"`
+}
+
+// EnrichmentAlert identifies an alert by its fingerprint. In requests it
+// holds the labels and annotations of the alert, in responses the
+// additional annotations.
+type EnrichmentAlert struct {
+	Fingerprint string         `json:"fingerprint"`
+	Labels      model.LabelSet `json:"labels,omitempty"`
+	Annotations model.LabelSet `json:"annotations,omitempty"`
+}
+
+type enrichmentEntry struct {
+	annotations model.LabelSet
+	expiresAt   time.Time
+}
+
+// EnrichStage adds annotations looked up from an HTTP endpoint to the alerts.
+// Annotations the alerts already have are kept. If the lookup fails the
+// alerts are passed on unchanged.
+type EnrichStage struct {
+	conf   *config.EnrichmentConfig
+	logger log.Logger
+	now    func() time.Time
+
+	mtx   sync.Mutex
+	cache map[model.Fingerprint]*enrichmentEntry
+}
+
+// NewEnrichStage returns a new EnrichStage.
+func NewEnrichStage(conf *config.EnrichmentConfig, l log.Logger) *EnrichStage {
+	return &EnrichStage{
+		conf:   conf,
+		logger: l,
+		now:    time.Now,
+		cache:  map[model.Fingerprint]*enrichmentEntry{},
+	}
+}
+
+// Exec implements the Stage interface.
+func (s *EnrichStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	now := s.now()
+
+	s.mtx.Lock()
+	var missing []*types.Alert
+	for fp, e := range s.cache {
+		if !e.expiresAt.After(now) {
+			delete(s.cache, fp)
+		}
+	}
+	for _, a := range alerts {
+		if _, ok := s.cache[a.Fingerprint()]; !ok {
+			missing = append(missing, a)
+		}
+	}
+	s.mtx.Unlock()
+
+	if len(missing) > 0 {
+		res, err := s.lookup(ctx, missing)
+		if err != nil {
+			level.Warn(l).Log("msg", "Enriching alerts failed", "err", err)
+		} else {
+			s.mtx.Lock()
+			for _, a := range missing {
+				s.cache[a.Fingerprint()] = &enrichmentEntry{
+					annotations: res[a.Fingerprint()],
+					expiresAt:   now.Add(time.Duration(s.conf.CacheTTL)),
+				}
+			}
+			s.mtx.Unlock()
+		}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	enriched := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		e, ok := s.cache[a.Fingerprint()]
+		if !ok || len(e.annotations) == 0 {
+			enriched = append(enriched, a)
+			continue
+		}
+		// Alerts are shared with other pipelines and must not be modified.
+		c := *a
+		c.Annotations = e.annotations.Merge(a.Annotations)
+		enriched = append(enriched, &c)
+	}
+	return ctx, enriched, nil
+}
+
+// lookup requests the additional annotations of the alerts.
+func (s *EnrichStage) lookup(ctx context.Context, alerts []*types.Alert) (map[model.Fingerprint]model.LabelSet, error) {
+	recv, _ := ReceiverName(ctx)
+	req := &EnrichmentRequest{
+		Version:  "1",
+		Receiver: recv,
+		Alerts:   make([]*EnrichmentAlert, 0, len(alerts)),
+	}
+	for _, a := range alerts {
+		req.Alerts = append(req.Alerts, &EnrichmentAlert{
+			Fingerprint: a.Fingerprint().String(),
+			Labels:      a.Labels,
+			Annotations: a.Annotations,
+		})
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(req); err != nil {
+		return nil, err
+	}
+
+	c, err := newHTTPClient(context.Background(), s.conf.HTTPConfig)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.conf.Timeout))
+	defer cancel()
+
+	httpReq, err := http.NewRequest("POST", s.conf.URL, &buf)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", contentTypeJSON)
+	httpReq.Header.Set("User-Agent", userAgentHeader)
+
+	resp, err := ctxhttp.Do(ctx, c, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	var res EnrichmentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	annotations := make(map[model.Fingerprint]model.LabelSet, len(res.Alerts))
+	for _, a := range res.Alerts {
+		fp, err := model.FingerprintFromString(a.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		annotations[fp] = a.Annotations
+	}
+	return annotations, nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestEnrichStage(t *testing.T) {
+	var requests []*EnrichmentRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req EnrichmentRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, &req)
+
+		var res EnrichmentResponse
+		for _, a := range req.Alerts {
+			if a.Labels["service"] == "db" {
+				res.Alerts = append(res.Alerts, &EnrichmentAlert{
+					Fingerprint: a.Fingerprint,
+					Annotations: model.LabelSet{"owner": "dba", "runbook": "https://runbooks/db"},
+				})
+			}
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+
+	conf := config.DefaultEnrichmentConfig
+	conf.URL = srv.URL
+	conf.HTTPConfig = &config.HTTPClientConfig{}
+	s := NewEnrichStage(&conf, log.NewNopLogger())
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	db := &types.Alert{Alert: model.Alert{
+		Labels:      model.LabelSet{"service": "db"},
+		Annotations: model.LabelSet{"runbook": "https://wiki/db"},
+	}}
+	web := &types.Alert{Alert: model.Alert{
+		Labels: model.LabelSet{"service": "web"},
+	}}
+
+	ctx := WithReceiverName(context.Background(), "team")
+	_, res, err := s.Exec(ctx, log.NewNopLogger(), db, web)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, model.LabelSet{"owner": "dba", "runbook": "https://wiki/db"}, res[0].Annotations)
+	require.Equal(t, web, res[1])
+	// The original alert is left untouched.
+	require.Equal(t, model.LabelSet{"runbook": "https://wiki/db"}, db.Annotations)
+
+	require.Len(t, requests, 1)
+	require.Equal(t, "team", requests[0].Receiver)
+	require.Len(t, requests[0].Alerts, 2)
+
+	// Cached results are used until they expire.
+	_, res, err = s.Exec(ctx, log.NewNopLogger(), db, web)
+	require.NoError(t, err)
+	require.Equal(t, "dba", string(res[0].Annotations["owner"]))
+	require.Len(t, requests, 1)
+
+	now = now.Add(time.Duration(conf.CacheTTL))
+	_, _, err = s.Exec(ctx, log.NewNopLogger(), db)
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+}
+
+func TestEnrichStageLookupFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	conf := config.DefaultEnrichmentConfig
+	conf.URL = srv.URL
+	conf.HTTPConfig = &config.HTTPClientConfig{}
+	s := NewEnrichStage(&conf, log.NewNopLogger())
+
+	alert := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"service": "db"}}}
+	_, res, err := s.Exec(context.Background(), log.NewNopLogger(), alert)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{alert}, res)
+	require.Len(t, s.cache, 0)
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// defaultExecOutputBytes is the number of bytes of the output of a failed
+// command included in the returned error if not configured otherwise.
+const defaultExecOutputBytes = 512
+
+// Exec implements a Notifier that runs a local command.
+type Exec struct {
+	conf   *config.ExecConfig
+	tmpl   *template.Template
+	logger log.Logger
+	sem    chan struct{}
+}
+
+// NewExec returns a new Exec notifier.
+func NewExec(c *config.ExecConfig, t *template.Template, l log.Logger) *Exec {
+	return &Exec{
+		conf:   c,
+		tmpl:   t,
+		logger: l,
+		sem:    make(chan struct{}, c.MaxConcurrency),
+	}
+}
+
+// Notify implements the Notifier interface.
+func (n *Exec) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	message := tmpl(n.conf.Message)
+	env := n.environ(key, data)
+	for k, v := range n.conf.Env {
+		env = append(env, k+"="+tmpl(v))
+	}
+	if err != nil {
+		return false, err
+	}
+
+	select {
+	case n.sem <- struct{}{}:
+		defer func() { <-n.sem }()
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(n.conf.Timeout))
+	defer cancel()
+
+	limit := n.conf.MaxOutputBytes
+	if limit == 0 {
+		limit = defaultExecOutputBytes
+	}
+	out := &limitedBuffer{limit: limit}
+
+	cmd := exec.Command(n.conf.Command, n.conf.Args...)
+	cmd.Dir = n.conf.WorkingDir
+	cmd.Env = env
+	cmd.Stdin = strings.NewReader(message)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	level.Debug(n.logger).Log("msg", "Running notification command", "command", n.conf.Command, "incident", key)
+
+	if err := runCommand(ctx, cmd); err != nil {
+		// The output of a killed command may still be written to.
+		if ctx.Err() == nil {
+			if msg := strings.TrimSpace(string(out.buf)); msg != "" {
+				err = fmt.Errorf("%s: %s", err, msg)
+			}
+		}
+		return true, fmt.Errorf("command %q failed: %s", n.conf.Command, err)
+	}
+	return false, nil
+}
+
+// environ returns the environment of the command.
+func (n *Exec) environ(key string, data *template.Data) []string {
+	var env []string
+	if n.conf.InheritEnv {
+		env = os.Environ()
+	} else if path, ok := os.LookupEnv("PATH"); ok {
+		env = append(env, "PATH="+path)
+	}
+	env = append(env,
+		"ALERTMANAGER_RECEIVER="+data.Receiver,
+		"ALERTMANAGER_STATUS="+data.Status,
+		"ALERTMANAGER_GROUP_KEY="+key,
+		"ALERTMANAGER_EXTERNAL_URL="+data.ExternalURL,
+		"ALERTMANAGER_ALERTS_FIRING="+strconv.Itoa(len(data.Alerts.Firing())),
+		"ALERTMANAGER_ALERTS_RESOLVED="+strconv.Itoa(len(data.Alerts.Resolved())),
+	)
+	for _, p := range data.GroupLabels.SortedPairs() {
+		env = append(env, "ALERTMANAGER_GROUP_LABEL_"+strings.ToUpper(p.Name)+"="+p.Value)
+	}
+	return env
+}
+
+// limitedBuffer keeps the first bytes written to it up to its limit and
+// discards the rest.
+type limitedBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if n := b.limit - len(b.buf); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		b.buf = append(b.buf, p[:n]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func newTestExec(t *testing.T, script string) *Exec {
+	conf := config.DefaultExecConfig
+	conf.Command = "/bin/sh"
+	conf.Args = []string{"-c", script}
+
+	return NewExec(&conf, createTmpl(t), log.NewNopLogger())
+}
+
+func notifyExec(n *Exec) (bool, error) {
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"team": "infra"})
+	ctx = WithReceiverName(ctx, "ops")
+	return n.Notify(ctx, &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Down", "team": "infra"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	})
+}
+
+func TestExec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("EXEC_TEST_SECRET", "s3cr3t")
+	defer os.Unsetenv("EXEC_TEST_SECRET")
+
+	n := newTestExec(t, "cat > message; env > env")
+	n.conf.WorkingDir = dir
+	n.conf.Message = `{{ .CommonLabels.alertname }}`
+	n.conf.Env = map[string]string{"TEAM": "{{ .CommonLabels.team }}"}
+
+	retry, err := notifyExec(n)
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "message"))
+	require.NoError(t, err)
+	require.Equal(t, "Down", string(b))
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "env"))
+	require.NoError(t, err)
+	env := strings.Split(string(b), "\n")
+	for _, v := range []string{
+		"ALERTMANAGER_RECEIVER=ops",
+		"ALERTMANAGER_STATUS=firing",
+		"ALERTMANAGER_GROUP_KEY=1",
+		"ALERTMANAGER_ALERTS_FIRING=1",
+		"ALERTMANAGER_ALERTS_RESOLVED=0",
+		"ALERTMANAGER_GROUP_LABEL_TEAM=infra",
+		"TEAM=infra",
+	} {
+		require.Contains(t, env, v)
+	}
+	require.NotContains(t, env, "EXEC_TEST_SECRET=s3cr3t")
+}
+
+func TestExecFailure(t *testing.T) {
+	n := newTestExec(t, `echo "unreachable" >&2; echo "more output"; exit 1`)
+	n.conf.MaxOutputBytes = 11
+
+	retry, err := notifyExec(n)
+	require.EqualError(t, err, `command "/bin/sh" failed: exit status 1: unreachable`)
+	require.True(t, retry)
+}
+
+func TestExecConcurrency(t *testing.T) {
+	n := newTestExec(t, "true")
+	n.sem <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	retry, err := n.Notify(WithGroupKey(ctx, "1"))
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.True(t, retry)
+}
@@ -0,0 +1,344 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// NotificationsSuppressedAlertName is the alert name of notifications about
+// notifications suppressed by the flood protection.
+const NotificationsSuppressedAlertName = "NotificationsSuppressed"
+
+var numFloodSuppressedNotifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "notifications_flood_suppressed_total",
+	Help:      "The total number of notifications suppressed by the flood protection.",
+}, []string{"receiver", "integration"})
+
+func init() {
+	prometheus.Register(numFloodSuppressedNotifications)
+}
+
+// FloodProtection limits the rate of notifications of all receivers
+// together. Suppressed notifications are counted and reported to a receiver
+// once per interval.
+type FloodProtection struct {
+	conf    *config.FloodProtectionConfig
+	limiter *rateLimiter
+	state   *FloodState
+	peer    string
+	logger  log.Logger
+	now     func() time.Time
+
+	mtx        sync.Mutex
+	suppressed map[string]int
+
+	stopc chan struct{}
+	done  chan struct{}
+}
+
+// NewFloodProtection returns a new FloodProtection. If the state is set,
+// the limit applies to all peers of the cluster together and the peer name
+// is added to the reports about suppressed notifications.
+func NewFloodProtection(conf *config.FloodProtectionConfig, st *FloodState, l log.Logger) *FloodProtection {
+	f := &FloodProtection{
+		conf: conf,
+		limiter: newRateLimiter(&config.RateLimitConfig{
+			Notifications: conf.Notifications,
+			Interval:      conf.Interval,
+			Burst:         conf.Burst,
+		}),
+		state:      st,
+		logger:     l,
+		now:        time.Now,
+		suppressed: map[string]int{},
+		stopc:      make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if st != nil {
+		f.peer = st.peer
+		st.setLimiter(f.limiter, func() time.Time { return f.now() })
+	}
+	return f
+}
+
+// allow consumes a token for a notification of the receiver. Otherwise the
+// notification is counted as suppressed.
+func (f *FloodProtection) allow(receiver string) bool {
+	if ok, _ := f.limiter.take(f.now()); ok {
+		if f.state != nil {
+			f.state.consumed()
+		}
+		return true
+	}
+	f.mtx.Lock()
+	f.suppressed[receiver]++
+	f.mtx.Unlock()
+	return false
+}
+
+// Run reports the suppressed notifications through the pipeline once per
+// interval until Stop is called.
+func (f *FloodProtection) Run(pipeline Stage, externalURL string) {
+	defer close(f.done)
+
+	t := time.NewTicker(time.Duration(f.conf.Interval))
+	defer t.Stop()
+
+	for {
+		select {
+		case <-f.stopc:
+			return
+		case <-t.C:
+		}
+		a := f.summarize(externalURL)
+		if a == nil || f.conf.Receiver == "" {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(f.conf.Interval))
+		ctx = WithReceiverName(ctx, f.conf.Receiver)
+		ctx = WithGroupKey(ctx, f.groupKey())
+		ctx = WithGroupLabels(ctx, a.Labels)
+		ctx = WithNow(ctx, f.now())
+		ctx = WithRepeatInterval(ctx, time.Duration(f.conf.Interval))
+
+		if _, _, err := pipeline.Exec(ctx, f.logger, a); err != nil {
+			level.Error(f.logger).Log("msg", "Notifying about suppressed notifications failed", "err", err)
+		}
+		cancel()
+	}
+}
+
+// Stop stops reporting suppressed notifications.
+func (f *FloodProtection) Stop() {
+	if f == nil {
+		return
+	}
+	close(f.stopc)
+	<-f.done
+}
+
+func (f *FloodProtection) groupKey() string {
+	return fmt.Sprintf("{}:{alertname=%q,peer=%q}", NotificationsSuppressedAlertName, f.peer)
+}
+
+// summarize returns the alert reporting the notifications suppressed since
+// the last call, or nil if there are none.
+func (f *FloodProtection) summarize(externalURL string) *types.Alert {
+	f.mtx.Lock()
+	suppressed := f.suppressed
+	f.suppressed = map[string]int{}
+	f.mtx.Unlock()
+
+	if len(suppressed) == 0 {
+		return nil
+	}
+	var (
+		total     int
+		receivers = make([]string, 0, len(suppressed))
+	)
+	for r, n := range suppressed {
+		total += n
+		receivers = append(receivers, fmt.Sprintf("%s: %d", r, n))
+	}
+	sort.Strings(receivers)
+
+	summary := fmt.Sprintf("%d notifications suppressed by flood protection", total)
+	if f.conf.DashboardURL != "" {
+		summary += ", see " + f.conf.DashboardURL
+	}
+	now := f.now()
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: NotificationsSuppressedAlertName,
+			},
+			Annotations: model.LabelSet{
+				"summary":     model.LabelValue(summary),
+				"description": model.LabelValue("Suppressed notifications by receiver: " + strings.Join(receivers, ", ")),
+				"suppressed":  model.LabelValue(fmt.Sprintf("%d", total)),
+			},
+			StartsAt:     now,
+			EndsAt:       now.Add(time.Duration(f.conf.Interval)),
+			GeneratorURL: externalURL,
+		},
+		UpdatedAt: now,
+	}
+	if f.peer != "" {
+		a.Labels["peer"] = model.LabelValue(f.peer)
+	}
+	if f.conf.DashboardURL != "" {
+		a.Annotations["dashboard_url"] = model.LabelValue(f.conf.DashboardURL)
+	}
+	return a
+}
+
+// FloodProtectionStage drops notifications exceeding the limit of the flood
+// protection. Reports about suppressed notifications are not limited.
+type FloodProtectionStage struct {
+	fp   *FloodProtection
+	recv *nflogpb.Receiver
+}
+
+// NewFloodProtectionStage returns a new FloodProtectionStage for the
+// integration of a receiver.
+func NewFloodProtectionStage(fp *FloodProtection, recv *nflogpb.Receiver) *FloodProtectionStage {
+	return &FloodProtectionStage{fp: fp, recv: recv}
+}
+
+// Exec implements the Stage interface.
+func (s *FloodProtectionStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	if gkey, ok := GroupKey(ctx); ok && gkey == s.fp.groupKey() {
+		return ctx, alerts, nil
+	}
+	if s.fp.allow(s.recv.GroupName) {
+		return ctx, alerts, nil
+	}
+	numFloodSuppressedNotifications.WithLabelValues(s.recv.GroupName, s.recv.Integration).Inc()
+	level.Debug(l).Log("msg", "Notification suppressed by flood protection", "receiver", s.recv.GroupName, "integration", s.recv.Integration)
+
+	// The suppressed notification is not recorded in the notification log
+	// so that it is sent with the next flush of the group.
+	return ctx, nil, nil
+}
+
+// FloodState shares the tokens consumed by the flood protection of a peer
+// with the other peers of the cluster. Each peer deducts the tokens
+// consumed by the others from its own bucket, so that the limit applies to
+// the cluster as a whole. The state outlives configuration reloads.
+type FloodState struct {
+	peer  string
+	start time.Time
+
+	mtx       sync.Mutex
+	usage     map[string]*floodUsage
+	limiter   *rateLimiter
+	now       func() time.Time
+	broadcast func([]byte)
+}
+
+// floodUsage is the number of tokens a peer consumed since it started.
+type floodUsage struct {
+	Peer   string    `json:"peer"`
+	Start  time.Time `json:"start"`
+	Tokens uint64    `json:"tokens"`
+}
+
+// NewFloodState returns a new FloodState of the peer with the given name.
+func NewFloodState(peer string) *FloodState {
+	s := &FloodState{
+		peer:      peer,
+		start:     time.Now(),
+		broadcast: func([]byte) {},
+	}
+	s.usage = map[string]*floodUsage{
+		peer: {Peer: peer, Start: s.start},
+	}
+	return s
+}
+
+// SetBroadcast sets a broadcast callback that will be invoked with serialized
+// state on updates.
+func (s *FloodState) SetBroadcast(f func([]byte)) {
+	s.mtx.Lock()
+	s.broadcast = f
+	s.mtx.Unlock()
+}
+
+func (s *FloodState) setLimiter(l *rateLimiter, now func() time.Time) {
+	s.mtx.Lock()
+	s.limiter, s.now = l, now
+	s.mtx.Unlock()
+}
+
+// consumed records a token consumed by the peer and broadcasts its usage.
+func (s *FloodState) consumed() {
+	s.mtx.Lock()
+	u := s.usage[s.peer]
+	u.Tokens++
+	b, err := json.Marshal(u)
+	broadcast := s.broadcast
+	s.mtx.Unlock()
+
+	if err == nil {
+		broadcast(b)
+	}
+}
+
+// MarshalBinary serializes the usage of all known peers.
+func (s *FloodState) MarshalBinary() ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, u := range s.usage {
+		if err := enc.Encode(u); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Merge merges the usage received from other peers and deducts the tokens
+// they consumed since the last update from the local bucket. The usage of a
+// peer seen for the first time, or restarted, is only taken as a baseline.
+func (s *FloodState) Merge(b []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for {
+		var u floodUsage
+		err := dec.Decode(&u)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if u.Peer == s.peer {
+			continue
+		}
+		prev, ok := s.usage[u.Peer]
+		switch {
+		case !ok || u.Start.After(prev.Start):
+			s.usage[u.Peer] = &u
+		case u.Start.Equal(prev.Start) && u.Tokens > prev.Tokens:
+			if s.limiter != nil {
+				s.limiter.consume(s.now(), float64(u.Tokens-prev.Tokens))
+			}
+			prev.Tokens = u.Tokens
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestFloodProtectionStage(t *testing.T) {
+	conf := &config.FloodProtectionConfig{
+		Notifications: 4,
+		Interval:      model.Duration(time.Hour),
+		Burst:         4,
+		DashboardURL:  "http://dashboard",
+	}
+	st1, st2 := NewFloodState("peer1"), NewFloodState("peer2")
+	fp := NewFloodProtection(conf, st1, log.NewNopLogger())
+	fp.now = func() time.Time { return time.Unix(0, 0) }
+	fp2 := NewFloodProtection(conf, st2, log.NewNopLogger())
+	fp2.now = fp.now
+
+	// The peers know each other before consuming tokens.
+	b, err := st1.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, st2.Merge(b))
+	b, err = st2.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, st1.Merge(b))
+	st1.SetBroadcast(func(b []byte) { require.NoError(t, st2.Merge(b)) })
+	st2.SetBroadcast(func(b []byte) { require.NoError(t, st1.Merge(b)) })
+
+	newStage := func(fp *FloodProtection, receiver string) *FloodProtectionStage {
+		return NewFloodProtectionStage(fp, &nflogpb.Receiver{GroupName: receiver, Integration: "webhook"})
+	}
+	s1, s2 := newStage(fp, "team-X"), newStage(fp, "team-Y")
+	other := newStage(fp2, "team-X")
+
+	ctx := WithGroupKey(context.Background(), "1")
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+
+	// The tokens consumed by the other peer count against the limit.
+	for i := 0; i < 3; i++ {
+		_, res, err := other.Exec(ctx, log.NewNopLogger(), a)
+		require.NoError(t, err)
+		require.Equal(t, []*types.Alert{a}, res)
+	}
+	_, res, err := s1.Exec(ctx, log.NewNopLogger(), a)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{a}, res)
+
+	// Suppressed notifications are dropped.
+	_, res, err = s1.Exec(ctx, log.NewNopLogger(), a)
+	require.NoError(t, err)
+	require.Nil(t, res)
+	_, res, err = s2.Exec(ctx, log.NewNopLogger(), a)
+	require.NoError(t, err)
+	require.Nil(t, res)
+	_, res, err = s2.Exec(ctx, log.NewNopLogger(), a)
+	require.NoError(t, err)
+	require.Nil(t, res)
+	_, res, err = other.Exec(ctx, log.NewNopLogger(), a)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	// Notifications about suppressed notifications are not limited.
+	sctx := WithGroupKey(ctx, fp.groupKey())
+	_, res, err = s1.Exec(sctx, log.NewNopLogger(), a)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{a}, res)
+
+	summary := fp.summarize("http://alertmanager")
+	require.NotNil(t, summary)
+	require.Equal(t, model.LabelSet{
+		model.AlertNameLabel: NotificationsSuppressedAlertName,
+		"peer":               "peer1",
+	}, summary.Labels)
+	require.Equal(t, model.LabelSet{
+		"summary":       "3 notifications suppressed by flood protection, see http://dashboard",
+		"description":   "Suppressed notifications by receiver: team-X: 1, team-Y: 2",
+		"suppressed":    "3",
+		"dashboard_url": "http://dashboard",
+	}, summary.Annotations)
+	require.Equal(t, "http://alertmanager", summary.GeneratorURL)
+
+	// The counts are reset after each summary.
+	require.Nil(t, fp.summarize("http://alertmanager"))
+}
+
+func TestFloodProtectionSuppressedNotLogged(t *testing.T) {
+	fp := NewFloodProtection(&config.FloodProtectionConfig{
+		Notifications: 1,
+		Interval:      model.Duration(time.Hour),
+		Burst:         1,
+	}, nil, log.NewNopLogger())
+	fp.now = func() time.Time { return time.Unix(0, 0) }
+
+	var logged int
+	tnflog := &testNflog{
+		logFunc: func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error {
+			logged++
+			return nil
+		},
+	}
+	recv := &nflogpb.Receiver{GroupName: "team-X", Integration: "webhook"}
+	var sent int
+	s := MultiStage{
+		NewFloodProtectionStage(fp, recv),
+		StageFunc(func(ctx context.Context, l log.Logger, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+			sent++
+			return ctx, as, nil
+		}),
+		NewSetNotifiesStage(tnflog, recv, 0),
+	}
+
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithFiringAlerts(ctx, []uint64{1})
+	ctx = WithResolvedAlerts(ctx, []uint64{})
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+
+	for i := 0; i < 2; i++ {
+		_, _, err := s.Exec(ctx, log.NewNopLogger(), a)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, sent)
+	require.Equal(t, 1, logged)
+}
+
+func TestFloodStateMerge(t *testing.T) {
+	st := NewFloodState("peer1")
+	l := newRateLimiter(&config.RateLimitConfig{Notifications: 10, Interval: model.Duration(time.Hour), Burst: 10})
+	st.setLimiter(l, func() time.Time { return time.Unix(0, 0) })
+
+	start := time.Unix(100, 0)
+	merge := func(start time.Time, tokens uint64) {
+		b, err := json.Marshal(&floodUsage{Peer: "peer2", Start: start, Tokens: tokens})
+		require.NoError(t, err)
+		require.NoError(t, st.Merge(b))
+	}
+	// The first usage seen of a peer is a baseline.
+	merge(start, 5)
+	require.Equal(t, 10.0, l.tokens)
+	merge(start, 7)
+	require.Equal(t, 8.0, l.tokens)
+	// Duplicate and outdated updates are ignored.
+	merge(start, 7)
+	merge(start, 6)
+	require.Equal(t, 8.0, l.tokens)
+	// A restarted peer starts a new baseline.
+	merge(start.Add(time.Minute), 1)
+	require.Equal(t, 8.0, l.tokens)
+	merge(start.Add(time.Minute), 2)
+	require.Equal(t, 7.0, l.tokens)
+	// The own usage received back is ignored.
+	b, err := json.Marshal(&floodUsage{Peer: "peer1", Start: start, Tokens: 3})
+	require.NoError(t, err)
+	require.NoError(t, st.Merge(b))
+	require.Equal(t, 7.0, l.tokens)
+}
+
+func TestFloodProtectionRun(t *testing.T) {
+	fp := NewFloodProtection(&config.FloodProtectionConfig{
+		Notifications: 1,
+		Interval:      model.Duration(10 * time.Millisecond),
+		Burst:         1,
+		Receiver:      "team-X",
+	}, nil, log.NewNopLogger())
+	fp.allow("team-Y")
+	fp.allow("team-Y")
+
+	alerts := make(chan *types.Alert, 1)
+	pipeline := StageFunc(func(ctx context.Context, l log.Logger, as ...*types.Alert) (context.Context, []*types.Alert, error) {
+		recv, ok := ReceiverName(ctx)
+		require.True(t, ok)
+		require.Equal(t, "team-X", recv)
+		for _, a := range as {
+			alerts <- a
+		}
+		return ctx, as, nil
+	})
+	go fp.Run(pipeline, "")
+
+	select {
+	case a := <-alerts:
+		require.Equal(t, model.LabelValue("1"), a.Annotations["suppressed"])
+		require.Equal(t, model.LabelSet{model.AlertNameLabel: NotificationsSuppressedAlertName}, a.Labels)
+	case <-time.After(time.Second):
+		t.Fatal("no notification about suppressed notifications")
+	}
+	fp.Stop()
+}
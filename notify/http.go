@@ -0,0 +1,246 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	commoncfg "github.com/prometheus/common/config"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// newHTTPClient returns a new HTTP client for the given notifier HTTP
+// configuration. The status codes of the responses are reported to the
+// status recorder of the context, if any.
+func newHTTPClient(ctx context.Context, cfg *config.HTTPClientConfig) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(&cfg.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the proxy of the receiver is used, environment proxies are
+	// ignored. It's the caller's job to handle timeouts.
+	var rt http.RoundTripper = &http.Transport{
+		Proxy:             http.ProxyURL(cfg.ProxyURL.URL),
+		DisableKeepAlives: true,
+		TLSClientConfig:   tlsConfig,
+	}
+
+	bearerToken := cfg.BearerToken
+	if len(bearerToken) == 0 && len(cfg.BearerTokenFile) > 0 {
+		b, err := ioutil.ReadFile(cfg.BearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read bearer token file %s: %s", cfg.BearerTokenFile, err)
+		}
+		bearerToken = config.Secret(strings.TrimSpace(string(b)))
+	}
+	if len(bearerToken) > 0 {
+		rt = commoncfg.NewBearerAuthRoundTripper(commoncfg.Secret(bearerToken), rt)
+	}
+	if cfg.BasicAuth != nil {
+		rt = commoncfg.NewBasicAuthRoundTripper(cfg.BasicAuth.Username, cfg.BasicAuth.Password, rt)
+	}
+	if cfg.OAuth2 != nil {
+		rt = &oauth2RoundTripper{conf: cfg.OAuth2, next: rt}
+	}
+	if rec, ok := ctx.Value(keyStatusRecorder).(*statusRecorder); ok {
+		rt = &statusRecordingRoundTripper{rec: rec, next: rt}
+	}
+	if rec, ok := ctx.Value(keyRequestRecorder).(*requestRecorder); ok {
+		rt = &requestRecordingRoundTripper{rec: rec, next: rt}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// newTLSConfig returns a new TLS configuration for the given notifier TLS
+// configuration.
+func newTLSConfig(cfg *config.TLSConfig) (*tls.Config, error) {
+	tlsConfig, err := commoncfg.NewTLSConfig(&commoncfg.TLSConfig{
+		CAFile:             cfg.CAFile,
+		CertFile:           cfg.CertFile,
+		KeyFile:            cfg.KeyFile,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.MinVersion = uint16(cfg.MinVersion)
+	return tlsConfig, nil
+}
+
+// oauth2RoundTripper sets an access token obtained through the OAuth2 client
+// credentials grant on each request.
+type oauth2RoundTripper struct {
+	conf *config.OAuth2
+	next http.RoundTripper
+}
+
+func (rt *oauth2RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, token, err := oauth2Tokens.get(req.Context(), rt.conf, rt.next)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests must not be modified by round trippers.
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := rt.next.RoundTrip(r)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		// The token may have been revoked, fetch a new one next time.
+		oauth2Tokens.invalidate(key)
+	}
+	return resp, err
+}
+
+// oauth2ExpiryDelta is the time before its expiry at which a token is
+// refreshed.
+const oauth2ExpiryDelta = 10 * time.Second
+
+// oauth2Tokens caches access tokens across notifications as HTTP clients are
+// created for each notification.
+var oauth2Tokens = newOAuth2TokenCache()
+
+// oauth2Token is the cached access token of a client. Its lock is held while
+// the token is fetched so that concurrent notifications of the client wait
+// for a single request instead of all requesting a token.
+type oauth2Token struct {
+	lock        chan struct{}
+	accessToken string
+	expiry      time.Time
+}
+
+// oauth2TokenCache caches access tokens by client. The mutex only guards the
+// map, tokens of different clients are fetched independently.
+type oauth2TokenCache struct {
+	now func() time.Time
+
+	mtx    sync.Mutex
+	tokens map[string]*oauth2Token
+}
+
+func newOAuth2TokenCache() *oauth2TokenCache {
+	return &oauth2TokenCache{
+		now:    time.Now,
+		tokens: map[string]*oauth2Token{},
+	}
+}
+
+// token returns the cached token of the key, creating it if needed.
+func (c *oauth2TokenCache) token(key string) *oauth2Token {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t, ok := c.tokens[key]
+	if !ok {
+		t = &oauth2Token{lock: make(chan struct{}, 1)}
+		c.tokens[key] = t
+	}
+	return t
+}
+
+// get returns the key of the client and a valid access token, which is
+// requested through the round tripper if it is not cached.
+func (c *oauth2TokenCache) get(ctx context.Context, conf *config.OAuth2, rt http.RoundTripper) (string, string, error) {
+	secret := string(conf.ClientSecret)
+	if conf.ClientSecretFile != "" {
+		b, err := ioutil.ReadFile(conf.ClientSecretFile)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to read client secret file %s: %s", conf.ClientSecretFile, err)
+		}
+		secret = strings.TrimSpace(string(b))
+	}
+	key := hashKey(strings.Join([]string{conf.TokenURL, conf.ClientID, secret, strings.Join(conf.Scopes, " ")}, "\xff"))
+
+	t := c.token(key)
+	select {
+	case t.lock <- struct{}{}:
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	}
+	defer func() { <-t.lock }()
+
+	if t.accessToken != "" && (t.expiry.IsZero() || c.now().Before(t.expiry)) {
+		return key, t.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(conf.Scopes) > 0 {
+		form.Set("scope", strings.Join(conf.Scopes, " "))
+	}
+	for k, v := range conf.EndpointParams {
+		form.Set(k, v)
+	}
+	tokenReq, err := http.NewRequest("POST", conf.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", err
+	}
+	tokenReq = tokenReq.WithContext(ctx)
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.Header.Set("User-Agent", userAgentHeader)
+	tokenReq.SetBasicAuth(url.QueryEscape(conf.ClientID), url.QueryEscape(secret))
+
+	resp, err := rt.RoundTrip(tokenReq)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return "", "", fmt.Errorf("unexpected status code %v requesting OAuth2 token", resp.StatusCode)
+	}
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return "", "", err
+	}
+	if tr.AccessToken == "" {
+		return "", "", fmt.Errorf("no access token in OAuth2 token response")
+	}
+
+	t.accessToken = tr.AccessToken
+	t.expiry = time.Time{}
+	if tr.ExpiresIn > 0 {
+		// Refresh the token a bit before it actually expires.
+		t.expiry = c.now().Add(time.Duration(tr.ExpiresIn)*time.Second - oauth2ExpiryDelta)
+	}
+
+	return key, t.accessToken, nil
+}
+
+func (c *oauth2TokenCache) invalidate(key string) {
+	c.mtx.Lock()
+	delete(c.tokens, key)
+	c.mtx.Unlock()
+}
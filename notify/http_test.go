@@ -0,0 +1,233 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	commoncfg "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/audit"
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var (
+		tokens  int
+		revoked bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			user, pass, _ := r.BasicAuth()
+			require.Equal(t, "am", user)
+			require.Equal(t, "s3cr3t", pass)
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			require.Equal(t, "alerts.write", r.PostForm.Get("scope"))
+			require.Equal(t, "api://alertmanager", r.PostForm.Get("resource"))
+			tokens++
+			fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, tokens)
+		case "/hook":
+			if revoked || r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", tokens) {
+				revoked = false
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	c, err := newHTTPClient(context.Background(), &config.HTTPClientConfig{
+		OAuth2: &config.OAuth2{
+			ClientID:       "am",
+			ClientSecret:   "s3cr3t",
+			TokenURL:       srv.URL + "/token",
+			Scopes:         []string{"alerts.write"},
+			EndpointParams: map[string]string{"resource": "api://alertmanager"},
+		},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(srv.URL + "/hook")
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	require.Equal(t, 1, tokens)
+
+	// A rejected token is fetched again on the next request.
+	revoked = true
+	resp, err := c.Get(srv.URL + "/hook")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = c.Get(srv.URL + "/hook")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, tokens)
+}
+
+func TestOAuth2TokenCacheConcurrency(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		tokens = map[string]int{}
+	)
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		if user == "slow" {
+			<-block
+		}
+		mtx.Lock()
+		tokens[user]++
+		mtx.Unlock()
+		fmt.Fprint(w, `{"access_token":"token","expires_in":3600}`)
+	}))
+	defer srv.Close()
+
+	cache := newOAuth2TokenCache()
+	get := func(client string) error {
+		_, _, err := cache.get(context.Background(), &config.OAuth2{ClientID: client, TokenURL: srv.URL}, http.DefaultTransport)
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, get("slow"))
+		}()
+	}
+
+	// Fetching the token of a slow token endpoint does not block the
+	// tokens of other clients.
+	done := make(chan error)
+	go func() { done <- get("fast") }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("token request blocked by another client")
+	}
+
+	// Concurrent notifications of a client request a single token.
+	close(block)
+	wg.Wait()
+	require.Equal(t, map[string]int{"slow": 1, "fast": 1}, tokens)
+}
+
+func TestHTTPClientMinTLSVersion(t *testing.T) {
+	tls13, ok := config.TLSVersions["TLS13"]
+	if !ok {
+		t.Skip("TLS 1.3 is not supported by this Go version")
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c, err := newHTTPClient(context.Background(), &config.HTTPClientConfig{
+		TLSConfig: config.TLSConfig{InsecureSkipVerify: true},
+	})
+	require.NoError(t, err)
+	resp, err := c.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	c, err = newHTTPClient(context.Background(), &config.HTTPClientConfig{
+		TLSConfig: config.TLSConfig{InsecureSkipVerify: true, MinVersion: tls13},
+	})
+	require.NoError(t, err)
+	_, err = c.Get(srv.URL)
+	require.Error(t, err)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	c, err := newHTTPClient(context.Background(), &config.HTTPClientConfig{
+		ProxyURL: commoncfg.URL{URL: proxyURL},
+	})
+	require.NoError(t, err)
+
+	resp, err := c.Get("http://webhook.internal/hook")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, []string{"http://webhook.internal/hook"}, proxied)
+}
+
+func TestAuditNotifierStatusCode(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var entries []*audit.Entry
+	n := &auditNotifier{
+		notifier: NewWebhook(
+			&config.WebhookConfig{URL: srv.URL, HTTPConfig: &config.HTTPClientConfig{}},
+			createTmpl(t),
+			log.NewNopLogger(),
+		),
+		log: auditLogFunc(func(e *audit.Entry) error {
+			entries = append(entries, e)
+			return nil
+		}),
+		recv:   &nflogpb.Receiver{GroupName: "team-X", Integration: "webhook"},
+		logger: log.NewNopLogger(),
+	}
+
+	ctx := WithGroupKey(context.Background(), "1")
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+	retry, err := n.Notify(ctx, a)
+	require.True(t, retry)
+	require.Error(t, err)
+
+	require.Len(t, entries, 1)
+	require.Equal(t, "team-X", entries[0].Receiver)
+	require.Equal(t, "webhook", entries[0].Integration)
+	require.Equal(t, "1", entries[0].GroupKey)
+	require.Equal(t, []string{a.Fingerprint().String()}, entries[0].Alerts)
+	require.False(t, entries[0].Success)
+	require.Equal(t, http.StatusServiceUnavailable, entries[0].StatusCode)
+}
+
+type auditLogFunc func(*audit.Entry) error
+
+func (f auditLogFunc) Log(e *audit.Entry) error {
+	return f(e)
+}
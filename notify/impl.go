@@ -14,12 +14,23 @@
 package notify
 
 import (
+	"bufio"
 	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"mime"
 	"mime/multipart"
@@ -29,12 +40,16 @@ import (
 	"net/smtp"
 	"net/textproto"
 	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
-	commoncfg "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/version"
 	"golang.org/x/net/context"
@@ -83,6 +98,12 @@ func (i *Integration) Notify(ctx context.Context, alerts ...*types.Alert) (bool,
 	if len(res) == 0 {
 		return false, nil
 	}
+	if ctx != nil {
+		if rec, ok := ctx.Value(keyRequestRecorder).(*requestRecorder); ok {
+			// Only the requests of the last attempt are kept.
+			rec.reset()
+		}
+	}
 
 	return i.notifier.Notify(ctx, res...)
 }
@@ -138,6 +159,54 @@ func BuildReceiverIntegrations(nc *config.Receiver, tmpl *template.Template, log
 		n := NewPushover(c, tmpl, logger)
 		add("pushover", i, n, c)
 	}
+	for i, c := range nc.NATSConfigs {
+		n := NewNATS(c, tmpl, logger)
+		add("nats", i, n, c)
+	}
+	for i, c := range nc.MQTTConfigs {
+		n := NewMQTT(c, tmpl, logger)
+		add("mqtt", i, n, c)
+	}
+	for i, c := range nc.TwilioConfigs {
+		n := NewTwilio(c, tmpl, logger)
+		add("twilio", i, n, c)
+	}
+	for i, c := range nc.VoiceConfigs {
+		n := NewVoice(c, tmpl, logger)
+		add("voice", i, n, c)
+	}
+	for i, c := range nc.JiraConfigs {
+		n := NewJira(c, tmpl, logger)
+		add("jira", i, n, c)
+	}
+	for i, c := range nc.ServiceNowConfigs {
+		n := NewServiceNow(c, tmpl, logger)
+		add("servicenow", i, n, c)
+	}
+	for i, c := range nc.SplunkConfigs {
+		n := NewSplunk(c, tmpl, logger)
+		add("splunk", i, n, c)
+	}
+	for i, c := range nc.PubSubConfigs {
+		n := NewPubSub(c, tmpl, logger)
+		add("pubsub", i, n, c)
+	}
+	for i, c := range nc.PluginConfigs {
+		n := NewPlugin(c, tmpl, logger)
+		add("plugin", i, n, c)
+	}
+	for i, c := range nc.ExecConfigs {
+		n := NewExec(c, tmpl, logger)
+		add("exec", i, n, c)
+	}
+	for i, c := range nc.SyslogConfigs {
+		n := NewSyslog(c, tmpl, logger)
+		add("syslog", i, n, c)
+	}
+	for i, c := range nc.SNMPConfigs {
+		n := NewSNMP(c, tmpl, logger)
+		add("snmp", i, n, c)
+	}
 	return integrations
 }
 
@@ -168,32 +237,75 @@ type WebhookMessage struct {
 
 // Notify implements the Notifier interface.
 func (w *Webhook) Notify(ctx context.Context, alerts ...*types.Alert) (bool, error) {
-	data := w.tmpl.Data(receiverName(ctx, w.logger), groupLabels(ctx, w.logger), alerts...)
+	data := tmplData(ctx, w.tmpl, w.logger, alerts...)
 
 	groupKey, ok := GroupKey(ctx)
 	if !ok {
 		level.Error(w.logger).Log("msg", "group key missing")
 	}
 
-	msg := &WebhookMessage{
-		Version:  "4",
-		Data:     data,
-		GroupKey: groupKey,
+	var (
+		buf         bytes.Buffer
+		contentType = contentTypeJSON
+	)
+	if w.conf.Body != "" {
+		var err error
+		tmpl := tmplText(w.tmpl, data, &err)
+		buf.WriteString(tmpl(w.conf.Body))
+		if err != nil {
+			return false, err
+		}
+	} else {
+		msg := &WebhookMessage{
+			Version:  "4",
+			Data:     data,
+			GroupKey: groupKey,
+		}
+		if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+			return false, err
+		}
+	}
+	if w.conf.ContentType != "" {
+		contentType = w.conf.ContentType
+	}
+	method := w.conf.Method
+	if method == "" {
+		method = "POST"
 	}
 
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
-		return false, err
+	var ce *cloudEvent
+	if w.conf.CloudEvents != nil {
+		now, ok := Now(ctx)
+		if !ok {
+			now = time.Now()
+		}
+		ce = newCloudEvent(w.conf.CloudEvents, data.ExternalURL, groupKey, now)
+		if w.conf.CloudEvents.Mode == "structured" {
+			b, err := ce.structured(buf.Bytes(), contentType)
+			if err != nil {
+				return false, err
+			}
+			buf.Reset()
+			buf.Write(b)
+			contentType = contentTypeCloudEvents
+		}
 	}
 
-	req, err := http.NewRequest("POST", w.conf.URL, &buf)
+	body := buf.Bytes()
+	req, err := http.NewRequest(method, w.conf.URL, &buf)
 	if err != nil {
 		return true, err
 	}
-	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("User-Agent", userAgentHeader)
+	if ce != nil && w.conf.CloudEvents.Mode == "binary" {
+		ce.setHeaders(req.Header)
+	}
+	if w.conf.SigningSecret != "" {
+		signWebhookRequest(req, []byte(w.conf.SigningSecret), body, time.Now())
+	}
 
-	c, err := commoncfg.NewHTTPClientFromConfig(w.conf.HTTPConfig)
+	c, err := newHTTPClient(ctx, w.conf.HTTPConfig)
 	if err != nil {
 		return false, err
 	}
@@ -207,6 +319,80 @@ func (w *Webhook) Notify(ctx context.Context, alerts ...*types.Alert) (bool, err
 	return w.retry(resp.StatusCode)
 }
 
+const contentTypeCloudEvents = "application/cloudevents+json"
+
+// cloudEvent holds the context attributes of a CloudEvents 1.0 event.
+type cloudEvent struct {
+	SpecVersion string `json:"specversion"`
+	ID          string `json:"id"`
+	Source      string `json:"source"`
+	Type        string `json:"type"`
+	Time        string `json:"time"`
+}
+
+// newCloudEvent returns the event of the notification of the group at the
+// given time. Retries of the same notification share the same ID so that
+// consumers can deduplicate them.
+func newCloudEvent(conf *config.CloudEventsConfig, externalURL, groupKey string, now time.Time) *cloudEvent {
+	source := conf.Source
+	if source == "" {
+		source = externalURL
+	}
+	return &cloudEvent{
+		SpecVersion: "1.0",
+		ID:          hashKey(fmt.Sprintf("%s/%d", groupKey, now.UnixNano())),
+		Source:      source,
+		Type:        conf.Type,
+		Time:        now.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// setHeaders sets the context attributes for the binary content mode, in
+// which the request body is the event data.
+func (e *cloudEvent) setHeaders(h http.Header) {
+	h.Set("ce-specversion", e.SpecVersion)
+	h.Set("ce-id", e.ID)
+	h.Set("ce-source", e.Source)
+	h.Set("ce-type", e.Type)
+	h.Set("ce-time", e.Time)
+}
+
+// structured returns the event in the structured content mode with the
+// given data. JSON data is embedded as is, anything else is base64 encoded.
+func (e *cloudEvent) structured(data []byte, contentType string) ([]byte, error) {
+	msg := struct {
+		*cloudEvent
+		DataContentType string          `json:"datacontenttype"`
+		Data            json.RawMessage `json:"data,omitempty"`
+		DataBase64      string          `json:"data_base64,omitempty"`
+	}{
+		cloudEvent:      e,
+		DataContentType: contentType,
+	}
+	if strings.HasSuffix(strings.SplitN(contentType, ";", 2)[0], "json") && json.Valid(data) {
+		msg.Data = json.RawMessage(bytes.TrimSpace(data))
+	} else {
+		msg.DataBase64 = base64.StdEncoding.EncodeToString(data)
+	}
+	return json.Marshal(msg)
+}
+
+// signWebhookRequest sets the HMAC-SHA256 signature of the timestamp and body
+// of a webhook request. Receivers verify the signature by computing the HMAC
+// of "<X-Alertmanager-Timestamp>.<body>" and should reject requests with an
+// old timestamp to prevent replays.
+func signWebhookRequest(req *http.Request, secret, body []byte, now time.Time) {
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	req.Header.Set("X-Alertmanager-Timestamp", ts)
+	req.Header.Set("X-Alertmanager-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+}
+
 func (w *Webhook) retry(statusCode int) (bool, error) {
 	// Webhooks are assumed to respond with 2xx response codes on a successful
 	// request and 5xx response codes are assumed to be recoverable.
@@ -222,6 +408,7 @@ type Email struct {
 	conf   *config.EmailConfig
 	tmpl   *template.Template
 	logger log.Logger
+	tokens *oauth2TokenSource
 }
 
 // NewEmail returns a new Email notifier.
@@ -235,15 +422,29 @@ func NewEmail(c *config.EmailConfig, t *template.Template, l log.Logger) *Email
 	if _, ok := c.Headers["From"]; !ok {
 		c.Headers["From"] = c.From
 	}
-	return &Email{conf: c, tmpl: t, logger: l}
+	n := &Email{conf: c, tmpl: t, logger: l}
+	if c.AuthOAuth2 != nil {
+		n.tokens = newOAuth2TokenSource(c.AuthOAuth2)
+	}
+	return n
 }
 
 // auth resolves a string of authentication mechanisms.
-func (n *Email) auth(mechs string) (smtp.Auth, error) {
+func (n *Email) auth(ctx context.Context, mechs string) (smtp.Auth, error) {
 	username := n.conf.AuthUsername
 
 	for _, mech := range strings.Split(mechs, " ") {
 		switch mech {
+		case "XOAUTH2":
+			if n.tokens == nil {
+				continue
+			}
+			token, err := n.tokens.Token(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get oauth2 token: %s", err)
+			}
+			return XOAuth2Auth(username, token), nil
+
 		case "CRAM-MD5":
 			secret := string(n.conf.AuthSecret)
 			if secret == "" {
@@ -322,7 +523,7 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	}
 
 	if ok, mech := c.Extension("AUTH"); ok {
-		auth, err := n.auth(mech)
+		auth, err := n.auth(ctx, mech)
 		if err != nil {
 			return true, err
 		}
@@ -334,7 +535,7 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	}
 
 	var (
-		data = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data = tmplData(ctx, n.tmpl, n.logger, as...)
 		tmpl = tmplText(n.tmpl, data, &err)
 		from = tmpl(n.conf.From)
 		to   = tmpl(n.conf.To)
@@ -382,12 +583,12 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	multipartWriter := multipart.NewWriter(buffer)
 
 	fmt.Fprintf(wc, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
-	fmt.Fprintf(wc, "Content-Type: multipart/alternative;  boundary=%s\r\n", multipartWriter.Boundary())
 	fmt.Fprintf(wc, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buffer, "Content-Type: multipart/alternative;  boundary=%s\r\n", multipartWriter.Boundary())
 
 	// TODO: Add some useful headers here, such as URL of the alertmanager
 	// and active/resolved.
-	fmt.Fprintf(wc, "\r\n")
+	fmt.Fprintf(buffer, "\r\n")
 
 	if len(n.conf.Text) > 0 {
 		// Text template
@@ -424,7 +625,18 @@ func (n *Email) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	}
 
 	multipartWriter.Close()
-	wc.Write(buffer.Bytes())
+
+	body := buffer.Bytes()
+	if n.conf.SMIME != nil {
+		signer, err := newSMIMESigner(n.conf.SMIME)
+		if err != nil {
+			return false, err
+		}
+		if body, err = signer.wrap(body); err != nil {
+			return false, fmt.Errorf("signing email: %s", err)
+		}
+	}
+	wc.Write(body)
 
 	return false, nil
 }
@@ -458,6 +670,32 @@ type pagerDutyMessage struct {
 	Client      string            `json:"client,omitempty"`
 	ClientURL   string            `json:"client_url,omitempty"`
 	Details     map[string]string `json:"details,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+	Images      []pagerDutyImage  `json:"images,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text,omitempty"`
+}
+
+type pagerDutyImage struct {
+	Src  string `json:"src"`
+	Alt  string `json:"alt,omitempty"`
+	Href string `json:"href,omitempty"`
+}
+
+type pagerDutyChangeEvent struct {
+	RoutingKey string                  `json:"routing_key"`
+	Payload    *pagerDutyChangePayload `json:"payload"`
+	Links      []pagerDutyLink         `json:"links,omitempty"`
+}
+
+type pagerDutyChangePayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source,omitempty"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
 }
 
 type pagerDutyPayload struct {
@@ -512,7 +750,7 @@ func (n *PagerDuty) notifyV2(ctx context.Context, c *http.Client, eventType, key
 		payload = &pagerDutyPayload{
 			Summary:       tmpl(n.conf.Description),
 			Source:        tmpl(n.conf.Client),
-			Severity:      tmpl(n.conf.Severity),
+			Severity:      n.severity(tmpl(n.conf.Severity)),
 			CustomDetails: details,
 			Class:         tmpl(n.conf.Class),
 			Component:     tmpl(n.conf.Component),
@@ -530,6 +768,8 @@ func (n *PagerDuty) notifyV2(ctx context.Context, c *http.Client, eventType, key
 	if eventType == pagerDutyEventTrigger {
 		msg.Client = tmpl(n.conf.Client)
 		msg.ClientURL = tmpl(n.conf.ClientURL)
+		msg.Links = n.links(tmpl)
+		msg.Images = n.images(tmpl)
 	}
 
 	var buf bytes.Buffer
@@ -546,6 +786,80 @@ func (n *PagerDuty) notifyV2(ctx context.Context, c *http.Client, eventType, key
 	return n.retryV2(resp.StatusCode)
 }
 
+// notifyChange sends a change event.
+//
+// https://developer.pagerduty.com/docs/events-api-v2/send-change-events/
+func (n *PagerDuty) notifyChange(ctx context.Context, c *http.Client, tmpl func(string) string, details map[string]string) (bool, error) {
+	now, ok := Now(ctx)
+	if !ok {
+		now = time.Now()
+	}
+	msg := &pagerDutyChangeEvent{
+		RoutingKey: tmpl(string(n.conf.RoutingKey)),
+		Payload: &pagerDutyChangePayload{
+			Summary:       tmpl(n.conf.Description),
+			Source:        tmpl(n.conf.Client),
+			Timestamp:     now.UTC().Format(time.RFC3339),
+			CustomDetails: details,
+		},
+		Links: n.links(tmpl),
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+		return false, err
+	}
+
+	u, err := url.Parse(n.conf.URL)
+	if err != nil {
+		return false, err
+	}
+	u.Path = path.Join(path.Dir(u.Path), "change/enqueue")
+
+	resp, err := ctxhttp.Post(ctx, c, u.String(), contentTypeJSON, &buf)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	return n.retryV2(resp.StatusCode)
+}
+
+// severity maps a rendered severity to a PagerDuty severity.
+func (n *PagerDuty) severity(s string) string {
+	if mapped, ok := n.conf.SeverityMap[s]; ok {
+		return mapped
+	}
+	switch s {
+	case "critical", "error", "warning", "info":
+		return s
+	}
+	level.Warn(n.logger).Log("msg", "Unknown PagerDuty severity, using error", "severity", s)
+	return "error"
+}
+
+func (n *PagerDuty) links(tmpl func(string) string) []pagerDutyLink {
+	var links []pagerDutyLink
+	for _, l := range n.conf.Links {
+		link := pagerDutyLink{Href: tmpl(l.Href), Text: tmpl(l.Text)}
+		if link.Href != "" {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func (n *PagerDuty) images(tmpl func(string) string) []pagerDutyImage {
+	var images []pagerDutyImage
+	for _, i := range n.conf.Images {
+		image := pagerDutyImage{Src: tmpl(i.Src), Alt: tmpl(i.Alt), Href: tmpl(i.Href)}
+		if image.Src != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
 // Notify implements the Notifier interface.
 //
 // https://v2.developer.pagerduty.com/docs/events-api-v2
@@ -558,7 +872,7 @@ func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 	var err error
 	var (
 		alerts    = types.Alerts(as...)
-		data      = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data      = tmplData(ctx, n.tmpl, n.logger, as...)
 		tmpl      = tmplText(n.tmpl, data, &err)
 		eventType = pagerDutyEventTrigger
 	)
@@ -566,6 +880,10 @@ func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 		eventType = pagerDutyEventResolve
 	}
 
+	if n.conf.ChangeEvents && eventType == pagerDutyEventResolve {
+		return false, nil
+	}
+
 	level.Debug(n.logger).Log("msg", "Notifying PagerDuty", "incident", key, "eventType", eventType)
 
 	details := make(map[string]string, len(n.conf.Details))
@@ -577,11 +895,14 @@ func (n *PagerDuty) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 		return false, err
 	}
 
-	c, err := commoncfg.NewHTTPClientFromConfig(n.conf.HTTPConfig)
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
 	if err != nil {
 		return false, err
 	}
 
+	if n.conf.ChangeEvents {
+		return n.notifyChange(ctx, c, tmpl, details)
+	}
 	if n.conf.ServiceKey != "" {
 		return n.notifyV1(ctx, c, eventType, key, tmpl, details, as...)
 	}
@@ -655,7 +976,7 @@ type slackAttachment struct {
 func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	var err error
 	var (
-		data     = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data     = tmplData(ctx, n.tmpl, n.logger, as...)
 		tmplText = tmplText(n.tmpl, data, &err)
 	)
 
@@ -723,7 +1044,7 @@ func (n *Slack) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 		return false, err
 	}
 
-	c, err := commoncfg.NewHTTPClientFromConfig(n.conf.HTTPConfig)
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
 	if err != nil {
 		return false, err
 	}
@@ -777,7 +1098,7 @@ func (n *Hipchat) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 	var err error
 	var msg string
 	var (
-		data     = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data     = tmplData(ctx, n.tmpl, n.logger, as...)
 		tmplText = tmplText(n.tmpl, data, &err)
 		tmplHTML = tmplHTML(n.tmpl, data, &err)
 		url      = fmt.Sprintf("%sv2/room/%s/notification?auth_token=%s", n.conf.APIURL, n.conf.RoomID, n.conf.AuthToken)
@@ -805,7 +1126,7 @@ func (n *Hipchat) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 		return false, err
 	}
 
-	c, err := commoncfg.NewHTTPClientFromConfig(n.conf.HTTPConfig)
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
 	if err != nil {
 		return false, err
 	}
@@ -878,7 +1199,7 @@ func (n *Wechat) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 	}
 
 	level.Debug(n.logger).Log("msg", "Notifying Wechat", "incident", key)
-	data := n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
 
 	var err error
 	tmpl := tmplText(n.tmpl, data, &err)
@@ -886,7 +1207,7 @@ func (n *Wechat) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
 		return false, err
 	}
 
-	c, err := commoncfg.NewHTTPClientFromConfig(n.conf.HTTPConfig)
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
 	if err != nil {
 		return false, err
 	}
@@ -1013,6 +1334,16 @@ type opsGenieCreateMessage struct {
 	Tags        []string            `json:"tags,omitempty"`
 	Note        string              `json:"note,omitempty"`
 	Priority    string              `json:"priority,omitempty"`
+	Responders  []opsGenieResponder `json:"responders,omitempty"`
+	Actions     []string            `json:"actions,omitempty"`
+	Entity      string              `json:"entity,omitempty"`
+}
+
+type opsGenieResponder struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+	Type     string `json:"type"`
 }
 
 type opsGenieCloseMessage struct {
@@ -1026,7 +1357,7 @@ func (n *OpsGenie) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 		return retry, err
 	}
 
-	c, err := commoncfg.NewHTTPClientFromConfig(n.conf.HTTPConfig)
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
 	if err != nil {
 		return false, err
 	}
@@ -1059,7 +1390,7 @@ func (n *OpsGenie) createRequest(ctx context.Context, as ...*types.Alert) (*http
 	if !ok {
 		return nil, false, fmt.Errorf("group key missing")
 	}
-	data := n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
 
 	level.Debug(n.logger).Log("msg", "Notifying OpsGenie", "incident", key)
 
@@ -1067,6 +1398,11 @@ func (n *OpsGenie) createRequest(ctx context.Context, as ...*types.Alert) (*http
 	tmpl := tmplText(n.tmpl, data, &err)
 
 	details := make(map[string]string, len(n.conf.Details))
+	if n.conf.LabelDetails {
+		for k, v := range data.CommonLabels {
+			details[k] = v
+		}
+	}
 	for k, v := range n.conf.Details {
 		details[k] = tmpl(v)
 	}
@@ -1095,6 +1431,22 @@ func (n *OpsGenie) createRequest(ctx context.Context, as ...*types.Alert) (*http
 		}
 		tags := safeSplit(string(tmpl(n.conf.Tags)), ",")
 
+		var responders []opsGenieResponder
+		for _, r := range n.conf.Responders {
+			responder := opsGenieResponder{
+				ID:       tmpl(r.ID),
+				Name:     tmpl(r.Name),
+				Username: tmpl(r.Username),
+				Type:     r.Type,
+			}
+			// Responders whose template renders empty are skipped, this
+			// allows selecting them by labels.
+			if responder.ID == "" && responder.Name == "" && responder.Username == "" {
+				continue
+			}
+			responders = append(responders, responder)
+		}
+
 		msg = &opsGenieCreateMessage{
 			Alias:       alias,
 			Message:     message,
@@ -1104,7 +1456,10 @@ func (n *OpsGenie) createRequest(ctx context.Context, as ...*types.Alert) (*http
 			Teams:       teams,
 			Tags:        tags,
 			Note:        tmpl(n.conf.Note),
-			Priority:    tmpl(n.conf.Priority),
+			Priority:    n.priority(data, tmpl),
+			Responders:  responders,
+			Actions:     safeSplit(tmpl(n.conf.Actions), ","),
+			Entity:      tmpl(n.conf.Entity),
 		}
 	}
 	if err != nil {
@@ -1125,6 +1480,31 @@ func (n *OpsGenie) createRequest(ctx context.Context, as ...*types.Alert) (*http
 	return req, true, nil
 }
 
+// priority returns the priority of the first priority rule matching the
+// common labels or the configured priority.
+func (n *OpsGenie) priority(data *template.Data, tmpl func(string) string) string {
+	for _, r := range n.conf.PriorityRules {
+		if opsGenieRuleMatches(r, data.CommonLabels) {
+			return r.Priority
+		}
+	}
+	return tmpl(n.conf.Priority)
+}
+
+func opsGenieRuleMatches(r *config.OpsGeniePriorityRule, labels template.KV) bool {
+	for k, v := range r.Match {
+		if labels[k] != v {
+			return false
+		}
+	}
+	for k, re := range r.MatchRE {
+		if !re.MatchString(labels[k]) {
+			return false
+		}
+	}
+	return true
+}
+
 func (n *OpsGenie) retry(statusCode int) (bool, error) {
 	// https://docs.opsgenie.com/docs/response#section-response-codes
 	// Response codes 429 (rate limiting) and 5xx are potentially recoverable
@@ -1182,7 +1562,7 @@ func (n *VictorOps) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 	var err error
 	var (
 		alerts       = types.Alerts(as...)
-		data         = n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+		data         = tmplData(ctx, n.tmpl, n.logger, as...)
 		tmpl         = tmplText(n.tmpl, data, &err)
 		apiURL       = fmt.Sprintf("%s%s/%s", n.conf.APIURL, n.conf.APIKey, tmpl(n.conf.RoutingKey))
 		messageType  = tmpl(n.conf.MessageType)
@@ -1219,7 +1599,7 @@ func (n *VictorOps) Notify(ctx context.Context, as ...*types.Alert) (bool, error
 		return false, err
 	}
 
-	c, err := commoncfg.NewHTTPClientFromConfig(n.conf.HTTPConfig)
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
 	if err != nil {
 		return false, err
 	}
@@ -1264,7 +1644,7 @@ func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 	if !ok {
 		return false, fmt.Errorf("group key missing")
 	}
-	data := n.tmpl.Data(receiverName(ctx, n.logger), groupLabels(ctx, n.logger), as...)
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
 
 	level.Debug(n.logger).Log("msg", "Notifying Pushover", "incident", key)
 
@@ -1316,7 +1696,7 @@ func (n *Pushover) Notify(ctx context.Context, as ...*types.Alert) (bool, error)
 	u.RawQuery = parameters.Encode()
 	level.Debug(n.logger).Log("msg", "Sending Pushover message", "incident", key, "url", u.String())
 
-	c, err := commoncfg.NewHTTPClientFromConfig(n.conf.HTTPConfig)
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
 	if err != nil {
 		return false, err
 	}
@@ -1343,57 +1723,1963 @@ func (n *Pushover) retry(statusCode int) (bool, error) {
 	return false, nil
 }
 
-func tmplText(tmpl *template.Template, data *template.Data, err *error) func(string) string {
-	return func(name string) (s string) {
-		if *err != nil {
-			return
+// NATS implements a Notifier for publishing to NATS subjects.
+type NATS struct {
+	conf   *config.NATSConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewNATS returns a new NATS notifier.
+func NewNATS(c *config.NATSConfig, t *template.Template, l log.Logger) *NATS {
+	return &NATS{conf: c, tmpl: t, logger: l}
+}
+
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+	Headers     bool `json:"headers"`
+}
+
+type natsConnect struct {
+	Verbose      bool   `json:"verbose"`
+	Pedantic     bool   `json:"pedantic"`
+	TLSRequired  bool   `json:"tls_required"`
+	Name         string `json:"name"`
+	Lang         string `json:"lang"`
+	Version      string `json:"version"`
+	Protocol     int    `json:"protocol"`
+	Headers      bool   `json:"headers"`
+	NoResponders bool   `json:"no_responders"`
+	User         string `json:"user,omitempty"`
+	Pass         string `json:"pass,omitempty"`
+	AuthToken    string `json:"auth_token,omitempty"`
+}
+
+type natsPubAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error,omitempty"`
+}
+
+// Notify implements the Notifier interface.
+func (n *NATS) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	subject := tmplText(n.tmpl, data, &err)(n.conf.Subject)
+	if err != nil {
+		return false, err
+	}
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return false, fmt.Errorf("invalid NATS subject %q", subject)
+	}
+
+	msg := &WebhookMessage{
+		Version:  "4",
+		Data:     data,
+		GroupKey: key,
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return false, err
+	}
+
+	level.Debug(n.logger).Log("msg", "Publishing NATS message", "incident", key, "subject", subject)
+
+	conn, info, err := n.connect(ctx)
+	if err != nil {
+		return true, err
+	}
+	defer conn.Close()
+
+	if !n.conf.JetStream {
+		if _, err := fmt.Fprintf(conn, "PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload); err != nil {
+			return true, err
 		}
-		s, *err = tmpl.ExecuteTextString(name, data)
-		return s
+		return n.awaitPong(conn)
+	}
+
+	if !info.Headers {
+		return false, fmt.Errorf("NATS server does not support headers required for JetStream publishing")
+	}
+	// Retries of the same notification share the same message ID so that the
+	// stream can deduplicate them.
+	now, ok := Now(ctx)
+	if !ok {
+		now = time.Now()
+	}
+	msgID := hashKey(fmt.Sprintf("%s/%d", key, now.UnixNano()))
+	inbox := "_INBOX." + hashKey(fmt.Sprintf("%s/%d", msgID, time.Now().UnixNano()))[:22]
+	hdr := "NATS/1.0\r\nNats-Msg-Id: " + msgID + "\r\n\r\n"
+
+	if _, err := fmt.Fprintf(conn, "SUB %s 1\r\nHPUB %s %s %d %d\r\n%s%s\r\n",
+		inbox, subject, inbox, len(hdr), len(hdr)+len(payload), hdr, payload); err != nil {
+		return true, err
 	}
+	return n.awaitAck(conn)
 }
 
-func tmplHTML(tmpl *template.Template, data *template.Data, err *error) func(string) string {
-	return func(name string) (s string) {
-		if *err != nil {
-			return
+// connect dials the NATS server and performs the connection handshake.
+func (n *NATS) connect(ctx context.Context) (*natsConn, *natsInfo, error) {
+	u, err := url.Parse(n.conf.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+	}
+	conn := &natsConn{Conn: c, r: bufio.NewReader(c)}
+
+	line, err := conn.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, nil, fmt.Errorf("unexpected NATS greeting %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(line[len("INFO "):]), &info); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	useTLS := u.Scheme == "tls" || info.TLSRequired || n.conf.TLS != nil
+	if useTLS {
+		tlsCfg := n.conf.TLS
+		if tlsCfg == nil {
+			tlsCfg = &config.TLSConfig{}
 		}
-		s, *err = tmpl.ExecuteHTMLString(name, data)
-		return s
+		tc, err := newTLSConfig(tlsCfg)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		if tc.ServerName == "" {
+			tc.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(c, tc)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		conn = &natsConn{Conn: tlsConn, r: bufio.NewReader(tlsConn)}
+	}
+
+	connect := natsConnect{
+		TLSRequired:  useTLS,
+		Name:         "alertmanager",
+		Lang:         "go",
+		Version:      version.Version,
+		Protocol:     1,
+		Headers:      info.Headers,
+		NoResponders: info.Headers,
+		User:         n.conf.Username,
+		Pass:         string(n.conf.Password),
+		AuthToken:    string(n.conf.Token),
+	}
+	if u.User != nil && connect.User == "" && connect.AuthToken == "" {
+		if pass, ok := u.User.Password(); ok {
+			connect.User, connect.Pass = u.User.Username(), pass
+		} else {
+			connect.AuthToken = u.User.Username()
+		}
+	}
+	b, err := json.Marshal(connect)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
 	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", b); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, &info, nil
 }
 
-type loginAuth struct {
-	username, password string
+// awaitPong waits for the server to answer the PING sent after a publish,
+// which guarantees that the message was processed by the server.
+func (n *NATS) awaitPong(conn *natsConn) (bool, error) {
+	for {
+		line, err := conn.readLine()
+		if err != nil {
+			return true, err
+		}
+		switch {
+		case line == "PONG":
+			return false, nil
+		case line == "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return true, err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return natsRetry(line)
+		}
+	}
 }
 
-func LoginAuth(username, password string) smtp.Auth {
-	return &loginAuth{username, password}
+// awaitAck waits for the JetStream acknowledgement of a publish.
+func (n *NATS) awaitAck(conn *natsConn) (bool, error) {
+	for {
+		line, err := conn.readLine()
+		if err != nil {
+			return true, err
+		}
+		switch {
+		case line == "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return true, err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return natsRetry(line)
+		case strings.HasPrefix(line, "MSG "), strings.HasPrefix(line, "HMSG "):
+			args := strings.Fields(line)
+			size, err := strconv.Atoi(args[len(args)-1])
+			if err != nil {
+				return false, fmt.Errorf("malformed NATS message %q", line)
+			}
+			body := make([]byte, size+2)
+			if _, err := io.ReadFull(conn.r, body); err != nil {
+				return true, err
+			}
+			body = body[:size]
+			if args[0] == "HMSG" {
+				hdrSize, err := strconv.Atoi(args[len(args)-2])
+				if err != nil || hdrSize > size {
+					return false, fmt.Errorf("malformed NATS message %q", line)
+				}
+				// A status header without payload is only sent when no
+				// stream is listening on the subject.
+				if status := strings.Fields(string(body[:hdrSize])); len(status) > 1 && status[1] == "503" {
+					return true, fmt.Errorf("no JetStream stream available for subject")
+				}
+				body = body[hdrSize:]
+			}
+			var ack natsPubAck
+			if err := json.Unmarshal(body, &ack); err != nil {
+				return false, fmt.Errorf("unexpected JetStream response %q", body)
+			}
+			if ack.Error != nil {
+				return ack.Error.Code/100 == 5, fmt.Errorf("JetStream error %d: %s", ack.Error.Code, ack.Error.Description)
+			}
+			level.Debug(n.logger).Log("msg", "Message acknowledged by JetStream", "stream", ack.Stream, "seq", ack.Seq)
+			return false, nil
+		}
+	}
 }
 
-func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
-	return "LOGIN", []byte{}, nil
+func natsRetry(line string) (bool, error) {
+	msg := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+	// Authentication and permission problems won't resolve by retrying.
+	lmsg := strings.ToLower(msg)
+	if strings.Contains(lmsg, "authorization") || strings.Contains(lmsg, "permissions") {
+		return false, fmt.Errorf("NATS error: %s", msg)
+	}
+	return true, fmt.Errorf("NATS error: %s", msg)
 }
 
-// Used for AUTH LOGIN. (Maybe password should be encrypted)
-func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
-	if more {
-		switch strings.ToLower(string(fromServer)) {
-		case "username:":
-			return []byte(a.username), nil
-		case "password:":
-			return []byte(a.password), nil
-		default:
-			return nil, errors.New("unexpected server challenge")
-		}
+type natsConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *natsConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
 	}
-	return nil, nil
+	return strings.TrimRight(line, "\r\n"), nil
 }
 
-// hashKey returns the sha256 for a group key as integrations may have
-// maximum length requirements on deduplication keys.
-func hashKey(s string) string {
-	h := sha256.New()
-	h.Write([]byte(s))
+// MQTT implements a Notifier for publishing to MQTT brokers.
+type MQTT struct {
+	conf   *config.MQTTConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewMQTT returns a new MQTT notifier.
+func NewMQTT(c *config.MQTTConfig, t *template.Template, l log.Logger) *MQTT {
+	return &MQTT{conf: c, tmpl: t, logger: l}
+}
+
+// MQTT 3.1.1 control packet types.
+const (
+	mqttConnect    = 1
+	mqttConnAck    = 2
+	mqttPublish    = 3
+	mqttPubAck     = 4
+	mqttPubRec     = 5
+	mqttPubRel     = 6
+	mqttPubComp    = 7
+	mqttDisconnect = 14
+)
+
+// Notify implements the Notifier interface.
+func (n *MQTT) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	topic := tmplText(n.tmpl, data, &err)(n.conf.Topic)
+	if err != nil {
+		return false, err
+	}
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		return false, fmt.Errorf("invalid MQTT topic %q", topic)
+	}
+
+	msg := &WebhookMessage{
+		Version:  "4",
+		Data:     data,
+		GroupKey: key,
+	}
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return false, err
+	}
+
+	level.Debug(n.logger).Log("msg", "Publishing MQTT message", "incident", key, "topic", topic)
+
+	conn, err := n.connect(ctx, key)
+	if err != nil {
+		return true, err
+	}
+	defer conn.Close()
+
+	if retry, err := n.connAck(conn); err != nil {
+		return retry, err
+	}
+
+	var (
+		flags    = byte(n.conf.QoS << 1)
+		packetID = uint16(1)
+		body     = mqttString(topic)
+	)
+	if n.conf.Retain {
+		flags |= 0x01
+	}
+	if n.conf.QoS > 0 {
+		body = append(body, byte(packetID>>8), byte(packetID))
+	}
+	body = append(body, payload...)
+	if err := mqttWrite(conn, mqttPublish<<4|flags, body); err != nil {
+		return true, err
+	}
+
+	switch n.conf.QoS {
+	case 1:
+		if err := mqttExpect(conn, mqttPubAck, packetID); err != nil {
+			return true, err
+		}
+	case 2:
+		if err := mqttExpect(conn, mqttPubRec, packetID); err != nil {
+			return true, err
+		}
+		if err := mqttWrite(conn, mqttPubRel<<4|0x02, []byte{byte(packetID >> 8), byte(packetID)}); err != nil {
+			return true, err
+		}
+		if err := mqttExpect(conn, mqttPubComp, packetID); err != nil {
+			return true, err
+		}
+	}
+
+	// A failed disconnect doesn't affect the delivery of the message.
+	mqttWrite(conn, mqttDisconnect<<4, nil)
+	return false, nil
+}
+
+func (n *MQTT) connect(ctx context.Context, key string) (net.Conn, error) {
+	u, err := url.Parse(n.conf.URL)
+	if err != nil {
+		return nil, err
+	}
+	useTLS := u.Scheme == "ssl" || u.Scheme == "tls" || u.Scheme == "mqtts"
+	host := u.Host
+	if u.Port() == "" {
+		port := "1883"
+		if useTLS {
+			port = "8883"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if useTLS || n.conf.TLS != nil {
+		tlsCfg := n.conf.TLS
+		if tlsCfg == nil {
+			tlsCfg = &config.TLSConfig{}
+		}
+		tc, err := newTLSConfig(tlsCfg)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if tc.ServerName == "" {
+			tc.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(conn, tc)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	clientID := n.conf.ClientID
+	if clientID == "" {
+		// Client identifiers must be unique per broker, connections are
+		// short-lived so derive one from the group key and the current time.
+		clientID = "alertmanager-" + hashKey(fmt.Sprintf("%s/%d", key, time.Now().UnixNano()))[:16]
+	}
+
+	// Protocol name, level 4 (3.1.1), connect flags and a 60s keep alive.
+	flags := byte(0x02) // clean session
+	if n.conf.Username != "" {
+		flags |= 0x80
+	}
+	if n.conf.Password != "" {
+		flags |= 0x40
+	}
+	body := append(mqttString("MQTT"), 4, flags, 0, 60)
+	body = append(body, mqttString(clientID)...)
+	if n.conf.Username != "" {
+		body = append(body, mqttString(n.conf.Username)...)
+	}
+	if n.conf.Password != "" {
+		body = append(body, mqttString(string(n.conf.Password))...)
+	}
+	if err := mqttWrite(conn, mqttConnect<<4, body); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (n *MQTT) connAck(conn net.Conn) (bool, error) {
+	typ, body, err := mqttRead(conn)
+	if err != nil {
+		return true, err
+	}
+	if typ != mqttConnAck || len(body) != 2 {
+		return false, fmt.Errorf("unexpected MQTT packet type %d, expected CONNACK", typ)
+	}
+	switch rc := body[1]; rc {
+	case 0:
+		return false, nil
+	case 1, 2, 4, 5:
+		// Unacceptable protocol version, rejected identifier, bad
+		// credentials or not authorized won't be resolved by retrying.
+		return false, fmt.Errorf("MQTT connection refused with return code %d", rc)
+	default:
+		return true, fmt.Errorf("MQTT connection refused with return code %d", rc)
+	}
+}
+
+// mqttString encodes s as a length-prefixed UTF-8 string.
+func mqttString(s string) []byte {
+	b := make([]byte, 2, 2+len(s))
+	b[0], b[1] = byte(len(s)>>8), byte(len(s))
+	return append(b, s...)
+}
+
+func mqttWrite(w io.Writer, header byte, body []byte) error {
+	pkt := []byte{header}
+	// Variable length encoding of the remaining length.
+	l := len(body)
+	for {
+		b := byte(l % 128)
+		l /= 128
+		if l > 0 {
+			b |= 0x80
+		}
+		pkt = append(pkt, b)
+		if l == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(pkt, body...))
+	return err
+}
+
+func mqttRead(r io.Reader) (byte, []byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, nil, err
+	}
+	typ := b[0] >> 4
+
+	l, mult := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, fmt.Errorf("malformed MQTT remaining length")
+		}
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, nil, err
+		}
+		l += int(b[0]&0x7f) * mult
+		mult *= 128
+		if b[0]&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, l)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return typ, body, nil
+}
+
+// mqttExpect reads the next packet and checks that it is of the given type
+// and acknowledges the given packet identifier.
+func mqttExpect(r io.Reader, typ byte, packetID uint16) error {
+	t, body, err := mqttRead(r)
+	if err != nil {
+		return err
+	}
+	if t != typ || len(body) != 2 {
+		return fmt.Errorf("unexpected MQTT packet type %d, expected %d", t, typ)
+	}
+	if id := uint16(body[0])<<8 | uint16(body[1]); id != packetID {
+		return fmt.Errorf("unexpected MQTT packet identifier %d, expected %d", id, packetID)
+	}
+	return nil
+}
+
+// Twilio implements a Notifier for SMS notifications via Twilio.
+type Twilio struct {
+	conf   *config.TwilioConfig
+	tmpl   *template.Template
+	logger log.Logger
+
+	// The recipients the current notification of each group was sent to,
+	// so that retries only resend it to the failed recipients.
+	mtx  sync.Mutex
+	sent map[string]*twilioSent
+}
+
+type twilioSent struct {
+	now time.Time
+	to  map[string]bool
+}
+
+// NewTwilio returns a new Twilio notifier.
+func NewTwilio(c *config.TwilioConfig, t *template.Template, l log.Logger) *Twilio {
+	return &Twilio{conf: c, tmpl: t, logger: l, sent: map[string]*twilioSent{}}
+}
+
+// sentTo returns the recipients the notification of the group at the given
+// time was already sent to.
+func (n *Twilio) sentTo(key string, now time.Time) *twilioSent {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	s, ok := n.sent[key]
+	if !ok || !s.now.Equal(now) {
+		s = &twilioSent{now: now, to: map[string]bool{}}
+		n.sent[key] = s
+	}
+	return s
+}
+
+type twilioErrorResponse struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"more_info"`
+}
+
+// Notify implements the Notifier interface.
+func (n *Twilio) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	message := tmplText(n.tmpl, data, &err)(n.conf.Message)
+	if err != nil {
+		return false, err
+	}
+	// Twilio rejects message bodies longer than 1600 characters.
+	if len(message) > 1600 {
+		message = message[:1597] + "..."
+		level.Debug(n.logger).Log("msg", "Truncated message due to Twilio message limit", "truncated_message", message, "incident", key)
+	}
+
+	authToken, err := twilioAuthToken(n.conf.AuthToken, n.conf.AuthTokenFile)
+	if err != nil {
+		return false, err
+	}
+
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
+	if err != nil {
+		return false, err
+	}
+
+	apiURL := fmt.Sprintf("%sAccounts/%s/Messages.json", n.conf.APIURL, url.PathEscape(n.conf.AccountSID))
+
+	now, ok := Now(ctx)
+	if !ok {
+		now = time.Now()
+	}
+	sent := n.sentTo(key, now)
+
+	var (
+		retry bool
+		errs  []string
+	)
+	for _, to := range n.conf.To {
+		n.mtx.Lock()
+		done := sent.to[to]
+		n.mtx.Unlock()
+		if done {
+			continue
+		}
+		parameters := url.Values{}
+		parameters.Add("To", to)
+		if n.conf.MessagingServiceSID != "" {
+			parameters.Add("MessagingServiceSid", n.conf.MessagingServiceSID)
+		} else {
+			parameters.Add("From", n.conf.From)
+		}
+		parameters.Add("Body", message)
+
+		level.Debug(n.logger).Log("msg", "Sending Twilio message", "incident", key, "to", to)
+
+		req, err := http.NewRequest("POST", apiURL, strings.NewReader(parameters.Encode()))
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", userAgentHeader)
+		req.SetBasicAuth(n.conf.AccountSID, authToken)
+
+		resp, err := ctxhttp.Do(ctx, c, req)
+		if err != nil {
+			retry = true
+			errs = append(errs, fmt.Sprintf("%s: %s", to, err))
+			continue
+		}
+		r, err := n.retry(resp)
+		resp.Body.Close()
+		if err != nil {
+			retry = retry || r
+			errs = append(errs, fmt.Sprintf("%s: %s", to, err))
+			continue
+		}
+		n.mtx.Lock()
+		sent.to[to] = true
+		n.mtx.Unlock()
+	}
+	// Forget the recipients once the notification will not be retried.
+	if len(errs) == 0 || !retry {
+		n.mtx.Lock()
+		if n.sent[key] == sent {
+			delete(n.sent, key)
+		}
+		n.mtx.Unlock()
+	}
+	if len(errs) > 0 {
+		return retry, fmt.Errorf("failed to send SMS: %s", strings.Join(errs, "; "))
+	}
+	return false, nil
+}
+
+func (n *Twilio) retry(resp *http.Response) (bool, error) {
+	return twilioRetry(resp)
+}
+
+// twilioAuthToken returns the inline auth token or reads it from the given file.
+func twilioAuthToken(token config.Secret, file string) (string, error) {
+	if file == "" {
+		return string(token), nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read auth token file %s: %s", file, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+func twilioRetry(resp *http.Response) (bool, error) {
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+
+	var twErr twilioErrorResponse
+	// The error body is informational only.
+	json.NewDecoder(resp.Body).Decode(&twErr)
+	err := fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	if twErr.Code != 0 {
+		err = fmt.Errorf("unexpected status code %v: Twilio error %d: %s", resp.StatusCode, twErr.Code, twErr.Message)
+	}
+
+	// Rate limiting (429 / error 20429) and server side errors are
+	// recoverable, all other errors indicate a configuration problem such as
+	// invalid or unsubscribed numbers.
+	// https://www.twilio.com/docs/api/errors
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 || twErr.Code == 20429 {
+		return true, err
+	}
+	return false, err
+}
+
+// Voice implements a Notifier placing phone calls that have to be
+// acknowledged by the callee.
+type Voice struct {
+	conf   *config.VoiceConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewVoice returns a new Voice notifier.
+func NewVoice(c *config.VoiceConfig, t *template.Template, l log.Logger) *Voice {
+	return &Voice{conf: c, tmpl: t, logger: l}
+}
+
+// voiceAckDigit is the key the callee has to press to acknowledge a call.
+const voiceAckDigit = "1"
+
+type voiceWebhookMessage struct {
+	CallID      string `json:"callId"`
+	To          string `json:"to"`
+	Message     string `json:"message"`
+	AckDigit    string `json:"ackDigit"`
+	CallbackURL string `json:"callbackUrl"`
+	GroupKey    string `json:"groupKey"`
+}
+
+// Notify implements the Notifier interface.
+func (n *Voice) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	message := tmplText(n.tmpl, data, &err)(n.conf.Message)
+	if err != nil {
+		return false, err
+	}
+
+	callbackURL := n.conf.CallbackURL
+	if callbackURL == "" {
+		if n.tmpl.ExternalURL == nil {
+			return false, fmt.Errorf("no callback URL configured")
+		}
+		callbackURL = strings.TrimSuffix(n.tmpl.ExternalURL.String(), "/") + "/api/v1/voice/callback"
+	}
+
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
+	if err != nil {
+		return false, err
+	}
+
+	receiver, _ := ReceiverName(ctx)
+
+	// Call the numbers in order until one acknowledges.
+	for _, to := range n.conf.To {
+		id := newVoiceCallID()
+		level.Debug(n.logger).Log("msg", "Placing voice call", "incident", key, "to", to, "call", id)
+
+		u, err := url.Parse(callbackURL)
+		if err != nil {
+			return false, err
+		}
+		q := u.Query()
+		q.Set("call", id)
+		u.RawQuery = q.Encode()
+
+		acks := voiceCalls.register(&voiceCall{
+			ID:          id,
+			GroupKey:    key,
+			Receiver:    receiver,
+			Provider:    n.conf.Provider,
+			CallbackURL: u.String(),
+		}, time.Duration(n.conf.AckTimeout))
+
+		var retry bool
+		if n.conf.Provider == "webhook" {
+			retry, err = n.callWebhook(ctx, c, key, id, to, message, u.String())
+		} else {
+			retry, err = n.callTwilio(ctx, c, to, message, u.String())
+		}
+		if err != nil {
+			voiceCalls.unregister(id)
+			if !retry {
+				return false, err
+			}
+			level.Warn(n.logger).Log("msg", "Placing voice call failed", "incident", key, "to", to, "err", err)
+			continue
+		}
+
+		acked := n.awaitAck(ctx, acks)
+		voiceCalls.unregister(id)
+		if acked {
+			level.Info(n.logger).Log("msg", "Voice call acknowledged", "incident", key, "to", to)
+			return false, nil
+		}
+		if ctx.Err() != nil {
+			return true, ctx.Err()
+		}
+	}
+	return true, fmt.Errorf("voice call not acknowledged by any of %d recipients", len(n.conf.To))
+}
+
+func (n *Voice) awaitAck(ctx context.Context, acks <-chan string) bool {
+	timer := time.NewTimer(time.Duration(n.conf.AckTimeout))
+	defer timer.Stop()
+
+	for {
+		select {
+		case digits := <-acks:
+			if digits == voiceAckDigit {
+				return true
+			}
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (n *Voice) callTwilio(ctx context.Context, c *http.Client, to, message, callbackURL string) (bool, error) {
+	authToken, err := twilioAuthToken(n.conf.AuthToken, n.conf.AuthTokenFile)
+	if err != nil {
+		return false, err
+	}
+
+	var twiml bytes.Buffer
+	fmt.Fprintf(&twiml, `<Response><Gather numDigits="1" timeout="10" action="`)
+	xml.EscapeText(&twiml, []byte(callbackURL))
+	twiml.WriteString(`"><Say>`)
+	xml.EscapeText(&twiml, []byte(message))
+	fmt.Fprintf(&twiml, `</Say><Say>Press %s to acknowledge.</Say></Gather><Say>No acknowledgement received. Goodbye.</Say></Response>`, voiceAckDigit)
+
+	parameters := url.Values{}
+	parameters.Add("To", to)
+	parameters.Add("From", n.conf.From)
+	parameters.Add("Twiml", twiml.String())
+
+	apiURL := fmt.Sprintf("%sAccounts/%s/Calls.json", n.conf.APIURL, url.PathEscape(n.conf.AccountSID))
+	req, err := http.NewRequest("POST", apiURL, strings.NewReader(parameters.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgentHeader)
+	req.SetBasicAuth(n.conf.AccountSID, authToken)
+
+	resp, err := ctxhttp.Do(ctx, c, req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	return twilioRetry(resp)
+}
+
+func (n *Voice) callWebhook(ctx context.Context, c *http.Client, key, id, to, message, callbackURL string) (bool, error) {
+	msg := &voiceWebhookMessage{
+		CallID:      id,
+		To:          to,
+		Message:     message,
+		AckDigit:    voiceAckDigit,
+		CallbackURL: callbackURL,
+		GroupKey:    key,
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest("POST", n.conf.URL, &buf)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("User-Agent", userAgentHeader)
+
+	resp, err := ctxhttp.Do(ctx, c, req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	return n.retry(resp.StatusCode)
+}
+
+func (n *Voice) retry(statusCode int) (bool, error) {
+	// Call gateways are expected to return 2xx on success and 5xx on
+	// temporary failures.
+	if statusCode/100 != 2 {
+		return (statusCode/100 == 5), fmt.Errorf("unexpected status code %v from %s", statusCode, n.conf.URL)
+	}
+	return false, nil
+}
+
+// Jira implements a Notifier for Jira issues.
+type Jira struct {
+	conf   *config.JiraConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewJira returns a new Jira notifier.
+func NewJira(c *config.JiraConfig, t *template.Template, l log.Logger) *Jira {
+	return &Jira{conf: c, tmpl: t, logger: l}
+}
+
+type jiraIssue struct {
+	Key    string `json:"key,omitempty"`
+	Fields struct {
+		Resolution *struct {
+			Name string `json:"name"`
+		} `json:"resolution"`
+	} `json:"fields"`
+}
+
+type jiraSearchResult struct {
+	Issues []jiraIssue `json:"issues"`
+}
+
+type jiraTransitions struct {
+	Transitions []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"transitions"`
+}
+
+// Notify implements the Notifier interface.
+func (n *Jira) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	fields := map[string]interface{}{
+		"summary":     tmpl(n.conf.Summary),
+		"description": tmpl(n.conf.Description),
+	}
+	if s := fields["summary"].(string); len(s) > 255 {
+		fields["summary"] = s[:252] + "..."
+		level.Debug(n.logger).Log("msg", "Truncated summary due to Jira summary limit", "incident", key)
+	}
+	// The label identifies the issue of the alert group.
+	groupLabel := "ALERT-" + hashKey(key)[:32]
+	labels := []string{groupLabel}
+	for _, l := range n.conf.Labels {
+		labels = append(labels, strings.Replace(tmpl(l), " ", "_", -1))
+	}
+	priority := tmpl(n.conf.Priority)
+	resolvedComment := tmpl(n.conf.ResolvedComment)
+	if err != nil {
+		return false, err
+	}
+
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
+	if err != nil {
+		return false, err
+	}
+
+	issue, retry, err := n.search(ctx, c, groupLabel)
+	if err != nil {
+		return retry, err
+	}
+
+	if types.Alerts(as...).Status() == model.AlertResolved {
+		if issue == nil || issue.Fields.Resolution != nil {
+			return false, nil
+		}
+		level.Debug(n.logger).Log("msg", "Resolving Jira issue", "incident", key, "issue", issue.Key)
+		if resolvedComment != "" {
+			if retry, err := n.do(ctx, c, "POST", "issue/"+issue.Key+"/comment", map[string]string{"body": resolvedComment}, nil); err != nil {
+				return retry, err
+			}
+		}
+		if n.conf.ResolveTransition != "" {
+			return n.transition(ctx, c, issue.Key, n.conf.ResolveTransition)
+		}
+		return false, nil
+	}
+
+	if issue == nil {
+		fields["project"] = map[string]string{"key": n.conf.Project}
+		fields["issuetype"] = map[string]string{"name": n.conf.IssueType}
+		fields["labels"] = labels
+		if priority != "" {
+			fields["priority"] = map[string]string{"name": priority}
+		}
+		var created jiraIssue
+		retry, err := n.do(ctx, c, "POST", "issue", map[string]interface{}{"fields": fields}, &created)
+		if err != nil {
+			return retry, err
+		}
+		level.Debug(n.logger).Log("msg", "Created Jira issue", "incident", key, "issue", created.Key)
+		return false, nil
+	}
+
+	if issue.Fields.Resolution != nil && n.conf.ReopenTransition != "" {
+		level.Debug(n.logger).Log("msg", "Reopening Jira issue", "incident", key, "issue", issue.Key)
+		if retry, err := n.transition(ctx, c, issue.Key, n.conf.ReopenTransition); err != nil {
+			return retry, err
+		}
+	}
+	level.Debug(n.logger).Log("msg", "Updating Jira issue", "incident", key, "issue", issue.Key)
+	return n.do(ctx, c, "PUT", "issue/"+issue.Key, map[string]interface{}{"fields": fields}, nil)
+}
+
+// search returns the most recent issue carrying the group label or nil if
+// there is none.
+func (n *Jira) search(ctx context.Context, c *http.Client, groupLabel string) (*jiraIssue, bool, error) {
+	jql := fmt.Sprintf(`project = %q AND labels = %q ORDER BY created DESC`, n.conf.Project, groupLabel)
+	query := url.Values{}
+	query.Set("jql", jql)
+	query.Set("fields", "resolution")
+	query.Set("maxResults", "1")
+
+	var res jiraSearchResult
+	if retry, err := n.do(ctx, c, "GET", "search?"+query.Encode(), nil, &res); err != nil {
+		return nil, retry, err
+	}
+	if len(res.Issues) == 0 {
+		return nil, false, nil
+	}
+	return &res.Issues[0], false, nil
+}
+
+func (n *Jira) transition(ctx context.Context, c *http.Client, issueKey, name string) (bool, error) {
+	var ts jiraTransitions
+	if retry, err := n.do(ctx, c, "GET", "issue/"+issueKey+"/transitions", nil, &ts); err != nil {
+		return retry, err
+	}
+	for _, t := range ts.Transitions {
+		if strings.EqualFold(t.Name, name) {
+			body := map[string]interface{}{"transition": map[string]string{"id": t.ID}}
+			return n.do(ctx, c, "POST", "issue/"+issueKey+"/transitions", body, nil)
+		}
+	}
+	return false, fmt.Errorf("transition %q not available for Jira issue %s", name, issueKey)
+}
+
+// do sends a request to the Jira REST API and decodes the response into out
+// if it is not nil.
+func (n *Jira) do(ctx context.Context, c *http.Client, method, path string, in, out interface{}) (bool, error) {
+	var body io.Reader
+	if in != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(in); err != nil {
+			return false, err
+		}
+		body = &buf
+	}
+	req, err := http.NewRequest(method, n.conf.APIURL+"rest/api/2/"+path, body)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Accept", contentTypeJSON)
+	req.Header.Set("User-Agent", userAgentHeader)
+	if n.conf.PersonalAccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+string(n.conf.PersonalAccessToken))
+	} else if n.conf.Username != "" {
+		req.SetBasicAuth(n.conf.Username, string(n.conf.Password))
+	}
+
+	resp, err := ctxhttp.Do(ctx, c, req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if retry, err := n.retry(resp.StatusCode); err != nil {
+		return retry, err
+	}
+	if out != nil {
+		return false, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return false, nil
+}
+
+func (n *Jira) retry(statusCode int) (bool, error) {
+	// Rate limited requests and server errors are recoverable.
+	// https://developer.atlassian.com/cloud/jira/platform/rate-limiting/
+	if statusCode/100 == 5 || statusCode == http.StatusTooManyRequests {
+		return true, fmt.Errorf("unexpected status code %v", statusCode)
+	} else if statusCode/100 != 2 {
+		return false, fmt.Errorf("unexpected status code %v", statusCode)
+	}
+	return false, nil
+}
+
+// ServiceNow implements a Notifier for ServiceNow incidents.
+type ServiceNow struct {
+	conf   *config.ServiceNowConfig
+	tmpl   *template.Template
+	logger log.Logger
+
+	mtx         sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewServiceNow returns a new ServiceNow notifier.
+func NewServiceNow(c *config.ServiceNowConfig, t *template.Template, l log.Logger) *ServiceNow {
+	return &ServiceNow{conf: c, tmpl: t, logger: l}
+}
+
+type serviceNowRecord struct {
+	SysID string `json:"sys_id"`
+}
+
+type serviceNowToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Notify implements the Notifier interface.
+func (n *ServiceNow) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	correlationID := hashKey(key)
+	fields := map[string]string{
+		"correlation_id":    correlationID,
+		"short_description": tmpl(n.conf.ShortDescription),
+		"description":       tmpl(n.conf.Description),
+		"impact":            tmpl(n.conf.Impact),
+		"urgency":           tmpl(n.conf.Urgency),
+	}
+	if n.conf.AssignmentGroup != "" {
+		fields["assignment_group"] = tmpl(n.conf.AssignmentGroup)
+	}
+	if n.conf.Category != "" {
+		fields["category"] = tmpl(n.conf.Category)
+	}
+	if n.conf.CallerID != "" {
+		fields["caller_id"] = tmpl(n.conf.CallerID)
+	}
+	for k, v := range n.conf.Fields {
+		fields[k] = tmpl(v)
+	}
+	closeNotes := tmpl(n.conf.CloseNotes)
+	if err != nil {
+		return false, err
+	}
+
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
+	if err != nil {
+		return false, err
+	}
+
+	query := url.Values{}
+	query.Set("sysparm_query", "active=true^correlation_id="+correlationID)
+	query.Set("sysparm_fields", "sys_id")
+	query.Set("sysparm_limit", "1")
+	var found struct {
+		Result []serviceNowRecord `json:"result"`
+	}
+	if retry, err := n.do(ctx, c, "GET", "?"+query.Encode(), nil, &found); err != nil {
+		return retry, err
+	}
+
+	if types.Alerts(as...).Status() == model.AlertResolved {
+		if len(found.Result) == 0 {
+			return false, nil
+		}
+		level.Debug(n.logger).Log("msg", "Resolving ServiceNow incident", "incident", key, "sys_id", found.Result[0].SysID)
+		body := map[string]string{
+			"state":       n.conf.ResolveState,
+			"close_code":  n.conf.CloseCode,
+			"close_notes": closeNotes,
+		}
+		return n.do(ctx, c, "PATCH", "/"+found.Result[0].SysID, body, nil)
+	}
+
+	if len(found.Result) == 0 {
+		level.Debug(n.logger).Log("msg", "Creating ServiceNow incident", "incident", key)
+		return n.do(ctx, c, "POST", "", fields, nil)
+	}
+	level.Debug(n.logger).Log("msg", "Updating ServiceNow incident", "incident", key, "sys_id", found.Result[0].SysID)
+	return n.do(ctx, c, "PATCH", "/"+found.Result[0].SysID, fields, nil)
+}
+
+// do sends a request to the table API. The path is appended to the table URL.
+func (n *ServiceNow) do(ctx context.Context, c *http.Client, method, path string, in, out interface{}) (bool, error) {
+	var body io.Reader
+	if in != nil {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(in); err != nil {
+			return false, err
+		}
+		body = &buf
+	}
+	req, err := http.NewRequest(method, n.conf.InstanceURL+"api/now/table/"+n.conf.Table+path, body)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("Accept", contentTypeJSON)
+	req.Header.Set("User-Agent", userAgentHeader)
+
+	if n.conf.ClientID != "" {
+		token, err := n.oauthToken(ctx, c)
+		if err != nil {
+			return true, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.SetBasicAuth(n.conf.Username, string(n.conf.Password))
+	}
+
+	resp, err := ctxhttp.Do(ctx, c, req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized && n.conf.ClientID != "" {
+		// Force a new token on the next attempt.
+		n.mtx.Lock()
+		n.token = ""
+		n.mtx.Unlock()
+		return true, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	if retry, err := n.retry(resp.StatusCode); err != nil {
+		return retry, err
+	}
+	if out != nil {
+		return false, json.NewDecoder(resp.Body).Decode(out)
+	}
+	return false, nil
+}
+
+// oauthToken returns a cached OAuth access token or requests a new one.
+func (n *ServiceNow) oauthToken(ctx context.Context, c *http.Client) (string, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.token != "" && time.Now().Before(n.tokenExpiry) {
+		return n.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("client_id", n.conf.ClientID)
+	form.Set("client_secret", string(n.conf.ClientSecret))
+	if n.conf.Username != "" {
+		form.Set("grant_type", "password")
+		form.Set("username", n.conf.Username)
+		form.Set("password", string(n.conf.Password))
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	resp, err := ctxhttp.PostForm(ctx, c, n.conf.InstanceURL+"oauth_token.do", form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("unexpected status code %v requesting OAuth token", resp.StatusCode)
+	}
+	var tok serviceNowToken
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("no access token in OAuth response")
+	}
+	n.token = tok.AccessToken
+	// Refresh the token a bit before it actually expires.
+	n.tokenExpiry = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - 30*time.Second)
+
+	return n.token, nil
+}
+
+func (n *ServiceNow) retry(statusCode int) (bool, error) {
+	// Rate limited requests and server errors are recoverable.
+	if statusCode/100 == 5 || statusCode == http.StatusTooManyRequests {
+		return true, fmt.Errorf("unexpected status code %v", statusCode)
+	} else if statusCode/100 != 2 {
+		return false, fmt.Errorf("unexpected status code %v", statusCode)
+	}
+	return false, nil
+}
+
+// Splunk implements a Notifier for the Splunk HTTP Event Collector.
+type Splunk struct {
+	conf   *config.SplunkConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewSplunk returns a new Splunk notifier.
+func NewSplunk(c *config.SplunkConfig, t *template.Template, l log.Logger) *Splunk {
+	return &Splunk{conf: c, tmpl: t, logger: l}
+}
+
+type splunkEvent struct {
+	Time       float64           `json:"time"`
+	Host       string            `json:"host,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	SourceType string            `json:"sourcetype,omitempty"`
+	Index      string            `json:"index,omitempty"`
+	Event      *WebhookMessage   `json:"event"`
+	Fields     map[string]string `json:"fields,omitempty"`
+}
+
+// Notify implements the Notifier interface.
+func (n *Splunk) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	now, ok := Now(ctx)
+	if !ok {
+		now = time.Now()
+	}
+	event := &splunkEvent{
+		Time:       float64(now.UnixNano()) / 1e9,
+		Host:       tmpl(n.conf.Host),
+		Source:     tmpl(n.conf.Source),
+		SourceType: tmpl(n.conf.SourceType),
+		Index:      tmpl(n.conf.Index),
+		Event: &WebhookMessage{
+			Version:  "4",
+			Data:     data,
+			GroupKey: key,
+		},
+	}
+	if len(n.conf.Fields) > 0 {
+		event.Fields = make(map[string]string, len(n.conf.Fields))
+		for k, v := range n.conf.Fields {
+			event.Fields[k] = tmpl(v)
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(event); err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest("POST", n.conf.URL, &buf)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("User-Agent", userAgentHeader)
+	req.Header.Set("Authorization", "Splunk "+string(n.conf.Token))
+
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
+	if err != nil {
+		return false, err
+	}
+
+	level.Debug(n.logger).Log("msg", "Sending Splunk event", "incident", key)
+
+	resp, err := ctxhttp.Do(ctx, c, req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	return n.retry(resp.StatusCode)
+}
+
+func (n *Splunk) retry(statusCode int) (bool, error) {
+	// The collector answers 503 when its queues are full and 429 when
+	// rate limited, both are recoverable like other server errors.
+	// https://docs.splunk.com/Documentation/Splunk/latest/Data/TroubleshootHTTPEventCollector
+	if statusCode/100 == 5 || statusCode == http.StatusTooManyRequests {
+		return true, fmt.Errorf("unexpected status code %v", statusCode)
+	} else if statusCode/100 != 2 {
+		return false, fmt.Errorf("unexpected status code %v", statusCode)
+	}
+	return false, nil
+}
+
+// PubSub implements a Notifier for Google Cloud Pub/Sub topics.
+type PubSub struct {
+	conf   *config.PubSubConfig
+	tmpl   *template.Template
+	logger log.Logger
+
+	mtx         sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewPubSub returns a new PubSub notifier.
+func NewPubSub(c *config.PubSubConfig, t *template.Template, l log.Logger) *PubSub {
+	return &PubSub{conf: c, tmpl: t, logger: l}
+}
+
+type pubSubMessage struct {
+	Data       []byte            `json:"data"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type pubSubPublishRequest struct {
+	Messages []pubSubMessage `json:"messages"`
+}
+
+// Notify implements the Notifier interface.
+func (n *PubSub) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	attrs := map[string]string{
+		"status":   data.Status,
+		"receiver": data.Receiver,
+	}
+	for k, v := range data.GroupLabels {
+		attrs[k] = v
+	}
+	for k, v := range n.conf.Attributes {
+		attrs[k] = tmpl(v)
+	}
+	topic := tmpl(n.conf.Topic)
+	if err != nil {
+		return false, err
+	}
+
+	payload, err := json.Marshal(&WebhookMessage{
+		Version:  "4",
+		Data:     data,
+		GroupKey: key,
+	})
+	if err != nil {
+		return false, err
+	}
+	var buf bytes.Buffer
+	msg := pubSubPublishRequest{Messages: []pubSubMessage{{Data: payload, Attributes: attrs}}}
+	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+		return false, err
+	}
+
+	c, err := newHTTPClient(ctx, n.conf.HTTPConfig)
+	if err != nil {
+		return false, err
+	}
+	token, err := n.accessToken(ctx, c)
+	if err != nil {
+		return true, err
+	}
+
+	u := fmt.Sprintf("%sv1/projects/%s/topics/%s:publish", n.conf.APIURL, url.PathEscape(n.conf.Project), url.PathEscape(topic))
+	req, err := http.NewRequest("POST", u, &buf)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentTypeJSON)
+	req.Header.Set("User-Agent", userAgentHeader)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	level.Debug(n.logger).Log("msg", "Publishing Pub/Sub message", "incident", key, "topic", topic)
+
+	resp, err := ctxhttp.Do(ctx, c, req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Force a new token on the next attempt.
+		n.mtx.Lock()
+		n.token = ""
+		n.mtx.Unlock()
+		return true, fmt.Errorf("unexpected status code %v", resp.StatusCode)
+	}
+	return n.retry(resp.StatusCode)
+}
+
+func (n *PubSub) retry(statusCode int) (bool, error) {
+	// https://cloud.google.com/pubsub/docs/reference/error-codes
+	switch {
+	case statusCode/100 == 2:
+		return false, nil
+	case statusCode/100 == 5, statusCode == http.StatusTooManyRequests, statusCode == http.StatusRequestTimeout:
+		return true, fmt.Errorf("unexpected status code %v", statusCode)
+	default:
+		return false, fmt.Errorf("unexpected status code %v", statusCode)
+	}
+}
+
+// accessToken returns a cached OAuth access token or requests a new one.
+func (n *PubSub) accessToken(ctx context.Context, c *http.Client) (string, error) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if n.token != "" && time.Now().Before(n.tokenExpiry) {
+		return n.token, nil
+	}
+	token, expiry, err := googleAccessToken(ctx, c, n.conf.CredentialsFile, googlePubSubScope)
+	if err != nil {
+		return "", err
+	}
+	n.token, n.tokenExpiry = token, expiry
+	return token, nil
+}
+
+const (
+	googlePubSubScope   = "https://www.googleapis.com/auth/pubsub"
+	googleTokenURL      = "https://oauth2.googleapis.com/token"
+	googleMetadataToken = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// googleCredentials holds the fields of service account and authorized user
+// credential files.
+type googleCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type googleToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// googleAccessToken obtains an access token from the given credentials file.
+// Without a file, application default credentials are looked up in the
+// GOOGLE_APPLICATION_CREDENTIALS environment variable, the gcloud well-known
+// file and finally the GCE metadata server.
+func googleAccessToken(ctx context.Context, c *http.Client, file, scope string) (string, time.Time, error) {
+	if file == "" {
+		file = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if file == "" {
+		if home := os.Getenv("HOME"); home != "" {
+			wellKnown := filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+			if _, err := os.Stat(wellKnown); err == nil {
+				file = wellKnown
+			}
+		}
+	}
+
+	var (
+		req *http.Request
+		err error
+	)
+	if file == "" {
+		req, err = http.NewRequest("GET", googleMetadataToken, nil)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+	} else {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		var creds googleCredentials
+		if err := json.Unmarshal(b, &creds); err != nil {
+			return "", time.Time{}, fmt.Errorf("unable to parse credentials file %s: %s", file, err)
+		}
+		if creds.TokenURI == "" {
+			creds.TokenURI = googleTokenURL
+		}
+
+		form := url.Values{}
+		switch creds.Type {
+		case "service_account":
+			assertion, err := googleJWT(&creds, scope)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+			form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+			form.Set("assertion", assertion)
+		case "authorized_user":
+			form.Set("grant_type", "refresh_token")
+			form.Set("client_id", creds.ClientID)
+			form.Set("client_secret", creds.ClientSecret)
+			form.Set("refresh_token", creds.RefreshToken)
+		default:
+			return "", time.Time{}, fmt.Errorf("unsupported credentials type %q in %s", creds.Type, file)
+		}
+		req, err = http.NewRequest("POST", creds.TokenURI, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := ctxhttp.Do(ctx, c, req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return "", time.Time{}, fmt.Errorf("unexpected status code %v requesting access token", resp.StatusCode)
+	}
+	var tok googleToken
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", time.Time{}, err
+	}
+	if tok.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("no access token in token response")
+	}
+	// Refresh the token a bit before it actually expires.
+	return tok.AccessToken, time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - 30*time.Second), nil
+}
+
+// googleJWT returns a signed JWT assertion for a service account.
+func googleJWT(creds *googleCredentials, scope string) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("invalid private key in credentials")
+	}
+	var key *rsa.PrivateKey
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		var ok bool
+		if key, ok = k.(*rsa.PrivateKey); !ok {
+			return "", fmt.Errorf("private key in credentials is not an RSA key")
+		}
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("unable to parse private key in credentials: %s", err)
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": scope,
+		"aud":   creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	h := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// tmplData returns the template data of a notification.
+func tmplData(ctx context.Context, tmpl *template.Template, l log.Logger, alerts ...*types.Alert) *template.Data {
+	recv := receiverName(ctx, l)
+	data := tmpl.Data(recv, groupLabels(ctx, l), alerts...)
+	setAcknowledgements(ctx, data, alerts)
+
+	groups, _ := ctx.Value(keyDigestGroups).([]*digestGroup)
+	for _, g := range groups {
+		gd := tmpl.Data(recv, g.labels, g.alerts...)
+		setAcknowledgements(ctx, gd, g.alerts)
+		data.Groups = append(data.Groups, gd)
+	}
+	return data
+}
+
+// setAcknowledgements adds the acknowledgements in the context to the
+// template data of the given alerts.
+func setAcknowledgements(ctx context.Context, data *template.Data, alerts []*types.Alert) {
+	acks := acknowledgements(ctx)
+	if len(acks) == 0 {
+		return
+	}
+	for i, a := range alerts {
+		if e, ok := acks[a.Fingerprint()]; ok {
+			data.Alerts[i].Acknowledgement = &template.Acknowledgement{
+				CreatedBy: e.CreatedBy,
+				Comment:   e.Comment,
+				UpdatedAt: e.UpdatedAt,
+			}
+		}
+	}
+}
+
+func tmplText(tmpl *template.Template, data *template.Data, err *error) func(string) string {
+	return func(name string) (s string) {
+		if *err != nil {
+			return
+		}
+		s, *err = tmpl.ExecuteTextString(name, data)
+		return s
+	}
+}
+
+func tmplHTML(tmpl *template.Template, data *template.Data, err *error) func(string) string {
+	return func(name string) (s string) {
+		if *err != nil {
+			return
+		}
+		s, *err = tmpl.ExecuteHTMLString(name, data)
+		return s
+	}
+}
+
+type loginAuth struct {
+	username, password string
+}
+
+func LoginAuth(username, password string) smtp.Auth {
+	return &loginAuth{username, password}
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "LOGIN", []byte{}, nil
+}
+
+// Used for AUTH LOGIN. (Maybe password should be encrypted)
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		switch strings.ToLower(string(fromServer)) {
+		case "username:":
+			return []byte(a.username), nil
+		case "password:":
+			return []byte(a.password), nil
+		default:
+			return nil, errors.New("unexpected server challenge")
+		}
+	}
+	return nil, nil
+}
+
+// hashKey returns the sha256 for a group key as integrations may have
+// maximum length requirements on deduplication keys.
+func hashKey(s string) string {
+	h := sha256.New()
+	h.Write([]byte(s))
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
+
+var (
+	syslogFacilities = map[string]int{
+		"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+		"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+		"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20,
+		"local5": 21, "local6": 22, "local7": 23,
+	}
+	syslogSeverities = map[string]int{
+		"emerg": 0, "alert": 1, "crit": 2, "err": 3,
+		"warning": 4, "notice": 5, "info": 6, "debug": 7,
+	}
+	sdParamEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+)
+
+// Syslog implements a Notifier sending RFC 5424 syslog messages.
+type Syslog struct {
+	conf   *config.SyslogConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewSyslog returns a new Syslog notifier.
+func NewSyslog(c *config.SyslogConfig, t *template.Template, l log.Logger) *Syslog {
+	return &Syslog{conf: c, tmpl: t, logger: l}
+}
+
+// Notify implements the Notifier interface.
+func (n *Syslog) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	msg, err := n.format(ctx, key, data)
+	if err != nil {
+		return false, err
+	}
+
+	level.Debug(n.logger).Log("msg", "Sending syslog message", "incident", key)
+
+	conn, err := n.dial(ctx)
+	if err != nil {
+		return true, err
+	}
+	defer conn.Close()
+
+	if n.conf.Network != "udp" {
+		// Use octet counting framing for stream transports, RFC 6587.
+		msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// format renders the notification as RFC 5424 message.
+func (n *Syslog) format(ctx context.Context, key string, data *template.Data) ([]byte, error) {
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	var (
+		severity = tmpl(n.conf.Severity)
+		msgID    = tmpl(n.conf.MsgID)
+		message  = tmpl(n.conf.Message)
+	)
+	if err != nil {
+		return nil, err
+	}
+	sev, ok := syslogSeverities[severity]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog severity %q", severity)
+	}
+
+	now, ok := Now(ctx)
+	if !ok {
+		now = time.Now()
+	}
+	hostname := n.conf.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %d %s [%s",
+		syslogFacilities[n.conf.Facility]*8+sev,
+		now.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(n.conf.AppName, 48),
+		os.Getpid(),
+		syslogHeaderField(msgID, 32),
+		n.conf.SDID,
+	)
+	fmt.Fprintf(&buf, ` groupKey="%s"`, sdParamEscaper.Replace(key))
+	for _, p := range data.CommonLabels.SortedPairs() {
+		// Label names are valid parameter names but may be too long.
+		if len(p.Name) > 32 {
+			continue
+		}
+		fmt.Fprintf(&buf, ` %s="%s"`, p.Name, sdParamEscaper.Replace(p.Value))
+	}
+	buf.WriteString("] \xef\xbb\xbf")
+	buf.WriteString(message)
+
+	return buf.Bytes(), nil
+}
+
+// syslogHeaderField returns the value for a header field that must consist
+// of printable ASCII characters and is limited in length.
+func syslogHeaderField(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (n *Syslog) dial(ctx context.Context) (net.Conn, error) {
+	var (
+		d       net.Dialer
+		network = n.conf.Network
+	)
+	if network == "tls" {
+		network = "tcp"
+	}
+	conn, err := d.DialContext(ctx, network, n.conf.Address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if n.conf.Network != "tls" {
+		return conn, nil
+	}
+
+	tlsCfg := n.conf.TLS
+	if tlsCfg == nil {
+		tlsCfg = &config.TLSConfig{}
+	}
+	tc, err := newTLSConfig(tlsCfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if tc.ServerName == "" {
+		host, _, err := net.SplitHostPort(n.conf.Address)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tc.ServerName = host
+	}
+	tlsConn := tls.Client(conn, tc)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
@@ -14,10 +14,29 @@
 package notify
 
 import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -273,3 +292,1148 @@ func TestOpsGenie(t *testing.T) {
 	require.Equal(t, true, retry)
 	require.Equal(t, expectedBody, readBody(t, req))
 }
+
+func TestOpsGenieResponders(t *testing.T) {
+	conf := &config.OpsGenieConfig{
+		Message: `{{ .CommonLabels.alertname }}`,
+		Responders: []*config.OpsGenieResponder{
+			{Name: `{{ .CommonLabels.team }}`, Type: "team"},
+			{Username: `{{ if eq .CommonLabels.severity "critical" }}oncall@example.com{{ end }}`, Type: "user"},
+			{ID: `{{ .CommonLabels.schedule }}`, Type: "schedule"},
+		},
+		Priority: "P3",
+		PriorityRules: []*config.OpsGeniePriorityRule{
+			{Match: map[string]string{"severity": "critical"}, Priority: "P1"},
+			{MatchRE: map[string]config.Regexp{"severity": {Regexp: regexp.MustCompile("^(?:warning)$")}}, Priority: "P2"},
+		},
+		Actions:      "restart,scale",
+		Entity:       `{{ .CommonLabels.instance }}`,
+		LabelDetails: true,
+		Details:      map[string]string{"team": "override"},
+		APIURL:       `https://opsgenie/api`,
+	}
+	notifier := NewOpsGenie(conf, createTmpl(t), log.NewNopLogger())
+	ctx := WithGroupKey(context.Background(), "1")
+
+	for severity, expected := range map[string]string{
+		"critical": `{"alias":"6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b","message":"Down","details":{"alertname":"Down","instance":"db1","severity":"critical","team":"override"},"source":"","priority":"P1","responders":[{"name":"dba","type":"team"},{"username":"oncall@example.com","type":"user"}],"actions":["restart","scale"],"entity":"db1"}
+`,
+		"warning": `{"alias":"6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b","message":"Down","details":{"alertname":"Down","instance":"db1","severity":"warning","team":"override"},"source":"","priority":"P2","responders":[{"name":"dba","type":"team"}],"actions":["restart","scale"],"entity":"db1"}
+`,
+		"info": `{"alias":"6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b","message":"Down","details":{"alertname":"Down","instance":"db1","severity":"info","team":"override"},"source":"","priority":"P3","responders":[{"name":"dba","type":"team"}],"actions":["restart","scale"],"entity":"db1"}
+`,
+	} {
+		alert := &types.Alert{
+			Alert: model.Alert{
+				Labels: model.LabelSet{
+					"alertname": "Down",
+					"instance":  "db1",
+					"severity":  model.LabelValue(severity),
+					"team":      "dba",
+				},
+				StartsAt: time.Now(),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+		req, _, err := notifier.createRequest(ctx, alert)
+		require.NoError(t, err)
+		require.Equal(t, expected, readBody(t, req), severity)
+	}
+}
+
+// fakeNATSServer accepts a single connection, records the published message
+// and answers with the given reply once the message has been received.
+func fakeNATSServer(t *testing.T, headers bool, reply func(w io.Writer, inbox string)) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	published := make(chan string, 1)
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "INFO {\"headers\":%t}\r\n", headers)
+
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args := strings.Fields(line)
+			switch args[0] {
+			case "PUB", "HPUB":
+				size, _ := strconv.Atoi(args[len(args)-1])
+				body := make([]byte, size+2)
+				if _, err := io.ReadFull(r, body); err != nil {
+					return
+				}
+				published <- args[1] + " " + string(body[:size])
+				if args[0] == "HPUB" {
+					reply(conn, args[2])
+				}
+			case "PING":
+				reply(conn, "")
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String(), published
+}
+
+func TestNATS(t *testing.T) {
+	addr, published := fakeNATSServer(t, false, func(w io.Writer, _ string) {
+		fmt.Fprint(w, "PONG\r\n")
+	})
+	notifier := NewNATS(
+		&config.NATSConfig{URL: addr, Subject: `alerts.{{ .CommonLabels.team }}`},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithGroupKey(ctx, "1")
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"team": "infra"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	retry, err := notifier.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	msg := <-published
+	require.True(t, strings.HasPrefix(msg, "alerts.infra {"), msg)
+	require.Contains(t, msg, `"groupKey":"1"`)
+}
+
+func TestNATSJetStream(t *testing.T) {
+	for _, tc := range []struct {
+		ack   string
+		retry bool
+		err   bool
+	}{
+		{ack: `{"stream":"ALERTS","seq":1}`},
+		{ack: `{"error":{"code":503,"description":"unavailable"}}`, retry: true, err: true},
+		{ack: `{"error":{"code":400,"description":"bad request"}}`, retry: false, err: true},
+	} {
+		addr, published := fakeNATSServer(t, true, func(w io.Writer, inbox string) {
+			if inbox != "" {
+				fmt.Fprintf(w, "MSG %s 1 %d\r\n%s\r\n", inbox, len(tc.ack), tc.ack)
+			}
+		})
+		notifier := NewNATS(
+			&config.NATSConfig{URL: addr, Subject: "alerts", JetStream: true},
+			createTmpl(t),
+			log.NewNopLogger(),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx = WithGroupKey(ctx, "1")
+
+		retry, err := notifier.Notify(ctx, &types.Alert{})
+		cancel()
+		require.Equal(t, tc.err, err != nil, fmt.Sprintf("ack %s: %v", tc.ack, err))
+		require.Equal(t, tc.retry, retry, fmt.Sprintf("ack %s", tc.ack))
+
+		msg := <-published
+		require.Contains(t, msg, "Nats-Msg-Id: ")
+	}
+}
+
+// fakeMQTTBroker accepts a single connection, answers the CONNECT with the
+// given return code and acknowledges a publish according to its QoS.
+func fakeMQTTBroker(t *testing.T, returnCode byte) (string, <-chan []byte) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	published := make(chan []byte, 1)
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if typ, _, err := mqttRead(conn); err != nil || typ != mqttConnect {
+			return
+		}
+		mqttWrite(conn, mqttConnAck<<4, []byte{0, returnCode})
+
+		for {
+			b := make([]byte, 1)
+			if _, err := io.ReadFull(conn, b); err != nil {
+				return
+			}
+			typ, body, err := mqttRead(io.MultiReader(bytes.NewReader(b), conn))
+			if err != nil {
+				return
+			}
+			switch typ {
+			case mqttPublish:
+				published <- body
+				switch qos := (b[0] >> 1) & 0x03; qos {
+				case 1, 2:
+					n := int(body[0])<<8 | int(body[1])
+					id := body[2+n : 4+n]
+					if qos == 1 {
+						mqttWrite(conn, mqttPubAck<<4, id)
+					} else {
+						mqttWrite(conn, mqttPubRec<<4, id)
+					}
+				}
+			case mqttPubRel:
+				mqttWrite(conn, mqttPubComp<<4, body)
+			case mqttDisconnect:
+				return
+			}
+		}
+	}()
+	return "tcp://" + ln.Addr().String(), published
+}
+
+func TestMQTT(t *testing.T) {
+	for _, qos := range []int{0, 1, 2} {
+		addr, published := fakeMQTTBroker(t, 0)
+		notifier := NewMQTT(
+			&config.MQTTConfig{URL: addr, Topic: `alerts/{{ .CommonLabels.team }}`, QoS: qos},
+			createTmpl(t),
+			log.NewNopLogger(),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx = WithGroupKey(ctx, "1")
+
+		alert := &types.Alert{
+			Alert: model.Alert{
+				Labels:   model.LabelSet{"team": "edge"},
+				StartsAt: time.Now(),
+				EndsAt:   time.Now().Add(time.Hour),
+			},
+		}
+		retry, err := notifier.Notify(ctx, alert)
+		cancel()
+		require.NoError(t, err, fmt.Sprintf("qos %d", qos))
+		require.False(t, retry)
+
+		body := <-published
+		require.Equal(t, mqttString("alerts/edge"), body[:len("alerts/edge")+2])
+		require.Contains(t, string(body), `"groupKey":"1"`)
+	}
+}
+
+func TestMQTTConnectionRefused(t *testing.T) {
+	for rc, expected := range map[byte]bool{3: true, 4: false, 5: false} {
+		addr, _ := fakeMQTTBroker(t, rc)
+		notifier := NewMQTT(
+			&config.MQTTConfig{URL: addr, Topic: "alerts", QoS: 1},
+			createTmpl(t),
+			log.NewNopLogger(),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ctx = WithGroupKey(ctx, "1")
+
+		retry, err := notifier.Notify(ctx, &types.Alert{})
+		cancel()
+		require.Error(t, err)
+		require.Equal(t, expected, retry, fmt.Sprintf("return code %d", rc))
+	}
+}
+
+func TestTwilioRetry(t *testing.T) {
+	notifier := new(Twilio)
+
+	retryCodes := append(defaultRetryCodes(), http.StatusTooManyRequests)
+	for statusCode, expected := range retryTests(retryCodes) {
+		resp := &http.Response{StatusCode: statusCode, Body: ioutil.NopCloser(strings.NewReader(""))}
+		actual, _ := notifier.retry(resp)
+		require.Equal(t, expected, actual, fmt.Sprintf("error on status %d", statusCode))
+	}
+}
+
+func TestTwilio(t *testing.T) {
+	var received []url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/Accounts/AC123/Messages.json", r.URL.Path)
+		user, pass, _ := r.BasicAuth()
+		require.Equal(t, "AC123", user)
+		require.Equal(t, "s3cr3t", pass)
+		require.NoError(t, r.ParseForm())
+		received = append(received, r.PostForm)
+
+		if r.PostForm.Get("To") == "+14155550000" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	notifier := NewTwilio(
+		&config.TwilioConfig{
+			HTTPConfig: &config.HTTPClientConfig{},
+			APIURL:     srv.URL + "/",
+			AccountSID: "AC123",
+			AuthToken:  "s3cr3t",
+			From:       "+15005550006",
+			To:         []string{"+14155552671", "+14155550000"},
+			Message:    `{{ .CommonLabels.alertname }} is {{ .Status }}`,
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+
+	ctx := WithGroupKey(context.Background(), "1")
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "HighLatency"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	retry, err := notifier.Notify(ctx, alert)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "21211")
+	require.False(t, retry)
+
+	require.Len(t, received, 2)
+	require.Equal(t, "+14155552671", received[0].Get("To"))
+	require.Equal(t, "+15005550006", received[0].Get("From"))
+	require.Equal(t, "HighLatency is firing", received[0].Get("Body"))
+}
+
+func TestTwilioRetryFailedRecipients(t *testing.T) {
+	var (
+		received []string
+		fail     = true
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		to := r.PostForm.Get("To")
+		received = append(received, to)
+		if to == "+14155550000" && fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	notifier := NewTwilio(
+		&config.TwilioConfig{
+			HTTPConfig: &config.HTTPClientConfig{},
+			APIURL:     srv.URL + "/",
+			AccountSID: "AC123",
+			AuthToken:  "s3cr3t",
+			From:       "+15005550006",
+			To:         []string{"+14155552671", "+14155550000"},
+			Message:    "test",
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+
+	now := time.Now()
+	ctx := WithNow(WithGroupKey(context.Background(), "1"), now)
+	retry, err := notifier.Notify(ctx, &types.Alert{})
+	require.Error(t, err)
+	require.True(t, retry)
+	require.Equal(t, []string{"+14155552671", "+14155550000"}, received)
+
+	// The retry is only sent to the recipient that failed.
+	fail = false
+	received = nil
+	retry, err = notifier.Notify(ctx, &types.Alert{})
+	require.NoError(t, err)
+	require.False(t, retry)
+	require.Equal(t, []string{"+14155550000"}, received)
+	require.Len(t, notifier.sent, 0)
+
+	// The next notification of the group is sent to all recipients.
+	received = nil
+	_, err = notifier.Notify(WithNow(ctx, now.Add(time.Minute)), &types.Alert{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"+14155552671", "+14155550000"}, received)
+}
+
+func TestVoiceEscalation(t *testing.T) {
+	var called []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg voiceWebhookMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		called = append(called, msg.To)
+
+		switch msg.To {
+		case "sip:unreachable@example.com":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "sip:oncall@example.com":
+			// Simulate the callee pressing the acknowledgement key.
+			go func() {
+				form := url.Values{"digits": {voiceAckDigit}}
+				req := httptest.NewRequest("POST", msg.CallbackURL, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				rec := httptest.NewRecorder()
+				VoiceCallback(nil)(rec, req)
+				require.Equal(t, http.StatusOK, rec.Code)
+			}()
+		}
+	}))
+	defer srv.Close()
+
+	notifier := NewVoice(
+		&config.VoiceConfig{
+			HTTPConfig:  &config.HTTPClientConfig{},
+			Provider:    "webhook",
+			URL:         srv.URL,
+			To:          []string{"sip:unreachable@example.com", "sip:oncall@example.com", "sip:backup@example.com"},
+			Message:     `{{ .CommonLabels.alertname }}`,
+			CallbackURL: "http://am/api/v1/voice/callback",
+			AckTimeout:  config.DefaultVoiceConfig.AckTimeout,
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithGroupKey(ctx, "1")
+
+	retry, err := notifier.Notify(ctx, &types.Alert{})
+	require.NoError(t, err)
+	require.False(t, retry)
+	require.Equal(t, []string{"sip:unreachable@example.com", "sip:oncall@example.com"}, called)
+}
+
+func TestVoiceNotAcknowledged(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	notifier := NewVoice(
+		&config.VoiceConfig{
+			HTTPConfig:  &config.HTTPClientConfig{},
+			Provider:    "webhook",
+			URL:         srv.URL,
+			To:          []string{"sip:oncall@example.com"},
+			CallbackURL: "http://am/api/v1/voice/callback",
+			AckTimeout:  config.DefaultVoiceConfig.AckTimeout,
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	ctx = WithGroupKey(ctx, "1")
+
+	retry, err := notifier.Notify(ctx, &types.Alert{})
+	require.Error(t, err)
+	require.True(t, retry)
+	require.Empty(t, voiceCalls.waiters)
+}
+
+func TestVoiceCallbackUnknownCall(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/v1/voice/callback?call=unknown&Digits=1", nil)
+	rec := httptest.NewRecorder()
+	VoiceCallback(nil)(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestJiraRetry(t *testing.T) {
+	notifier := new(Jira)
+
+	retryCodes := append(defaultRetryCodes(), http.StatusTooManyRequests)
+	for statusCode, expected := range retryTests(retryCodes) {
+		actual, _ := notifier.retry(statusCode)
+		require.Equal(t, expected, actual, fmt.Sprintf("error on status %d", statusCode))
+	}
+}
+
+func TestJira(t *testing.T) {
+	var (
+		issue    map[string]interface{}
+		resolved bool
+		comments []string
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer t0k3n", r.Header.Get("Authorization"))
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.URL.Path == "/rest/api/2/search":
+			require.Contains(t, r.URL.Query().Get("jql"), `project = "OPS" AND labels = "ALERT-`)
+			if issue == nil {
+				fmt.Fprint(w, `{"issues":[]}`)
+				return
+			}
+			resolution := "null"
+			if resolved {
+				resolution = `{"name":"Done"}`
+			}
+			fmt.Fprintf(w, `{"issues":[{"key":"OPS-1","fields":{"resolution":%s}}]}`, resolution)
+		case r.URL.Path == "/rest/api/2/issue" && r.Method == "POST":
+			var body map[string]map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			issue = body["fields"]
+			fmt.Fprint(w, `{"key":"OPS-1"}`)
+		case r.URL.Path == "/rest/api/2/issue/OPS-1" && r.Method == "PUT":
+		case r.URL.Path == "/rest/api/2/issue/OPS-1/comment":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			comments = append(comments, body["body"])
+		case r.URL.Path == "/rest/api/2/issue/OPS-1/transitions" && r.Method == "GET":
+			fmt.Fprint(w, `{"transitions":[{"id":"11","name":"Done"},{"id":"21","name":"Reopen"}]}`)
+		case r.URL.Path == "/rest/api/2/issue/OPS-1/transitions" && r.Method == "POST":
+			var body map[string]map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			resolved = body["transition"]["id"] == "11"
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	notifier := NewJira(
+		&config.JiraConfig{
+			HTTPConfig:          &config.HTTPClientConfig{},
+			APIURL:              srv.URL + "/",
+			PersonalAccessToken: "t0k3n",
+			Project:             "OPS",
+			IssueType:           "Bug",
+			Summary:             `{{ .CommonLabels.alertname }}`,
+			Labels:              []string{"{{ .CommonLabels.team }}"},
+			ResolvedComment:     "resolved",
+			ResolveTransition:   "Done",
+			ReopenTransition:    "Reopen",
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+	ctx := WithGroupKey(context.Background(), "1")
+
+	firing := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "DiskFull", "team": "storage"},
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	resolvedAlert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   firing.Labels,
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(-time.Minute),
+		},
+	}
+
+	// A new issue is created for the group.
+	_, err := notifier.Notify(ctx, firing)
+	require.NoError(t, err)
+	require.Equal(t, "DiskFull", issue["summary"])
+	require.Equal(t, map[string]interface{}{"key": "OPS"}, issue["project"])
+	require.Len(t, issue["labels"], 2)
+	require.Equal(t, "storage", issue["labels"].([]interface{})[1])
+
+	// Once resolved the issue is commented on and transitioned.
+	_, err = notifier.Notify(ctx, resolvedAlert)
+	require.NoError(t, err)
+	require.Equal(t, []string{"resolved"}, comments)
+	require.True(t, resolved)
+
+	// Firing again reopens and updates the same issue.
+	requests = nil
+	_, err = notifier.Notify(ctx, firing)
+	require.NoError(t, err)
+	require.False(t, resolved)
+	require.Equal(t, []string{
+		"GET /rest/api/2/search",
+		"GET /rest/api/2/issue/OPS-1/transitions",
+		"POST /rest/api/2/issue/OPS-1/transitions",
+		"PUT /rest/api/2/issue/OPS-1",
+	}, requests)
+}
+
+func TestServiceNowRetry(t *testing.T) {
+	notifier := new(ServiceNow)
+
+	retryCodes := append(defaultRetryCodes(), http.StatusTooManyRequests)
+	for statusCode, expected := range retryTests(retryCodes) {
+		actual, _ := notifier.retry(statusCode)
+		require.Equal(t, expected, actual, fmt.Sprintf("error on status %d", statusCode))
+	}
+}
+
+func TestServiceNow(t *testing.T) {
+	var (
+		tokens   int
+		incident map[string]string
+		requests []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth_token.do" {
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			tokens++
+			fmt.Fprint(w, `{"access_token":"t0k3n","expires_in":1800}`)
+			return
+		}
+		require.Equal(t, "Bearer t0k3n", r.Header.Get("Authorization"))
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch r.Method {
+		case "GET":
+			require.Equal(t, "/api/now/table/incident", r.URL.Path)
+			if incident == nil || incident["state"] == "6" {
+				fmt.Fprint(w, `{"result":[]}`)
+				return
+			}
+			fmt.Fprint(w, `{"result":[{"sys_id":"abc"}]}`)
+		case "POST":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&incident))
+			w.WriteHeader(http.StatusCreated)
+		case "PATCH":
+			require.Equal(t, "/api/now/table/incident/abc", r.URL.Path)
+			var update map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&update))
+			for k, v := range update {
+				incident[k] = v
+			}
+		}
+	}))
+	defer srv.Close()
+
+	conf := config.DefaultServiceNowConfig
+	conf.HTTPConfig = &config.HTTPClientConfig{}
+	conf.InstanceURL = srv.URL + "/"
+	conf.ClientID = "am"
+	conf.ClientSecret = "s3cr3t"
+	notifier := NewServiceNow(&conf, createTmpl(t), log.NewNopLogger())
+	ctx := WithGroupKey(context.Background(), "1")
+
+	firing := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "DiskFull", "severity": "critical"},
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+
+	_, err := notifier.Notify(ctx, firing)
+	require.NoError(t, err)
+	require.Equal(t, hashKey("1"), incident["correlation_id"])
+	require.Equal(t, "1", incident["impact"])
+	require.Equal(t, "1", incident["urgency"])
+
+	_, err = notifier.Notify(ctx, firing)
+	require.NoError(t, err)
+
+	resolved := &types.Alert{
+		Alert: model.Alert{
+			Labels:   firing.Labels,
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(-time.Minute),
+		},
+	}
+	_, err = notifier.Notify(ctx, resolved)
+	require.NoError(t, err)
+	require.Equal(t, "6", incident["state"])
+	require.Equal(t, "Resolved by caller", incident["close_code"])
+
+	require.Equal(t, 1, tokens)
+	require.Equal(t, []string{
+		"GET /api/now/table/incident",
+		"POST /api/now/table/incident",
+		"GET /api/now/table/incident",
+		"PATCH /api/now/table/incident/abc",
+		"GET /api/now/table/incident",
+		"PATCH /api/now/table/incident/abc",
+	}, requests)
+}
+
+func TestSplunkRetry(t *testing.T) {
+	notifier := new(Splunk)
+
+	retryCodes := append(defaultRetryCodes(), http.StatusTooManyRequests)
+	for statusCode, expected := range retryTests(retryCodes) {
+		actual, _ := notifier.retry(statusCode)
+		require.Equal(t, expected, actual, fmt.Sprintf("error on status %d", statusCode))
+	}
+}
+
+func TestSplunk(t *testing.T) {
+	var event map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Splunk s3cr3t", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		fmt.Fprint(w, `{"text":"Success","code":0}`)
+	}))
+	defer srv.Close()
+
+	notifier := NewSplunk(
+		&config.SplunkConfig{
+			HTTPConfig: &config.HTTPClientConfig{},
+			URL:        srv.URL,
+			Token:      "s3cr3t",
+			Index:      "alerts",
+			SourceType: "alertmanager",
+			Fields:     map[string]string{"team": "{{ .CommonLabels.team }}"},
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+	ctx := WithGroupKey(context.Background(), "1")
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"team": "infra"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	retry, err := notifier.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	require.Equal(t, "alerts", event["index"])
+	require.Equal(t, "alertmanager", event["sourcetype"])
+	require.Equal(t, map[string]interface{}{"team": "infra"}, event["fields"])
+	require.Equal(t, "1", event["event"].(map[string]interface{})["groupKey"])
+}
+
+func TestPubSubRetry(t *testing.T) {
+	notifier := new(PubSub)
+
+	retryCodes := append(defaultRetryCodes(), http.StatusTooManyRequests, http.StatusRequestTimeout)
+	for statusCode, expected := range retryTests(retryCodes) {
+		actual, _ := notifier.retry(statusCode)
+		require.Equal(t, expected, actual, fmt.Sprintf("error on status %d", statusCode))
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		tokens  int
+		publish pubSubPublishRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+
+			parts := strings.Split(r.PostForm.Get("assertion"), ".")
+			require.Len(t, parts, 3)
+			sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+			require.NoError(t, err)
+			h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			require.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, h[:], sig))
+
+			tokens++
+			fmt.Fprint(w, `{"access_token":"t0k3n","expires_in":3600}`)
+		case "/v1/projects/my-project/topics/alerts:publish":
+			require.Equal(t, "Bearer t0k3n", r.Header.Get("Authorization"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&publish))
+			fmt.Fprint(w, `{"messageIds":["1"]}`)
+		default:
+			t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	creds, err := json.Marshal(googleCredentials{
+		Type:        "service_account",
+		ClientEmail: "am@my-project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		TokenURI:    srv.URL + "/token",
+	})
+	require.NoError(t, err)
+	f, err := ioutil.TempFile("", "credentials")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.Write(creds)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	notifier := NewPubSub(
+		&config.PubSubConfig{
+			HTTPConfig:      &config.HTTPClientConfig{},
+			APIURL:          srv.URL + "/",
+			Project:         "my-project",
+			Topic:           "alerts",
+			CredentialsFile: f.Name(),
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithGroupLabels(ctx, model.LabelSet{"team": "infra"})
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"team": "infra"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	for i := 0; i < 2; i++ {
+		retry, err := notifier.Notify(ctx, alert)
+		require.NoError(t, err)
+		require.False(t, retry)
+	}
+
+	require.Equal(t, 1, tokens)
+	require.Len(t, publish.Messages, 1)
+	require.Equal(t, "infra", publish.Messages[0].Attributes["team"])
+	require.Equal(t, "firing", publish.Messages[0].Attributes["status"])
+
+	var msg WebhookMessage
+	require.NoError(t, json.Unmarshal(publish.Messages[0].Data, &msg))
+	require.Equal(t, "1", msg.GroupKey)
+}
+
+func TestWebhookSigning(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		headers = r.Header
+	}))
+	defer srv.Close()
+
+	notifier := NewWebhook(
+		&config.WebhookConfig{
+			HTTPConfig:    &config.HTTPClientConfig{},
+			URL:           srv.URL,
+			SigningSecret: "s3cr3t",
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+	ctx := WithGroupKey(context.Background(), "1")
+
+	_, err := notifier.Notify(ctx, &types.Alert{})
+	require.NoError(t, err)
+
+	ts := headers.Get("X-Alertmanager-Timestamp")
+	require.NotEmpty(t, ts)
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write([]byte(ts + "." + string(body)))
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), headers.Get("X-Alertmanager-Signature"))
+}
+
+func TestWebhookBodyTemplate(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+		method  string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		headers = r.Header
+		method = r.Method
+	}))
+	defer srv.Close()
+
+	notifier := NewWebhook(
+		&config.WebhookConfig{
+			HTTPConfig:  &config.HTTPClientConfig{},
+			URL:         srv.URL,
+			Body:        `{"text": {{ printf "%s: %d alerts" .Status (len .Alerts) | toJson }}}`,
+			ContentType: "application/vnd.example+json",
+			Method:      "PUT",
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+	ctx := WithGroupKey(context.Background(), "1")
+
+	_, err := notifier.Notify(ctx, &types.Alert{
+		Alert: model.Alert{
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "PUT", method)
+	require.Equal(t, "application/vnd.example+json", headers.Get("Content-Type"))
+	require.Equal(t, `{"text": "firing: 1 alerts"}`, string(body))
+}
+
+func TestWebhookCloudEvents(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		headers = r.Header
+	}))
+	defer srv.Close()
+
+	conf := &config.WebhookConfig{
+		HTTPConfig: &config.HTTPClientConfig{},
+		URL:        srv.URL,
+		CloudEvents: &config.CloudEventsConfig{
+			Mode:   "structured",
+			Source: "/alertmanager",
+			Type:   "io.prometheus.alertmanager.notification",
+		},
+	}
+	notifier := NewWebhook(conf, createTmpl(t), log.NewNopLogger())
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithNow(ctx, now)
+
+	_, err := notifier.Notify(ctx, &types.Alert{})
+	require.NoError(t, err)
+
+	require.Equal(t, contentTypeCloudEvents, headers.Get("Content-Type"))
+	var event struct {
+		SpecVersion     string          `json:"specversion"`
+		ID              string          `json:"id"`
+		Source          string          `json:"source"`
+		Type            string          `json:"type"`
+		DataContentType string          `json:"datacontenttype"`
+		Data            *WebhookMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &event))
+	require.Equal(t, "1.0", event.SpecVersion)
+	require.NotEmpty(t, event.ID)
+	require.Equal(t, "/alertmanager", event.Source)
+	require.Equal(t, "io.prometheus.alertmanager.notification", event.Type)
+	require.Equal(t, contentTypeJSON, event.DataContentType)
+	require.Equal(t, "1", event.Data.GroupKey)
+
+	// Retries of the notification carry the same ID.
+	id := event.ID
+	_, err = notifier.Notify(ctx, &types.Alert{})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(body, &event))
+	require.Equal(t, id, event.ID)
+
+	conf.CloudEvents.Mode = "binary"
+	_, err = notifier.Notify(ctx, &types.Alert{})
+	require.NoError(t, err)
+
+	require.Equal(t, contentTypeJSON, headers.Get("Content-Type"))
+	require.Equal(t, "1.0", headers.Get("ce-specversion"))
+	require.Equal(t, id, headers.Get("ce-id"))
+
+	// The next notification of the group gets a new ID.
+	_, err = notifier.Notify(WithNow(ctx, now.Add(time.Minute)), &types.Alert{})
+	require.NoError(t, err)
+	require.NotEqual(t, id, headers.Get("ce-id"))
+	require.Equal(t, "/alertmanager", headers.Get("ce-source"))
+	require.Equal(t, "io.prometheus.alertmanager.notification", headers.Get("ce-type"))
+	var msg WebhookMessage
+	require.NoError(t, json.Unmarshal(body, &msg))
+	require.Equal(t, "1", msg.GroupKey)
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	conf := config.DefaultSyslogConfig
+	conf.Address = pc.LocalAddr().String()
+	conf.Hostname = "am 1"
+	notifier := NewSyslog(&conf, createTmpl(t), log.NewNopLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithGroupKey(ctx, "{}:{alertname=\"Down\"}")
+	ctx = WithNow(ctx, time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Down", "severity": "critical"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	retry, err := notifier.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	b := make([]byte, 2048)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(b)
+	require.NoError(t, err)
+
+	expected := fmt.Sprintf(
+		`<26>1 2018-01-02T03:04:05.000000Z am1 alertmanager %d firing [alertman`+
+			`ager@32473 groupKey="{}:{alertname=\"Down\"}" alertname="Down" severity="critical"] `+
+			"\xef\xbb\xbf[FIRING:1]  (Down critical)",
+		os.Getpid(),
+	)
+	require.Equal(t, expected, string(b[:n]))
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b, _ := ioutil.ReadAll(conn)
+		received <- string(b)
+	}()
+
+	conf := config.DefaultSyslogConfig
+	conf.Network = "tcp"
+	conf.Address = ln.Addr().String()
+	conf.Message = "resolved"
+	notifier := NewSyslog(&conf, createTmpl(t), log.NewNopLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithGroupKey(ctx, "1")
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Down"},
+			StartsAt: time.Now().Add(-time.Hour),
+			EndsAt:   time.Now().Add(-time.Minute),
+		},
+	}
+	retry, err := notifier.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	msg := <-received
+	i := strings.Index(msg, " ")
+	require.Equal(t, strconv.Itoa(len(msg)-i-1), msg[:i])
+	require.True(t, strings.HasPrefix(msg[i+1:], "<29>1 "), msg)
+	require.True(t, strings.HasSuffix(msg, "\xef\xbb\xbfresolved"), msg)
+}
+
+func TestPagerDutyLinksAndSeverityMap(t *testing.T) {
+	var msg map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v2/enqueue", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+	}))
+	defer srv.Close()
+
+	notifier := NewPagerDuty(
+		&config.PagerdutyConfig{
+			HTTPConfig:  &config.HTTPClientConfig{},
+			RoutingKey:  "key",
+			URL:         srv.URL + "/v2/enqueue",
+			Severity:    `{{ .CommonLabels.severity }}`,
+			SeverityMap: map[string]string{"page": "critical"},
+			Links: []*config.PagerdutyLink{
+				{Href: `{{ .CommonAnnotations.runbook }}`, Text: "Runbook"},
+				{Href: `{{ .CommonAnnotations.dashboard }}`, Text: "Dashboard"},
+			},
+			Images: []*config.PagerdutyImage{
+				{Src: `{{ .CommonAnnotations.graph }}`, Alt: "Graph"},
+			},
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+	ctx := WithGroupKey(context.Background(), "1")
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{"severity": "page"},
+			Annotations: model.LabelSet{
+				"runbook": "https://runbooks/db",
+				"graph":   "https://graphs/db.png",
+			},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	retry, err := notifier.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	require.Equal(t, "critical", msg["payload"].(map[string]interface{})["severity"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"href": "https://runbooks/db", "text": "Runbook"},
+	}, msg["links"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"src": "https://graphs/db.png", "alt": "Graph"},
+	}, msg["images"])
+}
+
+func TestPagerDutyChangeEvents(t *testing.T) {
+	var (
+		requests int
+		msg      map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		require.Equal(t, "/v2/change/enqueue", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	notifier := NewPagerDuty(
+		&config.PagerdutyConfig{
+			HTTPConfig:   &config.HTTPClientConfig{},
+			RoutingKey:   "key",
+			URL:          srv.URL + "/v2/enqueue",
+			Description:  `Deployed {{ .CommonLabels.version }}`,
+			ChangeEvents: true,
+		},
+		createTmpl(t),
+		log.NewNopLogger(),
+	)
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithNow(ctx, time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"version": "1.2.3"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	retry, err := notifier.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	require.Equal(t, "key", msg["routing_key"])
+	payload := msg["payload"].(map[string]interface{})
+	require.Equal(t, "Deployed 1.2.3", payload["summary"])
+	require.Equal(t, "2018-01-02T03:04:05Z", payload["timestamp"])
+
+	// Resolved notifications are not sent.
+	alert.EndsAt = time.Now().Add(-time.Minute)
+	retry, err = notifier.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.False(t, retry)
+	require.Equal(t, 1, requests)
+}
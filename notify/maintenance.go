@@ -0,0 +1,125 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var (
+	maintenanceEnabled = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "maintenance_mode",
+		Help:      "Whether the maintenance mode pausing all notifications is enabled.",
+	})
+	numMaintenanceSuppressedNotifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notifications_maintenance_suppressed_total",
+		Help:      "The total number of notifications suppressed by the maintenance mode.",
+	}, []string{"receiver", "integration"})
+)
+
+func init() {
+	prometheus.Register(maintenanceEnabled)
+	prometheus.Register(numMaintenanceSuppressedNotifications)
+}
+
+// MaintenanceStatus is the state of the maintenance mode.
+type MaintenanceStatus struct {
+	Enabled bool `json:"enabled"`
+	// Since is when the maintenance mode was last enabled or disabled.
+	Since  time.Time `json:"since"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Maintenance is the maintenance mode, which pauses all notifications
+// while alerts are still received, grouped and inhibited. Paused
+// notifications are not recorded in the notification log, so they are sent
+// with the next flush of their group once the maintenance mode is disabled.
+type Maintenance struct {
+	mtx    sync.RWMutex
+	status MaintenanceStatus
+}
+
+// NewMaintenance returns a maintenance mode in the given state.
+func NewMaintenance(enabled bool, reason string) *Maintenance {
+	m := &Maintenance{}
+	m.Set(enabled, reason)
+	return m
+}
+
+// Set enables or disables the maintenance mode.
+func (m *Maintenance) Set(enabled bool, reason string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if !enabled {
+		reason = ""
+	}
+	if enabled != m.status.Enabled || m.status.Since.IsZero() {
+		m.status.Since = time.Now().UTC()
+	}
+	m.status.Enabled = enabled
+	m.status.Reason = reason
+
+	if enabled {
+		maintenanceEnabled.Set(1)
+	} else {
+		maintenanceEnabled.Set(0)
+	}
+}
+
+// Status returns the state of the maintenance mode.
+func (m *Maintenance) Status() MaintenanceStatus {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.status
+}
+
+// StageFactory returns the factory of the maintenance stage, which is
+// registered before the deduplication so that no enrichment requests are
+// sent either.
+func (m *Maintenance) StageFactory() StageFactory {
+	return func(rc *config.Receiver, integration string) Stage {
+		return &MaintenanceStage{m: m, receiver: rc.Name, integration: integration}
+	}
+}
+
+// MaintenanceStage drops all notifications of an integration while the
+// maintenance mode is enabled.
+type MaintenanceStage struct {
+	m           *Maintenance
+	receiver    string
+	integration string
+}
+
+// Exec implements the Stage interface.
+func (s *MaintenanceStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	if !s.m.Status().Enabled {
+		return ctx, alerts, nil
+	}
+	numMaintenanceSuppressedNotifications.WithLabelValues(s.receiver, s.integration).Inc()
+	level.Debug(l).Log("msg", "Notification paused by the maintenance mode")
+	return ctx, nil, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestMaintenanceStage(t *testing.T) {
+	m := NewMaintenance(true, "receiver migration")
+	st := m.Status()
+	require.True(t, st.Enabled)
+	require.Equal(t, "receiver migration", st.Reason)
+	require.False(t, st.Since.IsZero())
+
+	s := m.StageFactory()(&config.Receiver{Name: "team-X"}, "webhook")
+	a := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+
+	_, res, err := s.Exec(context.Background(), log.NewNopLogger(), a)
+	require.NoError(t, err)
+	require.Empty(t, res)
+
+	m.Set(false, "ignored")
+	st = m.Status()
+	require.False(t, st.Enabled)
+	require.Equal(t, "", st.Reason)
+
+	_, res, err = s.Exec(context.Background(), log.NewNopLogger(), a)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{a}, res)
+}
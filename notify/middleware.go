@@ -0,0 +1,142 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var (
+	stageDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_stage_duration_seconds",
+		Help:      "The duration of registered notification pipeline stages in seconds.",
+	}, []string{"stage"})
+
+	stageFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_stage_failures_total",
+		Help:      "The total number of failed executions of registered notification pipeline stages.",
+	}, []string{"stage"})
+)
+
+func init() {
+	prometheus.Register(stageDurationSeconds)
+	prometheus.Register(stageFailures)
+}
+
+// StagePosition is a point in the pipeline of an integration at which
+// registered stages are inserted.
+type StagePosition int
+
+const (
+	// BeforeDedup stages run after the gossip wait and before notifications
+	// that were already sent are filtered out.
+	BeforeDedup StagePosition = iota
+	// AfterDedup stages run once it is decided that a notification is
+	// sent.
+	AfterDedup
+	// BeforeSend stages run right before the notification is sent, after
+	// rate limiting.
+	BeforeSend
+	// AfterSend stages run once the notification was sent successfully.
+	AfterSend
+)
+
+// StageFactory creates a stage for an integration of a receiver. It may
+// return nil to not add the stage to the integration.
+type StageFactory func(rc *config.Receiver, integration string) Stage
+
+type registeredStage struct {
+	name    string
+	pos     StagePosition
+	order   int
+	factory StageFactory
+}
+
+var (
+	stageRegistryMtx sync.RWMutex
+	stageRegistry    []registeredStage
+)
+
+// RegisterStage registers a stage that is added to the pipeline of every
+// integration at the given position. Stages at the same position run in
+// ascending order and in the order of their registration if it is equal.
+// The stages are instrumented with the given name, which must be unique.
+// Stages only become part of pipelines built after their registration.
+func RegisterStage(pos StagePosition, name string, order int, f StageFactory) error {
+	stageRegistryMtx.Lock()
+	defer stageRegistryMtx.Unlock()
+
+	for _, rs := range stageRegistry {
+		if rs.name == name {
+			return fmt.Errorf("stage %q already registered", name)
+		}
+	}
+	stageRegistry = append(stageRegistry, registeredStage{
+		name:    name,
+		pos:     pos,
+		order:   order,
+		factory: f,
+	})
+	sort.SliceStable(stageRegistry, func(i, j int) bool {
+		return stageRegistry[i].order < stageRegistry[j].order
+	})
+	return nil
+}
+
+// registeredStages returns the registered stages for an integration at the
+// given position.
+func registeredStages(pos StagePosition, rc *config.Receiver, integration string) []Stage {
+	stageRegistryMtx.RLock()
+	defer stageRegistryMtx.RUnlock()
+
+	var stages []Stage
+	for _, rs := range stageRegistry {
+		if rs.pos != pos {
+			continue
+		}
+		if s := rs.factory(rc, integration); s != nil {
+			stages = append(stages, &instrumentedStage{name: rs.name, stage: s})
+		}
+	}
+	return stages
+}
+
+// instrumentedStage records the duration and failures of a stage.
+type instrumentedStage struct {
+	name  string
+	stage Stage
+}
+
+// Exec implements the Stage interface.
+func (s *instrumentedStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	start := time.Now()
+	ctx, alerts, err := s.stage.Exec(ctx, l, alerts...)
+	stageDurationSeconds.WithLabelValues(s.name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		stageFailures.WithLabelValues(s.name).Inc()
+	}
+	return ctx, alerts, err
+}
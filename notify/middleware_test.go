@@ -0,0 +1,121 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func resetStageRegistry() {
+	stageRegistryMtx.Lock()
+	stageRegistry = nil
+	stageRegistryMtx.Unlock()
+}
+
+func nopStageFactory(rc *config.Receiver, integration string) Stage {
+	return StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, alerts, nil
+	})
+}
+
+func TestRegisterStage(t *testing.T) {
+	defer resetStageRegistry()
+
+	require.NoError(t, RegisterStage(BeforeSend, "second", 10, nopStageFactory))
+	require.NoError(t, RegisterStage(BeforeSend, "first", 0, nopStageFactory))
+	require.NoError(t, RegisterStage(BeforeSend, "third", 10, nopStageFactory))
+	require.NoError(t, RegisterStage(BeforeDedup, "policy", 0, nopStageFactory))
+	require.NoError(t, RegisterStage(BeforeSend, "webhook-only", 0, func(rc *config.Receiver, integration string) Stage {
+		if integration != "webhook" {
+			return nil
+		}
+		return nopStageFactory(rc, integration)
+	}))
+	require.EqualError(t, RegisterStage(AfterDedup, "first", 0, nopStageFactory), `stage "first" already registered`)
+
+	names := func(stages []Stage) []string {
+		var res []string
+		for _, s := range stages {
+			res = append(res, s.(*instrumentedStage).name)
+		}
+		return res
+	}
+	rc := &config.Receiver{Name: "team"}
+	require.Equal(t, []string{"first", "second", "third"}, names(registeredStages(BeforeSend, rc, "email")))
+	require.Equal(t, []string{"first", "webhook-only", "second", "third"}, names(registeredStages(BeforeSend, rc, "webhook")))
+	require.Equal(t, []string{"policy"}, names(registeredStages(BeforeDedup, rc, "email")))
+	require.Nil(t, registeredStages(AfterDedup, rc, "email"))
+}
+
+func TestRegisteredStagesInPipeline(t *testing.T) {
+	defer resetStageRegistry()
+
+	var calls []string
+	record := func(name string) StageFactory {
+		return func(rc *config.Receiver, integration string) Stage {
+			return StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+				calls = append(calls, name)
+				return ctx, alerts, nil
+			})
+		}
+	}
+	require.NoError(t, RegisterStage(BeforeDedup, "before-dedup", 0, record("before-dedup")))
+	require.NoError(t, RegisterStage(AfterDedup, "after-dedup", 0, record("after-dedup")))
+	require.NoError(t, RegisterStage(BeforeSend, "before-send", 0, record("before-send")))
+
+	rc := &config.Receiver{
+		Name:           "team",
+		WebhookConfigs: []*config.WebhookConfig{{URL: "http://localhost"}},
+	}
+	fs := createStage(rc, nil, func() time.Duration { return 0 }, func() bool { return true }, nil, nil, nil, nil, nil, log.NewNopLogger()).(FanoutStage)
+	require.Len(t, fs, 1)
+
+	ms := fs[0].(MultiStage)
+	require.Len(t, ms, 7)
+	for i, name := range map[int]string{1: "before-dedup", 3: "after-dedup", 4: "before-send"} {
+		require.Equal(t, name, ms[i].(*instrumentedStage).name)
+		_, _, err := ms[i].Exec(context.Background(), log.NewNopLogger())
+		require.NoError(t, err)
+	}
+	require.Len(t, calls, 3)
+}
+
+func TestInstrumentedStageFailures(t *testing.T) {
+	s := &instrumentedStage{
+		name: "failing",
+		stage: StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+			return ctx, nil, errors.New("lookup failed")
+		}),
+	}
+	_, _, err := s.Exec(context.Background(), log.NewNopLogger())
+	require.EqualError(t, err, "lookup failed")
+
+	var m dto.Metric
+	require.NoError(t, stageFailures.WithLabelValues("failing").Write(&m))
+	require.Equal(t, float64(1), m.GetCounter().GetValue())
+
+	require.NoError(t, stageDurationSeconds.WithLabelValues("failing").(prometheus.Histogram).Write(&m))
+	require.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+}
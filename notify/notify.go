@@ -16,6 +16,8 @@ package notify
 import (
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,12 +29,16 @@ import (
 	"github.com/prometheus/common/model"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/annotation"
 	"github.com/prometheus/alertmanager/cluster"
 	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/nflog"
 	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pkg/tracing"
 	"github.com/prometheus/alertmanager/silence"
 	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/timeinterval"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -55,6 +61,24 @@ var (
 		Help:      "The latency of notifications in seconds.",
 		Buckets:   []float64{1, 5, 10, 15, 20},
 	}, []string{"integration"})
+
+	notificationRetryBudgetSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_retry_budget_remaining_seconds",
+		Help:      "The remaining retry time of the most recent notification in seconds.",
+	}, []string{"receiver", "integration"})
+
+	notificationRetryAttemptsRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_retry_attempts_remaining",
+		Help:      "The remaining attempts of the most recent notification, if attempts are limited.",
+	}, []string{"receiver", "integration"})
+
+	numFailovers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "notification_failovers_total",
+		Help:      "The total number of failed notifications sent to a fallback receiver.",
+	}, []string{"receiver", "fallback"})
 )
 
 func init() {
@@ -67,6 +91,18 @@ func init() {
 	numNotifications.WithLabelValues("opsgenie")
 	numNotifications.WithLabelValues("webhook")
 	numNotifications.WithLabelValues("victorops")
+	numNotifications.WithLabelValues("nats")
+	numNotifications.WithLabelValues("mqtt")
+	numNotifications.WithLabelValues("twilio")
+	numNotifications.WithLabelValues("voice")
+	numNotifications.WithLabelValues("jira")
+	numNotifications.WithLabelValues("servicenow")
+	numNotifications.WithLabelValues("splunk")
+	numNotifications.WithLabelValues("pubsub")
+	numNotifications.WithLabelValues("plugin")
+	numNotifications.WithLabelValues("exec")
+	numNotifications.WithLabelValues("syslog")
+	numNotifications.WithLabelValues("snmp")
 	numFailedNotifications.WithLabelValues("email")
 	numFailedNotifications.WithLabelValues("hipchat")
 	numFailedNotifications.WithLabelValues("pagerduty")
@@ -76,6 +112,18 @@ func init() {
 	numFailedNotifications.WithLabelValues("opsgenie")
 	numFailedNotifications.WithLabelValues("webhook")
 	numFailedNotifications.WithLabelValues("victorops")
+	numFailedNotifications.WithLabelValues("nats")
+	numFailedNotifications.WithLabelValues("mqtt")
+	numFailedNotifications.WithLabelValues("twilio")
+	numFailedNotifications.WithLabelValues("voice")
+	numFailedNotifications.WithLabelValues("jira")
+	numFailedNotifications.WithLabelValues("servicenow")
+	numFailedNotifications.WithLabelValues("splunk")
+	numFailedNotifications.WithLabelValues("pubsub")
+	numFailedNotifications.WithLabelValues("plugin")
+	numFailedNotifications.WithLabelValues("exec")
+	numFailedNotifications.WithLabelValues("syslog")
+	numFailedNotifications.WithLabelValues("snmp")
 	notificationLatencySeconds.WithLabelValues("email")
 	notificationLatencySeconds.WithLabelValues("hipchat")
 	notificationLatencySeconds.WithLabelValues("pagerduty")
@@ -85,10 +133,25 @@ func init() {
 	notificationLatencySeconds.WithLabelValues("opsgenie")
 	notificationLatencySeconds.WithLabelValues("webhook")
 	notificationLatencySeconds.WithLabelValues("victorops")
+	notificationLatencySeconds.WithLabelValues("nats")
+	notificationLatencySeconds.WithLabelValues("mqtt")
+	notificationLatencySeconds.WithLabelValues("twilio")
+	notificationLatencySeconds.WithLabelValues("voice")
+	notificationLatencySeconds.WithLabelValues("jira")
+	notificationLatencySeconds.WithLabelValues("servicenow")
+	notificationLatencySeconds.WithLabelValues("splunk")
+	notificationLatencySeconds.WithLabelValues("pubsub")
+	notificationLatencySeconds.WithLabelValues("plugin")
+	notificationLatencySeconds.WithLabelValues("exec")
+	notificationLatencySeconds.WithLabelValues("syslog")
+	notificationLatencySeconds.WithLabelValues("snmp")
 
 	prometheus.Register(numNotifications)
 	prometheus.Register(numFailedNotifications)
 	prometheus.Register(notificationLatencySeconds)
+	prometheus.Register(notificationRetryBudgetSeconds)
+	prometheus.Register(notificationRetryAttemptsRemaining)
+	prometheus.Register(numFailovers)
 }
 
 // MinTimeout is the minimum timeout that is set for the context of a call
@@ -107,6 +170,16 @@ const (
 	keyFiringAlerts
 	keyResolvedAlerts
 	keyNow
+	keyStatusRecorder
+	keyDigestGroups
+	keyAcknowledgements
+	keyMuteTimeIntervals
+	keyActiveTimeIntervals
+	keyFallbackReceiver
+	keyDefaultReceiver
+	keyFailedReceiver
+	keyRequestRecorder
+	keyNotifyAttempts
 )
 
 // WithReceiverName populates a context with a receiver name.
@@ -195,6 +268,86 @@ func Now(ctx context.Context) (time.Time, bool) {
 	return v, ok
 }
 
+// WithMuteTimeIntervals populates a context with the names of the time
+// intervals during which notifications are muted.
+func WithMuteTimeIntervals(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, keyMuteTimeIntervals, names)
+}
+
+// MuteTimeIntervals extracts the names of the mute time intervals from the
+// context. Iff none exists, the second argument is false.
+func MuteTimeIntervals(ctx context.Context) ([]string, bool) {
+	v, ok := ctx.Value(keyMuteTimeIntervals).([]string)
+	return v, ok
+}
+
+// WithActiveTimeIntervals populates a context with the names of the time
+// intervals outside of which notifications are muted.
+func WithActiveTimeIntervals(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, keyActiveTimeIntervals, names)
+}
+
+// ActiveTimeIntervals extracts the names of the active time intervals from
+// the context. Iff none exists, the second argument is false.
+func ActiveTimeIntervals(ctx context.Context) ([]string, bool) {
+	v, ok := ctx.Value(keyActiveTimeIntervals).([]string)
+	return v, ok
+}
+
+// WithFallbackReceiver populates a context with the name of the receiver
+// notified if notifying the receiver fails.
+func WithFallbackReceiver(ctx context.Context, rcv string) context.Context {
+	return context.WithValue(ctx, keyFallbackReceiver, rcv)
+}
+
+// FallbackReceiver extracts the fallback receiver name from the context.
+// Iff none exists, the second argument is false.
+func FallbackReceiver(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(keyFallbackReceiver).(string)
+	return v, ok
+}
+
+// WithFailedReceiver populates a context with the name of the receiver whose
+// notification failed, if the receiver of the context is its fallback.
+func WithFailedReceiver(ctx context.Context, rcv string) context.Context {
+	return context.WithValue(ctx, keyFailedReceiver, rcv)
+}
+
+// FailedReceiver extracts the failed receiver name from the context.
+// Iff none exists, the second argument is false.
+func FailedReceiver(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(keyFailedReceiver).(string)
+	return v, ok
+}
+
+// WithDefaultReceiver populates a context with the name of the receiver
+// notified if the receiver of the context does not exist.
+func WithDefaultReceiver(ctx context.Context, rcv string) context.Context {
+	return context.WithValue(ctx, keyDefaultReceiver, rcv)
+}
+
+// DefaultReceiver extracts the default receiver name from the context.
+// Iff none exists, the second argument is false.
+func DefaultReceiver(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(keyDefaultReceiver).(string)
+	return v, ok
+}
+
+// startSpan starts a tracing span with the group key and receiver of the
+// context as attributes.
+func startSpan(ctx context.Context, name string, attrs ...string) (context.Context, *tracing.Span) {
+	if ctx == nil {
+		return ctx, nil
+	}
+	if gkey, ok := GroupKey(ctx); ok {
+		attrs = append(attrs, tracing.AttrGroupKey, gkey)
+	}
+	if recv, ok := ReceiverName(ctx); ok {
+		attrs = append(attrs, tracing.AttrReceiver, recv)
+	}
+	return tracing.Start(ctx, name, attrs...)
+}
+
 // FiringAlerts extracts a slice of firing alerts from the context.
 // Iff none exists, the second argument is false.
 func FiringAlerts(ctx context.Context) ([]uint64, bool) {
@@ -223,7 +376,7 @@ func (f StageFunc) Exec(ctx context.Context, l log.Logger, alerts ...*types.Aler
 }
 
 type NotificationLog interface {
-	Log(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64) error
+	Log(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts, firingFingerprints, resolvedFingerprints []uint64, expiry time.Duration) error
 	Query(params ...nflog.QueryParam) ([]*nflogpb.Entry, error)
 }
 
@@ -234,37 +387,104 @@ func BuildPipeline(
 	wait func() time.Duration,
 	muter types.Muter,
 	silences *silence.Silences,
+	acks *ack.Acks,
+	annotations *annotation.Annotations,
+	timeIntervals map[string][]timeinterval.Matcher,
 	notificationLog NotificationLog,
+	deadLetters DeadLetterQueue,
+	auditLog AuditLog,
 	marker types.Marker,
 	peer *cluster.Peer,
+	sharded bool,
+	floodProtection *FloodProtection,
+	rateLimiters *RateLimiters,
 	logger log.Logger,
 ) RoutingStage {
 	rs := RoutingStage{}
 
-	ms := NewGossipSettleStage(peer)
+	ms := MultiStage{NewGossipSettleStage(peer)}
+	if sharded && peer != nil {
+		ms = append(ms, NewShardStage(peer.IsOwner))
+	}
 	is := NewInhibitStage(muter)
 	ss := NewSilenceStage(silences, marker)
+	as := NewAckStage(acks)
+	ans := NewAnnotateStage(annotations)
+	tms := NewTimeMuteStage(timeIntervals)
+	tas := NewTimeActiveStage(timeIntervals)
+
+	// Digests are sent by the first peer of the cluster. In sharded mode the
+	// owners of the groups send the digests of their groups.
+	digestSender := func() bool { return true }
+	if !sharded && peer != nil {
+		digestSender = func() bool { return peer.Position() == 0 }
+	}
 
+	limiters := rateLimiters.update(confs)
+	stages := make(map[string]Stage, len(confs))
 	for _, rc := range confs {
-		rs[rc.Name] = MultiStage{ms, is, ss, createStage(rc, tmpl, wait, notificationLog, logger)}
+		stages[rc.Name] = createStage(rc, tmpl, wait, digestSender, notificationLog, deadLetters, auditLog, floodProtection, limiters[rc.Name], logger)
+	}
+	for _, rc := range confs {
+		s := stages[rc.Name]
+		if cb := rc.CircuitBreaker; cb != nil {
+			var fallback Stage
+			if cb.FallbackReceiver != "" {
+				fallback = stages[cb.FallbackReceiver]
+			}
+			s = NewCircuitBreakerStage(rc.Name, cb, s, fallback)
+		}
+		fos := NewFailoverStage(notificationLog, rc.Name, time.Duration(rc.NotificationLogRetention))
+		rs[rc.Name] = MultiStage{ms, is, tms, tas, ss, as, ans, s, fos}
 	}
 	return rs
 }
 
 // createStage creates a pipeline of stages for a receiver.
-func createStage(rc *config.Receiver, tmpl *template.Template, wait func() time.Duration, notificationLog NotificationLog, logger log.Logger) Stage {
-	var fs FanoutStage
+func createStage(rc *config.Receiver, tmpl *template.Template, wait func() time.Duration, digestSender func() bool, notificationLog NotificationLog, deadLetters DeadLetterQueue, auditLog AuditLog, floodProtection *FloodProtection, limiter *rateLimiter, logger log.Logger) Stage {
+	var (
+		fs     FanoutStage
+		enrich *EnrichStage
+	)
+	if rc.Enrichment != nil {
+		enrich = NewEnrichStage(rc.Enrichment, logger)
+	}
 	for _, i := range BuildReceiverIntegrations(rc, tmpl, logger) {
 		recv := &nflogpb.Receiver{
 			GroupName:   rc.Name,
 			Integration: i.name,
 			Idx:         uint32(i.idx),
 		}
+		if auditLog != nil {
+			i.notifier = &auditNotifier{notifier: i.notifier, log: auditLog, recv: recv, logger: logger}
+		}
+		setNotifies := NewSetNotifiesStage(notificationLog, recv, time.Duration(rc.NotificationLogRetention))
+
 		var s MultiStage
 		s = append(s, NewWaitStage(wait))
+		s = append(s, registeredStages(BeforeDedup, rc, i.name)...)
 		s = append(s, NewDedupStage(notificationLog, recv))
-		s = append(s, NewRetryStage(i, rc.Name))
-		s = append(s, NewSetNotifiesStage(notificationLog, recv))
+		if enrich != nil {
+			s = append(s, enrich)
+		}
+		s = append(s, registeredStages(AfterDedup, rc, i.name)...)
+		if limiter != nil {
+			s = append(s, NewRateLimitStage(limiter, rc.RateLimit.Overflow, setNotifies))
+		}
+		if floodProtection != nil {
+			s = append(s, NewFloodProtectionStage(floodProtection, recv))
+		}
+		s = append(s, registeredStages(BeforeSend, rc, i.name)...)
+		var send Stage = NewRetryStage(i, rc.Name, rc.Retry)
+		if deadLetters != nil {
+			send = NewDeadLetterStage(deadLetters, recv, send)
+		}
+		if rc.Digest != nil {
+			send = NewDigestStage(rc.Digest, send, setNotifies, digestSender, logger)
+		}
+		s = append(s, send)
+		s = append(s, registeredStages(AfterSend, rc, i.name)...)
+		s = append(s, setNotifies)
 
 		fs = append(fs, s)
 	}
@@ -277,19 +497,76 @@ type RoutingStage map[string]Stage
 
 // Exec implements the Stage interface.
 func (rs RoutingStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	ctx, span := startSpan(ctx, "notify.pipeline", tracing.AttrAlerts, strconv.Itoa(len(alerts)))
+	defer span.Finish()
+
+	ctx, res, err := rs.exec(ctx, l, alerts...)
+	span.SetError(err)
+	return ctx, res, err
+}
+
+func (rs RoutingStage) exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
 	receiver, ok := ReceiverName(ctx)
 	if !ok {
 		return ctx, nil, fmt.Errorf("receiver missing")
 	}
 
 	s, ok := rs[receiver]
+	if !ok {
+		// Receivers computed from a template fall back to the default
+		// receiver if no receiver of the computed name exists.
+		if def, dok := DefaultReceiver(ctx); dok && def != "" {
+			receiver = def
+			ctx = WithReceiverName(ctx, def)
+			s, ok = rs[def]
+		}
+	}
 	if !ok {
 		return ctx, nil, fmt.Errorf("stage for receiver missing")
 	}
 
-	return s.Exec(ctx, l, alerts...)
+	rctx, res, err := s.Exec(ctx, l, alerts...)
+	if err == nil {
+		return rctx, res, nil
+	}
+	fallback, ok := FallbackReceiver(ctx)
+	if !ok || fallback == "" || fallback == receiver {
+		return rctx, res, err
+	}
+	fs, ok := rs[fallback]
+	if !ok {
+		return rctx, res, err
+	}
+
+	level.Warn(l).Log("msg", "Notify failed, notifying fallback receiver", "receiver", receiver, "fallback", fallback, "err", err)
+	numFailovers.WithLabelValues(receiver, fallback).Inc()
+
+	// The fallback receiver is notified with its own notification log
+	// entries, and the failover is recorded for it. If the primary receiver
+	// used up the time of the context, the fallback receiver gets a new
+	// timeout.
+	fctx := WithFallbackReceiver(WithReceiverName(ctx, fallback), "")
+	fctx = WithFailedReceiver(fctx, receiver)
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		fctx, cancel = context.WithTimeout(detachedContext{fctx}, MinTimeout)
+		defer cancel()
+	}
+	if _, _, ferr := fs.Exec(fctx, l, alerts...); ferr != nil {
+		return rctx, nil, fmt.Errorf("%s; fallback receiver %q: %s", err, fallback, ferr)
+	}
+	return rctx, nil, nil
+}
+
+// detachedContext keeps the values of a context but is never done.
+type detachedContext struct {
+	context.Context
 }
 
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
 // A MultiStage executes a series of stages sequencially.
 type MultiStage []Stage
 
@@ -355,6 +632,31 @@ func (n *GossipSettleStage) Exec(ctx context.Context, l log.Logger, alerts ...*t
 	return ctx, alerts, nil
 }
 
+// ShardStage drops the alerts of aggregation groups that another peer of
+// the cluster is responsible for notifying about.
+type ShardStage struct {
+	isOwner func(groupKey string) bool
+}
+
+// NewShardStage returns a new ShardStage. The function returns true if the
+// peer is responsible for the aggregation group with the given key.
+func NewShardStage(isOwner func(groupKey string) bool) *ShardStage {
+	return &ShardStage{isOwner: isOwner}
+}
+
+// Exec implements the Stage interface.
+func (n *ShardStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	gkey, ok := GroupKey(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("group key missing")
+	}
+	if !n.isOwner(gkey) {
+		level.Debug(l).Log("msg", "Aggregation group is notified by another peer", "group_key", gkey)
+		return ctx, nil, nil
+	}
+	return ctx, alerts, nil
+}
+
 // InhibitStage filters alerts through an inhibition muter.
 type InhibitStage struct {
 	muter types.Muter
@@ -367,6 +669,9 @@ func NewInhibitStage(m types.Muter) *InhibitStage {
 
 // Exec implements the Stage interface.
 func (n *InhibitStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	_, span := startSpan(ctx, "notify.inhibit", tracing.AttrAlerts, strconv.Itoa(len(alerts)))
+	defer span.Finish()
+
 	var filtered []*types.Alert
 	for _, a := range alerts {
 		// TODO(fabxc): increment total alerts counter.
@@ -396,6 +701,9 @@ func NewSilenceStage(s *silence.Silences, mk types.Marker) *SilenceStage {
 
 // Exec implements the Stage interface.
 func (n *SilenceStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	_, span := startSpan(ctx, "notify.silence", tracing.AttrAlerts, strconv.Itoa(len(alerts)))
+	defer span.Finish()
+
 	var filtered []*types.Alert
 	for _, a := range alerts {
 		// TODO(fabxc): increment total alerts counter.
@@ -424,6 +732,135 @@ func (n *SilenceStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.
 	return ctx, filtered, nil
 }
 
+// TimeMuteStage drops all alerts while the current time is within one of the
+// mute time intervals of the route.
+type TimeMuteStage struct {
+	intervals map[string][]timeinterval.Matcher
+}
+
+// NewTimeMuteStage returns a new TimeMuteStage for the given named time
+// intervals.
+func NewTimeMuteStage(intervals map[string][]timeinterval.Matcher) *TimeMuteStage {
+	return &TimeMuteStage{intervals: intervals}
+}
+
+// Exec implements the Stage interface.
+func (n *TimeMuteStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	names, ok := MuteTimeIntervals(ctx)
+	if !ok || len(names) == 0 {
+		return ctx, alerts, nil
+	}
+	now, ok := Now(ctx)
+	if !ok {
+		return ctx, alerts, fmt.Errorf("missing now timestamp")
+	}
+
+	for _, name := range names {
+		for _, ti := range n.intervals[name] {
+			if ti.ContainsTime(now) {
+				// The notification log is not updated, so the group is
+				// notified once the interval ends.
+				level.Debug(l).Log("msg", "Notifications muted by time interval", "time_interval", name)
+				return ctx, nil, nil
+			}
+		}
+	}
+	return ctx, alerts, nil
+}
+
+// TimeActiveStage drops all alerts while the current time is outside of all
+// active time intervals of the route.
+type TimeActiveStage struct {
+	intervals map[string][]timeinterval.Matcher
+}
+
+// NewTimeActiveStage returns a new TimeActiveStage for the given named time
+// intervals.
+func NewTimeActiveStage(intervals map[string][]timeinterval.Matcher) *TimeActiveStage {
+	return &TimeActiveStage{intervals: intervals}
+}
+
+// Exec implements the Stage interface.
+func (n *TimeActiveStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	names, ok := ActiveTimeIntervals(ctx)
+	if !ok || len(names) == 0 {
+		return ctx, alerts, nil
+	}
+	now, ok := Now(ctx)
+	if !ok {
+		return ctx, alerts, fmt.Errorf("missing now timestamp")
+	}
+
+	for _, name := range names {
+		for _, ti := range n.intervals[name] {
+			if ti.ContainsTime(now) {
+				return ctx, alerts, nil
+			}
+		}
+	}
+	// As with muting, the group is notified once an active interval begins.
+	level.Debug(l).Log("msg", "Notifications muted outside of active time intervals", "time_intervals", strings.Join(names, ","))
+	return ctx, nil, nil
+}
+
+// AckStage looks up the acknowledgements of alerts and adds them to the
+// context.
+type AckStage struct {
+	acks *ack.Acks
+}
+
+// NewAckStage returns a new AckStage.
+func NewAckStage(acks *ack.Acks) *AckStage {
+	return &AckStage{acks: acks}
+}
+
+// Exec implements the Stage interface.
+func (n *AckStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	if n.acks == nil {
+		return ctx, alerts, nil
+	}
+	acked := map[model.Fingerprint]*ack.Ack{}
+	for _, a := range alerts {
+		if e, ok := n.acks.Acknowledged(&a.Alert); ok {
+			acked[a.Fingerprint()] = e
+		}
+	}
+	return context.WithValue(ctx, keyAcknowledgements, acked), alerts, nil
+}
+
+// acknowledgements returns the acknowledgements of alerts added to the context
+// by the AckStage.
+func acknowledgements(ctx context.Context) map[model.Fingerprint]*ack.Ack {
+	v, _ := ctx.Value(keyAcknowledgements).(map[model.Fingerprint]*ack.Ack)
+	return v
+}
+
+// AnnotateStage overrides the annotations of alerts by the annotations
+// attached to them.
+type AnnotateStage struct {
+	annotations *annotation.Annotations
+}
+
+// NewAnnotateStage returns a new AnnotateStage.
+func NewAnnotateStage(annotations *annotation.Annotations) *AnnotateStage {
+	return &AnnotateStage{annotations: annotations}
+}
+
+// Exec implements the Stage interface.
+func (n *AnnotateStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	if n.annotations == nil {
+		return ctx, alerts, nil
+	}
+	res := make([]*types.Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if m := n.annotations.Apply(&a.Alert); m != &a.Alert {
+			a = &types.Alert{Alert: *m, UpdatedAt: a.UpdatedAt, Timeout: a.Timeout}
+		}
+		res = append(res, a)
+	}
+	return ctx, res, nil
+}
+
 // WaitStage waits for a certain amount of time before continuing or until the
 // context is done.
 type WaitStage struct {
@@ -463,7 +900,7 @@ func NewDedupStage(l NotificationLog, recv *nflogpb.Receiver) *DedupStage {
 		nflog: l,
 		recv:  recv,
 		now:   utcNow,
-		hash:  hashAlert,
+		hash:  HashAlert,
 	}
 }
 
@@ -486,7 +923,9 @@ func putHashBuffer(b []byte) {
 	hashBuffers.Put(b)
 }
 
-func hashAlert(a *types.Alert) uint64 {
+// HashAlert returns the hash of an alert as it is stored in the
+// notification log.
+func HashAlert(a *types.Alert) uint64 {
 	const sep = '\xff'
 
 	b := getHashBuffer()
@@ -511,7 +950,7 @@ func hashAlert(a *types.Alert) uint64 {
 	return hash
 }
 
-func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, firing, resolved map[uint64]struct{}, repeat time.Duration) (bool, error) {
+func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, firing, resolved map[uint64]struct{}, repeat time.Duration, acked bool) (bool, error) {
 	// If we haven't notified about the alert group before, notify right away
 	// unless we only have resolved alerts.
 	if entry == nil {
@@ -531,12 +970,20 @@ func (n *DedupStage) needsUpdate(entry *nflogpb.Entry, firing, resolved map[uint
 		return len(entry.FiringAlerts) > 0, nil
 	}
 
+	// Nothing changed and someone is looking at the alerts already.
+	if acked {
+		return false, nil
+	}
+
 	// Nothing changed, only notify if the repeat interval has passed.
 	return entry.Timestamp.Before(n.now().Add(-repeat)), nil
 }
 
 // Exec implements the Stage interface.
 func (n *DedupStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	_, span := startSpan(ctx, "notify.dedup", tracing.AttrAlerts, strconv.Itoa(len(alerts)))
+	defer span.Finish()
+
 	gkey, ok := GroupKey(ctx)
 	if !ok {
 		return ctx, nil, fmt.Errorf("group key missing")
@@ -552,7 +999,11 @@ func (n *DedupStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Al
 	firing := []uint64{}
 	resolved := []uint64{}
 
-	var hash uint64
+	var (
+		hash  uint64
+		acks  = acknowledgements(ctx)
+		acked = true
+	)
 	for _, a := range alerts {
 		hash = n.hash(a)
 		if a.Resolved() {
@@ -561,6 +1012,9 @@ func (n *DedupStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Al
 		} else {
 			firing = append(firing, hash)
 			firingSet[hash] = struct{}{}
+			if _, ok := acks[a.Fingerprint()]; !ok {
+				acked = false
+			}
 		}
 	}
 
@@ -580,7 +1034,7 @@ func (n *DedupStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Al
 	case 2:
 		return ctx, nil, fmt.Errorf("unexpected entry result size %d", len(entries))
 	}
-	if ok, err := n.needsUpdate(entry, firingSet, resolvedSet, repeatInterval); err != nil {
+	if ok, err := n.needsUpdate(entry, firingSet, resolvedSet, repeatInterval, acked); err != nil {
 		return ctx, nil, err
 	} else if ok {
 		return ctx, alerts, nil
@@ -593,18 +1047,60 @@ func (n *DedupStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Al
 type RetryStage struct {
 	integration Integration
 	groupName   string
+	conf        *config.RetryConfig
 }
 
-// NewRetryStage returns a new instance of a RetryStage.
-func NewRetryStage(i Integration, groupName string) *RetryStage {
+// NewRetryStage returns a new instance of a RetryStage. If conf is nil, the
+// default backoff is used.
+func NewRetryStage(i Integration, groupName string, conf *config.RetryConfig) *RetryStage {
 	return &RetryStage{
 		integration: i,
 		groupName:   groupName,
+		conf:        conf,
+	}
+}
+
+func (r RetryStage) newBackOff() *backoff.ExponentialBackOff {
+	b := backoff.NewExponentialBackOff()
+	if r.conf == nil {
+		return b
+	}
+	if r.conf.InitialBackoff != 0 {
+		b.InitialInterval = time.Duration(r.conf.InitialBackoff)
+	}
+	if r.conf.MaxBackoff != 0 {
+		b.MaxInterval = time.Duration(r.conf.MaxBackoff)
+	}
+	if r.conf.Budget != 0 {
+		b.MaxElapsedTime = time.Duration(r.conf.Budget)
+	}
+	return b
+}
+
+// observeBudget updates the remaining retry budget after the given number of
+// attempts.
+func (r RetryStage) observeBudget(b *backoff.ExponentialBackOff, attempts int) {
+	remaining := b.MaxElapsedTime - b.GetElapsedTime()
+	if remaining < 0 {
+		remaining = 0
+	}
+	notificationRetryBudgetSeconds.WithLabelValues(r.groupName, r.integration.name).Set(remaining.Seconds())
+	if r.conf != nil && r.conf.MaxAttempts > 0 {
+		notificationRetryAttemptsRemaining.WithLabelValues(r.groupName, r.integration.name).Set(float64(r.conf.MaxAttempts - attempts))
 	}
 }
 
 // Exec implements the Stage interface.
 func (r RetryStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	sctx, span := startSpan(ctx, "notify.retry", tracing.AttrIntegration, r.integration.name)
+	defer span.Finish()
+
+	_, res, err := r.exec(sctx, l, alerts...)
+	span.SetError(err)
+	return ctx, res, err
+}
+
+func (r RetryStage) exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
 	// If we shouldn't send notifications for resolved alerts, but there are only
 	// resolved alerts, report them all as successfully notified (we still want the
 	// notification log to log them).
@@ -620,7 +1116,7 @@ func (r RetryStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Ale
 
 	var (
 		i    = 0
-		b    = backoff.NewExponentialBackOff()
+		b    = r.newBackOff()
 		tick = backoff.NewTicker(b)
 		iErr error
 	)
@@ -640,16 +1136,28 @@ func (r RetryStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Ale
 		}
 
 		select {
-		case <-tick.C:
+		case _, ok := <-tick.C:
+			if !ok {
+				// The ticker stops once the retry budget is exhausted.
+				return ctx, nil, fmt.Errorf("retry budget for %q exhausted: %s", r.integration.name, iErr)
+			}
 			now := time.Now()
+			_, span := startSpan(ctx, "notify.send", tracing.AttrIntegration, r.integration.name, "attempt", strconv.Itoa(i))
+			countNotifyAttempt(ctx)
 			retry, err := r.integration.Notify(ctx, alerts...)
+			span.SetError(err)
+			span.Finish()
 			notificationLatencySeconds.WithLabelValues(r.integration.name).Observe(time.Since(now).Seconds())
 			if err != nil {
 				numFailedNotifications.WithLabelValues(r.integration.name).Inc()
 				level.Debug(l).Log("msg", "Notify attempt failed", "attempt", i, "integration", r.integration.name, "receiver", r.groupName, "err", err)
+				r.observeBudget(b, i)
 				if !retry {
 					return ctx, alerts, fmt.Errorf("cancelling notify retry for %q due to unrecoverable error: %s", r.integration.name, err)
 				}
+				if r.conf != nil && r.conf.MaxAttempts > 0 && i >= r.conf.MaxAttempts {
+					return ctx, nil, fmt.Errorf("giving up on %q after %d attempts: %s", r.integration.name, i, err)
+				}
 
 				// Save this error to be able to return the last seen error by an
 				// integration upon context timeout.
@@ -668,18 +1176,50 @@ func (r RetryStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Ale
 	}
 }
 
+// FailoverIntegration is the integration name of the notification log
+// entries recording that a receiver was notified as the fallback receiver of
+// another receiver.
+const FailoverIntegration = "failover"
+
+// FailoverStage records in the notification log that the alerts were sent
+// to the receiver as the fallback receiver of a failed receiver.
+type FailoverStage struct {
+	notifies *SetNotifiesStage
+}
+
+// NewFailoverStage returns a new instance of a FailoverStage for the given
+// receiver.
+func NewFailoverStage(l NotificationLog, receiver string, retention time.Duration) *FailoverStage {
+	recv := &nflogpb.Receiver{GroupName: receiver, Integration: FailoverIntegration}
+	return &FailoverStage{notifies: NewSetNotifiesStage(l, recv, retention)}
+}
+
+// Exec implements the Stage interface.
+func (s *FailoverStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	failed, ok := FailedReceiver(ctx)
+	if !ok {
+		return ctx, alerts, nil
+	}
+	level.Debug(l).Log("msg", "Recording failover", "receiver", s.notifies.recv.GroupName, "failed_receiver", failed)
+	return s.notifies.Exec(ctx, l, alerts...)
+}
+
 // SetNotifiesStage sets the notification information about passed alerts. The
 // passed alerts should have already been sent to the receivers.
 type SetNotifiesStage struct {
-	nflog NotificationLog
-	recv  *nflogpb.Receiver
+	nflog     NotificationLog
+	recv      *nflogpb.Receiver
+	retention time.Duration
 }
 
-// NewSetNotifiesStage returns a new instance of a SetNotifiesStage.
-func NewSetNotifiesStage(l NotificationLog, recv *nflogpb.Receiver) *SetNotifiesStage {
+// NewSetNotifiesStage returns a new instance of a SetNotifiesStage. The
+// notifications are kept in the log for the given retention or, if it is
+// zero, the retention of the log.
+func NewSetNotifiesStage(l NotificationLog, recv *nflogpb.Receiver, retention time.Duration) *SetNotifiesStage {
 	return &SetNotifiesStage{
-		nflog: l,
-		recv:  recv,
+		nflog:     l,
+		recv:      recv,
+		retention: retention,
 	}
 }
 
@@ -700,5 +1240,24 @@ func (n SetNotifiesStage) Exec(ctx context.Context, l log.Logger, alerts ...*typ
 		return ctx, nil, fmt.Errorf("resolved alerts missing")
 	}
 
-	return ctx, alerts, n.nflog.Log(n.recv, gkey, firing, resolved)
+	return ctx, alerts, n.log(gkey, alerts, firing, resolved)
+}
+
+// log records the notification of the alerts with the given hashes along
+// with their fingerprints.
+func (n SetNotifiesStage) log(gkey string, alerts []*types.Alert, firing, resolved []uint64) error {
+	fps := make(map[uint64]uint64, len(alerts))
+	for _, a := range alerts {
+		fps[HashAlert(a)] = uint64(a.Fingerprint())
+	}
+	fingerprints := func(hashes []uint64) []uint64 {
+		var res []uint64
+		for _, h := range hashes {
+			if fp, ok := fps[h]; ok {
+				res = append(res, fp)
+			}
+		}
+		return res
+	}
+	return n.nflog.Log(n.recv, gkey, firing, resolved, fingerprints(firing), fingerprints(resolved), n.retention)
 }
@@ -13,10 +13,12 @@
 package notify
 
 import (
+	stdcontext "context"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -25,10 +27,15 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 
+	"github.com/prometheus/alertmanager/ack"
+	"github.com/prometheus/alertmanager/annotation"
+	"github.com/prometheus/alertmanager/config"
 	"github.com/prometheus/alertmanager/nflog"
 	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/pkg/tracing"
 	"github.com/prometheus/alertmanager/silence"
 	"github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/timeinterval"
 	"github.com/prometheus/alertmanager/types"
 )
 
@@ -54,15 +61,15 @@ type testNflog struct {
 	qres []*nflogpb.Entry
 	qerr error
 
-	logFunc func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64) error
+	logFunc func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error
 }
 
 func (l *testNflog) Query(p ...nflog.QueryParam) ([]*nflogpb.Entry, error) {
 	return l.qres, l.qerr
 }
 
-func (l *testNflog) Log(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64) error {
-	return l.logFunc(r, gkey, firingAlerts, resolvedAlerts)
+func (l *testNflog) Log(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts, firingFingerprints, resolvedFingerprints []uint64, expiry time.Duration) error {
+	return l.logFunc(r, gkey, firingAlerts, resolvedAlerts, expiry)
 }
 
 func (l *testNflog) GC() (int, error) {
@@ -91,6 +98,7 @@ func TestDedupStageNeedsUpdate(t *testing.T) {
 		firingAlerts   map[uint64]struct{}
 		resolvedAlerts map[uint64]struct{}
 		repeat         time.Duration
+		acked          bool
 
 		res    bool
 		resErr bool
@@ -126,6 +134,24 @@ func TestDedupStageNeedsUpdate(t *testing.T) {
 			repeat:       10 * time.Minute,
 			firingAlerts: alertHashSet(1, 2, 3),
 			res:          true,
+		}, {
+			entry: &nflogpb.Entry{
+				FiringAlerts: []uint64{1, 2, 3},
+				Timestamp:    now.Add(-11 * time.Minute),
+			},
+			repeat:       10 * time.Minute,
+			firingAlerts: alertHashSet(1, 2, 3),
+			acked:        true,
+			res:          false,
+		}, {
+			entry: &nflogpb.Entry{
+				FiringAlerts: []uint64{1, 2},
+				Timestamp:    now.Add(-1 * time.Minute),
+			},
+			repeat:       10 * time.Minute,
+			firingAlerts: alertHashSet(1, 2, 3),
+			acked:        true,
+			res:          true,
 		}, {
 			entry: &nflogpb.Entry{
 				ResolvedAlerts: []uint64{1, 2, 3},
@@ -172,7 +198,7 @@ func TestDedupStageNeedsUpdate(t *testing.T) {
 		s := &DedupStage{
 			now: func() time.Time { return now },
 		}
-		ok, err := s.needsUpdate(c.entry, c.firingAlerts, c.resolvedAlerts, c.repeat)
+		ok, err := s.needsUpdate(c.entry, c.firingAlerts, c.resolvedAlerts, c.repeat, c.acked)
 		if c.resErr {
 			require.Error(t, err)
 		} else {
@@ -344,6 +370,103 @@ func TestRoutingStage(t *testing.T) {
 	}
 }
 
+func TestRoutingStageFallback(t *testing.T) {
+	var notified []string
+	record := func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		recv, _ := ReceiverName(ctx)
+		notified = append(notified, recv)
+		return ctx, alerts, nil
+	}
+	stage := RoutingStage{
+		"slack": StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+			record(ctx, l, alerts...)
+			<-ctx.Done()
+			return ctx, nil, ctx.Err()
+		}),
+		"email": StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+			// The fallback receiver gets a new timeout.
+			require.NoError(t, ctx.Err())
+			return record(ctx, l, alerts...)
+		}),
+		"broken": failStage{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ctx = WithReceiverName(ctx, "slack")
+	ctx = WithFallbackReceiver(ctx, "email")
+
+	_, _, err := stage.Exec(ctx, log.NewNopLogger(), &types.Alert{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"slack", "email"}, notified)
+
+	// Errors of both receivers are returned. Fallback receivers do not
+	// fall back any further.
+	ctx = WithReceiverName(context.Background(), "broken")
+	ctx = WithFallbackReceiver(ctx, "broken")
+	_, _, err = stage.Exec(ctx, log.NewNopLogger(), &types.Alert{})
+	require.EqualError(t, err, "some error")
+
+	stage["email"] = failStage{}
+	ctx = WithFallbackReceiver(ctx, "email")
+	_, _, err = stage.Exec(ctx, log.NewNopLogger(), &types.Alert{})
+	require.EqualError(t, err, `some error; fallback receiver "email": some error`)
+}
+
+func TestRoutingStageFailoverLog(t *testing.T) {
+	var logged []*nflogpb.Receiver
+	tnflog := &testNflog{
+		logFunc: func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error {
+			require.Equal(t, "1", gkey)
+			require.Equal(t, []uint64{1}, firingAlerts)
+			logged = append(logged, r)
+			return nil
+		},
+	}
+	ok := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		return ctx, alerts, nil
+	})
+	stage := RoutingStage{
+		"slack": failStage{},
+		"email": MultiStage{ok, NewFailoverStage(tnflog, "email", 0)},
+	}
+
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithFiringAlerts(ctx, []uint64{1})
+	ctx = WithResolvedAlerts(ctx, []uint64{})
+
+	// Notifying the receiver directly is not recorded as a failover.
+	_, _, err := stage.Exec(WithReceiverName(ctx, "email"), log.NewNopLogger(), &types.Alert{})
+	require.NoError(t, err)
+	require.Len(t, logged, 0)
+
+	ctx = WithReceiverName(ctx, "slack")
+	ctx = WithFallbackReceiver(ctx, "email")
+	_, _, err = stage.Exec(ctx, log.NewNopLogger(), &types.Alert{})
+	require.NoError(t, err)
+	require.Equal(t, []*nflogpb.Receiver{{GroupName: "email", Integration: FailoverIntegration}}, logged)
+}
+
+func TestRoutingStageDefault(t *testing.T) {
+	var notified []string
+	stage := RoutingStage{
+		"team-a": StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+			recv, _ := ReceiverName(ctx)
+			notified = append(notified, recv)
+			return ctx, alerts, nil
+		}),
+	}
+
+	ctx := WithReceiverName(context.Background(), "team-b")
+	_, _, err := stage.Exec(ctx, log.NewNopLogger(), &types.Alert{})
+	require.EqualError(t, err, "stage for receiver missing")
+
+	ctx = WithDefaultReceiver(ctx, "team-a")
+	_, _, err = stage.Exec(ctx, log.NewNopLogger(), &types.Alert{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"team-a"}, notified)
+}
+
 func TestIntegrationNoResolved(t *testing.T) {
 	res := []*types.Alert{}
 	r := notifierFunc(func(ctx context.Context, alerts ...*types.Alert) (bool, error) {
@@ -400,12 +523,106 @@ func TestIntegrationSendResolved(t *testing.T) {
 	require.Equal(t, res, alerts)
 }
 
+func TestRetryStageMaxAttempts(t *testing.T) {
+	attempts := 0
+	i := Integration{
+		notifier: notifierFunc(func(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+			attempts++
+			return true, fmt.Errorf("fail")
+		}),
+		conf: notifierConfigFunc(func() bool { return true }),
+		name: "test",
+	}
+	r := NewRetryStage(i, "test", &config.RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: model.Duration(time.Millisecond),
+		MaxBackoff:     model.Duration(time.Millisecond),
+	})
+
+	_, res, err := r.Exec(context.Background(), log.NewNopLogger(), &types.Alert{})
+	require.EqualError(t, err, `giving up on "test" after 3 attempts: fail`)
+	require.Nil(t, res)
+	require.Equal(t, 3, attempts)
+}
+
+func TestRetryStageBudget(t *testing.T) {
+	i := Integration{
+		notifier: notifierFunc(func(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+			return true, fmt.Errorf("fail")
+		}),
+		conf: notifierConfigFunc(func() bool { return true }),
+		name: "test",
+	}
+	r := NewRetryStage(i, "test", &config.RetryConfig{
+		InitialBackoff: model.Duration(time.Millisecond),
+		MaxBackoff:     model.Duration(time.Millisecond),
+		Budget:         model.Duration(20 * time.Millisecond),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, res, err := r.Exec(ctx, log.NewNopLogger(), &types.Alert{})
+	require.EqualError(t, err, `retry budget for "test" exhausted: fail`)
+	require.Nil(t, res)
+}
+
+func TestRetryStageTracing(t *testing.T) {
+	var (
+		mtx   sync.Mutex
+		spans []*tracing.Span
+	)
+	tracer := tracing.NewTracer(tracing.ExporterFunc(func(_ stdcontext.Context, s []*tracing.Span) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		spans = append(spans, s...)
+		return nil
+	}), tracing.Options{SampleRatio: 1}, nil)
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+	go tracer.Run()
+
+	attempts := 0
+	i := Integration{
+		notifier: notifierFunc(func(ctx context.Context, alerts ...*types.Alert) (bool, error) {
+			attempts++
+			if attempts == 1 {
+				return true, fmt.Errorf("fail")
+			}
+			return false, nil
+		}),
+		conf: notifierConfigFunc(func() bool { return true }),
+		name: "test",
+	}
+	r := NewRetryStage(i, "test", &config.RetryConfig{
+		InitialBackoff: model.Duration(time.Millisecond),
+		MaxBackoff:     model.Duration(time.Millisecond),
+	})
+
+	ctx := WithGroupKey(context.Background(), "{}:{}")
+	ctx = WithReceiverName(ctx, "team-X")
+	_, _, err := r.Exec(ctx, log.NewNopLogger(), &types.Alert{})
+	require.NoError(t, err)
+	tracer.Stop()
+
+	// The attempts are children of the span of the stage.
+	require.Len(t, spans, 3)
+	for _, s := range spans {
+		require.Equal(t, "{}:{}", s.Attributes[tracing.AttrGroupKey])
+		require.Equal(t, "team-X", s.Attributes[tracing.AttrReceiver])
+		require.Equal(t, "test", s.Attributes[tracing.AttrIntegration])
+	}
+	require.Equal(t, "notify.send", spans[0].Name)
+	require.EqualError(t, spans[0].Err, "fail")
+	require.Equal(t, "notify.send", spans[1].Name)
+	require.NoError(t, spans[1].Err)
+	require.Equal(t, "notify.retry", spans[2].Name)
+	require.Equal(t, spans[2].SpanID, spans[0].ParentID)
+	require.Equal(t, spans[2].SpanID, spans[1].ParentID)
+}
+
 func TestSetNotifiesStage(t *testing.T) {
 	tnflog := &testNflog{}
-	s := &SetNotifiesStage{
-		recv:  &nflogpb.Receiver{GroupName: "test"},
-		nflog: tnflog,
-	}
+	s := NewSetNotifiesStage(tnflog, &nflogpb.Receiver{GroupName: "test"}, 6*time.Hour)
 	alerts := []*types.Alert{{}, {}, {}}
 	ctx := context.Background()
 
@@ -430,11 +647,12 @@ func TestSetNotifiesStage(t *testing.T) {
 
 	ctx = WithResolvedAlerts(ctx, []uint64{})
 
-	tnflog.logFunc = func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64) error {
+	tnflog.logFunc = func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error {
 		require.Equal(t, s.recv, r)
 		require.Equal(t, "1", gkey)
 		require.Equal(t, []uint64{0, 1, 2}, firingAlerts)
 		require.Equal(t, []uint64{}, resolvedAlerts)
+		require.Equal(t, 6*time.Hour, expiry)
 		return nil
 	}
 	resctx, res, err = s.Exec(ctx, log.NewNopLogger(), alerts...)
@@ -445,7 +663,7 @@ func TestSetNotifiesStage(t *testing.T) {
 	ctx = WithFiringAlerts(ctx, []uint64{})
 	ctx = WithResolvedAlerts(ctx, []uint64{0, 1, 2})
 
-	tnflog.logFunc = func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64) error {
+	tnflog.logFunc = func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error {
 		require.Equal(t, s.recv, r)
 		require.Equal(t, "1", gkey)
 		require.Equal(t, []uint64{}, firingAlerts)
@@ -458,6 +676,137 @@ func TestSetNotifiesStage(t *testing.T) {
 	require.NotNil(t, resctx)
 }
 
+func TestSetNotifiesStageFingerprints(t *testing.T) {
+	l, err := nflog.New()
+	require.NoError(t, err)
+	s := NewSetNotifiesStage(l, &nflogpb.Receiver{GroupName: "test"}, 0)
+
+	firing := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a"}}}
+	resolved := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "b"}, EndsAt: time.Now().Add(-time.Minute)}}
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithFiringAlerts(ctx, []uint64{HashAlert(firing)})
+	ctx = WithResolvedAlerts(ctx, []uint64{HashAlert(resolved)})
+
+	_, _, err = s.Exec(ctx, log.NewNopLogger(), firing, resolved)
+	require.NoError(t, err)
+
+	entries, err := l.Query(nflog.QAlert(uint64(resolved.Fingerprint())))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, []uint64{uint64(firing.Fingerprint())}, entries[0].FiringFingerprints)
+	require.Equal(t, []uint64{uint64(resolved.Fingerprint())}, entries[0].ResolvedFingerprints)
+}
+
+func TestTimeMuteStage(t *testing.T) {
+	var weekend timeinterval.WeekdayRange
+	require.NoError(t, weekend.UnmarshalText([]byte("saturday:sunday")))
+	stage := NewTimeMuteStage(map[string][]timeinterval.Matcher{
+		"weekend": {timeinterval.TimeInterval{Weekdays: []timeinterval.WeekdayRange{weekend}}},
+	})
+
+	alerts := []*types.Alert{{}}
+	saturday := time.Date(2018, time.January, 13, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2018, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		names []string
+		now   time.Time
+		muted bool
+	}{
+		{names: []string{"weekend"}, now: saturday, muted: true},
+		{names: []string{"weekend"}, now: monday, muted: false},
+		{names: nil, now: saturday, muted: false},
+		{names: []string{"unknown"}, now: saturday, muted: false},
+	} {
+		ctx := WithNow(context.Background(), tc.now)
+		ctx = WithMuteTimeIntervals(ctx, tc.names)
+
+		_, res, err := stage.Exec(ctx, log.NewNopLogger(), alerts...)
+		require.NoError(t, err)
+		if tc.muted {
+			require.Len(t, res, 0)
+		} else {
+			require.Equal(t, alerts, res)
+		}
+	}
+
+	_, _, err := stage.Exec(WithMuteTimeIntervals(context.Background(), []string{"weekend"}), log.NewNopLogger(), alerts...)
+	require.EqualError(t, err, "missing now timestamp")
+}
+
+func TestTimeActiveStage(t *testing.T) {
+	var weekdays timeinterval.WeekdayRange
+	require.NoError(t, weekdays.UnmarshalText([]byte("monday:friday")))
+	stage := NewTimeActiveStage(map[string][]timeinterval.Matcher{
+		"business-days": {timeinterval.TimeInterval{Weekdays: []timeinterval.WeekdayRange{weekdays}}},
+	})
+
+	alerts := []*types.Alert{{}}
+	saturday := time.Date(2018, time.January, 13, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2018, time.January, 15, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		names []string
+		now   time.Time
+		muted bool
+	}{
+		{names: []string{"business-days"}, now: monday, muted: false},
+		{names: []string{"business-days"}, now: saturday, muted: true},
+		{names: nil, now: saturday, muted: false},
+	} {
+		ctx := WithNow(context.Background(), tc.now)
+		ctx = WithActiveTimeIntervals(ctx, tc.names)
+
+		_, res, err := stage.Exec(ctx, log.NewNopLogger(), alerts...)
+		require.NoError(t, err)
+		if tc.muted {
+			require.Len(t, res, 0)
+		} else {
+			require.Equal(t, alerts, res)
+		}
+	}
+}
+
+func TestAckStage(t *testing.T) {
+	acks, err := ack.New(ack.Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	a1 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"a": "1"}, StartsAt: time.Unix(100, 0)}}
+	a2 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"a": "2"}, StartsAt: time.Unix(100, 0)}}
+	require.NoError(t, acks.Ack(a1.Fingerprint(), a1.StartsAt, "alice", "looking"))
+
+	ctx, res, err := NewAckStage(acks).Exec(context.Background(), log.NewNopLogger(), a1, a2)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{a1, a2}, res)
+	require.Len(t, acknowledgements(ctx), 1)
+
+	data := tmplData(ctx, createTmpl(t), log.NewNopLogger(), a1, a2)
+	require.NotNil(t, data.Alerts[0].Acknowledgement)
+	require.Equal(t, "alice", data.Alerts[0].Acknowledgement.CreatedBy)
+	require.Nil(t, data.Alerts[1].Acknowledgement)
+}
+
+func TestAnnotateStage(t *testing.T) {
+	annotations, err := annotation.New(annotation.Options{Retention: time.Hour})
+	require.NoError(t, err)
+
+	a1 := &types.Alert{Alert: model.Alert{
+		Labels:      model.LabelSet{"a": "1"},
+		Annotations: model.LabelSet{"summary": "disk full"},
+		StartsAt:    time.Unix(100, 0),
+	}}
+	a2 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"a": "2"}, StartsAt: time.Unix(100, 0)}}
+	note := "known issue INC-1234"
+	require.NoError(t, annotations.Set(a1.Fingerprint(), a1.StartsAt, "alice", map[model.LabelName]*string{"note": &note}))
+
+	_, res, err := NewAnnotateStage(annotations).Exec(context.Background(), log.NewNopLogger(), a1, a2)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, model.LabelSet{"summary": "disk full", "note": "known issue INC-1234"}, res[0].Annotations)
+	require.Equal(t, model.LabelSet{"summary": "disk full"}, a1.Annotations)
+	require.True(t, a2 == res[1])
+}
+
 func TestSilenceStage(t *testing.T) {
 	silences, err := silence.New(silence.Options{})
 	if err != nil {
@@ -516,6 +865,22 @@ func TestSilenceStage(t *testing.T) {
 	}
 }
 
+func TestShardStage(t *testing.T) {
+	stage := NewShardStage(func(gkey string) bool { return gkey == "owned" })
+	alerts := []*types.Alert{{}}
+
+	_, res, err := stage.Exec(WithGroupKey(context.Background(), "owned"), log.NewNopLogger(), alerts...)
+	require.NoError(t, err)
+	require.Equal(t, alerts, res)
+
+	_, res, err = stage.Exec(WithGroupKey(context.Background(), "other"), log.NewNopLogger(), alerts...)
+	require.NoError(t, err)
+	require.Len(t, res, 0)
+
+	_, _, err = stage.Exec(context.Background(), log.NewNopLogger(), alerts...)
+	require.EqualError(t, err, "group key missing")
+}
+
 func TestInhibitStage(t *testing.T) {
 	// Mute all label sets that have a "mute" key.
 	muter := types.MuteFunc(func(lset model.LabelSet) bool {
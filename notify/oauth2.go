@@ -0,0 +1,86 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/smtp"
+	"strings"
+
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// oauth2TokenSource provides OAuth2 access tokens. Tokens requested with the
+// client credentials grant are shared with HTTP notifiers through the token
+// cache.
+type oauth2TokenSource struct {
+	conf  *config.OAuth2Config
+	cache *oauth2TokenCache
+}
+
+func newOAuth2TokenSource(c *config.OAuth2Config) *oauth2TokenSource {
+	return &oauth2TokenSource{conf: c, cache: oauth2Tokens}
+}
+
+// Token returns a valid access token.
+func (s *oauth2TokenSource) Token(ctx context.Context) (string, error) {
+	if s.conf.TokenFile != "" {
+		b, err := ioutil.ReadFile(s.conf.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read token file: %s", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+
+	// The token endpoint is requested like the endpoints of HTTP notifiers,
+	// without environment proxies.
+	c, err := newHTTPClient(context.Background(), &config.HTTPClientConfig{})
+	if err != nil {
+		return "", err
+	}
+	_, token, err := s.cache.get(ctx, &config.OAuth2{
+		ClientID:         s.conf.ClientID,
+		ClientSecret:     s.conf.ClientSecret,
+		ClientSecretFile: s.conf.ClientSecretFile,
+		TokenURL:         s.conf.TokenURL,
+		Scopes:           s.conf.Scopes,
+		EndpointParams:   s.conf.EndpointParams,
+	}, c.Transport)
+	return token, err
+}
+
+type xoauth2Auth struct {
+	username, token string
+}
+
+// XOAuth2Auth returns an smtp.Auth that implements the XOAUTH2 mechanism.
+func XOAuth2Auth(username, token string) smtp.Auth {
+	return &xoauth2Auth{username, token}
+}
+
+func (a *xoauth2Auth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+func (a *xoauth2Auth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		// The server sends an error description as challenge, an empty
+		// response makes it fail the authentication with an error code.
+		return []byte{}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+func TestOAuth2TokenSourceClientCredentials(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		user, pass, _ := r.BasicAuth()
+		require.Equal(t, "id", user)
+		require.Equal(t, "secret", pass)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		require.Equal(t, "a b", r.PostForm.Get("scope"))
+		require.Equal(t, "https://outlook.office365.com", r.PostForm.Get("resource"))
+		fmt.Fprintf(w, `{"access_token": "token-%d", "expires_in": 3600}`, requests)
+	}))
+	defer srv.Close()
+
+	f, err := ioutil.TempFile("", "secret")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("secret\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	now := time.Now()
+	s := newOAuth2TokenSource(&config.OAuth2Config{
+		ClientID:         "id",
+		ClientSecretFile: f.Name(),
+		TokenURL:         srv.URL,
+		Scopes:           []string{"a", "b"},
+		EndpointParams:   map[string]string{"resource": "https://outlook.office365.com"},
+	})
+	s.cache = newOAuth2TokenCache()
+	s.cache.now = func() time.Time { return now }
+
+	tok, err := s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-1", tok)
+
+	// The token is cached until shortly before it expires.
+	now = now.Add(59 * time.Minute)
+	tok, err = s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-1", tok)
+
+	now = now.Add(55 * time.Second)
+	tok, err = s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-2", tok)
+}
+
+func TestOAuth2TokenSourceFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("file-token\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s := newOAuth2TokenSource(&config.OAuth2Config{TokenFile: f.Name()})
+	tok, err := s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "file-token", tok)
+}
+
+func TestXOAuth2Auth(t *testing.T) {
+	mech, resp, err := XOAuth2Auth("alice@example.com", "token").Start(nil)
+	require.NoError(t, err)
+	require.Equal(t, "XOAUTH2", mech)
+	require.Equal(t, "user=alice@example.com\x01auth=Bearer token\x01\x01", string(resp))
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// PluginProtocolVersion is the version of the plugin protocol.
+const PluginProtocolVersion = "1"
+
+// PluginRequest is written as JSON to the standard input of a plugin for
+// every notification.
+type PluginRequest struct {
+	// The protocol version.
+	Version  string `json:"version"`
+	Plugin   string `json:"plugin"`
+	GroupKey string `json:"groupKey"`
+	// Settings are the plugin specific options of the configuration with
+	// their templates rendered.
+	Settings map[string]string `json:"settings,omitempty"`
+	// Payload is the rendered payload template of the configuration.
+	Payload string         `json:"payload,omitempty"`
+	Data    *template.Data `json:"data"`
+}
+
+// PluginResponse may be written as JSON to the standard output by a plugin.
+// A plugin exiting successfully without output has delivered the
+// notification. A plugin exiting with a non-zero status has failed and the
+// notification is retried.
+type PluginResponse struct {
+	// Error describes why the notification could not be delivered.
+	Error string `json:"error,omitempty"`
+	// Retry states whether delivering the notification may succeed later.
+	Retry bool `json:"retry,omitempty"`
+}
+
+// maxPluginStderr is the maximum number of bytes of the standard error of a
+// failed plugin included in the returned error.
+const maxPluginStderr = 512
+
+// Plugin implements a Notifier that delegates notifications to an external
+// executable.
+type Plugin struct {
+	conf   *config.PluginConfig
+	tmpl   *template.Template
+	logger log.Logger
+}
+
+// NewPlugin returns a new Plugin notifier.
+func NewPlugin(c *config.PluginConfig, t *template.Template, l log.Logger) *Plugin {
+	return &Plugin{conf: c, tmpl: t, logger: l}
+}
+
+// Notify implements the Notifier interface.
+func (n *Plugin) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	var err error
+	tmpl := tmplText(n.tmpl, data, &err)
+
+	req := &PluginRequest{
+		Version:  PluginProtocolVersion,
+		Plugin:   n.conf.Name,
+		GroupKey: key,
+		Payload:  tmpl(n.conf.Payload),
+		Data:     data,
+	}
+	if len(n.conf.Settings) > 0 {
+		req.Settings = make(map[string]string, len(n.conf.Settings))
+		for k, v := range n.conf.Settings {
+			req.Settings[k] = tmpl(v)
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var stdin bytes.Buffer
+	if err := json.NewEncoder(&stdin).Encode(req); err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(n.conf.Timeout))
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(n.conf.Command, n.conf.Args...)
+	cmd.Stdin = &stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	level.Debug(n.logger).Log("msg", "Running notification plugin", "plugin", n.conf.Name, "incident", key)
+
+	if err := runCommand(ctx, cmd); err != nil {
+		// The output of a killed plugin may still be written to.
+		if ctx.Err() != nil {
+			return true, fmt.Errorf("plugin %q failed: %s", n.conf.Name, err)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			if len(msg) > maxPluginStderr {
+				msg = msg[:maxPluginStderr]
+			}
+			err = fmt.Errorf("%s: %s", err, msg)
+		}
+		return true, fmt.Errorf("plugin %q failed: %s", n.conf.Name, err)
+	}
+
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return false, nil
+	}
+	var resp PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return false, fmt.Errorf("invalid response from plugin %q: %s", n.conf.Name, err)
+	}
+	if resp.Error != "" {
+		return resp.Retry, fmt.Errorf("plugin %q failed: %s", n.conf.Name, resp.Error)
+	}
+	return false, nil
+}
+
+// runCommand runs the command and kills it once the context is done.
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Waiting may block until children of the process holding its
+		// output open exit as well, so it is left to the goroutine.
+		cmd.Process.Kill()
+		return ctx.Err()
+	}
+}
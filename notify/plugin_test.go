@@ -0,0 +1,101 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func newTestPlugin(t *testing.T, script string) *Plugin {
+	conf := config.DefaultPluginConfig
+	conf.Name = "test"
+	conf.Command = "/bin/sh"
+	conf.Args = []string{"-c", script}
+	conf.Payload = `{{ .CommonLabels.alertname }}`
+	conf.Settings = map[string]string{"channel": "#{{ .CommonLabels.team }}"}
+
+	return NewPlugin(&conf, createTmpl(t), log.NewNopLogger())
+}
+
+func notifyPlugin(n *Plugin) (bool, error) {
+	ctx := WithGroupKey(context.Background(), "1")
+	return n.Notify(ctx, &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Down", "team": "infra"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	})
+}
+
+func TestPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "request.json")
+	retry, err := notifyPlugin(newTestPlugin(t, "cat > "+out))
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	b, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	var req PluginRequest
+	require.NoError(t, json.Unmarshal(b, &req))
+
+	require.Equal(t, PluginProtocolVersion, req.Version)
+	require.Equal(t, "test", req.Plugin)
+	require.Equal(t, "1", req.GroupKey)
+	require.Equal(t, "Down", req.Payload)
+	require.Equal(t, map[string]string{"channel": "#infra"}, req.Settings)
+	require.Equal(t, "firing", req.Data.Status)
+}
+
+func TestPluginResponse(t *testing.T) {
+	retry, err := notifyPlugin(newTestPlugin(t, `cat > /dev/null; echo '{"error":"quota exceeded","retry":true}'`))
+	require.EqualError(t, err, `plugin "test" failed: quota exceeded`)
+	require.True(t, retry)
+
+	retry, err = notifyPlugin(newTestPlugin(t, `cat > /dev/null; echo '{"error":"unknown channel"}'`))
+	require.EqualError(t, err, `plugin "test" failed: unknown channel`)
+	require.False(t, retry)
+}
+
+func TestPluginFailure(t *testing.T) {
+	retry, err := notifyPlugin(newTestPlugin(t, `cat > /dev/null; echo "connection refused" >&2; exit 3`))
+	require.EqualError(t, err, `plugin "test" failed: exit status 3: connection refused`)
+	require.True(t, retry)
+}
+
+func TestPluginTimeout(t *testing.T) {
+	n := newTestPlugin(t, "sleep 10")
+	n.conf.Timeout = config.DefaultPluginConfig.Timeout / 300
+
+	retry, err := notifyPlugin(n)
+	require.EqualError(t, err, `plugin "test" failed: context deadline exceeded`)
+	require.True(t, retry)
+}
@@ -0,0 +1,236 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+var numThrottledNotifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "notifications_throttled_total",
+	Help:      "The total number of notifications that exceeded the rate limit of their receiver.",
+}, []string{"receiver", "integration"})
+
+func init() {
+	prometheus.Register(numThrottledNotifications)
+}
+
+// RateLimiters keeps the rate limiters of receivers, and the alerts they
+// held back, across configuration reloads.
+type RateLimiters struct {
+	mtx      sync.Mutex
+	limiters map[string]*rateLimiter
+}
+
+// NewRateLimiters returns a new RateLimiters.
+func NewRateLimiters() *RateLimiters {
+	return &RateLimiters{limiters: map[string]*rateLimiter{}}
+}
+
+// update returns the rate limiters of the receivers by name. The limiter of
+// a receiver is kept if its limit is unchanged, the limiters of receivers
+// that are gone are dropped. A nil RateLimiters returns new limiters.
+func (r *RateLimiters) update(confs []*config.Receiver) map[string]*rateLimiter {
+	limiters := make(map[string]*rateLimiter, len(confs))
+	if r != nil {
+		r.mtx.Lock()
+		defer r.mtx.Unlock()
+	}
+	for _, rc := range confs {
+		if rc.RateLimit == nil {
+			continue
+		}
+		if r != nil {
+			if l, ok := r.limiters[rc.Name]; ok && l.sameLimit(rc.RateLimit) {
+				limiters[rc.Name] = l
+				continue
+			}
+		}
+		limiters[rc.Name] = newRateLimiter(rc.RateLimit)
+	}
+	if r != nil {
+		r.limiters = limiters
+	}
+	return limiters
+}
+
+// rateLimiter is a token bucket shared by all integrations of a receiver.
+type rateLimiter struct {
+	conf config.RateLimitConfig
+
+	mtx    sync.Mutex
+	rate   float64 // Tokens per second.
+	burst  float64
+	tokens float64
+	last   time.Time
+	// held are the alerts held back by the summarize overflow policy by
+	// integration and group.
+	held map[string]map[model.Fingerprint]*types.Alert
+}
+
+func newRateLimiter(c *config.RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		conf:   *c,
+		rate:   float64(c.Notifications) / time.Duration(c.Interval).Seconds(),
+		burst:  float64(c.Burst),
+		tokens: float64(c.Burst),
+		held:   map[string]map[model.Fingerprint]*types.Alert{},
+	}
+}
+
+// sameLimit returns true if the limiter enforces the same limit as the
+// given configuration.
+func (l *rateLimiter) sameLimit(c *config.RateLimitConfig) bool {
+	return l.conf.Notifications == c.Notifications && l.conf.Interval == c.Interval && l.conf.Burst == c.Burst
+}
+
+// hold keeps the alerts of a throttled notification with the given key
+// until its next notification.
+func (l *rateLimiter) hold(key string, alerts []*types.Alert) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	held, ok := l.held[key]
+	if !ok {
+		held = map[model.Fingerprint]*types.Alert{}
+		l.held[key] = held
+	}
+	for _, a := range alerts {
+		held[a.Fingerprint()] = a
+	}
+}
+
+// release returns the alerts with the held back alerts of the notification
+// with the given key appended, unless they are part of the alerts already.
+func (l *rateLimiter) release(key string, alerts []*types.Alert) []*types.Alert {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	held, ok := l.held[key]
+	if !ok {
+		return alerts
+	}
+	delete(l.held, key)
+	for _, a := range alerts {
+		delete(held, a.Fingerprint())
+	}
+	for _, a := range held {
+		alerts = append(alerts, a)
+	}
+	return alerts
+}
+
+// take consumes a token if one is available. Otherwise it returns the time
+// until the next token becomes available.
+func (l *rateLimiter) take(now time.Time) (bool, time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.refill(now)
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// consume removes n tokens consumed elsewhere, like by other peers sharing
+// the limit. The bucket may become negative and takes longer to refill.
+func (l *rateLimiter) consume(now time.Time, n float64) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.refill(now)
+	l.tokens -= n
+}
+
+func (l *rateLimiter) refill(now time.Time) {
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+	}
+	l.tokens = math.Min(l.burst, l.tokens)
+	l.last = now
+}
+
+// RateLimitStage limits the rate of notifications of a receiver. Depending
+// on the overflow policy, notifications exceeding the limit are dropped,
+// delayed, or summarized in the next notification of their group.
+type RateLimitStage struct {
+	limiter  *rateLimiter
+	overflow string
+	notifies *SetNotifiesStage
+	recv     *nflogpb.Receiver
+	now      func() time.Time
+}
+
+// NewRateLimitStage returns a new instance of a RateLimitStage. Throttled
+// notifications are recorded with the given stage.
+func NewRateLimitStage(l *rateLimiter, overflow string, notifies *SetNotifiesStage) *RateLimitStage {
+	return &RateLimitStage{
+		limiter:  l,
+		overflow: overflow,
+		notifies: notifies,
+		recv:     notifies.recv,
+		now:      time.Now,
+	}
+}
+
+// Exec implements the Stage interface.
+func (s *RateLimitStage) Exec(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+	gkey, ok := GroupKey(ctx)
+	if !ok {
+		return ctx, nil, fmt.Errorf("group key missing")
+	}
+	key := fmt.Sprintf("%s/%d/%s", s.recv.Integration, s.recv.Idx, gkey)
+
+	ok, wait := s.limiter.take(s.now())
+	if !ok {
+		numThrottledNotifications.WithLabelValues(s.recv.GroupName, s.recv.Integration).Inc()
+		level.Debug(l).Log("msg", "Notification rate limited", "receiver", s.recv.GroupName, "integration", s.recv.Integration, "overflow", s.overflow)
+	}
+	for !ok {
+		switch s.overflow {
+		case "queue":
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx, nil, ctx.Err()
+			}
+			ok, wait = s.limiter.take(s.now())
+		case "summarize":
+			s.limiter.hold(key, alerts)
+			fallthrough
+		default:
+			// Record the throttled notification as sent so that it is
+			// not retried before the repeat interval.
+			_, _, err := s.notifies.Exec(ctx, l, alerts...)
+			return ctx, nil, err
+		}
+	}
+	return ctx, s.limiter.release(key, alerts), nil
+}
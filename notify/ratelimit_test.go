@@ -0,0 +1,144 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/nflog/nflogpb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(&config.RateLimitConfig{
+		Notifications: 2,
+		Interval:      model.Duration(time.Minute),
+		Burst:         2,
+	})
+	now := time.Unix(0, 0)
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.take(now)
+		require.True(t, ok)
+	}
+	ok, wait := l.take(now)
+	require.False(t, ok)
+	require.Equal(t, 30*time.Second, wait)
+
+	ok, _ = l.take(now.Add(30 * time.Second))
+	require.True(t, ok)
+}
+
+func TestRateLimitStage(t *testing.T) {
+	var logged int
+	tnflog := &testNflog{
+		logFunc: func(r *nflogpb.Receiver, gkey string, firingAlerts, resolvedAlerts []uint64, expiry time.Duration) error {
+			logged++
+			return nil
+		},
+	}
+	newStage := func(overflow string) *RateLimitStage {
+		l := newRateLimiter(&config.RateLimitConfig{
+			Notifications: 1,
+			Interval:      model.Duration(time.Hour),
+			Burst:         1,
+		})
+		s := NewRateLimitStage(l, overflow, NewSetNotifiesStage(tnflog, &nflogpb.Receiver{GroupName: "test", Integration: "webhook"}, 0))
+		s.now = func() time.Time { return time.Unix(0, 0) }
+		return s
+	}
+
+	ctx := WithGroupKey(context.Background(), "1")
+	ctx = WithFiringAlerts(ctx, []uint64{1})
+	ctx = WithResolvedAlerts(ctx, []uint64{})
+
+	a1 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a1"}}}
+	a2 := &types.Alert{Alert: model.Alert{Labels: model.LabelSet{"alertname": "a2"}}}
+
+	// Throttled notifications are dropped but recorded in the notification log.
+	s := newStage("drop")
+	_, res, err := s.Exec(ctx, log.NewNopLogger(), a1)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{a1}, res)
+
+	_, res, err = s.Exec(ctx, log.NewNopLogger(), a2)
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.Equal(t, 1, logged)
+
+	// Throttled alerts are attached to the next notification of their group.
+	s = newStage("summarize")
+	_, res, err = s.Exec(ctx, log.NewNopLogger(), a1)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{a1}, res)
+
+	_, res, err = s.Exec(ctx, log.NewNopLogger(), a2)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	s.now = func() time.Time { return time.Unix(0, 0).Add(time.Hour) }
+	_, res, err = s.Exec(WithGroupKey(ctx, "2"), log.NewNopLogger(), a1)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{a1}, res)
+
+	s.now = func() time.Time { return time.Unix(0, 0).Add(2 * time.Hour) }
+	_, res, err = s.Exec(ctx, log.NewNopLogger(), a1)
+	require.NoError(t, err)
+	require.Equal(t, []*types.Alert{a1, a2}, res)
+
+	// Queued notifications give up when the context is done.
+	s = newStage("queue")
+	_, _, err = s.Exec(ctx, log.NewNopLogger(), a1)
+	require.NoError(t, err)
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, res, err = s.Exec(cctx, log.NewNopLogger(), a2)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.Nil(t, res)
+}
+
+func TestRateLimitersUpdate(t *testing.T) {
+	limit := &config.RateLimitConfig{
+		Notifications: 1,
+		Interval:      model.Duration(time.Hour),
+		Burst:         1,
+	}
+	r := NewRateLimiters()
+	limiters := r.update([]*config.Receiver{{Name: "a", RateLimit: limit}, {Name: "b"}})
+	require.Len(t, limiters, 1)
+	ok, _ := limiters["a"].take(time.Unix(0, 0))
+	require.True(t, ok)
+
+	// The state of the limiter is kept across reloads with the same limit.
+	changed := *limit
+	changed.Overflow = "summarize"
+	reloaded := r.update([]*config.Receiver{{Name: "a", RateLimit: &changed}})
+	require.True(t, limiters["a"] == reloaded["a"])
+	ok, _ = reloaded["a"].take(time.Unix(0, 0))
+	require.False(t, ok)
+
+	changed.Burst = 2
+	reloaded = r.update([]*config.Receiver{{Name: "a", RateLimit: &changed}})
+	require.False(t, limiters["a"] == reloaded["a"])
+	ok, _ = reloaded["a"].take(time.Unix(0, 0))
+	require.True(t, ok)
+}
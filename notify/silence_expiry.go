@@ -0,0 +1,140 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// SilenceExpiringAlertName is the alert name of notifications about
+// silences that are about to expire.
+const SilenceExpiringAlertName = "SilenceExpiring"
+
+// SilenceExpiryNotifier notifies a receiver about silences that are about
+// to expire. The notifications are sent through the pipeline of the
+// receiver as an alert named SilenceExpiring with the ID of the silence
+// in the silence_id label.
+type SilenceExpiryNotifier struct {
+	conf        *config.SilenceExpiryConfig
+	receivers   map[string]struct{}
+	pipeline    Stage
+	externalURL string
+	logger      log.Logger
+	now         func() time.Time
+}
+
+// NewSilenceExpiryNotifier returns a new SilenceExpiryNotifier sending
+// notifications through the pipeline built for the receivers.
+func NewSilenceExpiryNotifier(conf *config.SilenceExpiryConfig, receivers []*config.Receiver, pipeline Stage, externalURL string, l log.Logger) *SilenceExpiryNotifier {
+	names := make(map[string]struct{}, len(receivers))
+	for _, rc := range receivers {
+		names[rc.Name] = struct{}{}
+	}
+	return &SilenceExpiryNotifier{
+		conf:        conf,
+		receivers:   names,
+		pipeline:    pipeline,
+		externalURL: externalURL,
+		logger:      l,
+		now:         time.Now,
+	}
+}
+
+// Notify sends a notification about the silence. It does not block.
+func (n *SilenceExpiryNotifier) Notify(sil *pb.Silence) {
+	receiver := n.conf.Receiver
+	if _, ok := n.receivers[sil.CreatedBy]; ok && n.conf.NotifyCreator {
+		receiver = sil.CreatedBy
+	}
+	a := n.alert(sil)
+	now := n.now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(n.conf.Before))
+	ctx = WithReceiverName(ctx, receiver)
+	ctx = WithGroupKey(ctx, fmt.Sprintf("{}:{silence_id=%q}", sil.Id))
+	ctx = WithGroupLabels(ctx, a.Labels)
+	ctx = WithNow(ctx, now)
+	ctx = WithRepeatInterval(ctx, time.Duration(n.conf.Before))
+
+	l := log.With(n.logger, "silence", sil.Id, "receiver", receiver)
+	go func() {
+		defer cancel()
+		if _, _, err := n.pipeline.Exec(ctx, l, a); err != nil {
+			level.Error(l).Log("msg", "Notifying about expiring silence failed", "err", err)
+		}
+	}()
+}
+
+// alert returns the alert notified about the silence.
+func (n *SilenceExpiryNotifier) alert(sil *pb.Silence) *types.Alert {
+	matchers := make([]string, 0, len(sil.Matchers))
+	for _, m := range sil.Matchers {
+		var op string
+		switch m.Type {
+		case pb.Matcher_REGEXP:
+			op = "=~"
+		case pb.Matcher_ABSENT:
+			matchers = append(matchers, fmt.Sprintf("absent(%s)", m.Name))
+			continue
+		case pb.Matcher_PRESENT:
+			matchers = append(matchers, fmt.Sprintf("present(%s)", m.Name))
+			continue
+		case pb.Matcher_LESS_THAN:
+			op = "<"
+		case pb.Matcher_LESS_EQUAL:
+			op = "<="
+		case pb.Matcher_GREATER_THAN:
+			op = ">"
+		case pb.Matcher_GREATER_EQUAL:
+			op = ">="
+		default:
+			op = "="
+		}
+		matchers = append(matchers, fmt.Sprintf("%s%s%q", m.Name, op, m.Pattern))
+	}
+	end := sil.EndsAt.UTC().Format(time.RFC3339)
+
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: SilenceExpiringAlertName,
+				"silence_id":         model.LabelValue(sil.Id),
+			},
+			Annotations: model.LabelSet{
+				"summary": model.LabelValue(fmt.Sprintf("Silence %s expires at %s", sil.Id, end)),
+				"description": model.LabelValue(fmt.Sprintf(
+					"The silence {%s} created by %s expires at %s: %s",
+					strings.Join(matchers, ", "), sil.CreatedBy, end, sil.Comment,
+				)),
+				"created_by": model.LabelValue(sil.CreatedBy),
+				"ends_at":    model.LabelValue(end),
+			},
+			StartsAt:     n.now(),
+			EndsAt:       sil.EndsAt,
+			GeneratorURL: strings.TrimRight(n.externalURL, "/") + "/#/silences/" + sil.Id,
+		},
+		UpdatedAt: n.now(),
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSilenceExpiryNotifier(t *testing.T) {
+	type notification struct {
+		receiver string
+		groupKey string
+		alert    *types.Alert
+	}
+	notifications := make(chan notification, 1)
+	pipeline := StageFunc(func(ctx context.Context, l log.Logger, alerts ...*types.Alert) (context.Context, []*types.Alert, error) {
+		recv, _ := ReceiverName(ctx)
+		key, _ := GroupKey(ctx)
+		notifications <- notification{receiver: recv, groupKey: key, alert: alerts[0]}
+		return ctx, alerts, nil
+	})
+
+	conf := &config.SilenceExpiryConfig{
+		Receiver:      "ops",
+		Before:        model.Duration(15 * time.Minute),
+		NotifyCreator: true,
+	}
+	receivers := []*config.Receiver{{Name: "ops"}, {Name: "alice"}}
+	n := NewSilenceExpiryNotifier(conf, receivers, pipeline, "http://am.example.com/", log.NewNopLogger())
+
+	endsAt := time.Date(2018, 3, 9, 12, 0, 0, 0, time.UTC)
+	sil := &pb.Silence{
+		Id:        "abc",
+		Matchers:  []*pb.Matcher{{Name: "job", Pattern: "node.*", Type: pb.Matcher_REGEXP}},
+		EndsAt:    endsAt,
+		CreatedBy: "bob",
+		Comment:   "maintenance",
+	}
+
+	n.Notify(sil)
+	res := <-notifications
+	require.Equal(t, "ops", res.receiver)
+	require.Equal(t, `{}:{silence_id="abc"}`, res.groupKey)
+	require.Equal(t, model.LabelSet{"alertname": "SilenceExpiring", "silence_id": "abc"}, res.alert.Labels)
+	require.Equal(t, `The silence {job=~"node.*"} created by bob expires at 2018-03-09T12:00:00Z: maintenance`, string(res.alert.Annotations["description"]))
+	require.Equal(t, "http://am.example.com/#/silences/abc", res.alert.GeneratorURL)
+	require.Equal(t, endsAt, res.alert.EndsAt)
+
+	// The creator is notified if it is a receiver.
+	sil.CreatedBy = "alice"
+	n.Notify(sil)
+	res = <-notifications
+	require.Equal(t, "alice", res.receiver)
+}
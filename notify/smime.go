@@ -0,0 +1,260 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"mime/multipart"
+	"sort"
+	"time"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+var (
+	oidData                   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidDigestAlgorithmSHA256  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidEncryptionRSA          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSignatureECDSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// The following types implement the parts of the Cryptographic Message
+// Syntax (RFC 5652) needed for detached signatures.
+
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type cmsEncapsulatedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type cmsSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      cmsEncapsulatedContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      []cmsSignerInfo `asn1:"set"`
+}
+
+type cmsIssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type cmsSignerInfo struct {
+	Version            int
+	SID                cmsIssuerAndSerialNumber
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttributes   asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// smimeSigner signs emails with a certificate and its private key.
+type smimeSigner struct {
+	key   crypto.Signer
+	leaf  *x509.Certificate
+	chain [][]byte
+	now   func() time.Time
+}
+
+func newSMIMESigner(c *config.SMIMEConfig) (*smimeSigner, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load S/MIME certificate: %s", err)
+	}
+	key, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported S/MIME private key type %T", cert.PrivateKey)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse S/MIME certificate: %s", err)
+	}
+	return &smimeSigner{
+		key:   key,
+		leaf:  leaf,
+		chain: cert.Certificate,
+		now:   time.Now,
+	}, nil
+}
+
+// setOf returns the DER encoding of a SET OF the given encoded elements.
+// The elements are sorted as required by DER.
+func setOf(class, tag int, elems ...[]byte) asn1.RawValue {
+	sort.Slice(elems, func(i, j int) bool {
+		return bytes.Compare(elems[i], elems[j]) < 0
+	})
+	return asn1.RawValue{
+		Class:      class,
+		Tag:        tag,
+		IsCompound: true,
+		Bytes:      bytes.Join(elems, nil),
+	}
+}
+
+func newAttribute(typ asn1.ObjectIdentifier, v interface{}) ([]byte, error) {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(cmsAttribute{
+		Type:   typ,
+		Values: setOf(asn1.ClassUniversal, asn1.TagSet, b),
+	})
+}
+
+// sign returns a detached CMS signature of the content in DER format.
+func (s *smimeSigner) sign(content []byte) ([]byte, error) {
+	digest := sha256.Sum256(content)
+
+	var attrs [][]byte
+	for _, a := range []struct {
+		typ asn1.ObjectIdentifier
+		v   interface{}
+	}{
+		{oidAttributeContentType, oidData},
+		{oidAttributeMessageDigest, digest[:]},
+		{oidAttributeSigningTime, s.now().UTC()},
+	} {
+		b, err := newAttribute(a.typ, a.v)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, b)
+	}
+
+	// The signature covers the DER encoding of the signed attributes with
+	// the SET OF tag instead of their implicit context tag.
+	signed := setOf(asn1.ClassUniversal, asn1.TagSet, attrs...)
+	b, err := asn1.Marshal(signed)
+	if err != nil {
+		return nil, err
+	}
+	attrsDigest := sha256.Sum256(b)
+
+	sigAlg := pkix.AlgorithmIdentifier{Algorithm: oidEncryptionRSA}
+	switch s.key.Public().(type) {
+	case *rsa.PublicKey:
+	case *ecdsa.PublicKey:
+		sigAlg = pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSASHA256}
+	default:
+		return nil, fmt.Errorf("unsupported S/MIME key type %T", s.key.Public())
+	}
+	sig, err := s.key.Sign(rand.Reader, attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: oidDigestAlgorithmSHA256}
+	sd, err := asn1.Marshal(cmsSignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlg},
+		ContentInfo:      cmsEncapsulatedContentInfo{ContentType: oidData},
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      bytes.Join(s.chain, nil),
+		},
+		SignerInfos: []cmsSignerInfo{{
+			Version: 1,
+			SID: cmsIssuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: s.leaf.RawIssuer},
+				SerialNumber: s.leaf.SerialNumber,
+			},
+			DigestAlgorithm:    digestAlg,
+			SignedAttributes:   setOf(asn1.ClassContextSpecific, 0, attrs...),
+			SignatureAlgorithm: sigAlg,
+			Signature:          sig,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(cmsContentInfo{
+		ContentType: oidSignedData,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      sd,
+		},
+	})
+}
+
+// canonicalize converts all line endings to CRLF as required for signed
+// MIME entities.
+func canonicalize(b []byte) []byte {
+	b = bytes.Replace(b, []byte("\r\n"), []byte("\n"), -1)
+	return bytes.Replace(b, []byte("\n"), []byte("\r\n"), -1)
+}
+
+// wrap returns a multipart/signed MIME entity containing the given entity
+// and its signature.
+func (s *smimeSigner) wrap(entity []byte) ([]byte, error) {
+	entity = canonicalize(entity)
+
+	sig, err := s.sign(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		buf      bytes.Buffer
+		boundary = multipart.NewWriter(&buf).Boundary()
+	)
+	fmt.Fprintf(&buf, "Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; micalg=sha-256; boundary=%s\r\n\r\n", boundary)
+
+	// The signed entity brings its own headers.
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	buf.Write(entity)
+
+	fmt.Fprintf(&buf, "\r\n--%s\r\n", boundary)
+	fmt.Fprintf(&buf, "Content-Type: application/pkcs7-signature; name=smime.p7s\r\n")
+	fmt.Fprintf(&buf, "Content-Transfer-Encoding: base64\r\n")
+	fmt.Fprintf(&buf, "Content-Disposition: attachment; filename=smime.p7s\r\n\r\n")
+	enc := base64.StdEncoding.EncodeToString(sig)
+	for len(enc) > 76 {
+		fmt.Fprintf(&buf, "%s\r\n", enc[:76])
+		enc = enc[76:]
+	}
+	fmt.Fprintf(&buf, "%s\r\n", enc)
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSMIMESigner(t *testing.T) *smimeSigner {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "alertmanager@example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &smimeSigner{
+		key:   key,
+		leaf:  leaf,
+		chain: [][]byte{der},
+		now:   time.Now,
+	}
+}
+
+func TestSMIMESign(t *testing.T) {
+	s := newTestSMIMESigner(t)
+	content := []byte("Content-Type: text/plain\r\n\r\nhello\r\n")
+
+	b, err := s.sign(content)
+	require.NoError(t, err)
+
+	var ci cmsContentInfo
+	rest, err := asn1.Unmarshal(b, &ci)
+	require.NoError(t, err)
+	require.Len(t, rest, 0)
+	require.True(t, ci.ContentType.Equal(oidSignedData))
+
+	var sd cmsSignedData
+	_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+	require.NoError(t, err)
+	require.Len(t, sd.SignerInfos, 1)
+
+	si := sd.SignerInfos[0]
+	require.Equal(t, s.leaf.SerialNumber, si.SID.SerialNumber)
+	require.True(t, bytes.Equal(sd.Certificates.Bytes, s.leaf.Raw))
+
+	// The signature is computed over the attributes encoded as SET OF.
+	attrs := append([]byte(nil), si.SignedAttributes.FullBytes...)
+	attrs[0] = 0x31
+	require.NoError(t, s.leaf.CheckSignature(x509.SHA256WithRSA, attrs, si.Signature))
+}
+
+func TestSMIMEWrap(t *testing.T) {
+	s := newTestSMIMESigner(t)
+
+	b, err := s.wrap([]byte("Content-Type: text/plain\n\nhello\n"))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; micalg=sha-256;")
+	require.Contains(t, string(b), "Content-Type: text/plain\r\n\r\nhello\r\n")
+	require.Contains(t, string(b), "Content-Disposition: attachment; filename=smime.p7s")
+}
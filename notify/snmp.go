@@ -0,0 +1,370 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// BER tags used in SNMP messages.
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berOID         = 0x06
+	berSequence    = 0x30
+	berTimeTicks   = 0x43
+	berTrapV2PDU   = 0xa7
+)
+
+const (
+	oidSysUpTime = "1.3.6.1.2.1.1.3.0"
+	oidTrapOID   = "1.3.6.1.6.3.1.1.4.1.0"
+
+	// snmpDefaultEngineID is the engine ID used for SNMPv3 if none is
+	// configured. It is built from the enterprise number 32473 and the
+	// text "alertmanager" as described in RFC 3411.
+	snmpDefaultEngineID = "80007ed904616c6572746d616e61676572"
+)
+
+// snmpStart is the time SNMP uptimes are measured from.
+var snmpStart = time.Now()
+
+// SNMP implements a Notifier sending SNMP traps.
+type SNMP struct {
+	conf   *config.SNMPConfig
+	tmpl   *template.Template
+	logger log.Logger
+	now    func() time.Time
+}
+
+// NewSNMP returns a new SNMP notifier.
+func NewSNMP(c *config.SNMPConfig, t *template.Template, l log.Logger) *SNMP {
+	return &SNMP{conf: c, tmpl: t, logger: l, now: time.Now}
+}
+
+// Notify implements the Notifier interface.
+func (n *SNMP) Notify(ctx context.Context, as ...*types.Alert) (bool, error) {
+	key, ok := GroupKey(ctx)
+	if !ok {
+		return false, fmt.Errorf("group key missing")
+	}
+	data := tmplData(ctx, n.tmpl, n.logger, as...)
+
+	pdu, err := n.pdu(key, data)
+	if err != nil {
+		return false, err
+	}
+	var msg []byte
+	if n.conf.Version == "3" {
+		msg, err = n.messageV3(pdu)
+	} else {
+		msg = berSeq(berInt(1), berTLV(berOctetString, []byte(n.conf.Community)), pdu)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	level.Debug(n.logger).Log("msg", "Sending SNMP trap", "incident", key)
+
+	addr := n.conf.Address
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "162")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return true, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(msg); err != nil {
+		return true, err
+	}
+	return false, nil
+}
+
+// pdu returns the encoded SNMPv2-Trap-PDU for the notification.
+func (n *SNMP) pdu(key string, data *template.Data) ([]byte, error) {
+	trapOID := n.conf.EnterpriseOID + ".0.1"
+	if data.Status == string(model.AlertResolved) {
+		trapOID = n.conf.EnterpriseOID + ".0.2"
+	}
+
+	varbinds := n.conf.Varbinds
+	if len(varbinds) == 0 {
+		varbinds = []*config.SNMPVarbind{
+			{OID: n.conf.EnterpriseOID + ".1.1", Type: "string", Value: `{{ .Status }}`},
+			{OID: n.conf.EnterpriseOID + ".1.2", Type: "string", Value: key},
+			{OID: n.conf.EnterpriseOID + ".1.3", Type: "string", Value: `{{ template "__subject" . }}`},
+			{OID: n.conf.EnterpriseOID + ".1.4", Type: "string", Value: `{{ template "__alertmanagerURL" . }}`},
+		}
+	}
+
+	uptime := n.now().Sub(snmpStart) / (10 * time.Millisecond)
+	sysUpTime, err := berVarbind(oidSysUpTime, berUint(berTimeTicks, uint32(uptime)))
+	if err != nil {
+		return nil, err
+	}
+	oid, err := berObjectID(trapOID)
+	if err != nil {
+		return nil, err
+	}
+	trap, err := berVarbind(oidTrapOID, oid)
+	if err != nil {
+		return nil, err
+	}
+	vbs := [][]byte{sysUpTime, trap}
+
+	var tmplErr error
+	tmpl := tmplText(n.tmpl, data, &tmplErr)
+	for _, vb := range varbinds {
+		v := tmpl(vb.Value)
+		if tmplErr != nil {
+			return nil, tmplErr
+		}
+		var value []byte
+		switch vb.Type {
+		case "integer":
+			i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer value for SNMP varbind %s: %s", vb.OID, err)
+			}
+			value = berInt(i)
+		default:
+			value = berTLV(berOctetString, []byte(v))
+		}
+		b, err := berVarbind(vb.OID, value)
+		if err != nil {
+			return nil, err
+		}
+		vbs = append(vbs, b)
+	}
+
+	reqID, err := rand.Int(rand.Reader, big.NewInt(1<<31-1))
+	if err != nil {
+		return nil, err
+	}
+	return berTLV(berTrapV2PDU, bytes.Join([][]byte{
+		berInt(reqID.Int64()),
+		berInt(0),
+		berInt(0),
+		berSeq(vbs...),
+	}, nil)), nil
+}
+
+// messageV3 returns the SNMPv3 message for the PDU secured with the
+// user-based security model, RFC 3414.
+func (n *SNMP) messageV3(pdu []byte) ([]byte, error) {
+	engineIDHex := n.conf.EngineID
+	if engineIDHex == "" {
+		engineIDHex = snmpDefaultEngineID
+	}
+	engineID, err := hex.DecodeString(engineIDHex)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		flags     byte
+		boots     = uint32(1)
+		engTime   = uint32(n.now().Sub(snmpStart) / time.Second)
+		authParam []byte
+		privParam []byte
+		hashFn    func() hash.Hash
+	)
+	scoped := berSeq(berTLV(berOctetString, engineID), berTLV(berOctetString, nil), pdu)
+
+	switch n.conf.AuthProtocol {
+	case "MD5":
+		hashFn = md5.New
+	case "SHA":
+		hashFn = sha1.New
+	}
+	if hashFn != nil {
+		flags |= 0x01
+		authParam = make([]byte, 12)
+	}
+	if n.conf.PrivProtocol == "AES" {
+		flags |= 0x02
+		privParam = make([]byte, 8)
+		if _, err := rand.Read(privParam); err != nil {
+			return nil, err
+		}
+		key := snmpLocalizedKey(hashFn, string(n.conf.PrivPassword), engineID)[:16]
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		iv := make([]byte, 16)
+		binary.BigEndian.PutUint32(iv[0:], boots)
+		binary.BigEndian.PutUint32(iv[4:], engTime)
+		copy(iv[8:], privParam)
+
+		enc := make([]byte, len(scoped))
+		cipher.NewCFBEncrypter(block, iv).XORKeyStream(enc, scoped)
+		scoped = berTLV(berOctetString, enc)
+	}
+
+	usm := berSeq(
+		berTLV(berOctetString, engineID),
+		berInt(int64(boots)),
+		berInt(int64(engTime)),
+		berTLV(berOctetString, []byte(n.conf.User)),
+		berTLV(berOctetString, authParam),
+		berTLV(berOctetString, privParam),
+	)
+	msgID, err := rand.Int(rand.Reader, big.NewInt(1<<31-1))
+	if err != nil {
+		return nil, err
+	}
+	header := berSeq(
+		berInt(msgID.Int64()),
+		berInt(65507),
+		berTLV(berOctetString, []byte{flags}),
+		berInt(3),
+	)
+	msg := berSeq(berInt(3), header, berTLV(berOctetString, usm), scoped)
+
+	if hashFn != nil {
+		// The authentication parameters are computed over the message with
+		// the parameters zeroed and then filled in.
+		placeholder := berTLV(berOctetString, authParam)
+		i := bytes.Index(msg, placeholder)
+		if i < 0 {
+			return nil, fmt.Errorf("authentication parameters not found in SNMP message")
+		}
+		mac := hmac.New(hashFn, snmpLocalizedKey(hashFn, string(n.conf.AuthPassword), engineID))
+		mac.Write(msg)
+		copy(msg[i+2:], mac.Sum(nil)[:12])
+	}
+	return msg, nil
+}
+
+// snmpLocalizedKey derives the key for a password and engine as described
+// in RFC 3414, appendix A.2.
+func snmpLocalizedKey(hashFn func() hash.Hash, password string, engineID []byte) []byte {
+	h := hashFn()
+	pw := []byte(password)
+	for n := 0; n < 1<<20; n += len(pw) {
+		if rest := 1<<20 - n; rest < len(pw) {
+			pw = pw[:rest]
+		}
+		h.Write(pw)
+	}
+	ku := h.Sum(nil)
+
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// berTLV encodes a BER type-length-value triple.
+func berTLV(tag byte, content []byte) []byte {
+	b := []byte{tag}
+	switch l := len(content); {
+	case l < 0x80:
+		b = append(b, byte(l))
+	case l <= 0xff:
+		b = append(b, 0x81, byte(l))
+	default:
+		b = append(b, 0x82, byte(l>>8), byte(l))
+	}
+	return append(b, content...)
+}
+
+func berSeq(elems ...[]byte) []byte {
+	return berTLV(berSequence, bytes.Join(elems, nil))
+}
+
+// berInt encodes a signed integer in its shortest two's complement form.
+func berInt(v int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	for len(b) > 1 && ((b[0] == 0 && b[1]&0x80 == 0) || (b[0] == 0xff && b[1]&0x80 != 0)) {
+		b = b[1:]
+	}
+	return berTLV(berInteger, b)
+}
+
+// berUint encodes an unsigned application type such as TimeTicks.
+func berUint(tag byte, v uint32) []byte {
+	b := make([]byte, 5)
+	binary.BigEndian.PutUint32(b[1:], v)
+	for len(b) > 1 && b[0] == 0 && b[1]&0x80 == 0 {
+		b = b[1:]
+	}
+	return berTLV(tag, b)
+}
+
+func berObjectID(oid string) ([]byte, error) {
+	parts := strings.Split(oid, ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+	ids := make([]uint64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", oid)
+		}
+		ids[i] = v
+	}
+	if ids[0] > 2 || (ids[0] < 2 && ids[1] > 39) {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+
+	var b []byte
+	for _, v := range append([]uint64{ids[0]*40 + ids[1]}, ids[2:]...) {
+		enc := []byte{byte(v & 0x7f)}
+		for v >>= 7; v > 0; v >>= 7 {
+			enc = append([]byte{byte(v&0x7f) | 0x80}, enc...)
+		}
+		b = append(b, enc...)
+	}
+	return berTLV(berOID, b), nil
+}
+
+func berVarbind(oid string, value []byte) ([]byte, error) {
+	o, err := berObjectID(oid)
+	if err != nil {
+		return nil, err
+	}
+	return berSeq(o, value), nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/types"
+)
+
+type snmpVarbind struct {
+	OID   asn1.ObjectIdentifier
+	Value asn1.RawValue
+}
+
+type snmpPDU struct {
+	RequestID   int
+	ErrorStatus int
+	ErrorIndex  int
+	Varbinds    []snmpVarbind
+}
+
+func parseSNMPPDU(t *testing.T, raw asn1.RawValue) snmpPDU {
+	require.Equal(t, asn1.ClassContextSpecific, raw.Class)
+	require.Equal(t, 7, raw.Tag)
+
+	var pdu snmpPDU
+	raw.Class, raw.Tag = asn1.ClassUniversal, asn1.TagSequence
+	raw.FullBytes = nil
+	b, err := asn1.Marshal(raw)
+	require.NoError(t, err)
+	_, err = asn1.Unmarshal(b, &pdu)
+	require.NoError(t, err)
+	return pdu
+}
+
+func sendSNMPTrap(t *testing.T, conf *config.SNMPConfig) []byte {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	conf.Address = pc.LocalAddr().String()
+	notifier := NewSNMP(conf, createTmpl(t), log.NewNopLogger())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = WithGroupKey(ctx, "1")
+
+	alert := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "Down", "priority": "2"},
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+	retry, err := notifier.Notify(ctx, alert)
+	require.NoError(t, err)
+	require.False(t, retry)
+
+	b := make([]byte, 4096)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(b)
+	require.NoError(t, err)
+	return b[:n]
+}
+
+func TestSNMPv2c(t *testing.T) {
+	conf := config.DefaultSNMPConfig
+	conf.EnterpriseOID = "1.3.6.1.4.1.32473"
+	conf.Varbinds = []*config.SNMPVarbind{
+		{OID: "1.3.6.1.4.1.32473.1.1", Type: "string", Value: "{{ .CommonLabels.alertname }}"},
+		{OID: "1.3.6.1.4.1.32473.1.2", Type: "integer", Value: "{{ .CommonLabels.priority }}"},
+	}
+
+	var msg struct {
+		Version   int
+		Community []byte
+		PDU       asn1.RawValue
+	}
+	_, err := asn1.Unmarshal(sendSNMPTrap(t, &conf), &msg)
+	require.NoError(t, err)
+	require.Equal(t, 1, msg.Version)
+	require.Equal(t, "public", string(msg.Community))
+
+	pdu := parseSNMPPDU(t, msg.PDU)
+	require.Len(t, pdu.Varbinds, 4)
+	require.Equal(t, "1.3.6.1.2.1.1.3.0", pdu.Varbinds[0].OID.String())
+	require.Equal(t, 3, pdu.Varbinds[0].Value.Tag)
+
+	var trapOID asn1.ObjectIdentifier
+	_, err = asn1.Unmarshal(pdu.Varbinds[1].Value.FullBytes, &trapOID)
+	require.NoError(t, err)
+	require.Equal(t, "1.3.6.1.4.1.32473.0.1", trapOID.String())
+
+	require.Equal(t, "1.3.6.1.4.1.32473.1.1", pdu.Varbinds[2].OID.String())
+	require.Equal(t, "Down", string(pdu.Varbinds[2].Value.Bytes))
+
+	var priority int
+	_, err = asn1.Unmarshal(pdu.Varbinds[3].Value.FullBytes, &priority)
+	require.NoError(t, err)
+	require.Equal(t, 2, priority)
+}
+
+func TestSNMPv3(t *testing.T) {
+	conf := config.DefaultSNMPConfig
+	conf.Version = "3"
+	conf.EnterpriseOID = "1.3.6.1.4.1.32473"
+	conf.User = "alertmanager"
+	conf.AuthProtocol = "SHA"
+	conf.AuthPassword = "authpassword"
+	conf.PrivProtocol = "AES"
+	conf.PrivPassword = "privpassword"
+
+	b := sendSNMPTrap(t, &conf)
+
+	var msg struct {
+		Version  int
+		Header   asn1.RawValue
+		Security []byte
+		Scoped   []byte
+	}
+	_, err := asn1.Unmarshal(b, &msg)
+	require.NoError(t, err)
+	require.Equal(t, 3, msg.Version)
+
+	var usm struct {
+		EngineID  []byte
+		Boots     int
+		Time      int
+		User      []byte
+		AuthParam []byte
+		PrivParam []byte
+	}
+	_, err = asn1.Unmarshal(msg.Security, &usm)
+	require.NoError(t, err)
+	require.Equal(t, snmpDefaultEngineID, hex.EncodeToString(usm.EngineID))
+	require.Equal(t, "alertmanager", string(usm.User))
+
+	// Verify the message authentication code.
+	i := bytes.Index(b, usm.AuthParam)
+	zeroed := append([]byte(nil), b...)
+	copy(zeroed[i:], make([]byte, 12))
+	mac := hmac.New(sha1.New, snmpLocalizedKey(sha1.New, "authpassword", usm.EngineID))
+	mac.Write(zeroed)
+	require.Equal(t, mac.Sum(nil)[:12], usm.AuthParam)
+
+	// Decrypt the scoped PDU.
+	block, err := aes.NewCipher(snmpLocalizedKey(sha1.New, "privpassword", usm.EngineID)[:16])
+	require.NoError(t, err)
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv[0:], uint32(usm.Boots))
+	binary.BigEndian.PutUint32(iv[4:], uint32(usm.Time))
+	copy(iv[8:], usm.PrivParam)
+	plain := make([]byte, len(msg.Scoped))
+	cipher.NewCFBDecrypter(block, iv).XORKeyStream(plain, msg.Scoped)
+
+	var scoped struct {
+		EngineID []byte
+		Context  []byte
+		PDU      asn1.RawValue
+	}
+	_, err = asn1.Unmarshal(plain, &scoped)
+	require.NoError(t, err)
+
+	pdu := parseSNMPPDU(t, scoped.PDU)
+	require.Len(t, pdu.Varbinds, 6)
+	require.Equal(t, "1.3.6.1.4.1.32473.1.1", pdu.Varbinds[2].OID.String())
+	require.Equal(t, "firing", string(pdu.Varbinds[2].Value.Bytes))
+}
+
+func TestSNMPLocalizedKey(t *testing.T) {
+	// Test vectors from RFC 3414, appendix A.3.
+	engineID, _ := hex.DecodeString("000000000000000000000002")
+
+	require.Equal(t, "526f5eed9fcce26f8964c2930787d82b",
+		hex.EncodeToString(snmpLocalizedKey(md5.New, "maplesyrup", engineID)))
+	require.Equal(t, "6695febc9288e36282235fc7151f128497b38f3f",
+		hex.EncodeToString(snmpLocalizedKey(sha1.New, "maplesyrup", engineID)))
+}
@@ -0,0 +1,318 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// voiceCalls tracks voice calls awaiting acknowledgement.
+var voiceCalls = NewVoiceCalls()
+
+// VoiceCallState returns the state of the voice calls awaiting
+// acknowledgement. It is shared with the cluster so that any member can
+// receive the callback of a call placed by another member.
+func VoiceCallState() *VoiceCalls {
+	return voiceCalls
+}
+
+// voiceCall is a voice call awaiting acknowledgement.
+type voiceCall struct {
+	ID       string `json:"id"`
+	GroupKey string `json:"groupKey"`
+	Receiver string `json:"receiver"`
+	Provider string `json:"provider"`
+	// CallbackURL is the URL the provider reports the keypress to. Twilio
+	// signs its requests with it.
+	CallbackURL string    `json:"callbackUrl"`
+	Digits      string    `json:"digits,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// VoiceCalls holds the voice calls awaiting acknowledgement and notifies
+// the members that placed them about the keys pressed by the callee.
+type VoiceCalls struct {
+	now func() time.Time
+
+	mtx       sync.Mutex
+	calls     map[string]*voiceCall
+	waiters   map[string]chan string
+	broadcast func([]byte)
+}
+
+// NewVoiceCalls returns a new VoiceCalls object.
+func NewVoiceCalls() *VoiceCalls {
+	return &VoiceCalls{
+		now:       utcNow,
+		calls:     map[string]*voiceCall{},
+		waiters:   map[string]chan string{},
+		broadcast: func([]byte) {},
+	}
+}
+
+func newVoiceCallID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+// register adds a call awaiting acknowledgement for the given duration.
+// The keys pressed by the callee are sent to the returned channel until
+// the call is unregistered.
+func (v *VoiceCalls) register(c *voiceCall, timeout time.Duration) <-chan string {
+	now := v.now()
+	c.UpdatedAt = now
+	c.ExpiresAt = now.Add(timeout)
+	ch := make(chan string, 1)
+
+	v.mtx.Lock()
+	v.gc(now)
+	v.calls[c.ID] = c
+	v.waiters[c.ID] = ch
+	b, err := marshalVoiceCalls(c)
+	v.mtx.Unlock()
+
+	if err == nil {
+		v.broadcast(b)
+	}
+	return ch
+}
+
+// unregister stops waiting for the acknowledgement of the call.
+func (v *VoiceCalls) unregister(id string) {
+	v.mtx.Lock()
+	delete(v.waiters, id)
+	v.mtx.Unlock()
+}
+
+// get returns a copy of the call with the given ID.
+func (v *VoiceCalls) get(id string) (voiceCall, bool) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	c, ok := v.calls[id]
+	if !ok || !c.ExpiresAt.After(v.now()) {
+		return voiceCall{}, false
+	}
+	return *c, true
+}
+
+// report records the keys pressed by the callee.
+func (v *VoiceCalls) report(id, digits string) bool {
+	v.mtx.Lock()
+	c, ok := v.calls[id]
+	if !ok || !c.ExpiresAt.After(v.now()) {
+		v.mtx.Unlock()
+		return false
+	}
+	updated := *c
+	updated.Digits = digits
+	updated.UpdatedAt = v.now()
+	v.update(&updated)
+	b, err := marshalVoiceCalls(&updated)
+	v.mtx.Unlock()
+
+	if err == nil {
+		v.broadcast(b)
+	}
+	return true
+}
+
+// update stores the call and passes its keys to the waiting notifier, if
+// any. The lock must be held.
+func (v *VoiceCalls) update(c *voiceCall) {
+	v.calls[c.ID] = c
+	if c.Digits == "" {
+		return
+	}
+	if ch, ok := v.waiters[c.ID]; ok {
+		select {
+		case ch <- c.Digits:
+		default:
+		}
+	}
+}
+
+// gc removes expired calls. The lock must be held.
+func (v *VoiceCalls) gc(now time.Time) {
+	for id, c := range v.calls {
+		if !c.ExpiresAt.After(now) {
+			delete(v.calls, id)
+		}
+	}
+}
+
+// MarshalBinary serializes the calls awaiting acknowledgement.
+func (v *VoiceCalls) MarshalBinary() ([]byte, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	v.gc(v.now())
+	calls := make([]*voiceCall, 0, len(v.calls))
+	for _, c := range v.calls {
+		calls = append(calls, c)
+	}
+	return marshalVoiceCalls(calls...)
+}
+
+// Merge merges calls received from the cluster with the local state. The
+// most recently updated state of a call wins.
+func (v *VoiceCalls) Merge(b []byte) error {
+	var calls []*voiceCall
+	dec := json.NewDecoder(bytes.NewReader(b))
+	for {
+		var c voiceCall
+		err := dec.Decode(&c)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		calls = append(calls, &c)
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	now := v.now()
+	for _, c := range calls {
+		if !c.ExpiresAt.After(now) {
+			continue
+		}
+		if prev, ok := v.calls[c.ID]; ok && !prev.UpdatedAt.Before(c.UpdatedAt) {
+			continue
+		}
+		v.update(c)
+	}
+	return nil
+}
+
+// SetBroadcast sets a broadcast callback that will be invoked with serialized
+// state on updates.
+func (v *VoiceCalls) SetBroadcast(f func([]byte)) {
+	v.mtx.Lock()
+	v.broadcast = f
+	v.mtx.Unlock()
+}
+
+func marshalVoiceCalls(calls ...*voiceCall) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, c := range calls {
+		if err := enc.Encode(c); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// twilioSignature returns the signature Twilio sends in the
+// X-Twilio-Signature header of a request to the URL with the given form.
+// https://www.twilio.com/docs/usage/security#validating-requests
+func twilioSignature(authToken, u string, form url.Values) string {
+	keys := make([]string, 0, len(form))
+	for k := range form {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha1.New, []byte(authToken))
+	io.WriteString(mac, u)
+	for _, k := range keys {
+		for _, v := range form[k] {
+			io.WriteString(mac, k+v)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validTwilioSignature reports whether the request is signed with the auth
+// token of one of the Twilio voice configurations of the receiver.
+func validTwilioSignature(r *http.Request, rc *config.Receiver, callbackURL string) bool {
+	sig := r.Header.Get("X-Twilio-Signature")
+	if sig == "" || rc == nil {
+		return false
+	}
+	for _, c := range rc.VoiceConfigs {
+		if c.Provider != "twilio" || c.AccountSID != r.PostForm.Get("AccountSid") {
+			continue
+		}
+		authToken, err := twilioAuthToken(c.AuthToken, c.AuthTokenFile)
+		if err != nil {
+			continue
+		}
+		if hmac.Equal([]byte(sig), []byte(twilioSignature(authToken, callbackURL, r.PostForm))) {
+			return true
+		}
+	}
+	return false
+}
+
+// VoiceCallback returns a handler for keypresses reported by voice call
+// providers. The call is identified by the call query parameter and the
+// pressed keys are read from the Digits (Twilio) or digits form value.
+// Requests for calls placed with Twilio must be signed with the auth token
+// of the receiver, which is looked up by the group key and name of the
+// receiver of the call.
+func VoiceCallback(receiver func(groupKey, name string) *config.Receiver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c, ok := voiceCalls.get(r.URL.Query().Get("call"))
+		if !ok {
+			http.Error(w, "unknown call", http.StatusNotFound)
+			return
+		}
+		if c.Provider == "twilio" && !validTwilioSignature(r, receiver(c.GroupKey, c.Receiver), c.CallbackURL) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+
+		digits := r.PostForm.Get("Digits")
+		if digits == "" {
+			digits = r.PostForm.Get("digits")
+		}
+		if !voiceCalls.report(c.ID, digits) {
+			http.Error(w, "unknown call", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/xml")
+		if digits == voiceAckDigit {
+			io.WriteString(w, `<Response><Say>Alert acknowledged. Goodbye.</Say></Response>`)
+			return
+		}
+		io.WriteString(w, `<Response><Say>Not acknowledged. Goodbye.</Say></Response>`)
+	}
+}
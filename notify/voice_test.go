@@ -0,0 +1,170 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/pkg/web"
+)
+
+func TestTwilioSignature(t *testing.T) {
+	// Example of https://www.twilio.com/docs/usage/security#validating-requests.
+	form := url.Values{
+		"CallSid": {"CA1234567890ABCDE"},
+		"Caller":  {"+12349013030"},
+		"Digits":  {"1234"},
+		"From":    {"+12349013030"},
+		"To":      {"+18005551212"},
+	}
+	require.Equal(t, "0/KCTR6DLpKmkAf8muzZqo1nDgQ=", twilioSignature("12345", "https://mycompany.com/myapp.php?foo=1&bar=2", form))
+}
+
+func TestVoiceCallbackTwilioSignature(t *testing.T) {
+	calls := NewVoiceCalls()
+	orig := voiceCalls
+	voiceCalls = calls
+	defer func() { voiceCalls = orig }()
+
+	callbackURL := "https://am.example.com/api/v1/voice/callback?call=1"
+	acks := calls.register(&voiceCall{
+		ID:          "1",
+		GroupKey:    "{}:{}",
+		Receiver:    "oncall",
+		Provider:    "twilio",
+		CallbackURL: callbackURL,
+	}, time.Minute)
+
+	rc := &config.Receiver{
+		Name: "oncall",
+		VoiceConfigs: []*config.VoiceConfig{
+			{Provider: "twilio", AccountSID: "AC1", AuthToken: "t0k3n"},
+		},
+	}
+	var lookedUp []string
+	h := VoiceCallback(func(groupKey, name string) *config.Receiver {
+		lookedUp = append(lookedUp, groupKey+" "+name)
+		return rc
+	})
+
+	form := url.Values{"AccountSid": {"AC1"}, "Digits": {voiceAckDigit}}
+	do := func(sig string) int {
+		req := httptest.NewRequest("POST", "/api/v1/voice/callback?call=1", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if sig != "" {
+			req.Header.Set("X-Twilio-Signature", sig)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusForbidden, do(""))
+	require.Equal(t, http.StatusForbidden, do(twilioSignature("wrong", callbackURL, form)))
+	require.Len(t, acks, 0)
+
+	require.Equal(t, http.StatusOK, do(twilioSignature("t0k3n", callbackURL, form)))
+	require.Equal(t, voiceAckDigit, <-acks)
+	require.Equal(t, "{}:{} oncall", lookedUp[0])
+}
+
+func TestVoiceCallbackWebAuth(t *testing.T) {
+	calls := NewVoiceCalls()
+	orig := voiceCalls
+	voiceCalls = calls
+	defer func() { voiceCalls = orig }()
+
+	callbackURL := "https://am.example.com/api/v1/voice/callback?call=1"
+	acks := calls.register(&voiceCall{
+		ID:          "1",
+		Receiver:    "oncall",
+		Provider:    "twilio",
+		CallbackURL: callbackURL,
+	}, time.Minute)
+	rc := &config.Receiver{
+		Name: "oncall",
+		VoiceConfigs: []*config.VoiceConfig{
+			{Provider: "twilio", AccountSID: "AC1", AuthToken: "t0k3n"},
+		},
+	}
+
+	// Providers cannot authenticate their callbacks with the web
+	// authentication of Alertmanager.
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/voice/callback", VoiceCallback(func(string, string) *config.Receiver { return rc }))
+	h, err := web.NewHandler(&web.Config{
+		BasicAuthUsers: map[string]string{"alice": "$2y$10$invalid"},
+		ExemptPaths:    web.DefaultExemptPaths,
+	}, "/", mux)
+	require.NoError(t, err)
+
+	form := url.Values{"AccountSid": {"AC1"}, "Digits": {voiceAckDigit}}
+	req := httptest.NewRequest("POST", "/api/v1/voice/callback?call=1", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Twilio-Signature", twilioSignature("t0k3n", callbackURL, form))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, voiceAckDigit, <-acks)
+
+	// Other API requests still require authentication.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/alerts", nil))
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestVoiceCallsMerge(t *testing.T) {
+	now := time.Unix(1000, 0)
+	placing, answering := NewVoiceCalls(), NewVoiceCalls()
+	placing.now = func() time.Time { return now }
+	answering.now = func() time.Time { return now }
+
+	var bcast [][]byte
+	placing.SetBroadcast(func(b []byte) { bcast = append(bcast, b) })
+	answering.SetBroadcast(func(b []byte) { bcast = append(bcast, b) })
+
+	// The callback of the call placed by one peer reaches another peer.
+	acks := placing.register(&voiceCall{ID: "1", Provider: "webhook"}, time.Minute)
+	require.Len(t, bcast, 1)
+	require.False(t, answering.report("1", voiceAckDigit))
+	require.NoError(t, answering.Merge(bcast[0]))
+
+	now = now.Add(time.Second)
+	require.True(t, answering.report("1", voiceAckDigit))
+	require.Len(t, bcast, 2)
+	require.NoError(t, placing.Merge(bcast[1]))
+	require.Equal(t, voiceAckDigit, <-acks)
+
+	// Outdated states are ignored.
+	require.NoError(t, placing.Merge(bcast[0]))
+	c, ok := placing.get("1")
+	require.True(t, ok)
+	require.Equal(t, voiceAckDigit, c.Digits)
+
+	// Expired calls are unknown and not shared anymore.
+	now = now.Add(time.Minute)
+	_, ok = placing.get("1")
+	require.False(t, ok)
+	b, err := placing.MarshalBinary()
+	require.NoError(t, err)
+	require.Empty(t, b)
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package confighistory keeps the last applied configurations on disk so
+// that they can be listed and rolled back to.
+package confighistory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for versions not in the history.
+var ErrNotFound = errors.New("configuration version not found")
+
+// Possible sources of versions.
+const (
+	SourceFile     = "file"
+	SourceRemote   = "remote"
+	SourceAPI      = "api"
+	SourceRollback = "rollback"
+	// SourceRestore is the source of configurations restored after
+	// applying another configuration failed.
+	SourceRestore = "restore"
+)
+
+// Version is an applied configuration.
+type Version struct {
+	Version   int       `json:"version"`
+	Hash      string    `json:"hash"`
+	Timestamp time.Time `json:"timestamp"`
+	// Source is how the configuration was applied. Rollbacks have the
+	// source "rollback:<version>".
+	Source string `json:"source"`
+}
+
+// History is the history of applied configurations. The content of each
+// version is stored in a file named by the version, the versions in an
+// index file.
+type History struct {
+	dir string
+	max int
+
+	mtx      sync.RWMutex
+	versions []Version // Oldest first.
+}
+
+const indexFile = "index.json"
+
+// New returns the history stored in the directory, keeping at most max
+// versions.
+func New(dir string, max int) (*History, error) {
+	if max < 1 {
+		return nil, fmt.Errorf("invalid history size %d", max)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	h := &History{dir: dir, max: max}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, indexFile))
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &h.versions); err != nil {
+		return nil, fmt.Errorf("reading configuration history index: %s", err)
+	}
+	return h, nil
+}
+
+// Add records an applied configuration and returns its version. If the
+// configuration equals the latest one, no version is added and the latest
+// version is returned.
+func (h *History) Add(content []byte, source string) (Version, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	sum := sha256.Sum256(content)
+	v := Version{
+		Version:   1,
+		Hash:      hex.EncodeToString(sum[:]),
+		Timestamp: time.Now().UTC(),
+		Source:    source,
+	}
+	if n := len(h.versions); n > 0 {
+		latest := h.versions[n-1]
+		if latest.Hash == v.Hash {
+			return latest, nil
+		}
+		v.Version = latest.Version + 1
+	}
+	if err := writeFile(h.file(v.Version), content); err != nil {
+		return Version{}, err
+	}
+
+	versions := append(h.versions, v)
+	var pruned []Version
+	if len(versions) > h.max {
+		pruned = versions[:len(versions)-h.max]
+		versions = versions[len(versions)-h.max:]
+	}
+	b, err := json.Marshal(versions)
+	if err != nil {
+		return Version{}, err
+	}
+	if err := writeFile(filepath.Join(h.dir, indexFile), b); err != nil {
+		return Version{}, err
+	}
+	h.versions = versions
+
+	for _, p := range pruned {
+		os.Remove(h.file(p.Version))
+	}
+	return v, nil
+}
+
+// Versions returns the versions of the history, the latest first.
+func (h *History) Versions() []Version {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	res := make([]Version, 0, len(h.versions))
+	for i := len(h.versions) - 1; i >= 0; i-- {
+		res = append(res, h.versions[i])
+	}
+	return res
+}
+
+// Content returns the configuration of the version.
+func (h *History) Content(version int) ([]byte, error) {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+
+	for _, v := range h.versions {
+		if v.Version == version {
+			return ioutil.ReadFile(h.file(version))
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (h *History) file(version int) string {
+	return filepath.Join(h.dir, fmt.Sprintf("%d.yml", version))
+}
+
+// writeFile writes the file atomically by renaming a temporary file.
+func writeFile(filename string, content []byte) error {
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confighistory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	h, err := New(dir, 2)
+	require.NoError(t, err)
+	require.Len(t, h.Versions(), 0)
+
+	v, err := h.Add([]byte("a"), SourceFile)
+	require.NoError(t, err)
+	require.Equal(t, 1, v.Version)
+	require.Equal(t, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", v.Hash)
+
+	// Unchanged configurations are not recorded again.
+	v, err = h.Add([]byte("a"), SourceFile)
+	require.NoError(t, err)
+	require.Equal(t, 1, v.Version)
+
+	for i, c := range []string{"b", "c"} {
+		v, err = h.Add([]byte(c), SourceAPI)
+		require.NoError(t, err)
+		require.Equal(t, i+2, v.Version)
+	}
+
+	// The oldest versions are pruned.
+	versions := h.Versions()
+	require.Len(t, versions, 2)
+	require.Equal(t, 3, versions[0].Version)
+	require.Equal(t, 2, versions[1].Version)
+	_, err = h.Content(1)
+	require.Equal(t, ErrNotFound, err)
+	_, err = os.Stat(filepath.Join(dir, "1.yml"))
+	require.True(t, os.IsNotExist(err))
+
+	// The history is restored from disk.
+	h, err = New(dir, 2)
+	require.NoError(t, err)
+	require.Equal(t, versions, h.Versions())
+	for v, c := range map[int]string{2: "b", 3: "c"} {
+		b, err := h.Content(v)
+		require.NoError(t, err, fmt.Sprint(v))
+		require.Equal(t, c, string(b))
+	}
+	v, err = h.Add([]byte("a"), SourceRollback)
+	require.NoError(t, err)
+	require.Equal(t, 4, v.Version)
+
+	_, err = New(dir, 0)
+	require.Error(t, err)
+}
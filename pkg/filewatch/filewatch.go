@@ -0,0 +1,135 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filewatch detects changes of files by polling them.
+//
+// Files are compared by their content and the targets of their symlinks, so
+// that files replaced by renames and atomic symlink swaps, as done for
+// Kubernetes ConfigMap volumes, are detected as well as in-place writes.
+package filewatch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Watcher watches files and the files of directories for changes.
+type Watcher struct {
+	paths    []string
+	interval time.Duration
+	debounce time.Duration
+}
+
+// New returns a watcher of the paths, which are checked at the interval.
+// Changes are reported once the paths did not change for the debounce
+// period, so that files written in several steps are reported once.
+func New(paths []string, interval, debounce time.Duration) *Watcher {
+	return &Watcher{paths: paths, interval: interval, debounce: debounce}
+}
+
+// Run watches the paths until the context is done and calls changed after
+// changes.
+func (w *Watcher) Run(ctx context.Context, changed func()) {
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+
+	var (
+		last    = w.Fingerprint()
+		pending string
+		since   time.Time
+	)
+	for {
+		select {
+		case <-t.C:
+			fp := w.Fingerprint()
+			if fp == last {
+				pending = ""
+				continue
+			}
+			if fp != pending {
+				pending, since = fp, time.Now()
+			}
+			if time.Since(since) < w.debounce {
+				continue
+			}
+			last, pending = fp, ""
+			changed()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Fingerprint returns a hash of the resolved paths and contents of the
+// watched files. Missing and unreadable files are part of the fingerprint
+// as such.
+func (w *Watcher) Fingerprint() string {
+	h := sha256.New()
+	for _, p := range w.paths {
+		fingerprint(h, p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func fingerprint(h io.Writer, path string) {
+	io.WriteString(h, path+"\x00")
+
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		io.WriteString(h, "missing\x00")
+		return
+	}
+	io.WriteString(h, target+"\x00")
+
+	fi, err := os.Stat(target)
+	if err != nil {
+		io.WriteString(h, "missing\x00")
+		return
+	}
+	if fi.IsDir() {
+		files, err := ioutil.ReadDir(target)
+		if err != nil {
+			io.WriteString(h, "unreadable\x00")
+			return
+		}
+		names := make([]string, 0, len(files))
+		for _, f := range files {
+			// Hidden entries are the internals of ConfigMap volumes,
+			// which are reflected in the targets of the visible ones.
+			if f.Name()[0] != '.' {
+				names = append(names, f.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			fingerprint(h, filepath.Join(path, n))
+		}
+		return
+	}
+
+	f, err := os.Open(target)
+	if err != nil {
+		io.WriteString(h, "unreadable\x00")
+		return
+	}
+	defer f.Close()
+	io.Copy(h, f)
+	io.WriteString(h, "\x00")
+}
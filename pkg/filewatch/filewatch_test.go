@@ -0,0 +1,95 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filewatch
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The layout of a Kubernetes ConfigMap volume.
+	for _, v := range []string{"..v1", "..v2"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, v), 0777))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, v, "alertmanager.yml"), []byte(v), 0666))
+	}
+	require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	file := filepath.Join(dir, "alertmanager.yml")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "alertmanager.yml"), file))
+
+	w := New([]string{file}, time.Second, 0)
+	fp := w.Fingerprint()
+	require.Equal(t, fp, w.Fingerprint())
+
+	// Swap the data directory.
+	require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	swapped := w.Fingerprint()
+	require.NotEqual(t, fp, swapped)
+
+	// Write in place.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "..v2", "alertmanager.yml"), []byte("changed"), 0666))
+	require.NotEqual(t, swapped, w.Fingerprint())
+
+	// Watch a directory.
+	dw := New([]string{dir}, time.Second, 0)
+	fp = dw.Fingerprint()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "..v2", "alertmanager.yml"), []byte("changed again"), 0666))
+	require.NotEqual(t, fp, dw.Fingerprint())
+	fp = dw.Fingerprint()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "fragment.yml"), nil, 0666))
+	require.NotEqual(t, fp, dw.Fingerprint())
+}
+
+func TestRunDebounce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "alertmanager.yml")
+	require.NoError(t, ioutil.WriteFile(file, []byte("v1"), 0666))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 10)
+	go New([]string{file}, 10*time.Millisecond, 100*time.Millisecond).Run(ctx, func() {
+		changed <- struct{}{}
+	})
+
+	// Writes within the debounce period are reported once.
+	time.Sleep(30 * time.Millisecond)
+	for _, c := range []string{"v2", "v3", "v4"} {
+		require.NoError(t, ioutil.WriteFile(file, []byte(c), 0666))
+		time.Sleep(30 * time.Millisecond)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not reported")
+	}
+	select {
+	case <-changed:
+		t.Fatal("change reported twice")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
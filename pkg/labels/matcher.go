@@ -0,0 +1,154 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package labels provides matchers for label values. In addition to the
+// matchers of Prometheus it supports matching on the absence or presence of
+// a label and numeric comparisons of label values.
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// MatchType is an enum for label matching types.
+type MatchType int
+
+// Possible MatchTypes.
+const (
+	MatchEqual MatchType = iota
+	MatchNotEqual
+	MatchRegexp
+	MatchNotRegexp
+	// MatchAbsent and MatchPresent match if the label is not set or set
+	// respectively. The value of the matcher is empty.
+	MatchAbsent
+	MatchPresent
+	// The numeric match types compare the label value as a number with the
+	// value of the matcher. Label values that are not numbers do not match.
+	MatchLessThan
+	MatchLessEqual
+	MatchGreaterThan
+	MatchGreaterEqual
+)
+
+var typeToStr = map[MatchType]string{
+	MatchEqual:        "=",
+	MatchNotEqual:     "!=",
+	MatchRegexp:       "=~",
+	MatchNotRegexp:    "!~",
+	MatchAbsent:       "absent",
+	MatchPresent:      "present",
+	MatchLessThan:     "<",
+	MatchLessEqual:    "<=",
+	MatchGreaterThan:  ">",
+	MatchGreaterEqual: ">=",
+}
+
+func (m MatchType) String() string {
+	if str, ok := typeToStr[m]; ok {
+		return str
+	}
+	panic("unknown match type")
+}
+
+// IsNumeric returns true for the match types comparing numbers.
+func (m MatchType) IsNumeric() bool {
+	switch m {
+	case MatchLessThan, MatchLessEqual, MatchGreaterThan, MatchGreaterEqual:
+		return true
+	}
+	return false
+}
+
+// Matcher models the matching of a label.
+type Matcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+
+	re  *regexp.Regexp
+	num float64
+}
+
+// NewMatcher returns a matcher object.
+func NewMatcher(t MatchType, n, v string) (*Matcher, error) {
+	m := &Matcher{
+		Type:  t,
+		Name:  n,
+		Value: v,
+	}
+	switch {
+	case t == MatchRegexp || t == MatchNotRegexp:
+		re, err := regexp.Compile("^(?:" + v + ")$")
+		if err != nil {
+			return nil, err
+		}
+		m.re = re
+	case t == MatchAbsent || t == MatchPresent:
+		if v != "" {
+			return nil, fmt.Errorf("unexpected value %q for %s matcher", v, t)
+		}
+	case t.IsNumeric():
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", v)
+		}
+		m.num = f
+	}
+	return m, nil
+}
+
+func (m *Matcher) String() string {
+	if m.Type == MatchAbsent || m.Type == MatchPresent {
+		return fmt.Sprintf("%s(%s)", m.Type, m.Name)
+	}
+	return fmt.Sprintf("%s%s%q", m.Name, m.Type, m.Value)
+}
+
+// Matches returns whether the matcher matches the given string value. The
+// value of unset labels is the empty string.
+func (m *Matcher) Matches(s string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return s == m.Value
+	case MatchNotEqual:
+		return s != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(s)
+	case MatchNotRegexp:
+		return !m.re.MatchString(s)
+	case MatchAbsent:
+		return s == ""
+	case MatchPresent:
+		return s != ""
+	}
+	if m.Type.IsNumeric() {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return false
+		}
+		switch m.Type {
+		case MatchLessThan:
+			return f < m.num
+		case MatchLessEqual:
+			return f <= m.num
+		case MatchGreaterThan:
+			return f > m.num
+		case MatchGreaterEqual:
+			return f >= m.num
+		}
+	}
+	panic("labels.Matcher.Matches: invalid match type")
+}
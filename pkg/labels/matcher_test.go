@@ -0,0 +1,96 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package labels
+
+import (
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		matcher *Matcher
+		value   string
+		match   bool
+	}{
+		{matcher: mustNewMatcher(t, MatchEqual, "bar"), value: "bar", match: true},
+		{matcher: mustNewMatcher(t, MatchEqual, "bar"), value: "foo-bar", match: false},
+		{matcher: mustNewMatcher(t, MatchNotEqual, "bar"), value: "bar", match: false},
+		{matcher: mustNewMatcher(t, MatchNotEqual, "bar"), value: "foo-bar", match: true},
+		{matcher: mustNewMatcher(t, MatchRegexp, "bar"), value: "bar", match: true},
+		{matcher: mustNewMatcher(t, MatchRegexp, "bar"), value: "foo-bar", match: false},
+		{matcher: mustNewMatcher(t, MatchRegexp, ".*bar"), value: "foo-bar", match: true},
+		{matcher: mustNewMatcher(t, MatchNotRegexp, "bar"), value: "bar", match: false},
+		{matcher: mustNewMatcher(t, MatchNotRegexp, ".*bar"), value: "foo-bar", match: false},
+		{matcher: mustNewMatcher(t, MatchAbsent, ""), value: "", match: true},
+		{matcher: mustNewMatcher(t, MatchAbsent, ""), value: "bar", match: false},
+		{matcher: mustNewMatcher(t, MatchPresent, ""), value: "", match: false},
+		{matcher: mustNewMatcher(t, MatchPresent, ""), value: "bar", match: true},
+		{matcher: mustNewMatcher(t, MatchLessThan, "3"), value: "2.5", match: true},
+		{matcher: mustNewMatcher(t, MatchLessThan, "3"), value: "3", match: false},
+		{matcher: mustNewMatcher(t, MatchLessEqual, "3"), value: "3", match: true},
+		{matcher: mustNewMatcher(t, MatchGreaterThan, "3"), value: "10", match: true},
+		{matcher: mustNewMatcher(t, MatchGreaterThan, "3"), value: "3", match: false},
+		{matcher: mustNewMatcher(t, MatchGreaterEqual, "-1e3"), value: "-1000", match: true},
+		{matcher: mustNewMatcher(t, MatchGreaterEqual, "3"), value: "high", match: false},
+		{matcher: mustNewMatcher(t, MatchLessThan, "3"), value: "", match: false},
+	}
+
+	for _, test := range tests {
+		if got := test.matcher.Matches(test.value); got != test.match {
+			t.Errorf("%s matching %q: expected %t, got %t", test.matcher, test.value, test.match, got)
+		}
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	for _, test := range []struct {
+		t     MatchType
+		value string
+	}{
+		{t: MatchRegexp, value: "("},
+		{t: MatchAbsent, value: "bar"},
+		{t: MatchPresent, value: "bar"},
+		{t: MatchLessThan, value: "three"},
+		{t: MatchGreaterEqual, value: ""},
+	} {
+		if _, err := NewMatcher(test.t, "foo", test.value); err == nil {
+			t.Errorf("expected error for %s matcher with value %q", test.t, test.value)
+		}
+	}
+}
+
+func TestMatcherString(t *testing.T) {
+	for _, test := range []struct {
+		matcher *Matcher
+		s       string
+	}{
+		{matcher: mustNewMatcher(t, MatchEqual, "bar"), s: `foo="bar"`},
+		{matcher: mustNewMatcher(t, MatchNotRegexp, "b.*"), s: `foo!~"b.*"`},
+		{matcher: mustNewMatcher(t, MatchAbsent, ""), s: `absent(foo)`},
+		{matcher: mustNewMatcher(t, MatchPresent, ""), s: `present(foo)`},
+		{matcher: mustNewMatcher(t, MatchGreaterEqual, "3"), s: `foo>="3"`},
+	} {
+		if got := test.matcher.String(); got != test.s {
+			t.Errorf("expected %s, got %s", test.s, got)
+		}
+	}
+}
+
+func mustNewMatcher(t *testing.T, mType MatchType, value string) *Matcher {
+	m, err := NewMatcher(mType, "foo", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// azureBucket is a container in Azure Blob Storage accessed with a shared
+// access signature.
+type azureBucket struct {
+	client *http.Client
+	url    *url.URL
+	prefix string
+}
+
+func newAzureBucket(client *http.Client, endpoint, account, container, prefix, sasToken string) (*azureBucket, error) {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", account)
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/") + "/" + container + "/")
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = strings.TrimPrefix(sasToken, "?")
+	return &azureBucket{
+		client: client,
+		url:    u,
+		prefix: prefix,
+	}, nil
+}
+
+func (b *azureBucket) request(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	u := *b.url
+	u.Path += b.prefix + name
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if method == http.MethodPut {
+		req.Header.Set("x-ms-blob-type", "BlockBlob")
+	}
+	return ctxhttp.Do(ctx, b.client, req)
+}
+
+// Upload implements the Bucket interface.
+func (b *azureBucket) Upload(ctx context.Context, name string, content []byte) error {
+	resp, err := b.request(ctx, http.MethodPut, name, content)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+// Download implements the Bucket interface.
+func (b *azureBucket) Download(ctx context.Context, name string) ([]byte, error) {
+	resp, err := b.request(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,267 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package objstore stores snapshot files in S3, Google Cloud Storage or
+// Azure Blob Storage so they survive the loss of the local disk.
+//
+// The bucket is configured in a YAML file:
+//
+//	# One of s3, gcs or azure.
+//	type: s3
+//	# The bucket, or the container for Azure.
+//	bucket: alertmanager
+//	prefix: prod/
+//	# Defaults to AWS, https://storage.googleapis.com for GCS and
+//	# https://<account>.blob.core.windows.net for Azure.
+//	endpoint: https://minio.example.com
+//	# S3 and GCS, which is accessed with HMAC keys.
+//	region: eu-west-1
+//	access_key: AKIA...
+//	secret_key: ...
+//	# Azure.
+//	account: myaccount
+//	sas_token: sv=...&sig=...
+//	timeout: 1m
+package objstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// ErrNotFound is returned if an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Config configures the bucket snapshots are stored in.
+type Config struct {
+	// One of s3, gcs or azure.
+	Type string `yaml:"type"`
+	// The bucket, or the container for Azure.
+	Bucket string `yaml:"bucket"`
+	// Prepended to the names of all objects.
+	Prefix string `yaml:"prefix,omitempty"`
+	// Overrides the default endpoint of the storage service.
+	Endpoint string `yaml:"endpoint,omitempty"`
+
+	// S3 and GCS settings. GCS is accessed through its S3 compatible API
+	// with HMAC keys.
+	Region    string        `yaml:"region,omitempty"`
+	AccessKey string        `yaml:"access_key,omitempty"`
+	SecretKey config.Secret `yaml:"secret_key,omitempty"`
+
+	// Azure settings.
+	Account  string        `yaml:"account,omitempty"`
+	SASToken config.Secret `yaml:"sas_token,omitempty"`
+
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = Config{Timeout: time.Minute}
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.Bucket == "" {
+		return fmt.Errorf("missing bucket")
+	}
+	switch c.Type {
+	case "s3", "gcs":
+		if c.AccessKey == "" || c.SecretKey == "" {
+			return fmt.Errorf("access_key and secret_key must be set for %s", c.Type)
+		}
+	case "azure":
+		if c.Account == "" && c.Endpoint == "" {
+			return fmt.Errorf("one of account and endpoint must be set for azure")
+		}
+		if c.SASToken == "" {
+			return fmt.Errorf("sas_token must be set for azure")
+		}
+	default:
+		return fmt.Errorf("unknown object storage type %q", c.Type)
+	}
+	return nil
+}
+
+// LoadFile parses the object storage configuration in the given file.
+func LoadFile(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := yaml.UnmarshalStrict(content, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Bucket stores objects by name.
+type Bucket interface {
+	// Upload creates or replaces the object with the given name.
+	Upload(ctx context.Context, name string, b []byte) error
+	// Download returns the content of the object with the given name or
+	// ErrNotFound.
+	Download(ctx context.Context, name string) ([]byte, error)
+}
+
+// NewBucket returns the bucket described by the configuration.
+func NewBucket(c *Config) (Bucket, error) {
+	client := &http.Client{Timeout: c.Timeout}
+	prefix := strings.TrimLeft(c.Prefix, "/")
+
+	switch c.Type {
+	case "s3":
+		return newS3Bucket(client, c.Endpoint, c.Region, c.Bucket, prefix, c.AccessKey, string(c.SecretKey))
+	case "gcs":
+		endpoint := c.Endpoint
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		region := c.Region
+		if region == "" {
+			region = "auto"
+		}
+		return newS3Bucket(client, endpoint, region, c.Bucket, prefix, c.AccessKey, string(c.SecretKey))
+	case "azure":
+		return newAzureBucket(client, c.Endpoint, c.Account, c.Bucket, prefix, string(c.SASToken))
+	}
+	return nil, fmt.Errorf("unknown object storage type %q", c.Type)
+}
+
+// Restore downloads the object with the base name of the file to the file
+// if the file does not exist. It returns true if the file was restored.
+func Restore(ctx context.Context, b Bucket, filename string) (bool, error) {
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		return false, err
+	}
+	content, err := b.Download(ctx, filepath.Base(filename))
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return false, err
+	}
+	// Write to a temporary file first so an interrupted restore does not
+	// leave a truncated snapshot behind.
+	tmp := filename + ".restore"
+	if err := ioutil.WriteFile(tmp, content, 0666); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, filename)
+}
+
+// Shipper uploads snapshot files to a bucket.
+type Shipper struct {
+	bucket Bucket
+	files  []string
+	logger log.Logger
+
+	uploads        prometheus.Counter
+	uploadsFailed  prometheus.Counter
+	lastSuccessful prometheus.Gauge
+}
+
+// NewShipper returns a shipper uploading the given files to the bucket
+// under their base names.
+func NewShipper(b Bucket, l log.Logger, r prometheus.Registerer, files ...string) *Shipper {
+	s := &Shipper{
+		bucket: b,
+		files:  files,
+		logger: l,
+		uploads: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_snapshot_uploads_total",
+			Help: "The total number of snapshot files uploaded to object storage.",
+		}),
+		uploadsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "alertmanager_snapshot_uploads_failed_total",
+			Help: "The total number of failed snapshot file uploads to object storage.",
+		}),
+		lastSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "alertmanager_snapshot_last_upload_timestamp_seconds",
+			Help: "Timestamp of the last run uploading all snapshot files successfully.",
+		}),
+	}
+	if r != nil {
+		r.MustRegister(s.uploads, s.uploadsFailed, s.lastSuccessful)
+	}
+	return s
+}
+
+// Run uploads the files at the given interval until stopc is closed.
+func (s *Shipper) Run(interval time.Duration, stopc <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+			s.Ship()
+		}
+	}
+}
+
+// Ship uploads the files once. Files that do not exist yet are skipped.
+func (s *Shipper) Ship() {
+	failed := false
+	for _, f := range s.files {
+		content, err := ioutil.ReadFile(f)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err == nil {
+			s.uploads.Inc()
+			err = s.bucket.Upload(context.Background(), filepath.Base(f), content)
+		}
+		if err != nil {
+			failed = true
+			s.uploadsFailed.Inc()
+			level.Error(s.logger).Log("msg", "Uploading snapshot failed", "file", f, "err", err)
+		}
+	}
+	if !failed {
+		s.lastSuccessful.SetToCurrentTime()
+	}
+}
+
+// checkResponse returns an error for unsuccessful responses.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
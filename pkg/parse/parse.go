@@ -18,16 +18,23 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/alertmanager/pkg/labels"
 )
 
 var (
-	re      = regexp.MustCompile(`(?:\s?)(\w+)(=|=~|!=|!~)(?:\"([^"=~!]+)\"|([^"=~!]+)|\"\")`)
+	re      = regexp.MustCompile(`(?:\s?)(\w+)(=|=~|!=|!~|<=|>=|<|>)(?:\"([^"=~!]+)\"|([^"=~!]+)|\"\")`)
+	fnRe    = regexp.MustCompile(`^\s*(absent|present)\((\w+)\)\s*$`)
 	typeMap = map[string]labels.MatchType{
-		"=":  labels.MatchEqual,
-		"!=": labels.MatchNotEqual,
-		"=~": labels.MatchRegexp,
-		"!~": labels.MatchNotRegexp,
+		"=":       labels.MatchEqual,
+		"!=":      labels.MatchNotEqual,
+		"=~":      labels.MatchRegexp,
+		"!~":      labels.MatchNotRegexp,
+		"<":       labels.MatchLessThan,
+		"<=":      labels.MatchLessEqual,
+		">":       labels.MatchGreaterThan,
+		">=":      labels.MatchGreaterEqual,
+		"absent":  labels.MatchAbsent,
+		"present": labels.MatchPresent,
 	}
 )
 
@@ -81,7 +88,14 @@ func Matcher(s string) (*labels.Matcher, error) {
 	return m, nil
 }
 
+// Input parses a single matcher. Besides the operators of Prometheus, it
+// supports numeric comparisons like severity>=3 and matching on the absence
+// or presence of a label with absent(team) and present(team).
 func Input(s string) (name, value string, matchType labels.MatchType, err error) {
+	if ms := fnRe.FindStringSubmatch(s); ms != nil {
+		return ms[2], "", typeMap[ms[1]], nil
+	}
+
 	ms := re.FindStringSubmatch(s)
 	if len(ms) < 4 {
 		return "", "", labels.MatchEqual, fmt.Errorf("bad matcher format: %s", s)
@@ -17,7 +17,7 @@ import (
 	"reflect"
 	"testing"
 
-	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/alertmanager/pkg/labels"
 )
 
 func TestMatchers(t *testing.T) {
@@ -132,6 +132,27 @@ func TestMatchers(t *testing.T) {
 				return append(ms, m, m2)
 			}(),
 		},
+		{
+			input: `{absent(team), present(severity)}`,
+			want: func() []*labels.Matcher {
+				ms := []*labels.Matcher{}
+				m, _ := labels.NewMatcher(labels.MatchAbsent, "team", "")
+				m2, _ := labels.NewMatcher(labels.MatchPresent, "severity", "")
+				return append(ms, m, m2)
+			}(),
+		},
+		{
+			input: `{priority<3, severity>="2.5", code>=500,code<=599,age>0}`,
+			want: func() []*labels.Matcher {
+				ms := []*labels.Matcher{}
+				m, _ := labels.NewMatcher(labels.MatchLessThan, "priority", "3")
+				m2, _ := labels.NewMatcher(labels.MatchGreaterEqual, "severity", "2.5")
+				m3, _ := labels.NewMatcher(labels.MatchGreaterEqual, "code", "500")
+				m4, _ := labels.NewMatcher(labels.MatchLessEqual, "code", "599")
+				m5, _ := labels.NewMatcher(labels.MatchGreaterThan, "age", "0")
+				return append(ms, m, m2, m3, m4, m5)
+			}(),
+		},
 	}
 
 	for i, tc := range testCases {
@@ -0,0 +1,437 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package query implements the query language filtering alerts. Queries
+// combine predicates with and, or, not and parentheses:
+//
+//	severity=~"critical|error" and not silenced
+//	(team=a or team=b) and startsAt > now-2h
+//	{alertname="foo",job="bar"} or absent(team)
+//
+// The predicates are:
+//
+//   - Label matchers with the operators =, !=, =~, !~ and the numeric
+//     comparisons <, <=, >, >=. Values containing spaces, parentheses,
+//     braces or operators must be quoted.
+//   - absent(label) and present(label).
+//   - Lists of matchers in braces as accepted by the filters of the API,
+//     which match if all their matchers match.
+//   - Comparisons of the startsAt, endsAt and updatedAt times of alerts with
+//     now, now plus or minus a duration like now-2h, or an RFC 3339 time.
+//     Labels of these names can be matched in braces.
+//   - The states active, suppressed, unprocessed, silenced and inhibited.
+//
+// And binds stronger than or.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
+	"github.com/prometheus/alertmanager/pkg/parse"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Expr is a parsed query.
+type Expr interface {
+	// Matches returns true if the alert in the given state matches the
+	// query at the given time.
+	Matches(a *types.Alert, status types.AlertStatus, now time.Time) bool
+}
+
+type andExpr struct{ l, r Expr }
+
+func (e andExpr) Matches(a *types.Alert, s types.AlertStatus, now time.Time) bool {
+	return e.l.Matches(a, s, now) && e.r.Matches(a, s, now)
+}
+
+type orExpr struct{ l, r Expr }
+
+func (e orExpr) Matches(a *types.Alert, s types.AlertStatus, now time.Time) bool {
+	return e.l.Matches(a, s, now) || e.r.Matches(a, s, now)
+}
+
+type notExpr struct{ e Expr }
+
+func (e notExpr) Matches(a *types.Alert, s types.AlertStatus, now time.Time) bool {
+	return !e.e.Matches(a, s, now)
+}
+
+type matchersExpr []*labels.Matcher
+
+func (e matchersExpr) Matches(a *types.Alert, _ types.AlertStatus, _ time.Time) bool {
+	for _, m := range e {
+		if !m.Matches(string(a.Labels[model.LabelName(m.Name)])) {
+			return false
+		}
+	}
+	return true
+}
+
+// The states alerts can be queried for.
+var states = map[string]func(types.AlertStatus) bool{
+	"active":      func(s types.AlertStatus) bool { return s.State == types.AlertStateActive },
+	"suppressed":  func(s types.AlertStatus) bool { return s.State == types.AlertStateSuppressed },
+	"unprocessed": func(s types.AlertStatus) bool { return s.State == types.AlertStateUnprocessed },
+	"silenced":    func(s types.AlertStatus) bool { return len(s.SilencedBy) > 0 },
+	"inhibited":   func(s types.AlertStatus) bool { return len(s.InhibitedBy) > 0 },
+}
+
+type stateExpr func(types.AlertStatus) bool
+
+func (e stateExpr) Matches(_ *types.Alert, s types.AlertStatus, _ time.Time) bool {
+	return e(s)
+}
+
+// The times of alerts that can be compared.
+var timeFields = map[string]func(*types.Alert) time.Time{
+	"startsAt":  func(a *types.Alert) time.Time { return a.StartsAt },
+	"endsAt":    func(a *types.Alert) time.Time { return a.EndsAt },
+	"updatedAt": func(a *types.Alert) time.Time { return a.UpdatedAt },
+}
+
+type timeExpr struct {
+	field func(*types.Alert) time.Time
+	op    string
+	// Either an absolute time or an offset to now.
+	t      time.Time
+	offset time.Duration
+}
+
+func (e timeExpr) Matches(a *types.Alert, _ types.AlertStatus, now time.Time) bool {
+	t := e.t
+	if t.IsZero() {
+		t = now.Add(e.offset)
+	}
+	v := e.field(a)
+	switch e.op {
+	case "<":
+		return v.Before(t)
+	case "<=":
+		return !v.After(t)
+	case ">":
+		return v.After(t)
+	case ">=":
+		return !v.Before(t)
+	}
+	return false
+}
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokMatchers
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokEOF {
+		return "end of query"
+	}
+	return strconv.Quote(t.val)
+}
+
+// lex splits the query into tokens.
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			toks = append(toks, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			toks = append(toks, token{tokRParen, ")", i})
+			i++
+		case c == '{':
+			j, err := skipQuoted(s, i, '}')
+			if err != nil {
+				// A missing closing brace of a trailing list of matchers
+				// is tolerated like by parse.Matchers.
+				j = len(s)
+			}
+			toks = append(toks, token{tokMatchers, s[i:j], i})
+			i = j
+		case c == '"':
+			j, err := skipQuoted(s, i, '"')
+			if err != nil {
+				return nil, err
+			}
+			v, err := strconv.Unquote(s[i:j])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %s", i, err)
+			}
+			toks = append(toks, token{tokString, v, i})
+			i = j
+		case strings.ContainsRune("=!~<>", rune(c)):
+			op := string(c)
+			if i+1 < len(s) && strings.ContainsRune("=~", rune(s[i+1])) {
+				op = s[i : i+2]
+			}
+			switch op {
+			case "=", "!=", "=~", "!~", "<", "<=", ">", ">=":
+			default:
+				return nil, fmt.Errorf("invalid operator %q at position %d", op, i)
+			}
+			toks = append(toks, token{tokOp, op, i})
+			i += len(op)
+		default:
+			j := i
+			for j < len(s) && !unicode.IsSpace(rune(s[j])) && !strings.ContainsRune(`()"{}=!~<>`, rune(s[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			toks = append(toks, token{tokWord, s[i:j], i})
+			i = j
+		}
+	}
+	return append(toks, token{typ: tokEOF, pos: len(s)}), nil
+}
+
+// skipQuoted returns the position after the closing character of the
+// section starting at i. Closing characters in strings are skipped.
+func skipQuoted(s string, i int, end byte) (int, error) {
+	inString := end == '"'
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			if end == '"' {
+				return j + 1, nil
+			}
+			inString = !inString
+		case end:
+			if !inString {
+				return j + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated %q at position %d", s[i], i)
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// Parse parses a query.
+func Parse(s string) (Expr, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isWord(w string) bool {
+	t := p.peek()
+	return t.typ == tokWord && t.val == w
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("or") {
+		p.next()
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orExpr{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("and") {
+		p.next()
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = andExpr{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.next()
+	switch t.typ {
+	case tokLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.typ != tokRParen {
+			return nil, fmt.Errorf("expected \")\" at position %d, got %s", t.pos, t)
+		}
+		return e, nil
+	case tokMatchers:
+		ms, err := parse.Matchers(t.val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid matchers at position %d: %s", t.pos, err)
+		}
+		return matchersExpr(ms), nil
+	case tokWord:
+		return p.parsePredicate(t)
+	}
+	return nil, fmt.Errorf("unexpected %s at position %d", t, t.pos)
+}
+
+func (p *parser) parsePredicate(t token) (Expr, error) {
+	if t.val == "not" {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	if (t.val == "absent" || t.val == "present") && p.peek().typ == tokLParen {
+		p.next()
+		name := p.next()
+		if name.typ != tokWord || !model.LabelName(name.val).IsValid() {
+			return nil, fmt.Errorf("expected label name at position %d, got %s", name.pos, name)
+		}
+		if r := p.next(); r.typ != tokRParen {
+			return nil, fmt.Errorf("expected \")\" at position %d, got %s", r.pos, r)
+		}
+		typ := labels.MatchAbsent
+		if t.val == "present" {
+			typ = labels.MatchPresent
+		}
+		m, _ := labels.NewMatcher(typ, name.val, "")
+		return matchersExpr{m}, nil
+	}
+
+	if p.peek().typ != tokOp {
+		if state, ok := states[t.val]; ok {
+			return stateExpr(state), nil
+		}
+		return nil, fmt.Errorf("expected operator after %s at position %d", t, p.peek().pos)
+	}
+	op := p.next()
+	v := p.next()
+	if v.typ != tokWord && v.typ != tokString {
+		return nil, fmt.Errorf("expected value at position %d, got %s", v.pos, v)
+	}
+
+	if field, ok := timeFields[t.val]; ok {
+		switch op.val {
+		case "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("invalid operator %q for time %s at position %d", op.val, t.val, op.pos)
+		}
+		e := timeExpr{field: field, op: op.val}
+		if err := parseTime(v.val, &e); err != nil {
+			return nil, fmt.Errorf("invalid time at position %d: %s", v.pos, err)
+		}
+		return e, nil
+	}
+
+	if !model.LabelName(t.val).IsValid() {
+		return nil, fmt.Errorf("invalid label name %s at position %d", t, t.pos)
+	}
+	typ := map[string]labels.MatchType{
+		"=":  labels.MatchEqual,
+		"!=": labels.MatchNotEqual,
+		"=~": labels.MatchRegexp,
+		"!~": labels.MatchNotRegexp,
+		"<":  labels.MatchLessThan,
+		"<=": labels.MatchLessEqual,
+		">":  labels.MatchGreaterThan,
+		">=": labels.MatchGreaterEqual,
+	}[op.val]
+	m, err := labels.NewMatcher(typ, t.val, v.val)
+	if err != nil {
+		return nil, fmt.Errorf("invalid matcher at position %d: %s", t.pos, err)
+	}
+	return matchersExpr{m}, nil
+}
+
+// parseTime parses now, now with an offset like now-2h or an RFC 3339 time.
+func parseTime(s string, e *timeExpr) error {
+	if !strings.HasPrefix(s, "now") {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		e.t = t
+		return nil
+	}
+	s = s[len("now"):]
+	if s == "" {
+		return nil
+	}
+	sign := time.Duration(1)
+	switch s[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return fmt.Errorf("expected + or - after now")
+	}
+	d, err := model.ParseDuration(s[1:])
+	if err != nil {
+		return err
+	}
+	e.offset = sign * time.Duration(d)
+	return nil
+}
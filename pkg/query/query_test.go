@@ -0,0 +1,99 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestMatches(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	a := &types.Alert{
+		Alert: model.Alert{
+			Labels:   model.LabelSet{"alertname": "HighLatency", "severity": "critical", "team": "a", "level": "3"},
+			StartsAt: now.Add(-time.Hour),
+			EndsAt:   now.Add(time.Hour),
+		},
+		UpdatedAt: now.Add(-time.Minute),
+	}
+	silenced := types.AlertStatus{State: types.AlertStateSuppressed, SilencedBy: []string{"s1"}}
+
+	for _, tc := range []struct {
+		query   string
+		matches bool
+	}{
+		{`severity=critical`, true},
+		{`severity="critical"`, true},
+		{`severity!=critical`, false},
+		{`severity=~"crit.*|error"`, true},
+		{`severity!~"crit.*"`, false},
+		{`level>=3 and level<4`, true},
+		{`level>3`, false},
+		{`absent(cluster)`, true},
+		{`present(cluster)`, false},
+		{`{alertname="HighLatency",team="a"}`, true},
+		{`{alertname="HighLatency",team="b"}`, false},
+		{`{alertname="HighLatency"`, true},
+		{`team=b or team=a`, true},
+		{`team=b or team=c`, false},
+		{`team=a and severity=warning or alertname=HighLatency`, true},
+		{`team=a and (severity=warning or alertname=Other)`, false},
+		{`not team=b`, true},
+		{`not (team=a or team=b)`, false},
+		{`silenced`, true},
+		{`not silenced and not inhibited`, false},
+		{`suppressed and not active and not unprocessed`, true},
+		{`startsAt > now-2h`, true},
+		{`startsAt > now-30m`, false},
+		{`endsAt <= now+1h`, true},
+		{`updatedAt < now`, true},
+		{`startsAt >= 2018-06-01T11:00:00Z`, true},
+		{`startsAt < "2018-06-01T10:59:59Z"`, false},
+		{`{startsAt=""} and startsAt<now`, true},
+	} {
+		e, err := Parse(tc.query)
+		require.NoError(t, err, tc.query)
+		require.Equal(t, tc.matches, e.Matches(a, silenced, now), tc.query)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, q := range []string{
+		``,
+		`team`,
+		`team=`,
+		`team=a and`,
+		`(team=a`,
+		`team=a)`,
+		`team=a team=b`,
+		`team==a`,
+		`team=~"("`,
+		`level>high`,
+		`startsAt=now`,
+		`startsAt>yesterday`,
+		`startsAt>now*2h`,
+		`"unterminated`,
+		`absent(1)`,
+		`1team=a`,
+	} {
+		_, err := Parse(q)
+		require.Error(t, err, q)
+	}
+}
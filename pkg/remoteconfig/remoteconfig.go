@@ -0,0 +1,190 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remoteconfig loads the Alertmanager configuration from an
+// HTTP(S) URL, S3 (s3://bucket/key) or Google Cloud Storage (gs://bucket/key).
+//
+// Requests to S3 and GCS are signed with AWS signature version 4 if the
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are set,
+// which hold HMAC keys for GCS. The S3 region is read from AWS_REGION.
+package remoteconfig
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/pkg/objstore"
+)
+
+// IsRemote returns true if the configuration file name is a URL of a
+// supported scheme.
+func IsRemote(name string) bool {
+	u, err := url.Parse(name)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "s3", "gs":
+		return true
+	}
+	return false
+}
+
+// Source is a remote configuration file. It remembers the last fetched
+// content and its ETag to detect changes.
+type Source struct {
+	url    string
+	client *http.Client
+	sign   func(*http.Request)
+
+	mtx     sync.Mutex
+	etag    string
+	hash    [sha256.Size]byte
+	content []byte
+}
+
+// NewSource returns a source for the configuration at the given URL.
+func NewSource(rawurl string, client *http.Client) (*Source, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = &http.Client{Timeout: time.Minute}
+	}
+	s := &Source{url: rawurl, client: client}
+
+	var (
+		accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		region    string
+	)
+	switch u.Scheme {
+	case "http", "https":
+		return s, nil
+	case "s3":
+		region = os.Getenv("AWS_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		s.url = fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", u.Host, region, u.Path)
+	case "gs":
+		region = "auto"
+		s.url = fmt.Sprintf("https://storage.googleapis.com/%s%s", u.Host, u.Path)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q of configuration URL", u.Scheme)
+	}
+	if u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		return nil, fmt.Errorf("configuration URL %q must name a bucket and a key", rawurl)
+	}
+	if accessKey != "" && secretKey != "" {
+		s.sign = func(req *http.Request) {
+			objstore.SignV4(req, nil, region, "s3", accessKey, secretKey, time.Now())
+		}
+	}
+	return s, nil
+}
+
+// Fetch downloads the configuration unless its ETag did not change since
+// the last fetch. It returns true if the content changed.
+func (s *Source) Fetch(ctx context.Context) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, err
+	}
+	if s.etag != "" && s.content != nil {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	if s.sign != nil {
+		s.sign(req)
+	}
+	resp, err := ctxhttp.Do(ctx, s.client, req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return false, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return false, fmt.Errorf("unexpected status code %v fetching configuration", resp.StatusCode)
+	}
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	// Servers not supporting ETags always return the content, so changes
+	// are detected by its hash as well.
+	s.etag = resp.Header.Get("ETag")
+	h := sha256.Sum256(content)
+	changed := s.content == nil || h != s.hash
+	s.hash, s.content = h, content
+	return changed, nil
+}
+
+// Load fetches the configuration if it changed and parses it. Relative
+// paths in the configuration are resolved against the working directory.
+func (s *Source) Load(ctx context.Context) (*config.Config, []byte, error) {
+	if _, err := s.Fetch(ctx); err != nil {
+		return nil, nil, err
+	}
+	s.mtx.Lock()
+	content := s.content
+	s.mtx.Unlock()
+
+	cfg, err := config.Load(string(content))
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, content, nil
+}
+
+// Poll fetches the configuration every interval until the context is
+// canceled and calls changed whenever the content changed. Fetch errors
+// are passed to the error callback.
+func (s *Source) Poll(ctx context.Context, interval time.Duration, changed func(), errf func(error)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			ok, err := s.Fetch(ctx)
+			if err != nil {
+				errf(err)
+				continue
+			}
+			if ok {
+				changed()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remoteconfig
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const validConfig = `
+route:
+  receiver: team-X
+receivers:
+- name: team-X
+`
+
+// configServer serves a configuration, optionally with an ETag.
+type configServer struct {
+	mtx     sync.Mutex
+	content string
+	etag    string
+}
+
+func (s *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.etag != "" {
+		if r.Header.Get("If-None-Match") == s.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.etag)
+	}
+	w.Write([]byte(s.content))
+}
+
+func (s *configServer) set(content, etag string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.content, s.etag = content, etag
+}
+
+func TestIsRemote(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"alertmanager.yml":              false,
+		"/etc/alertmanager/config.yml":  false,
+		"http://example.com/am.yml":     true,
+		"https://example.com/am.yml":    true,
+		"s3://bucket/alertmanager.yml":  true,
+		"gs://bucket/alertmanager.yml":  true,
+		"ftp://example.com/config.yaml": false,
+	} {
+		require.Equal(t, expected, IsRemote(name), name)
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	os.Setenv("AWS_REGION", "eu-west-1")
+	defer os.Unsetenv("AWS_REGION")
+
+	s, err := NewSource("s3://bucket/am/config.yml", nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://bucket.s3.eu-west-1.amazonaws.com/am/config.yml", s.url)
+	require.Nil(t, s.sign)
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKIA")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	s, err = NewSource("gs://bucket/config.yml", nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://storage.googleapis.com/bucket/config.yml", s.url)
+	req, _ := http.NewRequest(http.MethodGet, s.url, nil)
+	s.sign(req)
+	require.True(t, strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIA/"))
+	require.Contains(t, req.Header.Get("Authorization"), "/auto/s3/aws4_request")
+
+	_, err = NewSource("s3://bucket/", nil)
+	require.EqualError(t, err, `configuration URL "s3://bucket/" must name a bucket and a key`)
+}
+
+func TestSourceFetch(t *testing.T) {
+	srv := &configServer{content: validConfig, etag: `"v1"`}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	s, err := NewSource(ts.URL, nil)
+	require.NoError(t, err)
+
+	conf, content, err := s.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "team-X", conf.Route.Receiver)
+	require.Equal(t, validConfig, string(content))
+
+	// Unchanged ETags are not downloaded again.
+	changed, err := s.Fetch(context.Background())
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	srv.set(strings.Replace(validConfig, "team-X", "team-Y", -1), `"v2"`)
+	changed, err = s.Fetch(context.Background())
+	require.NoError(t, err)
+	require.True(t, changed)
+	conf, _, err = s.Load(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "team-Y", conf.Route.Receiver)
+
+	// Without ETags, changes are detected by the content.
+	srv.set(validConfig, "")
+	changed, err = s.Fetch(context.Background())
+	require.NoError(t, err)
+	require.True(t, changed)
+	changed, err = s.Fetch(context.Background())
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Invalid configurations are rejected like local ones.
+	srv.set("route: {}", "")
+	_, _, err = s.Load(context.Background())
+	require.EqualError(t, err, "root route must specify a default receiver")
+}
+
+func TestSourcePoll(t *testing.T) {
+	srv := &configServer{content: validConfig, etag: `"v1"`}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	s, err := NewSource(ts.URL, nil)
+	require.NoError(t, err)
+	_, _, err = s.Load(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan struct{}, 10)
+	go s.Poll(ctx, 5*time.Millisecond, func() { changes <- struct{}{} }, func(err error) { t.Error(err) })
+
+	srv.set(validConfig+"\n", `"v2"`)
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not detected")
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapcrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/prometheus/alertmanager/pkg/objstore"
+)
+
+// decryptDataKey returns the plain text of the data key in the encrypted key
+// file by calling the Decrypt action of the AWS KMS API.
+func decryptDataKey(ctx context.Context, c *KMSConfig) ([]byte, error) {
+	content, err := ioutil.ReadFile(c.EncryptedKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	blob := strings.TrimSpace(string(content))
+	if _, err := base64.StdEncoding.DecodeString(blob); err != nil {
+		return nil, fmt.Errorf("invalid encrypted key in %s: %s", c.EncryptedKeyFile, err)
+	}
+
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", c.Region)
+	}
+	body, err := json.Marshal(struct {
+		CiphertextBlob string
+	}{
+		CiphertextBlob: blob,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	objstore.SignV4(req, body, c.Region, "kms", c.AccessKey, string(c.SecretKey), time.Now())
+
+	resp, err := ctxhttp.Do(ctx, &http.Client{Timeout: c.Timeout}, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, b)
+	}
+	var res struct {
+		Plaintext string
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(res.Plaintext)
+}
@@ -0,0 +1,231 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapcrypt encrypts snapshot files at rest with AES-GCM.
+//
+// The key is configured in a YAML file:
+//
+//	# A file holding the hex encoded 128, 192 or 256 bit key.
+//	key_file: /etc/alertmanager/snapshot.key
+//	# Alternatively a data key encrypted by AWS KMS, which is decrypted
+//	# once on startup.
+//	kms:
+//	  # A file holding the base64 encoded ciphertext blob of the data key,
+//	  # e.g. as returned by 'aws kms generate-data-key'.
+//	  encrypted_key_file: /etc/alertmanager/snapshot.key.enc
+//	  region: eu-west-1
+//	  # Defaults to https://kms.<region>.amazonaws.com.
+//	  endpoint: https://kms.example.com
+//	  access_key: AKIA...
+//	  secret_key: ...
+//	  timeout: 10s
+package snapcrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// magic prefixes encrypted snapshots. Snapshots without it are read as
+// plain text so encryption can be enabled for existing snapshots.
+var magic = []byte("AMSNAPENC1")
+
+// Config configures the key snapshots are encrypted with.
+type Config struct {
+	KeyFile string     `yaml:"key_file,omitempty"`
+	KMS     *KMSConfig `yaml:"kms,omitempty"`
+}
+
+// KMSConfig configures a data key encrypted by AWS KMS.
+type KMSConfig struct {
+	EncryptedKeyFile string        `yaml:"encrypted_key_file"`
+	Region           string        `yaml:"region"`
+	Endpoint         string        `yaml:"endpoint,omitempty"`
+	AccessKey        string        `yaml:"access_key"`
+	SecretKey        config.Secret `yaml:"secret_key"`
+	Timeout          time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if (c.KeyFile == "") == (c.KMS == nil) {
+		return fmt.Errorf("exactly one of key_file and kms must be set")
+	}
+	return nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *KMSConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = KMSConfig{Timeout: 10 * time.Second}
+	type plain KMSConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.EncryptedKeyFile == "" {
+		return fmt.Errorf("missing encrypted_key_file in kms configuration")
+	}
+	if c.Region == "" {
+		return fmt.Errorf("missing region in kms configuration")
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return fmt.Errorf("access_key and secret_key must be set for kms")
+	}
+	return nil
+}
+
+// LoadFile parses the encryption configuration in the given file.
+func LoadFile(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := yaml.UnmarshalStrict(content, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Cipher encrypts and decrypts snapshots. The methods of a nil Cipher pass
+// snapshots through unencrypted.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New returns a cipher using the given AES key.
+func New(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// NewFromConfig returns a cipher using the key described by the
+// configuration.
+func NewFromConfig(ctx context.Context, c *Config) (*Cipher, error) {
+	if c.KMS != nil {
+		key, err := decryptDataKey(ctx, c.KMS)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting data key: %s", err)
+		}
+		return New(key)
+	}
+	content, err := ioutil.ReadFile(c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid key in %s: %s", c.KeyFile, err)
+	}
+	return New(key)
+}
+
+// Encrypt returns the encrypted snapshot.
+func (c *Cipher) Encrypt(plaintext []byte) ([]byte, error) {
+	if c == nil {
+		return plaintext, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(magic)+len(nonce)+len(plaintext)+c.aead.Overhead())
+	out = append(out, magic...)
+	out = append(out, nonce...)
+	// The magic is authenticated so it cannot be stripped to make the
+	// ciphertext pass as a plain text snapshot.
+	return c.aead.Seal(out, nonce, plaintext, magic), nil
+}
+
+// Decrypt returns the plain text of an encrypted snapshot. Snapshots that
+// are not encrypted are returned unchanged.
+func (c *Cipher) Decrypt(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, errors.New("snapshot is encrypted but no encryption key is configured")
+	}
+	data = data[len(magic):]
+	if len(data) < c.aead.NonceSize() {
+		return nil, errors.New("encrypted snapshot is truncated")
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, magic)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting snapshot: %s", err)
+	}
+	return plaintext, nil
+}
+
+// Reader returns a reader of the plain text of the snapshot read from r.
+func (c *Cipher) Reader(r io.Reader) (io.Reader, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := c.Decrypt(data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(plaintext), nil
+}
+
+// Writer returns a writer encrypting the snapshot written to it. The
+// encrypted snapshot is written to w on Close, which does not close w.
+func (c *Cipher) Writer(w io.Writer) io.WriteCloser {
+	return &writer{c: c, w: w}
+}
+
+type writer struct {
+	c   *Cipher
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *writer) Close() error {
+	b, err := w.c.Encrypt(w.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(b)
+	return err
+}
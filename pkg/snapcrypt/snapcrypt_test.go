@@ -0,0 +1,200 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapcrypt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+var testKey = bytes.Repeat([]byte{0x42}, 32)
+
+func TestConfig(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{
+			in: `{key_file: /etc/key}`,
+		},
+		{
+			in: `{kms: {encrypted_key_file: /etc/key.enc, region: eu-west-1, access_key: a, secret_key: s}}`,
+		},
+		{
+			in:  `{}`,
+			err: "exactly one of key_file and kms must be set",
+		},
+		{
+			in:  `{key_file: /etc/key, kms: {encrypted_key_file: /etc/key.enc, region: eu-west-1, access_key: a, secret_key: s}}`,
+			err: "exactly one of key_file and kms must be set",
+		},
+		{
+			in:  `{kms: {encrypted_key_file: /etc/key.enc, access_key: a, secret_key: s}}`,
+			err: "missing region in kms configuration",
+		},
+	} {
+		var c Config
+		err := yaml.UnmarshalStrict([]byte(tc.in), &c)
+		if tc.err == "" {
+			require.NoError(t, err, tc.in)
+			continue
+		}
+		require.EqualError(t, err, tc.err, tc.in)
+	}
+}
+
+func TestCipher(t *testing.T) {
+	c, err := New(testKey)
+	require.NoError(t, err)
+
+	plaintext := []byte("alertname=InstanceDown instance=db-customer-42")
+	enc, err := c.Encrypt(plaintext)
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(enc, plaintext))
+
+	dec, err := c.Decrypt(enc)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, dec)
+
+	// Plain text snapshots are read unchanged.
+	dec, err = c.Decrypt(plaintext)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, dec)
+
+	// Tampered snapshots are rejected.
+	enc[len(enc)-1] ^= 1
+	_, err = c.Decrypt(enc)
+	require.Error(t, err)
+
+	other, err := New(bytes.Repeat([]byte{0x43}, 32))
+	require.NoError(t, err)
+	enc, err = c.Encrypt(plaintext)
+	require.NoError(t, err)
+	_, err = other.Decrypt(enc)
+	require.Error(t, err)
+
+	var none *Cipher
+	_, err = none.Decrypt(enc)
+	require.EqualError(t, err, "snapshot is encrypted but no encryption key is configured")
+}
+
+func TestReaderWriter(t *testing.T) {
+	c, err := New(testKey)
+	require.NoError(t, err)
+
+	for _, c := range []*Cipher{c, nil} {
+		var buf bytes.Buffer
+		w := c.Writer(&buf)
+		_, err = w.Write([]byte("part one, "))
+		require.NoError(t, err)
+		_, err = w.Write([]byte("part two"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		r, err := c.Reader(&buf)
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, "part one, part two", string(b))
+	}
+}
+
+func TestNewFromConfigKeyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapcrypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(strings.Repeat("42", 32)+"\n"), 0600))
+
+	c, err := NewFromConfig(context.Background(), &Config{KeyFile: keyFile})
+	require.NoError(t, err)
+	expected, err := New(testKey)
+	require.NoError(t, err)
+
+	enc, err := c.Encrypt([]byte("snapshot"))
+	require.NoError(t, err)
+	dec, err := expected.Decrypt(enc)
+	require.NoError(t, err)
+	require.Equal(t, "snapshot", string(dec))
+
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("not hex"), 0600))
+	_, err = NewFromConfig(context.Background(), &Config{KeyFile: keyFile})
+	require.Error(t, err)
+}
+
+func TestNewFromConfigKMS(t *testing.T) {
+	blob := base64.StdEncoding.EncodeToString([]byte("wrapped key"))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "TrentService.Decrypt", r.Header.Get("X-Amz-Target"))
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		require.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request")
+
+		var req struct {
+			CiphertextBlob string
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if req.CiphertextBlob != blob {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"KeyId":     "arn:aws:kms:eu-west-1:123456789012:key/test",
+			"Plaintext": base64.StdEncoding.EncodeToString(testKey),
+		})
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "snapcrypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "key.enc")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(blob+"\n"), 0600))
+
+	conf := &Config{KMS: &KMSConfig{
+		EncryptedKeyFile: keyFile,
+		Region:           "eu-west-1",
+		Endpoint:         srv.URL,
+		AccessKey:        "AKID",
+		SecretKey:        "secret",
+	}}
+	c, err := NewFromConfig(context.Background(), conf)
+	require.NoError(t, err)
+	expected, err := New(testKey)
+	require.NoError(t, err)
+
+	enc, err := c.Encrypt([]byte("snapshot"))
+	require.NoError(t, err)
+	dec, err := expected.Decrypt(enc)
+	require.NoError(t, err)
+	require.Equal(t, "snapshot", string(dec))
+
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString([]byte("other"))), 0600))
+	_, err = NewFromConfig(context.Background(), conf)
+	require.Error(t, err)
+}
@@ -0,0 +1,418 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing records spans of the path of alerts through Alertmanager
+// and exports them with the OpenTelemetry protocol.
+//
+// Spans are started with Start, which does nothing unless a tracer was set
+// with SetTracer. All methods of spans may be called on nil spans.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	exportedSpans = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "tracing_exported_spans_total",
+		Help:      "The total number of exported spans.",
+	})
+	droppedSpans = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "alertmanager",
+		Name:      "tracing_dropped_spans_total",
+		Help:      "The total number of spans dropped because the export queue was full or the export failed.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(exportedSpans, droppedSpans)
+}
+
+// Common span attributes.
+const (
+	AttrGroupKey    = "alertmanager.group_key"
+	AttrReceiver    = "alertmanager.receiver"
+	AttrIntegration = "alertmanager.integration"
+	AttrAlerts      = "alertmanager.alerts"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// Span is a timed operation of a trace.
+type Span struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	ParentID   SpanID
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Err        error
+
+	tracer *Tracer
+	mtx    sync.Mutex
+	ended  bool
+}
+
+// SetAttribute sets an attribute of the span. It must not be called after
+// the span is finished.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.Attributes[key] = value
+}
+
+// SetError marks the span as failed with the given error. A nil error is
+// ignored.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.Err = err
+}
+
+// Finish ends the span and queues it for export. Only the first call has
+// an effect.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mtx.Lock()
+	if s.ended {
+		s.mtx.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mtx.Unlock()
+
+	s.tracer.queue(s)
+}
+
+type spanKey struct{}
+
+// SpanFromContext returns the span of the context or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithSpan returns a context holding the span. Spans started with
+// the context are children of the span.
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// Start starts a span with the given name and attributes given as key
+// value pairs. The span is a child of the span of the context. If no tracer
+// is set or the trace is not sampled, the returned span is nil.
+func Start(ctx context.Context, name string, attrs ...string) (context.Context, *Span) {
+	t := getTracer()
+	if t == nil {
+		return ctx, nil
+	}
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		if rc, ok := ctx.Value(remoteKey{}).(remoteContext); ok {
+			parent = &Span{TraceID: rc.traceID, SpanID: rc.spanID}
+		} else if !t.sample() {
+			return ctx, nil
+		}
+	}
+
+	s := &Span{
+		Name:       name,
+		Start:      time.Now(),
+		Attributes: make(map[string]string, len(attrs)/2),
+		tracer:     t,
+	}
+	if parent != nil {
+		s.TraceID = parent.TraceID
+		s.ParentID = parent.SpanID
+	} else {
+		rand.Read(s.TraceID[:])
+	}
+	rand.Read(s.SpanID[:])
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.Attributes[attrs[i]] = attrs[i+1]
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+type remoteKey struct{}
+
+type remoteContext struct {
+	traceID TraceID
+	spanID  SpanID
+}
+
+// Extract returns a context continuing the trace of the W3C traceparent
+// header of the request, which is sampled regardless of the sample ratio.
+// Without a valid sampled traceparent header, the context is returned
+// unchanged.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	// The header has the format version-traceid-parentid-flags.
+	parts := strings.Split(h.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ctx
+	}
+	var rc remoteContext
+	tid, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return ctx
+	}
+	sid, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return ctx
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 || flags[0]&1 == 0 {
+		return ctx
+	}
+	copy(rc.traceID[:], tid)
+	copy(rc.spanID[:], sid)
+	if rc.traceID == (TraceID{}) || rc.spanID == (SpanID{}) {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, rc)
+}
+
+// Exporter exports finished spans.
+type Exporter interface {
+	Export(ctx context.Context, spans []*Span) error
+}
+
+// ExporterFunc is a function implementing Exporter.
+type ExporterFunc func(context.Context, []*Span) error
+
+// Export implements Exporter.
+func (f ExporterFunc) Export(ctx context.Context, spans []*Span) error {
+	return f(ctx, spans)
+}
+
+// Options configure a tracer.
+type Options struct {
+	// The fraction of traces that are sampled, between 0 and 1.
+	SampleRatio float64
+	// Finished spans are exported in batches of at most BatchSize spans
+	// every FlushInterval. At most QueueSize spans wait for export.
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+}
+
+// Tracer collects finished spans and exports them in batches.
+type Tracer struct {
+	exporter Exporter
+	opts     Options
+	logger   log.Logger
+
+	mtx     sync.Mutex
+	pending []*Span
+
+	flushc chan struct{}
+	stopc  chan struct{}
+	done   chan struct{}
+}
+
+// NewTracer returns a new tracer exporting spans with the exporter. The
+// tracer has to be run to export spans.
+func NewTracer(e Exporter, o Options, l log.Logger) *Tracer {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 512
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = 5 * time.Second
+	}
+	if o.QueueSize <= 0 {
+		o.QueueSize = 2048
+	}
+	if l == nil {
+		l = log.NewNopLogger()
+	}
+	return &Tracer{
+		exporter: e,
+		opts:     o,
+		logger:   l,
+		flushc:   make(chan struct{}, 1),
+		stopc:    make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// sample returns true if a new trace is sampled.
+func (t *Tracer) sample() bool {
+	if t.opts.SampleRatio >= 1 {
+		return true
+	}
+	if t.opts.SampleRatio <= 0 {
+		return false
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	return float64(binary.BigEndian.Uint64(b[:])>>11)/(1<<53) < t.opts.SampleRatio
+}
+
+func (t *Tracer) queue(s *Span) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if len(t.pending) >= t.opts.QueueSize {
+		droppedSpans.Inc()
+		return
+	}
+	t.pending = append(t.pending, s)
+	if len(t.pending) >= t.opts.BatchSize {
+		select {
+		case t.flushc <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run exports the finished spans until the tracer is stopped.
+func (t *Tracer) Run() {
+	defer close(t.done)
+
+	tick := time.NewTicker(t.opts.FlushInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+		case <-t.flushc:
+		case <-t.stopc:
+			t.flush()
+			return
+		}
+		t.flush()
+	}
+}
+
+// Stop exports the remaining spans and stops the tracer.
+func (t *Tracer) Stop() {
+	close(t.stopc)
+	<-t.done
+}
+
+func (t *Tracer) flush() {
+	for {
+		t.mtx.Lock()
+		n := len(t.pending)
+		if n > t.opts.BatchSize {
+			n = t.opts.BatchSize
+		}
+		batch := t.pending[:n:n]
+		t.pending = t.pending[n:]
+		t.mtx.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), t.opts.FlushInterval)
+		err := t.exporter.Export(ctx, batch)
+		cancel()
+		if err != nil {
+			droppedSpans.Add(float64(len(batch)))
+			level.Error(t.logger).Log("msg", "Exporting spans failed", "spans", len(batch), "err", err)
+			continue
+		}
+		exportedSpans.Add(float64(len(batch)))
+	}
+}
+
+var (
+	globalMtx    sync.RWMutex
+	globalTracer *Tracer
+)
+
+// SetTracer sets the tracer of spans started with Start. A nil tracer
+// disables tracing.
+func SetTracer(t *Tracer) {
+	globalMtx.Lock()
+	defer globalMtx.Unlock()
+
+	globalTracer = t
+}
+
+func getTracer() *Tracer {
+	globalMtx.RLock()
+	defer globalMtx.RUnlock()
+
+	return globalTracer
+}
+
+// NewLogExporter returns an exporter logging the spans.
+func NewLogExporter(l log.Logger) Exporter {
+	return ExporterFunc(func(_ context.Context, spans []*Span) error {
+		for _, s := range spans {
+			kv := []interface{}{
+				"msg", "Span",
+				"name", s.Name,
+				"trace_id", hex.EncodeToString(s.TraceID[:]),
+				"span_id", hex.EncodeToString(s.SpanID[:]),
+				"parent_id", hex.EncodeToString(s.ParentID[:]),
+				"duration", s.End.Sub(s.Start),
+			}
+			for k, v := range s.Attributes {
+				kv = append(kv, k, v)
+			}
+			if s.Err != nil {
+				kv = append(kv, "err", s.Err)
+			}
+			level.Info(l).Log(kv...)
+		}
+		return nil
+	})
+}
+
+// String returns the hex encoding of the trace ID.
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// String returns the hex encoding of the span ID.
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// Traceparent returns the W3C traceparent header value of the span.
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
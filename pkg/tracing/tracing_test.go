@@ -0,0 +1,152 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is an exporter keeping the exported spans.
+type recorder struct {
+	mtx   sync.Mutex
+	spans []*Span
+}
+
+func (r *recorder) Export(_ context.Context, spans []*Span) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func TestStart(t *testing.T) {
+	// Without a tracer no spans are started.
+	ctx, s := Start(context.Background(), "noop")
+	require.Nil(t, s)
+	require.Nil(t, SpanFromContext(ctx))
+	s.SetAttribute("k", "v")
+	s.SetError(errors.New("ignored"))
+	s.Finish()
+
+	rec := &recorder{}
+	tracer := NewTracer(rec, Options{SampleRatio: 1}, nil)
+	SetTracer(tracer)
+	defer SetTracer(nil)
+	go tracer.Run()
+
+	ctx, root := Start(context.Background(), "root", AttrGroupKey, "{}:{}")
+	_, child := Start(ctx, "child")
+	child.SetError(errors.New("failed"))
+	child.Finish()
+	root.SetAttribute(AttrReceiver, "team-X")
+	root.Finish()
+	root.Finish()
+
+	tracer.Stop()
+
+	require.Len(t, rec.spans, 2)
+	c, r := rec.spans[0], rec.spans[1]
+	require.Equal(t, "child", c.Name)
+	require.Equal(t, "root", r.Name)
+	require.Equal(t, r.TraceID, c.TraceID)
+	require.Equal(t, r.SpanID, c.ParentID)
+	require.Equal(t, SpanID{}, r.ParentID)
+	require.EqualError(t, c.Err, "failed")
+	require.Equal(t, map[string]string{AttrGroupKey: "{}:{}", AttrReceiver: "team-X"}, r.Attributes)
+	require.False(t, r.End.Before(r.Start))
+}
+
+func TestSampling(t *testing.T) {
+	tracer := NewTracer(&recorder{}, Options{SampleRatio: 0}, nil)
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	_, s := Start(context.Background(), "root")
+	require.Nil(t, s)
+
+	// Sampled remote parents are traced regardless of the ratio.
+	h := http.Header{}
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, s = Start(Extract(context.Background(), h), "root")
+	require.NotNil(t, s)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.TraceID.String())
+	require.Equal(t, "00f067aa0ba902b7", s.ParentID.String())
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+s.SpanID.String()+"-01", s.Traceparent())
+
+	for _, tp := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bx-01",
+	} {
+		h.Set("traceparent", tp)
+		_, s = Start(Extract(context.Background(), h), "root")
+		require.Nil(t, s, tp)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var req otlpRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/traces", r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+	}))
+	defer srv.Close()
+
+	_, err := NewOTLPExporter("grpc://localhost:4317", "alertmanager", nil)
+	require.Error(t, err)
+
+	e, err := NewOTLPExporter(srv.URL, "alertmanager", nil)
+	require.NoError(t, err)
+
+	s := &Span{
+		TraceID:    TraceID{1},
+		SpanID:     SpanID{2},
+		ParentID:   SpanID{3},
+		Name:       "notify.send",
+		Attributes: map[string]string{AttrIntegration: "webhook", AttrReceiver: "team-X"},
+		Err:        errors.New("failed"),
+	}
+	require.NoError(t, e.Export(context.Background(), []*Span{s}))
+
+	require.Len(t, req.ResourceSpans, 1)
+	rs := req.ResourceSpans[0]
+	require.Equal(t, "service.name", rs.Resource.Attributes[0].Key)
+	require.Len(t, rs.ScopeSpans[0].Spans, 1)
+	os := rs.ScopeSpans[0].Spans[0]
+	require.Equal(t, "01000000000000000000000000000000", os.TraceID)
+	require.Equal(t, "0200000000000000", os.SpanID)
+	require.Equal(t, "0300000000000000", os.ParentSpanID)
+	require.Equal(t, []otlpKeyValue{
+		{Key: AttrIntegration, Value: otlpValue{StringValue: "webhook"}},
+		{Key: AttrReceiver, Value: otlpValue{StringValue: "team-X"}},
+	}, os.Attributes)
+	require.Equal(t, otlpStatus{Code: otlpStatusError, Message: "failed"}, os.Status)
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	require.Error(t, e.Export(context.Background(), []*Span{s}))
+}
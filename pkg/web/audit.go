@@ -0,0 +1,243 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/prometheus/alertmanager/audit"
+)
+
+var numAuditLogFailures = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "http_audit_log_failures_total",
+	Help:      "The total number of API requests that could not be written to the audit log.",
+})
+
+func init() {
+	prometheus.Register(numAuditLogFailures)
+}
+
+// AuditLogConfig configures the audit log of the requests changing the
+// state of Alertmanager, which are all requests but GET, HEAD and OPTIONS
+// requests. Exactly one of the file and syslog must be set.
+type AuditLogConfig struct {
+	// File is the path of the file the entries are appended to as JSON
+	// lines.
+	File string `yaml:"file,omitempty"`
+	// MaxSizeBytes is the size after which the file is rotated. Defaults
+	// to 10MiB.
+	MaxSizeBytes int64 `yaml:"max_size_bytes,omitempty"`
+	// MaxFiles is the number of rotated files that are kept. Defaults
+	// to 5.
+	MaxFiles int           `yaml:"max_files,omitempty"`
+	Syslog   *SyslogConfig `yaml:"syslog,omitempty"`
+	// MaxPayloadBytes is the maximum number of bytes of request bodies
+	// recorded in the entries. Defaults to 1024, -1 records no bodies.
+	MaxPayloadBytes int `yaml:"max_payload_bytes,omitempty"`
+}
+
+// SyslogConfig configures sending audit entries to syslog.
+type SyslogConfig struct {
+	// Network and Address of the syslog server, for example udp and
+	// localhost:514. The local syslog server is used if they are empty.
+	Network string `yaml:"network,omitempty"`
+	Address string `yaml:"address,omitempty"`
+	// Tag of the messages. Defaults to alertmanager.
+	Tag string `yaml:"tag,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *AuditLogConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AuditLogConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if (c.File == "") == (c.Syslog == nil) {
+		return fmt.Errorf("exactly one of file and syslog must be set in audit_log")
+	}
+	if c.MaxPayloadBytes == 0 {
+		c.MaxPayloadBytes = 1024
+	}
+	if c.MaxSizeBytes == 0 {
+		c.MaxSizeBytes = 10 << 20
+	}
+	if c.MaxFiles == 0 {
+		c.MaxFiles = 5
+	}
+	if c.Syslog != nil && c.Syslog.Tag == "" {
+		c.Syslog.Tag = "alertmanager"
+	}
+	return nil
+}
+
+// AuditEntry is a request recorded in the audit log.
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Identity is the authenticated identity of the client, prefixed by
+	// the authentication method, like user:alice. It is empty if the
+	// request was not authenticated.
+	Identity string `json:"identity,omitempty"`
+	SourceIP string `json:"sourceIP"`
+	Method   string `json:"method"`
+	// Path is the path and the query of the request.
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	// Payload holds the beginning of the request body. The bodies of
+	// configuration changes are left out as they may contain secrets.
+	Payload string `json:"payload,omitempty"`
+	// PayloadBytes and PayloadSHA256 are the size and the hash of the
+	// read request body.
+	PayloadBytes  int64  `json:"payloadBytes"`
+	PayloadSHA256 string `json:"payloadSHA256,omitempty"`
+}
+
+// auditSink writes audit entries.
+type auditSink interface {
+	write(b []byte) error
+	close() error
+}
+
+// fileSink appends audit entries to a rotating file.
+type fileSink struct {
+	l *audit.Log
+}
+
+func (s *fileSink) write(b []byte) error {
+	return s.l.Append(b)
+}
+
+func (s *fileSink) close() error {
+	return s.l.Close()
+}
+
+type auditLog struct {
+	sink       auditSink
+	maxPayload int
+	prefix     string
+	now        func() time.Time
+}
+
+func newAuditLog(c *AuditLogConfig, prefix string) (*auditLog, error) {
+	l := &auditLog{maxPayload: c.MaxPayloadBytes, prefix: prefix, now: time.Now}
+	if c.File != "" {
+		f, err := audit.New(audit.Options{
+			Path:     c.File,
+			MaxSize:  c.MaxSizeBytes,
+			MaxFiles: c.MaxFiles,
+		})
+		if err != nil {
+			return nil, err
+		}
+		l.sink = &fileSink{l: f}
+		return l, nil
+	}
+	s, err := newSyslogSink(c.Syslog)
+	if err != nil {
+		return nil, err
+	}
+	l.sink = s
+	return l, nil
+}
+
+// audited returns whether requests with the method are recorded.
+func audited(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	return true
+}
+
+// record serves the request with the given function, which returns the
+// authenticated identity, and records it in the audit log.
+func (l *auditLog) record(w http.ResponseWriter, r *http.Request, serve func(http.ResponseWriter, *http.Request) string) {
+	max := l.maxPayload
+	if strings.HasPrefix(strings.TrimPrefix(r.URL.Path, l.prefix), "/api/v1/config") {
+		max = 0
+	}
+	body := &auditBody{ReadCloser: r.Body, max: max, hash: sha256.New()}
+	if r.Body != nil {
+		r.Body = body
+	}
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+	e := &AuditEntry{
+		Timestamp: l.now().UTC(),
+		SourceIP:  clientIP(r),
+		Method:    r.Method,
+		Path:      r.URL.RequestURI(),
+	}
+	e.Identity = serve(sw, r)
+	e.Status = sw.status
+	e.Payload = string(body.payload)
+	e.PayloadBytes = body.n
+	if body.n > 0 {
+		e.PayloadSHA256 = hex.EncodeToString(body.hash.Sum(nil))
+	}
+
+	b, err := json.Marshal(e)
+	if err == nil {
+		err = l.sink.write(b)
+	}
+	if err != nil {
+		numAuditLogFailures.Inc()
+	}
+}
+
+// auditBody keeps the beginning of the request body and hashes it while it
+// is read.
+type auditBody struct {
+	io.ReadCloser
+	max     int
+	payload []byte
+	n       int64
+	hash    hash.Hash
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.hash.Write(p[:n])
+		b.n += int64(n)
+		if rest := b.max - len(b.payload); rest > 0 {
+			if rest > n {
+				rest = n
+			}
+			b.payload = append(b.payload, p[:rest]...)
+		}
+	}
+	return n, err
+}
+
+// statusWriter records the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
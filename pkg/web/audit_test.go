@@ -0,0 +1,166 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "audit.log")
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	c, err := loadConfig(t, fmt.Sprintf(`
+basic_auth_users:
+  alice: %s
+  bob: %s
+authorization:
+  roles:
+    admin: [alice]
+  default_role: viewer
+audit_log:
+  file: %s
+  max_payload_bytes: 4
+`, hash, hash, fn))
+	require.NoError(t, err)
+
+	h, err := NewHandler(c, "/am", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	require.NoError(t, err)
+	defer h.Close()
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.audit.now = func() time.Time { return now }
+
+	do := func(method, path, user, body string) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.RemoteAddr = "10.0.0.1:1234"
+		if user != "" {
+			r.SetBasicAuth(user, "secret")
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	do("GET", "/am/api/v1/silences", "alice", "")
+	do("POST", "/am/api/v1/silences?x=y", "alice", `{"id":"1"}`)
+	do("DELETE", "/am/api/v1/silence/1", "bob", "")
+	do("POST", "/am/api/v1/alerts", "", "[]")
+	do("POST", "/am/api/v1/config", "alice", "secret")
+
+	f, err := os.Open(fn)
+	require.NoError(t, err)
+	defer f.Close()
+	var entries []*AuditEntry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var e AuditEntry
+		require.NoError(t, json.Unmarshal(s.Bytes(), &e))
+		entries = append(entries, &e)
+	}
+	require.NoError(t, s.Err())
+
+	require.Equal(t, []*AuditEntry{
+		{
+			Timestamp:     now,
+			Identity:      "user:alice",
+			SourceIP:      "10.0.0.1",
+			Method:        "POST",
+			Path:          "/am/api/v1/silences?x=y",
+			Status:        http.StatusAccepted,
+			Payload:       `{"id`,
+			PayloadBytes:  10,
+			PayloadSHA256: "5811967f540d300d249ab30ae681359a7815fdb5d3dc71a94be1d491006a6b27",
+		},
+		{
+			Timestamp: now,
+			Identity:  "user:bob",
+			SourceIP:  "10.0.0.1",
+			Method:    "DELETE",
+			Path:      "/am/api/v1/silence/1",
+			Status:    http.StatusForbidden,
+		},
+		{
+			Timestamp: now,
+			SourceIP:  "10.0.0.1",
+			Method:    "POST",
+			Path:      "/am/api/v1/alerts",
+			Status:    http.StatusUnauthorized,
+		},
+		{
+			Timestamp:     now,
+			Identity:      "user:alice",
+			SourceIP:      "10.0.0.1",
+			Method:        "POST",
+			Path:          "/am/api/v1/config",
+			Status:        http.StatusAccepted,
+			PayloadBytes:  6,
+			PayloadSHA256: "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b",
+		},
+	}, entries)
+
+	for _, content := range []string{
+		"audit_log: {}\n",
+		"audit_log:\n  file: audit.log\n  syslog: {}\n",
+	} {
+		_, err := loadConfig(t, content)
+		require.Error(t, err, content)
+	}
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "audit.log")
+
+	c, err := loadConfig(t, fmt.Sprintf(`
+audit_log:
+  file: %s
+  max_size_bytes: 200
+  max_files: 1
+`, fn))
+	require.NoError(t, err)
+
+	h, err := NewHandler(c, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/alerts", strings.NewReader("[]")))
+	}
+	require.NoError(t, h.Close())
+
+	for _, p := range []string{fn, fn + ".1"} {
+		fi, err := os.Stat(p)
+		require.NoError(t, err)
+		require.True(t, fi.Size() <= 200, p)
+	}
+	_, err = os.Stat(fn + ".2")
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,207 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Role is the role of an authenticated identity, which authorizes its
+// requests.
+type Role string
+
+// Roles of identities. Each role includes the permissions of the roles
+// before it.
+const (
+	// RoleViewer may read alerts, silences and the status.
+	RoleViewer Role = "viewer"
+	// RoleSilencer may also create, update and expire silences, post
+	// alerts and acknowledge and annotate alerts.
+	RoleSilencer Role = "silencer"
+	// RoleAdmin may do anything, like changing or reloading the
+	// configuration.
+	RoleAdmin Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleSilencer: 2,
+	RoleAdmin:    3,
+}
+
+// includes returns whether the role has the permissions of the other role.
+func (r Role) includes(o Role) bool {
+	return roleLevels[r] > 0 && roleLevels[r] >= roleLevels[o]
+}
+
+func validateRole(name string, r Role) error {
+	if roleLevels[r] == 0 {
+		return fmt.Errorf("unknown %s %q", name, r)
+	}
+	return nil
+}
+
+// AuthorizationConfig configures the roles of the identities authenticated
+// by client certificates, basic authentication or bearer tokens. Without
+// it, these identities may do anything. The roles of users authenticated by
+// OpenID Connect are mapped from their claims.
+type AuthorizationConfig struct {
+	// Roles maps roles to the identities granted them: the names of basic
+	// authentication users and the common names of client certificates.
+	// Identities are granted the highest of their roles.
+	Roles map[Role][]string `yaml:"roles,omitempty"`
+	// DefaultRole is the role of identities without any granted role. Such
+	// identities are denied if it is empty.
+	DefaultRole Role `yaml:"default_role,omitempty"`
+	// BearerTokenRole is the role of requests authenticated by a bearer
+	// token. Defaults to the default role.
+	BearerTokenRole Role `yaml:"bearer_token_role,omitempty"`
+	// Rules set the roles required by requests. They are checked before the
+	// default rules and the first matching rule applies.
+	Rules []*AuthorizationRule `yaml:"rules,omitempty"`
+}
+
+// AuthorizationRule sets the role required by matching requests.
+type AuthorizationRule struct {
+	// Methods are the matched request methods. Any method matches if empty.
+	Methods []string `yaml:"methods,omitempty"`
+	// PathPrefix is the prefix of the matched paths, relative to the route
+	// prefix. Any path matches if empty.
+	PathPrefix string `yaml:"path_prefix,omitempty"`
+	Role       Role   `yaml:"role"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *AuthorizationConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AuthorizationConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	for r := range c.Roles {
+		if err := validateRole("role", r); err != nil {
+			return err
+		}
+	}
+	if c.DefaultRole != "" {
+		if err := validateRole("default_role", c.DefaultRole); err != nil {
+			return err
+		}
+	}
+	if c.BearerTokenRole != "" {
+		if err := validateRole("bearer_token_role", c.BearerTokenRole); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *AuthorizationRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain AuthorizationRule
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	for i, m := range r.Methods {
+		r.Methods[i] = strings.ToUpper(m)
+	}
+	return validateRole("role of rule", r.Role)
+}
+
+func (r *AuthorizationRule) matches(req *http.Request, path string) bool {
+	return matchRequest(r.Methods, r.PathPrefix, req, path)
+}
+
+// matchRequest returns whether the request, whose path is relative to the
+// route prefix, has one of the methods, if any, and the path prefix.
+func matchRequest(methods []string, prefix string, r *http.Request, path string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	if len(methods) == 0 {
+		return true
+	}
+	for _, m := range methods {
+		if m == r.Method {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultRules let viewers read and run read-only checks, silencers change
+// silences, post, acknowledge and annotate alerts, and admins do anything
+// else.
+var defaultRules = []*AuthorizationRule{
+	{Methods: []string{"GET", "HEAD", "OPTIONS"}, Role: RoleViewer},
+	{Methods: []string{"POST"}, PathPrefix: "/api/v1/silences/preview", Role: RoleViewer},
+	{Methods: []string{"POST"}, PathPrefix: "/api/v1/routes/test", Role: RoleViewer},
+	{Methods: []string{"POST"}, PathPrefix: "/api/v1/config/diff", Role: RoleViewer},
+	{Methods: []string{"POST"}, PathPrefix: "/api/v1/config/validate", Role: RoleViewer},
+	// Matches the silences, a single silence and the silence templates.
+	{PathPrefix: "/api/v1/silence", Role: RoleSilencer},
+	{PathPrefix: "/api/v1/alert/", Role: RoleSilencer},
+	{Methods: []string{"POST"}, PathPrefix: "/api/v1/alerts", Role: RoleSilencer},
+	{Role: RoleAdmin},
+}
+
+type authorizer struct {
+	roles       map[string]Role
+	defaultRole Role
+	tokenRole   Role
+	rules       []*AuthorizationRule
+}
+
+func newAuthorizer(c *AuthorizationConfig) *authorizer {
+	if c == nil {
+		return &authorizer{defaultRole: RoleAdmin, tokenRole: RoleAdmin, rules: defaultRules}
+	}
+	a := &authorizer{
+		roles:       map[string]Role{},
+		defaultRole: c.DefaultRole,
+		tokenRole:   c.BearerTokenRole,
+		rules:       append(append([]*AuthorizationRule{}, c.Rules...), defaultRules...),
+	}
+	if a.tokenRole == "" {
+		a.tokenRole = a.defaultRole
+	}
+	for r, ids := range c.Roles {
+		for _, id := range ids {
+			if roleLevels[r] > roleLevels[a.roles[id]] {
+				a.roles[id] = r
+			}
+		}
+	}
+	return a
+}
+
+// role returns the role of the identity.
+func (a *authorizer) role(id string) Role {
+	if r, ok := a.roles[id]; ok {
+		return r
+	}
+	return a.defaultRole
+}
+
+// requiredRole returns the role required for the request, whose path is
+// relative to the route prefix.
+func (a *authorizer) requiredRole(r *http.Request, path string) Role {
+	for _, rule := range a.rules {
+		if rule.matches(r, path) {
+			return rule.Role
+		}
+	}
+	return RoleAdmin
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRoleIncludes(t *testing.T) {
+	require.False(t, Role("").includes(RoleViewer))
+	require.True(t, RoleViewer.includes(RoleViewer))
+	require.True(t, RoleSilencer.includes(RoleViewer))
+	require.False(t, RoleSilencer.includes(RoleAdmin))
+	require.True(t, RoleAdmin.includes(RoleSilencer))
+}
+
+func TestAuthorization(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	c, err := loadConfig(t, fmt.Sprintf(`
+basic_auth_users:
+  alice: %s
+  bob: %s
+  carol: %s
+bearer_tokens: [token]
+authorization:
+  roles:
+    silencer: [alice]
+    admin: [carol, prometheus]
+  default_role: viewer
+  bearer_token_role: admin
+  rules:
+  - methods: [get]
+    path_prefix: /api/v1/config
+    role: admin
+`, hash, hash, hash))
+	require.NoError(t, err)
+	c.TLSServerConfig = &TLSServerConfig{ClientAuthType: "RequireAndVerifyClientCert"}
+
+	h, err := NewHandler(c, "/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	require.NoError(t, err)
+
+	user := func(name string) func(*http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(name, "secret") }
+	}
+	for _, tc := range []struct {
+		method, path string
+		prepare      func(*http.Request)
+		code         int
+	}{
+		{"GET", "/api/v1/alerts", user("bob"), http.StatusOK},
+		{"POST", "/api/v1/silences", user("bob"), http.StatusForbidden},
+		{"POST", "/api/v1/silences/preview", user("bob"), http.StatusOK},
+		{"POST", "/api/v1/alert/abc/ack", user("bob"), http.StatusForbidden},
+		{"POST", "/api/v1/alert/abc/annotations", user("bob"), http.StatusForbidden},
+		{"GET", "/api/v1/config", user("bob"), http.StatusForbidden},
+		{"POST", "/api/v1/alerts", user("bob"), http.StatusForbidden},
+
+		{"POST", "/api/v1/silences", user("alice"), http.StatusOK},
+		{"DELETE", "/api/v1/silence/abc", user("alice"), http.StatusOK},
+		{"POST", "/api/v1/silence_templates/maintenance", user("alice"), http.StatusOK},
+		{"POST", "/api/v1/alert/abc/ack", user("alice"), http.StatusOK},
+		{"POST", "/api/v1/alert/abc/annotations", user("alice"), http.StatusOK},
+		{"POST", "/api/v1/alerts", user("alice"), http.StatusOK},
+		{"POST", "/-/reload", user("alice"), http.StatusForbidden},
+
+		{"POST", "/-/reload", user("carol"), http.StatusOK},
+		{"GET", "/api/v1/config", user("carol"), http.StatusOK},
+
+		{"POST", "/api/v1/alerts", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+
+		{"POST", "/-/reload", func(r *http.Request) {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: "prometheus"}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}, http.StatusOK},
+		{"POST", "/-/reload", func(r *http.Request) {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: "grafana"}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}, http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		tc.prepare(r)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		require.Equal(t, tc.code, w.Code, "%s %s", tc.method, tc.path)
+	}
+
+	for _, content := range []string{
+		"authorization:\n  roles:\n    editor: [alice]\n",
+		"authorization:\n  default_role: root\n",
+		"authorization:\n  rules:\n  - path_prefix: /api\n",
+	} {
+		_, err := loadConfig(t, content)
+		require.Error(t, err, content)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var numLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "alertmanager",
+	Name:      "http_requests_limited_total",
+	Help:      "The total number of HTTP requests rejected for exceeding a rate or body size limit.",
+}, []string{"path_prefix", "reason"})
+
+func init() {
+	prometheus.Register(numLimitedRequests)
+}
+
+// RequestLimit limits the rate and the body size of the requests matching
+// its methods and path prefix. All matching limits apply.
+type RequestLimit struct {
+	// Methods are the matched request methods. Any method matches if empty.
+	Methods []string `yaml:"methods,omitempty"`
+	// PathPrefix is the prefix of the matched paths, relative to the route
+	// prefix. Any path matches if empty.
+	PathPrefix string `yaml:"path_prefix,omitempty"`
+	// Rate is the number of requests per second allowed per client. Clients
+	// are identified by their authenticated identity, or else by their IP
+	// address. 0 means no limit.
+	Rate float64 `yaml:"rate,omitempty"`
+	// Burst is the number of requests a client may send at once. Defaults
+	// to the rate, but at least 1.
+	Burst int `yaml:"burst,omitempty"`
+	// MaxBodyBytes is the maximum size of request bodies. 0 means no limit.
+	MaxBodyBytes int64 `yaml:"max_body_bytes,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (l *RequestLimit) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RequestLimit
+	if err := unmarshal((*plain)(l)); err != nil {
+		return err
+	}
+	for i, m := range l.Methods {
+		l.Methods[i] = strings.ToUpper(m)
+	}
+	if l.Rate < 0 || l.Burst < 0 || l.MaxBodyBytes < 0 {
+		return fmt.Errorf("rate, burst and max_body_bytes of request limits must not be negative")
+	}
+	if l.Rate == 0 && l.MaxBodyBytes == 0 {
+		return fmt.Errorf("request limit without rate or max_body_bytes")
+	}
+	if l.Burst == 0 {
+		l.Burst = int(math.Max(1, math.Ceil(l.Rate)))
+	}
+	return nil
+}
+
+// bucket is the token bucket of a client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// requestLimit holds the token buckets of the clients of a limit.
+type requestLimit struct {
+	*RequestLimit
+
+	mtx     sync.Mutex
+	buckets map[string]*bucket
+	lastGC  time.Time
+}
+
+// take consumes a token of the client if one is available. Otherwise it
+// returns the time until the next token becomes available.
+func (l *requestLimit) take(client string, now time.Time) (bool, time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	burst := float64(l.Burst)
+	if now.Sub(l.lastGC) > time.Minute {
+		// Full buckets are equal to new ones.
+		for c, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.Rate >= burst {
+				delete(l.buckets, c)
+			}
+		}
+		l.lastGC = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+}
+
+type limiter struct {
+	limits []*requestLimit
+	now    func() time.Time
+}
+
+func newLimiter(limits []*RequestLimit) *limiter {
+	l := &limiter{now: time.Now}
+	for _, rl := range limits {
+		l.limits = append(l.limits, &requestLimit{RequestLimit: rl, buckets: map[string]*bucket{}})
+	}
+	return l
+}
+
+// clientIP returns the IP address of the client of the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allow applies the matching limits to the request, whose path is relative
+// to the route prefix, of the client with the given identity. It responds
+// to requests exceeding a limit and returns false. Bodies are limited while
+// they are read.
+func (l *limiter) allow(w http.ResponseWriter, r *http.Request, id, path string) bool {
+	client := id
+	if client == "" {
+		client = "ip:" + clientIP(r)
+	}
+	for _, rl := range l.limits {
+		if !matchRequest(rl.Methods, rl.PathPrefix, r, path) {
+			continue
+		}
+		if rl.MaxBodyBytes > 0 {
+			if r.ContentLength > rl.MaxBodyBytes {
+				numLimitedRequests.WithLabelValues(rl.PathPrefix, "body_size").Inc()
+				http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", rl.MaxBodyBytes), http.StatusRequestEntityTooLarge)
+				return false
+			}
+			if r.Body != nil {
+				r.Body = &limitedBody{
+					ReadCloser: r.Body,
+					remaining:  rl.MaxBodyBytes,
+					prefix:     rl.PathPrefix,
+				}
+			}
+		}
+		if rl.Rate > 0 {
+			if ok, wait := rl.take(client, l.now()); !ok {
+				numLimitedRequests.WithLabelValues(rl.PathPrefix, "rate").Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// errBodyTooLarge is returned when reading a request body exceeding its
+// limit.
+var errBodyTooLarge = errors.New("http: request body too large")
+
+// limitedBody cuts off request bodies exceeding their limit without a
+// declared length while they are read, and counts them.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	prefix    string
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, errBodyTooLarge
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// Read one more byte than remaining to detect an exceeded limit.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n = int(b.remaining)
+	b.remaining = 0
+	b.exceeded = true
+	numLimitedRequests.WithLabelValues(b.prefix, "body_size").Inc()
+	return n, errBodyTooLarge
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func limitedRequests(t *testing.T, prefix, reason string) float64 {
+	var m dto.Metric
+	require.NoError(t, numLimitedRequests.WithLabelValues(prefix, reason).Write(&m))
+	return m.GetCounter().GetValue()
+}
+
+func TestRequestLimits(t *testing.T) {
+	c, err := loadConfig(t, `
+request_limits:
+- methods: [post]
+  path_prefix: /api/v1/alerts
+  rate: 0.5
+- path_prefix: /api/v1/silences
+  max_body_bytes: 10
+`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"POST"}, c.RequestLimits[0].Methods)
+	require.Equal(t, 1, c.RequestLimits[0].Burst)
+
+	h, err := NewHandler(c, "/am", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	require.NoError(t, err)
+	now := time.Now()
+	h.limits.now = func() time.Time { return now }
+
+	do := func(method, path, remoteAddr string, body io.Reader) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, body)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	limited := limitedRequests(t, "/api/v1/alerts", "rate")
+	require.Equal(t, http.StatusOK, do("POST", "/am/api/v1/alerts", "10.0.0.1:1234", nil).Code)
+	w := do("POST", "/am/api/v1/alerts", "10.0.0.1:1235", nil)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "2", w.Header().Get("Retry-After"))
+	require.Equal(t, limited+1, limitedRequests(t, "/api/v1/alerts", "rate"))
+
+	// Other methods and clients are not limited.
+	require.Equal(t, http.StatusOK, do("GET", "/am/api/v1/alerts", "10.0.0.1:1234", nil).Code)
+	require.Equal(t, http.StatusOK, do("POST", "/am/api/v1/alerts", "10.0.0.2:1234", nil).Code)
+
+	now = now.Add(2 * time.Second)
+	require.Equal(t, http.StatusOK, do("POST", "/am/api/v1/alerts", "10.0.0.1:1234", nil).Code)
+
+	limited = limitedRequests(t, "/api/v1/silences", "body_size")
+	require.Equal(t, http.StatusOK, do("POST", "/am/api/v1/silences", "10.0.0.1:1234", strings.NewReader("0123456789")).Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, do("POST", "/am/api/v1/silences", "10.0.0.1:1234", strings.NewReader("0123456789a")).Code)
+	// Bodies of unknown length are cut off.
+	require.Equal(t, http.StatusOK, do("POST", "/am/api/v1/silences", "10.0.0.1:1234", io.MultiReader(strings.NewReader("0123456789"))).Code)
+	w = do("POST", "/am/api/v1/silences", "10.0.0.1:1234", io.MultiReader(strings.NewReader("0123456789a")))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, limited+2, limitedRequests(t, "/api/v1/silences", "body_size"))
+
+	for _, content := range []string{
+		"request_limits:\n- path_prefix: /api\n",
+		"request_limits:\n- rate: -1\n",
+	} {
+		_, err := loadConfig(t, content)
+		require.Error(t, err, content)
+	}
+}
+
+func TestRequestLimitsPerIdentity(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	c, err := loadConfig(t, fmt.Sprintf(`
+basic_auth_users:
+  alice: %s
+  bob: %s
+request_limits:
+- rate: 1
+`, hash, hash))
+	require.NoError(t, err)
+
+	h, err := NewHandler(c, "/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	require.NoError(t, err)
+
+	do := func(user string) int {
+		r := httptest.NewRequest("GET", "/api/v1/alerts", nil)
+		r.SetBasicAuth(user, "secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, do("alice"))
+	require.Equal(t, http.StatusTooManyRequests, do("alice"))
+	require.Equal(t, http.StatusOK, do("bob"))
+}
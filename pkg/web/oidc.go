@@ -0,0 +1,630 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	// Registers the SHA-384 and SHA-512 hashes of tokens.
+	_ "crypto/sha512"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// OIDCConfig configures the authentication of users by OpenID Connect.
+// Browsers are logged in by the authorization-code flow, API clients pass
+// the tokens of the provider as bearer tokens.
+type OIDCConfig struct {
+	// IssuerURL is the URL of the provider, whose configuration is
+	// discovered below /.well-known/openid-configuration.
+	IssuerURL    string        `yaml:"issuer_url"`
+	ClientID     string        `yaml:"client_id"`
+	ClientSecret config.Secret `yaml:"client_secret,omitempty"`
+	// RedirectURL is the URL the provider redirects browsers to after the
+	// login, whose path is served by Alertmanager. The login of browsers is
+	// disabled if it is empty.
+	RedirectURL string   `yaml:"redirect_url,omitempty"`
+	Scopes      []string `yaml:"scopes,omitempty"`
+	// Audiences are the accepted audiences of tokens. Defaults to the
+	// client ID.
+	Audiences []string `yaml:"audiences,omitempty"`
+	// RolesClaim is the claim, a string or a list of strings, whose values
+	// are mapped to roles. Nested claims are separated by dots.
+	RolesClaim string `yaml:"roles_claim,omitempty"`
+	// Roles maps roles to the claim values granting them. Users are granted
+	// the highest of their roles.
+	Roles map[Role][]string `yaml:"roles,omitempty"`
+	// DefaultRole is the role of users without any granted role. Such users
+	// are denied if it is empty.
+	DefaultRole Role `yaml:"default_role,omitempty"`
+	// CAFile holds the CAs verifying the certificate of the provider.
+	CAFile string `yaml:"ca_file,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *OIDCConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain OIDCConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.IssuerURL == "" || c.ClientID == "" {
+		return fmt.Errorf("issuer_url and client_id must be set in oidc")
+	}
+	if c.RedirectURL != "" {
+		if _, err := url.Parse(c.RedirectURL); err != nil {
+			return fmt.Errorf("invalid redirect_url: %s", err)
+		}
+	}
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"openid", "profile", "email"}
+	}
+	if len(c.Audiences) == 0 {
+		c.Audiences = []string{c.ClientID}
+	}
+	if c.RolesClaim == "" {
+		c.RolesClaim = "groups"
+	}
+	for r := range c.Roles {
+		if err := validateRole("role", r); err != nil {
+			return err
+		}
+	}
+	if c.DefaultRole != "" {
+		return validateRole("default_role", c.DefaultRole)
+	}
+	return nil
+}
+
+const (
+	sessionCookie = "alertmanager_session"
+	stateCookie   = "alertmanager_oidc_state"
+
+	// The allowed clock skew between Alertmanager and the provider.
+	tokenLeeway = time.Minute
+	// The minimum time between fetches of the signing keys of the provider.
+	keysRefreshInterval = time.Minute
+)
+
+// errNoToken is returned if a request carries no token.
+var errNoToken = errors.New("no token")
+
+type oidcProvider struct {
+	cfg          *OIDCConfig
+	client       *http.Client
+	callbackPath string
+	now          func() time.Time
+
+	mtx         sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func newOIDCProvider(c *OIDCConfig) (*oidcProvider, error) {
+	p := &oidcProvider{
+		cfg:    c,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+	if c.CAFile != "" {
+		ca, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA file %q", c.CAFile)
+		}
+		p.client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool},
+		}
+	}
+	if c.RedirectURL != "" {
+		u, err := url.Parse(c.RedirectURL)
+		if err != nil {
+			return nil, err
+		}
+		p.callbackPath = u.Path
+	}
+	return p, nil
+}
+
+func (p *oidcProvider) getJSON(u string, v interface{}) error {
+	resp, err := p.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, u)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// discover returns the configuration of the provider, which is fetched on
+// first use.
+func (p *oidcProvider) discover() (*oidcDiscovery, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	d := &oidcDiscovery{}
+	u := strings.TrimSuffix(p.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(u, d); err != nil {
+		return nil, fmt.Errorf("discovering OpenID provider: %s", err)
+	}
+	if d.Issuer != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("issuer %q of the OpenID provider does not match %q", d.Issuer, p.cfg.IssuerURL)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("incomplete configuration of the OpenID provider")
+	}
+	p.discovery = d
+	return d, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	b64 := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := b64(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// key returns the signing key with the given ID. The keys are fetched again
+// if the ID is unknown, as the provider may have rotated them.
+func (p *oidcProvider) key(kid string) (crypto.PublicKey, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	if p.now().Sub(p.keysFetched) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	p.keysFetched = p.now()
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching signing keys: %s", err)
+	}
+	p.keys = map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped.
+		if pk, err := k.publicKey(); err == nil {
+			p.keys[k.Kid] = pk
+		}
+	}
+	if k, ok := p.lookupKey(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey returns the signing key with the given ID. Tokens without key
+// ID are signed by the only key. The caller must hold the lock.
+func (p *oidcProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// verifySignature verifies the signature of a JSON Web Token.
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch strings.TrimLeft(alg, "RSPE") {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(k, hash, digest, sig)
+		case "PS":
+			return rsa.VerifyPSS(k, hash, digest, sig, nil)
+		}
+	case *ecdsa.PublicKey:
+		if alg[:2] != "ES" {
+			break
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("algorithm %q does not match the signing key", alg)
+}
+
+// verify verifies the token and returns its claims.
+func (p *oidcProvider) verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %s", err)
+	}
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %s", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %s", err)
+	}
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if !p.audienceAccepted(claims["aud"]) {
+		return nil, fmt.Errorf("unexpected audience")
+	}
+	now := p.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("token without expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(tokenLeeway)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(tokenLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token not valid yet")
+	}
+	return claims, nil
+}
+
+func (p *oidcProvider) audienceAccepted(aud interface{}) bool {
+	var auds []string
+	switch v := aud.(type) {
+	case string:
+		auds = []string{v}
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+	}
+	for _, a := range auds {
+		for _, accepted := range p.cfg.Audiences {
+			if a == accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// role returns the highest role granted by the claims.
+func (p *oidcProvider) role(claims map[string]interface{}) Role {
+	var v interface{} = claims
+	for _, name := range strings.Split(p.cfg.RolesClaim, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			v = nil
+			break
+		}
+		v = m[name]
+	}
+	values := map[string]bool{}
+	switch v := v.(type) {
+	case string:
+		values[v] = true
+	case []interface{}:
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				values[s] = true
+			}
+		}
+	}
+
+	role := p.cfg.DefaultRole
+	for r, granting := range p.cfg.Roles {
+		for _, g := range granting {
+			if values[g] && roleLevels[r] > roleLevels[role] {
+				role = r
+			}
+		}
+	}
+	return role
+}
+
+// authenticate returns the identity and the role of the user of the
+// request, authenticated by a bearer token or the session cookie. It
+// returns errNoToken if the request carries neither. Invalid session
+// cookies are ignored, so that browsers log in again.
+func (p *oidcProvider) authenticate(r *http.Request) (string, Role, error) {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		claims, err := p.verify(strings.TrimPrefix(auth, "Bearer "))
+		if err != nil {
+			return "", "", err
+		}
+		return oidcIdentity(claims), p.role(claims), nil
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		if claims, err := p.verify(c.Value); err == nil {
+			return oidcIdentity(claims), p.role(claims), nil
+		}
+	}
+	return "", "", errNoToken
+}
+
+func oidcIdentity(claims map[string]interface{}) string {
+	sub, _ := claims["sub"].(string)
+	return "oidc:" + sub
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// login redirects the browser to the provider to log in. The state and
+// nonce of the login are kept in a cookie until the callback.
+func (p *oidcProvider) login(w http.ResponseWriter, r *http.Request) {
+	d, err := p.discover()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	state, err := randomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	nonce, err := randomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state + "." + nonce,
+		Path:     p.callbackPath,
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// callback completes the login by exchanging the authorization code for an
+// ID token, which is kept in the session cookie, and redirects the browser
+// to the given path.
+func (p *oidcProvider) callback(w http.ResponseWriter, r *http.Request, redirect string) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, fmt.Sprintf("login failed: %s %s", e, q.Get("error_description")), http.StatusUnauthorized)
+		return
+	}
+	c, err := r.Cookie(stateCookie)
+	if err != nil {
+		http.Error(w, "login state missing", http.StatusBadRequest)
+		return
+	}
+	parts := strings.SplitN(c.Value, ".", 2)
+	if len(parts) != 2 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(q.Get("state"))) != 1 {
+		http.Error(w, "login state mismatch", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: p.callbackPath, MaxAge: -1})
+
+	token, err := p.exchange(q.Get("code"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	claims, err := p.verify(token)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid ID token: %s", err), http.StatusUnauthorized)
+		return
+	}
+	if nonce, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(nonce), []byte(parts[1])) != 1 {
+		http.Error(w, "invalid ID token: nonce mismatch", http.StatusUnauthorized)
+		return
+	}
+
+	exp := time.Unix(int64(claims["exp"].(float64)), 0)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     redirect,
+		Expires:  exp,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// exchange exchanges the authorization code for an ID token.
+func (p *oidcProvider) exchange(code string) (string, error) {
+	d, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.cfg.RedirectURL},
+	}
+	req, err := http.NewRequest("POST", d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(string(p.cfg.ClientSecret)))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("exchanging authorization code: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("exchanging authorization code: unexpected status code %d", resp.StatusCode)
+	}
+	var res struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("exchanging authorization code: %s", err)
+	}
+	if res.IDToken == "" {
+		return "", fmt.Errorf("exchanging authorization code: no ID token returned")
+	}
+	return res.IDToken, nil
+}
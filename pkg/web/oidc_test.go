@@ -0,0 +1,228 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	nonce string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	p := &testProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/auth",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if r.FormValue("code") != "code" || user != "alertmanager" || password != "secret" {
+			http.Error(w, "invalid grant", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": p.token(t, map[string]interface{}{"groups": []string{"sre"}, "nonce": p.nonce}),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// token returns a token signed by the provider, with the given claims added
+// to valid default claims.
+func (p *testProvider) token(t *testing.T, claims map[string]interface{}) string {
+	c := map[string]interface{}{
+		"iss": p.URL,
+		"aud": "alertmanager",
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		c[k] = v
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDC(t *testing.T) {
+	p := newTestProvider(t)
+	defer p.Close()
+
+	c, err := loadConfig(t, fmt.Sprintf(`
+oidc:
+  issuer_url: %s
+  client_id: alertmanager
+  client_secret: secret
+  redirect_url: https://alertmanager.example.com/am/-/oidc/callback
+  roles:
+    silencer: [sre]
+    admin: [sre-leads]
+  default_role: viewer
+`, p.URL))
+	require.NoError(t, err)
+	require.Equal(t, []string{"alertmanager"}, c.OIDC.Audiences)
+
+	h, err := NewHandler(c, "/am", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	require.NoError(t, err)
+
+	do := func(method, path string, prepare func(*http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if prepare != nil {
+			prepare(r)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	bearer := func(claims map[string]interface{}) func(*http.Request) {
+		token := p.token(t, claims)
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token) }
+	}
+
+	require.Equal(t, http.StatusUnauthorized, do("GET", "/am/api/v1/alerts", nil).Code)
+
+	for _, tc := range []struct {
+		method, path string
+		claims       map[string]interface{}
+		code         int
+	}{
+		{"GET", "/am/api/v1/alerts", nil, http.StatusOK},
+		{"POST", "/am/api/v1/silences", nil, http.StatusForbidden},
+		{"POST", "/am/api/v1/silences", map[string]interface{}{"groups": []string{"eng", "sre"}}, http.StatusOK},
+		{"DELETE", "/am/api/v1/silence/abc", map[string]interface{}{"groups": "sre"}, http.StatusOK},
+		{"POST", "/am/-/reload", map[string]interface{}{"groups": []string{"sre"}}, http.StatusForbidden},
+		{"POST", "/am/-/reload", map[string]interface{}{"groups": []string{"sre", "sre-leads"}}, http.StatusOK},
+		{"GET", "/am/api/v1/alerts", map[string]interface{}{"aud": []string{"other", "alertmanager"}}, http.StatusOK},
+		{"GET", "/am/api/v1/alerts", map[string]interface{}{"aud": "other"}, http.StatusUnauthorized},
+		{"GET", "/am/api/v1/alerts", map[string]interface{}{"iss": "https://other.example.com"}, http.StatusUnauthorized},
+		{"GET", "/am/api/v1/alerts", map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}, http.StatusUnauthorized},
+		{"GET", "/am/api/v1/alerts", map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()}, http.StatusUnauthorized},
+	} {
+		require.Equal(t, tc.code, do(tc.method, tc.path, bearer(tc.claims)).Code, "%s %s %v", tc.method, tc.path, tc.claims)
+	}
+
+	// Tokens with tampered claims are rejected.
+	parts := strings.Split(p.token(t, nil), ".")
+	claims, err := json.Marshal(map[string]interface{}{"iss": p.URL, "aud": "alertmanager", "groups": "sre-leads", "exp": time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(claims) + "." + parts[2]
+	w := do("POST", "/am/api/v1/alerts", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+tampered) })
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Browsers are redirected to the provider and logged in on callback.
+	w = do("GET", "/am/", nil)
+	require.Equal(t, http.StatusFound, w.Code)
+	loc, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, p.URL+"/auth", loc.Scheme+"://"+loc.Host+loc.Path)
+	require.Equal(t, "code", loc.Query().Get("response_type"))
+	require.Equal(t, "openid profile email", loc.Query().Get("scope"))
+	state := w.Result().Cookies()[0]
+	require.Equal(t, stateCookie, state.Name)
+	p.nonce = loc.Query().Get("nonce")
+
+	w = do("GET", "/am/-/oidc/callback?code=code&state=wrong", func(r *http.Request) { r.AddCookie(state) })
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do("GET", "/am/-/oidc/callback?code=code&state="+loc.Query().Get("state"), func(r *http.Request) { r.AddCookie(state) })
+	require.Equal(t, http.StatusFound, w.Code)
+	require.Equal(t, "/am/", w.Header().Get("Location"))
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookie {
+			session = c
+		}
+	}
+	require.NotNil(t, session)
+
+	require.Equal(t, http.StatusOK, do("GET", "/am/", func(r *http.Request) { r.AddCookie(session) }).Code)
+	w = do("POST", "/am/api/v1/silences", func(r *http.Request) { r.AddCookie(session) })
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// A login with another nonce is rejected.
+	p.nonce = "other"
+	w = do("GET", "/am/-/oidc/callback?code=code&state="+loc.Query().Get("state"), func(r *http.Request) { r.AddCookie(state) })
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestOIDCRole(t *testing.T) {
+	p := &oidcProvider{cfg: &OIDCConfig{
+		RolesClaim: "realm_access.roles",
+		Roles: map[Role][]string{
+			RoleSilencer: {"sre"},
+			RoleAdmin:    {"sre-leads"},
+		},
+	}}
+	for _, tc := range []struct {
+		claims string
+		role   Role
+	}{
+		{`{}`, ""},
+		{`{"realm_access": {"roles": ["eng"]}}`, ""},
+		{`{"realm_access": {"roles": ["eng", "sre"]}}`, RoleSilencer},
+		{`{"realm_access": {"roles": ["sre-leads", "sre"]}}`, RoleAdmin},
+		{`{"realm_access": {"roles": "sre"}}`, RoleSilencer},
+		{`{"realm_access": "sre"}`, ""},
+	} {
+		var claims map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(tc.claims), &claims))
+		require.Equal(t, tc.role, p.role(claims), tc.claims)
+	}
+}
@@ -0,0 +1,497 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package web implements TLS, authentication, authorization, request limits
+// and the audit log of the web interface and the API, configured by a web
+// configuration file:
+//
+//	tls_server_config:
+//	  cert_file: server.crt
+//	  key_file: server.key
+//	  client_ca_file: ca.crt
+//	  client_auth_type: VerifyClientCertIfGiven
+//	basic_auth_users:
+//	  alice: $2y$10$...
+//	bearer_tokens:
+//	- s3cr3t
+//	oidc:
+//	  issuer_url: https://sso.example.com
+//	  client_id: alertmanager
+//	  client_secret: s3cr3t
+//	  redirect_url: https://alertmanager.example.com/-/oidc/callback
+//	  roles_claim: groups
+//	  roles:
+//	    admin: [sre-leads]
+//	    silencer: [sre]
+//	  default_role: viewer
+//	authorization:
+//	  roles:
+//	    silencer: [alice]
+//	  default_role: viewer
+//	  bearer_token_role: admin
+//	request_limits:
+//	- methods: [POST]
+//	  path_prefix: /api/v1/alerts
+//	  rate: 10
+//	  burst: 50
+//	  max_body_bytes: 1048576
+//	audit_log:
+//	  file: /var/log/alertmanager/audit.log
+//	  max_size_bytes: 10485760
+//	  max_files: 5
+//	exempt_paths:
+//	- /-/healthy
+//	- /-/ready
+//	- /api/v1/voice/callback
+//
+// Requests are authenticated by a verified client certificate, a user and
+// password of the basic authentication users, whose passwords are bcrypt
+// hashes, a bearer token or an OpenID Connect token. Any of the configured
+// methods suffices. Browsers are redirected to the OpenID provider to log
+// in, and the ID token is kept in a session cookie.
+//
+// Requests are authorized by the role of their identity: viewers may only
+// read, silencers may also change silences and post alerts and admins may
+// do anything.
+// The roles of users authenticated by OpenID Connect are mapped from the
+// claims of their token, the roles of other identities are configured in
+// the authorization section.
+//
+// Request limits apply to the requests of all clients. Rates are limited
+// per authenticated identity, or per client IP address if the request needs
+// no authentication.
+//
+// The audit log records the identity, the client IP address, the status
+// and a summary of the body of every request but GET, HEAD and OPTIONS
+// requests, as JSON lines in a file that is rotated once it exceeds its
+// maximum size or as syslog messages.
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/alertmanager/config"
+)
+
+// DefaultExemptPaths are the paths served without authentication if the
+// configuration does not list any. Voice call providers cannot
+// authenticate their callbacks, which are verified by their signature and
+// the ID of the call instead.
+var DefaultExemptPaths = []string{"/-/healthy", "/-/ready", "/api/v1/voice/callback"}
+
+// Config is the web configuration.
+type Config struct {
+	TLSServerConfig *TLSServerConfig `yaml:"tls_server_config,omitempty"`
+	// BasicAuthUsers maps users to the bcrypt hashes of their passwords.
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users,omitempty"`
+	BearerTokens   []string          `yaml:"bearer_tokens,omitempty"`
+	OIDC           *OIDCConfig       `yaml:"oidc,omitempty"`
+	// Authorization configures the roles of the identities not
+	// authenticated by OpenID Connect.
+	Authorization *AuthorizationConfig `yaml:"authorization,omitempty"`
+	// RequestLimits limit the rate and the body size of requests.
+	RequestLimits []*RequestLimit `yaml:"request_limits,omitempty"`
+	// AuditLog records the requests changing the state of Alertmanager.
+	AuditLog *AuditLogConfig `yaml:"audit_log,omitempty"`
+	// ExemptPaths are the paths, relative to the route prefix, served
+	// without authentication. Defaults to DefaultExemptPaths.
+	ExemptPaths []string `yaml:"exempt_paths,omitempty"`
+}
+
+// TLSServerConfig configures the TLS connections of clients.
+type TLSServerConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile holds the CAs verifying client certificates.
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+	// ClientAuthType is the policy for client certificates. Defaults to
+	// RequireAndVerifyClientCert if a client CA file is set.
+	ClientAuthType string            `yaml:"client_auth_type,omitempty"`
+	MinVersion     config.TLSVersion `yaml:"min_version,omitempty"`
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	for user, hash := range c.BasicAuthUsers {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("invalid password hash of user %q: %s", user, err)
+		}
+	}
+	for _, t := range c.BearerTokens {
+		if t == "" {
+			return fmt.Errorf("empty bearer token")
+		}
+	}
+	return nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TLSServerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TLSServerConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("cert_file and key_file must be set in tls_server_config")
+	}
+	if c.ClientAuthType == "" {
+		c.ClientAuthType = "NoClientCert"
+		if c.ClientCAFile != "" {
+			c.ClientAuthType = "RequireAndVerifyClientCert"
+		}
+	}
+	switch t, ok := clientAuthTypes[c.ClientAuthType]; {
+	case !ok:
+		return fmt.Errorf("unknown client_auth_type %q", c.ClientAuthType)
+	case (t == tls.VerifyClientCertIfGiven || t == tls.RequireAndVerifyClientCert) && c.ClientCAFile == "":
+		return fmt.Errorf("client_ca_file must be set to verify client certificates")
+	}
+	return nil
+}
+
+// LoadConfigFile parses the given web configuration file. Relative paths
+// in the file are resolved against its directory.
+func LoadConfigFile(filename string) (*Config, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(content, c); err != nil {
+		return nil, err
+	}
+	if c.ExemptPaths == nil {
+		c.ExemptPaths = DefaultExemptPaths
+	}
+	if tc := c.TLSServerConfig; tc != nil {
+		dir := filepath.Dir(filename)
+		for _, f := range []*string{&tc.CertFile, &tc.KeyFile, &tc.ClientCAFile} {
+			if *f != "" && !filepath.IsAbs(*f) {
+				*f = filepath.Join(dir, *f)
+			}
+		}
+	}
+	if oc := c.OIDC; oc != nil && oc.CAFile != "" && !filepath.IsAbs(oc.CAFile) {
+		oc.CAFile = filepath.Join(filepath.Dir(filename), oc.CAFile)
+	}
+	if al := c.AuditLog; al != nil && al.File != "" && !filepath.IsAbs(al.File) {
+		al.File = filepath.Join(filepath.Dir(filename), al.File)
+	}
+	return c, nil
+}
+
+// TLSConfig returns the TLS configuration of the server. It returns nil if
+// TLS is not configured.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	tc := c.TLSServerConfig
+	if tc == nil {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading server certificate: %s", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuthTypes[tc.ClientAuthType],
+		MinVersion:   uint16(tc.MinVersion),
+	}
+	if tc.ClientCAFile != "" {
+		ca, err := ioutil.ReadFile(tc.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = x509.NewCertPool()
+		if !cfg.ClientCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in client CA file %q", tc.ClientCAFile)
+		}
+	}
+	return cfg, nil
+}
+
+// ListenAndServe serves the handler on the address, with TLS if it is
+// configured.
+func ListenAndServe(addr string, c *Config, h http.Handler) error {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Addr: addr, Handler: h, TLSConfig: tlsConfig}
+	if tlsConfig == nil {
+		return srv.ListenAndServe()
+	}
+	// The certificates are part of the TLS configuration.
+	return srv.ListenAndServeTLS("", "")
+}
+
+// Handler authenticates requests before passing them to the next handler.
+type Handler struct {
+	next   http.Handler
+	prefix string
+
+	users  map[string]string
+	tokens []string
+	// Whether verified client certificates authenticate requests.
+	clientCerts bool
+	exempt      map[string]bool
+	oidc        *oidcProvider
+	authz       *authorizer
+	limits      *limiter
+	audit       *auditLog
+
+	// Hashes of the users and passwords that were authenticated, so that
+	// the costly bcrypt comparison is only done once.
+	mtx    sync.Mutex
+	cached map[[sha256.Size]byte]bool
+}
+
+// maxCached is the number of authenticated users and passwords that are
+// cached.
+const maxCached = 1024
+
+// NewHandler returns a handler authenticating and authorizing the requests
+// to the next handler according to the configuration. The exempt paths are relative to
+// the route prefix.
+func NewHandler(c *Config, routePrefix string, next http.Handler) (*Handler, error) {
+	h := &Handler{
+		next:   next,
+		prefix: strings.TrimSuffix(path.Join("/", routePrefix), "/"),
+		users:  c.BasicAuthUsers,
+		tokens: c.BearerTokens,
+		exempt: map[string]bool{},
+		authz:  newAuthorizer(c.Authorization),
+		limits: newLimiter(c.RequestLimits),
+		cached: map[[sha256.Size]byte]bool{},
+	}
+	if tc := c.TLSServerConfig; tc != nil {
+		switch clientAuthTypes[tc.ClientAuthType] {
+		case tls.VerifyClientCertIfGiven, tls.RequireAndVerifyClientCert:
+			h.clientCerts = true
+		}
+	}
+	for _, p := range c.ExemptPaths {
+		h.exempt[path.Join("/", routePrefix, p)] = true
+	}
+	if c.OIDC != nil {
+		p, err := newOIDCProvider(c.OIDC)
+		if err != nil {
+			return nil, err
+		}
+		h.oidc = p
+	}
+	if c.AuditLog != nil {
+		l, err := newAuditLog(c.AuditLog, h.prefix)
+		if err != nil {
+			return nil, err
+		}
+		h.audit = l
+	}
+	return h, nil
+}
+
+// Close closes the audit log of the handler.
+func (h *Handler) Close() error {
+	if h.audit == nil {
+		return nil
+	}
+	return h.audit.sink.close()
+}
+
+func (h *Handler) required() bool {
+	return len(h.users) > 0 || len(h.tokens) > 0 || h.clientCerts || h.oidc != nil
+}
+
+// AuthenticationRequired returns whether requests have to be
+// authenticated.
+func (h *Handler) AuthenticationRequired() bool {
+	return h.required()
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.audit == nil || !audited(r.Method) {
+		h.serve(w, r)
+		return
+	}
+	h.audit.record(w, r, h.serve)
+}
+
+// serve authorizes and limits the request before passing it to the next
+// handler. It returns the authenticated identity.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) string {
+	id, role, ok := h.authorize(w, r)
+	if ok && h.limits.allow(w, r, id, strings.TrimPrefix(r.URL.Path, h.prefix)) {
+		if id != "" {
+			r = r.WithContext(WithIdentity(r.Context(), Identity{ID: id, Role: role}))
+		}
+		h.next.ServeHTTP(w, r)
+	}
+	return id
+}
+
+// authorize authenticates and authorizes the request. It returns the
+// authenticated identity and its role, which are empty if authentication
+// is not required. Otherwise it responds to the request and returns false,
+// along with the identity if it was authenticated but not authorized.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) (string, Role, bool) {
+	if !h.required() || h.exempt[r.URL.Path] {
+		return "", "", true
+	}
+	if h.oidc != nil && h.oidc.callbackPath != "" && r.URL.Path == h.oidc.callbackPath {
+		h.oidc.callback(w, r, h.prefix+"/")
+		return "", "", false
+	}
+
+	id, role, ok := h.authenticated(r)
+	if !ok && h.oidc != nil {
+		var err error
+		id, role, err = h.oidc.authenticate(r)
+		switch {
+		case err == nil:
+			ok = true
+		case err != errNoToken:
+			http.Error(w, fmt.Sprintf("Unauthorized: %s", err), http.StatusUnauthorized)
+			return "", "", false
+		case h.oidc.callbackPath != "" && r.Method == "GET" && !strings.HasPrefix(r.URL.Path, h.prefix+"/api/"):
+			// Browsers are logged in, API clients have to pass a token.
+			h.oidc.login(w, r)
+			return "", "", false
+		}
+	}
+	if !ok {
+		if len(h.users) > 0 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="Alertmanager"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", "", false
+	}
+
+	if required := h.authz.requiredRole(r, strings.TrimPrefix(r.URL.Path, h.prefix)); !role.includes(required) {
+		http.Error(w, fmt.Sprintf("Forbidden: the %s role is required", required), http.StatusForbidden)
+		return id, "", false
+	}
+	return id, role, true
+}
+
+type contextKey int
+
+const identityKey contextKey = iota
+
+// Identity is the authenticated identity of a request, prefixed by the
+// authentication method like user:alice, and its role.
+type Identity struct {
+	ID   string
+	Role Role
+}
+
+// WithIdentity returns a context carrying the authenticated identity of a
+// request.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey, id)
+}
+
+// IdentityFromContext returns the authenticated identity of the request of
+// the context. It returns false if the request was not authenticated.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey).(Identity)
+	return id, ok
+}
+
+// authenticated returns the identity and the role of the request
+// authenticated by a client certificate, a basic authentication user or a
+// bearer token.
+func (h *Handler) authenticated(r *http.Request) (string, Role, bool) {
+	if h.clientCerts && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		return "cert:" + cn, h.authz.role(cn), true
+	}
+	if user, password, ok := r.BasicAuth(); ok && len(h.users) > 0 {
+		if !h.checkPassword(user, password) {
+			return "", "", false
+		}
+		return "user:" + user, h.authz.role(user), true
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token := []byte(strings.TrimPrefix(auth, "Bearer "))
+		id := ""
+		for i, t := range h.tokens {
+			if subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+				id = fmt.Sprintf("token:%d", i)
+			}
+		}
+		return id, h.authz.tokenRole, id != ""
+	}
+	return "", "", false
+}
+
+var (
+	// dummyHash is compared with the passwords of unknown users, so that
+	// they take as long to reject as wrong passwords.
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+func (h *Handler) checkPassword(user, password string) bool {
+	key := sha256.Sum256([]byte(user + "\x00" + password))
+	h.mtx.Lock()
+	ok := h.cached[key]
+	h.mtx.Unlock()
+	if ok {
+		return true
+	}
+
+	hash, known := h.users[user]
+	if !known {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+		})
+		hash = string(dummyHash)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil || !known {
+		return false
+	}
+
+	h.mtx.Lock()
+	if len(h.cached) >= maxCached {
+		h.cached = map[[sha256.Size]byte]bool{}
+	}
+	h.cached[key] = true
+	h.mtx.Unlock()
+	return true
+}
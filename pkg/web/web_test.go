@@ -0,0 +1,149 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func loadConfig(t *testing.T, content string) (*Config, error) {
+	dir, err := ioutil.TempDir("", "web")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "web.yml")
+	require.NoError(t, ioutil.WriteFile(fn, []byte(content), 0644))
+	return LoadConfigFile(fn)
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	c, err := loadConfig(t, `
+tls_server_config:
+  cert_file: server.crt
+  key_file: /etc/server.key
+  client_ca_file: ca.crt
+`)
+	require.NoError(t, err)
+	require.Equal(t, "RequireAndVerifyClientCert", c.TLSServerConfig.ClientAuthType)
+	require.True(t, filepath.IsAbs(c.TLSServerConfig.CertFile))
+	require.Equal(t, "/etc/server.key", c.TLSServerConfig.KeyFile)
+	require.Equal(t, DefaultExemptPaths, c.ExemptPaths)
+
+	c, err = loadConfig(t, "exempt_paths: []\n")
+	require.NoError(t, err)
+	require.Len(t, c.ExemptPaths, 0)
+
+	for _, content := range []string{
+		"basic_auth_users:\n  alice: plain\n",
+		"bearer_tokens: ['']\n",
+		"tls_server_config:\n  cert_file: server.crt\n",
+		"tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_auth_type: Verify\n",
+		"tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_auth_type: VerifyClientCertIfGiven\n",
+		"unknown: true\n",
+	} {
+		_, err := loadConfig(t, content)
+		require.Error(t, err, content)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	c := &Config{
+		TLSServerConfig: &TLSServerConfig{ClientAuthType: "VerifyClientCertIfGiven"},
+		BasicAuthUsers:  map[string]string{"alice": string(hash)},
+		BearerTokens:    []string{"token"},
+		ExemptPaths:     DefaultExemptPaths,
+	}
+	h, err := NewHandler(c, "/am", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	require.NoError(t, err)
+
+	do := func(path string, prepare func(*http.Request)) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if prepare != nil {
+			prepare(r)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("/am/api/v1/alerts", nil)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, `Basic realm="Alertmanager"`, w.Header().Get("WWW-Authenticate"))
+
+	require.Equal(t, http.StatusOK, do("/am/-/healthy", nil).Code)
+	require.Equal(t, http.StatusUnauthorized, do("/-/healthy", nil).Code)
+
+	for _, tc := range []struct {
+		prepare func(*http.Request)
+		code    int
+	}{
+		{func(r *http.Request) { r.SetBasicAuth("alice", "secret") }, http.StatusOK},
+		// Cached passwords are checked as well.
+		{func(r *http.Request) { r.SetBasicAuth("alice", "secret") }, http.StatusOK},
+		{func(r *http.Request) { r.SetBasicAuth("alice", "wrong") }, http.StatusUnauthorized},
+		{func(r *http.Request) { r.SetBasicAuth("bob", "secret") }, http.StatusUnauthorized},
+		{func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+		{func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") }, http.StatusUnauthorized},
+		{func(r *http.Request) {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}
+		}, http.StatusOK},
+		{func(r *http.Request) { r.TLS = &tls.ConnectionState{} }, http.StatusUnauthorized},
+	} {
+		require.Equal(t, tc.code, do("/am/api/v1/alerts", tc.prepare).Code)
+	}
+
+	// Without authentication methods, all requests are served.
+	h, err = NewHandler(&Config{}, "/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, do("/api/v1/alerts", nil).Code)
+}
+
+func TestHandlerIdentity(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	c := &Config{
+		BasicAuthUsers: map[string]string{"alice": string(hash)},
+		ExemptPaths:    DefaultExemptPaths,
+	}
+	var (
+		id Identity
+		ok bool
+	)
+	h, err := NewHandler(c, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok = IdentityFromContext(r.Context())
+	}))
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/api/v1/alerts", nil)
+	r.SetBasicAuth("alice", "secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	require.True(t, ok)
+	require.Equal(t, Identity{ID: "user:alice", Role: RoleAdmin}, id)
+
+	// Exempt paths are served without an identity.
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/-/healthy", nil))
+	require.False(t, ok)
+}
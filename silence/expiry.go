@@ -0,0 +1,110 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// ExpiryScheduler calls a function once for every silence that is about to
+// expire. Silences that are extended are reported again before their new
+// end time.
+type ExpiryScheduler struct {
+	silences *Silences
+
+	mtx    sync.Mutex
+	before time.Duration
+	f      func(*pb.Silence)
+	// The end times of the silences the function was called for.
+	notified map[string]time.Time
+}
+
+// NewExpiryScheduler returns a new ExpiryScheduler for the silences. It does
+// nothing until it is configured with Update.
+func NewExpiryScheduler(s *Silences) *ExpiryScheduler {
+	return &ExpiryScheduler{
+		silences: s,
+		notified: map[string]time.Time{},
+	}
+}
+
+// Update sets the function called for silences that expire within the
+// given duration. A nil function disables the scheduler.
+func (e *ExpiryScheduler) Update(before time.Duration, f func(*pb.Silence)) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	e.before = before
+	e.f = f
+}
+
+// Run checks for expiring silences at the given interval until stopc is
+// closed.
+func (e *ExpiryScheduler) Run(interval time.Duration, stopc <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return
+		case <-t.C:
+			e.check()
+		}
+	}
+}
+
+// check calls the function for all silences expiring soon that it was not
+// called for yet.
+func (e *ExpiryScheduler) check() {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	if e.f == nil {
+		return
+	}
+	sils, err := e.silences.Query()
+	if err != nil {
+		return
+	}
+	now := e.silences.now()
+
+	active := make(map[string]struct{}, len(sils))
+	for _, sil := range sils {
+		if getTimeRangeState(sil, now) != types.SilenceStateActive {
+			continue
+		}
+		active[sil.Id] = struct{}{}
+
+		// Silences shorter than the notification period would be
+		// reported right after they were created.
+		if sil.EndsAt.Sub(sil.StartsAt) <= e.before || sil.EndsAt.Sub(now) > e.before {
+			continue
+		}
+		if endsAt, ok := e.notified[sil.Id]; ok && endsAt.Equal(sil.EndsAt) {
+			continue
+		}
+		e.notified[sil.Id] = sil.EndsAt
+		e.f(sil)
+	}
+	for id := range e.notified {
+		if _, ok := active[id]; !ok {
+			delete(e.notified, id)
+		}
+	}
+}
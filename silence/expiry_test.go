@@ -0,0 +1,77 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpirySchedulerCheck(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	newSilence := func(d time.Duration) string {
+		id, err := s.Set(&pb.Silence{
+			Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+			StartsAt: now,
+			EndsAt:   now.Add(d),
+		})
+		require.NoError(t, err)
+		return id
+	}
+	long := newSilence(2 * time.Hour)
+	newSilence(10 * time.Minute)
+
+	var notified []string
+	e := NewExpiryScheduler(s)
+
+	// Nothing happens without a function.
+	e.check()
+
+	e.Update(15*time.Minute, func(sil *pb.Silence) {
+		notified = append(notified, sil.Id)
+	})
+	e.check()
+	require.Empty(t, notified, "silences shorter than the period must not be reported")
+
+	now = now.Add(time.Hour + 50*time.Minute)
+	e.check()
+	e.check()
+	require.Equal(t, []string{long}, notified)
+
+	// Extending the silence reports it again before its new end.
+	sil, err := s.QueryOne(QIDs(long))
+	require.NoError(t, err)
+	sil.EndsAt = now.Add(time.Hour)
+	_, err = s.Set(sil)
+	require.NoError(t, err)
+
+	e.check()
+	require.Equal(t, []string{long}, notified)
+
+	now = now.Add(50 * time.Minute)
+	e.check()
+	require.Equal(t, []string{long, long}, notified)
+
+	now = now.Add(time.Hour)
+	e.check()
+	require.Empty(t, e.notified)
+}
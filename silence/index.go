@@ -0,0 +1,105 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"github.com/prometheus/common/model"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+// indexKey is the label pair of the equality matcher a silence is indexed by.
+type indexKey struct {
+	name, value string
+}
+
+// silenceIndex is an inverted index of silences by label pairs. Each silence
+// is indexed by one of its equality matchers, as it can only match label sets
+// containing that label pair. Silences without an equality matcher on a
+// non-empty value may match any label set and are kept in a separate bucket.
+//
+// The zero value is an empty index.
+type silenceIndex struct {
+	eq    map[indexKey]map[string]struct{}
+	other map[string]struct{}
+	// keys holds the key each silence ID is indexed by. Silences in the
+	// other bucket have no entry.
+	keys map[string]indexKey
+}
+
+// keyOf returns the label pair the silence is indexed by or false if it is
+// kept in the other bucket.
+func keyOf(sil *pb.Silence) (indexKey, bool) {
+	for _, m := range sil.Matchers {
+		// An equality matcher on the empty value also matches label sets
+		// without the label.
+		if m.Type == pb.Matcher_EQUAL && m.Pattern != "" {
+			return indexKey{name: m.Name, value: m.Pattern}, true
+		}
+	}
+	return indexKey{}, false
+}
+
+// add indexes the silence, replacing a previous entry with the same ID.
+func (idx *silenceIndex) add(sil *pb.Silence) {
+	if idx.eq == nil {
+		idx.eq = map[indexKey]map[string]struct{}{}
+		idx.other = map[string]struct{}{}
+		idx.keys = map[string]indexKey{}
+	}
+	idx.delete(sil.Id)
+
+	k, ok := keyOf(sil)
+	if !ok {
+		idx.other[sil.Id] = struct{}{}
+		return
+	}
+	ids, ok := idx.eq[k]
+	if !ok {
+		ids = map[string]struct{}{}
+		idx.eq[k] = ids
+	}
+	ids[sil.Id] = struct{}{}
+	idx.keys[sil.Id] = k
+}
+
+// delete removes the silence with the given ID from the index.
+func (idx *silenceIndex) delete(id string) {
+	k, ok := idx.keys[id]
+	if !ok {
+		delete(idx.other, id)
+		return
+	}
+	delete(idx.keys, id)
+
+	ids := idx.eq[k]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(idx.eq, k)
+	}
+}
+
+// candidates returns the IDs of all silences that may match the label set.
+func (idx *silenceIndex) candidates(lset model.LabelSet) []string {
+	res := make([]string, 0, len(idx.other))
+	for id := range idx.other {
+		res = append(res, id)
+	}
+	for ln, lv := range lset {
+		for id := range idx.eq[indexKey{name: string(ln), value: string(lv)}] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
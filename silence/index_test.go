@@ -0,0 +1,145 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func TestSilenceIndex(t *testing.T) {
+	var idx silenceIndex
+
+	idx.add(&pb.Silence{Id: "1", Matchers: []*pb.Matcher{
+		{Type: pb.Matcher_REGEXP, Name: "job", Pattern: "a.*"},
+		{Type: pb.Matcher_EQUAL, Name: "team", Pattern: "db"},
+	}})
+	idx.add(&pb.Silence{Id: "2", Matchers: []*pb.Matcher{
+		{Type: pb.Matcher_EQUAL, Name: "team", Pattern: ""},
+	}})
+	idx.add(&pb.Silence{Id: "3", Matchers: []*pb.Matcher{
+		{Type: pb.Matcher_EQUAL, Name: "team", Pattern: "web"},
+	}})
+
+	candidates := func(lset model.LabelSet) []string {
+		ids := idx.candidates(lset)
+		sort.Strings(ids)
+		return ids
+	}
+	require.Equal(t, []string{"1", "2"}, candidates(model.LabelSet{"team": "db"}))
+	require.Equal(t, []string{"2", "3"}, candidates(model.LabelSet{"team": "web"}))
+	require.Equal(t, []string{"2"}, candidates(model.LabelSet{"job": "api"}))
+
+	// Updates replace the previous key.
+	idx.add(&pb.Silence{Id: "3", Matchers: []*pb.Matcher{
+		{Type: pb.Matcher_REGEXP, Name: "team", Pattern: "web"},
+	}})
+	require.Equal(t, []string{"2", "3"}, candidates(model.LabelSet{"job": "api"}))
+
+	idx.delete("1")
+	idx.delete("3")
+	require.Equal(t, []string{"2"}, candidates(model.LabelSet{"team": "db"}))
+	require.Empty(t, idx.eq)
+}
+
+// newSilencesWithMatchers returns silences populated with n silences, most of
+// which are on equality matchers.
+func newSilencesWithMatchers(t testing.TB, n int) *Silences {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := utcNow()
+	for i := 0; i < n; i++ {
+		ms := []*pb.Matcher{
+			{Type: pb.Matcher_EQUAL, Name: "instance", Pattern: fmt.Sprintf("host-%d", i%1000)},
+			{Type: pb.Matcher_EQUAL, Name: "job", Pattern: fmt.Sprintf("job-%d", i%10)},
+		}
+		switch i % 100 {
+		case 0:
+			ms = []*pb.Matcher{{Type: pb.Matcher_REGEXP, Name: "instance", Pattern: fmt.Sprintf("host-%d.*", i%1000)}}
+		case 1:
+			ms = append(ms[1:], &pb.Matcher{Type: pb.Matcher_EQUAL, Name: "instance", Pattern: ""})
+		}
+		_, err := s.Set(&pb.Silence{
+			Matchers: ms,
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		})
+		require.NoError(t, err)
+	}
+	return s
+}
+
+func TestQueryMatchesIndexed(t *testing.T) {
+	s := newSilencesWithMatchers(t, 2000)
+
+	for _, lset := range []model.LabelSet{
+		{"instance": "host-1", "job": "job-1"},
+		{"instance": "host-10", "job": "job-0"},
+		{"instance": "host-100", "job": "job-3"},
+		{"job": "job-1"},
+		{"other": "value"},
+		{},
+	} {
+		indexed := &query{}
+		require.NoError(t, QState(types.SilenceStateActive)(indexed))
+		require.NoError(t, QMatches(lset)(indexed))
+		// Without the label set the query scans all silences.
+		scan := &query{filters: indexed.filters}
+
+		res, err := s.query(indexed, s.now())
+		require.NoError(t, err)
+		exp, err := s.query(scan, s.now())
+		require.NoError(t, err)
+
+		ids := func(sils []*pb.Silence) []string {
+			res := []string{}
+			for _, sil := range sils {
+				res = append(res, sil.Id)
+			}
+			sort.Strings(res)
+			return res
+		}
+		require.Equal(t, ids(exp), ids(res), "label set %v", lset)
+	}
+}
+
+func benchmarkQueryMatches(b *testing.B, n int) {
+	s := newSilencesWithMatchers(b, n)
+	lset := model.LabelSet{
+		"alertname": "InstanceDown",
+		"instance":  "host-42",
+		"job":       "job-2",
+		"severity":  "critical",
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sils, err := s.Query(QState(types.SilenceStateActive), QMatches(lset))
+		require.NoError(b, err)
+		require.NotEmpty(b, sils)
+	}
+}
+
+func BenchmarkQueryMatches100(b *testing.B)   { benchmarkQueryMatches(b, 100) }
+func BenchmarkQueryMatches1000(b *testing.B)  { benchmarkQueryMatches(b, 1000) }
+func BenchmarkQueryMatches10000(b *testing.B) { benchmarkQueryMatches(b, 10000) }
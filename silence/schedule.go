@@ -0,0 +1,247 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+)
+
+// Schedule is the parsed schedule of a silence. The silence is active for
+// the configured duration every time the cron expression matches.
+type Schedule struct {
+	cron     *cronExpr
+	duration time.Duration
+	loc      *time.Location
+}
+
+// ParseSchedule parses the schedule of a silence.
+func ParseSchedule(s *pb.Schedule) (*Schedule, error) {
+	expr, err := parseCron(s.Cron)
+	if err != nil {
+		return nil, err
+	}
+	if s.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive")
+	}
+	loc, err := loadLocation(s.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	return &Schedule{cron: expr, duration: s.Duration, loc: loc}, nil
+}
+
+// Next returns the start of the first window after t. It returns the zero
+// time if the cron expression does not match within the next five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.cron.next(t.In(s.loc))
+}
+
+// Contains returns true if t is within a window of the schedule.
+func (s *Schedule) Contains(t time.Time) bool {
+	start := s.Next(t.Add(-s.duration))
+	return !start.IsZero() && !start.After(t)
+}
+
+var (
+	locationsMtx sync.Mutex
+	locations    = map[string]*time.Location{}
+)
+
+// loadLocation returns the location for the IANA time zone name. Loading
+// a location reads the time zone database, so they are cached.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	locationsMtx.Lock()
+	defer locationsMtx.Unlock()
+
+	if loc, ok := locations[name]; ok {
+		return loc, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	locations[name] = loc
+	return loc, nil
+}
+
+// cronExpr is a parsed cron expression in the standard five field format.
+type cronExpr struct {
+	minute, hour, dom, month, dow uint64
+	// Day of month and day of week match if either matches, unless one
+	// of them is a wildcard.
+	domStar, dowStar bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+func parseCron(s string) (*cronExpr, error) {
+	if m, ok := cronMacros[strings.TrimSpace(s)]; ok {
+		s = m
+	}
+	parts := strings.Fields(s)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", s, len(cronFields))
+	}
+	var bits [5]uint64
+	for i, f := range cronFields {
+		b, err := f.parse(parts[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in cron expression %q: %s", f.name, s, err)
+		}
+		bits[i] = b
+	}
+	// Sunday may be given as 0 or 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return &cronExpr{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: parts[2] == "*",
+		dowStar: parts[4] == "*",
+	}, nil
+}
+
+// parse returns the set of values of the field as a bit set.
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", item[i+1:])
+			}
+			rng, step = item[:i], n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			switch {
+			case len(bounds) == 2:
+				if hi, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			case step == 1:
+				hi = lo
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, n := range f.names {
+		if n != "" && strings.EqualFold(s, n) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+func (c *cronExpr) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first time after t matched by the expression in the
+// location of t.
+func (c *cronExpr) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.Year() + 5
+
+wrap:
+	if t.Year() > limit {
+		return time.Time{}
+	}
+	for c.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !c.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for c.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for c.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
@@ -0,0 +1,177 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package silence
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/prometheus/alertmanager/silence/silencepb"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	cases := []struct {
+		expr string
+		err  string
+	}{
+		{expr: "0 22 * * *"},
+		{expr: "*/15 9-17 * * mon-fri"},
+		{expr: "0 0 1,15 jan,jul 7"},
+		{expr: "@weekly"},
+		{expr: "0 22 * *", err: "must have 5 fields"},
+		{expr: "60 * * * *", err: `invalid minute in cron expression "60 * * * *": invalid value "60"`},
+		{expr: "0 5-3 * * *", err: `invalid range "5-3"`},
+		{expr: "*/0 * * * *", err: `invalid step "0"`},
+		{expr: "0 0 * foo *", err: `invalid value "foo"`},
+	}
+	for _, c := range cases {
+		_, err := parseCron(c.expr)
+		if c.err == "" {
+			require.NoError(t, err, c.expr)
+			continue
+		}
+		require.Error(t, err, c.expr)
+		require.Contains(t, err.Error(), c.err)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	ts := time.Date(2018, 3, 9, 23, 30, 0, 0, time.UTC) // A Friday.
+
+	cases := []struct {
+		expr string
+		next time.Time
+	}{
+		{expr: "0 22 * * *", next: time.Date(2018, 3, 10, 22, 0, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", next: time.Date(2018, 3, 9, 23, 45, 0, 0, time.UTC)},
+		{expr: "0 9 * * mon-fri", next: time.Date(2018, 3, 12, 9, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 0", next: time.Date(2018, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 * * 7", next: time.Date(2018, 3, 11, 0, 0, 0, 0, time.UTC)},
+		// Day of month and day of week match if either matches.
+		{expr: "0 0 15 * sun", next: time.Date(2018, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{expr: "@yearly", next: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 feb *", next: time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 31 feb *", next: time.Time{}},
+	}
+	for _, c := range cases {
+		expr, err := parseCron(c.expr)
+		require.NoError(t, err)
+		require.Equal(t, c.next, expr.next(ts), c.expr)
+	}
+}
+
+func TestScheduleContains(t *testing.T) {
+	sched, err := ParseSchedule(&pb.Schedule{
+		Cron:     "0 22 * * *",
+		Duration: 8 * time.Hour,
+		TimeZone: "Europe/Berlin",
+	})
+	require.NoError(t, err)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	cases := []struct {
+		ts       time.Time
+		contains bool
+	}{
+		{ts: time.Date(2018, 3, 9, 21, 59, 0, 0, berlin), contains: false},
+		{ts: time.Date(2018, 3, 9, 22, 0, 0, 0, berlin), contains: true},
+		{ts: time.Date(2018, 3, 10, 3, 0, 0, 0, berlin), contains: true},
+		{ts: time.Date(2018, 3, 10, 5, 59, 59, 0, berlin), contains: true},
+		{ts: time.Date(2018, 3, 10, 6, 0, 0, 0, berlin), contains: false},
+		// The time zone is respected regardless of the given location.
+		{ts: time.Date(2018, 3, 9, 21, 30, 0, 0, time.UTC), contains: true},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.contains, sched.Contains(c.ts), c.ts.String())
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	_, err := ParseSchedule(&pb.Schedule{Cron: "0 22 * * *"})
+	require.EqualError(t, err, "duration must be positive")
+
+	_, err = ParseSchedule(&pb.Schedule{Cron: "0 22 * * *", Duration: time.Hour, TimeZone: "Mars/Olympus"})
+	require.EqualError(t, err, `unknown time zone "Mars/Olympus"`)
+}
+
+func TestScheduledSilenceState(t *testing.T) {
+	start := time.Date(2018, 3, 9, 12, 0, 0, 0, time.UTC)
+	sil := &pb.Silence{
+		StartsAt: start,
+		EndsAt:   start.Add(48 * time.Hour),
+		Schedule: &pb.Schedule{
+			Cron:     "0 22 * * *",
+			Duration: 2 * time.Hour,
+		},
+	}
+
+	cases := []struct {
+		ts    time.Time
+		state types.SilenceState
+		next  time.Time
+	}{
+		{
+			ts:    start.Add(-time.Hour),
+			state: types.SilenceStatePending,
+			next:  time.Date(2018, 3, 9, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			ts:    start,
+			state: types.SilenceStatePending,
+			next:  time.Date(2018, 3, 9, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			ts:    time.Date(2018, 3, 9, 23, 0, 0, 0, time.UTC),
+			state: types.SilenceStateActive,
+			next:  time.Date(2018, 3, 10, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			// The last window starts after the silence ends.
+			ts:    time.Date(2018, 3, 10, 23, 0, 0, 0, time.UTC),
+			state: types.SilenceStateActive,
+		},
+		{
+			ts:    start.Add(49 * time.Hour),
+			state: types.SilenceStateExpired,
+		},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.state, getState(sil, c.ts), c.ts.String())
+
+		next, ok := NextActivation(sil, c.ts)
+		require.Equal(t, !c.next.IsZero(), ok, c.ts.String())
+		require.Equal(t, c.next, next, c.ts.String())
+	}
+}
+
+func TestScheduledSilenceStartsInWindow(t *testing.T) {
+	start := time.Date(2018, 3, 9, 23, 0, 0, 0, time.UTC)
+	sil := &pb.Silence{
+		StartsAt: start,
+		EndsAt:   start.Add(48 * time.Hour),
+		Schedule: &pb.Schedule{
+			Cron:     "0 22 * * *",
+			Duration: 2 * time.Hour,
+		},
+	}
+	require.Equal(t, types.SilenceStateActive, getState(sil, start))
+
+	next, ok := NextActivation(sil, start.Add(-time.Hour))
+	require.True(t, ok)
+	require.Equal(t, start, next)
+}
@@ -17,6 +17,7 @@ package silence
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"math/rand"
@@ -30,6 +31,7 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/matttproud/golang_protobuf_extensions/pbutil"
 	"github.com/pkg/errors"
+	"github.com/prometheus/alertmanager/pkg/snapcrypt"
 	pb "github.com/prometheus/alertmanager/silence/silencepb"
 	"github.com/prometheus/alertmanager/types"
 	"github.com/prometheus/client_golang/prometheus"
@@ -47,6 +49,17 @@ func utcNow() time.Time {
 	return time.Now().UTC()
 }
 
+// matcherOperators maps the matcher types of silences to the operators of
+// matchers. Equality and regular expression matchers have no operator.
+var matcherOperators = map[pb.Matcher_Type]types.MatchOperator{
+	pb.Matcher_ABSENT:        types.MatchAbsent,
+	pb.Matcher_PRESENT:       types.MatchPresent,
+	pb.Matcher_LESS_THAN:     types.MatchLessThan,
+	pb.Matcher_LESS_EQUAL:    types.MatchLessEqual,
+	pb.Matcher_GREATER_THAN:  types.MatchGreaterThan,
+	pb.Matcher_GREATER_EQUAL: types.MatchGreaterEqual,
+}
+
 type matcherCache map[*pb.Silence]types.Matchers
 
 // Get retrieves the matchers for a given silence. If it is a missed cache
@@ -77,6 +90,12 @@ func (c matcherCache) add(s *pb.Silence) (types.Matchers, error) {
 			mt.IsRegex = false
 		case pb.Matcher_REGEXP:
 			mt.IsRegex = true
+		default:
+			op, ok := matcherOperators[m.Type]
+			if !ok {
+				return nil, fmt.Errorf("unknown matcher type %q", m.Type)
+			}
+			mt.Operator = op
 		}
 		err := mt.Init()
 		if err != nil {
@@ -97,15 +116,23 @@ type Silences struct {
 	metrics   *metrics
 	now       func() time.Time
 	retention time.Duration
-
-	mtx       sync.RWMutex
-	st        state
-	broadcast func([]byte)
-	mc        matcherCache
+	cipher    *snapcrypt.Cipher
+
+	mtx             sync.RWMutex
+	st              state
+	broadcast       func([]byte)
+	quorumBroadcast func(context.Context, []byte) error
+	mc              matcherCache
+	idx             silenceIndex
+
+	// operatorsSupported returns false while peers that treat the
+	// matcher operators as equality matchers are part of the cluster.
+	operatorsSupported func() bool
 }
 
 type metrics struct {
 	gcDuration       prometheus.Summary
+	gcRemovedTotal   prometheus.Counter
 	snapshotDuration prometheus.Summary
 	snapshotSize     prometheus.Gauge
 	queriesTotal     prometheus.Counter
@@ -140,6 +167,10 @@ func newMetrics(r prometheus.Registerer, s *Silences) *metrics {
 		Name: "alertmanager_silences_gc_duration_seconds",
 		Help: "Duration of the last silence garbage collection cycle.",
 	})
+	m.gcRemovedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "alertmanager_silences_gc_removed_total",
+		Help: "How many expired silences were removed by garbage collection.",
+	})
 	m.snapshotDuration = prometheus.NewSummary(prometheus.SummaryOpts{
 		Name: "alertmanager_silences_snapshot_duration_seconds",
 		Help: "Duration of the last silence snapshot.",
@@ -169,6 +200,7 @@ func newMetrics(r prometheus.Registerer, s *Silences) *metrics {
 	if r != nil {
 		r.MustRegister(
 			m.gcDuration,
+			m.gcRemovedTotal,
 			m.snapshotDuration,
 			m.snapshotSize,
 			m.queriesTotal,
@@ -194,6 +226,10 @@ type Options struct {
 	// garbage collected after the given duration after they ended.
 	Retention time.Duration
 
+	// Cipher encrypts the snapshots written by Maintenance and decrypts
+	// the initial snapshot. Snapshots are not encrypted if it is nil.
+	Cipher *snapcrypt.Cipher
+
 	// A logger used by background processing.
 	Logger  log.Logger
 	Metrics prometheus.Registerer
@@ -224,6 +260,7 @@ func New(o Options) (*Silences, error) {
 		mc:        matcherCache{},
 		logger:    log.NewNopLogger(),
 		retention: o.Retention,
+		cipher:    o.Cipher,
 		now:       utcNow,
 		broadcast: func([]byte) {},
 		st:        state{},
@@ -234,7 +271,11 @@ func New(o Options) (*Silences, error) {
 		s.logger = o.Logger
 	}
 	if o.SnapshotReader != nil {
-		if err := s.loadSnapshot(o.SnapshotReader); err != nil {
+		r, err := s.cipher.Reader(o.SnapshotReader)
+		if err != nil {
+			return s, err
+		}
+		if err := s.loadSnapshot(r); err != nil {
 			return s, err
 		}
 	}
@@ -268,7 +309,11 @@ func (s *Silences) Maintenance(interval time.Duration, snapf string, stopc <-cha
 		if err != nil {
 			return err
 		}
-		if size, err = s.Snapshot(f); err != nil {
+		w := s.cipher.Writer(f)
+		if size, err = s.Snapshot(w); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
 			return err
 		}
 		return f.Close()
@@ -298,11 +343,14 @@ Loop:
 // than the configured retention time ago.
 func (s *Silences) GC() (int, error) {
 	start := time.Now()
-	defer func() { s.metrics.gcDuration.Observe(time.Since(start).Seconds()) }()
-
 	now := s.now()
 	var n int
 
+	defer func() {
+		s.metrics.gcDuration.Observe(time.Since(start).Seconds())
+		s.metrics.gcRemovedTotal.Add(float64(n))
+	}()
+
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
@@ -313,6 +361,7 @@ func (s *Silences) GC() (int, error) {
 		if !sil.ExpiresAt.After(now) {
 			delete(s.st, id)
 			delete(s.mc, sil.Silence)
+			s.idx.delete(id)
 			n++
 		}
 	}
@@ -334,7 +383,14 @@ func validateMatcher(m *pb.Matcher) error {
 			return fmt.Errorf("invalid regular expression %q: %s", m.Pattern, err)
 		}
 	default:
-		return fmt.Errorf("unknown matcher type %q", m.Type)
+		op, ok := matcherOperators[m.Type]
+		if !ok {
+			return fmt.Errorf("unknown matcher type %q", m.Type)
+		}
+		mt := types.Matcher{Name: m.Name, Value: m.Pattern, Operator: op}
+		if err := mt.Validate(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -363,6 +419,11 @@ func validateSilence(s *pb.Silence) error {
 	if s.UpdatedAt.IsZero() {
 		return errors.New("invalid zero update timestamp")
 	}
+	if s.Schedule != nil {
+		if _, err := ParseSchedule(s.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule: %s", err)
+		}
+	}
 	return nil
 }
 
@@ -397,14 +458,65 @@ func (s *Silences) setSilence(sil *pb.Silence) error {
 	}
 
 	s.st.merge(msil)
+	s.idx.add(s.st[sil.Id].Silence)
 	s.broadcast(b)
 
 	return nil
 }
 
+// Origin describes who requested a modification of a silence. It is
+// recorded in the history of the silence.
+type Origin struct {
+	// The user requesting the modification. Defaults to the creator of
+	// the silence.
+	Author string
+	// The address the modification was requested from.
+	RemoteAddr string
+}
+
+// maxHistory is the maximum number of changes kept in the history of a
+// silence. The oldest changes are dropped first.
+const maxHistory = 50
+
+// addChange appends a change of the given type to the history of sil. The
+// history of prev is carried over unless sil replaces it. The previous
+// version is recorded without its history and comment thread, which are
+// kept by the silence itself.
+func addChange(sil, prev *pb.Silence, typ pb.SilenceChange_Type, o Origin, now time.Time) {
+	c := &pb.SilenceChange{
+		Type:       typ,
+		Timestamp:  now,
+		Author:     o.Author,
+		RemoteAddr: o.RemoteAddr,
+	}
+	if c.Author == "" {
+		c.Author = sil.CreatedBy
+	}
+	var history []*pb.SilenceChange
+	if prev != nil {
+		c.Previous = cloneSilence(prev)
+		c.Previous.History = nil
+		c.Previous.Thread = nil
+		if typ != pb.SilenceChange_CREATED {
+			history = prev.History
+		}
+	}
+	if len(history) >= maxHistory {
+		history = history[len(history)-maxHistory+1:]
+	}
+	// Never append to the history of the stored silence in place.
+	sil.History = append(history[:len(history):len(history)], c)
+}
+
 // Set the specified silence. If a silence with the ID already exists and the modification
 // modifies history, the old silence gets expired and a new one is created.
 func (s *Silences) Set(sil *pb.Silence) (string, error) {
+	return s.SetFrom(sil, Origin{})
+}
+
+// SetFrom sets the specified silence like Set and records the origin of
+// the modification in the history of the silence.
+func (s *Silences) SetFrom(sil *pb.Silence, o Origin) (string, error) {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
@@ -414,13 +526,23 @@ func (s *Silences) Set(sil *pb.Silence) (string, error) {
 	if sil.Id != "" && !ok {
 		return "", ErrNotFound
 	}
+	if s.operatorsSupported != nil && !s.operatorsSupported() {
+		for _, m := range sil.Matchers {
+			if _, ok := matcherOperators[m.Type]; ok {
+				return "", fmt.Errorf("matcher type %s is not supported by all peers of the cluster", m.Type)
+			}
+		}
+	}
 	if ok {
 		if canUpdate(prev, sil, now) {
+			// Comments can only be appended with AddComment.
+			sil.Thread = prev.Thread
+			addChange(sil, prev, pb.SilenceChange_UPDATED, o, now)
 			return sil.Id, s.setSilence(sil)
 		}
 		if getState(prev, s.now()) != types.SilenceStateExpired {
 			// We cannot update the silence, expire the old one.
-			if err := s.expire(prev.Id); err != nil {
+			if err := s.expire(prev.Id, o); err != nil {
 				return "", errors.Wrap(err, "expire previous silence")
 			}
 		}
@@ -431,18 +553,47 @@ func (s *Silences) Set(sil *pb.Silence) (string, error) {
 	if sil.StartsAt.Before(now) {
 		sil.StartsAt = now
 	}
+	addChange(sil, prev, pb.SilenceChange_CREATED, o, now)
 
 	return sil.Id, s.setSilence(sil)
 }
 
+// AddComment appends a comment by the given author to the thread of the
+// silence with the given ID.
+func (s *Silences) AddComment(id, author, comment string) error {
+	if comment == "" {
+		return errors.New("comment missing")
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	prev, ok := s.getSilence(id)
+	if !ok {
+		return ErrNotFound
+	}
+	sil := cloneSilence(prev)
+	c := &pb.Comment{
+		Author:    author,
+		Comment:   comment,
+		Timestamp: s.now(),
+	}
+	// Never append to the thread of the stored silence in place.
+	sil.Thread = append(prev.Thread[:len(prev.Thread):len(prev.Thread)], c)
+
+	return s.setSilence(sil)
+}
+
 // canUpdate returns true if silence a can be updated to b without
 // affecting the historic view of silencing.
 func canUpdate(a, b *pb.Silence, now time.Time) bool {
 	if !reflect.DeepEqual(a.Matchers, b.Matchers) {
 		return false
 	}
+	if !reflect.DeepEqual(a.Schedule, b.Schedule) {
+		return false
+	}
 	// Allowed timestamp modifications depend on the current time.
-	switch st := getState(a, now); st {
+	switch st := getTimeRangeState(a, now); st {
 	case types.SilenceStateActive:
 		if !b.StartsAt.Equal(a.StartsAt) {
 			return false
@@ -464,21 +615,27 @@ func canUpdate(a, b *pb.Silence, now time.Time) bool {
 
 // Expire the silence with the given ID immediately.
 func (s *Silences) Expire(id string) error {
+	return s.ExpireFrom(id, Origin{})
+}
+
+// ExpireFrom expires the silence with the given ID immediately and records
+// the origin of the modification in the history of the silence.
+func (s *Silences) ExpireFrom(id string, o Origin) error {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
-	return s.expire(id)
+	return s.expire(id, o)
 }
 
 // Expire the silence with the given ID immediately.
-func (s *Silences) expire(id string) error {
-	sil, ok := s.getSilence(id)
+func (s *Silences) expire(id string, o Origin) error {
+	prev, ok := s.getSilence(id)
 	if !ok {
 		return ErrNotFound
 	}
-	sil = cloneSilence(sil)
+	sil := cloneSilence(prev)
 	now := s.now()
 
-	switch getState(sil, now) {
+	switch getTimeRangeState(sil, now) {
 	case types.SilenceStateExpired:
 		return errors.Errorf("silence %s already expired", id)
 	case types.SilenceStateActive:
@@ -488,6 +645,7 @@ func (s *Silences) expire(id string) error {
 		sil.StartsAt = now
 		sil.EndsAt = now
 	}
+	addChange(sil, prev, pb.SilenceChange_EXPIRED, o, now)
 
 	return s.setSilence(sil)
 }
@@ -498,6 +656,9 @@ type QueryParam func(*query) error
 type query struct {
 	ids     []string
 	filters []silenceFilter
+	// The label set silences must match. It restricts the base set to the
+	// silences from the index, the filters still apply.
+	matches model.LabelSet
 }
 
 // silenceFilter is a function that returns true if a silence
@@ -534,12 +695,28 @@ func QMatches(set model.LabelSet) QueryParam {
 			return m.Match(set), nil
 		}
 		q.filters = append(q.filters, f)
+		q.matches = set
 		return nil
 	}
 }
 
 // getState returns a silence's SilenceState at the given timestamp.
+// Scheduled silences are pending outside of their windows.
 func getState(sil *pb.Silence, ts time.Time) types.SilenceState {
+	st := getTimeRangeState(sil, ts)
+	if st != types.SilenceStateActive || sil.Schedule == nil {
+		return st
+	}
+	sched, err := ParseSchedule(sil.Schedule)
+	if err != nil || sched.Contains(ts) {
+		return st
+	}
+	return types.SilenceStatePending
+}
+
+// getTimeRangeState returns a silence's SilenceState at the given timestamp
+// regardless of its schedule.
+func getTimeRangeState(sil *pb.Silence, ts time.Time) types.SilenceState {
 	if ts.Before(sil.StartsAt) {
 		return types.SilenceStatePending
 	}
@@ -549,6 +726,35 @@ func getState(sil *pb.Silence, ts time.Time) types.SilenceState {
 	return types.SilenceStateActive
 }
 
+// State returns a silence's SilenceState at the given timestamp.
+func State(sil *pb.Silence, ts time.Time) types.SilenceState {
+	return getState(sil, ts)
+}
+
+// NextActivation returns the time after ts at which a scheduled silence
+// becomes active next. It returns false if the silence has no schedule or
+// does not become active again before it ends.
+func NextActivation(sil *pb.Silence, ts time.Time) (time.Time, bool) {
+	if sil.Schedule == nil {
+		return time.Time{}, false
+	}
+	sched, err := ParseSchedule(sil.Schedule)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if ts.Before(sil.StartsAt) {
+		if sched.Contains(sil.StartsAt) {
+			return sil.StartsAt, true
+		}
+		ts = sil.StartsAt
+	}
+	next := sched.Next(ts)
+	if next.IsZero() || next.After(sil.EndsAt) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
 // QState filters queried silences by the given states.
 func QState(states ...types.SilenceState) QueryParam {
 	return func(q *query) error {
@@ -626,6 +832,12 @@ func (s *Silences) query(q *query, now time.Time) ([]*pb.Silence, error) {
 				res = append(res, s.Silence)
 			}
 		}
+	} else if q.matches != nil {
+		for _, id := range s.idx.candidates(q.matches) {
+			if s, ok := s.st[id]; ok {
+				res = append(res, s.Silence)
+			}
+		}
 	} else {
 		for _, sil := range s.st {
 			res = append(res, sil.Silence)
@@ -654,7 +866,8 @@ func (s *Silences) query(q *query, now time.Time) ([]*pb.Silence, error) {
 }
 
 // loadSnapshot loads a snapshot generated by Snapshot() into the state.
-// Any previous state is wiped.
+// Any previous state is wiped. The expiration of the loaded silences is
+// updated to the configured retention.
 func (s *Silences) loadSnapshot(r io.Reader) error {
 	st, err := decodeState(r)
 	if err != nil {
@@ -667,10 +880,19 @@ func (s *Silences) loadSnapshot(r io.Reader) error {
 			e.Silence.CreatedBy = e.Silence.Comments[0].Author
 			e.Silence.Comments = nil
 		}
+		// Expire the loaded silences with the current retention.
+		if s.retention > 0 {
+			e.ExpiresAt = e.Silence.EndsAt.Add(s.retention)
+		}
 		st[e.Silence.Id] = e
 	}
+	var idx silenceIndex
+	for _, e := range st {
+		idx.add(e.Silence)
+	}
 	s.mtx.Lock()
 	s.st = st
+	s.idx = idx
 	s.mtx.Unlock()
 
 	return nil
@@ -712,6 +934,7 @@ func (s *Silences) Merge(b []byte) error {
 
 	for _, e := range st {
 		s.st.merge(e)
+		s.idx.add(s.st[e.Silence.Id].Silence)
 	}
 	return nil
 }
@@ -722,6 +945,47 @@ func (s *Silences) SetBroadcast(f func([]byte)) {
 	s.mtx.Unlock()
 }
 
+// SetMatcherOperatorsSupported sets the function returning whether all
+// peers of the cluster support the absent, present and numeric matchers.
+// Older peers treat them as equality matchers, so that a silence with a
+// present matcher would mute all alerts with the label on those peers.
+// Silences using the operators are rejected until all peers are upgraded.
+func (s *Silences) SetMatcherOperatorsSupported(f func() bool) {
+	s.mtx.Lock()
+	s.operatorsSupported = f
+	s.mtx.Unlock()
+}
+
+// SetQuorumBroadcast sets the function used by Replicate to broadcast a
+// silence and wait for its acknowledgement by a quorum of peers.
+func (s *Silences) SetQuorumBroadcast(f func(context.Context, []byte) error) {
+	s.mtx.Lock()
+	s.quorumBroadcast = f
+	s.mtx.Unlock()
+}
+
+// Replicate broadcasts the current state of the silence with the given ID
+// and returns once it was acknowledged by a quorum of peers. It returns
+// immediately if no quorum broadcast is set.
+func (s *Silences) Replicate(ctx context.Context, id string) error {
+	s.mtx.RLock()
+	msil, ok := s.st[id]
+	f := s.quorumBroadcast
+	s.mtx.RUnlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+	if f == nil {
+		return nil
+	}
+	b, err := marshalMeshSilence(msil)
+	if err != nil {
+		return err
+	}
+	return f(ctx, b)
+}
+
 type state map[string]*pb.MeshSilence
 
 func (s state) merge(e *pb.MeshSilence) {
@@ -15,16 +15,21 @@ package silence
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/matttproud/golang_protobuf_extensions/pbutil"
+	"github.com/prometheus/alertmanager/pkg/snapcrypt"
 	pb "github.com/prometheus/alertmanager/silence/silencepb"
 	"github.com/prometheus/alertmanager/types"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 )
@@ -94,6 +99,10 @@ func TestSilencesGC(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, 2, n)
 	require.Equal(t, want, s.st)
+
+	var m dto.Metric
+	require.NoError(t, s.metrics.gcRemovedTotal.Write(&m))
+	require.Equal(t, float64(2), m.GetCounter().GetValue())
 }
 
 func TestSilencesSnapshot(t *testing.T) {
@@ -161,6 +170,65 @@ func TestSilencesSnapshot(t *testing.T) {
 	}
 }
 
+func TestSilencesSnapshotRetention(t *testing.T) {
+	now := utcNow()
+
+	s1 := &Silences{st: state{}, metrics: newMetrics(nil, nil)}
+	s1.st["1"] = &pb.MeshSilence{
+		Silence: &pb.Silence{
+			Id:        "1",
+			Matchers:  []*pb.Matcher{{Name: "a", Pattern: "b", Type: pb.Matcher_EQUAL}},
+			StartsAt:  now,
+			EndsAt:    now.Add(time.Hour),
+			UpdatedAt: now,
+		},
+		ExpiresAt: now.Add(121 * time.Hour),
+	}
+	var buf bytes.Buffer
+	_, err := s1.Snapshot(&buf)
+	require.NoError(t, err)
+
+	s2 := &Silences{mc: matcherCache{}, st: state{}, retention: 24 * time.Hour}
+	require.NoError(t, s2.loadSnapshot(&buf))
+	require.Equal(t, now.Add(25*time.Hour), s2.st["1"].ExpiresAt)
+}
+
+func TestSilencesEncryptedSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "silences")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	snapf := filepath.Join(dir, "silences")
+
+	c, err := snapcrypt.New(bytes.Repeat([]byte{0x42}, 32))
+	require.NoError(t, err)
+
+	s1, err := New(Options{Cipher: c, Retention: time.Hour})
+	require.NoError(t, err)
+	now := utcNow()
+	_, err = s1.Set(&pb.Silence{
+		Matchers: []*pb.Matcher{{Type: pb.Matcher_EQUAL, Name: "instance", Pattern: "db-customer-42"}},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	stopc := make(chan struct{})
+	close(stopc)
+	// Writes the final snapshot only.
+	s1.Maintenance(time.Hour, snapf, stopc)
+
+	b, err := ioutil.ReadFile(snapf)
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(b, []byte("db-customer-42")), "snapshot is not encrypted")
+
+	s2, err := New(Options{Cipher: c, SnapshotFile: snapf})
+	require.NoError(t, err)
+	require.Equal(t, s1.st, s2.st)
+
+	_, err = New(Options{SnapshotFile: snapf})
+	require.Error(t, err)
+}
+
 func TestSilencesSetSilence(t *testing.T) {
 	s, err := New(Options{
 		Retention: time.Minute,
@@ -212,6 +280,40 @@ func TestSilencesSetSilence(t *testing.T) {
 	require.Equal(t, want, s.st, "Unexpected silence state")
 }
 
+func TestSilencesReplicate(t *testing.T) {
+	s, err := New(Options{
+		Retention: time.Minute,
+	})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	id, err := s.Set(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "abc", Pattern: "def"}},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// Without a quorum broadcast there is nothing to wait for.
+	require.NoError(t, s.Replicate(context.Background(), id))
+	require.Equal(t, ErrNotFound, s.Replicate(context.Background(), "unknown"))
+
+	var got pb.MeshSilence
+	s.SetQuorumBroadcast(func(_ context.Context, b []byte) error {
+		_, err := pbutil.ReadDelimited(bytes.NewReader(b), &got)
+		return err
+	})
+	require.NoError(t, s.Replicate(context.Background(), id))
+	require.Equal(t, s.st[id], &got)
+
+	s.SetQuorumBroadcast(func(context.Context, []byte) error {
+		return errors.New("no quorum")
+	})
+	require.EqualError(t, s.Replicate(context.Background(), id), "no quorum")
+}
+
 func TestSilenceSet(t *testing.T) {
 	s, err := New(Options{
 		Retention: time.Hour,
@@ -240,6 +342,9 @@ func TestSilenceSet(t *testing.T) {
 				StartsAt:  now1.Add(2 * time.Minute),
 				EndsAt:    now1.Add(5 * time.Minute),
 				UpdatedAt: now1,
+				History: []*pb.SilenceChange{
+					{Type: pb.SilenceChange_CREATED, Timestamp: now1},
+				},
 			},
 			ExpiresAt: now1.Add(5*time.Minute + s.retention),
 		},
@@ -267,6 +372,9 @@ func TestSilenceSet(t *testing.T) {
 				StartsAt:  now2,
 				EndsAt:    now2.Add(1 * time.Minute),
 				UpdatedAt: now2,
+				History: []*pb.SilenceChange{
+					{Type: pb.SilenceChange_CREATED, Timestamp: now2},
+				},
 			},
 			ExpiresAt: now2.Add(1*time.Minute + s.retention),
 		},
@@ -284,6 +392,7 @@ func TestSilenceSet(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, id2, id3)
 
+	prev := withoutHistory(want[id2].Silence)
 	want = state{
 		id1: want[id1],
 		id2: &pb.MeshSilence{
@@ -293,6 +402,11 @@ func TestSilenceSet(t *testing.T) {
 				StartsAt:  now2,
 				EndsAt:    now3.Add(100 * time.Minute),
 				UpdatedAt: now3,
+				History: append(want[id2].Silence.History, &pb.SilenceChange{
+					Type:      pb.SilenceChange_UPDATED,
+					Timestamp: now3,
+					Previous:  prev,
+				}),
 			},
 			ExpiresAt: now3.Add(100*time.Minute + s.retention),
 		},
@@ -310,6 +424,7 @@ func TestSilenceSet(t *testing.T) {
 	require.NoError(t, err)
 	require.NotEqual(t, id2, id4)
 
+	prev = withoutHistory(want[id2].Silence)
 	want = state{
 		id1: want[id1],
 		id2: &pb.MeshSilence{
@@ -319,6 +434,11 @@ func TestSilenceSet(t *testing.T) {
 				StartsAt:  now2,
 				EndsAt:    now4,
 				UpdatedAt: now4,
+				History: append(want[id2].Silence.History, &pb.SilenceChange{
+					Type:      pb.SilenceChange_EXPIRED,
+					Timestamp: now4,
+					Previous:  prev,
+				}),
 			},
 			ExpiresAt: now4.Add(s.retention),
 		},
@@ -329,6 +449,9 @@ func TestSilenceSet(t *testing.T) {
 				StartsAt:  now4,
 				EndsAt:    now3.Add(100 * time.Minute),
 				UpdatedAt: now4,
+				History: []*pb.SilenceChange{
+					{Type: pb.SilenceChange_CREATED, Timestamp: now4, Previous: prev},
+				},
 			},
 			ExpiresAt: now3.Add(100*time.Minute + s.retention),
 		},
@@ -358,6 +481,9 @@ func TestSilenceSet(t *testing.T) {
 				StartsAt:  now5,
 				EndsAt:    now5.Add(5 * time.Minute),
 				UpdatedAt: now5,
+				History: []*pb.SilenceChange{
+					{Type: pb.SilenceChange_CREATED, Timestamp: now5, Previous: withoutHistory(want[id2].Silence)},
+				},
 			},
 			ExpiresAt: now5.Add(5*time.Minute + s.retention),
 		},
@@ -365,6 +491,93 @@ func TestSilenceSet(t *testing.T) {
 	require.Equal(t, want, s.st, "unexpected state after silence creation")
 }
 
+// withoutHistory returns a copy of the silence without its history and
+// comment thread, as it is recorded as the previous version in changes.
+func withoutHistory(sil *pb.Silence) *pb.Silence {
+	s := cloneSilence(sil)
+	s.History = nil
+	s.Thread = nil
+	return s
+}
+
+func TestSilencesAddComment(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	id, err := s.Set(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, ErrNotFound, s.AddComment("unknown", "alice", "extended"))
+	require.EqualError(t, s.AddComment(id, "alice", ""), "comment missing")
+
+	now = now.Add(time.Minute)
+	require.NoError(t, s.AddComment(id, "alice", "waiting for the fix"))
+	now = now.Add(time.Minute)
+	require.NoError(t, s.AddComment(id, "bob", "extended during handover"))
+
+	sil, err := s.QueryOne(QIDs(id))
+	require.NoError(t, err)
+	require.Equal(t, []*pb.Comment{
+		{Author: "alice", Comment: "waiting for the fix", Timestamp: now.Add(-time.Minute)},
+		{Author: "bob", Comment: "extended during handover", Timestamp: now},
+	}, sil.Thread)
+	require.Equal(t, now, sil.UpdatedAt)
+
+	// Updates keep the thread.
+	now = now.Add(time.Minute)
+	upd := cloneSilence(sil)
+	upd.Thread = nil
+	upd.EndsAt = now.Add(2 * time.Hour)
+	_, err = s.Set(upd)
+	require.NoError(t, err)
+
+	sil, err = s.QueryOne(QIDs(id))
+	require.NoError(t, err)
+	require.Len(t, sil.Thread, 2)
+	// The previous versions in the history don't repeat the thread.
+	require.Nil(t, sil.History[len(sil.History)-1].Previous.Thread)
+}
+
+func TestSilencesHistoryLimit(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	id, err := s.Set(&pb.Silence{
+		Matchers: []*pb.Matcher{{Name: "a", Pattern: "b"}},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	for i := 1; i <= maxHistory+10; i++ {
+		now = now.Add(time.Second)
+		sil, err := s.QueryOne(QIDs(id))
+		require.NoError(t, err)
+		upd := cloneSilence(sil)
+		upd.EndsAt = upd.EndsAt.Add(time.Minute)
+		_, err = s.Set(upd)
+		require.NoError(t, err)
+	}
+
+	// Only the most recent changes are kept.
+	sil, err := s.QueryOne(QIDs(id))
+	require.NoError(t, err)
+	require.Len(t, sil.History, maxHistory)
+	require.Equal(t, pb.SilenceChange_UPDATED, sil.History[0].Type)
+	last := sil.History[len(sil.History)-1]
+	require.Equal(t, sil.EndsAt.Add(-time.Minute), last.Previous.EndsAt)
+}
+
 func TestSilencesSetFail(t *testing.T) {
 	s, err := New(Options{})
 	require.NoError(t, err)
@@ -402,6 +615,33 @@ func TestSilencesSetFail(t *testing.T) {
 	}
 }
 
+func TestSilencesMatcherOperatorsSupported(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	supported := false
+	s.SetMatcherOperatorsSupported(func() bool { return supported })
+
+	now := utcNow()
+	sil := func(typ pb.Matcher_Type) *pb.Silence {
+		return &pb.Silence{
+			Matchers: []*pb.Matcher{{Type: typ, Name: "a", Pattern: ""}},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Hour),
+		}
+	}
+
+	// Older peers would treat a present matcher as matching the empty value.
+	_, err = s.Set(sil(pb.Matcher_PRESENT))
+	require.EqualError(t, err, "matcher type PRESENT is not supported by all peers of the cluster")
+	_, err = s.Set(sil(pb.Matcher_REGEXP))
+	require.NoError(t, err)
+
+	supported = true
+	_, err = s.Set(sil(pb.Matcher_PRESENT))
+	require.NoError(t, err)
+}
+
 func TestQState(t *testing.T) {
 	now := utcNow()
 
@@ -446,6 +686,44 @@ func TestQState(t *testing.T) {
 	}
 }
 
+func TestQMatchesOperators(t *testing.T) {
+	s, err := New(Options{})
+	require.NoError(t, err)
+
+	now := utcNow()
+	s.now = func() time.Time { return now }
+
+	id, err := s.Set(&pb.Silence{
+		Matchers: []*pb.Matcher{
+			{Type: pb.Matcher_ABSENT, Name: "owner"},
+			{Type: pb.Matcher_LESS_THAN, Name: "priority", Pattern: "3"},
+		},
+		StartsAt: now,
+		EndsAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		lset model.LabelSet
+		ids  []string
+	}{
+		{lset: model.LabelSet{"priority": "2"}, ids: []string{id}},
+		{lset: model.LabelSet{"priority": "3"}, ids: nil},
+		{lset: model.LabelSet{"priority": "high"}, ids: nil},
+		{lset: model.LabelSet{"priority": "1", "owner": "team-A"}, ids: nil},
+	}
+	for _, c := range cases {
+		sils, err := s.Query(QState(types.SilenceStateActive), QMatches(c.lset))
+		require.NoError(t, err)
+
+		var ids []string
+		for _, sil := range sils {
+			ids = append(ids, sil.Id)
+		}
+		require.Equal(t, c.ids, ids, "label set %v", c.lset)
+	}
+}
+
 func TestQMatches(t *testing.T) {
 	qp := QMatches(model.LabelSet{
 		"job":      "test",
@@ -747,14 +1025,17 @@ func TestSilenceExpire(t *testing.T) {
 		}},
 	}
 
+	pending := s.st["pending"].Silence
+	active := s.st["active"].Silence
+
 	count, err := s.CountState(types.SilenceStatePending)
 	require.NoError(t, err)
 	require.Equal(t, 1, count)
 
-	require.NoError(t, s.expire("pending"))
-	require.NoError(t, s.expire("active"))
+	require.NoError(t, s.Expire("pending"))
+	require.NoError(t, s.ExpireFrom("active", Origin{Author: "admin", RemoteAddr: "10.0.0.1"}))
 
-	err = s.expire("expired")
+	err = s.Expire("expired")
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "already expired")
 
@@ -766,6 +1047,9 @@ func TestSilenceExpire(t *testing.T) {
 		StartsAt:  now,
 		EndsAt:    now,
 		UpdatedAt: now,
+		History: []*pb.SilenceChange{
+			{Type: pb.SilenceChange_EXPIRED, Timestamp: now, Previous: pending},
+		},
 	}, sil)
 
 	count, err = s.CountState(types.SilenceStatePending)
@@ -785,6 +1069,13 @@ func TestSilenceExpire(t *testing.T) {
 		StartsAt:  now.Add(-time.Minute),
 		EndsAt:    now,
 		UpdatedAt: now,
+		History: []*pb.SilenceChange{{
+			Type:       pb.SilenceChange_EXPIRED,
+			Timestamp:  now,
+			Author:     "admin",
+			RemoteAddr: "10.0.0.1",
+			Previous:   active,
+		}},
 	}, sil)
 
 	sil, err = s.QueryOne(QIDs("expired"))
@@ -838,6 +1129,33 @@ func TestValidateMatcher(t *testing.T) {
 				Type:    333,
 			},
 			err: "unknown matcher type",
+		}, {
+			m: &pb.Matcher{
+				Name: "a",
+				Type: pb.Matcher_ABSENT,
+			},
+			err: "",
+		}, {
+			m: &pb.Matcher{
+				Name:    "a",
+				Pattern: "b",
+				Type:    pb.Matcher_PRESENT,
+			},
+			err: "unexpected value",
+		}, {
+			m: &pb.Matcher{
+				Name:    "a",
+				Pattern: "1.5",
+				Type:    pb.Matcher_GREATER_EQUAL,
+			},
+			err: "",
+		}, {
+			m: &pb.Matcher{
+				Name:    "a",
+				Pattern: "b",
+				Type:    pb.Matcher_LESS_THAN,
+			},
+			err: "invalid number",
 		},
 	}
 
@@ -964,6 +1282,19 @@ func TestValidateSilence(t *testing.T) {
 			},
 			err: "invalid zero update timestamp",
 		},
+		{
+			s: &pb.Silence{
+				Id: "some_id",
+				Matchers: []*pb.Matcher{
+					&pb.Matcher{Name: "a", Pattern: "b"},
+				},
+				StartsAt:  validTimestamp,
+				EndsAt:    validTimestamp,
+				UpdatedAt: validTimestamp,
+				Schedule:  &pb.Schedule{Cron: "0 22 * *", Duration: time.Hour},
+			},
+			err: "invalid schedule",
+		},
 	}
 	for _, c := range cases {
 		err := validateSilence(c.s)
@@ -1061,6 +1392,23 @@ func TestStateCoding(t *testing.T) {
 					},
 					ExpiresAt: now.Add(24 * time.Hour),
 				},
+				{
+					Silence: &pb.Silence{
+						Id: "9b1cd8a6-3f8b-4d0e-9a3e-5f3b0d6c2a11",
+						Matchers: []*pb.Matcher{
+							{Name: "label1", Pattern: "val1", Type: pb.Matcher_EQUAL},
+						},
+						StartsAt:  now,
+						EndsAt:    now.Add(30 * 24 * time.Hour),
+						UpdatedAt: now,
+						Schedule: &pb.Schedule{
+							Cron:     "0 22 * * *",
+							Duration: 8 * time.Hour,
+							TimeZone: "Europe/Berlin",
+						},
+					},
+					ExpiresAt: now.Add(31 * 24 * time.Hour),
+				},
 			},
 		},
 	}
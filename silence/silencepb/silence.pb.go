@@ -12,6 +12,8 @@
 		Comment
 		Silence
 		MeshSilence
+		Schedule
+		SilenceChange
 */
 package silencepb
 
@@ -46,15 +48,37 @@ type Matcher_Type int32
 const (
 	Matcher_EQUAL  Matcher_Type = 0
 	Matcher_REGEXP Matcher_Type = 1
+	// The label is not set. The pattern is empty.
+	Matcher_ABSENT Matcher_Type = 2
+	// The label is set. The pattern is empty.
+	Matcher_PRESENT Matcher_Type = 3
+	// The label value is a number less than, less than or equal to,
+	// greater than or greater than or equal to the number in the pattern.
+	Matcher_LESS_THAN     Matcher_Type = 4
+	Matcher_LESS_EQUAL    Matcher_Type = 5
+	Matcher_GREATER_THAN  Matcher_Type = 6
+	Matcher_GREATER_EQUAL Matcher_Type = 7
 )
 
 var Matcher_Type_name = map[int32]string{
 	0: "EQUAL",
 	1: "REGEXP",
+	2: "ABSENT",
+	3: "PRESENT",
+	4: "LESS_THAN",
+	5: "LESS_EQUAL",
+	6: "GREATER_THAN",
+	7: "GREATER_EQUAL",
 }
 var Matcher_Type_value = map[string]int32{
-	"EQUAL":  0,
-	"REGEXP": 1,
+	"EQUAL":         0,
+	"REGEXP":        1,
+	"ABSENT":        2,
+	"PRESENT":       3,
+	"LESS_THAN":     4,
+	"LESS_EQUAL":    5,
+	"GREATER_THAN":  6,
+	"GREATER_EQUAL": 7,
 }
 
 func (x Matcher_Type) String() string {
@@ -62,6 +86,30 @@ func (x Matcher_Type) String() string {
 }
 func (Matcher_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptorSilence, []int{0, 0} }
 
+type SilenceChange_Type int32
+
+const (
+	SilenceChange_CREATED SilenceChange_Type = 0
+	SilenceChange_UPDATED SilenceChange_Type = 1
+	SilenceChange_EXPIRED SilenceChange_Type = 2
+)
+
+var SilenceChange_Type_name = map[int32]string{
+	0: "CREATED",
+	1: "UPDATED",
+	2: "EXPIRED",
+}
+var SilenceChange_Type_value = map[string]int32{
+	"CREATED": 0,
+	"UPDATED": 1,
+	"EXPIRED": 2,
+}
+
+func (x SilenceChange_Type) String() string {
+	return proto.EnumName(SilenceChange_Type_name, int32(x))
+}
+func (SilenceChange_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptorSilence, []int{5, 0} }
+
 // Matcher specifies a rule, which can match or set of labels or not.
 type Matcher struct {
 	Type Matcher_Type `protobuf:"varint,1,opt,name=type,proto3,enum=silencepb.Matcher_Type" json:"type,omitempty"`
@@ -107,6 +155,16 @@ type Silence struct {
 	// Comment for the silence.
 	CreatedBy string `protobuf:"bytes,8,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
 	Comment   string `protobuf:"bytes,9,opt,name=comment,proto3" json:"comment,omitempty"`
+	// An optional schedule limiting the silence to recurring windows
+	// within its time range.
+	Schedule *Schedule `protobuf:"bytes,10,opt,name=schedule" json:"schedule,omitempty"`
+	// The changes made to the silence, oldest first.
+	History []*SilenceChange `protobuf:"bytes,11,rep,name=history" json:"history,omitempty"`
+	// Comments appended to the silence after its creation, oldest first.
+	Thread []*Comment `protobuf:"bytes,12,rep,name=thread" json:"thread,omitempty"`
+	// The team owning the silence. Only the owning team may modify or expire
+	// an owned silence if the silence policy enforces ownership.
+	Owner string `protobuf:"bytes,13,opt,name=owner,proto3" json:"owner,omitempty"`
 }
 
 func (m *Silence) Reset()                    { *m = Silence{} }
@@ -126,12 +184,48 @@ func (m *MeshSilence) String() string            { return proto.CompactTextStrin
 func (*MeshSilence) ProtoMessage()               {}
 func (*MeshSilence) Descriptor() ([]byte, []int) { return fileDescriptorSilence, []int{3} }
 
+// Schedule specifies recurring windows during which a silence is active.
+type Schedule struct {
+	// A cron expression of the times at which a window starts.
+	Cron string `protobuf:"bytes,1,opt,name=cron,proto3" json:"cron,omitempty"`
+	// The length of each window.
+	Duration time.Duration `protobuf:"bytes,2,opt,name=duration,stdduration" json:"duration"`
+	// The IANA time zone the cron expression is evaluated in. Defaults to UTC.
+	TimeZone string `protobuf:"bytes,3,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+}
+
+func (m *Schedule) Reset()                    { *m = Schedule{} }
+func (m *Schedule) String() string            { return proto.CompactTextString(m) }
+func (*Schedule) ProtoMessage()               {}
+func (*Schedule) Descriptor() ([]byte, []int) { return fileDescriptorSilence, []int{4} }
+
+// SilenceChange records a modification of a silence.
+type SilenceChange struct {
+	Type SilenceChange_Type `protobuf:"varint,1,opt,name=type,proto3,enum=silencepb.SilenceChange_Type" json:"type,omitempty"`
+	// The time of the change.
+	Timestamp time.Time `protobuf:"bytes,2,opt,name=timestamp,stdtime" json:"timestamp"`
+	// The user who made the change.
+	Author string `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	// The address the change was requested from.
+	RemoteAddr string `protobuf:"bytes,4,opt,name=remote_addr,json=remoteAddr,proto3" json:"remote_addr,omitempty"`
+	// The silence before the change, without its history.
+	Previous *Silence `protobuf:"bytes,5,opt,name=previous" json:"previous,omitempty"`
+}
+
+func (m *SilenceChange) Reset()                    { *m = SilenceChange{} }
+func (m *SilenceChange) String() string            { return proto.CompactTextString(m) }
+func (*SilenceChange) ProtoMessage()               {}
+func (*SilenceChange) Descriptor() ([]byte, []int) { return fileDescriptorSilence, []int{5} }
+
 func init() {
 	proto.RegisterType((*Matcher)(nil), "silencepb.Matcher")
 	proto.RegisterType((*Comment)(nil), "silencepb.Comment")
 	proto.RegisterType((*Silence)(nil), "silencepb.Silence")
 	proto.RegisterType((*MeshSilence)(nil), "silencepb.MeshSilence")
+	proto.RegisterType((*Schedule)(nil), "silencepb.Schedule")
+	proto.RegisterType((*SilenceChange)(nil), "silencepb.SilenceChange")
 	proto.RegisterEnum("silencepb.Matcher_Type", Matcher_Type_name, Matcher_Type_value)
+	proto.RegisterEnum("silencepb.SilenceChange_Type", SilenceChange_Type_name, SilenceChange_Type_value)
 }
 func (m *Matcher) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
@@ -287,6 +381,46 @@ func (m *Silence) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintSilence(dAtA, i, uint64(len(m.Comment)))
 		i += copy(dAtA[i:], m.Comment)
 	}
+	if m.Schedule != nil {
+		dAtA[i] = 0x52
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(m.Schedule.Size()))
+		n7, err := m.Schedule.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n7
+	}
+	if len(m.History) > 0 {
+		for _, msg := range m.History {
+			dAtA[i] = 0x5a
+			i++
+			i = encodeVarintSilence(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.Thread) > 0 {
+		for _, msg := range m.Thread {
+			dAtA[i] = 0x62
+			i++
+			i = encodeVarintSilence(dAtA, i, uint64(msg.Size()))
+			n, err := msg.MarshalTo(dAtA[i:])
+			if err != nil {
+				return 0, err
+			}
+			i += n
+		}
+	}
+	if len(m.Owner) > 0 {
+		dAtA[i] = 0x6a
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.Owner)))
+		i += copy(dAtA[i:], m.Owner)
+	}
 	return i, nil
 }
 
@@ -326,6 +460,97 @@ func (m *MeshSilence) MarshalTo(dAtA []byte) (int, error) {
 	return i, nil
 }
 
+func (m *Schedule) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Schedule) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if len(m.Cron) > 0 {
+		dAtA[i] = 0xa
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.Cron)))
+		i += copy(dAtA[i:], m.Cron)
+	}
+	dAtA[i] = 0x12
+	i++
+	i = encodeVarintSilence(dAtA, i, uint64(types.SizeOfStdDuration(m.Duration)))
+	n8, err := types.StdDurationMarshalTo(m.Duration, dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n8
+	if len(m.TimeZone) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.TimeZone)))
+		i += copy(dAtA[i:], m.TimeZone)
+	}
+	return i, nil
+}
+
+func (m *SilenceChange) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalTo(dAtA)
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *SilenceChange) MarshalTo(dAtA []byte) (int, error) {
+	var i int
+	_ = i
+	var l int
+	_ = l
+	if m.Type != 0 {
+		dAtA[i] = 0x8
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(m.Type))
+	}
+	dAtA[i] = 0x12
+	i++
+	i = encodeVarintSilence(dAtA, i, uint64(types.SizeOfStdTime(m.Timestamp)))
+	n9, err := types.StdTimeMarshalTo(m.Timestamp, dAtA[i:])
+	if err != nil {
+		return 0, err
+	}
+	i += n9
+	if len(m.Author) > 0 {
+		dAtA[i] = 0x1a
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.Author)))
+		i += copy(dAtA[i:], m.Author)
+	}
+	if len(m.RemoteAddr) > 0 {
+		dAtA[i] = 0x22
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(len(m.RemoteAddr)))
+		i += copy(dAtA[i:], m.RemoteAddr)
+	}
+	if m.Previous != nil {
+		dAtA[i] = 0x2a
+		i++
+		i = encodeVarintSilence(dAtA, i, uint64(m.Previous.Size()))
+		n10, err := m.Previous.MarshalTo(dAtA[i:])
+		if err != nil {
+			return 0, err
+		}
+		i += n10
+	}
+	return i, nil
+}
+
 func encodeVarintSilence(dAtA []byte, offset int, v uint64) int {
 	for v >= 1<<7 {
 		dAtA[offset] = uint8(v&0x7f | 0x80)
@@ -401,6 +626,26 @@ func (m *Silence) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovSilence(uint64(l))
 	}
+	if m.Schedule != nil {
+		l = m.Schedule.Size()
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	if len(m.History) > 0 {
+		for _, e := range m.History {
+			l = e.Size()
+			n += 1 + l + sovSilence(uint64(l))
+		}
+	}
+	if len(m.Thread) > 0 {
+		for _, e := range m.Thread {
+			l = e.Size()
+			n += 1 + l + sovSilence(uint64(l))
+		}
+	}
+	l = len(m.Owner)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
 	return n
 }
 
@@ -416,6 +661,45 @@ func (m *MeshSilence) Size() (n int) {
 	return n
 }
 
+func (m *Schedule) Size() (n int) {
+	var l int
+	_ = l
+	l = len(m.Cron)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	l = types.SizeOfStdDuration(m.Duration)
+	n += 1 + l + sovSilence(uint64(l))
+	l = len(m.TimeZone)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	return n
+}
+
+func (m *SilenceChange) Size() (n int) {
+	var l int
+	_ = l
+	if m.Type != 0 {
+		n += 1 + sovSilence(uint64(m.Type))
+	}
+	l = types.SizeOfStdTime(m.Timestamp)
+	n += 1 + l + sovSilence(uint64(l))
+	l = len(m.Author)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	l = len(m.RemoteAddr)
+	if l > 0 {
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	if m.Previous != nil {
+		l = m.Previous.Size()
+		n += 1 + l + sovSilence(uint64(l))
+	}
+	return n
+}
+
 func sovSilence(x uint64) (n int) {
 	for {
 		n++
@@ -962,59 +1246,42 @@ func (m *Silence) Unmarshal(dAtA []byte) error {
 			}
 			m.Comment = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipSilence(dAtA[iNdEx:])
-			if err != nil {
-				return err
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Schedule", wireType)
 			}
-			if skippy < 0 {
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
 				return ErrInvalidLengthSilence
 			}
-			if (iNdEx + skippy) > l {
+			postIndex := iNdEx + msglen
+			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *MeshSilence) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowSilence
-			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			if m.Schedule == nil {
+				m.Schedule = &Schedule{}
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
-			if b < 0x80 {
-				break
+			if err := m.Schedule.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: MeshSilence: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: MeshSilence: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+			iNdEx = postIndex
+		case 11:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Silence", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field History", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -1038,16 +1305,14 @@ func (m *MeshSilence) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if m.Silence == nil {
-				m.Silence = &Silence{}
-			}
-			if err := m.Silence.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+			m.History = append(m.History, &SilenceChange{})
+			if err := m.History[len(m.History)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
-		case 2:
+		case 12:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ExpiresAt", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Thread", wireType)
 			}
 			var msglen int
 			for shift := uint(0); ; shift += 7 {
@@ -1071,7 +1336,478 @@ func (m *MeshSilence) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			if err := types.StdTimeUnmarshal(&m.ExpiresAt, dAtA[iNdEx:postIndex]); err != nil {
+			m.Thread = append(m.Thread, &Comment{})
+			if err := m.Thread[len(m.Thread)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Owner", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Owner = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipSilence(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthSilence
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MeshSilence) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowSilence
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MeshSilence: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MeshSilence: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Silence", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Silence == nil {
+				m.Silence = &Silence{}
+			}
+			if err := m.Silence.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExpiresAt", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := types.StdTimeUnmarshal(&m.ExpiresAt, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipSilence(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthSilence
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Schedule) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowSilence
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Schedule: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Schedule: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cron", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Cron = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Duration", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := types.StdDurationUnmarshal(&m.Duration, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TimeZone", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TimeZone = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipSilence(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthSilence
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *SilenceChange) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowSilence
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: SilenceChange: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: SilenceChange: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= (SilenceChange_Type(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := types.StdTimeUnmarshal(&m.Timestamp, dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Author", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Author = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RemoteAddr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= (uint64(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RemoteAddr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Previous", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowSilence
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthSilence
+			}
+			postIndex := iNdEx + msglen
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Previous == nil {
+				m.Previous = &Silence{}
+			}
+			if err := m.Previous.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
 			iNdEx = postIndex
@@ -1204,33 +1940,51 @@ var (
 func init() { proto.RegisterFile("silence.proto", fileDescriptorSilence) }
 
 var fileDescriptorSilence = []byte{
-	// 444 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x51, 0x4d, 0x6b, 0xdb, 0x40,
-	0x10, 0xf5, 0x2a, 0x8e, 0x65, 0x8d, 0x69, 0x30, 0x43, 0x69, 0x85, 0x21, 0xb6, 0xd1, 0xc9, 0xd0,
-	0x22, 0x83, 0x7b, 0xee, 0x41, 0x0e, 0xa6, 0x97, 0x06, 0x5a, 0x35, 0x85, 0xde, 0xca, 0xda, 0x9a,
-	0xda, 0x82, 0x48, 0xbb, 0x48, 0x63, 0xa8, 0x4f, 0x2d, 0xf4, 0x0f, 0xf4, 0x67, 0xf9, 0xd8, 0x5f,
-	0xd0, 0x0f, 0xff, 0x8b, 0xde, 0x8a, 0x56, 0x2b, 0x37, 0x21, 0x27, 0xdf, 0x66, 0x66, 0xdf, 0x9b,
-	0xb7, 0xef, 0x0d, 0x3c, 0x2a, 0xd3, 0x5b, 0xca, 0x57, 0x14, 0xea, 0x42, 0xb1, 0x42, 0xcf, 0xb6,
-	0x7a, 0x39, 0x18, 0xad, 0x95, 0x5a, 0xdf, 0xd2, 0xd4, 0x3c, 0x2c, 0xb7, 0x9f, 0xa6, 0x9c, 0x66,
-	0x54, 0xb2, 0xcc, 0x74, 0x8d, 0x1d, 0x3c, 0x5e, 0xab, 0xb5, 0x32, 0xe5, 0xb4, 0xaa, 0xea, 0x69,
-	0xf0, 0x4d, 0x80, 0x7b, 0x2d, 0x79, 0xb5, 0xa1, 0x02, 0x9f, 0x41, 0x9b, 0x77, 0x9a, 0x7c, 0x31,
-	0x16, 0x93, 0x8b, 0xd9, 0xd3, 0xf0, 0xb8, 0x3c, 0xb4, 0x88, 0xf0, 0x66, 0xa7, 0x29, 0x36, 0x20,
-	0x44, 0x68, 0xe7, 0x32, 0x23, 0xdf, 0x19, 0x8b, 0x89, 0x17, 0x9b, 0x1a, 0x7d, 0x70, 0xb5, 0x64,
-	0xa6, 0x22, 0xf7, 0xcf, 0xcc, 0xb8, 0x69, 0x83, 0x4b, 0x68, 0x57, 0x5c, 0xf4, 0xe0, 0x7c, 0xf1,
-	0xf6, 0x7d, 0xf4, 0xba, 0xdf, 0x42, 0x80, 0x4e, 0xbc, 0x78, 0xb5, 0xf8, 0xf0, 0xa6, 0x2f, 0x82,
-	0x2f, 0xe0, 0x5e, 0xa9, 0x2c, 0xa3, 0x9c, 0xf1, 0x09, 0x74, 0xe4, 0x96, 0x37, 0xaa, 0x30, 0xdf,
-	0xf0, 0x62, 0xdb, 0x55, 0xbb, 0x57, 0x35, 0xc4, 0x4a, 0x36, 0x2d, 0xce, 0xc1, 0x3b, 0x7a, 0x35,
-	0xba, 0xbd, 0xd9, 0x20, 0xac, 0xd3, 0x08, 0x9b, 0x34, 0xc2, 0x9b, 0x06, 0x31, 0xef, 0xee, 0x7f,
-	0x8e, 0x5a, 0xdf, 0x7f, 0x8d, 0x44, 0xfc, 0x9f, 0x16, 0xfc, 0x75, 0xc0, 0x7d, 0x57, 0xdb, 0xc5,
-	0x0b, 0x70, 0xd2, 0xc4, 0xaa, 0x3b, 0x69, 0x82, 0x21, 0x74, 0xb3, 0xda, 0x7f, 0xe9, 0x3b, 0xe3,
-	0xb3, 0x49, 0x6f, 0x86, 0x0f, 0xa3, 0x89, 0x8f, 0x18, 0x8c, 0xc0, 0x2b, 0x59, 0x16, 0x5c, 0x7e,
-	0x94, 0x7c, 0xd2, 0x7f, 0xba, 0x35, 0x2d, 0x62, 0x7c, 0x09, 0x2e, 0xe5, 0x89, 0x59, 0xd0, 0x3e,
-	0x61, 0x41, 0xa7, 0x22, 0x45, 0x8c, 0x57, 0x00, 0x5b, 0x9d, 0x48, 0xa6, 0xa4, 0xda, 0x70, 0x7e,
-	0x4a, 0x24, 0x96, 0x17, 0x71, 0x65, 0xdb, 0x26, 0x5c, 0xfa, 0xee, 0x03, 0xdb, 0xf6, 0x5c, 0xf1,
-	0x11, 0x83, 0x97, 0x00, 0xab, 0x82, 0x8c, 0xe8, 0x72, 0xe7, 0x77, 0x4d, 0x7c, 0x9e, 0x9d, 0xcc,
-	0x77, 0x77, 0xef, 0xe7, 0xdd, 0xbb, 0x5f, 0xf0, 0x55, 0x40, 0xef, 0x9a, 0xca, 0x4d, 0x93, 0xff,
-	0x73, 0x70, 0xad, 0x8e, 0x39, 0xc2, 0x7d, 0x5d, 0x0b, 0x8a, 0x1b, 0x48, 0xe5, 0x95, 0x3e, 0xeb,
-	0xb4, 0x20, 0x93, 0x96, 0x73, 0x8a, 0x57, 0xcb, 0x8b, 0x78, 0xde, 0xdf, 0xff, 0x19, 0xb6, 0xf6,
-	0x87, 0xa1, 0xf8, 0x71, 0x18, 0x8a, 0xdf, 0x87, 0xa1, 0x58, 0x76, 0x0c, 0xf5, 0xc5, 0xbf, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0xde, 0x36, 0xea, 0xdd, 0x71, 0x03, 0x00, 0x00,
+	// 726 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0xcf, 0x6e, 0xd3, 0x4e,
+	0x10, 0x8e, 0x9d, 0x3f, 0xb6, 0x27, 0x4d, 0xe5, 0xdf, 0xfe, 0x2a, 0x30, 0x45, 0x4d, 0x2a, 0x9f,
+	0x2a, 0xfe, 0x38, 0x22, 0x9c, 0x11, 0x72, 0x12, 0xab, 0x20, 0xb5, 0x55, 0x70, 0x52, 0xa9, 0xe2,
+	0x12, 0x39, 0xf1, 0x92, 0x58, 0x6a, 0xbc, 0xd6, 0x7a, 0x03, 0xa4, 0x42, 0x82, 0x47, 0xe0, 0xc8,
+	0x09, 0xf1, 0x38, 0x3d, 0xf2, 0x04, 0x50, 0xfa, 0x24, 0xc8, 0xbb, 0xeb, 0x90, 0x50, 0xf5, 0x10,
+	0x6e, 0xf3, 0xcd, 0x7e, 0x33, 0xb3, 0xf3, 0xcd, 0xee, 0x40, 0x2d, 0x8d, 0xce, 0x71, 0x3c, 0xc6,
+	0x4e, 0x42, 0x09, 0x23, 0xc8, 0x90, 0x30, 0x19, 0xed, 0x36, 0x26, 0x84, 0x4c, 0xce, 0x71, 0x93,
+	0x1f, 0x8c, 0xe6, 0x6f, 0x9a, 0x2c, 0x9a, 0xe1, 0x94, 0x05, 0xb3, 0x44, 0x70, 0x77, 0xeb, 0x7f,
+	0x13, 0xc2, 0x39, 0x0d, 0x58, 0x44, 0x62, 0x79, 0xbe, 0x33, 0x21, 0x13, 0xc2, 0xcd, 0x66, 0x66,
+	0x09, 0xaf, 0x7d, 0xa5, 0x80, 0x76, 0x1c, 0xb0, 0xf1, 0x14, 0x53, 0xf4, 0x10, 0x4a, 0x6c, 0x91,
+	0x60, 0x4b, 0xd9, 0x57, 0x0e, 0xb6, 0x5b, 0x77, 0x9d, 0x65, 0x71, 0x47, 0x32, 0x9c, 0xc1, 0x22,
+	0xc1, 0x3e, 0x27, 0x21, 0x04, 0xa5, 0x38, 0x98, 0x61, 0x4b, 0xdd, 0x57, 0x0e, 0x0c, 0x9f, 0xdb,
+	0xc8, 0x02, 0x2d, 0x09, 0x18, 0xc3, 0x34, 0xb6, 0x8a, 0xdc, 0x9d, 0x43, 0xfb, 0x02, 0x4a, 0x59,
+	0x2c, 0x32, 0xa0, 0xec, 0xbd, 0x3a, 0x75, 0x8f, 0xcc, 0x02, 0x02, 0xa8, 0xf8, 0xde, 0xa1, 0x77,
+	0xd6, 0x33, 0x95, 0xcc, 0x76, 0xdb, 0x7d, 0xef, 0x64, 0x60, 0xaa, 0xa8, 0x0a, 0x5a, 0xcf, 0xf7,
+	0x38, 0x28, 0xa2, 0x1a, 0x18, 0x47, 0x5e, 0xbf, 0x3f, 0x1c, 0xbc, 0x70, 0x4f, 0xcc, 0x12, 0xda,
+	0x06, 0xe0, 0x50, 0xe4, 0x28, 0x23, 0x13, 0xb6, 0x0e, 0x7d, 0xcf, 0x1d, 0x78, 0xbe, 0x60, 0x54,
+	0xd0, 0x7f, 0x50, 0xcb, 0x3d, 0x82, 0xa4, 0xd9, 0x1f, 0x41, 0xeb, 0x90, 0xd9, 0x0c, 0xc7, 0x0c,
+	0xdd, 0x81, 0x4a, 0x30, 0x67, 0x53, 0x42, 0x79, 0x8f, 0x86, 0x2f, 0x51, 0x76, 0xf1, 0xb1, 0xa0,
+	0xc8, 0x7e, 0x72, 0x88, 0xda, 0x60, 0x2c, 0x85, 0xe6, 0x4d, 0x55, 0x5b, 0xbb, 0x8e, 0x50, 0xda,
+	0xc9, 0x95, 0x76, 0x06, 0x39, 0xa3, 0xad, 0x5f, 0xfe, 0x68, 0x14, 0x3e, 0xff, 0x6c, 0x28, 0xfe,
+	0x9f, 0x30, 0xfb, 0x5b, 0x09, 0xb4, 0xbe, 0xd0, 0x12, 0x6d, 0x83, 0x1a, 0x85, 0xb2, 0xba, 0x1a,
+	0x85, 0xc8, 0x01, 0x7d, 0x26, 0xc4, 0x4d, 0x2d, 0x75, 0xbf, 0x78, 0x50, 0x6d, 0xa1, 0x9b, 0xba,
+	0xfb, 0x4b, 0x0e, 0x72, 0xc1, 0x48, 0x59, 0x40, 0x59, 0x3a, 0x0c, 0xd8, 0x46, 0xf7, 0xd1, 0x45,
+	0x98, 0xcb, 0xd0, 0x33, 0xd0, 0x70, 0x1c, 0xf2, 0x04, 0xa5, 0x0d, 0x12, 0x54, 0xb2, 0x20, 0x97,
+	0xa1, 0x0e, 0xc0, 0x3c, 0x09, 0x03, 0x86, 0xc3, 0x2c, 0x43, 0x79, 0x13, 0x49, 0x64, 0x9c, 0xcb,
+	0xb2, 0xb6, 0xa5, 0xc2, 0xa9, 0xa5, 0xdd, 0x68, 0x5b, 0x8e, 0xcb, 0x5f, 0x72, 0xd0, 0x1e, 0xc0,
+	0x98, 0x62, 0x5e, 0x74, 0xb4, 0xb0, 0x74, 0x2e, 0x9f, 0x21, 0x3d, 0xed, 0xc5, 0xea, 0xfc, 0x8c,
+	0xf5, 0xf9, 0x35, 0x41, 0x4f, 0xc7, 0x53, 0x1c, 0xce, 0xcf, 0xb1, 0x05, 0xfc, 0xae, 0xff, 0xaf,
+	0x14, 0xea, 0xcb, 0x23, 0x7f, 0x49, 0x42, 0x2d, 0xd0, 0xa6, 0x51, 0xca, 0x08, 0x5d, 0x58, 0x55,
+	0x7e, 0x31, 0x6b, 0x95, 0x2f, 0xac, 0xce, 0x34, 0x88, 0x27, 0xd8, 0xcf, 0x89, 0xe8, 0x01, 0x54,
+	0xd8, 0x94, 0xe2, 0x20, 0xb4, 0xb6, 0x6e, 0xed, 0x45, 0x32, 0xd0, 0x0e, 0x94, 0xc9, 0xbb, 0x18,
+	0x53, 0xab, 0xc6, 0x2f, 0x2a, 0x80, 0xfd, 0x49, 0x81, 0xea, 0x31, 0x4e, 0xa7, 0xf9, 0x33, 0x79,
+	0x04, 0x9a, 0x4c, 0xc1, 0xdf, 0xca, 0x7a, 0x4a, 0x49, 0xf2, 0x73, 0x4a, 0x36, 0x12, 0xfc, 0x3e,
+	0x89, 0x28, 0xe6, 0x43, 0x55, 0x37, 0x19, 0x89, 0x8c, 0x73, 0x99, 0xfd, 0x01, 0xf4, 0x5c, 0x8e,
+	0xec, 0x73, 0x8f, 0x29, 0x89, 0xe5, 0x3b, 0xe5, 0x36, 0x7a, 0x0e, 0x7a, 0xbe, 0x51, 0x64, 0x89,
+	0x7b, 0x37, 0x4a, 0x74, 0x25, 0x41, 0x54, 0xf8, 0xc2, 0xdf, 0x5d, 0x1e, 0x84, 0xee, 0x8b, 0xaf,
+	0x34, 0xbc, 0x20, 0x31, 0x96, 0xfb, 0x41, 0xcf, 0x1c, 0xaf, 0x49, 0x8c, 0xed, 0xaf, 0x2a, 0xd4,
+	0xd6, 0xd4, 0x45, 0x4f, 0xd6, 0xb6, 0xd1, 0xde, 0x6d, 0x53, 0x58, 0xdd, 0x49, 0x6b, 0x9f, 0x55,
+	0xfd, 0xa7, 0xcf, 0xba, 0xb2, 0x22, 0x8a, 0x6b, 0x2b, 0xa2, 0x01, 0x55, 0x8a, 0x67, 0x84, 0xe1,
+	0x61, 0x10, 0x86, 0x94, 0xff, 0x1c, 0xc3, 0x07, 0xe1, 0x72, 0xc3, 0x90, 0x66, 0x4f, 0x3a, 0xa1,
+	0xf8, 0x6d, 0x44, 0xe6, 0xa9, 0x55, 0xbe, 0x75, 0x66, 0x4b, 0x8e, 0xfd, 0x58, 0xae, 0xc4, 0x2a,
+	0x68, 0x1d, 0xbe, 0xb1, 0xba, 0x66, 0x21, 0x03, 0xa7, 0xbd, 0x2e, 0x07, 0x4a, 0x06, 0xbc, 0xb3,
+	0xde, 0x4b, 0xdf, 0xeb, 0x9a, 0x6a, 0xdb, 0xbc, 0xfc, 0x55, 0x2f, 0x5c, 0x5e, 0xd7, 0x95, 0xef,
+	0xd7, 0x75, 0xe5, 0xea, 0xba, 0xae, 0x8c, 0x2a, 0xbc, 0xa5, 0xa7, 0xbf, 0x07, 0x00, 0x76, 0xc7,
+	0xf8, 0x55, 0x34, 0x06, 0x00, 0x00,
 }
@@ -68,7 +68,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _templateDefaultTmpl = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x1b\xff\x4f\xdb\xb8\xfe\xf7\xfc\x15\x9f\xcb\xe9\xe9\x86\xd4\x36\x85\xdd\x4d\x47\x69\x79\xea\x4a\x18\xd1\x2b\x29\x4a\xc3\x76\xd3\xe9\x84\xdc\xc4\x6d\xbd\x25\x76\xce\x76\x28\x3d\xd6\xff\xfd\xc9\x4e\xfa\x25\x34\x85\x82\x76\xd0\xf7\x0e\xd0\x46\xe2\xf8\xf3\xfd\xab\x63\xe7\xf6\x16\x42\x3c\x24\x14\x83\x79\x75\x85\x22\xcc\x65\x8c\x28\x1a\x61\x6e\xc2\x6c\xd6\x56\xf7\xe7\xd9\xfd\xed\x2d\x60\x1a\xc2\x6c\x66\x6c\x04\xb9\xf4\xba\x0a\xea\xf6\x16\x6a\xf6\x8d\xc4\x9c\xa2\xe8\xd2\xeb\xc2\x6c\x66\xfd\x68\xe9\x79\xe2\xdf\x1c\x07\x98\x5c\x63\xde\x52\x93\xbc\xfc\x26\x83\xc9\xb1\x17\xd1\x8b\x74\xf0\x05\x07\x52\xa1\xfd\x5d\x81\xf4\x25\x92\xa9\x80\x6f\x20\xd9\x65\x92\xcc\x41\xc9\x10\xf0\x9f\x8b\x87\xe6\x90\x70\x42\x47\x0a\xa6\xa1\x60\xb4\x14\xa2\x76\xaa\x47\xe1\x1b\x44\x98\xae\x52\xfc\x03\xd4\xa4\x0f\x9c\xa5\x49\x17\x0d\x70\x24\x6a\x7d\xc6\x25\x0e\x2f\x10\xe1\xa2\xf6\x11\x45\x29\x56\x04\xbf\x30\x42\xc1\x04\x85\x15\x32\x92\x23\x09\x6f\x14\xae\x5a\x87\xc5\x31\xa3\x19\xf0\x5e\x3e\xb6\x82\x6f\x0f\x66\xb3\x37\xb7\xb7\x30\x21\x72\x5c\x9c\x5c\xf3\x70\xcc\xae\x71\x91\xba\x8b\x62\x2c\x72\x35\x96\x51\x5f\x30\xbe\xb7\xb8\xda\x60\x9b\x10\x8b\x80\x93\x44\x12\x46\xcd\x7b\x74\x2c\xf1\x8d\xcc\xec\x78\x15\x11\x21\xf3\xa9\x1c\xd1\x11\x86\x1a\xcc\x66\x19\x5f\x0d\x63\x39\xb8\xae\x27\xa5\x95\xaa\x56\xa4\x62\x5f\xdd\xb5\x60\x21\x40\xce\x58\x46\xbc\x4d\x29\x93\x48\xf1\x54\x40\xb9\x32\xfc\x34\xbc\x7d\x96\xf2\x00\x37\x32\x63\x62\x8a\x39\x92\x8c\x67\xee\x67\x94\x28\xaa\xa0\x03\x11\xa1\xe0\x6b\x2d\xc4\x43\x94\x46\xb2\x26\x89\x8c\x70\xae\x05\x89\xe3\x24\x42\xb2\xe8\x8b\xb5\x4d\x2a\x2f\xe2\x49\x85\x0a\x81\xb8\x0c\x55\x31\xd0\xb6\xc4\x37\x44\x51\x34\x40\xc1\xd7\x35\x7c\xa5\xec\x2b\xa4\xf0\x0d\x1e\x9a\x18\x11\xfa\x75\x6b\x0e\x12\x8e\x95\xb3\x98\xdb\xcd\x5e\xc1\x7f\xaf\x02\x74\xda\xd8\x92\x03\x12\x30\x8a\x63\xf6\x85\x6c\xc9\x83\x9a\x9f\xf2\x68\x5b\x8e\xb7\x17\x6e\xc8\x98\xcc\x92\xe4\x06\x9f\x1a\x93\x24\x18\x23\xb9\x04\xe0\x2c\x7e\xba\x27\xdc\xc5\x16\x63\x21\xd0\xe8\x11\x5e\x5a\xe0\x2d\x51\xd4\xc2\x54\x4e\x17\xf8\xd6\x53\xc5\xe3\x3c\x7f\x1d\x63\x10\x11\x4c\xe5\xd3\x25\xde\x84\x71\x59\x64\x9e\xe6\x4f\xeb\x78\x09\x15\x12\xd1\x00\x8b\x12\xbc\x6b\xb9\xf1\x1e\xad\xb2\x44\x8c\x30\x25\xf8\xe9\x46\xba\x0f\xd9\xba\x85\xf2\x52\xb2\x21\x73\x96\xd6\x0e\xe3\x4e\xe5\x2a\x94\xc6\x3d\xa8\x43\x75\x36\x33\xb2\x41\xc8\x06\x75\x8e\xbe\x5f\x23\xc5\xfa\xaa\x89\x54\x57\x24\x2a\xa1\xe7\x61\xc1\xa2\x6b\x1c\xde\xa1\x38\x1f\xde\x9e\xe6\x1c\x62\x8d\x6a\x75\x1b\x95\x0a\x5d\x32\x1e\xef\x4d\x05\xab\x4f\xf0\x53\x02\xd3\x78\xb5\xdf\x3d\xf6\x6b\xaf\xea\x9f\x47\x6b\xf8\x4a\xed\xb3\x8a\xa0\x68\xa2\x6b\x12\x48\xc6\x59\x22\x96\x96\x97\x48\xe2\xab\xa2\xad\x5e\xcd\xf1\xb8\x70\x5a\xd7\x2a\xa6\x92\xc8\xe9\x55\x48\x44\x12\xa1\xe9\xd5\x86\xde\xe7\xe1\xdc\xb7\x8e\x39\x66\x94\x48\xa6\x14\x72\x25\x19\x8b\x1e\x59\x55\x56\x71\xe3\x18\x91\x68\xe9\x07\xcb\xe5\xc5\xa3\xb9\x2c\x62\x1a\xcb\x58\xb3\x65\x34\x7f\x38\xe9\x75\xfc\xcf\x17\x36\xa8\x21\xb8\xb8\x7c\xdf\x75\x3a\x60\x56\x2d\xeb\xd3\xdb\x8e\x65\x9d\xf8\x27\xf0\xdb\x99\x7f\xde\x85\xfd\x5a\x1d\x7c\x8e\xa8\x20\xca\xd9\x50\x64\x59\xb6\x6b\x82\x39\x96\x32\x69\x58\xd6\x64\x32\xa9\x4d\xde\xd6\x18\x1f\x59\xbe\x67\xdd\x28\x5c\xfb\x0a\x38\xbf\xac\xca\x15\xc8\x5a\x28\x43\xf3\xd8\x68\xfe\x50\xad\x1a\x7d\x39\x8d\x30\x20\x1a\x82\x26\x12\x62\x4e\x94\x41\x55\xf7\x01\x0a\xb5\x68\x58\xd6\x88\xc8\x71\x3a\xa8\x05\x2c\xb6\x94\x0c\xa3\x94\x5a\x1a\x1d\x0a\x32\x7c\x55\x2d\x5a\x75\xae\x0e\x61\x18\x86\x3f\xc6\x70\xee\xf8\xd0\x25\x01\xa6\x02\xc3\x9b\x73\xc7\xdf\x33\x8c\x0e\x4b\xa6\x9c\x8c\xc6\x12\xde\x04\x7b\x70\x50\xdf\xff\x19\xce\x33\x8c\x86\x71\x81\x79\x4c\x84\x20\x8c\x02\x11\x30\xc6\x1c\x0f\xa6\x30\xe2\x88\x4a\x1c\x56\x60\xc8\x31\x06\x36\x84\x60\x8c\xf8\x08\x57\x40\x32\x40\x74\x0a\x09\xe6\x82\x51\x60\x03\x89\x08\x55\xfe\x8f\x20\x60\xc9\xd4\x60\x43\x90\x63\x22\x40\xb0\xa1\x9c\x20\x9e\x49\x88\x84\x60\x01\x41\x12\x87\x10\xb2\x20\x8d\x31\xcd\x02\x17\x86\x24\xc2\x02\xde\xc8\x31\x06\xb3\x9f\x43\x98\x7b\x9a\x48\x88\x51\x64\x10\x0a\xea\xd9\xfc\x91\x5e\x99\xb1\x54\x02\xc7\x42\x72\xa2\xb5\x50\x01\x42\x83\x28\x0d\x15\x0f\xf3\xc7\x11\x89\x49\x4e\x41\x81\x6b\xc1\x85\x21\x19\xa4\x02\x57\x34\x9f\x15\x88\x59\x48\x86\xea\x2f\xd6\x62\x25\xe9\x20\x22\x62\x5c\x81\x90\x28\xd4\x83\x54\xe2\x0a\x08\x35\xa8\xf5\x58\x51\x72\x58\x8c\x83\xc0\x51\x64\x04\x2c\x21\x58\x80\x96\x75\xc9\x9d\x9e\xa3\x58\x4f\x94\x42\x65\xae\x22\xa1\x46\x26\x63\x16\x17\x25\x21\xc2\x18\xa6\x9c\x12\x31\xc6\x1a\x26\x64\x20\x98\xa6\xa8\xbc\x59\x8d\xa8\xe9\x43\x16\x45\x6c\xa2\x44\x0b\x18\x0d\x49\xbe\x18\xd3\x46\x46\x03\xb5\x20\x0d\x16\x76\xa5\x4c\x92\x20\x53\xb7\x36\x40\xb2\xb4\x6a\xfe\x48\x8c\x51\x14\xc1\x00\xe7\x0a\xc3\x21\x10\x0a\x68\x45\x1c\xae\xc8\xab\x16\x4b\x12\x14\x41\xc2\xb8\xa6\x77\x57\xcc\x9a\x61\xf8\x67\x36\xf4\x7b\xa7\xfe\xa7\xb6\x67\x83\xd3\x87\x0b\xaf\xf7\xd1\x39\xb1\x4f\xc0\x6c\xf7\xc1\xe9\x9b\x15\xf8\xe4\xf8\x67\xbd\x4b\x1f\x3e\xb5\x3d\xaf\xed\xfa\x9f\xa1\x77\x0a\x6d\xf7\x33\xfc\xc7\x71\x4f\x2a\x60\xff\x76\xe1\xd9\xfd\x3e\xf4\x3c\xc3\x39\xbf\xe8\x3a\xf6\x49\x05\x1c\xb7\xd3\xbd\x3c\x71\xdc\x0f\xf0\xfe\xd2\x07\xb7\xe7\x43\xd7\x39\x77\x7c\xfb\x04\xfc\x1e\x28\x82\x39\x2a\xc7\xee\x2b\x64\xe7\xb6\xd7\x39\x6b\xbb\x7e\xfb\xbd\xd3\x75\xfc\xcf\x15\xe3\xd4\xf1\x5d\x85\xf3\xb4\xe7\x41\x1b\x2e\xda\x9e\xef\x74\x2e\xbb\x6d\x0f\x2e\x2e\xbd\x8b\x5e\xdf\x86\xb6\x7b\x02\x6e\xcf\x75\xdc\x53\xcf\x71\x3f\xd8\xe7\xb6\xeb\xd7\xc0\x71\xc1\xed\x81\xfd\xd1\x76\x7d\xe8\x9f\xb5\xbb\x5d\x45\xca\x68\x5f\xfa\x67\x3d\x4f\xf1\x07\x9d\xde\xc5\x67\xcf\xf9\x70\xe6\xc3\x59\xaf\x7b\x62\x7b\x7d\x78\x6f\x43\xd7\x69\xbf\xef\xda\x19\x29\xf7\x33\x74\xba\x6d\xe7\xbc\x02\x27\xed\xf3\xf6\x07\x5b\x43\xf5\xfc\x33\xdb\x33\xd4\xb4\x8c\x3b\xf8\x74\x66\xab\x21\x45\xaf\xed\x42\xbb\xe3\x3b\x3d\x57\x89\xd1\xe9\xb9\xbe\xd7\xee\xf8\x15\xf0\x7b\x9e\xbf\x00\xfd\xe4\xf4\xed\x0a\xb4\x3d\xa7\xaf\x14\x72\xea\xf5\xce\x2b\x86\x52\x67\xef\x54\x4d\x71\x5c\x05\xe7\xda\x19\x16\xa5\x6a\x28\x58\xa4\xe7\xe9\xfb\xcb\xbe\xbd\x40\x08\x27\x76\xbb\xeb\xb8\x1f\xfa\x0a\x58\x89\x38\x9f\x5c\x33\xaa\xd5\x63\xa3\xa9\x53\xe0\x4d\x1c\x51\xd1\x2a\x49\x6c\xfb\x87\x87\x87\x59\x3e\x33\xb7\x9b\x24\x54\x72\x6b\x99\x43\x46\x65\x75\x88\x62\x12\x4d\x1b\xf0\xd3\x19\x8e\xae\xb1\x24\x01\x02\x17\xa7\xf8\xa7\x0a\x2c\x06\x2a\xd0\xe6\x04\x45\x15\x10\x88\x8a\xaa\xc0\x9c\x0c\x8f\x60\xc0\x6e\xaa\x82\xfc\xa5\x6a\x31\x0c\x18\x0f\x31\xaf\x0e\xd8\xcd\x11\x68\xa4\x82\xfc\x85\x1b\xb0\xff\x73\x72\x73\x04\x31\xe2\x23\x42\x1b\x50\x3f\x52\xb9\x75\x8c\x51\xf8\x92\xf4\x63\x2c\x11\xa8\x8a\xda\x32\xaf\x09\x9e\xa8\x28\x32\x55\xf4\x4a\x4c\x65\xcb\x9c\x90\x50\x8e\x5b\x21\xbe\x26\x01\xae\xea\x9b\x97\x53\x16\x58\x73\x76\x95\x31\xab\xf8\xcf\x94\x5c\xb7\xcc\x4e\xc6\x6a\xd5\x9f\x26\x78\x85\x71\xd5\x8a\x58\xca\xb8\x47\xba\x12\x08\x2c\x5b\x97\xfe\x69\xf5\xd7\x17\x66\x5f\xbf\xba\x78\x39\x73\xdf\xd7\x8b\x34\x2d\xcd\xdc\xb1\x61\x34\x2d\xe5\x94\xea\x62\xc0\xc2\x29\x10\x89\x63\x11\xb0\x04\xb7\x4c\x53\xdf\xc8\xa9\xba\xce\x23\x4a\x04\x63\x1c\x23\x1d\x51\xb6\xaa\xee\xe7\xf3\xde\xf7\x59\x85\xac\x4e\xf0\xe0\x2b\x91\xd5\xec\x41\xcc\x98\x1c\x6b\xa0\xac\x36\x10\x24\x70\xb8\x9c\xa4\x7c\x43\x43\x57\x51\xf8\x25\x15\xb2\x01\x94\x51\x7c\x04\x63\xac\x2a\x53\x03\xf6\xeb\xf5\x7f\x1d\x41\x44\x28\xae\x2e\x86\x6a\xef\x70\x7c\x04\x3a\x02\xb2\x09\xf0\x03\x89\x55\xb0\x20\x2a\x8f\x60\x80\x82\xaf\x23\xce\x52\x1a\x56\x03\x16\x31\xde\x80\x1f\x87\xef\xd4\xef\xaa\xfa\x21\x41\x61\xa8\xb9\x52\xde\x30\x18\xe9\x99\x2d\x33\x9f\x69\x2a\x7d\x4b\x34\x78\x6e\xf7\x58\x11\x69\x4b\x39\x4a\x79\x07\x68\x4a\xfe\x82\x79\x0c\x40\x71\xf0\xcc\x99\xf4\x1a\x73\x85\x24\xaa\xa2\x88\x8c\x68\x03\x24\x4b\x8a\x8a\xba\xd6\x0f\x5a\xa6\x64\x89\x79\xdc\xb4\x64\xb8\x64\x34\xcb\xac\xe6\xbb\x7a\xfd\x99\x43\xa5\x94\xe9\x7c\x69\xd5\x80\x41\xc4\x82\xaf\x05\xdf\x8e\xd1\x4d\x35\x77\x92\x77\xf5\x7a\x72\x53\x78\x18\x44\x18\x71\x45\x50\x8e\x0b\xe3\x9b\x02\x65\xa1\x1c\x40\xa9\x64\x77\x42\xa2\xa0\x2d\xad\x28\x80\x66\x48\xae\x9f\xdb\xad\x8a\xf2\xde\x55\xce\xfd\x42\xcc\xf9\x56\x46\xd6\xc1\x9c\xdb\x59\x69\xc2\x84\x00\x47\x51\x3e\xbb\x65\xd6\xb3\x7b\x91\xa0\x60\x7e\xff\xac\x82\xe6\x0f\x39\x0a\x49\x2a\x1a\xf0\x56\x8f\x95\x24\x80\xe1\xb0\x90\xc5\x32\xb0\x06\xec\x27\x37\x20\x58\x44\x42\xf8\x11\x1f\xaa\xdf\x62\x62\x18\x0e\x57\x74\xb1\x0b\xd9\x61\xc9\xc9\xf3\x65\x89\x77\x1b\x03\xae\xa0\x5d\x0d\x32\xc9\x4b\xcd\x2f\xf5\xfa\x11\xe8\x12\x95\xcf\x0f\x30\x95\x98\x97\xd9\x4b\xff\xab\x6b\xa3\xac\xdb\xcd\x7e\xf7\xcb\xc1\x41\xa7\xbc\x00\x1d\x28\xbf\x36\x21\x8f\xb7\x8c\xc0\xaa\xf5\x32\xd8\xf2\x88\x9c\xff\x2c\x77\x40\x17\x5b\x9f\xa0\x5f\x96\x94\xbe\x4b\xda\x83\x7d\x98\xcd\xc4\xe2\x85\x07\x0c\x19\x87\xe5\x2e\xdd\x86\x5d\x52\x98\xcd\xee\x50\x85\xd5\x3d\xbb\x56\x61\xc7\x6e\x6d\x5a\xfe\x6a\xa5\x60\xfc\x45\x0e\x5e\xdc\xf3\x57\x37\xdd\xa6\x98\x2d\x9d\x67\x3f\x73\x9e\xfb\x7c\x63\xe7\x73\xdf\x46\xb5\xef\x96\x13\xec\xba\x2b\xd4\xa1\x3e\xcf\x25\xf7\xb9\x43\x2e\x06\x82\x31\xc7\xc3\x96\xb9\xcd\x4b\xf7\x67\xf6\x87\x79\xd2\x3c\x3d\x3d\xcd\x93\x6f\x88\x03\xc6\xf5\x3b\xb9\xf9\xf2\xa0\xb0\x20\x38\x50\xcb\x81\x42\xde\x1e\xb0\x28\x2c\x4f\xdc\x41\xca\x85\xc2\x9e\x30\x92\x0d\x2c\x1a\x0a\x42\x35\xd2\xbc\xaf\xb8\x93\xe0\x7f\x51\x8c\x69\x7c\xfa\x25\xea\x90\xf1\xb8\x01\x01\x4a\x88\x44\x11\xf9\x0b\x97\x26\xfd\xb7\x3f\xff\x8a\x43\x54\x52\xaf\xd7\x66\xe4\xc3\x5a\xcb\x8d\xac\x90\x2f\x06\x17\xdd\x5b\x72\x93\x9b\xf7\xf8\x23\xc1\x13\x20\x14\x1e\x7c\x3b\xde\xb4\x50\xa9\x0f\xdf\x49\xbc\xe5\xe9\x37\xfb\x79\x68\xf3\xa3\xa4\x28\xbc\x86\xec\xdf\x13\xb2\x42\x72\x46\x47\x2f\xa7\xda\xdf\x37\x9f\xb3\xfa\x23\xdf\xf9\x6a\x5a\x19\x93\xdf\xc1\xeb\x4a\x1a\x86\xfc\xc9\xfc\x30\xd1\xdd\x2d\xb4\x57\x3f\xfc\x67\xf8\x61\xd6\x9a\x2e\x5c\xad\x39\x78\x39\x33\x83\x55\xae\xa3\x07\x4e\xd1\x6d\x3e\xea\xf6\xc2\xc2\x6c\x8e\x3b\x28\xa9\x05\xcb\x4d\xf4\xac\x12\xbc\xb8\x67\xac\x70\xb4\x2b\xee\xf1\xa0\x46\x1f\x3c\x1a\xf9\x3f\xea\x2c\xab\x1d\xe6\xdd\xb3\x9a\x2f\xd4\x50\xce\xdb\xad\xb5\x9e\x32\xa5\x21\xe6\xaa\xfb\x2b\xba\x53\x76\xda\x54\x35\x51\xbb\x97\x63\x9e\x56\x4d\xb7\x6c\xef\x56\xcf\x9a\x94\x9a\xf7\xb5\x2b\xdc\x99\x6a\xbc\x73\x9e\x09\xd0\x1c\xef\x20\x4f\x3b\xa7\xa7\xc7\x44\xf0\x7d\x1d\xf1\x6b\x60\xfd\x7f\xb6\xb9\xab\xcb\xad\xc5\x99\xbd\xe5\x82\x6b\x3e\xf4\x02\x4b\xae\xd5\x13\x84\xaf\xde\xf8\xcf\xf0\xc6\xd7\x45\xd7\xeb\xa2\xeb\x75\xd1\xb5\xeb\xce\xf2\xba\xe8\xda\x99\x96\x6d\x93\xa1\x9a\x96\xde\x8f\x3b\x7e\xc4\x56\xe8\x02\x64\x39\xf2\xec\x27\x31\x0a\x47\x93\x56\x4e\x9a\x2c\x0d\x7d\x78\x78\x78\xdf\x06\x77\x71\x67\x77\x7d\x4b\x72\x37\x9a\x86\x5d\x6a\x5f\x9e\xb3\x75\x39\xd8\xd8\xba\x94\x6e\xa2\x3d\x64\xf2\x95\xde\xe6\xce\xb9\x86\xe2\x29\xac\xd5\x74\x55\xfc\x9a\xfc\xf9\x1c\xe2\x60\x35\x5b\x69\x89\xb6\x4e\x55\x98\x4a\x18\x4c\xb7\xdb\x87\x5b\xcf\x1d\x6b\xe7\x1d\xee\x66\x86\xa6\x15\x92\xeb\xe3\xec\x7f\xa3\x98\x26\x76\xad\xad\xdd\x70\xbc\x2e\x13\x71\x99\xbf\x9a\xd6\x80\x85\x53\x35\x32\x96\x71\x74\x6c\x18\xe5\xdf\xef\x24\xa9\x18\xb3\x6b\xcc\xbf\xc3\xd7\xda\x6b\xa8\xfe\xfe\xef\xc1\xbe\xcf\xe7\x60\xdb\x7f\x0d\xf6\xfd\x3e\x06\x5b\xa1\xb9\x85\x26\x97\x9f\x5c\x3f\xe2\xb3\xca\xff\x06\x00\x00\xff\xff\x96\x70\x0b\x9a\x8b\x42\x00\x00")
+var _templateDefaultTmpl = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x5c\x7b\x73\xda\x46\xd7\xff\x5f\x9f\xe2\x54\x9d\x4e\xe3\x19\x6e\x76\xda\x4c\x83\x8d\xdf\x21\x18\xc7\xcc\x8b\xc1\x03\x72\xd2\x4c\xa7\xe3\x59\xa4\x03\x6c\x22\xed\xaa\xbb\x2b\x30\x75\xf9\xee\xcf\xac\x6e\x48\x20\x30\x4e\xf3\xd8\x7e\x5a\x9b\x49\x06\xad\x76\x7f\xe7\xba\x67\xcf\xde\xb8\xbb\x03\x07\xc7\x94\x21\x98\x37\x37\xc4\x45\xa1\x3c\xc2\xc8\x04\x85\x09\xcb\x65\x53\x3f\x5f\x46\xcf\x77\x77\x80\xcc\x81\xe5\xd2\xd8\xda\xe4\x7a\xd0\xd5\xad\xee\xee\xa0\xd2\xbe\x55\x28\x18\x71\xaf\x07\x5d\x58\x2e\xab\xdf\x57\x43\x68\xf9\x7f\x02\x6d\xa4\x33\x14\x0d\x5d\x69\x10\x3f\x44\x6d\x62\xf4\x3c\xbc\x0c\x46\x9f\xd1\x56\x1a\xf6\x37\xdd\x64\xa8\x88\x0a\x24\xfc\x05\x8a\x5f\xfb\x7e\xd2\x94\x8e\x01\xff\x48\x5f\x9a\x63\x2a\x28\x9b\xe8\x36\x75\xdd\x26\x94\x42\x56\xce\xc3\x52\xf8\x0b\x5c\x64\x59\x8a\xbf\x83\xae\xf4\x5e\xf0\xc0\xef\x92\x11\xba\xb2\x32\xe4\x42\xa1\x73\x45\xa8\x90\x95\x0f\xc4\x0d\x50\x13\xfc\xcc\x29\x03\x13\x34\xaa\x6e\x40\xc7\x30\x51\xf0\x4a\x63\x55\x5a\xdc\xf3\x38\x8b\x1a\x1f\xc4\x65\x19\xbc\x03\x58\x2e\x5f\xdd\xdd\xc1\x9c\xaa\x69\xbe\x72\x65\x80\x1e\x9f\x61\x9e\x7a\x8f\x78\x28\x63\x35\x16\x51\x4f\x19\x3f\x48\xbf\x6d\xb1\x8d\x83\xd2\x16\xd4\x57\x94\xb3\x5c\x43\x23\x5f\x4d\xe1\xad\x8a\xec\x78\xe3\x52\xa9\xe2\xaa\x82\xb0\x09\x42\x05\x96\xcb\x88\xd7\xba\xb1\x2a\xdc\xd4\x93\xd6\x4a\x59\xeb\x25\x64\x5f\x3f\x35\x20\x15\x20\x66\x2c\x52\x77\x93\x31\xae\x88\xe6\x29\x07\x99\x29\xfe\x3a\xdc\x21\x0f\x84\x8d\xf5\x90\xea\x7b\x64\x28\x88\xe2\x22\x72\xbf\x55\xa5\xf4\x8b\x91\xd3\x81\x74\x89\xfd\xa5\xe2\xe0\x98\x04\xae\xaa\x28\xaa\x5c\x8c\xb5\xa0\xd0\xf3\x5d\xa2\xf2\xbe\x58\xc9\x21\x6d\xc5\x09\xa4\xee\x02\x5e\x11\x54\xbe\xa3\xed\x89\x37\x26\xae\x3b\x22\xf6\x97\x0d\xbc\x42\xf6\x35\x28\xfc\x05\xf7\x55\x74\x29\xfb\xb2\x37\x07\xbe\x40\xed\x2c\xe6\x7e\xb5\x33\xf8\x3b\x15\x10\x86\x8d\x3d\x39\xa0\x36\x67\xe8\xf1\xcf\x74\x4f\x1e\x74\xfd\x40\xb8\x7b\xd6\x7e\x80\x70\x63\xce\x15\x8a\x7c\xe5\x9c\x4f\x4d\xa9\x6f\x4f\x89\x5a\x35\x10\xdc\xbb\x47\x11\x3b\xb4\xb0\x8e\xe6\xa1\x94\x64\xf2\x00\x2f\xcd\xf1\xe6\x6b\xbf\x73\x02\xb5\x48\xf1\x36\x43\xc5\x1e\x98\x3b\x11\x6d\x97\x22\x53\x05\x60\x7b\x4a\xbc\x0d\x71\x35\xc8\x7c\x9d\x3f\x6d\xe2\x52\x26\x15\x61\x36\xca\x02\xdc\x8d\xd8\xb8\x43\xab\xdc\x97\x13\x64\x14\xbf\xde\x48\xbb\xc0\x36\x2d\x14\x0f\x25\x5b\x22\x67\xe1\xc8\x65\xac\x8d\x5c\xb9\xa1\xf1\x00\x6a\x50\x5e\x2e\x8d\xa8\x10\xa2\xf1\xb2\x6e\xac\xb1\xbe\xa9\x91\xfc\xf8\x1a\x6a\xbb\x9c\x91\xa8\x80\xde\x00\x25\x77\x67\xe8\xac\x51\x4c\x8a\xf7\xa7\x99\xb4\xd8\xa0\x5a\xde\x47\xa5\x32\x1c\x32\x1e\xee\x4d\x39\xab\xcf\xf1\x6b\x3a\xa6\xf1\x62\xbf\x1d\xf6\x6b\x66\xf5\x2f\xdc\xba\xb1\x8f\x7d\xb2\x00\x79\x13\xcd\xa8\xad\xb8\xe0\xbe\x5c\x59\x5e\x11\x85\x37\x79\x5b\xbd\x98\x63\x9b\x39\xf2\x0c\x6c\xd7\x2a\x32\x45\xd5\xe2\xc6\xa1\xd2\x77\xc9\xe2\x66\x4b\xee\x73\x7f\xec\xdb\x44\xf6\x38\xa3\x8a\x6b\xad\xde\x28\xce\xdd\x02\xd4\xac\x4b\x6c\xf4\xd7\x0c\x36\x7a\x84\xba\x29\x6e\xca\xcb\x57\x70\x99\x47\x9a\x2a\x2f\x64\xcb\x38\xf9\xee\xac\xdf\xb2\x3e\x5d\xb5\x41\x17\xc1\xd5\xf5\xbb\x6e\xa7\x05\x66\xb9\x5a\xfd\xf8\xba\x55\xad\x9e\x59\x67\xf0\xeb\x85\x75\xd9\x85\xc3\x4a\x0d\x2c\x41\x98\xa4\x3a\x76\x13\xb7\x5a\x6d\xf7\x4c\x30\xa7\x4a\xf9\xf5\x6a\x75\x3e\x9f\x57\xe6\xaf\x2b\x5c\x4c\xaa\xd6\xa0\x7a\xab\xb1\x0e\x75\xe3\xf8\x6b\x59\x65\x5a\x56\x1c\xe5\x98\xa7\xc6\xc9\x77\xe5\xb2\x31\x54\x0b\x17\x81\x30\x07\x42\x22\x0e\x0a\xaa\x0d\x3a\x16\xdc\x03\x0d\x2d\xeb\xd5\xea\x84\xaa\x69\x30\xaa\xd8\xdc\xab\x6a\x19\x26\x01\xab\x86\x70\xc4\x8e\x38\x29\x87\xa2\x95\x13\x75\x48\xc3\x30\xac\x29\xc2\x65\xc7\x82\x2e\xb5\x91\x49\x84\x57\x97\x1d\xeb\xc0\x30\x5a\xdc\x5f\x08\x3a\x99\x2a\x78\x65\x1f\xc0\x51\xed\xf0\x27\xb8\x8c\x10\x0d\xe3\x0a\x85\x47\xa5\xa4\x9c\x01\x95\x30\x45\x81\xa3\x05\x4c\x04\x61\x0a\x9d\x12\x8c\x05\x22\xf0\x31\xd8\x53\x22\x26\x58\x02\xc5\x81\xb0\x05\xf8\x28\x24\x67\xc0\x47\x8a\x50\xa6\xfd\x9f\x80\xcd\xfd\x85\xc1\xc7\xa0\xa6\x54\x82\xe4\x63\x35\x27\x22\x92\x90\x48\xc9\x6d\x4a\x14\x3a\xe0\x70\x3b\xf0\x90\x45\xe3\x20\x8c\xa9\x8b\x12\x5e\xa9\x29\x82\x39\x8c\x5b\x98\x07\x21\x11\x07\x89\x6b\x50\x06\xfa\x5d\xf2\x2a\x9c\x99\xf1\x40\x81\x40\xa9\x04\x0d\xb5\x50\x02\xca\x6c\x37\x70\x34\x0f\xc9\x6b\x97\x7a\x34\xa6\xa0\x9b\x87\x82\x4b\x43\x71\x08\x24\x96\x42\x3e\x4b\xe0\x71\x87\x8e\x17\x25\xf0\x30\x14\xcb\x0f\x46\x2e\x95\xd3\x12\x38\x54\x43\x8f\x02\x85\x25\x90\xba\x30\xd4\x63\x49\xcb\x51\xe5\x02\x24\xba\xae\x61\x73\x9f\xa2\xd4\x5a\xc9\x72\x17\xd6\xd1\xac\xfb\x5a\xa1\x2a\x56\x91\xd4\x25\xf3\x29\xf7\xf2\x92\x50\x69\x8c\x03\xc1\xa8\x9c\xa2\xa3\x6b\x38\x1c\x24\x0f\x29\x6a\x6f\xd6\x25\xba\xfa\x98\xbb\x2e\x9f\x6b\xd1\x6c\xce\x1c\x1a\x4f\xc6\x42\x23\x93\x91\x9e\x90\xda\xa9\x5d\x19\x57\xd4\x8e\xd4\x1d\x1a\xc0\x5f\x59\x35\x7e\x25\xa7\xc4\x75\x61\x84\xb1\xc2\xd0\x01\xca\x80\x64\xc4\x11\x9a\xbc\x4e\xb1\x14\x25\x2e\xf8\x5c\x84\xf4\xd6\xc5\xac\x18\x86\x75\xd1\x86\x61\xff\xdc\xfa\xd8\x1c\xb4\xa1\x33\x84\xab\x41\xff\x43\xe7\xac\x7d\x06\x66\x73\x08\x9d\xa1\x59\x82\x8f\x1d\xeb\xa2\x7f\x6d\xc1\xc7\xe6\x60\xd0\xec\x59\x9f\xa0\x7f\x0e\xcd\xde\x27\xf8\xff\x4e\xef\xac\x04\xed\x5f\xaf\x06\xed\xe1\x10\xfa\x03\xa3\x73\x79\xd5\xed\xb4\xcf\x4a\xd0\xe9\xb5\xba\xd7\x67\x9d\xde\x7b\x78\x77\x6d\x41\xaf\x6f\x41\xb7\x73\xd9\xb1\xda\x67\x60\xf5\x41\x13\x8c\xa1\x3a\xed\xa1\x06\xbb\x6c\x0f\x5a\x17\xcd\x9e\xd5\x7c\xd7\xe9\x76\xac\x4f\x25\xe3\xbc\x63\xf5\x34\xe6\x79\x7f\x00\x4d\xb8\x6a\x0e\xac\x4e\xeb\xba\xdb\x1c\xc0\xd5\xf5\xe0\xaa\x3f\x6c\x43\xb3\x77\x06\xbd\x7e\xaf\xd3\x3b\x1f\x74\x7a\xef\xdb\x97\xed\x9e\x55\x81\x4e\x0f\x7a\x7d\x68\x7f\x68\xf7\x2c\x18\x5e\x34\xbb\x5d\x4d\xca\x68\x5e\x5b\x17\xfd\x81\xe6\x0f\x5a\xfd\xab\x4f\x83\xce\xfb\x0b\x0b\x2e\xfa\xdd\xb3\xf6\x60\x08\xef\xda\xd0\xed\x34\xdf\x75\xdb\x11\xa9\xde\x27\x68\x75\x9b\x9d\xcb\x12\x9c\x35\x2f\x9b\xef\x35\x77\x03\xe8\x5b\x17\xed\x81\xa1\xab\x45\xdc\xc1\xc7\x8b\xb6\x2e\xd2\xf4\x9a\x3d\x68\xb6\xac\x4e\xbf\xa7\xc5\x68\xf5\x7b\xd6\xa0\xd9\xb2\x4a\x60\xf5\x07\x56\xda\xf4\x63\x67\xd8\x2e\x41\x73\xd0\x19\x6a\x85\x9c\x0f\xfa\x97\x25\x43\xab\xb3\x7f\xae\xab\x74\x7a\xd0\xea\xf7\x7a\xed\x08\x45\xab\x1a\x72\x16\xe9\x0f\xc2\xe7\xeb\x61\x3b\x05\x84\xb3\x76\xb3\xdb\xe9\xbd\x1f\x6a\x0e\xb4\x88\x49\xe5\x8a\x51\x2e\x9f\x1a\x27\x3a\x56\xc1\xad\xe7\x32\xd9\x28\x08\x6c\x87\x6f\xdf\xbe\x8d\xe2\x99\xb9\x5f\x25\xa9\x16\x2e\x36\xcc\x31\x67\xaa\x3c\x26\x1e\x75\x17\x75\xf8\xf1\x02\xdd\x19\x2a\x6a\x13\xe8\x61\x80\x3f\x96\x20\x2d\x28\x41\x53\x50\xe2\x96\x40\x12\x26\xcb\x12\x05\x1d\x1f\xc3\x88\xdf\x96\x25\xfd\x53\x8f\xc5\x30\xe2\xc2\x41\x51\x1e\xf1\xdb\x63\x08\x41\x25\xfd\x13\xeb\x70\xf8\x93\x7f\x7b\x0c\x1e\x11\x13\xca\xea\x50\x3b\xd6\xb1\x75\x8a\xc4\x79\x4a\xfa\x1e\x2a\x02\x7a\x44\x6d\x98\x33\x8a\x73\xdd\x8b\x4c\xb0\x39\x53\xc8\x54\xc3\x9c\x53\x47\x4d\x1b\x0e\xce\xa8\x8d\xe5\xf0\xe1\xe9\x94\x05\xd5\x84\x5d\x6d\xcc\x32\xfe\x11\xd0\x59\xc3\x6c\x45\xac\x96\xad\x85\x8f\x19\xc6\x75\x2a\x52\xd5\xc6\x3d\x0e\x47\x02\x89\xaa\x71\x6d\x9d\x97\x7f\x79\x62\xf6\xc3\xa5\x8b\x27\x63\xe1\x74\x57\x2e\x72\x52\x0d\x99\x3b\x35\x8c\x93\xaa\x76\x4a\xfd\x65\xc4\x9d\x05\x50\x85\x9e\xb4\xb9\x8f\x0d\xd3\x0c\x1f\xd4\xc2\xc7\xb4\x47\x49\x7b\x8a\x1e\x09\xbb\x5d\x5b\x8f\xee\x97\x49\xee\xfb\xa8\x42\x96\xe7\x38\xfa\x42\x55\x39\x7a\xe1\x71\xae\xa6\xa1\x66\xa2\xb1\x81\x12\x89\xce\xaa\x92\xf6\x8d\xb0\x75\x99\x38\x9f\x03\xa9\xea\xc0\x38\xc3\x63\x98\xa2\x1e\x78\xeb\x70\x58\xab\xfd\x70\x0c\x2e\x65\x58\x4e\x8b\x2a\x6f\xd0\x3b\x86\xb0\x07\x44\x15\xe0\x3b\xea\xe9\xce\x42\x98\x3a\x06\xbd\x7a\x36\x11\x3c\x60\x4e\xd9\xe6\x2e\x17\x75\xf8\x7e\xfc\x46\x7f\xb2\xea\x07\x9f\x38\x7a\xd8\xd7\xdf\x4d\x18\x4d\xc2\x9a\x0d\x33\xae\x69\x6a\x7d\x2b\x32\x7a\x6c\xf7\xc8\x88\xb4\xa7\x1c\x85\xbc\x03\x9c\x28\xf1\xb8\x9c\x67\x38\x3a\x35\x00\x34\x07\x8f\x1c\x49\x67\x28\x34\xaa\x5b\x26\x2e\x9d\xb0\x3a\x28\xee\xe7\xd8\x82\x59\xf8\xa2\x61\x2a\xee\x9b\xa7\x27\x55\xe5\xac\x18\x0d\xf5\xde\x30\xdf\xd4\x6a\xe6\x33\x60\x3a\x9e\x5a\xd5\x61\xe4\x72\xfb\x4b\xce\xb7\x3d\x72\x5b\x8e\x9d\xe4\x4d\xad\xe6\xdf\xe6\x5e\xda\x2e\x12\xa1\x09\xaa\x69\xae\x3c\xe3\x55\xb9\xf2\x54\x39\x40\x02\xc5\xd7\xba\x44\x4e\x5b\xa1\xa2\x00\x4e\x1c\x3a\x7b\x5c\xfd\xac\xcb\xbb\xae\x9c\xdd\x42\x24\x7c\x6b\x23\x87\x9d\x39\xb6\xb3\x0e\x19\x26\xd8\xe8\xba\x71\xed\x86\x59\x8b\x9e\xa5\x4f\xec\xe4\xf9\x51\x05\x8d\x5f\x0a\xe2\xd0\x40\xd6\xe1\xb5\x7f\x5b\x1c\x00\xc6\xe3\x8c\xc8\x49\xb3\x3a\x1c\xfa\xb7\x20\xb9\x4b\x1d\xf8\x1e\xdf\xea\x4f\x3e\xa8\x8d\xc7\x19\x5d\x3c\x87\xe8\x90\xfc\x3d\x66\x94\x78\xb3\xb5\xc3\xe5\xb4\x1b\x36\x99\xc7\x43\xcd\xcf\xb5\xda\x31\x84\x43\x54\x5c\xdf\x46\xa6\x50\x14\xd9\x2b\xfc\x57\x83\x5a\xa1\xdd\xda\x6f\x7e\x3e\x3a\x6a\x65\x15\xb1\x72\xd4\xa3\x9a\x7f\x7b\x6c\x42\xdc\xdf\x22\x02\x59\xeb\x45\x6d\x8b\x7b\x64\xf2\xb7\xda\x01\x4d\xb7\x3e\x21\x5c\x2c\x29\x5c\x4b\x3a\x80\x43\x58\x2e\x65\xba\xe0\x01\x63\x2e\x60\xb5\x4b\x97\xdd\xa7\xcc\x2c\x8e\xe9\x75\x8f\x84\x5e\xf2\x97\xd9\xb3\x6b\xe4\x76\xec\x36\xaa\xc5\x4b\x2b\x49\x89\xfe\xac\x62\x70\xfa\x2c\x72\xcf\xff\x4a\x37\xdd\x67\x30\x5b\x39\xcf\x61\xe4\x3c\xbb\x7c\xe3\xd9\xc7\xbe\xad\x6a\x7f\x5e\x4e\xf0\xdc\x5d\xa1\x06\x35\x38\xba\xdf\x1d\x62\x31\x08\x4c\x05\x8e\x1b\xe6\x8e\x15\xd6\x74\xd1\xfd\x91\xfd\x21\x09\x9a\xe7\xe7\xe7\x71\xf0\x75\xd0\xe6\x22\x5c\x93\x4b\xa6\x07\xb9\x09\xc1\x11\x7a\x6b\x71\x7b\xc4\x5d\xa7\x38\x70\xdb\x81\x90\x3a\x24\xfb\x9c\x46\x05\x69\x42\x41\x59\x08\x1a\xe7\x15\x6b\x01\xfe\x67\xdd\x2b\x43\xbc\x70\x11\x75\xcc\x85\x57\x07\x9b\xf8\x54\x11\x97\xfe\x89\x85\x41\xff\xf5\x4f\xbf\xa0\x43\x72\xc6\x8a\x51\xd7\x6b\xc4\xc5\xa1\x96\xeb\xd1\x40\x9e\x16\xa6\xd9\x9b\x7f\x1b\x9b\xf7\xf4\x03\xc5\xb9\x5e\x7f\xdb\x61\xbb\x64\x1a\x49\x0a\x7d\x78\x2d\xf0\x16\x87\xdf\x34\x74\xef\xdc\xfc\x58\x2e\x5f\xba\xec\x23\x75\x59\xa9\x04\x67\x93\xa7\x53\xed\x6f\xdb\xcf\x59\xfd\x1e\xef\x7c\x9d\x54\x23\x26\xbf\x81\xd7\x15\x24\x0c\xf1\x9b\xe4\x30\x51\x8e\x93\x17\x3f\xfc\xd7\xf8\x61\x74\x30\x2d\x75\xb5\x93\xd1\xd3\x99\x59\xaf\x23\x26\x7a\x29\xf6\xd2\xc2\x3c\x7a\xfb\x51\xb7\x27\x16\x66\x7b\xbf\x2b\x1a\x0b\x56\x9b\xe8\x7a\x53\x7a\xb9\x7c\x72\xcf\xc8\x70\xf4\x5c\xdc\xe3\x5e\x8d\x26\xd1\x6c\xc5\xfa\x3f\xc3\x59\xb2\x19\xe6\xfa\x59\xcd\x27\x4a\x28\x93\x74\x6b\x23\xa7\x0c\x98\x83\x42\x67\x7f\x39\x11\x4f\xa3\xd3\xa6\x3a\x89\x7a\x62\x4d\x7f\xb3\xd1\xd4\xb8\xaf\x4b\x6f\x9e\x35\x29\x34\xef\x4b\x56\xf8\x6c\xb2\xc2\x67\xe7\x99\x00\x27\xd3\x67\xc8\xd3\xff\x74\x0f\xde\x95\x11\xbf\xa4\xb9\xff\xcc\x34\x37\x3b\xdd\x4a\xcf\xec\xad\x26\x5c\x49\x51\x9a\xe8\xfc\x4d\x17\xdb\xee\x60\x99\x24\x65\x8d\x9b\x97\x49\xd7\xcb\xa4\xeb\x65\xd2\xf5\x32\xe9\x7a\x99\x74\xbd\x4c\xba\x5e\x26\x5d\xdb\x26\x5d\x1b\xb5\xf5\x7e\xdc\xa9\xb1\x0b\x38\x0f\x99\x36\x59\x95\x3c\xfa\x49\x8c\x74\x1b\xa2\xf6\x43\xee\xa4\xc9\xca\xd0\x6f\xdf\xbe\x2d\x1e\xe8\xa2\x94\xeb\xd4\xd8\xbd\x25\xf9\x54\x96\x3e\x35\x9e\x6b\xfa\xf2\x98\xa9\xcb\xd1\xd6\xd4\xa5\x70\x13\xed\x3e\x93\x67\x72\x9b\xb5\x73\x0d\xb9\x54\x27\x17\xae\xf2\xb7\xc9\x1f\xcf\x21\x8e\xb2\xd1\x2a\x74\xe2\xbd\x43\x15\x32\x05\xa3\xc5\x7e\xfb\x70\x9b\xb1\x63\x3d\x6e\x6c\x44\x86\x93\xaa\x43\x67\xa7\xd1\xff\x46\x3e\x4c\x3c\xb7\xb4\x76\xdd\xb0\x31\xa3\x91\x88\xab\xf8\x75\x52\xd5\xa7\x58\x75\x89\x3e\x0e\x7c\x6a\x18\xc5\xf7\x77\xfc\x40\x4e\xf9\x0c\x45\x7a\xf1\xe6\xeb\x6f\x6b\x6f\x40\xfd\xf7\xef\x83\x7d\x9b\xeb\x60\x19\x59\x0a\xa8\x25\x53\xb0\x3c\xbd\xbf\x7b\x19\x2c\x43\x73\x0f\x4d\xae\xae\x5c\x6f\xf3\xfe\xf4\x04\xc1\x0a\x30\x67\x67\x35\xa7\x2e\xe5\x5b\x4c\xb3\x87\x9d\x73\x60\x33\x4e\x6d\xdc\x03\x6b\xb3\x7b\x86\x97\x5c\xc6\xd4\x0e\x1d\xa0\x02\xbb\x68\x57\xe0\xc1\x4e\x53\x01\x73\xbb\x06\x3e\x53\x41\x52\x9e\x65\xe0\x79\x44\x2c\xf6\x97\x7f\x1b\xd0\xcb\x35\xe2\xfd\xaf\x11\x67\xaf\xa1\xd6\x61\x1f\x67\x5e\xe3\xb9\xd0\x02\x22\x26\x76\x63\x73\xcf\x8b\xaf\xc8\x37\x5d\x37\x3a\x94\x17\xdf\xfe\xa2\x12\xf4\x89\x11\x1f\xa6\x64\x86\x30\x42\x64\x90\x34\xab\x6c\x71\x17\x89\x42\xdf\xfe\x60\x7c\x9e\x52\x92\x53\x2e\xd4\xcd\xa6\xc5\x1f\xe6\x3e\x05\xc0\xbb\x20\xb7\x7b\xdb\x43\x28\x50\xcf\x27\xe9\xbd\xcc\xf8\xe7\x5d\x72\xbf\x9c\x22\x71\x86\x82\xaa\x05\x98\xb6\xa0\xe1\xf0\xa3\x2b\x1f\x6a\x7c\x57\xe2\x3d\x4d\xe6\x44\xb0\xf8\x17\x62\x8e\x92\x16\xcb\xe5\xeb\x94\xb9\x7d\xb9\x0c\xc4\x04\x99\xbd\xd9\x2b\x77\x08\xf4\x10\x25\xd8\x2e\x97\x78\xc3\xb8\x42\xf9\xad\x9c\xc4\x77\x03\xf6\xe5\xdb\x5e\x80\xc7\x5b\xb4\xef\x8d\xad\xfb\x7a\x45\x9e\xdb\x85\x74\xf9\x24\x6d\x96\xd8\x2f\xef\x15\xc9\x8f\xfe\x24\xa2\xeb\xb7\x3a\x68\xdb\xb8\x9f\x2f\x64\xdd\x47\x7f\x7f\xb0\x07\xc5\x5f\x93\x76\xcb\x25\x8a\xd5\x8f\x25\xad\x24\xdb\x2e\x97\x27\x27\xd4\x89\x85\x4a\xc4\xd9\xb3\xe5\xd6\x91\x6c\x4b\xb7\xfe\xcf\x00\x76\xeb\x2c\x08\xee\x49\x00\x00")
 
 func templateDefaultTmplBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,7 +83,7 @@ func templateDefaultTmpl() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "template/default.tmpl", size: 17035, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
+	info := bindataFileInfo{name: "template/default.tmpl", size: 18926, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -15,6 +15,7 @@ package template
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/url"
 	"path/filepath"
 	"regexp"
@@ -135,6 +136,12 @@ var DefaultFuncs = FuncMap{
 		re := regexp.MustCompile(pattern)
 		return re.ReplaceAllString(text, repl)
 	},
+	// toJson encodes a value as JSON so it can be embedded safely in
+	// templated request bodies.
+	"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
 }
 
 // Pair is a key/value string pair.
@@ -229,6 +236,10 @@ type Data struct {
 	CommonAnnotations KV `json:"commonAnnotations"`
 
 	ExternalURL string `json:"externalURL"`
+
+	// Groups holds the data of the individual groups of a digest
+	// notification.
+	Groups []*Data `json:"groups,omitempty"`
 }
 
 // Alert holds one alert for notification templates.
@@ -239,6 +250,15 @@ type Alert struct {
 	StartsAt     time.Time `json:"startsAt"`
 	EndsAt       time.Time `json:"endsAt"`
 	GeneratorURL string    `json:"generatorURL"`
+
+	Acknowledgement *Acknowledgement `json:"acknowledgement,omitempty"`
+}
+
+// Acknowledgement holds information about who acknowledged an alert.
+type Acknowledgement struct {
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 // Alerts is a list of Alert objects.
@@ -0,0 +1,150 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tenant separates the alerts of tenants sharing an Alertmanager.
+//
+// An alert belongs to the tenant named by its tenant label. Each tenant has
+// its own dispatcher and inhibitor receiving only the tenant's alerts, while
+// alerts of no configured tenant are handled by the configuration's root
+// route. The notification log and the silences are shared, the group keys
+// of tenants are namespaced by the matcher of their root route.
+package tenant
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/dispatch"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/types"
+)
+
+// Route returns the routing tree of the tenant. Its root route only matches
+// the alerts of the tenant, which also namespaces the tenant's group keys.
+func Route(t *config.Tenant, label model.LabelName) *dispatch.Route {
+	r := dispatch.NewRoute(t.Route, nil)
+	r.Matchers = append(r.Matchers, types.NewMatcher(label, t.Name))
+	sort.Sort(r.Matchers)
+	return r
+}
+
+// GroupKeys returns a function that returns true for the keys of the groups
+// of the tenant's routing tree.
+func GroupKeys(t *config.Tenant, label model.LabelName) func(string) bool {
+	key := Route(t, label).Key()
+	return func(gkey string) bool {
+		return strings.HasPrefix(gkey, key+":") || strings.HasPrefix(gkey, key+"/")
+	}
+}
+
+// Only returns a view of the alerts of the tenant.
+func Only(ap provider.Alerts, label model.LabelName, name string) provider.Alerts {
+	return Filter(ap, func(lset model.LabelSet) bool {
+		return lset[label] == model.LabelValue(name)
+	})
+}
+
+// Exclude returns a view of the alerts not belonging to any of the tenants.
+func Exclude(ap provider.Alerts, label model.LabelName, tenants []*config.Tenant) provider.Alerts {
+	if len(tenants) == 0 {
+		return ap
+	}
+	names := make(map[model.LabelValue]struct{}, len(tenants))
+	for _, t := range tenants {
+		names[model.LabelValue(t.Name)] = struct{}{}
+	}
+	return Filter(ap, func(lset model.LabelSet) bool {
+		_, ok := names[lset[label]]
+		return !ok
+	})
+}
+
+// Filter returns a view of the alerts whose labels match. Alerts of any
+// labels can be put.
+func Filter(ap provider.Alerts, match func(model.LabelSet) bool) provider.Alerts {
+	return &filteredAlerts{Alerts: ap, match: match}
+}
+
+type filteredAlerts struct {
+	provider.Alerts
+	match func(model.LabelSet) bool
+}
+
+// Subscribe implements provider.Alerts.
+func (f *filteredAlerts) Subscribe() provider.AlertIterator {
+	return f.filter(f.Alerts.Subscribe())
+}
+
+// GetPending implements provider.Alerts.
+func (f *filteredAlerts) GetPending() provider.AlertIterator {
+	return f.filter(f.Alerts.GetPending())
+}
+
+// Get implements provider.Alerts.
+func (f *filteredAlerts) Get(fp model.Fingerprint) (*types.Alert, error) {
+	a, err := f.Alerts.Get(fp)
+	if err != nil {
+		return nil, err
+	}
+	if !f.match(a.Labels) {
+		return nil, provider.ErrNotFound
+	}
+	return a, nil
+}
+
+func (f *filteredAlerts) filter(it provider.AlertIterator) provider.AlertIterator {
+	fi := &filteredIterator{
+		AlertIterator: it,
+		ch:            make(chan *types.Alert),
+		done:          make(chan struct{}),
+	}
+	go func() {
+		defer close(fi.ch)
+
+		for {
+			select {
+			case a, ok := <-it.Next():
+				if !ok {
+					return
+				}
+				if !f.match(a.Labels) {
+					continue
+				}
+				select {
+				case fi.ch <- a:
+				case <-fi.done:
+					return
+				}
+			case <-fi.done:
+				return
+			}
+		}
+	}()
+	return fi
+}
+
+type filteredIterator struct {
+	provider.AlertIterator
+	ch   chan *types.Alert
+	done chan struct{}
+}
+
+func (fi *filteredIterator) Next() <-chan *types.Alert { return fi.ch }
+
+func (fi *filteredIterator) Close() {
+	close(fi.done)
+	fi.AlertIterator.Close()
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenant
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/provider"
+	"github.com/prometheus/alertmanager/provider/mem"
+	"github.com/prometheus/alertmanager/types"
+)
+
+func newAlert(lset model.LabelSet) *types.Alert {
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels:   lset,
+			StartsAt: time.Now(),
+			EndsAt:   time.Now().Add(time.Hour),
+		},
+	}
+}
+
+func names(t *testing.T, it provider.AlertIterator, n int) []model.LabelValue {
+	defer it.Close()
+
+	var res []model.LabelValue
+	for len(res) < n {
+		select {
+		case a := <-it.Next():
+			res = append(res, a.Labels["alertname"])
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of %d alerts", len(res), n)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func TestFilter(t *testing.T) {
+	alerts, err := mem.NewAlerts(types.NewMarker(), time.Hour)
+	require.NoError(t, err)
+	defer alerts.Close()
+
+	a := newAlert(model.LabelSet{"alertname": "a", "tenant": "team-a"})
+	b := newAlert(model.LabelSet{"alertname": "b", "tenant": "team-b"})
+	c := newAlert(model.LabelSet{"alertname": "c"})
+	require.NoError(t, alerts.Put(a, b, c))
+
+	tenants := []*config.Tenant{{Name: "team-a"}, {Name: "team-b"}}
+	require.Equal(t, []model.LabelValue{"a"}, names(t, Only(alerts, "tenant", "team-a").GetPending(), 1))
+	require.Equal(t, []model.LabelValue{"c"}, names(t, Exclude(alerts, "tenant", tenants).GetPending(), 1))
+	require.Equal(t, []model.LabelValue{"b", "c"}, names(t, Exclude(alerts, "tenant", tenants[:1]).Subscribe(), 2))
+
+	_, err = Only(alerts, "tenant", "team-a").Get(b.Fingerprint())
+	require.Equal(t, provider.ErrNotFound, err)
+	got, err := Only(alerts, "tenant", "team-b").Get(b.Fingerprint())
+	require.NoError(t, err)
+	require.Equal(t, b.Labels, got.Labels)
+}
+
+func TestRoute(t *testing.T) {
+	r := Route(&config.Tenant{
+		Name: "team-a",
+		Route: &config.Route{
+			Receiver: "default",
+			Routes: []*config.Route{
+				{Receiver: "db", Match: map[string]string{"service": "db"}},
+			},
+		},
+	}, "tenant")
+
+	require.Nil(t, r.Match(model.LabelSet{"service": "db"}))
+	require.Nil(t, r.Match(model.LabelSet{"service": "db", "tenant": "team-b"}))
+
+	routes := r.Match(model.LabelSet{"service": "db", "tenant": "team-a"})
+	require.Len(t, routes, 1)
+	require.Equal(t, "db", routes[0].RouteOpts.Receiver)
+	require.Equal(t, `{tenant="team-a"}/{service="db"}`, routes[0].Key())
+
+	owns := GroupKeys(&config.Tenant{Name: "team-a", Route: &config.Route{Receiver: "default"}}, "tenant")
+	require.True(t, owns(`{tenant="team-a"}:{alertname="a"}`))
+	require.True(t, owns(`{tenant="team-a"}/{service="db"}:{alertname="a"}`))
+	require.False(t, owns(`{tenant="team-ab"}:{alertname="a"}`))
+	require.False(t, owns(`{}/{tenant="team-a"}:{alertname="a"}`))
+}
@@ -0,0 +1,270 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeinterval
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Matcher matches points in time.
+type Matcher interface {
+	ContainsTime(t time.Time) bool
+}
+
+// Calendar holds the events of an iCalendar (RFC 5545) file.
+type Calendar struct {
+	events []event
+	// Skipped is the number of events that could not be used, for
+	// example because of unsupported recurrence rules.
+	Skipped int
+}
+
+type event struct {
+	start, end time.Time
+	yearly     bool
+	until      time.Time
+}
+
+// ContainsTime returns true if t is within one of the events of the
+// calendar.
+func (c *Calendar) ContainsTime(t time.Time) bool {
+	for _, e := range c.events {
+		if e.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of events in the calendar.
+func (c *Calendar) Len() int {
+	return len(c.events)
+}
+
+func (e event) contains(t time.Time) bool {
+	if !e.yearly {
+		return !t.Before(e.start) && t.Before(e.end)
+	}
+	if !e.until.IsZero() && t.After(e.until.Add(e.end.Sub(e.start))) {
+		return false
+	}
+	// An occurrence containing t starts in the same or the previous year.
+	for _, y := range []int{t.Year() - 1, t.Year()} {
+		start := e.start.AddDate(y-e.start.Year(), 0, 0)
+		if start.Before(e.start) || (!e.until.IsZero() && start.After(e.until)) {
+			continue
+		}
+		end := e.end.AddDate(y-e.start.Year(), 0, 0)
+		if !t.Before(start) && t.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCalendar parses the events of an iCalendar file. Times without a time
+// zone are interpreted in the given location.
+func ParseCalendar(r io.Reader, loc *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		cal   = &Calendar{}
+		props map[string]property
+	)
+	for i, l := range lines {
+		p, err := parseProperty(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		switch {
+		case p.name == "BEGIN" && p.value == "VEVENT":
+			props = map[string]property{}
+		case p.name == "END" && p.value == "VEVENT":
+			if props == nil {
+				return nil, fmt.Errorf("line %d: unexpected END:VEVENT", i+1)
+			}
+			e, ok, err := newEvent(props, loc)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", i+1, err)
+			}
+			if ok {
+				cal.events = append(cal.events, e)
+			} else {
+				cal.Skipped++
+			}
+			props = nil
+		case props != nil:
+			props[p.name] = p
+		}
+	}
+	return cal, nil
+}
+
+// unfold returns the content lines of an iCalendar file. Long lines are
+// folded by continuing them on lines starting with whitespace.
+func unfold(r io.Reader) ([]string, error) {
+	var (
+		lines []string
+		s     = bufio.NewScanner(r)
+	)
+	for s.Scan() {
+		l := strings.TrimRight(s.Text(), "\r")
+		if len(l) > 0 && (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, s.Err()
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseProperty(l string) (property, error) {
+	i := strings.Index(l, ":")
+	if i < 0 {
+		return property{}, fmt.Errorf("invalid content line %q", l)
+	}
+	parts := strings.Split(l[:i], ";")
+	p := property{
+		name:   strings.ToUpper(parts[0]),
+		params: map[string]string{},
+		value:  l[i+1:],
+	}
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return p, nil
+}
+
+// newEvent returns the event described by the properties. It returns false
+// if the event has a recurrence rule other than a plain yearly one.
+func newEvent(props map[string]property, loc *time.Location) (event, bool, error) {
+	dtstart, ok := props["DTSTART"]
+	if !ok {
+		return event{}, false, fmt.Errorf("event without DTSTART")
+	}
+	start, allDay, err := parseDateTime(dtstart, loc)
+	if err != nil {
+		return event{}, false, err
+	}
+
+	e := event{start: start}
+	switch {
+	case props["DTEND"].value != "":
+		if e.end, _, err = parseDateTime(props["DTEND"], loc); err != nil {
+			return event{}, false, err
+		}
+	case props["DURATION"].value != "":
+		d, err := parseDuration(props["DURATION"].value)
+		if err != nil {
+			return event{}, false, err
+		}
+		e.end = start.Add(d)
+	case allDay:
+		e.end = start.AddDate(0, 0, 1)
+	default:
+		e.end = start
+	}
+	if e.end.Before(e.start) {
+		return event{}, false, fmt.Errorf("event ends before it starts")
+	}
+
+	if rrule, ok := props["RRULE"]; ok {
+		rule := map[string]string{}
+		for _, part := range strings.Split(rrule.value, ";") {
+			kv := strings.SplitN(part, "=", 2)
+			if len(kv) == 2 {
+				rule[strings.ToUpper(kv[0])] = kv[1]
+			}
+		}
+		for k, v := range rule {
+			switch {
+			case k == "FREQ" && v == "YEARLY":
+			case k == "INTERVAL" && v == "1":
+			case k == "UNTIL":
+				if e.until, _, err = parseDateTime(property{value: v}, loc); err != nil {
+					return event{}, false, err
+				}
+			default:
+				return event{}, false, nil
+			}
+		}
+		if rule["FREQ"] != "YEARLY" {
+			return event{}, false, nil
+		}
+		e.yearly = true
+	}
+	return e, true, nil
+}
+
+// parseDateTime parses a DATE or DATE-TIME value. It returns true for dates.
+func parseDateTime(p property, loc *time.Location) (time.Time, bool, error) {
+	if tzid, ok := p.params["TZID"]; ok {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+		loc = l
+	}
+	v := p.value
+	switch {
+	case len(v) == 8:
+		t, err := time.ParseInLocation("20060102", v, loc)
+		return t, true, err
+	case strings.HasSuffix(v, "Z"):
+		t, err := time.Parse("20060102T150405Z", v)
+		return t, false, err
+	default:
+		t, err := time.ParseInLocation("20060102T150405", v, loc)
+		return t, false, err
+	}
+}
+
+var durationRE = regexp.MustCompile(`^\+?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration parses an iCalendar duration like P1D or PT1H30M.
+func parseDuration(s string) (time.Duration, error) {
+	m := durationRE.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, _ := strconv.Atoi(m[i+1])
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
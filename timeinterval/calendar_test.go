@@ -0,0 +1,186 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeinterval
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:New Year's Day\r\n" +
+	"DTSTART;VALUE=DATE:20180101\r\n" +
+	"DTEND;VALUE=DATE:20180102\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas\r\n" +
+	"DTSTART;VALUE=DATE:20171225\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Team off\r\n" +
+	" site\r\n" +
+	"DTSTART;TZID=America/New_York:20180305T090000\r\n" +
+	"DURATION:PT8H\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Maintenance\r\n" +
+	"DTSTART:20180310T220000Z\r\n" +
+	"DTEND:20180311T020000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Weekly sync\r\n" +
+	"DTSTART:20180305T150000Z\r\n" +
+	"DURATION:PT1H\r\n" +
+	"RRULE:FREQ=WEEKLY\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseCalendar(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	cal, err := ParseCalendar(strings.NewReader(testCalendar), berlin)
+	require.NoError(t, err)
+	require.Equal(t, 4, cal.Len())
+	require.Equal(t, 1, cal.Skipped)
+
+	for _, tc := range []struct {
+		t        string
+		contains bool
+	}{
+		// Dates are interpreted in Berlin.
+		{"2018-01-01T12:00:00Z", true},
+		{"2017-12-31T23:30:00Z", true},
+		{"2018-01-01T23:30:00Z", false},
+		{"2018-12-25T12:00:00Z", true},
+		{"2021-12-25T12:00:00Z", true},
+		{"2016-12-25T12:00:00Z", false},
+		{"2018-12-26T12:00:00Z", false},
+		// 09:00 in New York is 14:00 UTC.
+		{"2018-03-05T14:00:00Z", true},
+		{"2018-03-05T21:59:00Z", true},
+		{"2018-03-05T22:00:00Z", false},
+		{"2018-03-11T01:00:00Z", true},
+		{"2018-03-11T02:00:00Z", false},
+		// Weekly events are skipped.
+		{"2018-03-12T15:30:00Z", false},
+	} {
+		ts, err := time.Parse(time.RFC3339, tc.t)
+		require.NoError(t, err)
+		require.Equal(t, tc.contains, cal.ContainsTime(ts), tc.t)
+	}
+
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{"BEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT\n", "line 3: event without DTSTART"},
+		{"BEGIN:VEVENT\nDTSTART:2018\nEND:VEVENT\n", `line 3: parsing time "2018" as "20060102T150405": cannot parse "" as "01"`},
+		{"BEGIN:VEVENT\nDTSTART:20180102\nDTEND:20180101\nEND:VEVENT\n", "line 4: event ends before it starts"},
+		{"BEGIN:VEVENT\nDTSTART:20180101\nDURATION:1D\nEND:VEVENT\n", `line 4: invalid duration "1D"`},
+		{"BEGIN:VEVENT\nDTSTART;TZID=Mars/Olympus_Mons:20180101T000000\nEND:VEVENT\n", `line 3: unknown time zone "Mars/Olympus_Mons"`},
+		{"END:VEVENT\n", "line 1: unexpected END:VEVENT"},
+		{"BEGIN:VEVENT\ngarbage\n", `line 2: invalid content line "garbage"`},
+	} {
+		_, err := ParseCalendar(strings.NewReader(tc.in), time.UTC)
+		require.EqualError(t, err, tc.err, tc.in)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for s, d := range map[string]time.Duration{
+		"P1D":      24 * time.Hour,
+		"P1W":      7 * 24 * time.Hour,
+		"PT1H30M":  90 * time.Minute,
+		"P1DT2H":   26 * time.Hour,
+		"+PT15S":   15 * time.Second,
+		"PT0S":     0,
+		"P2DT1M1S": 48*time.Hour + time.Minute + time.Second,
+	} {
+		res, err := parseDuration(s)
+		require.NoError(t, err, s)
+		require.Equal(t, d, res, s)
+	}
+	for _, s := range []string{"P", "PT", "P1DT", "1D", "P1H"} {
+		_, err := parseDuration(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestCalendarConfig(t *testing.T) {
+	var c CalendarConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`{url: "https://example.com/holidays.ics"}`), &c))
+	require.Equal(t, DefaultCalendarRefreshInterval, c.RefreshInterval)
+
+	for _, in := range []string{`{refresh_interval: 1h}`, `{url: "https://example.com", file: /tmp/holidays.ics}`} {
+		var c CalendarConfig
+		require.EqualError(t, yaml.UnmarshalStrict([]byte(in), &c), "exactly one of url and file must be set in a calendar", in)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	newYear := time.Date(2018, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	var status = http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		fmt.Fprint(w, testCalendar)
+	}))
+	defer srv.Close()
+
+	f := NewFeed(&CalendarConfig{URL: srv.URL, RefreshInterval: DefaultCalendarRefreshInterval}, nil)
+	require.False(t, f.ContainsTime(newYear))
+	require.NoError(t, f.Refresh(context.Background()))
+	require.True(t, f.ContainsTime(newYear))
+
+	// The previous calendar is kept if loading fails.
+	status = http.StatusInternalServerError
+	require.EqualError(t, f.Refresh(context.Background()), fmt.Sprintf("unexpected status code 500 from %s", srv.URL))
+	require.True(t, f.ContainsTime(newYear))
+
+	file, err := ioutil.TempFile("", "calendar")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(testCalendar)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	f = NewFeed(&CalendarConfig{File: file.Name(), RefreshInterval: DefaultCalendarRefreshInterval}, nil)
+	done := make(chan struct{})
+	go func() {
+		f.Run()
+		close(done)
+	}()
+	for i := 0; !f.ContainsTime(newYear); i++ {
+		if i == 100 {
+			t.Fatal("calendar was not loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.Stop()
+	<-done
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeinterval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/common/model"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// DefaultCalendarRefreshInterval is the default refresh interval of calendar
+// feeds.
+const DefaultCalendarRefreshInterval = model.Duration(time.Hour)
+
+// CalendarConfig configures an iCalendar feed, such as a holiday calendar,
+// read from a URL or a file.
+type CalendarConfig struct {
+	URL  string `yaml:"url,omitempty" json:"url,omitempty"`
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+	// Location is the time zone of events without one. Defaults to UTC.
+	Location        *Location      `yaml:"location,omitempty" json:"location,omitempty"`
+	RefreshInterval model.Duration `yaml:"refresh_interval,omitempty" json:"refresh_interval,omitempty"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *CalendarConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain CalendarConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	if (c.URL == "") == (c.File == "") {
+		return fmt.Errorf("exactly one of url and file must be set in a calendar")
+	}
+	if c.RefreshInterval == 0 {
+		c.RefreshInterval = DefaultCalendarRefreshInterval
+	}
+	return nil
+}
+
+// Feed periodically loads a calendar. It contains the times within the
+// events of the last successfully loaded calendar.
+type Feed struct {
+	conf   *CalendarConfig
+	client *http.Client
+	logger log.Logger
+
+	mtx sync.RWMutex
+	cal *Calendar
+
+	stopc chan struct{}
+	done  chan struct{}
+}
+
+// NewFeed returns a new feed for the given calendar. Call Run to load it.
+func NewFeed(conf *CalendarConfig, logger log.Logger) *Feed {
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	return &Feed{
+		conf:   conf,
+		client: &http.Client{},
+		logger: logger,
+		stopc:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// ContainsTime returns true if t is within one of the events of the
+// calendar. It returns false as long as the calendar was never loaded.
+func (f *Feed) ContainsTime(t time.Time) bool {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	if f.cal == nil {
+		return false
+	}
+	return f.cal.ContainsTime(t)
+}
+
+// Run loads the calendar and reloads it in the configured interval until
+// Stop is called. The previous calendar is kept if loading fails.
+func (f *Feed) Run() {
+	defer close(f.done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-f.stopc
+		cancel()
+	}()
+
+	t := time.NewTicker(time.Duration(f.conf.RefreshInterval))
+	defer t.Stop()
+
+	for {
+		if err := f.Refresh(ctx); err != nil && ctx.Err() == nil {
+			level.Error(f.logger).Log("msg", "Loading calendar failed", "err", err)
+		}
+		select {
+		case <-f.stopc:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Stop stops a running feed and waits for it to terminate.
+func (f *Feed) Stop() {
+	close(f.stopc)
+	<-f.done
+}
+
+// Refresh loads the calendar once.
+func (f *Feed) Refresh(ctx context.Context) error {
+	var r io.ReadCloser
+	if f.conf.File != "" {
+		file, err := os.Open(f.conf.File)
+		if err != nil {
+			return err
+		}
+		r = file
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+
+		resp, err := ctxhttp.Get(ctx, f.client, f.conf.URL)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode/100 != 2 {
+			resp.Body.Close()
+			return fmt.Errorf("unexpected status code %v from %s", resp.StatusCode, f.conf.URL)
+		}
+		r = resp.Body
+	}
+	defer r.Close()
+
+	loc := time.UTC
+	if f.conf.Location != nil {
+		loc = f.conf.Location.Location
+	}
+	cal, err := ParseCalendar(r, loc)
+	if err != nil {
+		return err
+	}
+	if cal.Skipped > 0 {
+		level.Warn(f.logger).Log("msg", "Skipped calendar events with unsupported recurrence rules", "count", cal.Skipped)
+	}
+	level.Debug(f.logger).Log("msg", "Calendar loaded", "events", cal.Len())
+
+	f.mtx.Lock()
+	f.cal = cal
+	f.mtx.Unlock()
+	return nil
+}
@@ -0,0 +1,382 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timeinterval describes recurring intervals of time, such as the
+// business hours of a team, in the configuration of the Alertmanager.
+package timeinterval
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeInterval describes intervals of time. A time is contained if it
+// matches all of the configured fields. An empty field matches all times.
+type TimeInterval struct {
+	Times       []TimeRange       `yaml:"times,omitempty" json:"times,omitempty"`
+	Weekdays    []WeekdayRange    `yaml:"weekdays,omitempty" json:"weekdays,omitempty"`
+	DaysOfMonth []DayOfMonthRange `yaml:"days_of_month,omitempty" json:"days_of_month,omitempty"`
+	Months      []MonthRange      `yaml:"months,omitempty" json:"months,omitempty"`
+	// Location is the time zone the other fields are evaluated in.
+	// Defaults to UTC.
+	Location *Location `yaml:"location,omitempty" json:"location,omitempty"`
+}
+
+// TimeRange is a range of minutes within a day. The start is inclusive, the
+// end is exclusive.
+type TimeRange struct {
+	StartMinute int
+	EndMinute   int
+}
+
+// InclusiveRange is a range of integers including both ends.
+type InclusiveRange struct {
+	Begin int
+	End   int
+}
+
+// WeekdayRange is a range of weekdays, with Sunday being 0. Ranges may wrap
+// around the end of the week, like saturday:sunday.
+type WeekdayRange struct {
+	InclusiveRange
+}
+
+// DayOfMonthRange is a range of days of the month. Negative values count
+// from the end of the month, -1 being its last day.
+type DayOfMonthRange struct {
+	InclusiveRange
+}
+
+// MonthRange is a range of months, with January being 1.
+type MonthRange struct {
+	InclusiveRange
+}
+
+// Location wraps time.Location to parse and print IANA time zone names.
+type Location struct {
+	*time.Location
+}
+
+var (
+	daysOfWeek = map[string]int{
+		"sunday":    0,
+		"monday":    1,
+		"tuesday":   2,
+		"wednesday": 3,
+		"thursday":  4,
+		"friday":    5,
+		"saturday":  6,
+	}
+	daysOfWeekInv = invert(daysOfWeek)
+
+	months = map[string]int{
+		"january":   1,
+		"february":  2,
+		"march":     3,
+		"april":     4,
+		"may":       5,
+		"june":      6,
+		"july":      7,
+		"august":    8,
+		"september": 9,
+		"october":   10,
+		"november":  11,
+		"december":  12,
+	}
+	monthsInv = invert(months)
+)
+
+func invert(m map[string]int) map[int]string {
+	res := make(map[int]string, len(m))
+	for k, v := range m {
+		res[v] = k
+	}
+	return res
+}
+
+// ContainsTime returns true if t is within the interval.
+func (tp TimeInterval) ContainsTime(t time.Time) bool {
+	if tp.Location != nil {
+		t = t.In(tp.Location.Location)
+	} else {
+		t = t.UTC()
+	}
+
+	if len(tp.Times) > 0 {
+		minute := t.Hour()*60 + t.Minute()
+		var in bool
+		for _, r := range tp.Times {
+			if minute >= r.StartMinute && minute < r.EndMinute {
+				in = true
+				break
+			}
+		}
+		if !in {
+			return false
+		}
+	}
+	if len(tp.DaysOfMonth) > 0 {
+		// The last day of the month is the day before the first of
+		// the next month.
+		last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		var in bool
+		for _, r := range tp.DaysOfMonth {
+			begin, end := r.Begin, r.End
+			if begin < 0 {
+				begin = last + begin + 1
+			}
+			if end < 0 {
+				end = last + end + 1
+			}
+			if t.Day() >= begin && t.Day() <= end {
+				in = true
+				break
+			}
+		}
+		if !in {
+			return false
+		}
+	}
+	if len(tp.Months) > 0 {
+		var in bool
+		for _, r := range tp.Months {
+			if int(t.Month()) >= r.Begin && int(t.Month()) <= r.End {
+				in = true
+				break
+			}
+		}
+		if !in {
+			return false
+		}
+	}
+	if len(tp.Weekdays) > 0 {
+		var in bool
+		wd := int(t.Weekday())
+		for _, r := range tp.Weekdays {
+			if r.Begin <= r.End && wd >= r.Begin && wd <= r.End {
+				in = true
+				break
+			}
+			if r.Begin > r.End && (wd >= r.Begin || wd <= r.End) {
+				in = true
+				break
+			}
+		}
+		if !in {
+			return false
+		}
+	}
+	return true
+}
+
+var timeRE = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+
+// parseMinute parses a time of day in the 24 hour HH:MM format into the
+// number of minutes since midnight. 24:00 denotes the end of the day.
+func parseMinute(s string) (int, error) {
+	m := timeRE.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	h, _ := strconv.Atoi(m[1])
+	min, _ := strconv.Atoi(m[2])
+	if min > 59 || h > 24 || (h == 24 && min != 0) {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return h*60 + min, nil
+}
+
+type timeRangeYAML struct {
+	StartTime string `yaml:"start_time" json:"start_time"`
+	EndTime   string `yaml:"end_time" json:"end_time"`
+}
+
+func (tr *TimeRange) fromYAML(y timeRangeYAML) error {
+	if y.StartTime == "" || y.EndTime == "" {
+		return fmt.Errorf("both start_time and end_time must be set in a time range")
+	}
+	start, err := parseMinute(y.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := parseMinute(y.EndTime)
+	if err != nil {
+		return err
+	}
+	if start >= end {
+		return fmt.Errorf("start_time %s must be before end_time %s", y.StartTime, y.EndTime)
+	}
+	tr.StartMinute, tr.EndMinute = start, end
+	return nil
+}
+
+func (tr TimeRange) toYAML() timeRangeYAML {
+	f := func(m int) string { return fmt.Sprintf("%02d:%02d", m/60, m%60) }
+	return timeRangeYAML{StartTime: f(tr.StartMinute), EndTime: f(tr.EndMinute)}
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (tr *TimeRange) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var y timeRangeYAML
+	if err := unmarshal(&y); err != nil {
+		return err
+	}
+	return tr.fromYAML(y)
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (tr TimeRange) MarshalYAML() (interface{}, error) {
+	return tr.toYAML(), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (tr *TimeRange) UnmarshalJSON(b []byte) error {
+	var y timeRangeYAML
+	if err := json.Unmarshal(b, &y); err != nil {
+		return err
+	}
+	return tr.fromYAML(y)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (tr TimeRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(tr.toYAML())
+}
+
+// parseRange parses a single value or a range of two values separated by a
+// colon. Values are looked up in names if it is not nil, otherwise they are
+// parsed as integers.
+func parseRange(s string, names map[string]int) (InclusiveRange, error) {
+	parse := func(v string) (int, error) {
+		if names != nil {
+			if n, ok := names[strings.ToLower(v)]; ok {
+				return n, nil
+			}
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q", v)
+		}
+		return n, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 2 {
+		return InclusiveRange{}, fmt.Errorf("invalid range %q", s)
+	}
+	begin, err := parse(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return InclusiveRange{}, err
+	}
+	end := begin
+	if len(parts) == 2 {
+		if end, err = parse(strings.TrimSpace(parts[1])); err != nil {
+			return InclusiveRange{}, err
+		}
+	}
+	return InclusiveRange{Begin: begin, End: end}, nil
+}
+
+func (r InclusiveRange) format(names map[int]string) string {
+	f := func(v int) string {
+		if n, ok := names[v]; ok {
+			return n
+		}
+		return strconv.Itoa(v)
+	}
+	if r.Begin == r.End {
+		return f(r.Begin)
+	}
+	return f(r.Begin) + ":" + f(r.End)
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (r *WeekdayRange) UnmarshalText(b []byte) error {
+	ir, err := parseRange(string(b), daysOfWeek)
+	if err != nil {
+		return err
+	}
+	if ir.Begin < 0 || ir.Begin > 6 || ir.End < 0 || ir.End > 6 {
+		return fmt.Errorf("invalid weekday range %q", b)
+	}
+	r.InclusiveRange = ir
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (r WeekdayRange) MarshalText() ([]byte, error) {
+	return []byte(r.format(daysOfWeekInv)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (r *DayOfMonthRange) UnmarshalText(b []byte) error {
+	ir, err := parseRange(string(b), nil)
+	if err != nil {
+		return err
+	}
+	valid := func(d int) bool { return (d >= 1 && d <= 31) || (d <= -1 && d >= -31) }
+	if !valid(ir.Begin) || !valid(ir.End) {
+		return fmt.Errorf("invalid day of month range %q", b)
+	}
+	// Ranges mixing positive and negative days are resolved per month.
+	if (ir.Begin > 0) == (ir.End > 0) && ir.Begin > ir.End {
+		return fmt.Errorf("invalid day of month range %q", b)
+	}
+	r.InclusiveRange = ir
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (r DayOfMonthRange) MarshalText() ([]byte, error) {
+	return []byte(r.format(nil)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (r *MonthRange) UnmarshalText(b []byte) error {
+	ir, err := parseRange(string(b), months)
+	if err != nil {
+		return err
+	}
+	if ir.Begin < 1 || ir.End > 12 || ir.Begin > ir.End {
+		return fmt.Errorf("invalid month range %q", b)
+	}
+	r.InclusiveRange = ir
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (r MonthRange) MarshalText() ([]byte, error) {
+	return []byte(r.format(monthsInv)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (l *Location) UnmarshalText(b []byte) error {
+	loc, err := time.LoadLocation(string(b))
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q", b)
+	}
+	l.Location = loc
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (l Location) MarshalText() ([]byte, error) {
+	if l.Location == nil {
+		return []byte("UTC"), nil
+	}
+	return []byte(l.Location.String()), nil
+}
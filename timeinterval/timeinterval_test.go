@@ -0,0 +1,136 @@
+// Copyright 2018 Prometheus Team
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeinterval
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func mustParse(t *testing.T, s string) TimeInterval {
+	var ti TimeInterval
+	require.NoError(t, yaml.UnmarshalStrict([]byte(s), &ti))
+	return ti
+}
+
+func TestUnmarshal(t *testing.T) {
+	ti := mustParse(t, `
+times:
+- start_time: "09:00"
+  end_time: "17:30"
+weekdays: ['monday:friday', saturday]
+days_of_month: ['1:5', '-3:-1', 15]
+months: ['january:march', 12]
+location: Europe/Berlin
+`)
+	require.Equal(t, []TimeRange{{StartMinute: 9 * 60, EndMinute: 17*60 + 30}}, ti.Times)
+	require.Equal(t, []WeekdayRange{{InclusiveRange{1, 5}}, {InclusiveRange{6, 6}}}, ti.Weekdays)
+	require.Equal(t, []DayOfMonthRange{{InclusiveRange{1, 5}}, {InclusiveRange{-3, -1}}, {InclusiveRange{15, 15}}}, ti.DaysOfMonth)
+	require.Equal(t, []MonthRange{{InclusiveRange{1, 3}}, {InclusiveRange{12, 12}}}, ti.Months)
+	require.Equal(t, "Europe/Berlin", ti.Location.String())
+
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{`{times: [{start_time: "17:00", end_time: "09:00"}]}`, "start_time 17:00 must be before end_time 09:00"},
+		{`{times: [{start_time: "09:00"}]}`, "both start_time and end_time must be set in a time range"},
+		{`{times: [{start_time: "9am", end_time: "17:00"}]}`, `invalid time of day "9am", expected HH:MM`},
+		{`{times: [{start_time: "24:30", end_time: "25:00"}]}`, `invalid time of day "24:30"`},
+		{`{weekdays: ['monday:7']}`, `invalid weekday range "monday:7"`},
+		{`{weekdays: [funday]}`, `invalid value "funday"`},
+		{`{days_of_month: ['0:5']}`, `invalid day of month range "0:5"`},
+		{`{days_of_month: ['10:5']}`, `invalid day of month range "10:5"`},
+		{`{months: ['13']}`, `invalid month range "13"`},
+		{`{location: Mars/Olympus_Mons}`, `unknown time zone "Mars/Olympus_Mons"`},
+	} {
+		var ti TimeInterval
+		err := yaml.UnmarshalStrict([]byte(tc.in), &ti)
+		require.EqualError(t, err, tc.err, tc.in)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	ti := mustParse(t, `
+times:
+- start_time: "00:00"
+  end_time: "24:00"
+weekdays: ['monday:friday']
+days_of_month: ['-3:-1']
+months: [february]
+location: America/New_York
+`)
+
+	b, err := yaml.Marshal(ti)
+	require.NoError(t, err)
+	require.Equal(t, ti, mustParse(t, string(b)))
+
+	b, err = json.Marshal(ti)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"times": [{"start_time": "00:00", "end_time": "24:00"}],
+		"weekdays": ["monday:friday"],
+		"days_of_month": ["-3:-1"],
+		"months": ["february"],
+		"location": "America/New_York"
+	}`, string(b))
+
+	var res TimeInterval
+	require.NoError(t, json.Unmarshal(b, &res))
+	require.Equal(t, ti, res)
+}
+
+func TestContainsTime(t *testing.T) {
+	businessHours := mustParse(t, `
+times: [{start_time: "09:00", end_time: "17:00"}]
+weekdays: ['monday:friday']
+location: Europe/Berlin
+`)
+	weekend := mustParse(t, `{weekdays: ['friday:sunday']}`)
+	endOfMonth := mustParse(t, `{days_of_month: ['-2:-1']}`)
+	winter := mustParse(t, `{months: ['december', 'january:february']}`)
+
+	for _, tc := range []struct {
+		ti       TimeInterval
+		t        string
+		contains bool
+	}{
+		// 08:30 UTC is 09:30 in Berlin in winter.
+		{businessHours, "2018-01-08T08:30:00Z", true},
+		{businessHours, "2018-01-08T07:30:00Z", false},
+		// 16:30 UTC is 18:30 in Berlin in summer.
+		{businessHours, "2018-07-09T16:30:00Z", false},
+		{businessHours, "2018-07-09T14:59:00Z", true},
+		// 23:30 UTC on Friday is Saturday in Berlin.
+		{businessHours, "2018-01-12T23:30:00Z", false},
+		{weekend, "2018-01-12T12:00:00Z", true},
+		{weekend, "2018-01-14T12:00:00Z", true},
+		{weekend, "2018-01-15T12:00:00Z", false},
+		{endOfMonth, "2018-02-27T12:00:00Z", true},
+		{endOfMonth, "2018-02-26T12:00:00Z", false},
+		{endOfMonth, "2018-03-30T12:00:00Z", true},
+		{endOfMonth, "2018-03-29T12:00:00Z", false},
+		{winter, "2018-12-24T12:00:00Z", true},
+		{winter, "2018-03-01T00:00:00Z", false},
+		{TimeInterval{}, "2018-03-01T00:00:00Z", true},
+	} {
+		ts, err := time.Parse(time.RFC3339, tc.t)
+		require.NoError(t, err)
+		require.Equal(t, tc.contains, tc.ti.ContainsTime(ts), tc.t)
+	}
+}
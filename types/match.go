@@ -21,19 +21,62 @@ import (
 	"bytes"
 
 	"github.com/prometheus/common/model"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
+)
+
+// MatchOperator is an operator of a Matcher other than equality and
+// regular expression matching.
+type MatchOperator string
+
+// Possible MatchOperators.
+const (
+	// MatchAbsent and MatchPresent match if the label is not set or set
+	// respectively. The value of the matcher is empty.
+	MatchAbsent  MatchOperator = "absent"
+	MatchPresent MatchOperator = "present"
+	// The numeric operators compare the label value as a number with the
+	// value of the matcher.
+	MatchLessThan     MatchOperator = "<"
+	MatchLessEqual    MatchOperator = "<="
+	MatchGreaterThan  MatchOperator = ">"
+	MatchGreaterEqual MatchOperator = ">="
 )
 
+var operatorTypes = map[MatchOperator]labels.MatchType{
+	MatchAbsent:       labels.MatchAbsent,
+	MatchPresent:      labels.MatchPresent,
+	MatchLessThan:     labels.MatchLessThan,
+	MatchLessEqual:    labels.MatchLessEqual,
+	MatchGreaterThan:  labels.MatchGreaterThan,
+	MatchGreaterEqual: labels.MatchGreaterEqual,
+}
+
 // Matcher defines a matching rule for the value of a given label.
 type Matcher struct {
 	Name    string `json:"name"`
 	Value   string `json:"value"`
 	IsRegex bool   `json:"isRegex"`
+	// Operator replaces the equality or regular expression match if set.
+	Operator MatchOperator `json:"operator,omitempty"`
 
 	regex *regexp.Regexp
+	op    *labels.Matcher
 }
 
 // Init internals of the Matcher. Must be called before using Match.
 func (m *Matcher) Init() error {
+	if m.Operator != "" {
+		t, ok := operatorTypes[m.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %q", m.Operator)
+		}
+		op, err := labels.NewMatcher(t, m.Name, m.Value)
+		if err == nil {
+			m.op = op
+		}
+		return err
+	}
 	if !m.IsRegex {
 		return nil
 	}
@@ -45,6 +88,13 @@ func (m *Matcher) Init() error {
 }
 
 func (m *Matcher) String() string {
+	switch m.Operator {
+	case "":
+	case MatchAbsent, MatchPresent:
+		return fmt.Sprintf("%s(%s)", m.Operator, m.Name)
+	default:
+		return fmt.Sprintf("%s%s%q", m.Name, m.Operator, m.Value)
+	}
 	if m.IsRegex {
 		return fmt.Sprintf("%s=~%q", m.Name, m.Value)
 	}
@@ -56,6 +106,19 @@ func (m *Matcher) Validate() error {
 	if !model.LabelName(m.Name).IsValid() {
 		return fmt.Errorf("invalid name %q", m.Name)
 	}
+	if m.Operator != "" {
+		t, ok := operatorTypes[m.Operator]
+		if !ok {
+			return fmt.Errorf("unknown operator %q", m.Operator)
+		}
+		if m.IsRegex {
+			return fmt.Errorf("operator %q cannot be used with a regular expression", m.Operator)
+		}
+		if _, err := labels.NewMatcher(t, m.Name, m.Value); err != nil {
+			return err
+		}
+		return nil
+	}
 	if m.IsRegex {
 		if _, err := regexp.Compile(m.Value); err != nil {
 			return fmt.Errorf("invalid regular expression %q", m.Value)
@@ -74,6 +137,9 @@ func (m *Matcher) Match(lset model.LabelSet) bool {
 	// for the comparison below.
 	v := lset[model.LabelName(m.Name)]
 
+	if m.op != nil {
+		return m.op.Matches(string(v))
+	}
 	if m.IsRegex {
 		return m.regex.MatchString(string(v))
 	}
@@ -103,6 +169,31 @@ func NewRegexMatcher(name model.LabelName, re *regexp.Regexp) *Matcher {
 	}
 }
 
+// NewMatcherFromLabels returns a new matcher equivalent to the given label
+// matcher. The matcher is already initialized. Negative matchers are not
+// supported.
+func NewMatcherFromLabels(lm *labels.Matcher) (*Matcher, error) {
+	m := NewMatcher(model.LabelName(lm.Name), lm.Value)
+	switch lm.Type {
+	case labels.MatchEqual:
+	case labels.MatchRegexp:
+		m.IsRegex = true
+	default:
+		for op, t := range operatorTypes {
+			if t == lm.Type {
+				m.Operator = op
+			}
+		}
+		if m.Operator == "" {
+			return nil, fmt.Errorf("unsupported match type %s", lm.Type)
+		}
+	}
+	if err := m.Init(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // Matchers provides the Match and Fingerprint methods for a slice of Matchers.
 // Matchers must always be sorted.
 type Matchers []*Matcher
@@ -130,7 +221,10 @@ func (ms Matchers) Less(i, j int) bool {
 	if ms[i].Value < ms[j].Value {
 		return true
 	}
-	return !ms[i].IsRegex && ms[j].IsRegex
+	if ms[i].IsRegex != ms[j].IsRegex {
+		return !ms[i].IsRegex
+	}
+	return ms[i].Operator < ms[j].Operator
 }
 
 // Equal returns whether both Matchers are equal.
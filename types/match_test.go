@@ -20,6 +20,8 @@ import (
 
 	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus/alertmanager/pkg/labels"
 )
 
 func TestMatcherValidate(t *testing.T) {
@@ -46,6 +48,14 @@ func TestMatcherValidate(t *testing.T) {
 			matcher: Matcher{Name: validLabelName, Value: validRegexValue, IsRegex: true},
 			valid:   true,
 		},
+		{
+			matcher: Matcher{Name: validLabelName, Operator: MatchAbsent},
+			valid:   true,
+		},
+		{
+			matcher: Matcher{Name: validLabelName, Value: "2.5", Operator: MatchGreaterEqual},
+			valid:   true,
+		},
 		// invalid tests
 		{
 			matcher:  Matcher{Name: invalidLabelName, Value: validStringValue},
@@ -62,6 +72,26 @@ func TestMatcherValidate(t *testing.T) {
 			valid:    false,
 			errorMsg: fmt.Sprintf("invalid regular expression %q", invalidRegexValue),
 		},
+		{
+			matcher:  Matcher{Name: validLabelName, Value: validStringValue, Operator: MatchPresent},
+			valid:    false,
+			errorMsg: fmt.Sprintf("unexpected value %q for present matcher", validStringValue),
+		},
+		{
+			matcher:  Matcher{Name: validLabelName, Value: validStringValue, Operator: MatchLessThan},
+			valid:    false,
+			errorMsg: fmt.Sprintf("invalid number %q", validStringValue),
+		},
+		{
+			matcher:  Matcher{Name: validLabelName, Value: "1", Operator: MatchLessThan, IsRegex: true},
+			valid:    false,
+			errorMsg: `operator "<" cannot be used with a regular expression`,
+		},
+		{
+			matcher:  Matcher{Name: validLabelName, Value: "1", Operator: "~"},
+			valid:    false,
+			errorMsg: `unknown operator "~"`,
+		},
 	}
 
 	for _, test := range tests {
@@ -96,9 +126,16 @@ func TestMatcherMatch(t *testing.T) {
 		{matcher: Matcher{Name: "label", Value: "diffval.*", IsRegex: true}, expected: false},
 		//unset label
 		{matcher: Matcher{Name: "difflabel", Value: "value"}, expected: false},
+		{matcher: Matcher{Name: "difflabel", Operator: MatchAbsent}, expected: true},
+		{matcher: Matcher{Name: "label", Operator: MatchAbsent}, expected: false},
+		{matcher: Matcher{Name: "label", Operator: MatchPresent}, expected: true},
+		{matcher: Matcher{Name: "severity", Value: "3", Operator: MatchGreaterEqual}, expected: true},
+		{matcher: Matcher{Name: "severity", Value: "3", Operator: MatchGreaterThan}, expected: false},
+		{matcher: Matcher{Name: "severity", Value: "10", Operator: MatchLessThan}, expected: true},
+		{matcher: Matcher{Name: "label", Value: "10", Operator: MatchLessThan}, expected: false},
 	}
 
-	lset := model.LabelSet{"label": "value"}
+	lset := model.LabelSet{"label": "value", "severity": "3"}
 	for _, test := range tests {
 		test.matcher.Init()
 
@@ -124,6 +161,65 @@ func TestMatcherString(t *testing.T) {
 	if m.String() != "foo=~\".*\"" {
 		t.Errorf("unexpected matcher string %#v", m.String())
 	}
+
+	m = &Matcher{Name: "foo", Operator: MatchAbsent}
+
+	if m.String() != "absent(foo)" {
+		t.Errorf("unexpected matcher string %#v", m.String())
+	}
+
+	m = &Matcher{Name: "foo", Value: "3", Operator: MatchLessEqual}
+
+	if m.String() != "foo<=\"3\"" {
+		t.Errorf("unexpected matcher string %#v", m.String())
+	}
+}
+
+func TestNewMatcherFromLabels(t *testing.T) {
+	for _, test := range []struct {
+		t        labels.MatchType
+		value    string
+		expected Matcher
+		err      string
+	}{
+		{
+			t:        labels.MatchEqual,
+			value:    "bar",
+			expected: Matcher{Name: "foo", Value: "bar"},
+		},
+		{
+			t:        labels.MatchRegexp,
+			value:    "b.*",
+			expected: Matcher{Name: "foo", Value: "b.*", IsRegex: true},
+		},
+		{
+			t:        labels.MatchPresent,
+			expected: Matcher{Name: "foo", Operator: MatchPresent},
+		},
+		{
+			t:        labels.MatchGreaterThan,
+			value:    "1",
+			expected: Matcher{Name: "foo", Value: "1", Operator: MatchGreaterThan},
+		},
+		{
+			t:     labels.MatchNotEqual,
+			value: "bar",
+			err:   "unsupported match type !=",
+		},
+	} {
+		lm, err := labels.NewMatcher(test.t, "foo", test.value)
+		require.NoError(t, err)
+
+		m, err := NewMatcherFromLabels(lm)
+		if test.err != "" {
+			require.EqualError(t, err, test.err)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, test.expected.String(), m.String())
+		require.Equal(t, test.expected.IsRegex, m.IsRegex)
+		require.Equal(t, test.expected.Operator, m.Operator)
+	}
 }
 
 func TestMatchersString(t *testing.T) {
@@ -14,6 +14,7 @@
 package types
 
 import (
+	"encoding/json"
 	"strings"
 	"sync"
 	"time"
@@ -349,6 +350,16 @@ type Silence struct {
 	CreatedBy string `json:"createdBy"`
 	Comment   string `json:"comment,omitempty"`
 
+	// An optional schedule limiting the silence to recurring windows
+	// within its time range.
+	Schedule *SilenceSchedule `json:"schedule,omitempty"`
+
+	// Comments appended to the silence after its creation, oldest first.
+	Thread []*SilenceComment `json:"thread,omitempty"`
+
+	// The team owning the silence.
+	Owner string `json:"owner,omitempty"`
+
 	// timeFunc provides the time against which to evaluate
 	// the silence. Used for test injection.
 	now func() time.Time
@@ -362,8 +373,81 @@ func (s *Silence) Expired() bool {
 	return s.StartsAt.Equal(s.EndsAt)
 }
 
+// SilenceSchedule specifies recurring windows during which a silence is
+// active.
+type SilenceSchedule struct {
+	// A cron expression of the times at which a window starts.
+	Cron string `json:"cron"`
+	// The length of each window.
+	Duration model.Duration `json:"duration"`
+	// The IANA time zone the cron expression is evaluated in. Defaults to
+	// UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s *SilenceSchedule) MarshalJSON() ([]byte, error) {
+	type plain SilenceSchedule
+	return json.Marshal(&struct {
+		*plain
+		Duration string `json:"duration"`
+	}{
+		plain:    (*plain)(s),
+		Duration: s.Duration.String(),
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *SilenceSchedule) UnmarshalJSON(b []byte) error {
+	type plain SilenceSchedule
+	v := struct {
+		*plain
+		Duration string `json:"duration"`
+	}{
+		plain: (*plain)(s),
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	d, err := model.ParseDuration(v.Duration)
+	if err != nil {
+		return err
+	}
+	s.Duration = d
+	return nil
+}
+
+// SilenceComment is a comment appended to a silence.
+type SilenceComment struct {
+	Author    string    `json:"author"`
+	Comment   string    `json:"comment"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SilenceChange is a modification in the history of a silence.
+type SilenceChange struct {
+	Type      SilenceChangeType `json:"type"`
+	Timestamp time.Time         `json:"timestamp"`
+	// The user who made the change and the address it was requested from.
+	Author     string `json:"author,omitempty"`
+	RemoteAddr string `json:"remoteAddr,omitempty"`
+	// The silence before the change. For created silences it is only set
+	// if they replace another silence.
+	Previous *Silence `json:"previous,omitempty"`
+}
+
+type SilenceChangeType string
+
+const (
+	SilenceChangeCreated SilenceChangeType = "created"
+	SilenceChangeUpdated SilenceChangeType = "updated"
+	SilenceChangeExpired SilenceChangeType = "expired"
+)
+
 type SilenceStatus struct {
 	State SilenceState `json:"state"`
+	// The next time a scheduled silence becomes active.
+	NextActivation *time.Time `json:"nextActivation,omitempty"`
 }
 
 type SilenceState string
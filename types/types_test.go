@@ -14,6 +14,7 @@
 package types
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 	"time"
@@ -94,3 +95,20 @@ func TestSilenceExpired(t *testing.T) {
 	silence = Silence{StartsAt: now, EndsAt: now.Add(time.Hour)}
 	require.False(t, silence.Expired())
 }
+
+func TestSilenceScheduleJSON(t *testing.T) {
+	in := &SilenceSchedule{
+		Cron:     "0 22 * * *",
+		Duration: model.Duration(8 * time.Hour),
+		TimeZone: "Europe/Berlin",
+	}
+	b, err := json.Marshal(in)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"cron":"0 22 * * *","duration":"8h","timeZone":"Europe/Berlin"}`, string(b))
+
+	var out SilenceSchedule
+	require.NoError(t, json.Unmarshal(b, &out))
+	require.Equal(t, in, &out)
+
+	require.Error(t, json.Unmarshal([]byte(`{"cron":"0 22 * * *","duration":"8 hours"}`), &out))
+}